@@ -0,0 +1,27 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"mcprapi/backend/internal/infrastructure/container"
+)
+
+// runAuditVerify 校验审计链并输出结果，校验失败时以状态码1退出
+func runAuditVerify(c *container.Container) {
+	result, err := c.AuditTrailService.Verify()
+	if err != nil {
+		c.Close()
+		log.Fatalf("校验审计链失败: %v", err)
+	}
+
+	data, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Println(string(data))
+
+	if !result.Valid {
+		c.Close()
+		os.Exit(1)
+	}
+}
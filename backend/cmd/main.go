@@ -56,15 +56,110 @@ func main() {
 	}
 	defer c.Close()
 
+	// 子命令：校验审计链后退出
+	if flag.Arg(0) == "audit-verify" {
+		runAuditVerify(c)
+		return
+	}
+
+	// 初始化处理器并注册路由
+	r := newRouter(c, config)
+	checkAuditRoutes(r)
+
+	// 登记服务自身的路由，使其可以通过API ID授权
+	registerSystemAPIs(c, r)
+
+	// 启动后台任务，服务关闭时停止
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	c.LoginGuardService.Start(jobCtx)
+	c.DecisionLogger.Start(jobCtx)
+	c.AuditTrailService.Start(jobCtx)
+	c.APISyncService.Start(jobCtx)
+	c.APILifecycleService.Start(jobCtx)
+	c.BundleService.Start(jobCtx)
+	c.CascadeService.Start(jobCtx)
+	if c.LDAPService != nil {
+		c.LDAPService.Start(jobCtx)
+	}
+
+	// 启动HTTP服务器
+	port := config.GetInt("server.port")
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: r,
+	}
+
+	// 优雅关闭
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("启动HTTP服务器失败: %v", err)
+		}
+	}()
+
+	log.Printf("服务器已启动，监听端口: %d", port)
+
+	// 等待中断信号
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("关闭服务器...")
+
+	// 设置关闭超时
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 关闭HTTP服务器
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Fatalf("关闭服务器失败: %v", err)
+	}
+
+	// 停止后台任务，等待授权决策日志写完
+	stopJobs()
+	c.DecisionLogger.Wait()
+
+	log.Println("服务器已关闭")
+}
+
+// newRouter 创建处理器并注册全部路由
+func newRouter(c *container.Container, config *viper.Viper) *gin.Engine {
 	// 初始化处理器
 	userHandler := handler.NewUserHandler(c.UserService, c.AuthService)
 	roleHandler := handler.NewRoleHandler(c.RoleService)
 	apiHandler := handler.NewAPIHandler(c.APIService, c.AuthService)
+	apiImportHandler := handler.NewAPIImportHandler(c.APIImportService)
+	apiSyncHandler := handler.NewAPISyncHandler(c.APISyncService)
+	apiLifecycleHandler := handler.NewAPILifecycleHandler(c.APILifecycleService)
+	apiGroupHandler := handler.NewAPIGroupHandler(c.APIGroupService)
+	permissionBundleHandler := handler.NewPermissionBundleHandler(c.BundleService)
 	departmentHandler := handler.NewDepartmentHandler(c.DepartmentService, c.AuthService)
+	departmentReorgHandler := handler.NewDepartmentReorgHandler(c.DeptReorgService)
+	cascadeHandler := handler.NewCascadeHandler(c.CascadeService)
 	businessHandler := handler.NewBusinessHandler(c.BusinessService, c.AuthService)
 	casbinHandler := handler.NewCasbinHandler(c.CasbinService, c.AuthService)
 	dashboardHandler := handler.NewDashboardHandler(c.DashboardService, c.AuthService)
 	initHandler := handler.NewInitHandler(c.InitService)
+	oauthHandler := handler.NewOAuthHandler(c.OAuthService)
+	ssoHandler := handler.NewSSOHandler(c.SSOService)
+	qrCodeHandler := handler.NewQRCodeHandler(c.QRCodeService)
+	mfaHandler := handler.NewMFAHandler(c.MFAService)
+	loginGuardHandler := handler.NewLoginGuardHandler(c.LoginGuardService)
+	passwordHandler := handler.NewPasswordHandler(c.PasswordService)
+	sessionHandler := handler.NewSessionHandler(c.SessionService)
+	impersonationHandler := handler.NewImpersonationHandler(c.ImpersonationService)
+	// 代登录期间禁止的请求不包括结束代登录
+	impersonation := middleware.Impersonation(c.ImpersonationService, "/api/v1/auth/impersonation/stop")
+	tokenScopeHandler := handler.NewTokenScopeHandler(c.TokenScopeService)
+	// 会话存储不可用时是否放行由session.fail_open控制，默认拒绝
+	jwtAuth := middleware.JWT(config.GetString("jwt.secret"), c.SessionService, c.UserStatusService, config.GetBool("session.fail_open"))
+	// 管理操作审计
+	auditHandler := handler.NewAuditHandler(c.AuditService, c.AuditTrailService)
+	decisionLogHandler := handler.NewDecisionLogHandler(c.DecisionLogger)
+	audit := middleware.Audit(c.AuditService)
+	// 受限令牌可以校验权限、查看自己的信息和签发范围更小的令牌
+	tokenRestriction := middleware.TokenRestriction(c.AuthService, "/api/v1/api/check-permission", "/api/v1/user/info", "/api/v1/user/scoped-token")
+	// OAuth2令牌只能访问按scope校验的路由，以及权限校验和查看自己的信息
+	clientToken := middleware.ClientToken("/api/v1/api/check-permission", "/api/v1/user/info")
 
 	// 初始化部门级别处理器
 	deptPermissionHandler := handler.NewDeptPermissionHandler(c.DeptPermissionService)
@@ -81,6 +176,7 @@ func main() {
 
 	// 注册中间件
 	r.Use(middleware.CORS())
+	r.Use(middleware.RequestID())
 
 	// Swagger文档路由
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -103,9 +199,22 @@ func main() {
 		publicAPI.POST("/auth/login", userHandler.Login)
 		// 用户登出
 		publicAPI.POST("/auth/logout", userHandler.Logout)
+		// 登录二次验证
+		publicAPI.POST("/auth/mfa/verify", mfaHandler.Verify)
+		publicAPI.POST("/auth/mfa/enroll", mfaHandler.EnrollOnLogin)
+		// 密码策略、过期密码修改和重置
+		publicAPI.GET("/auth/password/policy", passwordHandler.Policy)
+		publicAPI.POST("/auth/password/change", passwordHandler.ChangeExpired)
+		publicAPI.POST("/auth/password/reset", passwordHandler.Reset)
 		// 扫码登录
-		publicAPI.POST("/auth/qrcode", userHandler.GenerateQRCode)
-		publicAPI.GET("/auth/qrcode/:id", userHandler.CheckQRCode)
+		publicAPI.POST("/auth/qrcode", qrCodeHandler.Generate)
+		publicAPI.GET("/auth/qrcode/:id", qrCodeHandler.Check)
+		// OAuth2令牌端点（客户端凭证认证）
+		publicAPI.POST("/oauth/token", oauthHandler.Token)
+		// 外部身份登录（OIDC）
+		publicAPI.GET("/auth/sso/providers", ssoHandler.ListProviders)
+		publicAPI.GET("/auth/sso/:provider/login", ssoHandler.Login)
+		publicAPI.GET("/auth/sso/:provider/callback", ssoHandler.Callback)
 		
 		// 数据库初始化相关路由（无需认证）
 		initHandler.Register(publicAPI)
@@ -113,7 +222,11 @@ func main() {
 
 	// 基础认证API（只需要JWT认证，不需要权限控制）
 	basicAuthAPI := r.Group("/api/v1")
-	basicAuthAPI.Use(middleware.JWT(config.GetString("jwt.secret")))
+	basicAuthAPI.Use(jwtAuth)
+	basicAuthAPI.Use(impersonation)
+	basicAuthAPI.Use(audit)
+	basicAuthAPI.Use(tokenRestriction)
+	basicAuthAPI.Use(clientToken)
 	{
 		// 获取当前用户信息 - 所有登录用户都可以访问
 		basicAuthAPI.GET("/user/info", userHandler.GetInfo)
@@ -121,6 +234,33 @@ func main() {
 		basicAuthAPI.GET("/user/:id/token", userHandler.GetUserToken)
 		basicAuthAPI.POST("/user/refresh-token", userHandler.RefreshUserToken)
 		basicAuthAPI.POST("/user/refresh-token-with-version", userHandler.RefreshUserTokenWithVersion)
+		// 扫码登录确认 - 已登录的手机端使用
+		basicAuthAPI.POST("/auth/qrcode/:id/scan", qrCodeHandler.Scan)
+		basicAuthAPI.POST("/auth/qrcode/:id/confirm", qrCodeHandler.Confirm)
+		basicAuthAPI.POST("/auth/qrcode/:id/cancel", qrCodeHandler.Cancel)
+		// 多因素认证 - 所有登录用户都可以访问
+		basicAuthAPI.GET("/user/mfa", mfaHandler.Status)
+		basicAuthAPI.POST("/user/mfa/enroll", mfaHandler.Enroll)
+		basicAuthAPI.POST("/user/mfa/activate", mfaHandler.Activate)
+		basicAuthAPI.POST("/user/mfa/disable", mfaHandler.Disable)
+		basicAuthAPI.POST("/user/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+		// 登录历史 - 所有登录用户都可以访问
+		basicAuthAPI.GET("/user/login-attempts", loginGuardHandler.ListMyAttempts)
+		// 修改密码 - 所有登录用户都可以访问
+		basicAuthAPI.GET("/user/password", passwordHandler.Status)
+		basicAuthAPI.PUT("/user/password", passwordHandler.Change)
+		// 会话管理 - 所有登录用户都可以访问
+		basicAuthAPI.GET("/user/sessions", sessionHandler.ListMySessions)
+		basicAuthAPI.DELETE("/user/sessions", sessionHandler.RevokeMyOtherSessions)
+		basicAuthAPI.DELETE("/user/sessions/:id", sessionHandler.RevokeMySession)
+		// 结束代登录 - 使用代登录令牌调用
+		basicAuthAPI.POST("/auth/impersonation/stop", impersonationHandler.Stop)
+		// 签发受限令牌
+		basicAuthAPI.POST("/user/scoped-token", tokenScopeHandler.Issue)
+		// 外部身份绑定 - 所有登录用户都可以访问
+		basicAuthAPI.GET("/user/identities", ssoHandler.ListIdentities)
+		basicAuthAPI.POST("/user/identities/:provider", ssoHandler.Link)
+		basicAuthAPI.DELETE("/user/identities/:provider", ssoHandler.Unlink)
 		// 获取所有业务线 - 所有登录用户都可以访问
 		basicAuthAPI.GET("/business/all", businessHandler.GetAll)
 		// API列表 - 所有登录用户都可以访问（非admin只能看到自己部门的）
@@ -136,11 +276,15 @@ func main() {
 		basicAuthAPI.PUT("/api/:id", apiHandler.Update)
 		basicAuthAPI.GET("/api/business/:id", apiHandler.ListByBusiness)
 		basicAuthAPI.GET("/api/category/list", apiHandler.ListCategories)
+		basicAuthAPI.GET("/api/category/tree", apiHandler.CategoryTree)
 		basicAuthAPI.GET("/api/category/:id", apiHandler.GetCategory)
 		// 创建API - 所有登录用户都可以访问
 		basicAuthAPI.POST("/api", apiHandler.Create)
 		// 权限校验 - 所有登录用户都可以访问
 		basicAuthAPI.POST("/api/check-permission", apiHandler.CheckPermission)
+		// OAuth2授权确认 - 登录用户为第三方客户端授权
+		basicAuthAPI.GET("/oauth/authorize", oauthHandler.GetAuthorize)
+		basicAuthAPI.POST("/oauth/authorize", oauthHandler.Authorize)
 
 		// Dashboard路由 - 所有登录用户都可以访问
 		dashboardHandler.RegisterRoutes(basicAuthAPI)
@@ -148,8 +292,10 @@ func main() {
 
 	// 认证API（需要JWT认证和权限控制）
 	authAPI := r.Group("/api/v1")
-	authAPI.Use(middleware.JWT(config.GetString("jwt.secret")))
-	authAPI.Use(middleware.Casbin(c.Enforcer, c.UserRepository)) // 添加权限控制中间件
+	authAPI.Use(jwtAuth)
+	authAPI.Use(impersonation)
+	authAPI.Use(audit)
+	authAPI.Use(middleware.Casbin(c.Enforcer, c.UserRepository, c.AuthService)) // 添加权限控制中间件
 	{
 		// 用户管理
 		userHandler.Register(authAPI)
@@ -159,9 +305,16 @@ func main() {
 
 		// API管理
 		apiHandler.Register(authAPI)
+		apiImportHandler.Register(authAPI)
+		apiSyncHandler.Register(authAPI)
+		apiLifecycleHandler.Register(authAPI)
+		apiGroupHandler.Register(authAPI)
+		permissionBundleHandler.Register(authAPI)
 
 		// 部门管理
 		departmentHandler.Register(authAPI)
+		departmentReorgHandler.Register(authAPI)
+		cascadeHandler.Register(authAPI)
 
 		// 业务线管理
 		businessHandler.Register(authAPI)
@@ -172,46 +325,54 @@ func main() {
 		deptRoleHandler.Register(authAPI)
 		deptBusinessHandler.Register(authAPI)
 		deptAPIHandler.Register(authAPI)
+
+		// OAuth2客户端管理
+		oauthHandler.Register(authAPI)
+
+		// 多因素认证重置
+		mfaHandler.Register(authAPI)
+
+		// 登录锁定管理和登录历史
+		loginGuardHandler.Register(authAPI)
+
+		// 密码重置和强制修改
+		passwordHandler.Register(authAPI)
+
+		// 用户会话管理
+		sessionHandler.Register(authAPI)
+
+		// 管理员代登录
+		impersonationHandler.Register(authAPI)
+
+		// 审计日志
+		auditHandler.Register(authAPI)
+		decisionLogHandler.Register(authAPI)
+
+		// LDAP目录同步
+		if c.LDAPService != nil {
+			handler.NewLDAPHandler(c.LDAPService).Register(authAPI)
+		}
 	}
 
 	// Casbin权限管理API（只需要JWT认证，不需要Casbin权限控制）
 	casbinAPI := r.Group("/api/v1")
-	casbinAPI.Use(middleware.JWT(config.GetString("jwt.secret")))
+	casbinAPI.Use(jwtAuth)
+	casbinAPI.Use(impersonation)
+	casbinAPI.Use(audit)
+	casbinAPI.Use(tokenRestriction)
+	casbinAPI.Use(clientToken)
 	{
 		// Casbin权限管理
 		casbinHandler.Register(casbinAPI)
 	}
 
-	// 启动HTTP服务器
-	port := config.GetInt("server.port")
-	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%d", port),
-		Handler: r,
-	}
-
-	// 优雅关闭
-	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("启动HTTP服务器失败: %v", err)
-		}
-	}()
-
-	log.Printf("服务器已启动，监听端口: %d", port)
-
-	// 等待中断信号
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("关闭服务器...")
-
-	// 设置关闭超时
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// 关闭HTTP服务器
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("关闭服务器失败: %v", err)
+	// SCIM用户和组同步（使用预置令牌认证）
+	if scimToken := config.GetString("scim.token"); scimToken != "" {
+		scimAPI := r.Group("/scim/v2")
+		scimAPI.Use(middleware.SCIMAuth(scimToken))
+		scimAPI.Use(audit)
+		handler.NewSCIMHandler(c.SCIMService).Register(scimAPI)
 	}
 
-	log.Println("服务器已关闭")
+	return r
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/infrastructure/container"
+)
+
+// systemRoutes 获取已注册的路由
+func systemRoutes(r *gin.Engine) []service.SystemRoute {
+	routes := make([]service.SystemRoute, 0, len(r.Routes()))
+	for _, route := range r.Routes() {
+		routes = append(routes, service.SystemRoute{
+			Method:  route.Method,
+			Path:    route.Path,
+			Handler: route.Handler,
+		})
+	}
+	return routes
+}
+
+// registerSystemAPIs 将已注册的路由登记为系统业务线下的API，失败时只记录日志
+func registerSystemAPIs(c *container.Container, r *gin.Engine) {
+	result, err := c.SystemAPIService.Register(systemRoutes(r))
+	if err != nil {
+		log.Printf("登记系统API失败: %v", err)
+		return
+	}
+	if result == nil {
+		return
+	}
+	log.Printf("登记系统API: 新增%d 更新%d 未变化%d 废弃%d", result.Created, result.Updated, result.Unchanged, result.Deprecated)
+	for _, conflict := range result.Conflicts {
+		log.Printf("系统路由已被其他业务线登记，跳过: %s", conflict)
+	}
+}
+
+// checkAuditRoutes 检查已注册路由的审计规则，只记录日志；未登记的修改操作仍按默认规则审计
+func checkAuditRoutes(r *gin.Engine) {
+	unlisted, stale := service.CheckAuditRoutes(systemRoutes(r))
+	for _, route := range unlisted {
+		log.Printf("修改操作未登记审计规则，按默认规则审计: %s", route)
+	}
+	for _, route := range stale {
+		log.Printf("审计规则对应的路由未注册: %s", route)
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/infrastructure/container"
+)
+
+func TestAuditRoutesCoverRegisteredRoutes(t *testing.T) {
+	config := viper.New()
+	config.Set("gin.mode", "test")
+	config.Set("scim.token", "token")
+	// 只注册路由，不处理请求；启用可选的LDAP和SCIM路由
+	r := newRouter(&container.Container{LDAPService: &service.LDAPService{}}, config)
+
+	unlisted, stale := service.CheckAuditRoutes(systemRoutes(r))
+	for _, route := range unlisted {
+		t.Errorf("修改操作既未登记审计规则也未豁免: %s", route)
+	}
+	for _, route := range stale {
+		t.Errorf("审计规则对应的路由未注册: %s", route)
+	}
+}
@@ -14,18 +14,30 @@ import (
 
 // API API实体
 type API struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Name        string    `gorm:"size:100" json:"name"`
-	Path        string    `gorm:"size:200;index" json:"path"`  // API路径
-	Method      string    `gorm:"size:10" json:"method"`       // HTTP方法: GET, POST, PUT, DELETE
-	Description string    `gorm:"size:200" json:"description"` // 描述
-	BusinessID  uint      `gorm:"index" json:"business_id"`    // 所属业务线ID
-	CategoryID  uint      `gorm:"index" json:"category_id"`    // 分类ID
-	Status      int       `gorm:"default:1" json:"status"`     // 1: 启用, 0: 禁用
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Name         string     `gorm:"size:100" json:"name"`
+	Path         string     `gorm:"size:200;index" json:"path"`                    // API路径
+	Method       string     `gorm:"size:10" json:"method"`                         // HTTP方法: GET, POST, PUT, DELETE
+	Description  string     `gorm:"size:200" json:"description"`                   // 描述
+	BusinessID   uint       `gorm:"index" json:"business_id"`                      // 所属业务线ID
+	CategoryID   uint       `gorm:"index" json:"category_id"`                      // 分类ID
+	Status       int        `gorm:"default:1" json:"status"`                       // 1: 启用, 0: 禁用
+	Lifecycle    string     `gorm:"size:20;default:active;index" json:"lifecycle"` // 生命周期: draft, active, deprecated, retired
+	DeprecatedAt *time.Time `json:"deprecated_at,omitempty"`                       // 标记为废弃的时间
+	SunsetAt     *time.Time `json:"sunset_at,omitempty"`                           // 下线时间，废弃的API到期后自动下线
+	SunsetNotice int        `gorm:"default:0" json:"-"`                            // 已发送的下线提醒次数
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 }
 
+// API生命周期
+const (
+	APILifecycleDraft      = "draft"      // 草稿，尚未正式发布
+	APILifecycleActive     = "active"     // 正常
+	APILifecycleDeprecated = "deprecated" // 已废弃，仍可访问，响应携带Deprecation/Sunset头
+	APILifecycleRetired    = "retired"    // 已下线，拒绝访问
+)
+
 // TableName 设置表名
 func (API) TableName() string {
 	return "apis"
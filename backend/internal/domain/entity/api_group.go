@@ -0,0 +1,62 @@
+package entity
+
+import (
+	"time"
+)
+
+// APIGroup API组，将同一资源操作的多个API（如/v1、/v2版本的同一接口）归为一组，如order.read，按组授权给角色
+type APIGroup struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:100" json:"name"`
+	Code        string    `gorm:"size:100;uniqueIndex" json:"code"` // 组编码，如order.read
+	Description string    `gorm:"size:200" json:"description"`
+	BusinessID  uint      `gorm:"index" json:"business_id"` // 所属业务线ID，0表示不限业务线
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (APIGroup) TableName() string {
+	return "api_groups"
+}
+
+// APIGroupMember API组成员
+type APIGroupMember struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	GroupID   uint      `gorm:"uniqueIndex:idx_group_api" json:"group_id"`
+	APIID     uint      `gorm:"uniqueIndex:idx_group_api;index" json:"api_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (APIGroupMember) TableName() string {
+	return "api_group_members"
+}
+
+// RoleAPIGroup 角色被授予的API组，组内的API编译为角色的Casbin策略
+type RoleAPIGroup struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	RoleID    uint      `gorm:"uniqueIndex:idx_role_group" json:"role_id"`
+	GroupID   uint      `gorm:"uniqueIndex:idx_role_group;index" json:"group_id"`
+	Dept      string    `gorm:"size:20;default:*" json:"dept"` // 策略的部门维度，*表示全部门
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (RoleAPIGroup) TableName() string {
+	return "role_api_groups"
+}
+
+// APIGroupPolicy 由API组编译生成并归组管理的角色策略，角色已直接拥有的策略不记录，撤销时不会被删除
+type APIGroupPolicy struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	RoleID uint   `gorm:"index" json:"role_id"`
+	Path   string `gorm:"size:200" json:"path"`
+	Method string `gorm:"size:10" json:"method"`
+	Dept   string `gorm:"size:20" json:"dept"`
+}
+
+// TableName 设置表名
+func (APIGroupPolicy) TableName() string {
+	return "api_group_policies"
+}
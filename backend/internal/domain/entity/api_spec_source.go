@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"time"
+)
+
+// 接口文档同步结果
+const (
+	APISyncStatusSuccess   = "success"   // 同步成功
+	APISyncStatusUnchanged = "unchanged" // 文档未变化，跳过
+	APISyncStatusFailed    = "failed"    // 读取或解析文档失败
+)
+
+// APISpecSource 业务线的接口文档来源，定期读取并与已登记的API同步
+type APISpecSource struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	BusinessID     uint       `gorm:"uniqueIndex" json:"business_id"` // 业务线ID，每个业务线一个来源
+	Location       string     `gorm:"size:500" json:"location"`       // 文件路径（相对于spec_dir）或http(s)地址
+	IgnoreBasePath bool       `json:"ignore_base_path"`               // 不拼接文档中的basePath或server路径
+	Enabled        bool       `gorm:"default:true" json:"enabled"`    // 是否参与定期同步
+	ContentHash    string     `gorm:"size:64" json:"content_hash"`    // 上次同步的文档SHA-256，未变化时跳过
+	LastSyncAt     *time.Time `json:"last_sync_at"`                   // 上次同步时间
+	LastStatus     string     `gorm:"size:20" json:"last_status"`     // 上次同步结果
+	LastError      string     `gorm:"size:500" json:"last_error"`     // 上次同步失败的原因
+	Report         string     `gorm:"type:mediumtext" json:"-"`       // 上次同步报告（JSON）
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (APISpecSource) TableName() string {
+	return "api_spec_sources"
+}
@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"time"
+)
+
+// 审计日志的操作结果
+const (
+	AuditResultSuccess = "success"
+	AuditResultFailure = "failure"
+)
+
+// AuditLog 管理操作审计日志，记录谁在什么时候对什么对象做了什么修改
+type AuditLog struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ActorID        uint      `gorm:"index" json:"actor_id"`
+	ActorUsername  string    `gorm:"size:50;index" json:"actor_username"`
+	ImpersonatorID uint      `gorm:"default:0" json:"impersonator_id,omitempty"` // 代登录时实际操作的管理员
+	Action         string    `gorm:"size:50;index" json:"action"`                // 操作，如user.create
+	TargetType     string    `gorm:"size:30;index:idx_audit_target" json:"target_type"`
+	TargetID       string    `gorm:"size:100;index:idx_audit_target" json:"target_id"`
+	DeptID         uint      `gorm:"index" json:"dept_id"` // 操作对象所属部门
+	Method         string    `gorm:"size:10" json:"method"`
+	Path           string    `gorm:"size:500" json:"path"`
+	Request        string    `gorm:"type:text" json:"request,omitempty"` // 请求体，敏感字段已脱敏
+	Before         string    `gorm:"type:text" json:"before,omitempty"`  // 修改前的对象JSON
+	After          string    `gorm:"type:text" json:"after,omitempty"`   // 修改后的对象JSON
+	Diff           string    `gorm:"type:text" json:"diff,omitempty"`    // 变更字段，格式为{"字段":{"before":..,"after":..}}
+	Result         string    `gorm:"size:10;index" json:"result"`
+	Status         int       `json:"status"`                  // HTTP状态码
+	Message        string    `gorm:"size:255" json:"message"` // 响应消息，失败时为错误原因
+	IP             string    `gorm:"size:64" json:"ip"`
+	RequestID      string    `gorm:"size:64;index" json:"request_id"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 设置表名
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
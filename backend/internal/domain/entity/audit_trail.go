@@ -0,0 +1,53 @@
+package entity
+
+import (
+	"time"
+)
+
+// AuditTrailEntry 防篡改审计链记录，每条记录保存上一条记录的哈希，只允许追加
+type AuditTrailEntry struct {
+	Seq           uint64 `gorm:"primaryKey;autoIncrement:false" json:"seq"` // 从1开始连续递增
+	AuditLogID    uint   `gorm:"index" json:"audit_log_id"`
+	Timestamp     int64  `json:"timestamp"` // 写入时间（Unix毫秒），参与哈希计算
+	ActorID       uint   `gorm:"index" json:"actor_id"`
+	ActorUsername string `gorm:"size:50" json:"actor_username"`
+	Action        string `gorm:"size:50" json:"action"`
+	TargetType    string `gorm:"size:30" json:"target_type"`
+	TargetID      string `gorm:"size:100" json:"target_id"`
+	Payload       string `gorm:"type:mediumtext" json:"payload"` // 审计日志JSON
+	PrevHash      string `gorm:"size:64" json:"prev_hash"`       // 上一条记录的哈希，第一条为空
+	Hash          string `gorm:"size:64;uniqueIndex" json:"hash"`
+}
+
+// TableName 设置表名
+func (AuditTrailEntry) TableName() string {
+	return "audit_trail"
+}
+
+// AuditCheckpoint 审计链签名检查点，对某一时刻链尾的序号和哈希签名，防止整条链被重写或截断
+type AuditCheckpoint struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Seq       uint64    `gorm:"index" json:"seq"`
+	Hash      string    `gorm:"size:64" json:"hash"`
+	Timestamp int64     `json:"timestamp"` // 签名时间（Unix毫秒），参与签名
+	KeyID     string    `gorm:"size:16" json:"key_id"`
+	Signature string    `gorm:"size:128" json:"signature"` // Ed25519签名，Base64编码
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (AuditCheckpoint) TableName() string {
+	return "audit_checkpoints"
+}
+
+// AuditSigningKey 检查点签名公钥，按密钥ID登记，轮换密钥后仍可校验历史检查点
+type AuditSigningKey struct {
+	KeyID     string    `gorm:"primaryKey;size:16" json:"key_id"` // 公钥SHA-256的前8字节（十六进制）
+	PublicKey string    `gorm:"size:64" json:"public_key"`        // Ed25519公钥，Base64编码
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (AuditSigningKey) TableName() string {
+	return "audit_signing_keys"
+}
@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"time"
+)
+
+// 授权决策的来源
+const (
+	DecisionSourceMiddleware      = "middleware"       // Casbin中间件
+	DecisionSourceCheckPermission = "check_permission" // 权限校验接口
+)
+
+// AuthzDecision 授权决策日志，记录每次访问控制判断的输入、结果和命中的策略
+type AuthzDecision struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Source      string    `gorm:"size:20" json:"source"`
+	UserID      uint      `gorm:"index" json:"user_id"`
+	Username    string    `gorm:"size:50" json:"username"`
+	Roles       string    `gorm:"size:255" json:"roles"` // 参与判断的角色，逗号分隔
+	DeptID      uint      `json:"dept_id"`
+	Path        string    `gorm:"size:500" json:"path"`
+	Method      string    `gorm:"size:10" json:"method"`
+	Allowed     bool      `gorm:"index" json:"allowed"`
+	MatchedRule string    `gorm:"size:255" json:"matched_rule"` // 命中的策略，如"admin, /api/v1/*, *, *, allow"
+	Reason      string    `gorm:"size:50" json:"reason"`        // 判断依据，见DecisionReason常量
+	LatencyUs   int64     `json:"latency_us"`                   // 判断耗时（微秒）
+	ClientID    string    `gorm:"size:64" json:"client_id,omitempty"`
+	RequestID   string    `gorm:"size:64;index" json:"request_id"`
+	IP          string    `gorm:"size:64" json:"ip"`
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 设置表名
+func (AuthzDecision) TableName() string {
+	return "authz_decisions"
+}
@@ -10,9 +10,12 @@ type Department struct {
 	Name      string    `gorm:"size:100" json:"name"`
 	Code      string    `gorm:"size:50;uniqueIndex" json:"code"`
 	ParentID  uint      `gorm:"index" json:"parent_id"` // 父部门ID，0表示顶级部门
+	Path      string    `gorm:"size:255;index" json:"path"` // 物化路径，由根部门到自身的ID组成，如/1/5/12/
 	Level     int       `gorm:"default:1" json:"level"`  // 层级，1表示集团，2表示部门，3表示子部门
 	Sort      int       `gorm:"default:0" json:"sort"`   // 排序
 	Status    int       `gorm:"default:1" json:"status"` // 1: 启用, 0: 禁用
+	Source    string    `gorm:"size:20;default:local" json:"source"` // 来源：local、ldap
+	ExternalID string   `gorm:"size:255;index" json:"external_id"`   // 外部目录中的唯一标识（如LDAP OU的DN）
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -39,4 +42,7 @@ type Business struct {
 // TableName 设置表名
 func (Business) TableName() string {
 	return "businesses"
-}
\ No newline at end of file
+}
+
+// SystemBusinessCode 系统业务线编码，服务自身的路由在启动时登记为该业务线下的API
+const SystemBusinessCode = "mcprapi-system"
\ No newline at end of file
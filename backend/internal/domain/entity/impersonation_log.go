@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"time"
+)
+
+// 代登录日志的操作类型
+const (
+	ImpersonationActionStart   = "start"   // 开始代登录
+	ImpersonationActionRequest = "request" // 代登录期间的请求
+	ImpersonationActionStop    = "stop"    // 结束代登录
+)
+
+// ImpersonationLog 管理员代登录日志，同时记录实际操作的管理员和被代登录的用户
+type ImpersonationLog struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	SessionID     string    `gorm:"size:64;index" json:"session_id"`
+	ActorID       uint      `gorm:"index" json:"actor_id"`
+	ActorUsername string    `gorm:"size:50" json:"actor_username"`
+	UserID        uint      `gorm:"index" json:"user_id"`
+	Username      string    `gorm:"size:50" json:"username"`
+	Action        string    `gorm:"size:20" json:"action"`
+	Method        string    `gorm:"size:10" json:"method"`
+	Path          string    `gorm:"size:500" json:"path"`
+	Status        int       `json:"status"`
+	Blocked       bool      `gorm:"default:false" json:"blocked"` // 被代登录限制拦截的请求
+	Reason        string    `gorm:"size:255" json:"reason"`       // 开始代登录时填写的原因
+	IP            string    `gorm:"size:64" json:"ip"`
+	CreatedAt     time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 设置表名
+func (ImpersonationLog) TableName() string {
+	return "impersonation_logs"
+}
@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"time"
+)
+
+// LoginAttempt 登录尝试记录
+type LoginAttempt struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index" json:"user_id"` // 用户不存在时为0
+	Username  string    `gorm:"size:50;index" json:"username"`
+	IP        string    `gorm:"size:64;index" json:"ip"`
+	UserAgent string    `gorm:"size:500" json:"user_agent"`
+	Success   bool      `json:"success"`
+	Reason    string    `gorm:"size:255" json:"reason"` // 失败原因
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 设置表名
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
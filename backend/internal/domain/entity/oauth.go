@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"time"
+)
+
+// OAuthClient OAuth2客户端实体
+type OAuthClient struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	ClientID      string    `gorm:"size:64;uniqueIndex" json:"client_id"`
+	ClientSecret  string    `gorm:"size:255" json:"-"` // 客户端密钥哈希
+	Name          string    `gorm:"size:100" json:"name"`
+	Description   string    `gorm:"size:200" json:"description"`
+	BusinessID    uint      `gorm:"index" json:"business_id"`       // 所属业务线ID
+	ServiceUserID uint      `gorm:"index" json:"service_user_id"`   // client_credentials模式使用的服务账号ID
+	RedirectURIs  string    `gorm:"size:1000" json:"redirect_uris"` // 回调地址，多个以空格分隔
+	Scopes        string    `gorm:"size:1000" json:"scopes"`        // 允许申请的scope，多个以空格分隔
+	GrantTypes    string    `gorm:"size:200" json:"grant_types"`    // 允许的授权类型，多个以空格分隔
+	Public        bool      `gorm:"default:false" json:"public"`    // 公共客户端（无密钥，仅支持PKCE）
+	Status        int       `gorm:"default:1" json:"status"`        // 1: 启用, 0: 禁用
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// OAuthConsent 用户对OAuth2客户端的授权记录
+type OAuthConsent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index:idx_oauth_consent,unique" json:"user_id"`
+	ClientID  string    `gorm:"size:64;index:idx_oauth_consent,unique" json:"client_id"`
+	Scopes    string    `gorm:"size:1000" json:"scopes"` // 已授权的scope，多个以空格分隔
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (OAuthConsent) TableName() string {
+	return "oauth_consents"
+}
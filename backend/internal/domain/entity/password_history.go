@@ -0,0 +1,18 @@
+package entity
+
+import (
+	"time"
+)
+
+// PasswordHistory 历史密码，用于禁止重复使用最近的密码
+type PasswordHistory struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Password  string    `gorm:"size:255" json:"-"` // 旧密码哈希
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (PasswordHistory) TableName() string {
+	return "password_histories"
+}
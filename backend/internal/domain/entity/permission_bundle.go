@@ -0,0 +1,77 @@
+package entity
+
+import (
+	"time"
+)
+
+// 权限模板规则类型
+const (
+	BundleRuleAPI      = "api"      // 指定的API
+	BundleRuleCategory = "category" // 分类下的所有API
+	BundleRuleBusiness = "business" // 业务线下的所有API
+	BundleRulePattern  = "pattern"  // 路径匹配keyMatch2模式的所有API
+)
+
+// PermissionBundle 权限模板，由API、分类、业务线或路径模式定义，可应用到角色；规则每次修改生成一个新版本
+type PermissionBundle struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:100" json:"name"`
+	Code        string    `gorm:"size:100;uniqueIndex" json:"code"`
+	Description string    `gorm:"size:200" json:"description"`
+	Version     int       `gorm:"default:1" json:"version"` // 当前版本
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (PermissionBundle) TableName() string {
+	return "permission_bundles"
+}
+
+// PermissionBundleVersion 权限模板的一个版本
+type PermissionBundleVersion struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	BundleID  uint      `gorm:"uniqueIndex:idx_bundle_version" json:"bundle_id"`
+	Version   int       `gorm:"uniqueIndex:idx_bundle_version" json:"version"`
+	Rules     string    `gorm:"type:text" json:"-"`        // 规则列表（JSON）
+	Comment   string    `gorm:"size:200" json:"comment"`   // 版本说明
+	CreatedBy string    `gorm:"size:50" json:"created_by"` // 创建人用户名
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (PermissionBundleVersion) TableName() string {
+	return "permission_bundle_versions"
+}
+
+// RolePermissionBundle 权限模板在角色上的应用记录
+type RolePermissionBundle struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	RoleID    uint      `gorm:"uniqueIndex:idx_role_bundle" json:"role_id"`
+	BundleID  uint      `gorm:"uniqueIndex:idx_role_bundle;index" json:"bundle_id"`
+	Version   int       `json:"version"`                       // 应用的模板版本
+	Dept      string    `gorm:"size:20;default:*" json:"dept"` // 策略的部门维度，*表示全部门
+	AppliedAt time.Time `json:"applied_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (RolePermissionBundle) TableName() string {
+	return "role_permission_bundles"
+}
+
+// PermissionBundlePolicy 由权限模板生成并归模板管理的角色策略，角色已拥有的策略不记录，撤销时不会被删除
+type PermissionBundlePolicy struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	RoleID   uint   `gorm:"index:idx_bundle_policy_role_bundle" json:"role_id"`
+	BundleID uint   `gorm:"index:idx_bundle_policy_role_bundle" json:"bundle_id"`
+	Path     string `gorm:"size:200" json:"path"`
+	Method   string `gorm:"size:10" json:"method"`
+	Dept     string `gorm:"size:20" json:"dept"`
+}
+
+// TableName 设置表名
+func (PermissionBundlePolicy) TableName() string {
+	return "permission_bundle_policies"
+}
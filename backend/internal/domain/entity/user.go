@@ -15,10 +15,21 @@ type User struct {
 	DeptID    uint      `gorm:"index" json:"dept_id"`
 	Status    int       `gorm:"default:1" json:"status"` // 1: 启用, 0: 禁用
 	TokenVersion int    `gorm:"default:1" json:"token_version"` // Token版本号，用于安全控制
+	Source    string    `gorm:"size:20;default:local" json:"source"` // 账号来源：local、ldap、scim
+	ExternalID string   `gorm:"size:255;index" json:"external_id"`   // 外部目录中的唯一标识（如LDAP DN）
+	PasswordChangedAt  *time.Time `json:"password_changed_at"`                    // 最近一次修改密码的时间，为空时按创建时间计算有效期
+	MustChangePassword bool       `gorm:"default:false" json:"must_change_password"` // 下次登录时必须修改密码
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// 用户和部门的数据来源
+const (
+	SourceLocal = "local"
+	SourceLDAP  = "ldap"
+	SourceSCIM  = "scim"
+)
+
 // TableName 设置表名
 func (User) TableName() string {
 	return "users"
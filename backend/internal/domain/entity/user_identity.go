@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"time"
+)
+
+// UserIdentity 用户外部身份绑定实体
+type UserIdentity struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"index" json:"user_id"`
+	Provider    string     `gorm:"size:50;index:idx_identity_subject,unique" json:"provider"`  // 登录提供方名称
+	Subject     string     `gorm:"size:255;index:idx_identity_subject,unique" json:"subject"` // 提供方中的用户唯一标识（sub）
+	Email       string     `gorm:"size:100" json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
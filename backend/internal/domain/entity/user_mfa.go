@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"time"
+)
+
+// UserMFA 用户多因素认证（TOTP）实体
+type UserMFA struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	UserID        uint       `gorm:"uniqueIndex" json:"user_id"`
+	Secret        string     `gorm:"size:255" json:"-"`            // 加密后的TOTP密钥
+	Enabled       bool       `gorm:"default:false" json:"enabled"` // 绑定后需验证一次验证码才启用
+	LastStep      int64      `json:"-"`                            // 最近一次使用的时间步，防止验证码重放
+	RecoveryCodes string     `gorm:"type:text" json:"-"`           // 恢复码哈希（JSON数组），使用后移除
+	EnabledAt     *time.Time `json:"enabled_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"mcprapi/backend/internal/domain/entity"
+)
+
+// APIGroupRepository API组仓库接口
+type APIGroupRepository interface {
+	// Create 创建API组
+	Create(group *entity.APIGroup) error
+
+	// Update 更新API组
+	Update(group *entity.APIGroup) error
+
+	// Delete 删除API组及其成员和角色授权
+	Delete(id uint) error
+
+	// GetByID 根据ID获取API组，不存在时返回nil
+	GetByID(id uint) (*entity.APIGroup, error)
+
+	// GetByCode 根据编码获取API组，不存在时返回nil
+	GetByCode(code string) (*entity.APIGroup, error)
+
+	// List 获取API组列表，businessID为0时返回全部
+	List(businessID uint) ([]*entity.APIGroup, error)
+
+	// ListAPIs 获取API组内的API
+	ListAPIs(groupID uint) ([]*entity.API, error)
+
+	// AddAPIs 向API组添加API，已在组内的忽略
+	AddAPIs(groupID uint, apiIDs []uint) error
+
+	// RemoveAPIs 从API组移除API
+	RemoveAPIs(groupID uint, apiIDs []uint) error
+
+	// ListGrantsByRole 获取角色被授予的API组
+	ListGrantsByRole(roleID uint) ([]*entity.RoleAPIGroup, error)
+
+	// ListGrantsByGroup 获取被授予API组的角色
+	ListGrantsByGroup(groupID uint) ([]*entity.RoleAPIGroup, error)
+
+	// SetRoleGrants 替换角色被授予的API组
+	SetRoleGrants(roleID uint, grants []*entity.RoleAPIGroup) error
+
+	// ListPolicies 获取由API组编译生成的角色策略
+	ListPolicies(roleID uint) ([]*entity.APIGroupPolicy, error)
+
+	// SetPolicies 替换由API组编译生成的角色策略记录
+	SetPolicies(roleID uint, policies []*entity.APIGroupPolicy) error
+}
@@ -37,6 +37,8 @@ type APIRepository interface {
 
 	// ListByBusiness 获取业务线下的API列表
 	ListByBusiness(businessID uint) ([]*entity.API, error)
+	// ListByLifecycle 获取处于指定生命周期的API列表
+	ListByLifecycle(lifecycles ...string) ([]*entity.API, error)
 
 	// ListCategories 获取API分类列表
 	ListCategories() ([]*entity.APICategory, error)
@@ -62,6 +64,15 @@ type APIRepository interface {
 	// GetAPIsByCategoryID 根据分类ID获取API列表
 	GetAPIsByCategoryID(categoryID uint) ([]*entity.API, error)
 
+	// CountByCategory 按分类ID统计API数量，未分类的API计入0
+	CountByCategory() (map[uint]int64, error)
+
+	// SortCategories 批量设置分类的父分类和排序，sorts为分类ID到排序值的映射
+	SortCategories(parentID uint, sorts map[uint]int) error
+
+	// DeleteCategoryAndReassign 删除分类，并将其API和子分类转移到目标分类，targetID为0时子分类成为顶级分类
+	DeleteCategoryAndReassign(id, targetID uint) error
+
 	// ListWithDepartmentFilter 根据部门过滤获取API列表
 	ListWithDepartmentFilter(page, pageSize int, query string, businessIDs []uint, category string) ([]*entity.API, int64, error)
 
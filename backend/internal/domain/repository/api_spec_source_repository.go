@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"mcprapi/backend/internal/domain/entity"
+)
+
+// APISpecSourceRepository 接口文档来源仓库接口
+type APISpecSourceRepository interface {
+	// Create 创建来源
+	Create(source *entity.APISpecSource) error
+
+	// Update 更新来源
+	Update(source *entity.APISpecSource) error
+
+	// Delete 删除来源
+	Delete(id uint) error
+
+	// GetByBusiness 获取业务线的来源，不存在时返回nil
+	GetByBusiness(businessID uint) (*entity.APISpecSource, error)
+
+	// List 获取所有来源
+	List() ([]*entity.APISpecSource, error)
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+)
+
+// AuditLogQuery 审计日志查询条件
+type AuditLogQuery struct {
+	ActorID       uint
+	ActorUsername string
+	Action        string
+	TargetType    string
+	TargetID      string
+	DeptID        uint
+	Result        string
+	RequestID     string
+	StartTime     *time.Time
+	EndTime       *time.Time
+	Page          int
+	PageSize      int
+}
+
+// AuditLogRepository 审计日志仓库接口
+type AuditLogRepository interface {
+	// Create 创建日志
+	Create(log *entity.AuditLog) error
+
+	// List 分页查询日志，按时间倒序
+	List(query *AuditLogQuery) ([]*entity.AuditLog, int64, error)
+}
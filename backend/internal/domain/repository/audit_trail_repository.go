@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"mcprapi/backend/internal/domain/entity"
+)
+
+// AuditTrailRepository 防篡改审计链仓库接口，只提供追加和查询，不提供修改和删除
+type AuditTrailRepository interface {
+	// Append 在事务中锁定链尾并追加记录，build根据链尾记录（空链时为nil）生成新记录
+	Append(build func(last *entity.AuditTrailEntry) (*entity.AuditTrailEntry, error)) (*entity.AuditTrailEntry, error)
+
+	// Last 获取链尾记录，空链时返回nil
+	Last() (*entity.AuditTrailEntry, error)
+
+	// ListAfter 按序号升序获取afterSeq之后的记录
+	ListAfter(afterSeq uint64, limit int) ([]*entity.AuditTrailEntry, error)
+
+	// CreateCheckpoint 创建检查点
+	CreateCheckpoint(checkpoint *entity.AuditCheckpoint) error
+
+	// LastCheckpoint 获取最近的检查点，没有时返回nil
+	LastCheckpoint() (*entity.AuditCheckpoint, error)
+
+	// ListCheckpoints 按序号升序获取所有检查点
+	ListCheckpoints() ([]*entity.AuditCheckpoint, error)
+
+	// SaveSigningKey 登记签名公钥，已登记时跳过
+	SaveSigningKey(key *entity.AuditSigningKey) error
+
+	// ListSigningKeys 获取所有登记的签名公钥
+	ListSigningKeys() ([]*entity.AuditSigningKey, error)
+
+	// EnsureAppendOnly 创建禁止修改和删除的数据库触发器
+	EnsureAppendOnly() error
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+)
+
+// AuthzDecisionQuery 授权决策日志查询条件
+type AuthzDecisionQuery struct {
+	UserID    uint
+	Path      string // 路径前缀
+	Method    string
+	Allowed   *bool
+	Source    string
+	RequestID string
+	StartTime *time.Time
+	EndTime   *time.Time
+	Page      int
+	PageSize  int
+}
+
+// AuthzDecisionRepository 授权决策日志仓库接口
+type AuthzDecisionRepository interface {
+	// CreateBatch 批量写入日志
+	CreateBatch(decisions []*entity.AuthzDecision) error
+
+	// List 分页查询日志，按时间倒序
+	List(query *AuthzDecisionQuery) ([]*entity.AuthzDecision, int64, error)
+
+	// DeleteBefore 删除指定时间之前的日志，返回删除的条数
+	DeleteBefore(before time.Time) (int64, error)
+}
@@ -0,0 +1,55 @@
+package repository
+
+// Endpoint API的路径和方法
+type Endpoint struct {
+	Path   string
+	Method string
+}
+
+// DeletePlan 级联删除计划，由服务层按各对象的级联和限制规则生成，仓库在一个事务中执行
+type DeletePlan struct {
+	DepartmentIDs []uint
+	BusinessIDs   []uint
+	RoleIDs       []uint // 同时删除角色的用户关联、API组和权限模板的授予记录及其策略记录
+	APIIDs        []uint // 同时删除API组成员
+
+	Endpoints []*Endpoint // 被删除API的路径和方法，删除API组和权限模板记录的对应策略
+	Depts     []string    // 被删除部门的策略维度，删除API组和权限模板在这些部门上的授予记录
+	RuleIDs   []uint      // 删除的Casbin策略
+
+	Orphans *OrphanRecords // 清理的孤立关联记录
+}
+
+// DeleteDependents 删除计划会连带删除的关联记录数量
+type DeleteDependents struct {
+	UserRoles      int64 // 用户角色关联
+	GroupMembers   int64 // API组成员
+	Grants         int64 // API组和权限模板在角色上的授予记录
+	LedgerPolicies int64 // API组和权限模板生成的策略记录
+}
+
+// OrphanRecords 引用的对象已不存在的孤立记录
+type OrphanRecords struct {
+	UserRoleIDs     []uint // 用户或角色不存在的用户角色关联
+	GroupMemberIDs  []uint // API组或API不存在的API组成员
+	RoleGroupIDs    []uint // 角色或API组不存在的API组授予记录
+	GroupPolicyIDs  []uint // 角色不存在的API组策略记录
+	RoleBundleIDs   []uint // 角色或权限模板不存在的权限模板应用记录
+	BundlePolicyIDs []uint // 角色或权限模板不存在的权限模板策略记录
+	APIIDs          []uint // 业务线不存在的API
+}
+
+// CascadeRepository 级联删除和孤立数据清理仓库接口
+type CascadeRepository interface {
+	// ListRules 获取所有Casbin策略
+	ListRules() ([]*CasbinRule, error)
+
+	// CountDependents 统计删除计划会连带删除的关联记录
+	CountDependents(plan *DeletePlan) (*DeleteDependents, error)
+
+	// Delete 在一个事务中执行删除计划
+	Delete(plan *DeletePlan) error
+
+	// FindOrphans 查找孤立记录
+	FindOrphans() (*OrphanRecords, error)
+}
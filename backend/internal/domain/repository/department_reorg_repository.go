@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"mcprapi/backend/internal/domain/entity"
+)
+
+// CasbinRule casbin_rule表中的一条策略，p策略为sub, obj, act, dept, eft，g策略为user, role
+type CasbinRule struct {
+	ID    uint   `gorm:"primaryKey;column:id" json:"id"`
+	PType string `gorm:"column:ptype" json:"ptype"`
+	V0    string `gorm:"column:v0" json:"v0"`
+	V1    string `gorm:"column:v1" json:"v1"`
+	V2    string `gorm:"column:v2" json:"v2"`
+	V3    string `gorm:"column:v3" json:"v3"`
+	V4    string `gorm:"column:v4" json:"v4"`
+	V5    string `gorm:"column:v5" json:"v5"`
+}
+
+// TableName 设置表名
+func (CasbinRule) TableName() string {
+	return "casbin_rule"
+}
+
+// DepartmentContents 部门直接拥有的对象
+type DepartmentContents struct {
+	Users      []*entity.User
+	Roles      []*entity.Role
+	Businesses []*entity.Business
+	Children   []*entity.Department
+	APICount   int64 // 部门业务线下的API数量
+}
+
+// DepartmentMergePlan 部门合并计划，由服务层生成，仓库在一个事务中执行
+type DepartmentMergePlan struct {
+	SourceID uint
+	TargetID uint
+
+	// 源部门管理员角色的处理：目标部门没有管理员角色时改名为目标部门的管理员角色，否则并入目标部门的管理员角色后删除
+	RenameRole      *entity.Role
+	MergeRoleID     uint
+	MergeIntoRoleID uint
+
+	UpdatedRules   []*CasbinRule // 改写后的策略
+	DeletedRuleIDs []uint        // 改写后与已有策略重复而删除的策略
+}
+
+// NewDepartmentPlaceholder 拆分计划中新部门ID的占位符，仓库创建部门后替换为实际ID
+const NewDepartmentPlaceholder = "{new}"
+
+// DepartmentSplitPlan 部门拆分计划，策略和角色编码中的新部门ID以NewDepartmentPlaceholder表示
+type DepartmentSplitPlan struct {
+	SourceID    uint
+	Department  *entity.Department // 新建的子部门
+	BusinessIDs []uint
+	UserIDs     []uint
+
+	// 转移的用户中有源部门管理员时创建新部门的管理员角色，这些用户的管理员角色从源部门转到新部门
+	AdminRole         *entity.Role
+	SourceAdminRoleID uint   // 源部门管理员角色，没有时为0
+	AdminUserIDs      []uint // 通过用户角色关联拥有源部门管理员角色的转移用户
+
+	AddedRules   []*CasbinRule // 转移的用户在新部门维度上的策略
+	UpdatedRules []*CasbinRule // 改写后的策略
+}
+
+// DepartmentReorgRepository 部门合并、拆分仓库接口
+type DepartmentReorgRepository interface {
+	// GetContents 获取部门直接拥有的用户、角色、业务线和下级部门
+	GetContents(deptID uint) (*DepartmentContents, error)
+
+	// ListRules 获取所有Casbin策略
+	ListRules() ([]*CasbinRule, error)
+
+	// ListUserRoles 获取用户通过用户角色关联拥有的角色，键为用户ID
+	ListUserRoles(userIDs []uint) (map[uint][]*entity.Role, error)
+
+	// Merge 在一个事务中执行合并：转移用户、角色、业务线和下级部门，改写策略和部门维度的授权记录，删除源部门
+	Merge(plan *DepartmentMergePlan) error
+
+	// Split 在一个事务中执行拆分：创建子部门，转移指定的业务线和用户，创建新部门的管理员角色并改写策略
+	Split(plan *DepartmentSplitPlan) error
+}
@@ -29,6 +29,21 @@ type DepartmentRepository interface {
 
 	// GetTotalCount 获取部门总数
 	GetTotalCount() (int64, error)
+
+	// ListPage 分页获取部门列表，query按名称或编码模糊匹配
+	ListPage(page, pageSize int, query string) ([]*entity.Department, int64, error)
+
+	// ListAncestors 获取部门的所有上级部门，由根部门到直接父部门排列
+	ListAncestors(id uint) ([]*entity.Department, error)
+
+	// ListDescendants 获取部门的所有下级部门，不包括部门自身
+	ListDescendants(id uint) ([]*entity.Department, error)
+
+	// Move 将部门连同其下级部门移动到新的父部门下，并重新计算路径和层级
+	Move(id, parentID uint) error
+
+	// RebuildPaths 按父部门关系重建所有部门的物化路径
+	RebuildPaths() error
 }
 
 // BusinessRepository 业务线仓库接口
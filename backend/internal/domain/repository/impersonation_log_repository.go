@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"mcprapi/backend/internal/domain/entity"
+)
+
+// ImpersonationLogQuery 代登录日志查询条件
+type ImpersonationLogQuery struct {
+	ActorID   uint
+	UserID    uint
+	SessionID string
+	Page      int
+	PageSize  int
+}
+
+// ImpersonationLogRepository 代登录日志仓库接口
+type ImpersonationLogRepository interface {
+	// Create 创建日志
+	Create(log *entity.ImpersonationLog) error
+
+	// List 分页查询日志，按时间倒序
+	List(query *ImpersonationLogQuery) ([]*entity.ImpersonationLog, int64, error)
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+)
+
+// LoginAttemptRepository 登录尝试记录仓库接口
+type LoginAttemptRepository interface {
+	// Create 创建登录尝试记录
+	Create(attempt *entity.LoginAttempt) error
+
+	// ListByUser 分页获取用户的登录尝试记录，按时间倒序
+	ListByUser(userID uint, page, pageSize int) ([]*entity.LoginAttempt, int64, error)
+
+	// DeleteBefore 删除指定时间之前的记录
+	DeleteBefore(t time.Time) (int64, error)
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"mcprapi/backend/internal/domain/entity"
+)
+
+// OAuthRepository OAuth2仓库接口
+type OAuthRepository interface {
+	// CreateClient 创建客户端
+	CreateClient(client *entity.OAuthClient) error
+
+	// UpdateClient 更新客户端
+	UpdateClient(client *entity.OAuthClient) error
+
+	// DeleteClient 删除客户端
+	DeleteClient(id uint) error
+
+	// GetClientByID 根据ID获取客户端
+	GetClientByID(id uint) (*entity.OAuthClient, error)
+
+	// GetClientByClientID 根据client_id获取客户端
+	GetClientByClientID(clientID string) (*entity.OAuthClient, error)
+
+	// ListClients 获取客户端列表，businessID为0时返回全部
+	ListClients(businessID uint) ([]*entity.OAuthClient, error)
+
+	// GetConsent 获取用户对客户端的授权记录
+	GetConsent(userID uint, clientID string) (*entity.OAuthConsent, error)
+
+	// SaveConsent 保存用户授权记录
+	SaveConsent(consent *entity.OAuthConsent) error
+
+	// DeleteConsentsByClient 删除客户端的所有授权记录
+	DeleteConsentsByClient(clientID string) error
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"mcprapi/backend/internal/domain/entity"
+)
+
+// PasswordHistoryRepository 历史密码仓库接口
+type PasswordHistoryRepository interface {
+	// Create 记录历史密码
+	Create(history *entity.PasswordHistory) error
+
+	// ListRecent 获取用户最近的历史密码，按时间倒序
+	ListRecent(userID uint, limit int) ([]*entity.PasswordHistory, error)
+
+	// Prune 只保留用户最近keep条历史密码
+	Prune(userID uint, keep int) error
+}
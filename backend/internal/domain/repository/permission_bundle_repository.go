@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"mcprapi/backend/internal/domain/entity"
+)
+
+// PermissionBundleRepository 权限模板仓库接口
+type PermissionBundleRepository interface {
+	// Create 创建模板及其第一个版本
+	Create(bundle *entity.PermissionBundle, version *entity.PermissionBundleVersion) error
+
+	// Update 更新模板，version不为nil时同时保存新版本
+	Update(bundle *entity.PermissionBundle, version *entity.PermissionBundleVersion) error
+
+	// Delete 删除模板及其所有版本
+	Delete(id uint) error
+
+	// GetByID 根据ID获取模板，不存在时返回nil
+	GetByID(id uint) (*entity.PermissionBundle, error)
+
+	// GetByCode 根据编码获取模板，不存在时返回nil
+	GetByCode(code string) (*entity.PermissionBundle, error)
+
+	// List 获取所有模板
+	List() ([]*entity.PermissionBundle, error)
+
+	// GetVersion 获取模板的指定版本，不存在时返回nil
+	GetVersion(bundleID uint, version int) (*entity.PermissionBundleVersion, error)
+
+	// ListVersions 获取模板的所有版本，新版本在前
+	ListVersions(bundleID uint) ([]*entity.PermissionBundleVersion, error)
+
+	// ListApplications 获取模板在各角色上的应用记录
+	ListApplications(bundleID uint) ([]*entity.RolePermissionBundle, error)
+
+	// ListApplicationsByRole 获取角色应用的模板
+	ListApplicationsByRole(roleID uint) ([]*entity.RolePermissionBundle, error)
+
+	// GetApplication 获取模板在角色上的应用记录，不存在时返回nil
+	GetApplication(bundleID, roleID uint) (*entity.RolePermissionBundle, error)
+
+	// SaveApplication 保存应用记录及模板为角色生成的策略记录
+	SaveApplication(application *entity.RolePermissionBundle, policies []*entity.PermissionBundlePolicy) error
+
+	// DeleteApplication 删除应用记录及模板为角色生成的策略记录
+	DeleteApplication(bundleID, roleID uint) error
+
+	// ListPolicies 获取模板为角色生成的策略记录
+	ListPolicies(bundleID, roleID uint) ([]*entity.PermissionBundlePolicy, error)
+
+	// ListPoliciesByRole 获取所有模板为角色生成的策略记录
+	ListPoliciesByRole(roleID uint) ([]*entity.PermissionBundlePolicy, error)
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"mcprapi/backend/internal/domain/entity"
+)
+
+// UserIdentityRepository 用户外部身份仓库接口
+type UserIdentityRepository interface {
+	// Create 创建身份绑定
+	Create(identity *entity.UserIdentity) error
+
+	// Update 更新身份绑定
+	Update(identity *entity.UserIdentity) error
+
+	// Delete 删除身份绑定
+	Delete(id uint) error
+
+	// GetByProviderSubject 根据提供方和外部用户标识获取身份绑定
+	GetByProviderSubject(provider, subject string) (*entity.UserIdentity, error)
+
+	// GetByUserAndProvider 获取用户在指定提供方的身份绑定
+	GetByUserAndProvider(userID uint, provider string) (*entity.UserIdentity, error)
+
+	// ListByUser 获取用户的所有身份绑定
+	ListByUser(userID uint) ([]*entity.UserIdentity, error)
+}
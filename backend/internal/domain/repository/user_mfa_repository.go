@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"mcprapi/backend/internal/domain/entity"
+)
+
+// UserMFARepository 用户多因素认证仓库接口
+type UserMFARepository interface {
+	// Save 创建或更新多因素认证配置
+	Save(mfa *entity.UserMFA) error
+
+	// GetByUserID 获取用户的多因素认证配置
+	GetByUserID(userID uint) (*entity.UserMFA, error)
+
+	// DeleteByUserID 删除用户的多因素认证配置
+	DeleteByUserID(userID uint) error
+}
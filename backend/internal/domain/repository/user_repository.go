@@ -21,6 +21,9 @@ type UserRepository interface {
 	// GetByUsername 根据用户名获取用户
 	GetByUsername(username string) (*entity.User, error)
 
+	// GetByEmail 根据邮箱获取用户
+	GetByEmail(email string) (*entity.User, error)
+
 	// List 获取用户列表
 	List(page, pageSize int, query string) ([]*entity.User, int64, error)
 
@@ -38,4 +41,7 @@ type UserRepository interface {
 
 	// AssignRoles 分配角色给用户
 	AssignRoles(userID uint, roleIDs []uint) error
+
+	// GetUsersByRole 获取拥有指定角色的用户
+	GetUsersByRole(roleID uint) ([]*entity.User, error)
 }
\ No newline at end of file
@@ -0,0 +1,357 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// 删除分类的方式
+const (
+	APICategoryDeleteBlock    = "block"    // 存在子分类或API时拒绝删除
+	APICategoryDeleteReassign = "reassign" // 将子分类和API转移到目标分类后删除
+)
+
+// APICategoryReferrer 引用分类的模块（如权限模板），删除分类前检查引用，分类树变化后重新计算
+type APICategoryReferrer interface {
+	// CategoryReferences 返回引用该分类的对象名称
+	CategoryReferences(categoryID uint) ([]string, error)
+	// CategoryTreeChanged 分类树结构变化后调用
+	CategoryTreeChanged()
+}
+
+// APICategoryNode 分类树节点
+type APICategoryNode struct {
+	*entity.APICategory
+	APICount      int64              `json:"api_count"`       // 直接属于该分类的API数量
+	TotalAPICount int64              `json:"total_api_count"` // 包括所有子分类在内的API数量
+	Children      []*APICategoryNode `json:"children"`
+}
+
+// MoveAPICategoryRequest 移动分类请求
+type MoveAPICategoryRequest struct {
+	ParentID uint `json:"parent_id"`      // 新的父分类ID，0表示顶级分类
+	Sort     *int `json:"sort,omitempty"` // 排序，不传时排在同级分类最后
+}
+
+// ReorderAPICategoriesRequest 调整同级分类顺序请求
+type ReorderAPICategoriesRequest struct {
+	ParentID uint   `json:"parent_id"`                    // 父分类ID，0表示顶级分类
+	IDs      []uint `json:"ids" binding:"required,min=1"` // 按新顺序排列的分类ID，不在父分类下的分类会被移入
+}
+
+// DeleteAPICategoryRequest 删除分类请求
+type DeleteAPICategoryRequest struct {
+	Mode     string `json:"mode" form:"mode"`           // block（默认）或reassign
+	TargetID uint   `json:"target_id" form:"target_id"` // reassign时的目标分类，0表示父分类
+}
+
+// GetAPICategoryTree 获取分类树，每个节点包含直接和子树内的API数量
+func (s *APIService) GetAPICategoryTree() ([]*APICategoryNode, error) {
+	categories, err := s.apiRepo.ListCategories()
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.apiRepo.CountByCategory()
+	if err != nil {
+		return nil, err
+	}
+	return buildAPICategoryTree(categories, counts), nil
+}
+
+// MoveAPICategory 将分类（连同其子分类）移动到新的父分类下
+func (s *APIService) MoveAPICategory(id uint, req *MoveAPICategoryRequest) (*entity.APICategory, error) {
+	categories, err := s.apiRepo.ListCategories()
+	if err != nil {
+		return nil, err
+	}
+	category := findAPICategory(categories, id)
+	if category == nil {
+		return nil, errors.New("分类不存在")
+	}
+	if err := checkAPICategoryParent(categories, id, req.ParentID); err != nil {
+		return nil, err
+	}
+
+	sortValue := 0
+	if req.Sort != nil {
+		sortValue = *req.Sort
+	} else {
+		for _, sibling := range categories {
+			if sibling.ParentID == req.ParentID && sibling.ID != id && sibling.Sort >= sortValue {
+				sortValue = sibling.Sort + 1
+			}
+		}
+	}
+
+	moved := category.ParentID != req.ParentID
+	if err := s.apiRepo.SortCategories(req.ParentID, map[uint]int{id: sortValue}); err != nil {
+		return nil, err
+	}
+	if moved {
+		s.categoryTreeChanged()
+	}
+	return s.apiRepo.GetCategoryByID(id)
+}
+
+// ReorderAPICategories 按给定顺序重新排列父分类下的子分类，未列出的原有子分类排在后面
+func (s *APIService) ReorderAPICategories(req *ReorderAPICategoriesRequest) ([]*APICategoryNode, error) {
+	categories, err := s.apiRepo.ListCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	sorts := make(map[uint]int, len(req.IDs))
+	moved := false
+	for _, id := range req.IDs {
+		if _, ok := sorts[id]; ok {
+			return nil, fmt.Errorf("分类重复: %d", id)
+		}
+		category := findAPICategory(categories, id)
+		if category == nil {
+			return nil, fmt.Errorf("分类不存在: %d", id)
+		}
+		if err := checkAPICategoryParent(categories, id, req.ParentID); err != nil {
+			return nil, fmt.Errorf("分类%s: %v", category.Name, err)
+		}
+		if category.ParentID != req.ParentID {
+			moved = true
+		}
+		sorts[id] = len(sorts)
+	}
+	for _, sibling := range sortedAPICategoryChildren(categories, req.ParentID) {
+		if _, ok := sorts[sibling.ID]; !ok {
+			sorts[sibling.ID] = len(sorts)
+		}
+	}
+
+	if err := s.apiRepo.SortCategories(req.ParentID, sorts); err != nil {
+		return nil, err
+	}
+	if moved {
+		s.categoryTreeChanged()
+	}
+	return s.GetAPICategoryTree()
+}
+
+// DeleteAPICategory 删除API分类；block方式在存在子分类或API时拒绝删除，reassign方式将它们转移到目标分类
+func (s *APIService) DeleteAPICategory(id uint, req *DeleteAPICategoryRequest) error {
+	categories, err := s.apiRepo.ListCategories()
+	if err != nil {
+		return err
+	}
+	category := findAPICategory(categories, id)
+	if category == nil {
+		return errors.New("分类不存在")
+	}
+
+	// 被引用的分类删除后规则将失效，需先修改引用方
+	if s.categoryReferrer != nil {
+		refs, err := s.categoryReferrer.CategoryReferences(id)
+		if err != nil {
+			return err
+		}
+		if len(refs) > 0 {
+			return fmt.Errorf("分类被以下权限模板引用，无法删除: %s", strings.Join(refs, ", "))
+		}
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = APICategoryDeleteBlock
+	}
+	switch mode {
+	case APICategoryDeleteBlock:
+		if len(sortedAPICategoryChildren(categories, id)) > 0 {
+			return errors.New("存在子分类，无法删除")
+		}
+		apis, err := s.apiRepo.GetAPIsByCategoryID(id)
+		if err != nil {
+			return err
+		}
+		if len(apis) > 0 {
+			return errors.New("存在使用该分类的API，无法删除")
+		}
+		return s.apiRepo.DeleteCategory(id)
+	case APICategoryDeleteReassign:
+		targetID := req.TargetID
+		if targetID == 0 {
+			targetID = category.ParentID
+		} else {
+			if findAPICategory(categories, targetID) == nil {
+				return errors.New("目标分类不存在")
+			}
+			if targetID == id || containsUint(apiCategoryDescendantIDs(categories, id), targetID) {
+				return errors.New("目标分类不能是被删除的分类或其子分类")
+			}
+		}
+		if err := s.apiRepo.DeleteCategoryAndReassign(id, targetID); err != nil {
+			return err
+		}
+		s.categoryTreeChanged()
+		return nil
+	default:
+		return fmt.Errorf("不支持的删除方式: %s", mode)
+	}
+}
+
+// categoryTreeChanged 通知引用方分类树已变化
+func (s *APIService) categoryTreeChanged() {
+	if s.categoryReferrer != nil {
+		s.categoryReferrer.CategoryTreeChanged()
+	}
+}
+
+// checkCategoryParent 检查分类的父分类存在且不会形成环，id为0表示新建分类
+func (s *APIService) checkCategoryParent(id, parentID uint) error {
+	if parentID == 0 {
+		return nil
+	}
+	categories, err := s.apiRepo.ListCategories()
+	if err != nil {
+		return err
+	}
+	return checkAPICategoryParent(categories, id, parentID)
+}
+
+// buildAPICategoryTree 构建分类树并汇总子树的API数量，父分类不存在的分类作为顶级分类
+func buildAPICategoryTree(categories []*entity.APICategory, counts map[uint]int64) []*APICategoryNode {
+	nodes := make(map[uint]*APICategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &APICategoryNode{
+			APICategory: category,
+			APICount:    counts[category.ID],
+			Children:    []*APICategoryNode{},
+		}
+	}
+
+	roots := make([]*APICategoryNode, 0)
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if parent, ok := nodes[category.ParentID]; ok && category.ParentID != category.ID {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	// 数据异常形成环时，环上的分类没有顶级祖先，不会出现在树中
+	var walk func(list []*APICategoryNode)
+	walk = func(list []*APICategoryNode) {
+		sortAPICategoryNodes(list)
+		for _, node := range list {
+			walk(node.Children)
+			node.TotalAPICount = node.APICount
+			for _, child := range node.Children {
+				node.TotalAPICount += child.TotalAPICount
+			}
+		}
+	}
+	walk(roots)
+	return roots
+}
+
+// checkAPICategoryParent 父分类必须存在，且不能是分类自身或其子分类
+func checkAPICategoryParent(categories []*entity.APICategory, id, parentID uint) error {
+	if parentID == 0 {
+		return nil
+	}
+	if findAPICategory(categories, parentID) == nil {
+		return errors.New("父分类不存在")
+	}
+	if id == 0 {
+		return nil
+	}
+	if parentID == id {
+		return errors.New("父分类不能是分类自身")
+	}
+	if containsUint(apiCategoryDescendantIDs(categories, id), parentID) {
+		return errors.New("父分类不能是该分类的子分类")
+	}
+	return nil
+}
+
+// apiCategoryDescendantIDs 返回分类的所有子孙分类ID，不包括分类自身
+func apiCategoryDescendantIDs(categories []*entity.APICategory, id uint) []uint {
+	children := make(map[uint][]uint, len(categories))
+	for _, category := range categories {
+		children[category.ParentID] = append(children[category.ParentID], category.ID)
+	}
+
+	var descendants []uint
+	visited := map[uint]bool{id: true}
+	queue := []uint{id}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, child := range children[current] {
+			if visited[child] {
+				continue
+			}
+			visited[child] = true
+			descendants = append(descendants, child)
+			queue = append(queue, child)
+		}
+	}
+	return descendants
+}
+
+// sortedAPICategoryChildren 返回父分类的直接子分类，按排序值和ID排列
+func sortedAPICategoryChildren(categories []*entity.APICategory, parentID uint) []*entity.APICategory {
+	var children []*entity.APICategory
+	for _, category := range categories {
+		if category.ParentID == parentID && category.ID != parentID {
+			children = append(children, category)
+		}
+	}
+	sort.SliceStable(children, func(i, j int) bool {
+		if children[i].Sort != children[j].Sort {
+			return children[i].Sort < children[j].Sort
+		}
+		return children[i].ID < children[j].ID
+	})
+	return children
+}
+
+// sortAPICategoryNodes 按排序值和ID排列分类树节点
+func sortAPICategoryNodes(nodes []*APICategoryNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Sort != nodes[j].Sort {
+			return nodes[i].Sort < nodes[j].Sort
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+}
+
+// findAPICategory 按ID查找分类
+func findAPICategory(categories []*entity.APICategory, id uint) *entity.APICategory {
+	for _, category := range categories {
+		if category.ID == id {
+			return category
+		}
+	}
+	return nil
+}
+
+// apiCategoryStats 按顶级分类汇总子树内的API数量，未分类的API单独统计
+func apiCategoryStats(roots []*APICategoryNode, counts map[uint]int64) []*repository.CategoryStatsItem {
+	stats := make([]*repository.CategoryStatsItem, 0, len(roots)+1)
+	var categorized int64
+	for _, root := range roots {
+		stats = append(stats, &repository.CategoryStatsItem{Name: root.Name, Value: root.TotalAPICount})
+		categorized += root.TotalAPICount
+	}
+
+	var total int64
+	for _, count := range counts {
+		total += count
+	}
+	if uncategorized := total - categorized; uncategorized > 0 {
+		stats = append(stats, &repository.CategoryStatsItem{Name: "未分类", Value: uncategorized})
+	}
+	return stats
+}
@@ -0,0 +1,441 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/pkg/casbinx"
+)
+
+// apiGroupCodePattern API组编码格式，如order.read
+var apiGroupCodePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._:-]*$`)
+
+// apiVersionPattern 路径中的版本段，如v1、v2
+var apiVersionPattern = regexp.MustCompile(`^v[0-9]+$`)
+
+// APIGroupService API组服务：按组管理同一资源的多个API，角色按组授权，组成员变化时自动重新编译角色的Casbin策略
+type APIGroupService struct {
+	groupRepo    repository.APIGroupRepository
+	apiRepo      repository.APIRepository
+	roleRepo     repository.RoleRepository
+	businessRepo repository.BusinessRepository
+	enforcer     *casbinx.Enforcer
+
+	// 编译会读写多个角色的策略，串行执行
+	mu sync.Mutex
+}
+
+// NewAPIGroupService 创建API组服务
+func NewAPIGroupService(groupRepo repository.APIGroupRepository, apiRepo repository.APIRepository, roleRepo repository.RoleRepository,
+	businessRepo repository.BusinessRepository, enforcer *casbinx.Enforcer) *APIGroupService {
+	return &APIGroupService{
+		groupRepo:    groupRepo,
+		apiRepo:      apiRepo,
+		roleRepo:     roleRepo,
+		businessRepo: businessRepo,
+		enforcer:     enforcer,
+	}
+}
+
+// CreateAPIGroupRequest 创建API组请求
+type CreateAPIGroupRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Code        string `json:"code" binding:"required,max=100"` // 组编码，如order.read
+	Description string `json:"description" binding:"max=200"`
+	BusinessID  uint   `json:"business_id"` // 所属业务线，0表示不限业务线
+	APIIDs      []uint `json:"api_ids"`     // 组内的API
+}
+
+// UpdateAPIGroupRequest 更新API组请求，编码和所属业务线不可修改
+type UpdateAPIGroupRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description" binding:"max=200"`
+}
+
+// APIGroupAPIsRequest 添加或移除API组成员请求
+type APIGroupAPIsRequest struct {
+	APIIDs []uint `json:"api_ids" binding:"required,min=1"`
+}
+
+// SetRoleAPIGroupsRequest 设置角色被授予的API组请求
+type SetRoleAPIGroupsRequest struct {
+	GroupIDs []uint `json:"group_ids"`
+	DeptID   uint   `json:"dept_id,omitempty"` // 策略的部门维度，0表示全部门
+}
+
+// APIGroupDetail API组详情
+type APIGroupDetail struct {
+	*entity.APIGroup
+	APIs  []*APIGroupAPI      `json:"apis"`
+	Roles []*APIGroupRoleInfo `json:"roles"`
+}
+
+// APIGroupAPI API组内的API
+type APIGroupAPI struct {
+	*entity.API
+	Version string `json:"version,omitempty"` // 路径中的版本段，如v1
+}
+
+// APIGroupRoleInfo 被授予API组的角色
+type APIGroupRoleInfo struct {
+	RoleID uint   `json:"role_id"`
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	Dept   string `json:"dept"`
+}
+
+// RoleAPIGroupInfo 角色被授予的API组
+type RoleAPIGroupInfo struct {
+	*entity.APIGroup
+	Dept string `json:"dept"`
+}
+
+// ListGroups 获取API组列表
+func (s *APIGroupService) ListGroups(businessID uint) ([]*entity.APIGroup, error) {
+	return s.groupRepo.List(businessID)
+}
+
+// GetGroup 获取API组详情，包括组内的API和被授予的角色
+func (s *APIGroupService) GetGroup(id uint) (*APIGroupDetail, error) {
+	group, err := s.getGroup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	apis, err := s.groupRepo.ListAPIs(id)
+	if err != nil {
+		return nil, err
+	}
+	detail := &APIGroupDetail{
+		APIGroup: group,
+		APIs:     make([]*APIGroupAPI, 0, len(apis)),
+		Roles:    []*APIGroupRoleInfo{},
+	}
+	for _, api := range apis {
+		detail.APIs = append(detail.APIs, &APIGroupAPI{API: api, Version: apiVersion(api.Path)})
+	}
+
+	grants, err := s.groupRepo.ListGrantsByGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, grant := range grants {
+		role, err := s.roleRepo.GetByID(grant.RoleID)
+		if err != nil || role == nil {
+			continue
+		}
+		detail.Roles = append(detail.Roles, &APIGroupRoleInfo{RoleID: role.ID, Code: role.Code, Name: role.Name, Dept: grant.Dept})
+	}
+	return detail, nil
+}
+
+// CreateGroup 创建API组
+func (s *APIGroupService) CreateGroup(req *CreateAPIGroupRequest) (*entity.APIGroup, error) {
+	code := strings.TrimSpace(req.Code)
+	if !apiGroupCodePattern.MatchString(code) {
+		return nil, errors.New("API组编码只能包含小写字母、数字和._:-，如order.read")
+	}
+	existing, err := s.groupRepo.GetByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("API组编码已存在")
+	}
+	if req.BusinessID > 0 {
+		business, err := s.businessRepo.GetByID(req.BusinessID)
+		if err != nil || business == nil {
+			return nil, errors.New("业务线不存在")
+		}
+	}
+
+	group := &entity.APIGroup{
+		Name:        req.Name,
+		Code:        code,
+		Description: req.Description,
+		BusinessID:  req.BusinessID,
+	}
+	apiIDs, err := s.checkAPIs(group, req.APIIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.groupRepo.Create(group); err != nil {
+		return nil, err
+	}
+	if err := s.groupRepo.AddAPIs(group.ID, apiIDs); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// UpdateGroup 更新API组
+func (s *APIGroupService) UpdateGroup(id uint, req *UpdateAPIGroupRequest) (*entity.APIGroup, error) {
+	group, err := s.getGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	group.Name = req.Name
+	group.Description = req.Description
+	if err := s.groupRepo.Update(group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// DeleteGroup 删除API组，并撤销已授予角色的组内策略
+func (s *APIGroupService) DeleteGroup(id uint) error {
+	if _, err := s.getGroup(id); err != nil {
+		return err
+	}
+	grants, err := s.groupRepo.ListGrantsByGroup(id)
+	if err != nil {
+		return err
+	}
+	if err := s.groupRepo.Delete(id); err != nil {
+		return err
+	}
+	return s.compileRoles(grants)
+}
+
+// AddAPIs 向API组添加API，并为被授予该组的角色生成策略
+func (s *APIGroupService) AddAPIs(id uint, req *APIGroupAPIsRequest) (*APIGroupDetail, error) {
+	group, err := s.getGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	apiIDs, err := s.checkAPIs(group, req.APIIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.groupRepo.AddAPIs(id, apiIDs); err != nil {
+		return nil, err
+	}
+	if err := s.compileGroup(id); err != nil {
+		return nil, err
+	}
+	return s.GetGroup(id)
+}
+
+// RemoveAPIs 从API组移除API，并撤销被授予该组的角色的相应策略
+func (s *APIGroupService) RemoveAPIs(id uint, req *APIGroupAPIsRequest) (*APIGroupDetail, error) {
+	if _, err := s.getGroup(id); err != nil {
+		return nil, err
+	}
+	if err := s.groupRepo.RemoveAPIs(id, req.APIIDs); err != nil {
+		return nil, err
+	}
+	if err := s.compileGroup(id); err != nil {
+		return nil, err
+	}
+	return s.GetGroup(id)
+}
+
+// GetRoleGroups 获取角色被授予的API组
+func (s *APIGroupService) GetRoleGroups(roleID uint) ([]*RoleAPIGroupInfo, error) {
+	grants, err := s.groupRepo.ListGrantsByRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]*RoleAPIGroupInfo, 0, len(grants))
+	for _, grant := range grants {
+		group, err := s.groupRepo.GetByID(grant.GroupID)
+		if err != nil {
+			return nil, err
+		}
+		if group != nil {
+			groups = append(groups, &RoleAPIGroupInfo{APIGroup: group, Dept: grant.Dept})
+		}
+	}
+	return groups, nil
+}
+
+// SetRoleGroups 替换角色被授予的API组，并重新编译角色的组内策略；角色直接拥有的策略不受影响
+func (s *APIGroupService) SetRoleGroups(roleID uint, req *SetRoleAPIGroupsRequest) ([]*RoleAPIGroupInfo, error) {
+	role, err := s.roleRepo.GetByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, errors.New("角色不存在")
+	}
+
+	dept := "*"
+	if req.DeptID > 0 {
+		dept = strconv.FormatUint(uint64(req.DeptID), 10)
+	}
+	grants := make([]*entity.RoleAPIGroup, 0, len(req.GroupIDs))
+	seen := make(map[uint]bool, len(req.GroupIDs))
+	for _, groupID := range req.GroupIDs {
+		if seen[groupID] {
+			continue
+		}
+		seen[groupID] = true
+		if _, err := s.getGroup(groupID); err != nil {
+			return nil, fmt.Errorf("API组%d: %v", groupID, err)
+		}
+		grants = append(grants, &entity.RoleAPIGroup{RoleID: roleID, GroupID: groupID, Dept: dept})
+	}
+
+	if err := s.groupRepo.SetRoleGrants(roleID, grants); err != nil {
+		return nil, err
+	}
+	if err := s.CompileRole(roleID); err != nil {
+		return nil, err
+	}
+	return s.GetRoleGroups(roleID)
+}
+
+// CompileRole 按角色被授予的API组重新生成其策略：补齐缺少的策略，删除不再属于任何组的组内策略
+func (s *APIGroupService) CompileRole(roleID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.compileRole(roleID); err != nil {
+		return err
+	}
+	// 同步策略到内存（安全方式）
+	return s.enforcer.SyncPolicyToMemory()
+}
+
+// compileGroup 重新编译被授予API组的所有角色
+func (s *APIGroupService) compileGroup(groupID uint) error {
+	grants, err := s.groupRepo.ListGrantsByGroup(groupID)
+	if err != nil {
+		return err
+	}
+	return s.compileRoles(grants)
+}
+
+// compileRoles 重新编译授权记录涉及的角色
+func (s *APIGroupService) compileRoles(grants []*entity.RoleAPIGroup) error {
+	if len(grants) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, grant := range grants {
+		if err := s.compileRole(grant.RoleID); err != nil {
+			return fmt.Errorf("编译角色%d的API组策略失败: %v", grant.RoleID, err)
+		}
+	}
+	return s.enforcer.SyncPolicyToMemory()
+}
+
+// compileRole 编译一个角色的组内策略，调用方持有锁
+func (s *APIGroupService) compileRole(roleID uint) error {
+	role, err := s.roleRepo.GetByID(roleID)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return nil
+	}
+
+	grants, err := s.groupRepo.ListGrantsByRole(roleID)
+	if err != nil {
+		return err
+	}
+	var desired []*entity.APIGroupPolicy
+	desiredKeys := make(map[string]bool)
+	for _, grant := range grants {
+		apis, err := s.groupRepo.ListAPIs(grant.GroupID)
+		if err != nil {
+			return err
+		}
+		for _, api := range apis {
+			policy := &entity.APIGroupPolicy{RoleID: roleID, Path: api.Path, Method: api.Method, Dept: grant.Dept}
+			if key := apiGroupPolicyKey(policy); !desiredKeys[key] {
+				desiredKeys[key] = true
+				desired = append(desired, policy)
+			}
+		}
+	}
+
+	previous, err := s.groupRepo.ListPolicies(roleID)
+	if err != nil {
+		return err
+	}
+	owned := make(map[string]bool, len(previous))
+	for _, policy := range previous {
+		owned[apiGroupPolicyKey(policy)] = true
+	}
+
+	// 只记录由组生成的策略，角色已直接拥有的策略撤销时保留
+	compiled := make([]*entity.APIGroupPolicy, 0, len(desired))
+	for _, policy := range desired {
+		added, err := s.enforcer.AddPolicyWithDept(role.Code, policy.Path, policy.Method, policy.Dept, "allow")
+		if err != nil {
+			return err
+		}
+		if added || owned[apiGroupPolicyKey(policy)] {
+			compiled = append(compiled, policy)
+		}
+	}
+	for _, policy := range previous {
+		if desiredKeys[apiGroupPolicyKey(policy)] {
+			continue
+		}
+		if _, err := s.enforcer.RemovePolicyWithDept(role.Code, policy.Path, policy.Method, policy.Dept, "allow"); err != nil {
+			return err
+		}
+	}
+
+	return s.groupRepo.SetPolicies(roleID, compiled)
+}
+
+// getGroup 获取API组，不存在时返回错误
+func (s *APIGroupService) getGroup(id uint) (*entity.APIGroup, error) {
+	group, err := s.groupRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, errors.New("API组不存在")
+	}
+	return group, nil
+}
+
+// checkAPIs 检查API存在，限定业务线的组只能包含该业务线的API，返回去重后的ID
+func (s *APIGroupService) checkAPIs(group *entity.APIGroup, apiIDs []uint) ([]uint, error) {
+	checked := make([]uint, 0, len(apiIDs))
+	for _, apiID := range apiIDs {
+		if containsUint(checked, apiID) {
+			continue
+		}
+		api, err := s.apiRepo.GetByID(apiID)
+		if err != nil {
+			return nil, err
+		}
+		if api == nil {
+			return nil, fmt.Errorf("API不存在: %d", apiID)
+		}
+		if group.BusinessID > 0 && api.BusinessID != group.BusinessID {
+			return nil, fmt.Errorf("API %s %s不属于该API组的业务线", api.Method, api.Path)
+		}
+		checked = append(checked, apiID)
+	}
+	return checked, nil
+}
+
+// apiGroupPolicyKey 组内策略的唯一键
+func apiGroupPolicyKey(policy *entity.APIGroupPolicy) string {
+	return policy.Method + " " + policy.Path + " " + policy.Dept
+}
+
+// apiVersion 路径中的版本段，如/order/v2/items返回v2，没有时返回空
+func apiVersion(path string) string {
+	for _, segment := range strings.Split(path, "/") {
+		if apiVersionPattern.MatchString(segment) {
+			return segment
+		}
+	}
+	return ""
+}
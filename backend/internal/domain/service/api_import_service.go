@@ -0,0 +1,547 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"regexp"
+	"strings"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// apiImportMethods 可以导入的HTTP方法，与创建API时允许的方法一致
+var apiImportMethods = map[string]bool{"GET": true, "POST": true, "PUT": true, "DELETE": true}
+
+// categoryCodeInvalid 分类代码中需要替换的字符
+var categoryCodeInvalid = regexp.MustCompile(`[^a-z0-9_\-]+`)
+
+// APIImportRequest 导入接口文档请求，Spec为OpenAPI 3或Swagger 2文档（JSON或YAML）
+type APIImportRequest struct {
+	BusinessID     uint   `json:"business_id" form:"business_id" binding:"required"`
+	CategoryID     uint   `json:"category_id" form:"category_id"` // 没有标签的操作使用的分类
+	Spec           string `json:"spec" form:"-"`
+	RoleIDs        []uint `json:"role_ids" form:"role_ids"`                 // 新建的API自动授权给这些角色
+	RemoveMissing  bool   `json:"remove_missing" form:"remove_missing"`     // 删除业务线下文档中已不存在的API
+	IgnoreBasePath bool   `json:"ignore_base_path" form:"ignore_base_path"` // 不拼接文档中的basePath或server路径
+}
+
+// APIImportItem 导入预览中的一个API
+type APIImportItem struct {
+	ID          uint     `json:"id,omitempty"`
+	Name        string   `json:"name"`
+	Path        string   `json:"path"`
+	Method      string   `json:"method"`
+	Description string   `json:"description,omitempty"`
+	Category    string   `json:"category,omitempty"` // 分类代码
+	OldPath     string   `json:"old_path,omitempty"` // 路径变更前的路径
+	Changes     []string `json:"changes,omitempty"`  // 变更的字段
+	Reason      string   `json:"reason,omitempty"`   // 冲突或跳过的原因
+}
+
+// APIImportResult 导入预览或导入结果
+type APIImportResult struct {
+	Format        string           `json:"format"`
+	Title         string           `json:"title"`
+	BasePath      string           `json:"base_path"`
+	DeptCode      string           `json:"dept_code"`
+	Created       []*APIImportItem `json:"created"`
+	Updated       []*APIImportItem `json:"updated"`
+	Renamed       []*APIImportItem `json:"renamed"`              // 名称和方法不变、路径变化的API，原地更新路径
+	Removed       []*APIImportItem `json:"removed"`              // 业务线下文档中已不存在的API，remove_missing时删除
+	Deprecated    []*APIImportItem `json:"deprecated,omitempty"` // 本次标记为废弃的API（同步时）
+	Conflicts     []*APIImportItem `json:"conflicts"`            // 路径和方法已被其他业务线登记
+	Skipped       []*APIImportItem `json:"skipped"`              // 不支持的方法或重复的操作
+	Unchanged     int              `json:"unchanged"`
+	NewCategories []string         `json:"new_categories"` // 需要新建的分类代码
+	Applied       bool             `json:"applied"`
+	GrantedRoles  []string         `json:"granted_roles,omitempty"`
+}
+
+// 文档中已不存在的API的处理方式
+const (
+	apiRemovalKeep      = "keep"      // 保留
+	apiRemovalDelete    = "delete"    // 删除
+	apiRemovalDeprecate = "deprecate" // 标记为废弃
+)
+
+// apiImportPlan 导入计划，预览和导入共用
+type apiImportPlan struct {
+	result     *APIImportResult
+	business   *entity.Business
+	roles      []*entity.Role
+	create     []*entity.API
+	createTags []string // 与create一一对应的分类代码，新分类创建后回填分类ID
+	update     []*entity.API
+	updateTags []string
+	remove     []*entity.API
+	categories map[string]*entity.APICategory // 分类代码到分类，新分类的ID为0
+}
+
+// APIImportService 从OpenAPI/Swagger文档批量登记API
+type APIImportService struct {
+	apiRepo      repository.APIRepository
+	businessRepo repository.BusinessRepository
+	deptRepo     repository.DepartmentRepository
+	roleRepo     repository.RoleRepository
+	roleService  *RoleService
+}
+
+// NewAPIImportService 创建接口文档导入服务
+func NewAPIImportService(apiRepo repository.APIRepository, businessRepo repository.BusinessRepository, deptRepo repository.DepartmentRepository,
+	roleRepo repository.RoleRepository, roleService *RoleService) *APIImportService {
+	return &APIImportService{
+		apiRepo:      apiRepo,
+		businessRepo: businessRepo,
+		deptRepo:     deptRepo,
+		roleRepo:     roleRepo,
+		roleService:  roleService,
+	}
+}
+
+// Preview 预览导入结果：新增、变更、删除的API，不做任何修改
+func (s *APIImportService) Preview(req *APIImportRequest) (*APIImportResult, error) {
+	plan, err := s.plan(req)
+	if err != nil {
+		return nil, err
+	}
+	return plan.result, nil
+}
+
+// Import 按预览结果导入：创建分类和API、更新变更的API，按需删除文档中已不存在的API并为新API授权
+func (s *APIImportService) Import(req *APIImportRequest) (*APIImportResult, error) {
+	plan, err := s.plan(req)
+	if err != nil {
+		return nil, err
+	}
+	removal := apiRemovalKeep
+	if req.RemoveMissing {
+		removal = apiRemovalDelete
+	}
+	return s.apply(plan, removal)
+}
+
+// apply 执行导入计划，removal为文档中已不存在的API的处理方式
+func (s *APIImportService) apply(plan *apiImportPlan, removal string) (*APIImportResult, error) {
+	result := plan.result
+
+	for _, code := range result.NewCategories {
+		category := plan.categories[code]
+		if err := s.apiRepo.CreateCategory(category); err != nil {
+			return nil, fmt.Errorf("创建分类%s失败: %v", code, err)
+		}
+	}
+
+	for i, api := range plan.create {
+		if code := plan.createTags[i]; code != "" {
+			api.CategoryID = plan.categories[code].ID
+		}
+		if err := s.apiRepo.Create(api); err != nil {
+			return nil, fmt.Errorf("创建API %s %s失败: %v", api.Method, api.Path, err)
+		}
+		result.Created[i].ID = api.ID
+	}
+
+	for i, api := range plan.update {
+		if code := plan.updateTags[i]; code != "" {
+			api.CategoryID = plan.categories[code].ID
+		}
+		if err := s.apiRepo.Update(api); err != nil {
+			return nil, fmt.Errorf("更新API %s %s失败: %v", api.Method, api.Path, err)
+		}
+	}
+
+	switch removal {
+	case apiRemovalDelete:
+		for _, api := range plan.remove {
+			if err := s.apiRepo.Delete(api.ID); err != nil {
+				return nil, fmt.Errorf("删除API %s %s失败: %v", api.Method, api.Path, err)
+			}
+		}
+	case apiRemovalDeprecate:
+		now := time.Now()
+		for i, api := range plan.remove {
+			if api.Lifecycle == entity.APILifecycleDeprecated || api.Lifecycle == entity.APILifecycleRetired {
+				continue
+			}
+			api.Lifecycle = entity.APILifecycleDeprecated
+			api.DeprecatedAt = &now
+			if err := s.apiRepo.Update(api); err != nil {
+				return nil, fmt.Errorf("标记API %s %s为废弃失败: %v", api.Method, api.Path, err)
+			}
+			result.Deprecated = append(result.Deprecated, result.Removed[i])
+		}
+	}
+
+	if len(plan.create) > 0 {
+		for _, role := range plan.roles {
+			if err := s.roleService.GrantAPIs(role.ID, plan.create); err != nil {
+				return nil, fmt.Errorf("为角色%s授权失败: %v", role.Code, err)
+			}
+			result.GrantedRoles = append(result.GrantedRoles, role.Code)
+		}
+	}
+
+	result.Applied = true
+	return result, nil
+}
+
+// plan 解析文档并与业务线下已登记的API比对
+func (s *APIImportService) plan(req *APIImportRequest) (*apiImportPlan, error) {
+	business, err := s.businessRepo.GetByID(req.BusinessID)
+	if err != nil {
+		return nil, err
+	}
+	if business == nil {
+		return nil, errors.New("业务线不存在")
+	}
+	if business.Code == entity.SystemBusinessCode {
+		return nil, errors.New("系统业务线的API由服务启动时自动登记")
+	}
+	dept, err := s.deptRepo.GetByID(business.DeptID)
+	if err != nil {
+		return nil, err
+	}
+	if dept == nil {
+		return nil, errors.New("业务线所属部门不存在")
+	}
+
+	if req.CategoryID > 0 {
+		category, err := s.apiRepo.GetCategoryByID(req.CategoryID)
+		if err != nil || category == nil {
+			return nil, errors.New("分类不存在")
+		}
+	}
+
+	plan := &apiImportPlan{
+		business:   business,
+		categories: make(map[string]*entity.APICategory),
+	}
+	for _, roleID := range req.RoleIDs {
+		role, err := s.roleRepo.GetByID(roleID)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return nil, fmt.Errorf("角色不存在: %d", roleID)
+		}
+		plan.roles = append(plan.roles, role)
+	}
+
+	spec, err := parseAPISpec([]byte(req.Spec))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &APIImportResult{
+		Format:        spec.Format,
+		Title:         spec.Title,
+		BasePath:      spec.BasePath,
+		DeptCode:      dept.Code,
+		Created:       []*APIImportItem{},
+		Updated:       []*APIImportItem{},
+		Renamed:       []*APIImportItem{},
+		Removed:       []*APIImportItem{},
+		Conflicts:     []*APIImportItem{},
+		Skipped:       []*APIImportItem{},
+		NewCategories: []string{},
+	}
+	plan.result = result
+
+	existing, err := s.apiRepo.ListByBusiness(business.ID)
+	if err != nil {
+		return nil, err
+	}
+	existingByKey := make(map[string]*entity.API, len(existing))
+	for _, api := range existing {
+		existingByKey[api.Method+" "+api.Path] = api
+	}
+
+	allCategories, err := s.apiRepo.ListCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(spec.Operations))
+	for _, op := range spec.Operations {
+		path := op.Path
+		if !req.IgnoreBasePath {
+			path = spec.BasePath + path
+		}
+		path = withDeptCodePrefix(dept.Code, path)
+
+		item := &APIImportItem{
+			Name:        truncateString(apiImportName(op), 100),
+			Path:        path,
+			Method:      op.Method,
+			Description: truncateString(apiImportDescription(op), 200),
+		}
+		key := item.Method + " " + item.Path
+
+		if !apiImportMethods[op.Method] {
+			item.Reason = "不支持的HTTP方法"
+			result.Skipped = append(result.Skipped, item)
+			continue
+		}
+		if len(item.Path) > 200 {
+			item.Reason = "路径超过200个字符"
+			result.Skipped = append(result.Skipped, item)
+			continue
+		}
+		if seen[key] {
+			item.Reason = "文档中重复的操作"
+			result.Skipped = append(result.Skipped, item)
+			continue
+		}
+		seen[key] = true
+
+		categoryCode, categoryID := s.resolveCategory(plan, allCategories, op.Tag, req.CategoryID)
+		item.Category = categoryCode
+
+		api := existingByKey[key]
+		if api == nil {
+			// 路径和方法全局唯一，已被其他业务线登记时不能导入
+			other, err := s.apiRepo.GetByPath(item.Path, item.Method)
+			if err != nil {
+				return nil, err
+			}
+			if other != nil {
+				item.ID = other.ID
+				item.Reason = fmt.Sprintf("已登记在业务线%d下", other.BusinessID)
+				result.Conflicts = append(result.Conflicts, item)
+				continue
+			}
+
+			plan.create = append(plan.create, &entity.API{
+				Name:        item.Name,
+				Path:        item.Path,
+				Method:      item.Method,
+				Description: item.Description,
+				BusinessID:  business.ID,
+				CategoryID:  categoryID,
+				Status:      1,
+				Lifecycle:   entity.APILifecycleActive,
+			})
+			plan.createTags = append(plan.createTags, newCategoryCode(plan, categoryCode))
+			result.Created = append(result.Created, item)
+			continue
+		}
+
+		item.ID = api.ID
+		if api.Name != item.Name {
+			item.Changes = append(item.Changes, "name")
+		}
+		if api.Description != item.Description {
+			item.Changes = append(item.Changes, "description")
+		}
+		if categoryCode != "" && (categoryID == 0 || api.CategoryID != categoryID) {
+			item.Changes = append(item.Changes, "category")
+		}
+		// 已废弃的API重新出现在文档中时恢复，手动下线的API保持下线
+		if api.Lifecycle == entity.APILifecycleDeprecated {
+			item.Changes = append(item.Changes, "lifecycle")
+		}
+		if len(item.Changes) == 0 {
+			result.Unchanged++
+			continue
+		}
+
+		updated := *api
+		updated.Name = item.Name
+		updated.Description = item.Description
+		if categoryCode != "" {
+			updated.CategoryID = categoryID
+		}
+		reactivateAPI(&updated)
+		plan.update = append(plan.update, &updated)
+		plan.updateTags = append(plan.updateTags, newCategoryCode(plan, categoryCode))
+		result.Updated = append(result.Updated, item)
+	}
+
+	var missing []*entity.API
+	for _, api := range existing {
+		if !seen[api.Method+" "+api.Path] {
+			missing = append(missing, api)
+		}
+	}
+	missing = s.matchRenamed(plan, missing)
+
+	for _, api := range missing {
+		plan.remove = append(plan.remove, api)
+		result.Removed = append(result.Removed, &APIImportItem{
+			ID:          api.ID,
+			Name:        api.Name,
+			Path:        api.Path,
+			Method:      api.Method,
+			Description: api.Description,
+		})
+	}
+
+	return plan, nil
+}
+
+// matchRenamed 将文档中已不存在的API与新增的操作按方法和名称一一匹配，匹配成功视为路径变更，原地更新API以保留其ID；返回未匹配的API
+func (s *APIImportService) matchRenamed(plan *apiImportPlan, missing []*entity.API) []*entity.API {
+	if len(missing) == 0 || len(plan.create) == 0 {
+		return missing
+	}
+
+	// 方法和名称相同的候选只有一个时才认为是同一个操作
+	countKey := func(method, name string) string { return method + " " + name }
+	missingCount := make(map[string]int, len(missing))
+	for _, api := range missing {
+		missingCount[countKey(api.Method, api.Name)]++
+	}
+	createCount := make(map[string]int, len(plan.create))
+	for _, api := range plan.create {
+		createCount[countKey(api.Method, api.Name)]++
+	}
+
+	result := plan.result
+	renamed := make(map[uint]bool)
+	create := plan.create[:0]
+	createTags := plan.createTags[:0]
+	created := result.Created[:0]
+	for i, api := range plan.create {
+		key := countKey(api.Method, api.Name)
+		var old *entity.API
+		if missingCount[key] == 1 && createCount[key] == 1 {
+			for _, candidate := range missing {
+				if countKey(candidate.Method, candidate.Name) == key {
+					old = candidate
+					break
+				}
+			}
+		}
+		if old == nil {
+			create = append(create, api)
+			createTags = append(createTags, plan.createTags[i])
+			created = append(created, result.Created[i])
+			continue
+		}
+
+		item := result.Created[i]
+		item.ID = old.ID
+		item.OldPath = old.Path
+		item.Changes = []string{"path"}
+		if old.Description != api.Description {
+			item.Changes = append(item.Changes, "description")
+		}
+		if api.CategoryID != old.CategoryID || plan.createTags[i] != "" {
+			item.Changes = append(item.Changes, "category")
+		}
+
+		updated := *old
+		updated.Path = api.Path
+		updated.Description = api.Description
+		updated.CategoryID = api.CategoryID
+		reactivateAPI(&updated)
+		plan.update = append(plan.update, &updated)
+		plan.updateTags = append(plan.updateTags, plan.createTags[i])
+		result.Renamed = append(result.Renamed, item)
+		renamed[old.ID] = true
+	}
+	plan.create = create
+	plan.createTags = createTags
+	result.Created = created
+
+	remaining := missing[:0]
+	for _, api := range missing {
+		if !renamed[api.ID] {
+			remaining = append(remaining, api)
+		}
+	}
+	return remaining
+}
+
+// resolveCategory 按标签匹配分类（代码或名称相同），没有匹配时计划新建；没有标签时使用默认分类
+func (s *APIImportService) resolveCategory(plan *apiImportPlan, categories []*entity.APICategory, tag string, defaultID uint) (string, uint) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		if defaultID == 0 {
+			return "", 0
+		}
+		for _, category := range categories {
+			if category.ID == defaultID {
+				return category.Code, category.ID
+			}
+		}
+		return "", defaultID
+	}
+
+	for _, category := range categories {
+		if strings.EqualFold(category.Code, tag) || category.Name == tag {
+			return category.Code, category.ID
+		}
+	}
+
+	code := categoryCodeInvalid.ReplaceAllString(strings.ToLower(tag), "-")
+	code = truncateString(strings.Trim(code, "-"), 50)
+	if code == "" {
+		// 标签全部由非ASCII字符组成时按名称生成代码
+		code = fmt.Sprintf("tag-%08x", crc32.ChecksumIEEE([]byte(tag)))
+	}
+	for _, category := range categories {
+		if category.Code == code {
+			return category.Code, category.ID
+		}
+	}
+
+	if _, ok := plan.categories[code]; !ok {
+		plan.categories[code] = &entity.APICategory{
+			Name: truncateString(tag, 50),
+			Code: code,
+		}
+		plan.result.NewCategories = append(plan.result.NewCategories, code)
+	}
+	return code, 0
+}
+
+// newCategoryCode 分类需要新建时返回分类代码，用于导入时回填分类ID
+func newCategoryCode(plan *apiImportPlan, code string) string {
+	if category, ok := plan.categories[code]; ok && category.ID == 0 {
+		return code
+	}
+	return ""
+}
+
+// apiImportName API名称：优先使用summary，其次operationId，最后为方法和路径
+func apiImportName(op *apiSpecOperation) string {
+	switch {
+	case op.Summary != "":
+		return op.Summary
+	case op.OperationID != "":
+		return op.OperationID
+	default:
+		return op.Method + " " + op.Path
+	}
+}
+
+// apiImportDescription API描述：优先使用description，其次summary，已废弃的操作加上标记
+func apiImportDescription(op *apiSpecOperation) string {
+	description := op.Description
+	if description == "" {
+		description = op.Summary
+	}
+	// 多行描述只保留第一行
+	if i := strings.IndexAny(description, "\r\n"); i >= 0 {
+		description = strings.TrimSpace(description[:i])
+	}
+	if op.Deprecated {
+		description = strings.TrimSpace("[已废弃] " + description)
+	}
+	return description
+}
+
+// reactivateAPI 已废弃的API重新出现在文档中时恢复为正常，草稿和已下线的API保持不变
+func reactivateAPI(api *entity.API) {
+	if api.Lifecycle != entity.APILifecycleDeprecated {
+		return
+	}
+	api.Lifecycle = entity.APILifecycleActive
+	api.DeprecatedAt = nil
+	api.SunsetAt = nil
+	api.SunsetNotice = 0
+}
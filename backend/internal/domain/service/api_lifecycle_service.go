@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/internal/pkg/logger"
+)
+
+// APILifecycleConfig API生命周期配置
+type APILifecycleConfig struct {
+	CheckInterval time.Duration   `mapstructure:"check_interval"` // 检查到期下线和发送提醒的间隔
+	NoticeBefore  []time.Duration `mapstructure:"notice_before"`  // 距下线时间多久时提醒业务线负责人，如720h、168h、24h
+	CacheTTL      time.Duration   `mapstructure:"cache_ttl"`      // API索引的缓存时间
+}
+
+// apiLifecycleIndex 权限校验使用的API索引，精确路径按方法和路径查找，带参数或通配符的路径按顺序匹配
+type apiLifecycleIndex struct {
+	exact     map[string]*entity.API
+	wildcards []*entity.API
+}
+
+// apiLifecycleTransitions 允许的生命周期变更，草稿只能在创建时指定
+var apiLifecycleTransitions = map[string][]string{
+	entity.APILifecycleDraft:      {entity.APILifecycleActive, entity.APILifecycleRetired},
+	entity.APILifecycleActive:     {entity.APILifecycleDeprecated, entity.APILifecycleRetired},
+	entity.APILifecycleDeprecated: {entity.APILifecycleActive, entity.APILifecycleDeprecated, entity.APILifecycleRetired},
+	entity.APILifecycleRetired:    {entity.APILifecycleActive, entity.APILifecycleDeprecated},
+}
+
+// SetAPILifecycleRequest 变更API生命周期请求
+type SetAPILifecycleRequest struct {
+	Lifecycle string     `json:"lifecycle" binding:"required,oneof=active deprecated retired"`
+	SunsetAt  *time.Time `json:"sunset_at"` // 废弃API的下线时间，到期后自动下线；为空时不自动下线
+}
+
+// APILifecycleService API生命周期服务：变更生命周期、为权限校验提供API索引、
+// 到期自动下线并提醒业务线负责人
+type APILifecycleService struct {
+	cfg          APILifecycleConfig
+	apiRepo      repository.APIRepository
+	businessRepo repository.BusinessRepository
+	notifier     APISunsetNotifier
+	logger       *logger.Logger
+
+	mu       sync.RWMutex
+	index    *apiLifecycleIndex
+	loadedAt time.Time
+}
+
+// NewAPILifecycleService 创建API生命周期服务
+func NewAPILifecycleService(cfg APILifecycleConfig, apiRepo repository.APIRepository, businessRepo repository.BusinessRepository,
+	notifier APISunsetNotifier, logger *logger.Logger) *APILifecycleService {
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = 30 * time.Second
+	}
+	if cfg.NoticeBefore == nil {
+		cfg.NoticeBefore = []time.Duration{720 * time.Hour, 168 * time.Hour, 24 * time.Hour}
+	}
+	sort.Slice(cfg.NoticeBefore, func(i, j int) bool {
+		return cfg.NoticeBefore[i] > cfg.NoticeBefore[j]
+	})
+
+	return &APILifecycleService{
+		cfg:          cfg,
+		apiRepo:      apiRepo,
+		businessRepo: businessRepo,
+		notifier:     notifier,
+		logger:       logger,
+	}
+}
+
+// SetLifecycle 变更API生命周期：废弃时可指定下线时间，恢复为active时清除废弃和下线时间
+func (s *APILifecycleService) SetLifecycle(id uint, req *SetAPILifecycleRequest) (*entity.API, error) {
+	api, err := s.apiRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if api == nil {
+		return nil, errors.New("API不存在")
+	}
+	business, err := s.businessRepo.GetByID(api.BusinessID)
+	if err == nil && business != nil && business.Code == entity.SystemBusinessCode {
+		return nil, errors.New("系统API的生命周期由服务启动时自动维护")
+	}
+
+	now := time.Now()
+	current := apiEffectiveLifecycle(api, now)
+	if current == req.Lifecycle && current != entity.APILifecycleDeprecated {
+		return api, nil
+	}
+	if !containsString(apiLifecycleTransitions[current], req.Lifecycle) {
+		return nil, fmt.Errorf("API不能从%s变更为%s", current, req.Lifecycle)
+	}
+
+	switch req.Lifecycle {
+	case entity.APILifecycleActive:
+		api.DeprecatedAt = nil
+		api.SunsetAt = nil
+		api.SunsetNotice = 0
+	case entity.APILifecycleDeprecated:
+		if req.SunsetAt != nil && !req.SunsetAt.After(now) {
+			return nil, errors.New("下线时间必须晚于当前时间")
+		}
+		if current != entity.APILifecycleDeprecated || api.DeprecatedAt == nil {
+			api.DeprecatedAt = &now
+		}
+		if !sameTime(api.SunsetAt, req.SunsetAt) {
+			api.SunsetNotice = 0
+		}
+		api.SunsetAt = req.SunsetAt
+	case entity.APILifecycleRetired:
+		if api.SunsetAt == nil || api.SunsetAt.After(now) {
+			api.SunsetAt = &now
+		}
+	}
+	api.Lifecycle = req.Lifecycle
+
+	if err := s.apiRepo.Update(api); err != nil {
+		return nil, err
+	}
+	s.Invalidate()
+	return api, nil
+}
+
+// Lookup 查找请求路径和方法对应的废弃、下线、草稿或禁用的API，返回的副本中Lifecycle为生效的生命周期；
+// 正常启用的API和未登记的路径返回nil
+func (s *APILifecycleService) Lookup(path, method string) *entity.API {
+	index := s.loadIndex()
+	if index == nil {
+		return nil
+	}
+
+	// 精确登记的API优先于通配路径，避免/users/:id下线影响/users/me
+	matched := index.exact[apiIndexKey(method, path)]
+	if matched == nil {
+		for _, api := range index.wildcards {
+			if matchRestrictedAPI(api.Path, api.Method, path, method) {
+				matched = api
+				break
+			}
+		}
+	}
+	if matched == nil {
+		return nil
+	}
+
+	lifecycle := apiEffectiveLifecycle(matched, time.Now())
+	if lifecycle == entity.APILifecycleActive && matched.Status == 1 {
+		return nil
+	}
+	api := *matched
+	api.Lifecycle = lifecycle
+	return &api
+}
+
+// APIDenyReason Lookup返回的API拒绝访问时的决策原因和提示：已下线、已禁用或尚未发布的草稿；可以访问时返回空字符串
+func APIDenyReason(api *entity.API) (reason, message string) {
+	switch {
+	case api == nil:
+		return "", ""
+	case api.Lifecycle == entity.APILifecycleRetired:
+		return DecisionReasonAPIRetired, "该API已下线"
+	case api.Status != 1:
+		return DecisionReasonAPIUnavailable, "该API已禁用"
+	case api.Lifecycle == entity.APILifecycleDraft:
+		return DecisionReasonAPIUnavailable, "该API尚未发布"
+	}
+	return "", ""
+}
+
+// Invalidate 清除索引缓存，下次查找时重新加载
+func (s *APILifecycleService) Invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+// loadIndex 返回所有API的索引，缓存过期时重新加载，加载失败时沿用旧索引
+func (s *APILifecycleService) loadIndex() *apiLifecycleIndex {
+	s.mu.RLock()
+	index, loadedAt := s.index, s.loadedAt
+	s.mu.RUnlock()
+	if time.Since(loadedAt) < s.cfg.CacheTTL {
+		return index
+	}
+
+	apis, err := s.apiRepo.ListAll()
+	if err != nil {
+		s.logger.Error("加载API索引失败: %v", err)
+		return index
+	}
+	index = &apiLifecycleIndex{exact: make(map[string]*entity.API, len(apis))}
+	for _, api := range apis {
+		if api.Method == "*" || strings.ContainsAny(api.Path, ":*") {
+			index.wildcards = append(index.wildcards, api)
+			continue
+		}
+		index.exact[apiIndexKey(api.Method, api.Path)] = api
+	}
+
+	s.mu.Lock()
+	s.index, s.loadedAt = index, time.Now()
+	s.mu.Unlock()
+	return index
+}
+
+// apiIndexKey API索引的键，方法不区分大小写
+func apiIndexKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// Start 启动后台任务：定期将到期的废弃API下线，并在下线前按配置提醒业务线负责人
+func (s *APILifecycleService) Start(ctx context.Context) {
+	if s.cfg.CheckInterval <= 0 {
+		return
+	}
+	go func() {
+		s.check()
+
+		ticker := time.NewTicker(s.cfg.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.check()
+			}
+		}
+	}()
+}
+
+// check 下线到期的废弃API，并汇总发送下线提醒
+func (s *APILifecycleService) check() {
+	apis, err := s.apiRepo.ListByLifecycle(entity.APILifecycleDeprecated)
+	if err != nil {
+		s.logger.Error("获取废弃API失败: %v", err)
+		return
+	}
+
+	now := time.Now()
+	retired := 0
+	due := make(map[uint][]*entity.API)
+	for _, api := range apis {
+		if api.SunsetAt == nil {
+			continue
+		}
+		if !api.SunsetAt.After(now) {
+			api.Lifecycle = entity.APILifecycleRetired
+			if err := s.apiRepo.Update(api); err != nil {
+				s.logger.Error("下线API失败: %s %s: %v", api.Method, api.Path, err)
+				continue
+			}
+			retired++
+			continue
+		}
+		if stage := s.noticeStage(api.SunsetAt.Sub(now)); stage > api.SunsetNotice {
+			api.SunsetNotice = stage
+			due[api.BusinessID] = append(due[api.BusinessID], api)
+		}
+	}
+	if retired > 0 {
+		s.logger.Info("已下线到期的废弃API %d个", retired)
+		s.Invalidate()
+	}
+
+	for businessID, apis := range due {
+		if err := s.notify(businessID, apis); err != nil {
+			s.logger.Error("发送API下线提醒失败: business=%d: %v", businessID, err)
+			continue
+		}
+		// 发送成功后再记录提醒次数，失败时下次检查重试
+		for _, api := range apis {
+			if err := s.apiRepo.Update(api); err != nil {
+				s.logger.Error("记录API下线提醒失败: %s %s: %v", api.Method, api.Path, err)
+			}
+		}
+	}
+}
+
+// noticeStage 距下线remaining时应已发送的提醒次数，跳过的阶段不再补发
+func (s *APILifecycleService) noticeStage(remaining time.Duration) int {
+	stage := 0
+	for _, before := range s.cfg.NoticeBefore {
+		if remaining <= before {
+			stage++
+		}
+	}
+	return stage
+}
+
+// notify 向业务线负责人发送即将下线的API列表
+func (s *APILifecycleService) notify(businessID uint, apis []*entity.API) error {
+	business, err := s.businessRepo.GetByID(businessID)
+	if err != nil {
+		return err
+	}
+	if business == nil {
+		return errors.New("业务线不存在")
+	}
+
+	notice := &APISunsetNotice{
+		BusinessID:   business.ID,
+		BusinessName: business.Name,
+		Owner:        business.Owner,
+		Email:        business.Email,
+		APIs:         make([]*APISunsetNoticeAPI, 0, len(apis)),
+	}
+	for _, api := range apis {
+		notice.APIs = append(notice.APIs, &APISunsetNoticeAPI{
+			ID:       api.ID,
+			Name:     api.Name,
+			Path:     api.Path,
+			Method:   api.Method,
+			SunsetAt: *api.SunsetAt,
+		})
+	}
+	return s.notifier.NotifySunset(notice)
+}
+
+// apiEffectiveLifecycle API在now时生效的生命周期，已过下线时间的废弃API视为已下线
+func apiEffectiveLifecycle(api *entity.API, now time.Time) string {
+	if api.Lifecycle == entity.APILifecycleDeprecated && api.SunsetAt != nil && !api.SunsetAt.After(now) {
+		return entity.APILifecycleRetired
+	}
+	if api.Lifecycle == "" {
+		return entity.APILifecycleActive
+	}
+	return api.Lifecycle
+}
+
+// sameTime 两个可为空的时间是否相同
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
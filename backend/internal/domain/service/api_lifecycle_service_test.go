@@ -0,0 +1,54 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+)
+
+func TestAPILifecycleLookup(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	apis := &fakeAPIRepo{byBusiness: map[uint][]*entity.API{1: {
+		{ID: 1, Path: "/biz/users/:id", Method: "GET", Status: 1, Lifecycle: entity.APILifecycleRetired},
+		{ID: 2, Path: "/biz/users/me", Method: "GET", Status: 1, Lifecycle: entity.APILifecycleActive},
+		{ID: 3, Path: "/biz/orders", Method: "GET", Status: 0, Lifecycle: entity.APILifecycleActive},
+		{ID: 4, Path: "/biz/reports", Method: "POST", Status: 1, Lifecycle: entity.APILifecycleDraft},
+		{ID: 5, Path: "/biz/legacy", Method: "GET", Status: 1, Lifecycle: entity.APILifecycleDeprecated, SunsetAt: &past},
+		{ID: 6, Path: "/biz/old", Method: "GET", Status: 1, Lifecycle: entity.APILifecycleDeprecated},
+	}}}
+	s := NewAPILifecycleService(APILifecycleConfig{CacheTTL: time.Minute}, apis, nil, nil, nil)
+
+	tests := []struct {
+		name       string
+		path       string
+		method     string
+		wantID     uint
+		wantReason string
+	}{
+		{name: "通配路径命中下线API", path: "/biz/users/42", method: "get", wantID: 1, wantReason: DecisionReasonAPIRetired},
+		{name: "精确登记的API优先于通配路径", path: "/biz/users/me", method: "GET"},
+		{name: "禁用的API", path: "/biz/orders", method: "GET", wantID: 3, wantReason: DecisionReasonAPIUnavailable},
+		{name: "草稿API", path: "/biz/reports", method: "POST", wantID: 4, wantReason: DecisionReasonAPIUnavailable},
+		{name: "下线时间已到的废弃API", path: "/biz/legacy", method: "GET", wantID: 5, wantReason: DecisionReasonAPIRetired},
+		{name: "废弃API仍可访问", path: "/biz/old", method: "GET", wantID: 6},
+		{name: "方法不匹配", path: "/biz/reports", method: "GET"},
+		{name: "未登记的路径", path: "/biz/unknown", method: "GET"},
+	}
+	// fakeAPIRepo未实现GetByPath，命中通配路径时按请求查询数据库会panic
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := s.Lookup(tt.path, tt.method)
+			var id uint
+			if api != nil {
+				id = api.ID
+			}
+			if id != tt.wantID {
+				t.Fatalf("Lookup(%s %s) = API %d, want %d", tt.method, tt.path, id, tt.wantID)
+			}
+			if reason, _ := APIDenyReason(api); reason != tt.wantReason {
+				t.Errorf("APIDenyReason() = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
@@ -14,6 +14,9 @@ type APIService struct {
 	businessRepo repository.BusinessRepository
 	userRepo     repository.UserRepository
 	deptRepo     repository.DepartmentRepository
+
+	categoryReferrer APICategoryReferrer
+	deleter          EntityDeleter
 }
 
 // NewAPIService 创建API服务
@@ -26,6 +29,16 @@ func NewAPIService(apiRepo repository.APIRepository, businessRepo repository.Bus
 	}
 }
 
+// SetAPICategoryReferrer 设置分类的引用方，删除分类时检查引用，分类树变化时通知
+func (s *APIService) SetAPICategoryReferrer(referrer APICategoryReferrer) {
+	s.categoryReferrer = referrer
+}
+
+// SetDeleter 设置级联删除，未设置时只删除API本身
+func (s *APIService) SetDeleter(deleter EntityDeleter) {
+	s.deleter = deleter
+}
+
 // CreateAPIRequest 创建API请求
 type CreateAPIRequest struct {
 	Name        string `json:"name" binding:"required"`
@@ -35,6 +48,7 @@ type CreateAPIRequest struct {
 	DeptID      uint   `json:"dept_id" binding:"required"`
 	BusinessID  uint   `json:"business_id" binding:"required"`
 	CategoryID  uint   `json:"category_id"`
+	Lifecycle   string `json:"lifecycle" binding:"omitempty,oneof=draft active"` // 为空时为active，draft表示尚未正式发布
 }
 
 // UpdateAPIRequest 更新API请求
@@ -68,17 +82,12 @@ func (s *APIService) CreateAPI(req *CreateAPIRequest) (*entity.API, error) {
 	if business.DeptID != req.DeptID {
 		return nil, errors.New("业务线不属于指定部门")
 	}
+	if business.Code == entity.SystemBusinessCode {
+		return nil, errors.New("系统业务线的API由服务启动时自动登记")
+	}
 
 	// 自动在路径前拼接部门代码
-	finalPath := req.Path
-	if dept.Code != "" && !strings.HasPrefix(req.Path, "/"+dept.Code+"/") {
-		// 如果路径不是以部门代码开头，则自动添加
-		if strings.HasPrefix(req.Path, "/") {
-			finalPath = "/" + dept.Code + req.Path
-		} else {
-			finalPath = "/" + dept.Code + "/" + req.Path
-		}
-	}
+	finalPath := withDeptCodePrefix(dept.Code, req.Path)
 
 	// 检查API路径是否已存在
 	existingAPI, _ := s.apiRepo.GetByPath(finalPath, req.Method)
@@ -86,6 +95,11 @@ func (s *APIService) CreateAPI(req *CreateAPIRequest) (*entity.API, error) {
 		return nil, errors.New("API路径和方法已存在")
 	}
 
+	lifecycle := req.Lifecycle
+	if lifecycle == "" {
+		lifecycle = entity.APILifecycleActive
+	}
+
 	// 创建API
 	api := &entity.API{
 		Name:        req.Name,
@@ -95,6 +109,7 @@ func (s *APIService) CreateAPI(req *CreateAPIRequest) (*entity.API, error) {
 		BusinessID:  business.ID,
 		CategoryID:  req.CategoryID,
 		Status:      1, // 默认启用
+		Lifecycle:   lifecycle,
 	}
 
 	err = s.apiRepo.Create(api)
@@ -105,13 +120,27 @@ func (s *APIService) CreateAPI(req *CreateAPIRequest) (*entity.API, error) {
 	return api, nil
 }
 
+// withDeptCodePrefix 在路径前拼接部门代码，路径已以部门代码开头时保持不变
+func withDeptCodePrefix(deptCode, path string) string {
+	if deptCode == "" || strings.HasPrefix(path, "/"+deptCode+"/") {
+		return path
+	}
+	if strings.HasPrefix(path, "/") {
+		return "/" + deptCode + path
+	}
+	return "/" + deptCode + "/" + path
+}
+
 // UpdateAPI 更新API
 func (s *APIService) UpdateAPI(req *UpdateAPIRequest) (*entity.API, error) {
 	// 检查API是否存在
 	api, err := s.apiRepo.GetByID(req.ID)
-	if err != nil {
+	if err != nil || api == nil {
 		return nil, errors.New("API不存在")
 	}
+	if s.isSystemAPI(api) {
+		return nil, errors.New("系统API由服务启动时自动登记，不能修改")
+	}
 
 	// 检查部门是否存在
 	dept, err := s.deptRepo.GetByID(req.DeptID)
@@ -129,17 +158,12 @@ func (s *APIService) UpdateAPI(req *UpdateAPIRequest) (*entity.API, error) {
 	if business.DeptID != req.DeptID {
 		return nil, errors.New("业务线不属于指定部门")
 	}
+	if business.Code == entity.SystemBusinessCode {
+		return nil, errors.New("系统业务线的API由服务启动时自动登记")
+	}
 
 	// 自动在路径前拼接部门代码
-	finalPath := req.Path
-	if dept.Code != "" && !strings.HasPrefix(req.Path, "/"+dept.Code+"/") {
-		// 如果路径不是以部门代码开头，则自动添加
-		if strings.HasPrefix(req.Path, "/") {
-			finalPath = "/" + dept.Code + req.Path
-		} else {
-			finalPath = "/" + dept.Code + "/" + req.Path
-		}
-	}
+	finalPath := withDeptCodePrefix(dept.Code, req.Path)
 
 	// 检查API路径是否已存在（排除自身）
 	existingAPI, _ := s.apiRepo.GetByPath(finalPath, req.Method)
@@ -167,15 +191,29 @@ func (s *APIService) UpdateAPI(req *UpdateAPIRequest) (*entity.API, error) {
 // DeleteAPI 删除API
 func (s *APIService) DeleteAPI(id uint) error {
 	// 检查API是否存在
-	_, err := s.apiRepo.GetByID(id)
-	if err != nil {
+	api, err := s.apiRepo.GetByID(id)
+	if err != nil || api == nil {
 		return errors.New("API不存在")
 	}
+	if s.isSystemAPI(api) {
+		return errors.New("系统API由服务启动时自动登记，不能删除")
+	}
+
+	// 按级联和限制规则删除，同时删除API组成员和策略
+	if s.deleter != nil {
+		return s.deleter.Delete(DeleteTargetAPI, id)
+	}
 
 	// 删除API
 	return s.apiRepo.Delete(id)
 }
 
+// isSystemAPI API是否属于系统业务线
+func (s *APIService) isSystemAPI(api *entity.API) bool {
+	business, err := s.businessRepo.GetByID(api.BusinessID)
+	return err == nil && business != nil && business.Code == entity.SystemBusinessCode
+}
+
 // GetAPI 获取API
 func (s *APIService) GetAPI(id uint) (*entity.API, error) {
 	return s.apiRepo.GetByID(id)
@@ -285,9 +323,17 @@ func (s *APIService) GetTotalCount() (int64, error) {
 	return s.apiRepo.GetTotalCount()
 }
 
-// GetCategoryStats 获取API分类统计
+// GetCategoryStats 获取API分类统计，按顶级分类汇总其子树内的API数量
 func (s *APIService) GetCategoryStats() ([]*repository.CategoryStatsItem, error) {
-	return s.apiRepo.GetCategoryStats()
+	categories, err := s.apiRepo.ListCategories()
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.apiRepo.CountByCategory()
+	if err != nil {
+		return nil, err
+	}
+	return apiCategoryStats(buildAPICategoryTree(categories, counts), counts), nil
 }
 
 // ListAllAPIs 获取所有API列表
@@ -330,6 +376,11 @@ func (s *APIService) CreateAPICategory(req *CreateAPICategoryRequest) (*entity.A
 		return nil, errors.New("分类代码已存在")
 	}
 
+	// 检查父分类
+	if err := s.checkCategoryParent(0, req.ParentID); err != nil {
+		return nil, err
+	}
+
 	// 创建分类
 	category := &entity.APICategory{
 		Name:     req.Name,
@@ -350,7 +401,7 @@ func (s *APIService) CreateAPICategory(req *CreateAPICategoryRequest) (*entity.A
 func (s *APIService) UpdateAPICategory(req *UpdateAPICategoryRequest) (*entity.APICategory, error) {
 	// 检查分类是否存在
 	category, err := s.apiRepo.GetCategoryByID(req.ID)
-	if err != nil {
+	if err != nil || category == nil {
 		return nil, errors.New("分类不存在")
 	}
 
@@ -360,6 +411,12 @@ func (s *APIService) UpdateAPICategory(req *UpdateAPICategoryRequest) (*entity.A
 		return nil, errors.New("分类代码已存在")
 	}
 
+	// 检查父分类，不能形成环
+	if err := s.checkCategoryParent(req.ID, req.ParentID); err != nil {
+		return nil, err
+	}
+	moved := category.ParentID != req.ParentID
+
 	// 更新分类
 	category.Name = req.Name
 	category.Code = req.Code
@@ -370,32 +427,11 @@ func (s *APIService) UpdateAPICategory(req *UpdateAPICategoryRequest) (*entity.A
 	if err != nil {
 		return nil, err
 	}
-
-	return category, nil
-}
-
-// DeleteAPICategory 删除API分类
-func (s *APIService) DeleteAPICategory(id uint) error {
-	// 检查分类是否存在
-	_, err := s.apiRepo.GetCategoryByID(id)
-	if err != nil {
-		return errors.New("分类不存在")
-	}
-
-	// 检查是否有子分类
-	children, err := s.apiRepo.GetCategoriesByParentID(id)
-	if err == nil && len(children) > 0 {
-		return errors.New("存在子分类，无法删除")
-	}
-
-	// 检查是否有API使用该分类
-	apis, err := s.apiRepo.GetAPIsByCategoryID(id)
-	if err == nil && len(apis) > 0 {
-		return errors.New("存在使用该分类的API，无法删除")
+	if moved {
+		s.categoryTreeChanged()
 	}
 
-	// 删除分类
-	return s.apiRepo.DeleteCategory(id)
+	return category, nil
 }
 
 // GetAPICategory 获取API分类
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"mcprapi/backend/internal/pkg/logger"
+)
+
+// APISunsetNotice API下线提醒，按业务线汇总发给业务线负责人
+type APISunsetNotice struct {
+	BusinessID   uint                  `json:"business_id"`
+	BusinessName string                `json:"business_name"`
+	Owner        string                `json:"owner"`
+	Email        string                `json:"email"`
+	APIs         []*APISunsetNoticeAPI `json:"apis"`
+}
+
+// APISunsetNoticeAPI 即将下线的API
+type APISunsetNoticeAPI struct {
+	ID       uint      `json:"id"`
+	Name     string    `json:"name"`
+	Path     string    `json:"path"`
+	Method   string    `json:"method"`
+	SunsetAt time.Time `json:"sunset_at"`
+}
+
+// APISunsetNotifier API下线提醒的发送渠道，可替换为邮件、IM等实现
+type APISunsetNotifier interface {
+	// Channel 渠道名称
+	Channel() string
+	// NotifySunset 将下线提醒发送给业务线负责人
+	NotifySunset(notice *APISunsetNotice) error
+}
+
+// LogAPISunsetNotifier 将下线提醒写入服务日志
+type LogAPISunsetNotifier struct {
+	logger *logger.Logger
+}
+
+// NewLogAPISunsetNotifier 创建日志通知
+func NewLogAPISunsetNotifier(logger *logger.Logger) *LogAPISunsetNotifier {
+	return &LogAPISunsetNotifier{logger: logger}
+}
+
+// Channel 渠道名称
+func (n *LogAPISunsetNotifier) Channel() string {
+	return "log"
+}
+
+// NotifySunset 将下线提醒写入日志
+func (n *LogAPISunsetNotifier) NotifySunset(notice *APISunsetNotice) error {
+	apis := make([]string, 0, len(notice.APIs))
+	for _, api := range notice.APIs {
+		apis = append(apis, api.Method+" "+api.Path+"@"+api.SunsetAt.Format(time.RFC3339))
+	}
+	n.logger.Warn("API即将下线: business=%s owner=%s email=%s apis=[%s]",
+		notice.BusinessName, notice.Owner, notice.Email, strings.Join(apis, ", "))
+	return nil
+}
+
+// FileAPISunsetNotifier 将下线提醒以JSON Lines追加到文件，供其他程序投递
+type FileAPISunsetNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileAPISunsetNotifier 创建文件通知
+func NewFileAPISunsetNotifier(path string) *FileAPISunsetNotifier {
+	return &FileAPISunsetNotifier{path: path}
+}
+
+// Channel 渠道名称
+func (n *FileAPISunsetNotifier) Channel() string {
+	return "file"
+}
+
+// NotifySunset 追加写入一行通知
+func (n *FileAPISunsetNotifier) NotifySunset(notice *APISunsetNotice) error {
+	data, err := json.Marshal(notice)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(n.path), 0750); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(n.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
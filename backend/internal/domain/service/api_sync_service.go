@@ -0,0 +1,505 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/internal/pkg/logger"
+)
+
+// 悬空策略的原因
+const (
+	DanglingReasonMissing    = "missing"    // 没有任何已登记的API与策略匹配
+	DanglingReasonDeprecated = "deprecated" // 只匹配到已废弃或已下线的API
+)
+
+// APISyncConfig 接口文档同步配置
+type APISyncConfig struct {
+	Interval     time.Duration `mapstructure:"interval"`      // 定期同步间隔，0表示只支持手动同步
+	SpecDir      string        `mapstructure:"spec_dir"`      // 文件来源所在目录，文件路径不能超出该目录
+	AllowedHosts []string      `mapstructure:"allowed_hosts"` // 允许的http(s)来源主机，为空时只允许回环和内网地址
+	Timeout      time.Duration `mapstructure:"timeout"`       // 读取http(s)来源的超时时间
+	MaxSizeMB    int           `mapstructure:"max_size_mb"`   // 文档大小上限
+}
+
+// SaveAPISpecSourceRequest 设置业务线接口文档来源请求
+type SaveAPISpecSourceRequest struct {
+	Location       string `json:"location" binding:"required"`
+	IgnoreBasePath bool   `json:"ignore_base_path"`
+	Enabled        *bool  `json:"enabled"` // 为空时启用
+}
+
+// APISpecSourceDetail 接口文档来源及上次同步报告
+type APISpecSourceDetail struct {
+	*entity.APISpecSource
+	Report *APISyncReport `json:"report,omitempty"`
+}
+
+// APISyncReport 一次同步的报告
+type APISyncReport struct {
+	BusinessID       uint              `json:"business_id"`
+	Location         string            `json:"location"`
+	DryRun           bool              `json:"dry_run"`
+	Status           string            `json:"status"`
+	Error            string            `json:"error,omitempty"`
+	ContentHash      string            `json:"content_hash,omitempty"`
+	Diff             *APIImportResult  `json:"diff,omitempty"`
+	DanglingPolicies []*DanglingPolicy `json:"dangling_policies"` // 指向不存在或已废弃API的策略
+	StartedAt        time.Time         `json:"started_at"`
+	FinishedAt       time.Time         `json:"finished_at"`
+}
+
+// DanglingPolicy 指向不存在或已废弃API的Casbin策略
+type DanglingPolicy struct {
+	ID      int    `json:"id"`
+	Subject string `json:"subject"`
+	Path    string `json:"path"`
+	Method  string `json:"method"`
+	Dept    string `json:"dept"`
+	Reason  string `json:"reason"`
+}
+
+// APISyncService 接口文档持续同步：定期读取业务线的接口文档来源，新增和更新API，文档中已不存在的API标记为废弃，并报告悬空的策略
+type APISyncService struct {
+	cfg           APISyncConfig
+	sourceRepo    repository.APISpecSourceRepository
+	businessRepo  repository.BusinessRepository
+	deptRepo      repository.DepartmentRepository
+	apiRepo       repository.APIRepository
+	importService *APIImportService
+	casbinService *CasbinService
+	logger        *logger.Logger
+	client        *http.Client
+	mu            sync.Mutex // 同一时间只执行一次同步
+}
+
+// NewAPISyncService 创建接口文档同步服务
+func NewAPISyncService(cfg APISyncConfig, sourceRepo repository.APISpecSourceRepository, businessRepo repository.BusinessRepository,
+	deptRepo repository.DepartmentRepository, apiRepo repository.APIRepository, importService *APIImportService,
+	casbinService *CasbinService, logger *logger.Logger) *APISyncService {
+	if cfg.SpecDir == "" {
+		cfg.SpecDir = "specs"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.MaxSizeMB <= 0 {
+		cfg.MaxSizeMB = 10
+	}
+
+	s := &APISyncService{
+		cfg:           cfg,
+		sourceRepo:    sourceRepo,
+		businessRepo:  businessRepo,
+		deptRepo:      deptRepo,
+		apiRepo:       apiRepo,
+		importService: importService,
+		casbinService: casbinService,
+		logger:        logger,
+	}
+	s.client = s.newHTTPClient()
+	return s
+}
+
+// ListSources 获取所有接口文档来源
+func (s *APISyncService) ListSources() ([]*entity.APISpecSource, error) {
+	return s.sourceRepo.List()
+}
+
+// GetSource 获取业务线的接口文档来源和上次同步报告
+func (s *APISyncService) GetSource(businessID uint) (*APISpecSourceDetail, error) {
+	source, err := s.sourceRepo.GetByBusiness(businessID)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, errors.New("业务线未设置接口文档来源")
+	}
+
+	detail := &APISpecSourceDetail{APISpecSource: source}
+	if source.Report != "" {
+		var report APISyncReport
+		if err := json.Unmarshal([]byte(source.Report), &report); err == nil {
+			detail.Report = &report
+		}
+	}
+	return detail, nil
+}
+
+// SaveSource 设置业务线的接口文档来源，来源变化后下次同步重新读取
+func (s *APISyncService) SaveSource(businessID uint, req *SaveAPISpecSourceRequest) (*entity.APISpecSource, error) {
+	business, err := s.businessRepo.GetByID(businessID)
+	if err != nil {
+		return nil, err
+	}
+	if business == nil {
+		return nil, errors.New("业务线不存在")
+	}
+
+	location := strings.TrimSpace(req.Location)
+	if _, _, err := s.resolveLocation(location); err != nil {
+		return nil, err
+	}
+
+	source, err := s.sourceRepo.GetByBusiness(businessID)
+	if err != nil {
+		return nil, err
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+
+	if source == nil {
+		source = &entity.APISpecSource{
+			BusinessID:     businessID,
+			Location:       location,
+			IgnoreBasePath: req.IgnoreBasePath,
+			Enabled:        enabled,
+		}
+		if err := s.sourceRepo.Create(source); err != nil {
+			return nil, err
+		}
+		return source, nil
+	}
+
+	if source.Location != location || source.IgnoreBasePath != req.IgnoreBasePath {
+		source.ContentHash = ""
+	}
+	source.Location = location
+	source.IgnoreBasePath = req.IgnoreBasePath
+	source.Enabled = enabled
+	if err := s.sourceRepo.Update(source); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// DeleteSource 删除业务线的接口文档来源，已登记的API不受影响
+func (s *APISyncService) DeleteSource(businessID uint) error {
+	source, err := s.sourceRepo.GetByBusiness(businessID)
+	if err != nil {
+		return err
+	}
+	if source == nil {
+		return errors.New("业务线未设置接口文档来源")
+	}
+	return s.sourceRepo.Delete(source.ID)
+}
+
+// Sync 立即同步业务线的接口文档，文档未变化时也重新比对；dryRun时只返回报告不做修改
+func (s *APISyncService) Sync(businessID uint, dryRun bool) (*APISyncReport, error) {
+	source, err := s.sourceRepo.GetByBusiness(businessID)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, errors.New("业务线未设置接口文档来源")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sync(source, dryRun, true), nil
+}
+
+// Start 启动定期同步任务，ctx取消时停止；未配置同步间隔时不启动
+func (s *APISyncService) Start(ctx context.Context) {
+	if s.cfg.Interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.syncAll()
+			}
+		}
+	}()
+}
+
+// syncAll 同步所有启用的来源，文档未变化的来源跳过
+func (s *APISyncService) syncAll() {
+	sources, err := s.sourceRepo.List()
+	if err != nil {
+		s.logger.Error("获取接口文档来源失败: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, source := range sources {
+		if !source.Enabled {
+			continue
+		}
+		report := s.sync(source, false, false)
+		switch report.Status {
+		case entity.APISyncStatusFailed:
+			s.logger.Error("同步接口文档失败: business=%d location=%s err=%s", source.BusinessID, source.Location, report.Error)
+		case entity.APISyncStatusSuccess:
+			diff := report.Diff
+			s.logger.Info("同步接口文档: business=%d created=%d updated=%d renamed=%d deprecated=%d dangling=%d",
+				source.BusinessID, len(diff.Created), len(diff.Updated), len(diff.Renamed), len(diff.Deprecated), len(report.DanglingPolicies))
+		}
+	}
+}
+
+// sync 读取文档并同步，force为false时文档未变化则跳过；非dryRun时记录同步结果
+func (s *APISyncService) sync(source *entity.APISpecSource, dryRun, force bool) *APISyncReport {
+	report := &APISyncReport{
+		BusinessID:       source.BusinessID,
+		Location:         source.Location,
+		DryRun:           dryRun,
+		DanglingPolicies: []*DanglingPolicy{},
+		StartedAt:        time.Now(),
+	}
+
+	err := s.syncSource(source, report, dryRun, force)
+	report.FinishedAt = time.Now()
+	if err != nil {
+		report.Status = entity.APISyncStatusFailed
+		report.Error = err.Error()
+	}
+	if dryRun {
+		return report
+	}
+
+	source.LastSyncAt = &report.FinishedAt
+	source.LastStatus = report.Status
+	source.LastError = truncateString(report.Error, 500)
+	if report.Status != entity.APISyncStatusUnchanged {
+		if data, err := json.Marshal(report); err == nil {
+			source.Report = string(data)
+		}
+	}
+	if report.Status == entity.APISyncStatusSuccess {
+		source.ContentHash = report.ContentHash
+	}
+	if err := s.sourceRepo.Update(source); err != nil {
+		s.logger.Error("记录接口文档同步结果失败: business=%d err=%v", source.BusinessID, err)
+	}
+	return report
+}
+
+// syncSource 读取、比对并应用文档，结果写入report
+func (s *APISyncService) syncSource(source *entity.APISpecSource, report *APISyncReport, dryRun, force bool) error {
+	data, err := s.read(source.Location)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	report.ContentHash = hex.EncodeToString(sum[:])
+	if !force && report.ContentHash == source.ContentHash {
+		report.Status = entity.APISyncStatusUnchanged
+		return nil
+	}
+
+	plan, err := s.importService.plan(&APIImportRequest{
+		BusinessID:     source.BusinessID,
+		Spec:           string(data),
+		IgnoreBasePath: source.IgnoreBasePath,
+	})
+	if err != nil {
+		return err
+	}
+
+	report.Diff = plan.result
+	if !dryRun {
+		if report.Diff, err = s.importService.apply(plan, apiRemovalDeprecate); err != nil {
+			return err
+		}
+	}
+
+	dept, err := s.deptRepo.GetByID(plan.business.DeptID)
+	if err != nil {
+		return err
+	}
+	if dept != nil && dept.Code != "" {
+		if report.DanglingPolicies, err = s.danglingPolicies("/" + dept.Code + "/"); err != nil {
+			return err
+		}
+	}
+
+	report.Status = entity.APISyncStatusSuccess
+	return nil
+}
+
+// danglingPolicies 资源路径在prefix之下、却没有匹配任何正常API的策略
+func (s *APISyncService) danglingPolicies(prefix string) ([]*DanglingPolicy, error) {
+	rules, err := s.casbinService.ListPoliciesByPathPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	apis, err := s.apiRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+
+	dangling := []*DanglingPolicy{}
+	for _, rule := range rules {
+		matchedActive, matchedDeprecated := false, false
+		for _, api := range apis {
+			if !matchRestrictedAPI(rule.V1, rule.V2, api.Path, api.Method) {
+				continue
+			}
+			if api.Lifecycle == entity.APILifecycleDeprecated || api.Lifecycle == entity.APILifecycleRetired {
+				matchedDeprecated = true
+				continue
+			}
+			matchedActive = true
+			break
+		}
+		if matchedActive {
+			continue
+		}
+
+		reason := DanglingReasonMissing
+		if matchedDeprecated {
+			reason = DanglingReasonDeprecated
+		}
+		dangling = append(dangling, &DanglingPolicy{
+			ID:      rule.ID,
+			Subject: rule.V0,
+			Path:    rule.V1,
+			Method:  rule.V2,
+			Dept:    rule.V3,
+			Reason:  reason,
+		})
+	}
+	return dangling, nil
+}
+
+// read 读取文档来源
+func (s *APISyncService) read(location string) ([]byte, error) {
+	filePath, u, err := s.resolveLocation(location)
+	if err != nil {
+		return nil, err
+	}
+	limit := int64(s.cfg.MaxSizeMB) << 20
+
+	var reader io.Reader
+	if u != nil {
+		resp, err := s.client.Get(u.String())
+		if err != nil {
+			return nil, fmt.Errorf("读取接口文档失败: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("读取接口文档失败: HTTP %d", resp.StatusCode)
+		}
+		reader = resp.Body
+	} else {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("读取接口文档失败: %v", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取接口文档失败: %v", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("接口文档超过%dMB", s.cfg.MaxSizeMB)
+	}
+	return data, nil
+}
+
+// resolveLocation 解析来源：http(s)地址须在允许的主机之内，文件路径须在spec_dir之内
+func (s *APISyncService) resolveLocation(location string) (string, *url.URL, error) {
+	if location == "" {
+		return "", nil, errors.New("接口文档来源不能为空")
+	}
+
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		u, err := url.Parse(location)
+		if err != nil || u.Hostname() == "" {
+			return "", nil, errors.New("无效的接口文档地址")
+		}
+		if len(s.cfg.AllowedHosts) > 0 && !s.hostAllowed(u.Hostname()) {
+			return "", nil, fmt.Errorf("接口文档地址的主机不在允许范围内: %s", u.Hostname())
+		}
+		return "", u, nil
+	}
+	if strings.Contains(location, "://") {
+		return "", nil, errors.New("接口文档来源只支持文件路径和http(s)地址")
+	}
+
+	base, err := filepath.Abs(s.cfg.SpecDir)
+	if err != nil {
+		return "", nil, err
+	}
+	filePath := location
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(base, filePath)
+	}
+	filePath = filepath.Clean(filePath)
+	if rel, err := filepath.Rel(base, filePath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", nil, fmt.Errorf("接口文档文件必须位于%s目录下", s.cfg.SpecDir)
+	}
+	return filePath, nil, nil
+}
+
+// hostAllowed 主机是否在允许列表中
+func (s *APISyncService) hostAllowed(host string) bool {
+	for _, allowed := range s.cfg.AllowedHosts {
+		if strings.EqualFold(allowed, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// newHTTPClient 创建读取文档的HTTP客户端；未配置允许的主机时，只允许连接回环和内网地址（在建立连接时校验，防止DNS重绑定）
+func (s *APISyncService) newHTTPClient() *http.Client {
+	dialer := &net.Dialer{Timeout: s.cfg.Timeout}
+	if len(s.cfg.AllowedHosts) == 0 {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !(ip.IsLoopback() || ip.IsPrivate()) {
+				return fmt.Errorf("不允许访问的地址: %s", host)
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: s.cfg.Timeout,
+		Transport: &http.Transport{
+			Proxy:       nil,
+			DialContext: dialer.DialContext,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("重定向次数过多")
+			}
+			if len(s.cfg.AllowedHosts) > 0 && !s.hostAllowed(req.URL.Hostname()) {
+				return fmt.Errorf("接口文档地址的主机不在允许范围内: %s", req.URL.Hostname())
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,872 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/internal/pkg/logger"
+	"mcprapi/backend/pkg/casbinx"
+)
+
+// 审计目标类型
+const (
+	AuditTargetUser           = "user"
+	AuditTargetUserRole       = "user_role" // 用户的角色分配
+	AuditTargetRole           = "role"
+	AuditTargetRolePermission = "role_permission" // 角色的Casbin策略
+	AuditTargetAPI            = "api"
+	AuditTargetAPICategory    = "api_category"
+	AuditTargetDepartment     = "department"
+	AuditTargetBusiness       = "business"
+	AuditTargetCasbinRule     = "casbin_rule"
+	AuditTargetAPISpecSource  = "api_spec_source" // 业务线的接口文档来源，ID为业务线ID
+	AuditTargetAPIGroup       = "api_group"
+	AuditTargetBundle         = "permission_bundle"
+	AuditTargetOAuthClient    = "oauth_client" // OAuth2客户端及其服务账号的角色
+	AuditTargetRoleMember     = "role_member"  // 角色的成员
+	AuditTargetLDAP           = "ldap"         // LDAP目录同步，没有单个目标对象
+	AuditTargetLoginLock      = "login_lock"   // IP登录锁定，IP记录在请求内容中
+)
+
+// 不通过用户令牌操作时记录的操作人
+const (
+	AuditActorSystem = "system" // 后台任务
+	AuditActorSCIM   = "scim"   // 身份提供方通过SCIM同步
+)
+
+// auditMaxBody 审计日志中请求体、快照的最大长度
+const auditMaxBody = 16 * 1024
+
+// auditSensitiveKeys 审计日志中需要脱敏的字段名（包含即脱敏）
+var auditSensitiveKeys = []string{"password", "secret", "token"}
+
+// auditIgnoredKeys 计算变更时忽略的字段
+var auditIgnoredKeys = map[string]bool{"created_at": true, "updated_at": true}
+
+// AuditRoute 需要审计的路由
+type AuditRoute struct {
+	Action     string // 操作，如user.create
+	TargetType string // 目标类型
+	IDParam    string // 目标ID所在的路径参数
+	IDField    string // 目标ID所在的请求体字段，可以是数组；两者都为空时从响应数据的id字段获取（创建操作）
+	Report     bool   // 将响应数据记录为修改后的内容，用于没有单个目标对象的操作，如同步和清理报告
+}
+
+// auditRoutes 管理操作的审计规则，键为"METHOD 路由模板"；经过审计中间件的修改操作未在此登记时按默认规则记录
+var auditRoutes = map[string]AuditRoute{
+	// 用户管理
+	"POST /api/v1/user":              {Action: "user.create", TargetType: AuditTargetUser},
+	"PUT /api/v1/user/:id":           {Action: "user.update", TargetType: AuditTargetUser, IDParam: "id"},
+	"DELETE /api/v1/user/:id":        {Action: "user.delete", TargetType: AuditTargetUser, IDParam: "id"},
+	"POST /api/v1/user/:id/roles":    {Action: "user.assign_roles", TargetType: AuditTargetUserRole, IDParam: "id"},
+	"POST /api/v1/user/assign-roles": {Action: "user.assign_roles", TargetType: AuditTargetUserRole, IDField: "user_id"},
+
+	// 账号安全管理；用户对自己账号的操作（修改密码、多因素认证和会话）只影响操作人自己，见auditExemptRoutes
+	"DELETE /api/v1/user/:id/mfa":                  {Action: "user.reset_mfa", TargetType: AuditTargetUser, IDParam: "id"},
+	"POST /api/v1/user/:id/password/reset":         {Action: "user.reset_password", TargetType: AuditTargetUser, IDParam: "id"},
+	"POST /api/v1/user/:id/password/expire":        {Action: "user.expire_password", TargetType: AuditTargetUser, IDParam: "id"},
+	"DELETE /api/v1/user/:id/sessions":             {Action: "user.revoke_sessions", TargetType: AuditTargetUser, IDParam: "id"},
+	"DELETE /api/v1/user/:id/sessions/:session_id": {Action: "user.revoke_sessions", TargetType: AuditTargetUser, IDParam: "id"},
+	"POST /api/v1/user/:id/impersonate":            {Action: "user.impersonate", TargetType: AuditTargetUser, IDParam: "id"},
+	"POST /api/v1/user/:id/unlock":                 {Action: "user.unlock", TargetType: AuditTargetUser, IDParam: "id"},
+	"POST /api/v1/user/unlock-ip":                  {Action: "login.unlock_ip", TargetType: AuditTargetLoginLock},
+
+	// 角色管理
+	"POST /api/v1/role":                    {Action: "role.create", TargetType: AuditTargetRole},
+	"PUT /api/v1/role/:id":                 {Action: "role.update", TargetType: AuditTargetRole, IDParam: "id"},
+	"DELETE /api/v1/role/:id":              {Action: "role.delete", TargetType: AuditTargetRole, IDParam: "id"},
+	"PUT /api/v1/role/:id/permissions":     {Action: "role.update_permissions", TargetType: AuditTargetRolePermission, IDParam: "id"},
+	"PUT /api/v1/role/:id/api-permissions": {Action: "role.update_permissions", TargetType: AuditTargetRolePermission, IDParam: "id"},
+	"PUT /api/v1/role/:id/api-groups":      {Action: "role.update_api_groups", TargetType: AuditTargetRolePermission, IDParam: "id"},
+
+	// API管理
+	"POST /api/v1/api":                   {Action: "api.create", TargetType: AuditTargetAPI},
+	"PUT /api/v1/api/:id":                {Action: "api.update", TargetType: AuditTargetAPI, IDParam: "id"},
+	"DELETE /api/v1/api/:id":             {Action: "api.delete", TargetType: AuditTargetAPI, IDParam: "id"},
+	"PUT /api/v1/api/:id/lifecycle":      {Action: "api.lifecycle", TargetType: AuditTargetAPI, IDParam: "id"},
+	"POST /api/v1/api/import":            {Action: "api.import", TargetType: AuditTargetBusiness, IDField: "business_id"},
+	"POST /api/v1/api/category":          {Action: "api_category.create", TargetType: AuditTargetAPICategory},
+	"PUT /api/v1/api/category/:id":       {Action: "api_category.update", TargetType: AuditTargetAPICategory, IDParam: "id"},
+	"DELETE /api/v1/api/category/:id":    {Action: "api_category.delete", TargetType: AuditTargetAPICategory, IDParam: "id"},
+	"POST /api/v1/api/category/:id/move": {Action: "api_category.move", TargetType: AuditTargetAPICategory, IDParam: "id"},
+	"PUT /api/v1/api/category/reorder":   {Action: "api_category.reorder", TargetType: AuditTargetAPICategory},
+
+	// API组管理
+	"POST /api/v1/api-group":            {Action: "api_group.create", TargetType: AuditTargetAPIGroup},
+	"PUT /api/v1/api-group/:id":         {Action: "api_group.update", TargetType: AuditTargetAPIGroup, IDParam: "id"},
+	"DELETE /api/v1/api-group/:id":      {Action: "api_group.delete", TargetType: AuditTargetAPIGroup, IDParam: "id"},
+	"POST /api/v1/api-group/:id/apis":   {Action: "api_group.add_apis", TargetType: AuditTargetAPIGroup, IDParam: "id"},
+	"DELETE /api/v1/api-group/:id/apis": {Action: "api_group.remove_apis", TargetType: AuditTargetAPIGroup, IDParam: "id"},
+
+	// 权限模板
+	"POST /api/v1/permission-bundle":                      {Action: "permission_bundle.create", TargetType: AuditTargetBundle},
+	"PUT /api/v1/permission-bundle/:id":                   {Action: "permission_bundle.update", TargetType: AuditTargetBundle, IDParam: "id"},
+	"DELETE /api/v1/permission-bundle/:id":                {Action: "permission_bundle.delete", TargetType: AuditTargetBundle, IDParam: "id"},
+	"POST /api/v1/permission-bundle/:id/apply":            {Action: "permission_bundle.apply", TargetType: AuditTargetRolePermission, IDField: "role_ids"},
+	"POST /api/v1/permission-bundle/:id/reapply":          {Action: "permission_bundle.reapply", TargetType: AuditTargetBundle, IDParam: "id"},
+	"DELETE /api/v1/permission-bundle/:id/roles/:role_id": {Action: "permission_bundle.revoke", TargetType: AuditTargetRolePermission, IDParam: "role_id"},
+
+	// 部门管理
+	"POST /api/v1/department":           {Action: "department.create", TargetType: AuditTargetDepartment},
+	"PUT /api/v1/department/:id":        {Action: "department.update", TargetType: AuditTargetDepartment, IDParam: "id"},
+	"DELETE /api/v1/department/:id":     {Action: "department.delete", TargetType: AuditTargetDepartment, IDParam: "id"},
+	"POST /api/v1/department/:id/move":  {Action: "department.move", TargetType: AuditTargetDepartment, IDParam: "id"},
+	"POST /api/v1/department/:id/merge": {Action: "department.merge", TargetType: AuditTargetDepartment, IDParam: "id"},
+	"POST /api/v1/department/:id/split": {Action: "department.split", TargetType: AuditTargetDepartment, IDParam: "id"},
+
+	// 业务线管理
+	"POST /api/v1/business":                      {Action: "business.create", TargetType: AuditTargetBusiness},
+	"PUT /api/v1/business/:id/spec-source":       {Action: "api_spec_source.update", TargetType: AuditTargetAPISpecSource, IDParam: "id"},
+	"DELETE /api/v1/business/:id/spec-source":    {Action: "api_spec_source.delete", TargetType: AuditTargetAPISpecSource, IDParam: "id"},
+	"POST /api/v1/business/:id/spec-source/sync": {Action: "api_spec_source.sync", TargetType: AuditTargetAPISpecSource, IDParam: "id"},
+	"PUT /api/v1/business/:id":                   {Action: "business.update", TargetType: AuditTargetBusiness, IDParam: "id"},
+	"DELETE /api/v1/business/:id":                {Action: "business.delete", TargetType: AuditTargetBusiness, IDParam: "id"},
+
+	// Casbin策略管理
+	"POST /api/v1/casbin/policy":         {Action: "casbin.add_policy", TargetType: AuditTargetCasbinRule},
+	"PUT /api/v1/casbin/policy":          {Action: "casbin.update_policy", TargetType: AuditTargetCasbinRule, IDField: "id"},
+	"DELETE /api/v1/casbin/policy/:id":   {Action: "casbin.delete_policy", TargetType: AuditTargetCasbinRule, IDParam: "id"},
+	"DELETE /api/v1/casbin/policy/batch": {Action: "casbin.delete_policy", TargetType: AuditTargetCasbinRule, IDField: "ids"},
+	"POST /api/v1/casbin/policy/reload":  {Action: "casbin.reload", TargetType: AuditTargetCasbinRule},
+
+	// 孤立数据清理
+	"POST /api/v1/maintenance/orphans/cleanup": {Action: "maintenance.cleanup_orphans", TargetType: AuditTargetCasbinRule, Report: true},
+
+	// OAuth2客户端管理，client_credentials客户端会创建承载scope角色的服务账号
+	"POST /api/v1/oauth/clients":            {Action: "oauth_client.create", TargetType: AuditTargetOAuthClient},
+	"PUT /api/v1/oauth/clients/:id":         {Action: "oauth_client.update", TargetType: AuditTargetOAuthClient, IDParam: "id"},
+	"DELETE /api/v1/oauth/clients/:id":      {Action: "oauth_client.delete", TargetType: AuditTargetOAuthClient, IDParam: "id"},
+	"POST /api/v1/oauth/clients/:id/secret": {Action: "oauth_client.rotate_secret", TargetType: AuditTargetOAuthClient, IDParam: "id"},
+
+	// LDAP目录同步
+	"POST /api/v1/ldap/sync": {Action: "ldap.sync", TargetType: AuditTargetLDAP, Report: true},
+
+	// SCIM同步，用户和组的ID即本地用户和角色ID
+	"POST /scim/v2/Users":        {Action: "user.create", TargetType: AuditTargetUser},
+	"PUT /scim/v2/Users/:id":     {Action: "user.update", TargetType: AuditTargetUser, IDParam: "id"},
+	"PATCH /scim/v2/Users/:id":   {Action: "user.update", TargetType: AuditTargetUser, IDParam: "id"},
+	"DELETE /scim/v2/Users/:id":  {Action: "user.delete", TargetType: AuditTargetUser, IDParam: "id"},
+	"POST /scim/v2/Groups":       {Action: "role.create", TargetType: AuditTargetRoleMember},
+	"PUT /scim/v2/Groups/:id":    {Action: "role.update_members", TargetType: AuditTargetRoleMember, IDParam: "id"},
+	"PATCH /scim/v2/Groups/:id":  {Action: "role.update_members", TargetType: AuditTargetRoleMember, IDParam: "id"},
+	"DELETE /scim/v2/Groups/:id": {Action: "role.delete", TargetType: AuditTargetRoleMember, IDParam: "id"},
+
+	// 部门管理员
+	"POST /api/v1/dept-permission/grant-admin":  {Action: "dept.grant_admin", TargetType: AuditTargetUserRole, IDField: "user_id"},
+	"POST /api/v1/dept-permission/revoke-admin": {Action: "dept.revoke_admin", TargetType: AuditTargetUserRole, IDField: "user_id"},
+	"POST /api/v1/system/init-admin":            {Action: "system.init_admin", TargetType: AuditTargetUserRole, IDField: "user_id"},
+
+	// 部门级别管理
+	"POST /api/v1/dept-management/:dept_id/users":                      {Action: "user.create", TargetType: AuditTargetUser},
+	"PUT /api/v1/dept-management/:dept_id/users/:id":                   {Action: "user.update", TargetType: AuditTargetUser, IDParam: "id"},
+	"DELETE /api/v1/dept-management/:dept_id/users/:id":                {Action: "user.delete", TargetType: AuditTargetUser, IDParam: "id"},
+	"POST /api/v1/dept-management/:dept_id/users/:id/roles":            {Action: "user.assign_roles", TargetType: AuditTargetUserRole, IDParam: "id"},
+	"POST /api/v1/dept-management/:dept_id/roles":                      {Action: "role.create", TargetType: AuditTargetRole},
+	"PUT /api/v1/dept-management/:dept_id/roles/:id":                   {Action: "role.update", TargetType: AuditTargetRole, IDParam: "id"},
+	"DELETE /api/v1/dept-management/:dept_id/roles/:id":                {Action: "role.delete", TargetType: AuditTargetRole, IDParam: "id"},
+	"POST /api/v1/dept-management/:dept_id/roles/:id/permissions":      {Action: "role.update_permissions", TargetType: AuditTargetRolePermission, IDParam: "id"},
+	"POST /api/v1/dept-management/:dept_id/businesses":                 {Action: "business.create", TargetType: AuditTargetBusiness},
+	"PUT /api/v1/dept-management/:dept_id/businesses/:id":              {Action: "business.update", TargetType: AuditTargetBusiness, IDParam: "id"},
+	"DELETE /api/v1/dept-management/:dept_id/businesses/:id":           {Action: "business.delete", TargetType: AuditTargetBusiness, IDParam: "id"},
+	"POST /api/v1/dept-management/:dept_id/apis":                       {Action: "api.create", TargetType: AuditTargetAPI},
+	"PUT /api/v1/dept-management/:dept_id/apis/:id":                    {Action: "api.update", TargetType: AuditTargetAPI, IDParam: "id"},
+	"DELETE /api/v1/dept-management/:dept_id/apis/:id":                 {Action: "api.delete", TargetType: AuditTargetAPI, IDParam: "id"},
+	"POST /api/v1/dept-management/:dept_id/business-apis/:business_id": {Action: "api.create", TargetType: AuditTargetAPI},
+}
+
+// auditExemptRoutes 不审计的修改操作及原因，键为"METHOD 路由模板"
+var auditExemptRoutes = map[string]string{
+	// 登录流程，未登录时调用，不经过审计中间件
+	"POST /api/v1/auth/login":           "登录流程",
+	"POST /api/v1/auth/logout":          "登录流程",
+	"POST /api/v1/auth/mfa/verify":      "登录流程",
+	"POST /api/v1/auth/mfa/enroll":      "登录流程",
+	"POST /api/v1/auth/password/change": "登录流程",
+	"POST /api/v1/auth/password/reset":  "登录流程",
+	"POST /api/v1/auth/qrcode":          "登录流程",
+	"POST /api/v1/oauth/token":          "登录流程",
+	"POST /api/v1/init/database":        "系统初始化，未登录时调用",
+
+	// 用户对自己账号的操作，只影响操作人自己
+	"POST /api/v1/auth/qrcode/:id/scan":            "本人操作",
+	"POST /api/v1/auth/qrcode/:id/confirm":         "本人操作",
+	"POST /api/v1/auth/qrcode/:id/cancel":          "本人操作",
+	"POST /api/v1/user/mfa/enroll":                 "本人操作",
+	"POST /api/v1/user/mfa/activate":               "本人操作",
+	"POST /api/v1/user/mfa/disable":                "本人操作",
+	"POST /api/v1/user/mfa/recovery-codes":         "本人操作",
+	"PUT /api/v1/user/password":                    "本人操作",
+	"DELETE /api/v1/user/sessions":                 "本人操作",
+	"DELETE /api/v1/user/sessions/:id":             "本人操作",
+	"POST /api/v1/user/identities/:provider":       "本人操作",
+	"DELETE /api/v1/user/identities/:provider":     "本人操作",
+	"POST /api/v1/user/refresh-token":              "本人操作",
+	"POST /api/v1/user/refresh-token-with-version": "本人操作",
+	"POST /api/v1/user/scoped-token":               "本人操作",
+	"POST /api/v1/oauth/authorize":                 "本人操作",
+	"POST /api/v1/auth/impersonation/stop":         "由代登录日志记录",
+
+	// 只读查询
+	"POST /api/v1/api/check-permission":         "只读查询",
+	"POST /api/v1/api/import/preview":           "只读查询",
+	"POST /api/v1/dept-permission/check":        "只读查询",
+	"POST /api/v1/department/:id/merge/preview": "只读查询",
+	"POST /api/v1/department/:id/split/preview": "只读查询",
+}
+
+// isAuditMethod 是否为需要审计的修改操作方法
+func isAuditMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// CheckAuditRoutes 对照已注册的路由检查审计规则，返回既没有登记审计规则也没有豁免的修改操作，
+// 以及登记了审计规则或豁免但没有注册的路由
+func CheckAuditRoutes(routes []SystemRoute) (unlisted, stale []string) {
+	registered := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if !isAuditMethod(route.Method) {
+			continue
+		}
+		if _, ok := auditRoutes[key]; ok {
+			continue
+		}
+		if _, ok := auditExemptRoutes[key]; !ok {
+			unlisted = append(unlisted, key)
+		}
+	}
+	for key := range auditRoutes {
+		if !registered[key] {
+			stale = append(stale, key)
+		}
+	}
+	for key := range auditExemptRoutes {
+		if !registered[key] {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(unlisted)
+	sort.Strings(stale)
+	return unlisted, stale
+}
+
+// AuditRequest 一次被审计请求的上下文，由审计中间件在处理请求前后填充
+type AuditRequest struct {
+	Route          AuditRoute
+	ActorID        uint
+	ActorUsername  string
+	ActorDeptID    uint
+	ImpersonatorID uint
+	Method         string
+	Path           string
+	IP             string
+	RequestID      string
+	Params         map[string]string // 路径参数
+	Body           []byte
+
+	targetIDs []string
+	before    interface{}
+}
+
+// AuditEvent 不经过HTTP请求的修改，如后台任务重新应用权限模板、清理孤立数据和同步目录
+type AuditEvent struct {
+	Action     string
+	TargetType string
+	TargetIDs  []string
+	Detail     interface{} // 修改内容，如同步报告，记录为请求内容
+	Err        error       // 修改失败的原因
+}
+
+// AuditRecorder 记录后台任务的修改，操作人为system
+type AuditRecorder interface {
+	Record(event *AuditEvent)
+}
+
+// AuditLogListRequest 审计日志查询请求，时间支持RFC3339、"2006-01-02 15:04:05"和"2006-01-02"格式
+type AuditLogListRequest struct {
+	ActorID    uint   `form:"actor_id"`
+	Actor      string `form:"actor"` // 操作人用户名
+	Action     string `form:"action"`
+	TargetType string `form:"target_type"`
+	TargetID   string `form:"target_id"`
+	DeptID     uint   `form:"dept_id"`
+	Result     string `form:"result"`
+	RequestID  string `form:"request_id"`
+	StartTime  string `form:"start_time"`
+	EndTime    string `form:"end_time"`
+	Page       int    `form:"page"`
+	PageSize   int    `form:"page_size"`
+}
+
+// AuditLogListResponse 审计日志列表响应
+type AuditLogListResponse struct {
+	Total int64              `json:"total"`
+	Items []*entity.AuditLog `json:"items"`
+}
+
+// AuditChange 字段变更
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditService 管理操作审计服务
+type AuditService struct {
+	logRepo        repository.AuditLogRepository
+	userRepo       repository.UserRepository
+	roleRepo       repository.RoleRepository
+	apiRepo        repository.APIRepository
+	deptRepo       repository.DepartmentRepository
+	businessRepo   repository.BusinessRepository
+	specSourceRepo repository.APISpecSourceRepository
+	groupRepo      repository.APIGroupRepository
+	bundleRepo     repository.PermissionBundleRepository
+	oauthRepo      repository.OAuthRepository
+	casbinService  *CasbinService
+	enforcer       *casbinx.Enforcer
+	logger         *logger.Logger
+	trail          AuditTrailRecorder
+}
+
+// AuditTrailRecorder 防篡改审计链，审计日志写入后同时追加到审计链
+type AuditTrailRecorder interface {
+	Append(log *entity.AuditLog) error
+}
+
+// NewAuditService 创建审计服务
+func NewAuditService(logRepo repository.AuditLogRepository, userRepo repository.UserRepository, roleRepo repository.RoleRepository,
+	apiRepo repository.APIRepository, deptRepo repository.DepartmentRepository, businessRepo repository.BusinessRepository,
+	specSourceRepo repository.APISpecSourceRepository, groupRepo repository.APIGroupRepository, bundleRepo repository.PermissionBundleRepository,
+	oauthRepo repository.OAuthRepository, casbinService *CasbinService, enforcer *casbinx.Enforcer, logger *logger.Logger) *AuditService {
+	return &AuditService{
+		logRepo:        logRepo,
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		apiRepo:        apiRepo,
+		deptRepo:       deptRepo,
+		businessRepo:   businessRepo,
+		specSourceRepo: specSourceRepo,
+		groupRepo:      groupRepo,
+		bundleRepo:     bundleRepo,
+		oauthRepo:      oauthRepo,
+		casbinService:  casbinService,
+		enforcer:       enforcer,
+		logger:         logger,
+	}
+}
+
+// SetTrail 设置防篡改审计链
+func (s *AuditService) SetTrail(trail AuditTrailRecorder) {
+	s.trail = trail
+}
+
+// Route 获取路由对应的审计规则，不需要审计时返回false；
+// 未登记审计规则的修改操作按默认规则记录，操作为"METHOD 路由模板"，不解析目标对象
+func (s *AuditService) Route(method, fullPath string) (AuditRoute, bool) {
+	key := method + " " + fullPath
+	if route, ok := auditRoutes[key]; ok {
+		return route, true
+	}
+	if fullPath == "" || !isAuditMethod(method) {
+		return AuditRoute{}, false
+	}
+	if _, ok := auditExemptRoutes[key]; ok {
+		return AuditRoute{}, false
+	}
+	return AuditRoute{Action: key}, true
+}
+
+// Begin 在处理请求前解析目标ID并记录修改前的快照
+func (s *AuditService) Begin(req *AuditRequest) {
+	req.targetIDs = s.resolveTargetIDs(req)
+	if len(req.targetIDs) > 0 {
+		req.before = s.snapshot(req.Route.TargetType, req.targetIDs)
+	}
+}
+
+// Finish 请求处理完成后记录审计日志；response为响应体，用于判断结果和获取新建对象的ID
+func (s *AuditService) Finish(req *AuditRequest, status int, response []byte) {
+	var resp struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+		ID      interface{}     `json:"id"`     // SCIM资源直接返回对象
+		Detail  string          `json:"detail"` // SCIM错误原因
+	}
+	_ = json.Unmarshal(response, &resp)
+	if resp.Message == "" {
+		resp.Message = resp.Detail
+	}
+
+	result := entity.AuditResultFailure
+	if status < http.StatusBadRequest && resp.Code == 0 {
+		result = entity.AuditResultSuccess
+	}
+
+	// 创建操作的目标ID从响应数据中获取
+	targetIDs := req.targetIDs
+	if len(targetIDs) == 0 && req.Route.IDParam == "" && req.Route.IDField == "" && !req.Route.Report {
+		if len(resp.Data) > 0 {
+			var data map[string]interface{}
+			if err := json.Unmarshal(resp.Data, &data); err == nil {
+				targetIDs = auditIDs(data["id"])
+			}
+		} else {
+			targetIDs = auditIDs(resp.ID)
+		}
+	}
+
+	var after interface{}
+	if result == entity.AuditResultSuccess && len(targetIDs) > 0 && req.Route.TargetType != "" && !strings.HasSuffix(req.Route.Action, ".delete") {
+		after = s.snapshot(req.Route.TargetType, targetIDs)
+	}
+	if result == entity.AuditResultSuccess && req.Route.Report && len(resp.Data) > 0 {
+		after = resp.Data
+	}
+
+	log := &entity.AuditLog{
+		ActorID:        req.ActorID,
+		ActorUsername:  req.ActorUsername,
+		ImpersonatorID: req.ImpersonatorID,
+		Action:         req.Route.Action,
+		TargetType:     req.Route.TargetType,
+		TargetID:       truncateString(strings.Join(targetIDs, ","), 100),
+		DeptID:         s.resolveDeptID(req, targetIDs, req.before, after),
+		Method:         req.Method,
+		Path:           truncateString(req.Path, 500),
+		Request:        auditJSON(redactAuditBody(req.Body)),
+		Before:         auditJSON(req.before),
+		After:          auditJSON(after),
+		Result:         result,
+		Status:         status,
+		Message:        truncateString(resp.Message, 255),
+		IP:             req.IP,
+		RequestID:      req.RequestID,
+	}
+	if diff := auditDiff(req.before, after); len(diff) > 0 {
+		log.Diff = auditJSON(diff)
+	}
+	s.write(log)
+}
+
+// Record 记录后台任务的修改，成功时记录目标对象修改后的快照
+func (s *AuditService) Record(event *AuditEvent) {
+	log := &entity.AuditLog{
+		ActorUsername: AuditActorSystem,
+		Action:        event.Action,
+		TargetType:    event.TargetType,
+		TargetID:      truncateString(strings.Join(event.TargetIDs, ","), 100),
+		Request:       auditJSON(event.Detail),
+		Result:        entity.AuditResultSuccess,
+	}
+	if event.Err != nil {
+		log.Result = entity.AuditResultFailure
+		log.Message = truncateString(event.Err.Error(), 255)
+	} else if len(event.TargetIDs) > 0 {
+		after := s.snapshot(event.TargetType, event.TargetIDs)
+		log.After = auditJSON(after)
+		log.DeptID = auditDeptID(after)
+	}
+	s.write(log)
+}
+
+// write 写入审计日志并追加到审计链
+func (s *AuditService) write(log *entity.AuditLog) {
+	if err := s.logRepo.Create(log); err != nil {
+		s.logger.Error("记录审计日志失败: action=%s target=%s/%s err=%v", log.Action, log.TargetType, log.TargetID, err)
+	}
+	// 审计日志表写入失败时审计链仍然保留记录
+	if s.trail != nil {
+		if err := s.trail.Append(log); err != nil {
+			s.logger.Error("追加审计链失败: action=%s target=%s/%s err=%v", log.Action, log.TargetType, log.TargetID, err)
+		}
+	}
+}
+
+// List 分页查询审计日志
+func (s *AuditService) List(req *AuditLogListRequest) (*AuditLogListResponse, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+
+	query := &repository.AuditLogQuery{
+		ActorID:       req.ActorID,
+		ActorUsername: req.Actor,
+		Action:        req.Action,
+		TargetType:    req.TargetType,
+		TargetID:      req.TargetID,
+		DeptID:        req.DeptID,
+		Result:        req.Result,
+		RequestID:     req.RequestID,
+		Page:          req.Page,
+		PageSize:      req.PageSize,
+	}
+	if req.StartTime != "" {
+		t, err := parseAuditTime(req.StartTime, false)
+		if err != nil {
+			return nil, err
+		}
+		query.StartTime = &t
+	}
+	if req.EndTime != "" {
+		t, err := parseAuditTime(req.EndTime, true)
+		if err != nil {
+			return nil, err
+		}
+		query.EndTime = &t
+	}
+
+	logs, total, err := s.logRepo.List(query)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLogListResponse{Total: total, Items: logs}, nil
+}
+
+// resolveTargetIDs 从路径参数或请求体中获取目标ID
+func (s *AuditService) resolveTargetIDs(req *AuditRequest) []string {
+	if req.Route.IDParam != "" {
+		if id := req.Params[req.Route.IDParam]; id != "" {
+			return []string{id}
+		}
+		return nil
+	}
+	if req.Route.IDField != "" && len(req.Body) > 0 {
+		var body map[string]interface{}
+		if err := json.Unmarshal(req.Body, &body); err == nil {
+			return auditIDs(body[req.Route.IDField])
+		}
+	}
+	return nil
+}
+
+// resolveDeptID 操作对象所属部门：路径或请求体中的部门ID，其次是对象快照中的部门ID，最后为操作人所在部门
+func (s *AuditService) resolveDeptID(req *AuditRequest, targetIDs []string, before, after interface{}) uint {
+	if id, err := strconv.ParseUint(req.Params["dept_id"], 10, 32); err == nil && id > 0 {
+		return uint(id)
+	}
+	if req.Route.TargetType == AuditTargetDepartment && len(targetIDs) == 1 {
+		if id, err := strconv.ParseUint(targetIDs[0], 10, 32); err == nil {
+			return uint(id)
+		}
+	}
+	for _, v := range []interface{}{after, before} {
+		if deptID := auditDeptID(v); deptID > 0 {
+			return deptID
+		}
+	}
+	if len(req.Body) > 0 {
+		var body map[string]interface{}
+		if err := json.Unmarshal(req.Body, &body); err == nil {
+			if deptID, ok := body["dept_id"].(float64); ok && deptID > 0 {
+				return uint(deptID)
+			}
+		}
+	}
+	return req.ActorDeptID
+}
+
+// snapshot 获取目标对象的当前状态，多个目标时返回数组；获取失败时只记录日志
+func (s *AuditService) snapshot(targetType string, ids []string) interface{} {
+	if len(ids) == 1 {
+		v, err := s.load(targetType, ids[0])
+		if err != nil {
+			s.logger.Warn("获取审计快照失败: %s/%s %v", targetType, ids[0], err)
+			return nil
+		}
+		return v
+	}
+
+	items := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		v, err := s.load(targetType, id)
+		if err != nil {
+			s.logger.Warn("获取审计快照失败: %s/%s %v", targetType, id, err)
+			continue
+		}
+		if v != nil {
+			items = append(items, v)
+		}
+	}
+	return items
+}
+
+// load 按类型加载单个目标对象，不存在时返回nil
+func (s *AuditService) load(targetType, rawID string) (interface{}, error) {
+	id, err := strconv.ParseUint(rawID, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("无效的ID: %s", rawID)
+	}
+
+	var v interface{}
+	switch targetType {
+	case AuditTargetUser:
+		v, err = s.userRepo.GetByID(uint(id))
+	case AuditTargetUserRole:
+		user, err := s.userRepo.GetByID(uint(id))
+		if err != nil || user == nil {
+			return nil, err
+		}
+		roles, err := s.userRepo.GetUserRoles(uint(id))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"user_id": user.ID, "username": user.Username, "dept_id": user.DeptID, "roles": roles}, nil
+	case AuditTargetRole:
+		v, err = s.roleRepo.GetByID(uint(id))
+	case AuditTargetRolePermission:
+		role, err := s.roleRepo.GetByID(uint(id))
+		if err != nil || role == nil {
+			return nil, err
+		}
+		return map[string]interface{}{"role_id": role.ID, "code": role.Code, "dept_id": role.DeptID, "policies": s.enforcer.GetFilteredPolicy(0, role.Code)}, nil
+	case AuditTargetAPI:
+		v, err = s.apiRepo.GetByID(uint(id))
+	case AuditTargetAPICategory:
+		v, err = s.apiRepo.GetCategoryByID(uint(id))
+	case AuditTargetDepartment:
+		v, err = s.deptRepo.GetByID(uint(id))
+	case AuditTargetBusiness:
+		v, err = s.businessRepo.GetByID(uint(id))
+	case AuditTargetCasbinRule:
+		v, err = s.casbinService.GetPolicyByID(int(id))
+	case AuditTargetAPISpecSource:
+		v, err = s.specSourceRepo.GetByBusiness(uint(id))
+	case AuditTargetAPIGroup:
+		group, err := s.groupRepo.GetByID(uint(id))
+		if err != nil || group == nil {
+			return nil, err
+		}
+		apis, err := s.groupRepo.ListAPIs(group.ID)
+		if err != nil {
+			return nil, err
+		}
+		apiIDs := make([]uint, 0, len(apis))
+		for _, api := range apis {
+			apiIDs = append(apiIDs, api.ID)
+		}
+		return map[string]interface{}{"id": group.ID, "code": group.Code, "name": group.Name, "business_id": group.BusinessID, "api_ids": apiIDs}, nil
+	case AuditTargetRoleMember:
+		role, err := s.roleRepo.GetByID(uint(id))
+		if err != nil || role == nil {
+			return nil, err
+		}
+		users, err := s.userRepo.GetUsersByRole(role.ID)
+		if err != nil {
+			return nil, err
+		}
+		userIDs := make([]uint, 0, len(users))
+		for _, user := range users {
+			userIDs = append(userIDs, user.ID)
+		}
+		return map[string]interface{}{"role_id": role.ID, "code": role.Code, "name": role.Name, "dept_id": role.DeptID, "user_ids": userIDs}, nil
+	case AuditTargetOAuthClient:
+		client, err := s.oauthRepo.GetClientByID(uint(id))
+		if err != nil || client == nil {
+			return nil, err
+		}
+		snapshot, _ := toAuditMap(client)
+		if business, err := s.businessRepo.GetByID(client.BusinessID); err == nil && business != nil {
+			snapshot["dept_id"] = business.DeptID
+		}
+		// client_credentials客户端的权限来自服务账号的角色
+		if client.ServiceUserID > 0 {
+			roles, err := s.userRepo.GetUserRoles(client.ServiceUserID)
+			if err != nil {
+				return nil, err
+			}
+			snapshot["service_user_roles"] = roles
+		}
+		return snapshot, nil
+	case AuditTargetBundle:
+		bundle, err := s.bundleRepo.GetByID(uint(id))
+		if err != nil || bundle == nil {
+			return nil, err
+		}
+		version, err := s.bundleRepo.GetVersion(bundle.ID, bundle.Version)
+		if err != nil {
+			return nil, err
+		}
+		snapshot := map[string]interface{}{"id": bundle.ID, "code": bundle.Code, "name": bundle.Name, "version": bundle.Version}
+		if version != nil {
+			snapshot["rules"] = json.RawMessage(version.Rules)
+		}
+		return snapshot, nil
+	default:
+		return nil, errors.New("未知的审计目标类型")
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 仓库返回的nil指针视为不存在
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+	return v, nil
+}
+
+// auditIDs 将JSON中的ID（数字、字符串或数组）转换为字符串列表
+func auditIDs(v interface{}) []string {
+	switch id := v.(type) {
+	case float64:
+		return []string{strconv.FormatFloat(id, 'f', -1, 64)}
+	case string:
+		if id != "" {
+			return []string{id}
+		}
+	case []interface{}:
+		var ids []string
+		for _, item := range id {
+			ids = append(ids, auditIDs(item)...)
+		}
+		return ids
+	}
+	return nil
+}
+
+// auditDeptID 从对象快照中获取dept_id字段
+func auditDeptID(v interface{}) uint {
+	if v == nil {
+		return 0
+	}
+	m, ok := toAuditMap(v)
+	if !ok {
+		return 0
+	}
+	if deptID, ok := m["dept_id"].(float64); ok {
+		return uint(deptID)
+	}
+	return 0
+}
+
+// auditDiff 比较修改前后的对象，返回变更的顶层字段；创建和删除时返回nil
+func auditDiff(before, after interface{}) map[string]AuditChange {
+	if before == nil || after == nil {
+		return nil
+	}
+	b, okB := toAuditMap(before)
+	a, okA := toAuditMap(after)
+	if !okB || !okA {
+		if !reflect.DeepEqual(toAuditValue(before), toAuditValue(after)) {
+			return map[string]AuditChange{"value": {Before: toAuditValue(before), After: toAuditValue(after)}}
+		}
+		return nil
+	}
+
+	diff := make(map[string]AuditChange)
+	for key, bv := range b {
+		if auditIgnoredKeys[key] {
+			continue
+		}
+		if av, ok := a[key]; !ok || !reflect.DeepEqual(bv, av) {
+			diff[key] = AuditChange{Before: redactAuditValue(key, bv), After: redactAuditValue(key, a[key])}
+		}
+	}
+	for key, av := range a {
+		if _, ok := b[key]; !ok && !auditIgnoredKeys[key] {
+			diff[key] = AuditChange{After: redactAuditValue(key, av)}
+		}
+	}
+	return diff
+}
+
+// toAuditValue 将对象转换为JSON值，便于比较
+func toAuditValue(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var value interface{}
+	_ = json.Unmarshal(data, &value)
+	return value
+}
+
+// toAuditMap 将对象转换为JSON对象
+func toAuditMap(v interface{}) (map[string]interface{}, bool) {
+	m, ok := toAuditValue(v).(map[string]interface{})
+	return m, ok
+}
+
+// redactAuditBody 解析并脱敏请求体，非JSON时原样保留
+func redactAuditBody(body []byte) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return string(body)
+	}
+	return redactAuditValue("", value)
+}
+
+// redactAuditValue 递归脱敏字段名包含敏感词的值
+func redactAuditValue(key string, v interface{}) interface{} {
+	lower := strings.ToLower(key)
+	for _, sensitive := range auditSensitiveKeys {
+		if strings.Contains(lower, sensitive) {
+			return "******"
+		}
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			redacted[k] = redactAuditValue(k, item)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(value))
+		for i, item := range value {
+			redacted[i] = redactAuditValue("", item)
+		}
+		return redacted
+	}
+	return v
+}
+
+// auditJSON 序列化为JSON并截断，nil时返回空字符串
+func auditJSON(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(redactAuditValue("", toAuditValue(v)))
+	if err != nil {
+		return ""
+	}
+	return truncateString(string(data), auditMaxBody)
+}
+
+// parseAuditTime 解析查询时间；只有日期的结束时间包含当天
+func parseAuditTime(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("无效的时间格式: %s", value)
+}
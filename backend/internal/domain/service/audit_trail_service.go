@@ -0,0 +1,346 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/internal/pkg/logger"
+)
+
+// auditTrailVerifyBatch 校验审计链时每次读取的记录数
+const auditTrailVerifyBatch = 1000
+
+// AuditTrailConfig 防篡改审计链配置
+type AuditTrailConfig struct {
+	SigningKey         string        `mapstructure:"signing_key"`         // Ed25519私钥种子（32字节，Base64编码），为空时由JWT密钥派生
+	RequireSigningKey  bool          `mapstructure:"require_signing_key"` // 未配置签名密钥时启动失败，生产环境必须开启
+	CheckpointInterval time.Duration `mapstructure:"checkpoint_interval"` // 签名检查点的间隔
+}
+
+// AuditTrailVerifyResult 审计链校验结果
+type AuditTrailVerifyResult struct {
+	Valid       bool   `json:"valid"`
+	Entries     uint64 `json:"entries"`     // 校验通过的记录数
+	Checkpoints int    `json:"checkpoints"` // 校验通过的检查点数
+	LastSeq     uint64 `json:"last_seq"`
+	LastHash    string `json:"last_hash"`
+	BrokenSeq   uint64 `json:"broken_seq,omitempty"` // 第一个断开的记录序号
+	Reason      string `json:"reason,omitempty"`
+	KeyID       string `json:"key_id"`
+	PublicKey   string `json:"public_key"` // 当前检查点签名公钥（Base64），可用于离线校验；历史检查点按各自的密钥ID校验
+}
+
+// auditTrailContent 参与哈希计算的记录内容，字段顺序固定
+type auditTrailContent struct {
+	Seq           uint64 `json:"seq"`
+	AuditLogID    uint   `json:"audit_log_id"`
+	Timestamp     int64  `json:"timestamp"`
+	ActorID       uint   `json:"actor_id"`
+	ActorUsername string `json:"actor_username"`
+	Action        string `json:"action"`
+	TargetType    string `json:"target_type"`
+	TargetID      string `json:"target_id"`
+	Payload       string `json:"payload"`
+	PrevHash      string `json:"prev_hash"`
+}
+
+// AuditTrailService 防篡改审计链服务：审计日志同时追加到哈希链，定期对链尾签名
+type AuditTrailService struct {
+	cfg        AuditTrailConfig
+	repo       repository.AuditTrailRepository
+	logger     *logger.Logger
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+	keyID      string
+	mu         sync.Mutex
+}
+
+// NewAuditTrailService 创建防篡改审计链服务，fallbackSecret用于开发环境未配置签名密钥时派生密钥
+func NewAuditTrailService(cfg AuditTrailConfig, repo repository.AuditTrailRepository, fallbackSecret string, logger *logger.Logger) (*AuditTrailService, error) {
+	if cfg.CheckpointInterval <= 0 {
+		cfg.CheckpointInterval = time.Hour
+	}
+
+	var seed []byte
+	if cfg.SigningKey != "" {
+		var err error
+		seed, err = base64.StdEncoding.DecodeString(cfg.SigningKey)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, errors.New("审计链签名密钥必须是Base64编码的32字节种子")
+		}
+	} else if cfg.RequireSigningKey {
+		return nil, errors.New("未配置审计链签名密钥audit_trail.signing_key")
+	} else {
+		logger.Warn("未配置审计链签名密钥，使用JWT密钥派生，生产环境请配置audit_trail.signing_key")
+		sum := sha256.Sum256([]byte("audit-trail:" + fallbackSecret))
+		seed = sum[:]
+	}
+
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+
+	return &AuditTrailService{
+		cfg:        cfg,
+		repo:       repo,
+		logger:     logger,
+		privateKey: privateKey,
+		publicKey:  publicKey,
+		keyID:      signingKeyID(publicKey),
+	}, nil
+}
+
+// Append 将审计日志追加到审计链
+func (s *AuditTrailService) Append(log *entity.AuditLog) error {
+	payload, err := json.Marshal(log)
+	if err != nil {
+		return err
+	}
+
+	// 同一实例内串行追加，减少数据库锁等待
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.repo.Append(func(last *entity.AuditTrailEntry) (*entity.AuditTrailEntry, error) {
+		entry := &entity.AuditTrailEntry{
+			Seq:           1,
+			AuditLogID:    log.ID,
+			Timestamp:     time.Now().UnixMilli(),
+			ActorID:       log.ActorID,
+			ActorUsername: log.ActorUsername,
+			Action:        log.Action,
+			TargetType:    log.TargetType,
+			TargetID:      log.TargetID,
+			Payload:       string(payload),
+		}
+		if last != nil {
+			entry.Seq = last.Seq + 1
+			entry.PrevHash = last.Hash
+		}
+		hash, err := hashAuditTrailEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		entry.Hash = hash
+		return entry, nil
+	})
+	return err
+}
+
+// Checkpoint 链尾有新记录时对链尾签名，返回新建的检查点；没有新记录时返回nil
+func (s *AuditTrailService) Checkpoint() (*entity.AuditCheckpoint, error) {
+	last, err := s.repo.Last()
+	if err != nil {
+		return nil, err
+	}
+	if last == nil {
+		return nil, nil
+	}
+	previous, err := s.repo.LastCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	if previous != nil && previous.Seq >= last.Seq {
+		return nil, nil
+	}
+
+	// 先登记公钥，轮换密钥后历史检查点仍能按密钥ID找到对应公钥
+	if err := s.repo.SaveSigningKey(&entity.AuditSigningKey{
+		KeyID:     s.keyID,
+		PublicKey: base64.StdEncoding.EncodeToString(s.publicKey),
+	}); err != nil {
+		return nil, err
+	}
+
+	checkpoint := &entity.AuditCheckpoint{
+		Seq:       last.Seq,
+		Hash:      last.Hash,
+		Timestamp: time.Now().UnixMilli(),
+		KeyID:     s.keyID,
+	}
+	checkpoint.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(s.privateKey, checkpointMessage(checkpoint)))
+	if err := s.repo.CreateCheckpoint(checkpoint); err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// Start 启动定期签名检查点任务，ctx取消时停止
+func (s *AuditTrailService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.cfg.CheckpointInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if checkpoint, err := s.Checkpoint(); err != nil {
+					s.logger.Error("创建审计链检查点失败: %v", err)
+				} else if checkpoint != nil {
+					s.logger.Info("创建审计链检查点: seq=%d hash=%s", checkpoint.Seq, checkpoint.Hash)
+				}
+			}
+		}
+	}()
+}
+
+// Verify 从第一条记录开始校验整条审计链和所有检查点，返回第一个断开的位置
+func (s *AuditTrailService) Verify() (*AuditTrailVerifyResult, error) {
+	result := &AuditTrailVerifyResult{
+		KeyID:     s.keyID,
+		PublicKey: base64.StdEncoding.EncodeToString(s.publicKey),
+	}
+
+	keys, err := s.signingKeys()
+	if err != nil {
+		return nil, err
+	}
+	checkpoints, err := s.repo.ListCheckpoints()
+	if err != nil {
+		return nil, err
+	}
+	for _, checkpoint := range checkpoints {
+		if reason := verifyCheckpointSignature(checkpoint, keys); reason != "" {
+			result.BrokenSeq = checkpoint.Seq
+			result.Reason = fmt.Sprintf("检查点%d%s", checkpoint.ID, reason)
+			return result, nil
+		}
+	}
+
+	next := 0 // 下一个待比对的检查点
+	var afterSeq uint64
+	prevHash := ""
+	for {
+		entries, err := s.repo.ListAfter(afterSeq, auditTrailVerifyBatch)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			expected := afterSeq + 1
+			switch {
+			case entry.Seq != expected:
+				result.BrokenSeq = expected
+				result.Reason = fmt.Sprintf("记录缺失：期望序号%d，实际为%d", expected, entry.Seq)
+			case entry.PrevHash != prevHash:
+				result.BrokenSeq = entry.Seq
+				result.Reason = "上一条记录的哈希不匹配"
+			default:
+				hash, err := hashAuditTrailEntry(entry)
+				if err != nil {
+					return nil, err
+				}
+				if hash != entry.Hash {
+					result.BrokenSeq = entry.Seq
+					result.Reason = "记录内容与哈希不一致"
+				}
+			}
+			if result.Reason != "" {
+				return result, nil
+			}
+
+			for next < len(checkpoints) && checkpoints[next].Seq == entry.Seq {
+				if checkpoints[next].Hash != entry.Hash {
+					result.BrokenSeq = entry.Seq
+					result.Reason = fmt.Sprintf("记录哈希与检查点%d不一致", checkpoints[next].ID)
+					return result, nil
+				}
+				result.Checkpoints++
+				next++
+			}
+
+			afterSeq = entry.Seq
+			prevHash = entry.Hash
+			result.Entries++
+			result.LastSeq = entry.Seq
+			result.LastHash = entry.Hash
+		}
+
+		if len(entries) < auditTrailVerifyBatch {
+			break
+		}
+	}
+
+	// 检查点之后的记录被删除
+	if next < len(checkpoints) {
+		result.BrokenSeq = afterSeq + 1
+		result.Reason = fmt.Sprintf("记录被截断：检查点%d签名的序号为%d，审计链只到%d", checkpoints[next].ID, checkpoints[next].Seq, afterSeq)
+		return result, nil
+	}
+
+	result.Valid = true
+	return result, nil
+}
+
+// signingKeys 按密钥ID获取登记的签名公钥，密钥ID与公钥不匹配的登记记录不采用
+func (s *AuditTrailService) signingKeys() (map[string]ed25519.PublicKey, error) {
+	stored, err := s.repo.ListSigningKeys()
+	if err != nil {
+		return nil, err
+	}
+	keys := map[string]ed25519.PublicKey{s.keyID: s.publicKey}
+	for _, key := range stored {
+		publicKey, err := base64.StdEncoding.DecodeString(key.PublicKey)
+		if err != nil || len(publicKey) != ed25519.PublicKeySize || signingKeyID(publicKey) != key.KeyID {
+			continue
+		}
+		keys[key.KeyID] = publicKey
+	}
+	return keys, nil
+}
+
+// verifyCheckpointSignature 使用检查点密钥ID对应的公钥校验签名，通过时返回空字符串
+func verifyCheckpointSignature(checkpoint *entity.AuditCheckpoint, keys map[string]ed25519.PublicKey) string {
+	publicKey, ok := keys[checkpoint.KeyID]
+	if !ok {
+		return fmt.Sprintf("的签名密钥%s未登记", checkpoint.KeyID)
+	}
+	signature, err := base64.StdEncoding.DecodeString(checkpoint.Signature)
+	if err != nil || !ed25519.Verify(publicKey, checkpointMessage(checkpoint), signature) {
+		return "的签名无效"
+	}
+	return ""
+}
+
+// hashAuditTrailEntry 计算记录哈希：SHA-256(记录内容JSON)，内容包含上一条记录的哈希
+func hashAuditTrailEntry(entry *entity.AuditTrailEntry) (string, error) {
+	data, err := json.Marshal(auditTrailContent{
+		Seq:           entry.Seq,
+		AuditLogID:    entry.AuditLogID,
+		Timestamp:     entry.Timestamp,
+		ActorID:       entry.ActorID,
+		ActorUsername: entry.ActorUsername,
+		Action:        entry.Action,
+		TargetType:    entry.TargetType,
+		TargetID:      entry.TargetID,
+		Payload:       entry.Payload,
+		PrevHash:      entry.PrevHash,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// signingKeyID 密钥ID：公钥SHA-256的前8字节
+func signingKeyID(publicKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:8])
+}
+
+// checkpointMessage 检查点的签名内容
+func checkpointMessage(checkpoint *entity.AuditCheckpoint) []byte {
+	return []byte(fmt.Sprintf("audit-trail:%d:%s:%d", checkpoint.Seq, checkpoint.Hash, checkpoint.Timestamp))
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/pkg/logger"
+)
+
+// fakeAuditTrailRepo 内存中的审计链仓库
+type fakeAuditTrailRepo struct {
+	entries     []*entity.AuditTrailEntry
+	checkpoints []*entity.AuditCheckpoint
+	keys        []*entity.AuditSigningKey
+}
+
+func (r *fakeAuditTrailRepo) Append(build func(last *entity.AuditTrailEntry) (*entity.AuditTrailEntry, error)) (*entity.AuditTrailEntry, error) {
+	last, _ := r.Last()
+	entry, err := build(last)
+	if err != nil {
+		return nil, err
+	}
+	r.entries = append(r.entries, entry)
+	return entry, nil
+}
+
+func (r *fakeAuditTrailRepo) Last() (*entity.AuditTrailEntry, error) {
+	if len(r.entries) == 0 {
+		return nil, nil
+	}
+	return r.entries[len(r.entries)-1], nil
+}
+
+func (r *fakeAuditTrailRepo) ListAfter(afterSeq uint64, limit int) ([]*entity.AuditTrailEntry, error) {
+	var entries []*entity.AuditTrailEntry
+	for _, entry := range r.entries {
+		if entry.Seq > afterSeq && len(entries) < limit {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (r *fakeAuditTrailRepo) CreateCheckpoint(checkpoint *entity.AuditCheckpoint) error {
+	checkpoint.ID = uint(len(r.checkpoints) + 1)
+	r.checkpoints = append(r.checkpoints, checkpoint)
+	return nil
+}
+
+func (r *fakeAuditTrailRepo) LastCheckpoint() (*entity.AuditCheckpoint, error) {
+	if len(r.checkpoints) == 0 {
+		return nil, nil
+	}
+	return r.checkpoints[len(r.checkpoints)-1], nil
+}
+
+func (r *fakeAuditTrailRepo) ListCheckpoints() ([]*entity.AuditCheckpoint, error) {
+	return r.checkpoints, nil
+}
+
+func (r *fakeAuditTrailRepo) SaveSigningKey(key *entity.AuditSigningKey) error {
+	for _, existing := range r.keys {
+		if existing.KeyID == key.KeyID {
+			return nil
+		}
+	}
+	r.keys = append(r.keys, key)
+	return nil
+}
+
+func (r *fakeAuditTrailRepo) ListSigningKeys() ([]*entity.AuditSigningKey, error) {
+	return r.keys, nil
+}
+
+func (r *fakeAuditTrailRepo) EnsureAppendOnly() error {
+	return nil
+}
+
+// newTestAuditTrail 创建使用固定签名密钥的审计链服务，seed不同时密钥不同
+func newTestAuditTrail(t *testing.T, repo *fakeAuditTrailRepo, seed byte) *AuditTrailService {
+	t.Helper()
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{seed}, 32))
+	s, err := NewAuditTrailService(AuditTrailConfig{SigningKey: key}, repo, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// buildTestAuditTrail 追加5条记录，在第3条和第5条之后创建检查点
+func buildTestAuditTrail(t *testing.T, s *AuditTrailService) {
+	t.Helper()
+	for i := 1; i <= 5; i++ {
+		log := &entity.AuditLog{ID: uint(i), ActorID: 1, ActorUsername: "admin", Action: "user.update", TargetType: AuditTargetUser, TargetID: "2"}
+		if err := s.Append(log); err != nil {
+			t.Fatal(err)
+		}
+		if i == 3 || i == 5 {
+			if checkpoint, err := s.Checkpoint(); err != nil || checkpoint == nil {
+				t.Fatalf("Checkpoint() = (%v, %v)", checkpoint, err)
+			}
+		}
+	}
+}
+
+// rehash 重新计算记录哈希，模拟能直接写库的攻击者
+func rehash(t *testing.T, entry *entity.AuditTrailEntry) {
+	t.Helper()
+	hash, err := hashAuditTrailEntry(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.Hash = hash
+}
+
+func TestAuditTrailVerify(t *testing.T) {
+	tests := []struct {
+		name       string
+		tamper     func(t *testing.T, repo *fakeAuditTrailRepo)
+		otherKey   bool // 轮换为其他密钥后校验
+		wantValid  bool
+		wantBroken uint64
+		wantReason string
+	}{
+		{
+			name:      "完整的审计链",
+			tamper:    func(t *testing.T, repo *fakeAuditTrailRepo) {},
+			wantValid: true,
+		},
+		{
+			name: "修改记录内容",
+			tamper: func(t *testing.T, repo *fakeAuditTrailRepo) {
+				repo.entries[2].Payload = `{"action":"user.read"}`
+			},
+			wantBroken: 3,
+			wantReason: "记录内容与哈希不一致",
+		},
+		{
+			name: "修改记录内容并重新计算哈希",
+			tamper: func(t *testing.T, repo *fakeAuditTrailRepo) {
+				repo.entries[1].Action = "user.read"
+				rehash(t, repo.entries[1])
+			},
+			wantBroken: 3,
+			wantReason: "上一条记录的哈希不匹配",
+		},
+		{
+			name: "重写检查点之前的整段链",
+			tamper: func(t *testing.T, repo *fakeAuditTrailRepo) {
+				repo.entries[1].Action = "user.read"
+				rehash(t, repo.entries[1])
+				for _, entry := range repo.entries[2:] {
+					entry.PrevHash = repo.entries[entry.Seq-2].Hash
+					rehash(t, entry)
+				}
+			},
+			wantBroken: 3,
+			wantReason: "记录哈希与检查点",
+		},
+		{
+			name: "删除中间的记录",
+			tamper: func(t *testing.T, repo *fakeAuditTrailRepo) {
+				repo.entries = append(repo.entries[:1], repo.entries[2:]...)
+			},
+			wantBroken: 2,
+			wantReason: "记录缺失",
+		},
+		{
+			name: "截断检查点签名的记录",
+			tamper: func(t *testing.T, repo *fakeAuditTrailRepo) {
+				repo.entries = repo.entries[:4]
+			},
+			wantBroken: 5,
+			wantReason: "记录被截断",
+		},
+		{
+			name: "伪造检查点",
+			tamper: func(t *testing.T, repo *fakeAuditTrailRepo) {
+				repo.checkpoints[0].Hash = repo.entries[1].Hash
+			},
+			wantBroken: 3,
+			wantReason: "签名无效",
+		},
+		{
+			name:      "轮换签名密钥后校验历史检查点",
+			tamper:    func(t *testing.T, repo *fakeAuditTrailRepo) {},
+			otherKey:  true,
+			wantValid: true,
+		},
+		{
+			name: "签名公钥未登记",
+			tamper: func(t *testing.T, repo *fakeAuditTrailRepo) {
+				repo.keys = nil
+			},
+			otherKey:   true,
+			wantBroken: 3,
+			wantReason: "未登记",
+		},
+		{
+			name: "登记的公钥被替换",
+			tamper: func(t *testing.T, repo *fakeAuditTrailRepo) {
+				other := newTestAuditTrail(t, &fakeAuditTrailRepo{}, 2)
+				repo.keys[0].PublicKey = base64.StdEncoding.EncodeToString(other.publicKey)
+			},
+			otherKey:   true,
+			wantBroken: 3,
+			wantReason: "未登记",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeAuditTrailRepo{}
+			s := newTestAuditTrail(t, repo, 0)
+			buildTestAuditTrail(t, s)
+			tt.tamper(t, repo)
+			if tt.otherKey {
+				s = newTestAuditTrail(t, repo, 1)
+			}
+
+			result, err := s.Verify()
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if result.Valid != tt.wantValid || result.BrokenSeq != tt.wantBroken || !strings.Contains(result.Reason, tt.wantReason) {
+				t.Errorf("Verify() = valid=%v broken=%d reason=%q, want valid=%v broken=%d reason~%q",
+					result.Valid, result.BrokenSeq, result.Reason, tt.wantValid, tt.wantBroken, tt.wantReason)
+			}
+			if tt.wantValid && (result.Entries != 5 || result.Checkpoints != 2 || result.LastSeq != 5) {
+				t.Errorf("Verify() entries=%d checkpoints=%d last_seq=%d, want 5 2 5", result.Entries, result.Checkpoints, result.LastSeq)
+			}
+		})
+	}
+}
+
+func TestAuditTrailCheckpointWithoutNewEntries(t *testing.T) {
+	repo := &fakeAuditTrailRepo{}
+	s := newTestAuditTrail(t, repo, 0)
+
+	if checkpoint, err := s.Checkpoint(); err != nil || checkpoint != nil {
+		t.Fatalf("空链的Checkpoint() = (%v, %v), want (nil, nil)", checkpoint, err)
+	}
+	buildTestAuditTrail(t, s)
+	if checkpoint, err := s.Checkpoint(); err != nil || checkpoint != nil {
+		t.Fatalf("没有新记录时Checkpoint() = (%v, %v), want (nil, nil)", checkpoint, err)
+	}
+	if len(repo.checkpoints) != 2 {
+		t.Errorf("检查点数量 = %d, want 2", len(repo.checkpoints))
+	}
+
+	result, err := s.Verify()
+	if err != nil || !result.Valid {
+		t.Fatalf("Verify() = (%+v, %v)", result, err)
+	}
+}
+
+func TestNewAuditTrailServiceSigningKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     AuditTrailConfig
+		wantErr bool
+	}{
+		{name: "开发环境未配置密钥时派生", cfg: AuditTrailConfig{}},
+		{name: "要求配置密钥", cfg: AuditTrailConfig{RequireSigningKey: true}, wantErr: true},
+		{name: "密钥格式错误", cfg: AuditTrailConfig{SigningKey: "invalid"}, wantErr: true},
+		{name: "密钥长度错误", cfg: AuditTrailConfig{SigningKey: base64.StdEncoding.EncodeToString([]byte("short"))}, wantErr: true},
+		{name: "配置了有效密钥", cfg: AuditTrailConfig{SigningKey: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)), RequireSigningKey: true}},
+	}
+	log, err := logger.New(logger.Config{Level: "error"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAuditTrailService(tt.cfg, &fakeAuditTrailRepo{}, "secret", log)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewAuditTrailService() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
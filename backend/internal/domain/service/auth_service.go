@@ -15,7 +15,6 @@ import (
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
-	"github.com/google/uuid"
 
 	"mcprapi/backend/internal/domain/entity"
 	"mcprapi/backend/internal/domain/repository"
@@ -30,6 +29,46 @@ type AuthService struct {
 	apiRepo   repository.APIRepository
 	casbin    *casbinx.Enforcer
 	jwtSecret string
+
+	externalAuth ExternalAuthenticator
+	challenger   LoginChallenger
+	guard        LoginGuard
+	// 密码过期或被要求修改时的登录挑战，在二次验证之后执行
+	passwordChallenger LoginChallenger
+	sessions           SessionTracker
+	decisions          *DecisionLogger
+	lifecycle          APILifecycleLookup
+}
+
+// SessionTracker 会话跟踪，签发的每个Token对应一个会话，会话ID写入jti
+type SessionTracker interface {
+	Create(user *entity.User, claims *Claims, parentID string) (string, error)
+	Touch(sessionID, ip, userAgent string) (bool, error)
+	Revoke(sessionID string) error
+}
+
+// APILifecycleLookup 查找请求对应的废弃、下线、草稿或禁用的API，正常启用的API返回nil
+type APILifecycleLookup interface {
+	Lookup(path, method string) *entity.API
+}
+
+// ExternalAuthenticator 外部密码认证（如LDAP），认证成功后返回对应的本地用户
+type ExternalAuthenticator interface {
+	Authenticate(username, password string) (*entity.User, error)
+}
+
+// LoginChallenger 密码校验通过后的附加验证（如MFA），返回非nil时以其结果代替登录令牌；
+// 挑战完成后凭origin记录登录结果
+type LoginChallenger interface {
+	Challenge(user *entity.User, origin *LoginOrigin) (*LoginResponse, error)
+}
+
+// LoginGuard 密码登录防暴力破解，Check在校验密码前调用，Fail和Succeed记录本次尝试结果；
+// 需要二次验证时，验证失败同样计入失败次数，所有挑战完成后才记录成功
+type LoginGuard interface {
+	Check(req *LoginRequest) error
+	Fail(req *LoginRequest, user *entity.User, reason string)
+	Succeed(req *LoginRequest, user *entity.User)
 }
 
 // NewAuthService 创建认证服务
@@ -43,10 +82,55 @@ func NewAuthService(userRepo repository.UserRepository, roleRepo repository.Role
 	}
 }
 
+// SetExternalAuthenticator 设置外部密码认证
+func (s *AuthService) SetExternalAuthenticator(authenticator ExternalAuthenticator) {
+	s.externalAuth = authenticator
+}
+
+// SetLoginChallenger 设置密码登录后的附加验证
+func (s *AuthService) SetLoginChallenger(challenger LoginChallenger) {
+	s.challenger = challenger
+}
+
+// SetPasswordChallenger 设置密码过期强制修改的登录挑战
+func (s *AuthService) SetPasswordChallenger(challenger LoginChallenger) {
+	s.passwordChallenger = challenger
+}
+
+// SetSessionTracker 设置会话跟踪
+func (s *AuthService) SetSessionTracker(sessions SessionTracker) {
+	s.sessions = sessions
+}
+
+// SetLoginGuard 设置登录防暴力破解
+func (s *AuthService) SetLoginGuard(guard LoginGuard) {
+	s.guard = guard
+}
+
 // LoginRequest 登录请求
 type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	ClientIP  string `json:"-"` // 由处理器填充
+	UserAgent string `json:"-"`
+}
+
+// LoginOrigin 登录请求的来源，随登录挑战保存，挑战完成后用于登录防护和记录会话设备
+type LoginOrigin struct {
+	Username  string `json:"username"`
+	ClientIP  string `json:"client_ip"`
+	UserAgent string `json:"user_agent"`
+	External  bool   `json:"external,omitempty"` // 外部身份登录（SSO、扫码确认），不检查密码是否需要修改
+}
+
+// Origin 登录请求的来源，不包含密码
+func (r *LoginRequest) Origin() *LoginOrigin {
+	return &LoginOrigin{Username: r.Username, ClientIP: r.ClientIP, UserAgent: r.UserAgent}
+}
+
+// request 转换为登录防护使用的请求
+func (o *LoginOrigin) request() *LoginRequest {
+	return &LoginRequest{Username: o.Username, ClientIP: o.ClientIP, UserAgent: o.UserAgent}
 }
 
 // LoginResponse 登录响应
@@ -54,6 +138,16 @@ type LoginResponse struct {
 	Token     string       `json:"token"`
 	ExpiresAt int64        `json:"expires_at"`
 	User      *entity.User `json:"user"`
+	SessionID string       `json:"session_id,omitempty"`
+
+	// 需要二次验证时不返回Token，客户端凭MFAToken调用二次验证接口
+	MFARequired       bool   `json:"mfa_required,omitempty"`
+	MFAEnrollRequired bool   `json:"mfa_enroll_required,omitempty"` // 角色要求MFA但尚未绑定验证器
+	MFAToken          string `json:"mfa_token,omitempty"`
+
+	// 密码过期或被管理员要求修改时不返回Token，客户端凭PasswordChangeToken设置新密码后完成登录
+	PasswordChangeRequired bool   `json:"password_change_required,omitempty"`
+	PasswordChangeToken    string `json:"password_change_token,omitempty"`
 }
 
 // Login 用户登录
@@ -66,35 +160,168 @@ func (s *AuthService) Login(req *LoginRequest) (*LoginResponse, error) {
 		return nil, errors.New("密码不能为空")
 	}
 
-	// 根据用户名查找用户
-	user, err := s.userRepo.GetByUsername(req.Username)
-	if err != nil {
-		return nil, errors.New("用户名或密码错误")
-	}
-	if user == nil {
-		return nil, errors.New("用户名或密码错误")
+	// 锁定或限速中的用户名和IP直接拒绝
+	if s.guard != nil {
+		if err := s.guard.Check(req); err != nil {
+			return nil, err
+		}
 	}
 
-	// 验证密码
-	if !encrypt.VerifyPassword(req.Password, user.Password) {
+	user, err := s.authenticate(req)
+	if err != nil {
+		if s.guard != nil {
+			s.guard.Fail(req, user, err.Error())
+		}
 		return nil, errors.New("用户名或密码错误")
 	}
 
 	// 检查用户状态
 	if user.Status != 1 {
+		if s.guard != nil {
+			s.guard.Fail(req, user, "用户已禁用")
+		}
 		return nil, errors.New("用户已禁用")
 	}
 
+	// 二次验证，验证通过前不记录登录成功，失败计数保留
+	origin := req.Origin()
+	if s.challenger != nil {
+		challenge, err := s.challenger.Challenge(user, origin)
+		if err != nil {
+			return nil, err
+		}
+		if challenge != nil {
+			return challenge, nil
+		}
+	}
+
+	return s.finishPasswordLogin(user, origin)
+}
+
+// finishPasswordLogin 密码登录（含二次验证）通过后检查密码是否需要修改，再签发登录令牌
+func (s *AuthService) finishPasswordLogin(user *entity.User, origin *LoginOrigin) (*LoginResponse, error) {
+	if s.passwordChallenger != nil && (origin == nil || !origin.External) {
+		challenge, err := s.passwordChallenger.Challenge(user, origin)
+		if err != nil {
+			return nil, err
+		}
+		if challenge != nil {
+			return challenge, nil
+		}
+	}
+
+	return s.loginSucceeded(user, origin)
+}
+
+// externalLogin 外部身份（SSO、扫码确认）校验通过后登录，与密码登录一样需要通过二次验证才签发登录令牌
+func (s *AuthService) externalLogin(user *entity.User, origin *LoginOrigin) (*LoginResponse, error) {
+	origin.Username = user.Username
+	origin.External = true
+	if s.challenger != nil {
+		challenge, err := s.challenger.Challenge(user, origin)
+		if err != nil {
+			return nil, err
+		}
+		if challenge != nil {
+			return challenge, nil
+		}
+	}
+
+	return s.loginSucceeded(user, origin)
+}
+
+// checkLogin 登录挑战继续前校验用户名和IP是否已被锁定
+func (s *AuthService) checkLogin(user *entity.User, origin *LoginOrigin) error {
+	if s.guard == nil {
+		return nil
+	}
+	return s.guard.Check(loginOriginOf(user, origin).request())
+}
+
+// loginFailed 登录挑战（如二次验证）失败，计入登录失败次数
+func (s *AuthService) loginFailed(user *entity.User, origin *LoginOrigin, reason string) {
+	if s.guard != nil {
+		s.guard.Fail(loginOriginOf(user, origin).request(), user, reason)
+	}
+}
+
+// loginSucceeded 所有登录挑战完成后记录登录成功、签发登录令牌并记录登录设备
+func (s *AuthService) loginSucceeded(user *entity.User, origin *LoginOrigin) (*LoginResponse, error) {
+	origin = loginOriginOf(user, origin)
+	if s.guard != nil {
+		s.guard.Succeed(origin.request(), user)
+	}
+
+	resp, err := s.completeLogin(user)
+	if err != nil {
+		return nil, err
+	}
+	// 记录登录设备，失败不影响登录
+	if s.sessions != nil && resp.SessionID != "" {
+		_, _ = s.sessions.Touch(resp.SessionID, origin.ClientIP, origin.UserAgent)
+	}
+	return resp, nil
+}
+
+// loginOriginOf 挑战凭证中没有保存来源时（如升级前签发的凭证）按用户名计数
+func loginOriginOf(user *entity.User, origin *LoginOrigin) *LoginOrigin {
+	if origin != nil {
+		return origin
+	}
+	return &LoginOrigin{Username: user.Username}
+}
+
+// authenticate 校验用户名和密码；密码错误时如能确定用户也一并返回，用于记录登录历史
+func (s *AuthService) authenticate(req *LoginRequest) (*entity.User, error) {
+	// 根据用户名查找用户
+	user, err := s.userRepo.GetByUsername(req.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil || user.Source == entity.SourceLDAP {
+		// 本地不存在或来自外部目录的用户交给外部认证
+		if s.externalAuth == nil {
+			return user, errors.New("用户不存在")
+		}
+		external, err := s.externalAuth.Authenticate(req.Username, req.Password)
+		if err != nil {
+			return user, err
+		}
+		if external == nil {
+			return user, errors.New("外部认证失败")
+		}
+		return external, nil
+	}
+
+	// 验证密码
+	ok, needsRehash := encrypt.CheckPassword(req.Password, user.Password)
+	if !ok {
+		return user, errors.New("密码错误")
+	}
+	// 旧算法或弱参数的哈希在登录成功后升级，失败不影响本次登录
+	if needsRehash {
+		if passwordHash, err := encrypt.HashPassword(req.Password); err == nil {
+			user.Password = passwordHash
+			_ = s.userRepo.Update(user)
+		}
+	}
+	return user, nil
+}
+
+// completeLogin 签发登录令牌，调用方须已完成身份校验和所有登录挑战
+func (s *AuthService) completeLogin(user *entity.User) (*LoginResponse, error) {
 	// 生成JWT令牌
-	token, expiresAt, err := s.generateToken(user)
+	token, claims, err := s.generateToken(user)
 	if err != nil {
 		return nil, err
 	}
 
 	return &LoginResponse{
 		Token:     token,
-		ExpiresAt: expiresAt,
+		ExpiresAt: claims.ExpiresAt,
 		User:      user,
+		SessionID: claims.Id,
 	}, nil
 }
 
@@ -104,62 +331,120 @@ type Claims struct {
 	Username     string `json:"username"`
 	DeptID       uint   `json:"dept_id"`
 	TokenVersion int    `json:"v,omitempty"` // Token版本号，用于安全控制
+	ClientID     string `json:"client_id,omitempty"` // OAuth2客户端ID，仅OAuth2签发的Token携带
+	Scope        string `json:"scope,omitempty"`     // 授权范围，多个以空格分隔
+	Act          *Actor `json:"act,omitempty"`       // 代登录时实际操作的管理员
+	Rst          *TokenRestriction `json:"rst,omitempty"` // 受限令牌的访问范围
 	jwt.StandardClaims
 }
 
-// 生成JWT令牌
-func (s *AuthService) generateToken(user *entity.User) (string, int64, error) {
-	expiresAt := time.Now().Add(time.Hour * 24).Unix() // 24小时过期
+// Actor 代登录令牌中实际操作的身份（RFC 8693 act声明），令牌的用户字段为被代登录的用户
+type Actor struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// tokenOptions 签发令牌的参数，未设置的声明不写入令牌
+type tokenOptions struct {
+	ttl      time.Duration
+	clientID string
+	scope    string
+	act      *Actor
+	rst      *TokenRestriction
+	parent   string // 签发该令牌的会话，终止时该令牌一并失效
+}
+
+// issueToken 按参数构造声明、创建会话并签名，所有令牌都由此签发
+func (s *AuthService) issueToken(user *entity.User, opts tokenOptions) (string, *Claims, error) {
+	now := time.Now()
 
 	claims := &Claims{
 		UserID:       user.ID,
 		Username:     user.Username,
 		DeptID:       user.DeptID,
 		TokenVersion: user.TokenVersion, // 包含用户的Token版本号
+		ClientID:     opts.clientID,
+		Scope:        opts.scope,
+		Act:          opts.act,
+		Rst:          opts.rst,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expiresAt,
-			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: now.Add(opts.ttl).Unix(),
+			IssuedAt:  now.Unix(),
 			Issuer:    "api-auth-system",
 		},
 	}
+	if err := s.attachSession(user, claims, opts.parent); err != nil {
+		return "", nil, err
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString([]byte(s.jwtSecret))
 	if err != nil {
-		return "", 0, err
+		return "", nil, err
 	}
 
-	return tokenString, expiresAt, nil
+	return tokenString, claims, nil
+}
+
+// 生成JWT令牌
+func (s *AuthService) generateToken(user *entity.User) (string, *Claims, error) {
+	return s.issueToken(user, tokenOptions{ttl: 24 * time.Hour}) // 24小时过期
 }
 
 // GenerateUserToken 为用户生成Token（默认24小时过期）
 func (s *AuthService) GenerateUserToken(user *entity.User) (string, int64, error) {
-	return s.generateToken(user)
+	token, claims, err := s.generateToken(user)
+	if err != nil {
+		return "", 0, err
+	}
+	return token, claims.ExpiresAt, nil
+}
+
+// attachSession 为Token创建会话并写入jti，未启用会话跟踪时跳过
+func (s *AuthService) attachSession(user *entity.User, claims *Claims, parentID string) error {
+	if s.sessions == nil {
+		return nil
+	}
+	sessionID, err := s.sessions.Create(user, claims, parentID)
+	if err != nil {
+		return fmt.Errorf("创建会话失败: %v", err)
+	}
+	claims.Id = sessionID
+	return nil
 }
 
 // GenerateUserTokenWithExpiry 为用户生成指定过期时间的Token
 func (s *AuthService) GenerateUserTokenWithExpiry(user *entity.User, expireDays int) (string, int64, error) {
-	expiresAt := time.Now().Add(time.Duration(expireDays) * 24 * time.Hour).Unix()
-
-	claims := &Claims{
-		UserID:       user.ID,
-		Username:     user.Username,
-		DeptID:       user.DeptID,
-		TokenVersion: user.TokenVersion, // 包含用户的Token版本号
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expiresAt,
-			IssuedAt:  time.Now().Unix(),
-			Issuer:    "api-auth-system",
-		},
+	token, claims, err := s.issueToken(user, tokenOptions{ttl: time.Duration(expireDays) * 24 * time.Hour})
+	if err != nil {
+		return "", 0, err
 	}
+	return token, claims.ExpiresAt, nil
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.jwtSecret))
+// GenerateOAuthToken 为OAuth2客户端签发访问令牌，scope限制令牌可使用的角色
+func (s *AuthService) GenerateOAuthToken(user *entity.User, clientID, scope string, ttl time.Duration) (string, int64, error) {
+	token, claims, err := s.issueToken(user, tokenOptions{ttl: ttl, clientID: clientID, scope: scope})
 	if err != nil {
 		return "", 0, err
 	}
+	return token, claims.ExpiresAt, nil
+}
 
-	return tokenString, expiresAt, nil
+// GenerateImpersonationToken 为管理员签发代登录令牌，权限按被代登录的用户计算
+func (s *AuthService) GenerateImpersonationToken(user, actor *entity.User, ttl time.Duration) (string, *Claims, error) {
+	return s.issueToken(user, tokenOptions{
+		ttl: ttl,
+		act: &Actor{
+			UserID:   actor.ID,
+			Username: actor.Username,
+		},
+	})
+}
+
+// GenerateRestrictedToken 签发受限令牌，权限为用户角色权限与restriction的交集；parentSession不为空时随该会话一起终止
+func (s *AuthService) GenerateRestrictedToken(user *entity.User, restriction *TokenRestriction, ttl time.Duration, parentSession string) (string, *Claims, error) {
+	return s.issueToken(user, tokenOptions{ttl: ttl, rst: restriction, parent: parentSession})
 }
 
 // GenerateUserTokenWithVersionIncrement 生成Token并递增版本号
@@ -201,18 +486,38 @@ type CheckPermissionRequest struct {
 	UserID  string `json:"user_id"`
 	APIPath string `json:"api_path"`
 	Method  string `json:"method"`
+
+	// Restriction 受限令牌的访问范围，由处理器从当前令牌填充
+	Restriction *TokenRestriction `json:"-"`
+	// Scope OAuth2令牌的授权范围，由处理器从当前令牌填充，只使用scope中授予的角色
+	Scope string `json:"-"`
+	// 以下字段由处理器填充，仅用于授权决策日志
+	ClientID  string `json:"-"`
+	RequestID string `json:"-"`
+	ClientIP  string `json:"-"`
+
+	// quiet 内部校验（如签发受限令牌），不记录授权决策日志
+	quiet bool
 }
 
 // CheckPermissionResponse 权限检查响应
 type CheckPermissionResponse struct {
-	Allowed bool     `json:"allowed"`
-	Roles   []string `json:"roles"`
-	IsAdmin bool     `json:"is_admin"`
-	DeptID  uint     `json:"dept_id"`
+	Allowed    bool     `json:"allowed"`
+	Roles      []string `json:"roles"`
+	IsAdmin    bool     `json:"is_admin"`
+	DeptID     uint     `json:"dept_id"`
+	Restricted bool     `json:"restricted,omitempty"` // 令牌为受限令牌，结果已按其访问范围裁剪
+
+	// 以下字段仅在API已废弃或已下线时返回
+	Lifecycle    string     `json:"lifecycle,omitempty"`
+	DeprecatedAt *time.Time `json:"deprecated_at,omitempty"`
+	SunsetAt     *time.Time `json:"sunset_at,omitempty"`
 }
 
 // CheckPermission 检查用户是否有权限访问API
 func (s *AuthService) CheckPermission(req *CheckPermissionRequest) (*CheckPermissionResponse, error) {
+	start := time.Now()
+
 	// 获取用户角色
 	userID := uint(0)
 	if req.UserID != "" {
@@ -227,10 +532,25 @@ func (s *AuthService) CheckPermission(req *CheckPermissionRequest) (*CheckPermis
 	if err != nil {
 		return nil, err
 	}
+	// OAuth2令牌只能使用scope中授予的角色
+	roles = FilterRolesByScope(roles, req.Scope)
+
+	decision := &entity.AuthzDecision{
+		Source:    entity.DecisionSourceCheckPermission,
+		UserID:    userID,
+		Roles:     strings.Join(roles, ","),
+		Path:      req.APIPath,
+		Method:    req.Method,
+		ClientID:  req.ClientID,
+		RequestID: req.RequestID,
+		IP:        req.ClientIP,
+	}
 
 	// 获取用户信息以获取部门ID
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil || user == nil {
+		decision.Reason = DecisionReasonUserNotFound
+		s.logDecision(req, decision, start)
 		return &CheckPermissionResponse{
 			Allowed: false,
 			Roles:   []string{},
@@ -238,6 +558,8 @@ func (s *AuthService) CheckPermission(req *CheckPermissionRequest) (*CheckPermis
 			DeptID:  0,
 		}, nil
 	}
+	decision.Username = user.Username
+	decision.DeptID = user.DeptID
 
 	// 检查是否为管理员
 	isAdmin := false
@@ -248,40 +570,98 @@ func (s *AuthService) CheckPermission(req *CheckPermissionRequest) (*CheckPermis
 		}
 	}
 
+	// 检查权限
+	allowed := false
 	// 特殊处理：对于dashboard、business、api这3个路径组，只要是本部门登录用户即可操作
-	if s.isDepartmentLevelAPI(req.APIPath) {
-		// 只要用户已登录且有有效的部门ID，就允许访问
-		if user.DeptID > 0 {
-			return &CheckPermissionResponse{
-				Allowed: true,
-				Roles:   roles,
-				IsAdmin: isAdmin,
-				DeptID:  user.DeptID,
-			}, nil
+	if s.isDepartmentLevelAPI(req.APIPath) && user.DeptID > 0 {
+		allowed = true
+		decision.Reason = DecisionReasonDepartmentLevel
+	} else {
+		decision.Reason = DecisionReasonNoPolicy
+		if len(roles) == 0 {
+			decision.Reason = DecisionReasonNoRole
+		}
+		for _, role := range roles {
+			// 使用EnforceWithDept方法，包括部门信息
+			ok, rule := s.casbin.EnforceWithDeptEx(role, req.APIPath, req.Method, user.DeptID)
+			if !ok {
+				// 也尝试使用通配符部门（部门ID为0时会使用"*"）
+				ok, rule = s.casbin.EnforceWithDeptEx(role, req.APIPath, req.Method, 0)
+			}
+			if ok {
+				allowed = true
+				decision.Reason = DecisionReasonPolicy
+				decision.MatchedRule = strings.Join(rule, ", ")
+				break
+			}
 		}
 	}
 
-	// 检查权限
-	allowed := false
-	for _, role := range roles {
-		// 使用EnforceWithDept方法，包括部门信息
-		if s.casbin.EnforceWithDept(role, req.APIPath, req.Method, user.DeptID) {
-			allowed = true
-			break
+	// 受限令牌只能访问角色权限与令牌范围的交集
+	if allowed {
+		if allowed, err = s.AllowedByRestriction(req.Restriction, req.APIPath, req.Method); err != nil {
+			return nil, err
 		}
-		// 也尝试使用通配符部门（部门ID为0时会使用"*"）
-		if s.casbin.EnforceWithDept(role, req.APIPath, req.Method, 0) {
-			allowed = true
-			break
+		if !allowed {
+			decision.Reason = DecisionReasonTokenScope
 		}
 	}
 
-	return &CheckPermissionResponse{
-		Allowed: allowed,
-		Roles:   roles,
-		IsAdmin: isAdmin,
-		DeptID:  user.DeptID,
-	}, nil
+	// 已下线、已禁用和尚未发布的API拒绝访问
+	api := s.LookupAPILifecycle(req.APIPath, req.Method)
+	if reason, _ := APIDenyReason(api); allowed && reason != "" {
+		allowed = false
+		decision.Reason = reason
+	}
+
+	decision.Allowed = allowed
+	s.logDecision(req, decision, start)
+
+	resp := &CheckPermissionResponse{
+		Allowed:    allowed,
+		Roles:      roles,
+		IsAdmin:    isAdmin,
+		DeptID:     user.DeptID,
+		Restricted: req.Restriction != nil,
+	}
+	if api != nil {
+		resp.Lifecycle = api.Lifecycle
+		resp.DeprecatedAt = api.DeprecatedAt
+		resp.SunsetAt = api.SunsetAt
+	}
+	return resp, nil
+}
+
+// SetAPILifecycle 设置API生命周期查找，未设置时不校验生命周期
+func (s *AuthService) SetAPILifecycle(lifecycle APILifecycleLookup) {
+	s.lifecycle = lifecycle
+}
+
+// LookupAPILifecycle 查找请求对应的废弃、下线、草稿或禁用的API，正常启用的API返回nil
+func (s *AuthService) LookupAPILifecycle(path, method string) *entity.API {
+	if s.lifecycle == nil {
+		return nil
+	}
+	return s.lifecycle.Lookup(path, method)
+}
+
+// SetDecisionLogger 设置授权决策日志
+func (s *AuthService) SetDecisionLogger(decisions *DecisionLogger) {
+	s.decisions = decisions
+}
+
+// RecordDecision 记录一次授权决策，未启用决策日志时忽略
+func (s *AuthService) RecordDecision(decision *entity.AuthzDecision) {
+	s.decisions.Log(decision)
+}
+
+// logDecision 记录权限校验接口的决策及耗时
+func (s *AuthService) logDecision(req *CheckPermissionRequest, decision *entity.AuthzDecision, start time.Time) {
+	if req.quiet {
+		return
+	}
+	decision.LatencyUs = time.Since(start).Microseconds()
+	s.decisions.Log(decision)
 }
 
 // isDepartmentLevelAPI 检查是否为部门级别的API路径
@@ -300,25 +680,21 @@ type LogoutResponse struct {
 	Message string `json:"message"`
 }
 
-// Logout 用户登出
-func (s *AuthService) Logout() (*LogoutResponse, error) {
-	// 在JWT无状态的情况下，服务端不需要做特殊处理
-	// 客户端只需要删除本地存储的token即可
-	// 如果需要实现token黑名单功能，可以在这里将token加入黑名单
+// Logout 用户登出，携带有效Token时终止对应的会话
+func (s *AuthService) Logout(tokenString string) (*LogoutResponse, error) {
+	if s.sessions != nil && tokenString != "" {
+		claims := &Claims{}
+		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+			return []byte(s.jwtSecret), nil
+		})
+		if err == nil && token.Valid && claims.Id != "" {
+			if err := s.sessions.Revoke(claims.Id); err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	return &LogoutResponse{
 		Message: "登出成功",
 	}, nil
 }
-
-// GenerateQRCode 生成扫码登录二维码
-func (s *AuthService) GenerateQRCode() (string, error) {
-	// 生成唯一的二维码ID
-	qrID := uuid.New().String()
-
-	// 在实际应用中，这里应该将二维码ID存储到Redis中，设置过期时间
-	// 并关联一个状态（等待扫码、已扫码、已确认等）
-
-	// 返回二维码内容，前端可以使用这个内容生成二维码图片
-	return qrID, nil
-}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"testing"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/pkg/encrypt"
+)
+
+func (r *fakeUserRepo) GetByUsername(username string) (*entity.User, error) {
+	for _, user := range r.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+// fakeLoginGuard 记录登录防护收到的结果
+type fakeLoginGuard struct {
+	failed    []string
+	succeeded []string
+}
+
+func (g *fakeLoginGuard) Check(req *LoginRequest) error {
+	return nil
+}
+
+func (g *fakeLoginGuard) Fail(req *LoginRequest, user *entity.User, reason string) {
+	g.failed = append(g.failed, req.Username+"@"+req.ClientIP)
+}
+
+func (g *fakeLoginGuard) Succeed(req *LoginRequest, user *entity.User) {
+	g.succeeded = append(g.succeeded, req.Username+"@"+req.ClientIP)
+}
+
+// fakeLoginChallenger 返回固定的挑战，记录挑战时的请求来源
+type fakeLoginChallenger struct {
+	resp    *LoginResponse
+	origins []*LoginOrigin
+}
+
+func (c *fakeLoginChallenger) Challenge(user *entity.User, origin *LoginOrigin) (*LoginResponse, error) {
+	c.origins = append(c.origins, origin)
+	return c.resp, nil
+}
+
+func TestLoginRecordsSuccessAfterChallenges(t *testing.T) {
+	hash, err := encrypt.HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		password      string
+		mfa           *LoginResponse
+		passwordReset *LoginResponse
+		wantToken     bool
+		wantSucceeded int
+		wantFailed    int
+	}{
+		{name: "无需挑战", password: "secret", wantToken: true, wantSucceeded: 1},
+		{name: "密码错误", password: "wrong", wantFailed: 1},
+		{name: "需要二次验证", password: "secret", mfa: &LoginResponse{MFARequired: true}},
+		{name: "需要修改密码", password: "secret", passwordReset: &LoginResponse{PasswordChangeRequired: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeUserRepo{users: map[uint]*entity.User{1: {ID: 1, Username: "alice", Password: hash, Status: 1}}}
+			s := NewAuthService(users, nil, nil, nil, "test")
+			guard := &fakeLoginGuard{}
+			mfa := &fakeLoginChallenger{resp: tt.mfa}
+			passwordReset := &fakeLoginChallenger{resp: tt.passwordReset}
+			s.SetLoginGuard(guard)
+			s.SetLoginChallenger(mfa)
+			s.SetPasswordChallenger(passwordReset)
+
+			resp, err := s.Login(&LoginRequest{Username: "alice", Password: tt.password, ClientIP: "10.0.0.1"})
+			if (err != nil) != (tt.wantFailed > 0) {
+				t.Fatalf("Login() error = %v", err)
+			}
+			if err == nil && (resp.Token != "") != tt.wantToken {
+				t.Errorf("Login() token = %q, want token %v", resp.Token, tt.wantToken)
+			}
+			if len(guard.succeeded) != tt.wantSucceeded || len(guard.failed) != tt.wantFailed {
+				t.Errorf("succeeded = %v failed = %v, want %d %d", guard.succeeded, guard.failed, tt.wantSucceeded, tt.wantFailed)
+			}
+			for _, origin := range append(mfa.origins, passwordReset.origins...) {
+				if origin.Username != "alice" || origin.ClientIP != "10.0.0.1" {
+					t.Errorf("挑战的请求来源 = %+v", origin)
+				}
+			}
+		})
+	}
+}
+
+func TestLoginChallengeResults(t *testing.T) {
+	user := &entity.User{ID: 1, Username: "alice", Status: 1}
+	origin := &LoginOrigin{Username: "Alice", ClientIP: "10.0.0.1"}
+
+	s := NewAuthService(nil, nil, nil, nil, "test")
+	guard := &fakeLoginGuard{}
+	s.SetLoginGuard(guard)
+
+	// 二次验证失败计入该次登录的用户名和IP
+	s.loginFailed(user, origin, "验证码错误")
+	// 升级前签发的凭证没有保存来源时按用户名计数
+	s.loginFailed(user, nil, "验证码错误")
+	if want := []string{"Alice@10.0.0.1", "alice@"}; len(guard.failed) != 2 || guard.failed[0] != want[0] || guard.failed[1] != want[1] {
+		t.Errorf("failed = %v, want %v", guard.failed, want)
+	}
+
+	resp, err := s.finishPasswordLogin(user, origin)
+	if err != nil || resp.Token == "" {
+		t.Fatalf("finishPasswordLogin() = (%+v, %v)", resp, err)
+	}
+	if len(guard.succeeded) != 1 || guard.succeeded[0] != "Alice@10.0.0.1" {
+		t.Errorf("succeeded = %v, want [Alice@10.0.0.1]", guard.succeeded)
+	}
+}
+
+func TestExternalLoginRequiresMFA(t *testing.T) {
+	tests := []struct {
+		name          string
+		mfa           *LoginResponse
+		wantToken     bool
+		wantSucceeded int
+	}{
+		{name: "无需二次验证", wantToken: true, wantSucceeded: 1},
+		{name: "需要二次验证", mfa: &LoginResponse{MFARequired: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &entity.User{ID: 1, Username: "alice", Status: 1}
+			s := NewAuthService(nil, nil, nil, nil, "test")
+			guard := &fakeLoginGuard{}
+			mfa := &fakeLoginChallenger{resp: tt.mfa}
+			passwordReset := &fakeLoginChallenger{resp: &LoginResponse{PasswordChangeRequired: true}}
+			s.SetLoginGuard(guard)
+			s.SetLoginChallenger(mfa)
+			s.SetPasswordChallenger(passwordReset)
+
+			resp, err := s.externalLogin(user, &LoginOrigin{ClientIP: "10.0.0.1"})
+			if err != nil {
+				t.Fatalf("externalLogin() error = %v", err)
+			}
+			if (resp.Token != "") != tt.wantToken || resp.MFARequired == tt.wantToken {
+				t.Errorf("externalLogin() = %+v, want token %v", resp, tt.wantToken)
+			}
+			if len(guard.succeeded) != tt.wantSucceeded {
+				t.Errorf("succeeded = %v, want %d", guard.succeeded, tt.wantSucceeded)
+			}
+			if len(mfa.origins) != 1 || !mfa.origins[0].External || mfa.origins[0].Username != "alice" {
+				t.Errorf("二次验证挑战的请求来源 = %+v", mfa.origins)
+			}
+			// 外部身份登录不检查密码是否需要修改
+			if len(passwordReset.origins) != 0 {
+				t.Errorf("外部登录不应触发修改密码挑战: %+v", passwordReset.origins)
+			}
+		})
+	}
+}
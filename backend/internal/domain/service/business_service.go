@@ -12,6 +12,7 @@ import (
 type BusinessService struct {
 	businessRepo repository.BusinessRepository
 	deptRepo     repository.DepartmentRepository
+	deleter      EntityDeleter
 }
 
 // NewBusinessService 创建业务线服务
@@ -22,6 +23,11 @@ func NewBusinessService(businessRepo repository.BusinessRepository, deptRepo rep
 	}
 }
 
+// SetDeleter 设置级联删除，未设置时只删除业务线本身
+func (s *BusinessService) SetDeleter(deleter EntityDeleter) {
+	s.deleter = deleter
+}
+
 // CreateBusinessRequest 创建业务线请求
 type CreateBusinessRequest struct {
 	Name        string `json:"name" binding:"required"`
@@ -91,6 +97,10 @@ func (s *BusinessService) UpdateBusiness(req *UpdateBusinessRequest) (*entity.Bu
 		return nil, errors.New("部门不存在")
 	}
 
+	if business.Code == entity.SystemBusinessCode && req.Code != business.Code {
+		return nil, errors.New("系统业务线的编码不能修改")
+	}
+
 	// 检查业务线编码是否已存在（排除自身）
 	existingBusiness, _ := s.businessRepo.GetByCode(req.Code)
 	if existingBusiness != nil && existingBusiness.ID != req.ID {
@@ -117,10 +127,18 @@ func (s *BusinessService) UpdateBusiness(req *UpdateBusinessRequest) (*entity.Bu
 // DeleteBusiness 删除业务线
 func (s *BusinessService) DeleteBusiness(id uint) error {
 	// 检查业务线是否存在
-	_, err := s.businessRepo.GetByID(id)
-	if err != nil {
+	business, err := s.businessRepo.GetByID(id)
+	if err != nil || business == nil {
 		return errors.New("业务线不存在")
 	}
+	if business.Code == entity.SystemBusinessCode {
+		return errors.New("系统业务线不能删除")
+	}
+
+	// 按级联和限制规则删除，业务线下的API一并删除
+	if s.deleter != nil {
+		return s.deleter.Delete(DeleteTargetBusiness, id)
+	}
 
 	// 删除业务线
 	return s.businessRepo.Delete(id)
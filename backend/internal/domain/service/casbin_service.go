@@ -2,6 +2,7 @@ package service
 
 import (
 	"errors"
+	"strings"
 
 	"gorm.io/gorm"
 
@@ -228,6 +229,35 @@ func (s *CasbinService) BatchDeletePolicy(req *BatchDeletePolicyRequest) error {
 	return s.enforcer.LoadPolicy()
 }
 
+// GetPolicyByID 根据ID获取策略，不存在时返回nil
+func (s *CasbinService) GetPolicyByID(id int) (*PolicyRule, error) {
+	var rules []*PolicyRule
+	if err := s.db.Table("casbin_rule").Where("id = ?", id).Limit(1).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return rules[0], nil
+}
+
+// ListPoliciesByPathPrefix 获取资源路径以prefix开头的p策略
+func (s *CasbinService) ListPoliciesByPathPrefix(prefix string) ([]*PolicyRule, error) {
+	var rules []*PolicyRule
+	if err := s.db.Table("casbin_rule").Where("ptype = ? AND v1 LIKE ?", "p", prefix+"%").Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	// LIKE中的_和%是通配符，再按前缀精确过滤
+	matched := rules[:0]
+	for _, rule := range rules {
+		if strings.HasPrefix(rule.V1, prefix) {
+			matched = append(matched, rule)
+		}
+	}
+	return matched, nil
+}
+
 // ReloadPolicy 重新加载策略
 func (s *CasbinService) ReloadPolicy() error {
 	return s.enforcer.LoadPolicy()
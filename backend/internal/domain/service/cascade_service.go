@@ -0,0 +1,650 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/internal/pkg/logger"
+	"mcprapi/backend/pkg/casbinx"
+)
+
+// 删除对象类型
+const (
+	DeleteTargetDepartment = "department"
+	DeleteTargetBusiness   = "business"
+	DeleteTargetRole       = "role"
+	DeleteTargetAPI        = "api"
+)
+
+// CascadeConfig 级联删除配置
+type CascadeConfig struct {
+	OrphanCleanupInterval time.Duration `mapstructure:"orphan_cleanup_interval"` // 定期检查孤立数据的间隔，0表示只支持手动检查和清理
+	OrphanCleanupDelete   bool          `mapstructure:"orphan_cleanup_delete"`   // 定期检查时是否删除孤立数据，默认只记录日志
+}
+
+// EntityDeleter 按级联和限制规则删除对象，各服务的删除方法委托给它
+type EntityDeleter interface {
+	Delete(target string, id uint) error
+}
+
+// DeleteImpactItem 被级联删除的对象
+type DeleteImpactItem struct {
+	ID   uint   `json:"id"`
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// DeleteImpactPolicy 被删除的策略
+type DeleteImpactPolicy struct {
+	ID     uint     `json:"id"`
+	Values []string `json:"values"`
+}
+
+// DeleteImpact 删除对象的影响
+//
+// 各对象的删除规则：
+//   - 部门：存在下级部门、用户、业务线或其他角色时限制删除（可先合并部门）；级联删除部门管理员角色、部门维度的策略和授予记录
+//   - 业务线：系统业务线或存在绑定的API组时限制删除；级联删除业务线下的API
+//   - 角色：系统管理员角色限制删除；级联删除用户角色关联、角色的策略和用户绑定、API组和权限模板的授予记录
+//   - API：系统API限制删除；级联删除API组成员，没有其他API使用同一路径和方法时删除对应的策略
+type DeleteImpact struct {
+	Target         string                `json:"target"`
+	ID             uint                  `json:"id"`
+	Name           string                `json:"name"`
+	Deletable      bool                  `json:"deletable"`
+	Blockers       []string              `json:"blockers"`        // 限制删除的原因，需先处理
+	Roles          []*DeleteImpactItem   `json:"roles"`           // 级联删除的角色
+	APIs           []*DeleteImpactItem   `json:"apis"`            // 级联删除的API
+	UserRoles      int64                 `json:"user_roles"`      // 删除的用户角色关联数
+	GroupMembers   int64                 `json:"group_members"`   // 删除的API组成员数
+	Grants         int64                 `json:"grants"`          // 删除的API组和权限模板授予记录数
+	LedgerPolicies int64                 `json:"ledger_policies"` // 删除的API组和权限模板策略记录数
+	Policies       []*DeleteImpactPolicy `json:"policies"`        // 删除的策略
+}
+
+// OrphanReport 孤立数据
+type OrphanReport struct {
+	UserRoles      int                   `json:"user_roles"`      // 用户或角色不存在的用户角色关联
+	GroupMembers   int                   `json:"group_members"`   // API组或API不存在的API组成员
+	RoleGroups     int                   `json:"role_groups"`     // 角色或API组不存在的API组授予记录
+	GroupPolicies  int                   `json:"group_policies"`  // 角色不存在的API组策略记录
+	RoleBundles    int                   `json:"role_bundles"`    // 角色或权限模板不存在的权限模板应用记录
+	BundlePolicies int                   `json:"bundle_policies"` // 角色或权限模板不存在的权限模板策略记录
+	APIs           []*DeleteImpactItem   `json:"apis"`            // 业务线不存在的API
+	Policies       []*DeleteImpactPolicy `json:"policies"`        // 主体、角色或部门不存在的策略，以及孤立API的策略
+	Cleaned        bool                  `json:"cleaned"`         // 是否已清理
+}
+
+// Total 孤立记录总数
+func (r *OrphanReport) Total() int {
+	return r.UserRoles + r.GroupMembers + r.RoleGroups + r.GroupPolicies + r.RoleBundles + r.BundlePolicies +
+		len(r.APIs) + len(r.Policies)
+}
+
+// CascadeService 级联删除服务，定义各对象删除时的级联和限制规则，并清理已有的孤立数据
+type CascadeService struct {
+	cascadeRepo  repository.CascadeRepository
+	deptRepo     repository.DepartmentRepository
+	businessRepo repository.BusinessRepository
+	roleRepo     repository.RoleRepository
+	apiRepo      repository.APIRepository
+	userRepo     repository.UserRepository
+	groupRepo    repository.APIGroupRepository
+	enforcer     *casbinx.Enforcer
+	cfg          CascadeConfig
+	audit        AuditRecorder
+	logger       *logger.Logger
+
+	mu sync.Mutex // 删除和清理串行执行，避免计划基于过期的数据
+}
+
+// NewCascadeService 创建级联删除服务
+func NewCascadeService(cfg CascadeConfig, cascadeRepo repository.CascadeRepository, deptRepo repository.DepartmentRepository,
+	businessRepo repository.BusinessRepository, roleRepo repository.RoleRepository, apiRepo repository.APIRepository,
+	userRepo repository.UserRepository, groupRepo repository.APIGroupRepository, enforcer *casbinx.Enforcer, logger *logger.Logger) *CascadeService {
+	return &CascadeService{
+		cascadeRepo:  cascadeRepo,
+		deptRepo:     deptRepo,
+		businessRepo: businessRepo,
+		roleRepo:     roleRepo,
+		apiRepo:      apiRepo,
+		userRepo:     userRepo,
+		groupRepo:    groupRepo,
+		enforcer:     enforcer,
+		cfg:          cfg,
+		logger:       logger,
+	}
+}
+
+// SetAuditRecorder 设置审计记录，定期清理删除的孤立数据写入审计日志
+func (s *CascadeService) SetAuditRecorder(audit AuditRecorder) {
+	s.audit = audit
+}
+
+// Impact 获取删除对象的影响，不做任何修改
+func (s *CascadeService) Impact(target string, id uint) (*DeleteImpact, error) {
+	impact, _, err := s.plan(target, id)
+	return impact, err
+}
+
+// Delete 按级联和限制规则在一个事务中删除对象，并重新加载策略
+func (s *CascadeService) Delete(target string, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	impact, plan, err := s.plan(target, id)
+	if err != nil {
+		return err
+	}
+	if !impact.Deletable {
+		return fmt.Errorf("无法删除%s: %s", impact.Name, strings.Join(impact.Blockers, "；"))
+	}
+	if err := s.cascadeRepo.Delete(plan); err != nil {
+		return fmt.Errorf("删除失败: %v", err)
+	}
+	if len(plan.RuleIDs) > 0 {
+		// 同步策略到内存（安全方式）
+		if err := s.enforcer.SyncPolicyToMemory(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Orphans 查找孤立数据，不做任何修改
+func (s *CascadeService) Orphans() (*OrphanReport, error) {
+	report, _, err := s.planOrphans()
+	return report, err
+}
+
+// CleanupOrphans 在一个事务中清理孤立数据，并重新加载策略
+func (s *CascadeService) CleanupOrphans() (*OrphanReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report, plan, err := s.planOrphans()
+	if err != nil {
+		return nil, err
+	}
+	if report.Total() == 0 {
+		return report, nil
+	}
+	if err := s.cascadeRepo.Delete(plan); err != nil {
+		return nil, fmt.Errorf("清理孤立数据失败: %v", err)
+	}
+	report.Cleaned = true
+	if len(plan.RuleIDs) > 0 {
+		if err := s.enforcer.SyncPolicyToMemory(); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// Start 启动孤立数据定期检查，显式开启orphan_cleanup_delete后才会删除
+func (s *CascadeService) Start(ctx context.Context) {
+	if s.cfg.OrphanCleanupInterval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(s.cfg.OrphanCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !s.cfg.OrphanCleanupDelete {
+					s.reportOrphans()
+					continue
+				}
+				report, err := s.CleanupOrphans()
+				if err != nil {
+					s.logger.Error("清理孤立数据失败: %v", err)
+					s.record(nil, err)
+					continue
+				}
+				if report.Cleaned {
+					s.logger.Info("清理孤立数据%d条", report.Total())
+					s.record(report, nil)
+				}
+			}
+		}
+	}()
+}
+
+// reportOrphans 只检查孤立数据并记录日志，由管理员确认后手动清理
+func (s *CascadeService) reportOrphans() {
+	report, err := s.Orphans()
+	if err != nil {
+		s.logger.Error("检查孤立数据失败: %v", err)
+		return
+	}
+	if report.Total() > 0 {
+		s.logger.Warn("发现孤立数据%d条，未开启定期删除，请确认后手动清理", report.Total())
+	}
+}
+
+// record 记录定期清理的结果
+func (s *CascadeService) record(report *OrphanReport, err error) {
+	if s.audit == nil {
+		return
+	}
+	event := &AuditEvent{Action: "maintenance.cleanup_orphans", TargetType: AuditTargetCasbinRule, Err: err}
+	if report != nil {
+		event.Detail = report
+	}
+	s.audit.Record(event)
+}
+
+// plan 生成删除计划
+func (s *CascadeService) plan(target string, id uint) (*DeleteImpact, *repository.DeletePlan, error) {
+	p, err := s.newPlanner()
+	if err != nil {
+		return nil, nil, err
+	}
+	p.impact.Target, p.impact.ID = target, id
+
+	switch target {
+	case DeleteTargetDepartment:
+		err = s.planDepartment(p, id)
+	case DeleteTargetBusiness:
+		err = s.planBusiness(p, id)
+	case DeleteTargetRole:
+		err = s.planRole(p, id)
+	case DeleteTargetAPI:
+		err = s.planAPI(p, id)
+	default:
+		err = fmt.Errorf("不支持的删除对象: %s", target)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dependents, err := s.cascadeRepo.CountDependents(p.plan)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.impact.UserRoles = dependents.UserRoles
+	p.impact.GroupMembers = dependents.GroupMembers
+	p.impact.Grants = dependents.Grants
+	p.impact.LedgerPolicies = dependents.LedgerPolicies
+	p.impact.Policies = p.policies
+	p.impact.Deletable = len(p.impact.Blockers) == 0
+	return p.impact, p.plan, nil
+}
+
+// planDepartment 部门：存在下级部门、用户、业务线或其他角色时限制删除，级联删除部门管理员角色和部门维度的策略
+func (s *CascadeService) planDepartment(p *deletePlanner, id uint) error {
+	dept, err := s.deptRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if dept == nil {
+		return errors.New("部门不存在")
+	}
+	p.impact.Name = dept.Name
+
+	children, err := s.deptRepo.List(id)
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		p.block(fmt.Sprintf("存在%d个下级部门", len(children)))
+	}
+	_, users, err := s.userRepo.ListByDept(1, 1, "", id)
+	if err != nil {
+		return err
+	}
+	if users > 0 {
+		p.block(fmt.Sprintf("存在%d个用户", users))
+	}
+	businesses, err := s.businessRepo.List(id)
+	if err != nil {
+		return err
+	}
+	if len(businesses) > 0 {
+		p.block(fmt.Sprintf("存在%d个业务线", len(businesses)))
+	}
+
+	// 部门管理员角色随部门删除，其他角色需先处理
+	adminCode := deptAdminRoleCode(id)
+	admin, err := s.roleRepo.GetByCode(adminCode)
+	if err != nil {
+		return err
+	}
+	if admin != nil {
+		p.addRole(admin)
+	}
+	roles, err := s.roleRepo.ListByDept(id)
+	if err != nil {
+		return err
+	}
+	others := 0
+	for _, role := range roles {
+		if role.Code != adminCode {
+			others++
+		}
+	}
+	if others > 0 {
+		p.block(fmt.Sprintf("存在%d个角色", others))
+	}
+
+	dimension := strconv.FormatUint(uint64(id), 10)
+	deptPath := "/api/v1/dept/" + dimension
+	p.plan.DepartmentIDs = append(p.plan.DepartmentIDs, id)
+	p.plan.Depts = append(p.plan.Depts, dimension)
+	p.addRules(func(rule *repository.CasbinRule) bool {
+		return rule.PType == "p" &&
+			(rule.V3 == dimension || rule.V1 == deptPath || strings.HasPrefix(rule.V1, deptPath+"/"))
+	})
+	return nil
+}
+
+// planBusiness 业务线：系统业务线或存在绑定的API组时限制删除，级联删除业务线下的API
+func (s *CascadeService) planBusiness(p *deletePlanner, id uint) error {
+	business, err := s.businessRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if business == nil {
+		return errors.New("业务线不存在")
+	}
+	p.impact.Name = business.Name
+	if business.Code == entity.SystemBusinessCode {
+		p.block("系统业务线不能删除")
+	}
+
+	groups, err := s.groupRepo.List(id)
+	if err != nil {
+		return err
+	}
+	if len(groups) > 0 {
+		codes := make([]string, 0, len(groups))
+		for _, group := range groups {
+			codes = append(codes, group.Code)
+		}
+		p.block(fmt.Sprintf("存在绑定该业务线的API组: %s", strings.Join(codes, ", ")))
+	}
+
+	apis, err := s.apiRepo.ListByBusiness(id)
+	if err != nil {
+		return err
+	}
+	if err := p.addAPIs(apis); err != nil {
+		return err
+	}
+	p.plan.BusinessIDs = append(p.plan.BusinessIDs, id)
+	return nil
+}
+
+// planRole 角色：系统管理员角色限制删除，级联删除用户关联、策略和授予记录
+func (s *CascadeService) planRole(p *deletePlanner, id uint) error {
+	role, err := s.roleRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return errors.New("角色不存在")
+	}
+	p.impact.Name = role.Name
+	if role.Code == "admin" {
+		p.block("系统管理员角色不能删除")
+	}
+	p.addRole(role)
+	return nil
+}
+
+// planAPI API：系统API限制删除，级联删除API组成员和对应的策略
+func (s *CascadeService) planAPI(p *deletePlanner, id uint) error {
+	api, err := s.apiRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if api == nil {
+		return errors.New("API不存在")
+	}
+	p.impact.Name = api.Name
+	business, err := s.businessRepo.GetByID(api.BusinessID)
+	if err != nil {
+		return err
+	}
+	if business != nil && business.Code == entity.SystemBusinessCode {
+		p.block("系统API由服务启动时自动登记，不能删除")
+	}
+	return p.addAPIs([]*entity.API{api})
+}
+
+// planOrphans 生成孤立数据清理计划
+func (s *CascadeService) planOrphans() (*OrphanReport, *repository.DeletePlan, error) {
+	orphans, err := s.cascadeRepo.FindOrphans()
+	if err != nil {
+		return nil, nil, err
+	}
+	p, err := s.newPlanner()
+	if err != nil {
+		return nil, nil, err
+	}
+	p.plan.Orphans = orphans
+
+	// 业务线不存在的API按API删除规则处理
+	if len(orphans.APIIDs) > 0 {
+		apis, err := p.listAPIs()
+		if err != nil {
+			return nil, nil, err
+		}
+		var orphanAPIs []*entity.API
+		for _, api := range apis {
+			if containsUint(orphans.APIIDs, api.ID) {
+				orphanAPIs = append(orphanAPIs, api)
+			}
+		}
+		if err := p.addAPIs(orphanAPIs); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// 主体、角色或部门不存在的策略
+	isOrphanRule, err := s.orphanRuleMatcher()
+	if err != nil {
+		return nil, nil, err
+	}
+	p.addRules(isOrphanRule)
+
+	report := &OrphanReport{
+		UserRoles:      len(orphans.UserRoleIDs),
+		GroupMembers:   len(orphans.GroupMemberIDs),
+		RoleGroups:     len(orphans.RoleGroupIDs),
+		GroupPolicies:  len(orphans.GroupPolicyIDs),
+		RoleBundles:    len(orphans.RoleBundleIDs),
+		BundlePolicies: len(orphans.BundlePolicyIDs),
+		APIs:           p.impact.APIs,
+		Policies:       p.policies,
+	}
+	return report, p.plan, nil
+}
+
+// orphanRuleMatcher 返回判断策略是否孤立的函数：
+// p策略的主体不是现有的角色或用户，或部门维度指向不存在的部门；g策略的角色不存在，或用户（user_<id>）不存在
+func (s *CascadeService) orphanRuleMatcher() (func(rule *repository.CasbinRule) bool, error) {
+	roles, err := s.roleRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.userRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	depts, err := s.deptRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+
+	roleCodes := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		roleCodes[role.Code] = true
+	}
+	usernames := make(map[string]bool, len(users))
+	userIDs := make(map[uint64]bool, len(users))
+	for _, user := range users {
+		usernames[user.Username] = true
+		userIDs[uint64(user.ID)] = true
+	}
+	deptIDs := make(map[string]bool, len(depts))
+	for _, dept := range depts {
+		deptIDs[strconv.FormatUint(uint64(dept.ID), 10)] = true
+	}
+
+	// user_<id>形式的主体，返回用户是否存在；其他形式返回ok为false
+	userSubject := func(subject string) (exists, ok bool) {
+		if !strings.HasPrefix(subject, "user_") {
+			return false, false
+		}
+		id, err := strconv.ParseUint(strings.TrimPrefix(subject, "user_"), 10, 32)
+		if err != nil {
+			return false, false
+		}
+		return userIDs[id], true
+	}
+
+	return func(rule *repository.CasbinRule) bool {
+		switch rule.PType {
+		case "p":
+			if exists, ok := userSubject(rule.V0); ok {
+				if !exists {
+					return true
+				}
+			} else if !roleCodes[rule.V0] && !usernames[rule.V0] {
+				return true
+			}
+			if _, err := strconv.ParseUint(rule.V3, 10, 32); err == nil && !deptIDs[rule.V3] {
+				return true
+			}
+		case "g":
+			if !roleCodes[rule.V1] {
+				return true
+			}
+			if exists, ok := userSubject(rule.V0); ok && !exists {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// newPlanner 创建删除计划生成器
+func (s *CascadeService) newPlanner() (*deletePlanner, error) {
+	rules, err := s.cascadeRepo.ListRules()
+	if err != nil {
+		return nil, err
+	}
+	return &deletePlanner{
+		apiRepo: s.apiRepo,
+		rules:   rules,
+		deleted: make(map[uint]bool),
+		plan:    &repository.DeletePlan{},
+		impact: &DeleteImpact{
+			Blockers: []string{},
+			Roles:    []*DeleteImpactItem{},
+			APIs:     []*DeleteImpactItem{},
+		},
+		policies: []*DeleteImpactPolicy{},
+	}, nil
+}
+
+// deletePlanner 删除计划生成器，汇总级联删除的对象和策略
+type deletePlanner struct {
+	apiRepo  repository.APIRepository
+	rules    []*repository.CasbinRule
+	apis     []*entity.API // 所有API，按需加载
+	deleted  map[uint]bool // 已计入计划的策略
+	plan     *repository.DeletePlan
+	impact   *DeleteImpact
+	policies []*DeleteImpactPolicy
+}
+
+// block 记录限制删除的原因
+func (p *deletePlanner) block(reason string) {
+	p.impact.Blockers = append(p.impact.Blockers, reason)
+}
+
+// addRules 将命中的策略计入计划
+func (p *deletePlanner) addRules(match func(rule *repository.CasbinRule) bool) {
+	for _, rule := range p.rules {
+		if p.deleted[rule.ID] || !match(rule) {
+			continue
+		}
+		p.deleted[rule.ID] = true
+		p.plan.RuleIDs = append(p.plan.RuleIDs, rule.ID)
+		p.policies = append(p.policies, &DeleteImpactPolicy{ID: rule.ID, Values: casbinRuleValues(rule)})
+	}
+}
+
+// addRole 级联删除角色：角色的策略、以角色为主体或被绑定的g策略
+func (p *deletePlanner) addRole(role *entity.Role) {
+	if containsUint(p.plan.RoleIDs, role.ID) {
+		return
+	}
+	p.plan.RoleIDs = append(p.plan.RoleIDs, role.ID)
+	p.impact.Roles = append(p.impact.Roles, &DeleteImpactItem{ID: role.ID, Code: role.Code, Name: role.Name})
+	p.addRules(func(rule *repository.CasbinRule) bool {
+		return rule.V0 == role.Code || (rule.PType == "g" && rule.V1 == role.Code)
+	})
+}
+
+// addAPIs 级联删除API，没有其他API使用同一路径和方法时删除对应的策略
+func (p *deletePlanner) addAPIs(apis []*entity.API) error {
+	if len(apis) == 0 {
+		return nil
+	}
+	for _, api := range apis {
+		if containsUint(p.plan.APIIDs, api.ID) {
+			continue
+		}
+		p.plan.APIIDs = append(p.plan.APIIDs, api.ID)
+		p.impact.APIs = append(p.impact.APIs, &DeleteImpactItem{ID: api.ID, Code: api.Method + " " + api.Path, Name: api.Name})
+	}
+
+	all, err := p.listAPIs()
+	if err != nil {
+		return err
+	}
+	remaining := make(map[repository.Endpoint]bool, len(all))
+	for _, api := range all {
+		if !containsUint(p.plan.APIIDs, api.ID) {
+			remaining[repository.Endpoint{Path: api.Path, Method: api.Method}] = true
+		}
+	}
+	for _, api := range apis {
+		endpoint := repository.Endpoint{Path: api.Path, Method: api.Method}
+		if remaining[endpoint] {
+			continue
+		}
+		remaining[endpoint] = true // 同一路径和方法只处理一次
+		p.plan.Endpoints = append(p.plan.Endpoints, &repository.Endpoint{Path: api.Path, Method: api.Method})
+		p.addRules(func(rule *repository.CasbinRule) bool {
+			return rule.PType == "p" && rule.V1 == api.Path && rule.V2 == api.Method
+		})
+	}
+	return nil
+}
+
+// listAPIs 获取所有API
+func (p *deletePlanner) listAPIs() ([]*entity.API, error) {
+	if p.apis == nil {
+		apis, err := p.apiRepo.ListAll()
+		if err != nil {
+			return nil, err
+		}
+		p.apis = apis
+	}
+	return p.apis, nil
+}
@@ -0,0 +1,384 @@
+package service
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+func (r *fakeDeptRepo) List(parentID uint) ([]*entity.Department, error) {
+	var depts []*entity.Department
+	for _, dept := range r.depts {
+		if dept.ParentID == parentID {
+			depts = append(depts, dept)
+		}
+	}
+	return depts, nil
+}
+
+func (r *fakeDeptRepo) ListAll() ([]*entity.Department, error) {
+	var depts []*entity.Department
+	for _, dept := range r.depts {
+		depts = append(depts, dept)
+	}
+	return depts, nil
+}
+
+func (r *fakeRoleRepo) GetByID(id uint) (*entity.Role, error) {
+	for _, role := range r.roles {
+		if role.ID == id {
+			return role, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRoleRepo) ListByDept(deptID uint) ([]*entity.Role, error) {
+	var roles []*entity.Role
+	for _, role := range r.roles {
+		if role.DeptID == deptID {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+func (r *fakeRoleRepo) ListAll() ([]*entity.Role, error) {
+	return r.roles, nil
+}
+
+func (r *fakeUserRepo) ListByDept(page, pageSize int, query string, deptID uint) ([]*entity.User, int64, error) {
+	var users []*entity.User
+	for _, user := range r.users {
+		if user.DeptID == deptID {
+			users = append(users, user)
+		}
+	}
+	return users, int64(len(users)), nil
+}
+
+func (r *fakeUserRepo) ListAll() ([]*entity.User, error) {
+	var users []*entity.User
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (r *fakeBusinessRepo) List(deptID uint) ([]*entity.Business, error) {
+	var businesses []*entity.Business
+	for _, business := range r.businesses {
+		if business.DeptID == deptID {
+			businesses = append(businesses, business)
+		}
+	}
+	return businesses, nil
+}
+
+func (r *fakeAPIRepo) GetByID(id uint) (*entity.API, error) {
+	for _, apis := range r.byBusiness {
+		for _, api := range apis {
+			if api.ID == id {
+				return api, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// ListAll 按ID排序，使计划中的顺序稳定
+func (r *fakeAPIRepo) ListAll() ([]*entity.API, error) {
+	var all []*entity.API
+	for _, apis := range r.byBusiness {
+		all = append(all, apis...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	return all, nil
+}
+
+// fakeAPIGroupRepo 内存中的API组仓库，只实现测试用到的方法
+type fakeAPIGroupRepo struct {
+	repository.APIGroupRepository
+	byBusiness map[uint][]*entity.APIGroup
+}
+
+func (r *fakeAPIGroupRepo) List(businessID uint) ([]*entity.APIGroup, error) {
+	return r.byBusiness[businessID], nil
+}
+
+// fakeCascadeRepo 内存中的级联删除仓库，记录执行的删除计划
+type fakeCascadeRepo struct {
+	rules   []*repository.CasbinRule
+	orphans *repository.OrphanRecords
+	deleted []*repository.DeletePlan
+}
+
+func (r *fakeCascadeRepo) ListRules() ([]*repository.CasbinRule, error) {
+	return r.rules, nil
+}
+
+func (r *fakeCascadeRepo) CountDependents(plan *repository.DeletePlan) (*repository.DeleteDependents, error) {
+	return &repository.DeleteDependents{UserRoles: int64(len(plan.RoleIDs))}, nil
+}
+
+func (r *fakeCascadeRepo) Delete(plan *repository.DeletePlan) error {
+	r.deleted = append(r.deleted, plan)
+	return nil
+}
+
+func (r *fakeCascadeRepo) FindOrphans() (*repository.OrphanRecords, error) {
+	if r.orphans == nil {
+		return &repository.OrphanRecords{}, nil
+	}
+	return r.orphans, nil
+}
+
+// newTestCascade 部门5有下级部门7、用户、业务线和角色，部门8只有管理员角色；
+// 业务线1为系统业务线，业务线2绑定了API组，API 101和103的路径和方法相同，API 104所属的业务线99不存在
+func newTestCascade(repo *fakeCascadeRepo) *CascadeService {
+	repo.rules = []*repository.CasbinRule{
+		testRule(1, "p", "dev", "/biz/orders", "GET", "5", "allow"),
+		testRule(2, "p", "dev", "/biz/orders/:id", "DELETE", "*", "allow"),
+		testRule(3, "g", "user_1", "dev"),
+		testRule(4, "p", "dept_admin_8", "/api/v1/dept/8/*", "*", "8", "allow"),
+		testRule(5, "g", "user_2", "dept_admin_8"),
+		testRule(6, "p", "ops", "/biz/legacy", "GET", "*", "allow"),
+		testRule(7, "p", "dev", "/biz/legacy", "GET", "*", "allow"),
+		testRule(8, "g", "user_99", "dev"),
+		testRule(9, "p", "dev", "/biz/stats", "GET", "42", "allow"),
+		testRule(10, "p", "alice", "/biz/profile", "GET", "*", "allow"),
+		testRule(11, "p", "user_1", "/biz/profile", "GET", "*", "allow"),
+		testRule(12, "g", "user_1", "missing"),
+		testRule(13, "p", "dev", "/api/v1/dept/8", "GET", "*", "allow"),
+	}
+	depts := &fakeDeptRepo{depts: map[uint]*entity.Department{
+		5: {ID: 5, Name: "研发"},
+		6: {ID: 6, Name: "平台"},
+		7: {ID: 7, Name: "研发一组", ParentID: 5},
+		8: {ID: 8, Name: "空部门"},
+	}}
+	users := &fakeUserRepo{users: map[uint]*entity.User{
+		1: {ID: 1, Username: "alice", DeptID: 5},
+		2: {ID: 2, Username: "bob", DeptID: 6},
+	}}
+	roles := &fakeRoleRepo{roles: []*entity.Role{
+		{ID: 10, Code: "admin", Name: "系统管理员"},
+		{ID: 11, Code: "dept_admin_8", Name: "空部门管理员", DeptID: 8},
+		{ID: 12, Code: "dev", Name: "开发", DeptID: 5},
+		{ID: 13, Code: "dept_admin_5", Name: "研发部门管理员", DeptID: 5},
+	}}
+	businesses := &fakeBusinessRepo{businesses: map[uint]*entity.Business{
+		1: {ID: 1, Code: entity.SystemBusinessCode, Name: "系统", DeptID: 6},
+		2: {ID: 2, Code: "orders", Name: "订单", DeptID: 5},
+		3: {ID: 3, Code: "archive", Name: "归档", DeptID: 6},
+	}}
+	apis := &fakeAPIRepo{byBusiness: map[uint][]*entity.API{
+		1:  {{ID: 100, BusinessID: 1, Path: "/api/v1/users", Method: "GET"}},
+		2:  {{ID: 101, BusinessID: 2, Path: "/biz/orders", Method: "GET"}, {ID: 102, BusinessID: 2, Path: "/biz/orders/:id", Method: "DELETE"}},
+		3:  {{ID: 103, BusinessID: 3, Path: "/biz/orders", Method: "GET"}},
+		99: {{ID: 104, BusinessID: 99, Path: "/biz/legacy", Method: "GET"}},
+	}}
+	groups := &fakeAPIGroupRepo{byBusiness: map[uint][]*entity.APIGroup{2: {{Code: "order-read"}}}}
+	return NewCascadeService(CascadeConfig{}, repo, depts, businesses, roles, apis, users, groups, nil, nil)
+}
+
+func TestCascadePlan(t *testing.T) {
+	tests := []struct {
+		name          string
+		target        string
+		id            uint
+		wantErr       bool
+		wantBlockers  []string
+		wantRoles     []uint
+		wantAPIs      []uint
+		wantRules     []uint
+		wantEndpoints []repository.Endpoint
+	}{
+		{
+			name:      "空部门级联删除管理员角色和部门策略",
+			target:    DeleteTargetDepartment,
+			id:        8,
+			wantRoles: []uint{11},
+			wantRules: []uint{4, 5, 13},
+		},
+		{
+			name:         "部门存在下级部门、用户、业务线和其他角色",
+			target:       DeleteTargetDepartment,
+			id:           5,
+			wantBlockers: []string{"1个下级部门", "1个用户", "1个业务线", "1个角色"},
+			wantRoles:    []uint{13},
+			wantRules:    []uint{1},
+		},
+		{name: "部门不存在", target: DeleteTargetDepartment, id: 42, wantErr: true},
+		{
+			name:          "业务线绑定了API组",
+			target:        DeleteTargetBusiness,
+			id:            2,
+			wantBlockers:  []string{"order-read"},
+			wantAPIs:      []uint{101, 102},
+			wantRules:     []uint{2},
+			wantEndpoints: []repository.Endpoint{{Path: "/biz/orders/:id", Method: "DELETE"}},
+		},
+		{
+			name:         "系统业务线",
+			target:       DeleteTargetBusiness,
+			id:           1,
+			wantBlockers: []string{"系统业务线"},
+			wantAPIs:     []uint{100},
+			wantEndpoints: []repository.Endpoint{
+				{Path: "/api/v1/users", Method: "GET"},
+			},
+		},
+		{
+			name:     "其他API仍使用同一路径和方法时保留策略",
+			target:   DeleteTargetBusiness,
+			id:       3,
+			wantAPIs: []uint{103},
+		},
+		{
+			name:      "角色",
+			target:    DeleteTargetRole,
+			id:        12,
+			wantRoles: []uint{12},
+			wantRules: []uint{1, 2, 3, 7, 8, 9, 13},
+		},
+		{
+			name:         "系统管理员角色",
+			target:       DeleteTargetRole,
+			id:           10,
+			wantBlockers: []string{"系统管理员"},
+			wantRoles:    []uint{10},
+		},
+		{
+			name:          "API",
+			target:        DeleteTargetAPI,
+			id:            102,
+			wantAPIs:      []uint{102},
+			wantRules:     []uint{2},
+			wantEndpoints: []repository.Endpoint{{Path: "/biz/orders/:id", Method: "DELETE"}},
+		},
+		{
+			name:         "系统API",
+			target:       DeleteTargetAPI,
+			id:           100,
+			wantBlockers: []string{"系统API"},
+			wantAPIs:     []uint{100},
+			wantEndpoints: []repository.Endpoint{
+				{Path: "/api/v1/users", Method: "GET"},
+			},
+		},
+		{name: "不支持的对象", target: "user", id: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestCascade(&fakeCascadeRepo{})
+			impact, plan, err := s.plan(tt.target, tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("plan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(impact.Blockers) != len(tt.wantBlockers) || impact.Deletable != (len(tt.wantBlockers) == 0) {
+				t.Errorf("Blockers = %v deletable = %v, want %v", impact.Blockers, impact.Deletable, tt.wantBlockers)
+			}
+			for i, want := range tt.wantBlockers {
+				if i < len(impact.Blockers) && !strings.Contains(impact.Blockers[i], want) {
+					t.Errorf("Blockers[%d] = %q, want ~%q", i, impact.Blockers[i], want)
+				}
+			}
+			if !reflect.DeepEqual(plan.RoleIDs, tt.wantRoles) || len(impact.Roles) != len(tt.wantRoles) {
+				t.Errorf("RoleIDs = %v, want %v", plan.RoleIDs, tt.wantRoles)
+			}
+			if !reflect.DeepEqual(plan.APIIDs, tt.wantAPIs) || len(impact.APIs) != len(tt.wantAPIs) {
+				t.Errorf("APIIDs = %v, want %v", plan.APIIDs, tt.wantAPIs)
+			}
+			if !reflect.DeepEqual(plan.RuleIDs, tt.wantRules) || len(impact.Policies) != len(tt.wantRules) {
+				t.Errorf("RuleIDs = %v, want %v", plan.RuleIDs, tt.wantRules)
+			}
+			var endpoints []repository.Endpoint
+			for _, endpoint := range plan.Endpoints {
+				endpoints = append(endpoints, *endpoint)
+			}
+			if !reflect.DeepEqual(endpoints, tt.wantEndpoints) {
+				t.Errorf("Endpoints = %v, want %v", endpoints, tt.wantEndpoints)
+			}
+			if impact.UserRoles != int64(len(tt.wantRoles)) {
+				t.Errorf("UserRoles = %d, want %d", impact.UserRoles, len(tt.wantRoles))
+			}
+		})
+	}
+}
+
+func TestCascadeDeleteBlocked(t *testing.T) {
+	repo := &fakeCascadeRepo{}
+	s := newTestCascade(repo)
+	if err := s.Delete(DeleteTargetRole, 10); err == nil || !strings.Contains(err.Error(), "系统管理员") {
+		t.Fatalf("Delete() error = %v, want 系统管理员角色不能删除", err)
+	}
+	if len(repo.deleted) != 0 {
+		t.Error("限制删除时不应执行删除计划")
+	}
+}
+
+func TestCascadeOrphans(t *testing.T) {
+	repo := &fakeCascadeRepo{orphans: &repository.OrphanRecords{UserRoleIDs: []uint{1, 2}, APIIDs: []uint{104}}}
+	report, err := newTestCascade(repo).Orphans()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 孤立API的策略，以及主体、角色、用户或部门不存在的策略
+	var ruleIDs []uint
+	for _, policy := range report.Policies {
+		ruleIDs = append(ruleIDs, policy.ID)
+	}
+	if want := []uint{6, 7, 8, 9, 12}; !reflect.DeepEqual(ruleIDs, want) {
+		t.Errorf("孤立策略 = %v, want %v", ruleIDs, want)
+	}
+	if len(report.APIs) != 1 || report.APIs[0].ID != 104 {
+		t.Errorf("孤立API = %+v, want [104]", report.APIs)
+	}
+	if report.UserRoles != 2 || report.Total() != 8 || report.Cleaned {
+		t.Errorf("UserRoles = %d Total() = %d Cleaned = %v, want 2 8 false", report.UserRoles, report.Total(), report.Cleaned)
+	}
+	if len(repo.deleted) != 0 {
+		t.Error("Orphans() 不应执行删除计划")
+	}
+}
+
+func TestCascadeCleanupOrphans(t *testing.T) {
+	tests := []struct {
+		name        string
+		orphans     *repository.OrphanRecords
+		wantCleaned bool
+		wantDeleted int
+	}{
+		{"没有孤立数据", nil, false, 0},
+		{"孤立的关联记录", &repository.OrphanRecords{UserRoleIDs: []uint{1}, GroupMemberIDs: []uint{2}}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeCascadeRepo{orphans: tt.orphans}
+			s := newTestCascade(repo)
+			// 只保留不孤立的策略，清理时不需要重新加载策略
+			repo.rules = repo.rules[:5]
+
+			report, err := s.CleanupOrphans()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Cleaned != tt.wantCleaned || len(repo.deleted) != tt.wantDeleted {
+				t.Errorf("Cleaned = %v deleted = %d, want %v %d", report.Cleaned, len(repo.deleted), tt.wantCleaned, tt.wantDeleted)
+			}
+			if tt.wantCleaned && repo.deleted[0].Orphans != tt.orphans {
+				t.Error("删除计划应包含孤立的关联记录")
+			}
+		})
+	}
+}
@@ -77,6 +77,11 @@ func (s *DashboardService) GetAPICategories() ([]*repository.CategoryStatsItem,
 	return s.apiService.GetCategoryStats()
 }
 
+// GetAPICategoryTree 获取带子树API数量汇总的分类树
+func (s *DashboardService) GetAPICategoryTree() ([]*APICategoryNode, error) {
+	return s.apiService.GetAPICategoryTree()
+}
+
 // BusinessAPIStatsItem 业务线API统计项
 type BusinessAPIStatsItem struct {
 	Name  string `json:"name"`
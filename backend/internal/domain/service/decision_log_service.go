@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/internal/pkg/logger"
+)
+
+// 授权决策的判断依据
+const (
+	DecisionReasonPolicy          = "policy"           // 命中Casbin策略
+	DecisionReasonDepartmentLevel = "department_level" // 部门级API，本部门登录用户即可访问
+	DecisionReasonNoRole          = "no_role"          // 用户没有可用的角色
+	DecisionReasonNoPolicy        = "no_policy"        // 没有命中任何策略
+	DecisionReasonTokenScope      = "token_scope"      // 超出受限令牌的访问范围
+	DecisionReasonUserNotFound    = "user_not_found"   // 用户不存在
+	DecisionReasonAPIRetired      = "api_retired"      // API已下线
+	DecisionReasonAPIUnavailable  = "api_unavailable"  // API已禁用或尚未发布
+)
+
+// DecisionLogConfig 授权决策日志配置
+type DecisionLogConfig struct {
+	Sinks         []string           `mapstructure:"sinks"`          // 输出：db、file、stdout，为空时不记录
+	SampleAllow   float64            `mapstructure:"sample_allow"`   // 允许决策的采样率，0~1
+	SampleDeny    float64            `mapstructure:"sample_deny"`    // 拒绝决策的采样率，0~1
+	BufferSize    int                `mapstructure:"buffer_size"`    // 缓冲队列长度，队列满时丢弃
+	BatchSize     int                `mapstructure:"batch_size"`     // 每批写入的条数
+	FlushInterval time.Duration      `mapstructure:"flush_interval"` // 未满一批时的最长等待时间
+	RetentionDays int                `mapstructure:"retention_days"` // 数据库和轮转文件的保留天数，0表示不清理
+	File          DecisionFileConfig `mapstructure:"file"`
+}
+
+// DecisionFileConfig 决策日志文件输出配置
+type DecisionFileConfig struct {
+	Path       string `mapstructure:"path"`
+	MaxSizeMB  int    `mapstructure:"max_size_mb"` // 单个文件大小上限，超过后轮转
+	MaxBackups int    `mapstructure:"max_backups"` // 保留的轮转文件数，0表示只按保留天数清理
+}
+
+// AuthzDecisionListRequest 授权决策日志查询请求
+type AuthzDecisionListRequest struct {
+	UserID    uint   `form:"user_id"`
+	Path      string `form:"path"` // 路径前缀
+	Method    string `form:"method"`
+	Allowed   *bool  `form:"allowed"`
+	Source    string `form:"source"`
+	RequestID string `form:"request_id"`
+	StartTime string `form:"start_time"`
+	EndTime   string `form:"end_time"`
+	Page      int    `form:"page"`
+	PageSize  int    `form:"page_size"`
+}
+
+// AuthzDecisionListResponse 授权决策日志列表响应
+type AuthzDecisionListResponse struct {
+	Total int64                   `json:"total"`
+	Items []*entity.AuthzDecision `json:"items"`
+}
+
+// DecisionLogger 异步授权决策日志，按采样率写入缓冲队列，由后台任务批量写入各输出
+type DecisionLogger struct {
+	cfg     DecisionLogConfig
+	sinks   []DecisionSink
+	repo    repository.AuthzDecisionRepository
+	logger  *logger.Logger
+	queue   chan *entity.AuthzDecision
+	dropped int64
+	done    chan struct{}
+	started sync.Once
+}
+
+// NewDecisionLogger 创建授权决策日志
+func NewDecisionLogger(cfg DecisionLogConfig, sinks []DecisionSink, repo repository.AuthzDecisionRepository, logger *logger.Logger) *DecisionLogger {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 10000
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 200
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 2 * time.Second
+	}
+
+	return &DecisionLogger{
+		cfg:    cfg,
+		sinks:  sinks,
+		repo:   repo,
+		logger: logger,
+		queue:  make(chan *entity.AuthzDecision, cfg.BufferSize),
+		done:   make(chan struct{}),
+	}
+}
+
+// Enabled 是否配置了输出
+func (l *DecisionLogger) Enabled() bool {
+	return l != nil && len(l.sinks) > 0
+}
+
+// Log 按采样率记录一次授权决策，不阻塞调用方；队列满时丢弃并计数
+func (l *DecisionLogger) Log(decision *entity.AuthzDecision) {
+	if !l.Enabled() {
+		return
+	}
+
+	rate := l.cfg.SampleAllow
+	if !decision.Allowed {
+		rate = l.cfg.SampleDeny
+	}
+	if rate <= 0 || (rate < 1 && rand.Float64() >= rate) {
+		return
+	}
+
+	if decision.CreatedAt.IsZero() {
+		decision.CreatedAt = time.Now()
+	}
+	decision.Path = truncateString(decision.Path, 500)
+	decision.Roles = truncateString(decision.Roles, 255)
+	decision.MatchedRule = truncateString(decision.MatchedRule, 255)
+
+	select {
+	case l.queue <- decision:
+	default:
+		atomic.AddInt64(&l.dropped, 1)
+	}
+}
+
+// Start 启动后台写入和过期清理任务，ctx取消后写完队列中剩余的日志
+func (l *DecisionLogger) Start(ctx context.Context) {
+	if !l.Enabled() {
+		return
+	}
+	l.started.Do(func() {
+		go l.run(ctx)
+	})
+}
+
+// Wait 等待后台任务在ctx取消后写完剩余日志，未启动时立即返回
+func (l *DecisionLogger) Wait() {
+	if !l.Enabled() {
+		return
+	}
+	// 未启动时直接标记为已结束
+	l.started.Do(func() {
+		close(l.done)
+	})
+	<-l.done
+}
+
+// List 分页查询数据库中的授权决策日志
+func (l *DecisionLogger) List(req *AuthzDecisionListRequest) (*AuthzDecisionListResponse, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+
+	query := &repository.AuthzDecisionQuery{
+		UserID:    req.UserID,
+		Path:      req.Path,
+		Method:    strings.ToUpper(req.Method),
+		Allowed:   req.Allowed,
+		Source:    req.Source,
+		RequestID: req.RequestID,
+		Page:      req.Page,
+		PageSize:  req.PageSize,
+	}
+	if req.StartTime != "" {
+		t, err := parseAuditTime(req.StartTime, false)
+		if err != nil {
+			return nil, err
+		}
+		query.StartTime = &t
+	}
+	if req.EndTime != "" {
+		t, err := parseAuditTime(req.EndTime, true)
+		if err != nil {
+			return nil, err
+		}
+		query.EndTime = &t
+	}
+
+	decisions, total, err := l.repo.List(query)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthzDecisionListResponse{Total: total, Items: decisions}, nil
+}
+
+// run 批量写入，满一批或到达刷新间隔时写出；每小时按保留天数清理一次
+func (l *DecisionLogger) run(ctx context.Context) {
+	defer close(l.done)
+
+	flushTicker := time.NewTicker(l.cfg.FlushInterval)
+	defer flushTicker.Stop()
+	purgeTicker := time.NewTicker(time.Hour)
+	defer purgeTicker.Stop()
+
+	l.purge()
+	batch := make([]*entity.AuthzDecision, 0, l.cfg.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case d := <-l.queue:
+					batch = append(batch, d)
+					if len(batch) >= l.cfg.BatchSize {
+						batch = l.flush(batch)
+					}
+				default:
+					l.flush(batch)
+					l.closeSinks()
+					return
+				}
+			}
+		case d := <-l.queue:
+			batch = append(batch, d)
+			if len(batch) >= l.cfg.BatchSize {
+				batch = l.flush(batch)
+			}
+		case <-flushTicker.C:
+			batch = l.flush(batch)
+		case <-purgeTicker.C:
+			l.purge()
+		}
+	}
+}
+
+// flush 写出一批日志，返回清空后的批次
+func (l *DecisionLogger) flush(batch []*entity.AuthzDecision) []*entity.AuthzDecision {
+	if dropped := atomic.SwapInt64(&l.dropped, 0); dropped > 0 {
+		l.logger.Warn("授权决策日志队列已满，丢弃%d条", dropped)
+	}
+	if len(batch) == 0 {
+		return batch
+	}
+
+	for _, sink := range l.sinks {
+		if err := sink.Write(batch); err != nil {
+			l.logger.Error("写入授权决策日志失败: sink=%s count=%d err=%v", sink.Name(), len(batch), err)
+		}
+	}
+	return batch[:0]
+}
+
+// purge 按保留天数清理支持清理的输出
+func (l *DecisionLogger) purge() {
+	if l.cfg.RetentionDays <= 0 {
+		return
+	}
+	before := time.Now().AddDate(0, 0, -l.cfg.RetentionDays)
+	for _, sink := range l.sinks {
+		purger, ok := sink.(DecisionPurger)
+		if !ok {
+			continue
+		}
+		if err := purger.Purge(before); err != nil {
+			l.logger.Error("清理授权决策日志失败: sink=%s err=%v", sink.Name(), err)
+		}
+	}
+}
+
+// closeSinks 关闭持有文件的输出
+func (l *DecisionLogger) closeSinks() {
+	for _, sink := range l.sinks {
+		if closer, ok := sink.(interface{ Close() error }); ok {
+			if err := closer.Close(); err != nil {
+				l.logger.Error("关闭授权决策日志输出失败: sink=%s err=%v", sink.Name(), err)
+			}
+		}
+	}
+}
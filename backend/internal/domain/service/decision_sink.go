@@ -0,0 +1,226 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// DecisionSink 授权决策日志的输出，可替换为消息队列等实现
+type DecisionSink interface {
+	// Name 输出名称
+	Name() string
+	// Write 批量写入决策日志
+	Write(decisions []*entity.AuthzDecision) error
+}
+
+// DecisionPurger 支持按保留期限清理的输出
+type DecisionPurger interface {
+	// Purge 清理指定时间之前的日志
+	Purge(before time.Time) error
+}
+
+// DBDecisionSink 将决策日志写入authz_decisions表
+type DBDecisionSink struct {
+	repo repository.AuthzDecisionRepository
+}
+
+// NewDBDecisionSink 创建数据库输出
+func NewDBDecisionSink(repo repository.AuthzDecisionRepository) *DBDecisionSink {
+	return &DBDecisionSink{repo: repo}
+}
+
+// Name 输出名称
+func (s *DBDecisionSink) Name() string {
+	return "db"
+}
+
+// Write 批量写入数据库
+func (s *DBDecisionSink) Write(decisions []*entity.AuthzDecision) error {
+	return s.repo.CreateBatch(decisions)
+}
+
+// Purge 删除过期的日志
+func (s *DBDecisionSink) Purge(before time.Time) error {
+	_, err := s.repo.DeleteBefore(before)
+	return err
+}
+
+// WriterDecisionSink 将决策日志以JSON Lines写入io.Writer，如标准输出
+type WriterDecisionSink struct {
+	name   string
+	writer io.Writer
+	mu     sync.Mutex
+}
+
+// NewWriterDecisionSink 创建JSON Lines输出
+func NewWriterDecisionSink(name string, writer io.Writer) *WriterDecisionSink {
+	return &WriterDecisionSink{name: name, writer: writer}
+}
+
+// Name 输出名称
+func (s *WriterDecisionSink) Name() string {
+	return s.name
+}
+
+// Write 每条决策写一行JSON
+func (s *WriterDecisionSink) Write(decisions []*entity.AuthzDecision) error {
+	data, err := encodeDecisionLines(decisions)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.writer.Write(data)
+	return err
+}
+
+// FileDecisionSink 将决策日志以JSON Lines追加到文件，超过大小后轮转，按保留期限和份数清理轮转文件
+type FileDecisionSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewFileDecisionSink 创建文件输出，maxSizeMB为单个文件大小上限，maxBackups为保留的轮转文件数（0表示不限）
+func NewFileDecisionSink(path string, maxSizeMB, maxBackups int) *FileDecisionSink {
+	if maxSizeMB <= 0 {
+		maxSizeMB = 100
+	}
+	return &FileDecisionSink{
+		path:       path,
+		maxSize:    int64(maxSizeMB) * 1024 * 1024,
+		maxBackups: maxBackups,
+	}
+}
+
+// Name 输出名称
+func (s *FileDecisionSink) Name() string {
+	return "file"
+}
+
+// Write 追加写入，写入后超过大小上限时轮转
+func (s *FileDecisionSink) Write(decisions []*entity.AuthzDecision) error {
+	data, err := encodeDecisionLines(decisions)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.open(); err != nil {
+		return err
+	}
+	n, err := s.file.Write(data)
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+
+	if s.size >= s.maxSize {
+		return s.rotate()
+	}
+	return nil
+}
+
+// Purge 删除修改时间早于before的轮转文件，并只保留最近maxBackups个
+func (s *FileDecisionSink) Purge(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	backups, err := filepath.Glob(s.path + ".*")
+	if err != nil {
+		return err
+	}
+	// 轮转文件名带时间戳，按名称倒序即从新到旧
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+
+	for i, name := range backups {
+		info, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		if info.ModTime().Before(before) || (s.maxBackups > 0 && i >= s.maxBackups) {
+			if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close 关闭当前文件
+func (s *FileDecisionSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// open 打开当前文件，文件仅所有者可读写
+func (s *FileDecisionSink) open() error {
+	if s.file != nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+// rotate 将当前文件重命名为带时间戳的轮转文件，下次写入时创建新文件
+func (s *FileDecisionSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+	s.size = 0
+
+	backup := fmt.Sprintf("%s.%s", s.path, time.Now().Format("20060102-150405.000"))
+	return os.Rename(s.path, backup)
+}
+
+// encodeDecisionLines 编码为JSON Lines
+func encodeDecisionLines(decisions []*entity.AuthzDecision) ([]byte, error) {
+	var b strings.Builder
+	for _, d := range decisions {
+		data, err := json.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,572 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/pkg/casbinx"
+)
+
+// DepartmentReorgService 部门合并与拆分服务：转移部门拥有的用户、角色、业务线和API，并改写部门维度的策略
+type DepartmentReorgService struct {
+	reorgRepo repository.DepartmentReorgRepository
+	deptRepo  repository.DepartmentRepository
+	roleRepo  repository.RoleRepository
+	apiRepo   repository.APIRepository
+	enforcer  *casbinx.Enforcer
+}
+
+// NewDepartmentReorgService 创建部门合并与拆分服务
+func NewDepartmentReorgService(reorgRepo repository.DepartmentReorgRepository, deptRepo repository.DepartmentRepository,
+	roleRepo repository.RoleRepository, apiRepo repository.APIRepository, enforcer *casbinx.Enforcer) *DepartmentReorgService {
+	return &DepartmentReorgService{
+		reorgRepo: reorgRepo,
+		deptRepo:  deptRepo,
+		roleRepo:  roleRepo,
+		apiRepo:   apiRepo,
+		enforcer:  enforcer,
+	}
+}
+
+// 部门管理员角色在合并时的处理方式
+const (
+	DeptAdminRoleRename = "rename" // 目标部门没有管理员角色，源部门管理员角色改为目标部门的管理员角色
+	DeptAdminRoleMerge  = "merge"  // 源部门管理员角色并入目标部门的管理员角色后删除
+)
+
+// MergeDepartmentRequest 合并部门请求，源部门的所有内容转移到目标部门后删除源部门
+type MergeDepartmentRequest struct {
+	TargetID uint `json:"target_id" binding:"required"`
+}
+
+// SplitDepartmentRequest 拆分部门请求，在源部门下新建子部门并转移选定的业务线和用户
+type SplitDepartmentRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Code        string `json:"code" binding:"required,max=50"`
+	Sort        int    `json:"sort"`
+	BusinessIDs []uint `json:"business_ids"`
+	UserIDs     []uint `json:"user_ids"`
+}
+
+// DepartmentReorgItem 被转移的对象
+type DepartmentReorgItem struct {
+	ID   uint   `json:"id"`
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// DepartmentPolicyChange 策略改写
+type DepartmentPolicyChange struct {
+	ID     uint     `json:"id"`               // 新增的策略为0
+	Before []string `json:"before,omitempty"` // 为空表示新增的策略
+	After  []string `json:"after,omitempty"`  // 为空表示改写后与已有策略重复，直接删除
+}
+
+// DepartmentMergePreview 部门合并预览，执行合并后返回实际执行的内容
+type DepartmentMergePreview struct {
+	Source        *entity.Department        `json:"source"`
+	Target        *entity.Department        `json:"target"`
+	Users         []*DepartmentReorgItem    `json:"users"`
+	Roles         []*DepartmentReorgItem    `json:"roles"`
+	Businesses    []*DepartmentReorgItem    `json:"businesses"`
+	Children      []*DepartmentReorgItem    `json:"children"`   // 移动到目标部门下的下级部门
+	APICount      int64                     `json:"api_count"`  // 随业务线转移的API数量
+	AdminRole     string                    `json:"admin_role"` // 部门管理员角色的处理方式: rename, merge，源部门没有管理员角色时为空
+	PolicyChanges []*DepartmentPolicyChange `json:"policy_changes"`
+}
+
+// DepartmentSplitPreview 部门拆分预览，执行拆分后返回新部门；预览中新部门的ID以{new}表示
+type DepartmentSplitPreview struct {
+	Source        *entity.Department        `json:"source"`
+	Department    *entity.Department        `json:"department"` // 新建的子部门，预览时ID为0
+	Businesses    []*DepartmentReorgItem    `json:"businesses"`
+	Users         []*DepartmentReorgItem    `json:"users"`
+	APICount      int64                     `json:"api_count"`            // 随业务线转移的API数量
+	AdminRole     *DepartmentReorgItem      `json:"admin_role,omitempty"` // 为转移的源部门管理员创建的新部门管理员角色
+	Admins        []*DepartmentReorgItem    `json:"admins"`               // 管理员角色从源部门转到新部门的用户
+	PolicyChanges []*DepartmentPolicyChange `json:"policy_changes"`       // 转移的用户在新部门维度上的策略
+}
+
+// PreviewMerge 预览将部门合并到目标部门的影响
+func (s *DepartmentReorgService) PreviewMerge(sourceID uint, req *MergeDepartmentRequest) (*DepartmentMergePreview, error) {
+	preview, _, err := s.planMerge(sourceID, req.TargetID)
+	return preview, err
+}
+
+// Merge 在一个事务中将部门合并到目标部门，并重新加载策略
+func (s *DepartmentReorgService) Merge(sourceID uint, req *MergeDepartmentRequest) (*DepartmentMergePreview, error) {
+	preview, plan, err := s.planMerge(sourceID, req.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reorgRepo.Merge(plan); err != nil {
+		return nil, fmt.Errorf("合并部门失败: %v", err)
+	}
+	// 同步策略到内存（安全方式）
+	if err := s.enforcer.SyncPolicyToMemory(); err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// PreviewSplit 预览拆分部门的影响
+func (s *DepartmentReorgService) PreviewSplit(sourceID uint, req *SplitDepartmentRequest) (*DepartmentSplitPreview, error) {
+	preview, _, err := s.planSplit(sourceID, req)
+	return preview, err
+}
+
+// Split 在一个事务中新建子部门，转移选定的业务线和用户及其部门维度的权限，并重新加载策略
+func (s *DepartmentReorgService) Split(sourceID uint, req *SplitDepartmentRequest) (*DepartmentSplitPreview, error) {
+	preview, plan, err := s.planSplit(sourceID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reorgRepo.Split(plan); err != nil {
+		return nil, fmt.Errorf("拆分部门失败: %v", err)
+	}
+	// 同步策略到内存（安全方式）
+	if err := s.enforcer.SyncPolicyToMemory(); err != nil {
+		return nil, err
+	}
+
+	// 预览中的占位符替换为新部门的ID
+	placeholder := strings.NewReplacer(repository.NewDepartmentPlaceholder, deptDimension(plan.Department.ID))
+	preview.Department = plan.Department
+	if preview.AdminRole != nil {
+		preview.AdminRole.ID = plan.AdminRole.ID
+		preview.AdminRole.Code = plan.AdminRole.Code
+	}
+	for _, change := range preview.PolicyChanges {
+		for i := range change.Before {
+			change.Before[i] = placeholder.Replace(change.Before[i])
+		}
+		for i := range change.After {
+			change.After[i] = placeholder.Replace(change.After[i])
+		}
+	}
+	return preview, nil
+}
+
+// planMerge 生成合并计划
+func (s *DepartmentReorgService) planMerge(sourceID, targetID uint) (*DepartmentMergePreview, *repository.DepartmentMergePlan, error) {
+	if sourceID == targetID {
+		return nil, nil, errors.New("不能将部门合并到自身")
+	}
+	source, err := s.getDepartment(sourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := s.getDepartment(targetID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("目标%v", err)
+	}
+	descendants, err := s.deptRepo.ListDescendants(sourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, descendant := range descendants {
+		if descendant.ID == targetID {
+			return nil, nil, errors.New("不能将部门合并到其下级部门")
+		}
+	}
+
+	contents, err := s.reorgRepo.GetContents(sourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	preview := &DepartmentMergePreview{
+		Source:        source,
+		Target:        target,
+		Users:         make([]*DepartmentReorgItem, 0, len(contents.Users)),
+		Roles:         make([]*DepartmentReorgItem, 0, len(contents.Roles)),
+		Businesses:    make([]*DepartmentReorgItem, 0, len(contents.Businesses)),
+		Children:      make([]*DepartmentReorgItem, 0, len(contents.Children)),
+		APICount:      contents.APICount,
+		PolicyChanges: []*DepartmentPolicyChange{},
+	}
+	for _, user := range contents.Users {
+		preview.Users = append(preview.Users, &DepartmentReorgItem{ID: user.ID, Code: user.Username, Name: user.Name})
+	}
+	for _, role := range contents.Roles {
+		preview.Roles = append(preview.Roles, &DepartmentReorgItem{ID: role.ID, Code: role.Code, Name: role.Name})
+	}
+	for _, business := range contents.Businesses {
+		preview.Businesses = append(preview.Businesses, &DepartmentReorgItem{ID: business.ID, Code: business.Code, Name: business.Name})
+	}
+	for _, child := range contents.Children {
+		preview.Children = append(preview.Children, &DepartmentReorgItem{ID: child.ID, Code: child.Code, Name: child.Name})
+	}
+
+	plan := &repository.DepartmentMergePlan{SourceID: sourceID, TargetID: targetID}
+
+	// 部门管理员角色
+	sourceAdminCode, targetAdminCode := deptAdminRoleCode(sourceID), deptAdminRoleCode(targetID)
+	sourceAdmin, err := s.roleRepo.GetByCode(sourceAdminCode)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sourceAdmin != nil {
+		targetAdmin, err := s.roleRepo.GetByCode(targetAdminCode)
+		if err != nil {
+			return nil, nil, err
+		}
+		if targetAdmin != nil {
+			preview.AdminRole = DeptAdminRoleMerge
+			plan.MergeRoleID, plan.MergeIntoRoleID = sourceAdmin.ID, targetAdmin.ID
+		} else {
+			preview.AdminRole = DeptAdminRoleRename
+			renamed := *sourceAdmin
+			renamed.Code = targetAdminCode
+			renamed.DeptID = targetID
+			name := fmt.Sprintf("%s部门管理员", target.Name)
+			if existing, err := s.roleRepo.GetByName(name); err == nil && existing == nil {
+				renamed.Name = name
+				renamed.Description = fmt.Sprintf("%s部门的管理员角色", target.Name)
+			}
+			plan.RenameRole = &renamed
+		}
+	}
+
+	// 策略改写
+	rules, err := s.reorgRepo.ListRules()
+	if err != nil {
+		return nil, nil, err
+	}
+	rewriter := newDeptRuleRewriter(deptDimension(sourceID), deptDimension(targetID))
+	existing := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if _, changed := rewriter.rewrite(rule); !changed {
+			existing[casbinRuleKey(rule)] = true
+		}
+	}
+	for _, rule := range rules {
+		rewritten, changed := rewriter.rewrite(rule)
+		if !changed {
+			continue
+		}
+		change := &DepartmentPolicyChange{ID: rule.ID, Before: casbinRuleValues(rule)}
+		key := casbinRuleKey(rewritten)
+		if existing[key] {
+			plan.DeletedRuleIDs = append(plan.DeletedRuleIDs, rule.ID)
+		} else {
+			existing[key] = true
+			change.After = casbinRuleValues(rewritten)
+			plan.UpdatedRules = append(plan.UpdatedRules, rewritten)
+		}
+		preview.PolicyChanges = append(preview.PolicyChanges, change)
+	}
+
+	return preview, plan, nil
+}
+
+// planSplit 生成拆分计划
+func (s *DepartmentReorgService) planSplit(sourceID uint, req *SplitDepartmentRequest) (*DepartmentSplitPreview, *repository.DepartmentSplitPlan, error) {
+	source, err := s.getDepartment(sourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(req.BusinessIDs) == 0 && len(req.UserIDs) == 0 {
+		return nil, nil, errors.New("请选择要转移到新部门的业务线或用户")
+	}
+	code := strings.TrimSpace(req.Code)
+	existing, err := s.deptRepo.GetByCode(code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if existing != nil {
+		return nil, nil, errors.New("部门编码已存在")
+	}
+
+	contents, err := s.reorgRepo.GetContents(sourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	plan := &repository.DepartmentSplitPlan{
+		SourceID: sourceID,
+		Department: &entity.Department{
+			Name:     req.Name,
+			Code:     code,
+			ParentID: sourceID,
+			Level:    source.Level + 1,
+			Sort:     req.Sort,
+			Status:   1,
+		},
+	}
+	preview := &DepartmentSplitPreview{
+		Source:     source,
+		Department: plan.Department,
+		Businesses: make([]*DepartmentReorgItem, 0, len(req.BusinessIDs)),
+		Users:      make([]*DepartmentReorgItem, 0, len(req.UserIDs)),
+	}
+
+	businesses := make(map[uint]*entity.Business, len(contents.Businesses))
+	for _, business := range contents.Businesses {
+		businesses[business.ID] = business
+	}
+	for _, id := range req.BusinessIDs {
+		business, ok := businesses[id]
+		if !ok {
+			return nil, nil, fmt.Errorf("业务线%d不属于部门%s", id, source.Name)
+		}
+		if containsUint(plan.BusinessIDs, id) {
+			continue
+		}
+		count, err := s.apiRepo.GetCountByBusiness(id)
+		if err != nil {
+			return nil, nil, err
+		}
+		preview.APICount += count
+		plan.BusinessIDs = append(plan.BusinessIDs, id)
+		preview.Businesses = append(preview.Businesses, &DepartmentReorgItem{ID: business.ID, Code: business.Code, Name: business.Name})
+	}
+
+	users := make(map[uint]*entity.User, len(contents.Users))
+	for _, user := range contents.Users {
+		users[user.ID] = user
+	}
+	for _, id := range req.UserIDs {
+		user, ok := users[id]
+		if !ok {
+			return nil, nil, fmt.Errorf("用户%d不属于部门%s", id, source.Name)
+		}
+		if containsUint(plan.UserIDs, id) {
+			continue
+		}
+		plan.UserIDs = append(plan.UserIDs, id)
+		preview.Users = append(preview.Users, &DepartmentReorgItem{ID: user.ID, Code: user.Username, Name: user.Name})
+	}
+
+	if err := s.planSplitPolicies(source, plan, preview, users); err != nil {
+		return nil, nil, err
+	}
+	return preview, plan, nil
+}
+
+// planSplitPolicies 生成转移用户的权限改写：策略按用户部门匹配，转移的用户在源部门维度上的策略复制到新部门维度，
+// 转移的源部门管理员改为新部门的管理员，管理员策略中的部门维度、/api/v1/dept/<id>路径随之改写
+func (s *DepartmentReorgService) planSplitPolicies(source *entity.Department, plan *repository.DepartmentSplitPlan,
+	preview *DepartmentSplitPreview, users map[uint]*entity.User) error {
+	preview.Admins = []*DepartmentReorgItem{}
+	preview.PolicyChanges = []*DepartmentPolicyChange{}
+	if len(plan.UserIDs) == 0 {
+		return nil
+	}
+
+	userRoles, err := s.reorgRepo.ListUserRoles(plan.UserIDs)
+	if err != nil {
+		return err
+	}
+	rules, err := s.reorgRepo.ListRules()
+	if err != nil {
+		return err
+	}
+	split := planDepartmentSplit(deptDimension(source.ID), plan.UserIDs, userRoles, rules)
+	if len(split.admins) == 0 && len(split.added) == 0 {
+		return nil
+	}
+
+	if len(split.admins) > 0 {
+		sourceAdmin, err := s.roleRepo.GetByCode(deptAdminRoleCode(source.ID))
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("%s部门管理员", plan.Department.Name)
+		if existing, err := s.roleRepo.GetByName(name); err != nil {
+			return err
+		} else if existing != nil {
+			name = fmt.Sprintf("%s(%s)部门管理员", plan.Department.Name, plan.Department.Code)
+		}
+		plan.AdminRole = &entity.Role{
+			Name:        name,
+			Code:        deptAdminRolePrefix + repository.NewDepartmentPlaceholder,
+			Description: fmt.Sprintf("%s部门的管理员角色", plan.Department.Name),
+			Status:      1,
+		}
+		preview.AdminRole = &DepartmentReorgItem{Code: plan.AdminRole.Code, Name: plan.AdminRole.Name}
+		if sourceAdmin != nil {
+			plan.SourceAdminRoleID = sourceAdmin.ID
+			plan.AdminUserIDs = split.roleAdmins
+		}
+		for _, id := range split.admins {
+			user := users[id]
+			preview.Admins = append(preview.Admins, &DepartmentReorgItem{ID: user.ID, Code: user.Username, Name: user.Name})
+		}
+	}
+
+	plan.AddedRules, plan.UpdatedRules = split.added, split.updated
+	for _, rule := range split.added {
+		preview.PolicyChanges = append(preview.PolicyChanges, &DepartmentPolicyChange{After: casbinRuleValues(rule)})
+	}
+	for _, rule := range split.updated {
+		preview.PolicyChanges = append(preview.PolicyChanges, &DepartmentPolicyChange{
+			ID:     rule.ID,
+			Before: casbinRuleValues(split.original[rule.ID]),
+			After:  casbinRuleValues(rule),
+		})
+	}
+	return nil
+}
+
+// departmentSplitRules 拆分部门时转移用户的权限改写
+type departmentSplitRules struct {
+	admins     []uint                          // 转移的源部门管理员
+	roleAdmins []uint                          // 其中通过用户角色关联拥有管理员角色的用户
+	added      []*repository.CasbinRule        // 新部门维度上的策略
+	updated    []*repository.CasbinRule        // 改为新部门管理员角色的用户角色策略
+	original   map[uint]*repository.CasbinRule // 被改写策略的原值
+}
+
+// planDepartmentSplit 计算转移用户的权限改写，新部门ID以占位符表示：
+// 转移用户拥有的角色在源部门维度上的策略复制到新部门维度；
+// 有源部门管理员转移时，源部门管理员的策略按新部门改写后复制给新部门管理员角色，这些用户的g策略改为新部门管理员角色
+func planDepartmentSplit(source string, userIDs []uint, userRoles map[uint][]*entity.Role, rules []*repository.CasbinRule) *departmentSplitRules {
+	split := &departmentSplitRules{original: make(map[uint]*repository.CasbinRule)}
+	sourceAdmin := deptAdminRolePrefix + source
+	rewriter := newDeptRuleRewriter(source, repository.NewDepartmentPlaceholder)
+
+	subjects := make(map[string]uint, len(userIDs))
+	roles := make(map[string]bool)
+	admins := make(map[uint]bool)
+	for _, id := range userIDs {
+		subjects[fmt.Sprintf("user_%d", id)] = id
+		for _, role := range userRoles[id] {
+			roles[role.Code] = true
+			if role.Code == sourceAdmin && !admins[id] {
+				admins[id] = true
+				split.roleAdmins = append(split.roleAdmins, id)
+			}
+		}
+	}
+	existing := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		existing[casbinRuleKey(rule)] = true
+		if rule.PType != "g" {
+			continue
+		}
+		if id, ok := subjects[rule.V0]; ok {
+			roles[rule.V1] = true
+			if rule.V1 == sourceAdmin {
+				admins[id] = true
+				rewritten, _ := rewriter.rewrite(rule)
+				split.original[rule.ID] = rule
+				split.updated = append(split.updated, rewritten)
+			}
+		}
+	}
+	for _, id := range userIDs {
+		if admins[id] {
+			split.admins = append(split.admins, id)
+		}
+	}
+
+	add := func(rule *repository.CasbinRule) {
+		key := casbinRuleKey(rule)
+		if existing[key] {
+			return
+		}
+		existing[key] = true
+		rule.ID = 0
+		split.added = append(split.added, rule)
+	}
+	for _, rule := range rules {
+		if rule.PType != "p" {
+			continue
+		}
+		switch {
+		case rule.V0 == sourceAdmin:
+			if len(split.admins) > 0 {
+				rewritten, _ := rewriter.rewrite(rule)
+				add(rewritten)
+			}
+		case roles[rule.V0] && rule.V3 == source:
+			copied := *rule
+			copied.V3 = repository.NewDepartmentPlaceholder
+			add(&copied)
+		}
+	}
+	return split
+}
+
+// getDepartment 获取部门，不存在时返回错误
+func (s *DepartmentReorgService) getDepartment(id uint) (*entity.Department, error) {
+	dept, err := s.deptRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if dept == nil {
+		return nil, errors.New("部门不存在")
+	}
+	return dept, nil
+}
+
+// deptRuleRewriter 将策略中源部门的维度、/api/v1/dept/<id>路径和部门管理员角色改写为目标部门
+type deptRuleRewriter struct {
+	source, target           string
+	sourcePath, targetPath   string
+	sourceAdmin, targetAdmin string
+}
+
+// newDeptRuleRewriter 创建策略改写器，source和target为策略中部门维度的取值
+func newDeptRuleRewriter(source, target string) *deptRuleRewriter {
+	return &deptRuleRewriter{
+		source:      source,
+		target:      target,
+		sourcePath:  "/api/v1/dept/" + source,
+		targetPath:  "/api/v1/dept/" + target,
+		sourceAdmin: deptAdminRolePrefix + source,
+		targetAdmin: deptAdminRolePrefix + target,
+	}
+}
+
+// rewrite 返回改写后的策略，策略与源部门无关时返回false
+func (w *deptRuleRewriter) rewrite(rule *repository.CasbinRule) (*repository.CasbinRule, bool) {
+	rewritten := *rule
+	if rewritten.V0 == w.sourceAdmin {
+		rewritten.V0 = w.targetAdmin
+	}
+	switch rule.PType {
+	case "p":
+		if rewritten.V1 == w.sourcePath || strings.HasPrefix(rewritten.V1, w.sourcePath+"/") {
+			rewritten.V1 = w.targetPath + strings.TrimPrefix(rewritten.V1, w.sourcePath)
+		}
+		if rewritten.V3 == w.source {
+			rewritten.V3 = w.target
+		}
+	case "g":
+		if rewritten.V1 == w.sourceAdmin {
+			rewritten.V1 = w.targetAdmin
+		}
+	}
+	return &rewritten, rewritten != *rule
+}
+
+// deptAdminRolePrefix 部门管理员角色编码的前缀，后接部门ID
+const deptAdminRolePrefix = "dept_admin_"
+
+// deptAdminRoleCode 部门管理员角色编码
+func deptAdminRoleCode(deptID uint) string {
+	return deptAdminRolePrefix + deptDimension(deptID)
+}
+
+// deptDimension 策略中部门维度的取值
+func deptDimension(deptID uint) string {
+	return strconv.FormatUint(uint64(deptID), 10)
+}
+
+// casbinRuleValues 策略的取值，去掉末尾的空值
+func casbinRuleValues(rule *repository.CasbinRule) []string {
+	values := []string{rule.PType, rule.V0, rule.V1, rule.V2, rule.V3, rule.V4, rule.V5}
+	for len(values) > 0 && values[len(values)-1] == "" {
+		values = values[:len(values)-1]
+	}
+	return values
+}
+
+// casbinRuleKey 策略的唯一键
+func casbinRuleKey(rule *repository.CasbinRule) string {
+	return strings.Join([]string{rule.PType, rule.V0, rule.V1, rule.V2, rule.V3, rule.V4, rule.V5}, ",")
+}
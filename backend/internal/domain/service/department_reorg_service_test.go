@@ -0,0 +1,269 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// fakeDeptRepo 内存中的部门仓库，只实现测试用到的方法
+type fakeDeptRepo struct {
+	repository.DepartmentRepository
+	depts       map[uint]*entity.Department
+	descendants map[uint][]*entity.Department
+}
+
+func (r *fakeDeptRepo) GetByID(id uint) (*entity.Department, error) {
+	return r.depts[id], nil
+}
+
+func (r *fakeDeptRepo) ListDescendants(id uint) ([]*entity.Department, error) {
+	return r.descendants[id], nil
+}
+
+// fakeRoleRepo 内存中的角色仓库，只实现测试用到的方法
+type fakeRoleRepo struct {
+	repository.RoleRepository
+	roles []*entity.Role
+}
+
+func (r *fakeRoleRepo) GetByCode(code string) (*entity.Role, error) {
+	for _, role := range r.roles {
+		if role.Code == code {
+			return role, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRoleRepo) GetByName(name string) (*entity.Role, error) {
+	for _, role := range r.roles {
+		if role.Name == name {
+			return role, nil
+		}
+	}
+	return nil, nil
+}
+
+// fakeReorgRepo 内存中的部门合并、拆分仓库，只实现测试用到的方法
+type fakeReorgRepo struct {
+	repository.DepartmentReorgRepository
+	rules []*repository.CasbinRule
+}
+
+func (r *fakeReorgRepo) GetContents(deptID uint) (*repository.DepartmentContents, error) {
+	return &repository.DepartmentContents{}, nil
+}
+
+func (r *fakeReorgRepo) ListRules() ([]*repository.CasbinRule, error) {
+	return r.rules, nil
+}
+
+// testRule 按ptype, v0, v1...构造策略
+func testRule(id uint, values ...string) *repository.CasbinRule {
+	values = append(values, make([]string, 7-len(values))...)
+	return &repository.CasbinRule{ID: id, PType: values[0], V0: values[1], V1: values[2], V2: values[3], V3: values[4], V4: values[5], V5: values[6]}
+}
+
+func TestDeptRuleRewriter(t *testing.T) {
+	w := newDeptRuleRewriter("5", "6")
+	tests := []struct {
+		name        string
+		rule        *repository.CasbinRule
+		want        *repository.CasbinRule
+		wantChanged bool
+	}{
+		{"部门维度", testRule(1, "p", "dev", "/biz/orders", "GET", "5", "allow"), testRule(1, "p", "dev", "/biz/orders", "GET", "6", "allow"), true},
+		{"部门管理员策略", testRule(1, "p", "dept_admin_5", "/api/v1/dept/5/users/:id", "GET", "5", "allow"), testRule(1, "p", "dept_admin_6", "/api/v1/dept/6/users/:id", "GET", "6", "allow"), true},
+		{"部门路径本身", testRule(1, "p", "dev", "/api/v1/dept/5", "GET", "*", "allow"), testRule(1, "p", "dev", "/api/v1/dept/6", "GET", "*", "allow"), true},
+		{"前缀相同的其他部门路径", testRule(1, "p", "dev", "/api/v1/dept/50", "GET", "50", "allow"), testRule(1, "p", "dev", "/api/v1/dept/50", "GET", "50", "allow"), false},
+		{"前缀相同的其他部门管理员", testRule(1, "p", "dept_admin_50", "/biz/orders", "GET", "*", "allow"), testRule(1, "p", "dept_admin_50", "/biz/orders", "GET", "*", "allow"), false},
+		{"用户角色", testRule(1, "g", "user_10", "dept_admin_5"), testRule(1, "g", "user_10", "dept_admin_6"), true},
+		{"其他角色的用户角色", testRule(1, "g", "user_10", "dev"), testRule(1, "g", "user_10", "dev"), false},
+		{"g策略不改写部门维度", testRule(1, "g", "user_10", "dev", "5"), testRule(1, "g", "user_10", "dev", "5"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := *tt.rule
+			got, changed := w.rewrite(tt.rule)
+			if changed != tt.wantChanged || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rewrite() = (%v, %v), want (%v, %v)", casbinRuleValues(got), changed, casbinRuleValues(tt.want), tt.wantChanged)
+			}
+			if *tt.rule != original {
+				t.Error("rewrite() 修改了原策略")
+			}
+		})
+	}
+}
+
+func TestPlanDepartmentSplit(t *testing.T) {
+	// 用户10通过g策略、用户11通过用户角色关联拥有源部门管理员角色，用户12拥有dev和qa角色，用户13不转移
+	rules := []*repository.CasbinRule{
+		testRule(1, "g", "user_10", "dept_admin_5"),
+		testRule(2, "g", "user_12", "dev"),
+		testRule(3, "g", "user_13", "ops"),
+		testRule(4, "p", "dept_admin_5", "/api/v1/dept/5/*", "*", "5", "allow"),
+		testRule(5, "p", "dev", "/biz/orders", "GET", "5", "allow"),
+		testRule(6, "p", "dev", "/biz/orders", "GET", "*", "allow"),
+		testRule(7, "p", "ops", "/biz/reports", "GET", "5", "allow"),
+		testRule(8, "p", "qa", "/biz/cases", "POST", "5", "allow"),
+		testRule(9, "p", "qa", "/biz/cases", "POST", repository.NewDepartmentPlaceholder, "allow"),
+	}
+	userRoles := map[uint][]*entity.Role{
+		11: {{Code: "dept_admin_5"}},
+		12: {{Code: "qa"}},
+	}
+	adminRule := testRule(0, "p", "dept_admin_{new}", "/api/v1/dept/{new}/*", "*", "{new}", "allow")
+	devRule := testRule(0, "p", "dev", "/biz/orders", "GET", "{new}", "allow")
+
+	tests := []struct {
+		name           string
+		userIDs        []uint
+		wantAdmins     []uint
+		wantRoleAdmins []uint
+		wantAdded      []*repository.CasbinRule
+		wantUpdated    []*repository.CasbinRule
+	}{
+		{
+			name:           "转移部门管理员",
+			userIDs:        []uint{12, 11, 10},
+			wantAdmins:     []uint{11, 10},
+			wantRoleAdmins: []uint{11},
+			wantAdded:      []*repository.CasbinRule{adminRule, devRule},
+			wantUpdated:    []*repository.CasbinRule{testRule(1, "g", "user_10", "dept_admin_{new}")},
+		},
+		{
+			name:      "只转移普通用户",
+			userIDs:   []uint{12},
+			wantAdded: []*repository.CasbinRule{devRule},
+		},
+		{
+			name:    "转移的用户在源部门维度上没有策略",
+			userIDs: []uint{14},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			split := planDepartmentSplit("5", tt.userIDs, userRoles, rules)
+			if !reflect.DeepEqual(split.admins, tt.wantAdmins) || !reflect.DeepEqual(split.roleAdmins, tt.wantRoleAdmins) {
+				t.Errorf("admins = %v roleAdmins = %v, want %v %v", split.admins, split.roleAdmins, tt.wantAdmins, tt.wantRoleAdmins)
+			}
+			if !reflect.DeepEqual(split.added, tt.wantAdded) {
+				t.Errorf("added = %v, want %v", testRuleValues(split.added), testRuleValues(tt.wantAdded))
+			}
+			if !reflect.DeepEqual(split.updated, tt.wantUpdated) {
+				t.Errorf("updated = %v, want %v", testRuleValues(split.updated), testRuleValues(tt.wantUpdated))
+			}
+			for _, rule := range split.updated {
+				if split.original[rule.ID] != rules[rule.ID-1] {
+					t.Errorf("original[%d] 应为改写前的策略", rule.ID)
+				}
+			}
+		})
+	}
+	if rules[0].V1 != "dept_admin_5" || rules[3].V3 != "5" || rules[4].ID != 5 {
+		t.Error("planDepartmentSplit() 修改了原策略")
+	}
+}
+
+func TestDepartmentPlanMerge(t *testing.T) {
+	depts := &fakeDeptRepo{
+		depts: map[uint]*entity.Department{
+			5: {ID: 5, Name: "研发", Code: "rd"},
+			6: {ID: 6, Name: "平台", Code: "platform"},
+			7: {ID: 7, Name: "研发一组", Code: "rd-1", ParentID: 5},
+		},
+		descendants: map[uint][]*entity.Department{5: {{ID: 7}}},
+	}
+	rules := []*repository.CasbinRule{
+		testRule(1, "g", "user_10", "dept_admin_5"),
+		testRule(2, "g", "user_10", "dept_admin_6"),
+		testRule(3, "p", "dev", "/biz/orders", "GET", "5", "allow"),
+		testRule(4, "p", "dev", "/biz/orders", "GET", "6", "allow"),
+		testRule(5, "p", "dept_admin_5", "/api/v1/dept/5", "GET", "5", "allow"),
+		testRule(6, "p", "ops", "/biz/reports", "GET", "*", "allow"),
+	}
+	sourceAdmin := &entity.Role{ID: 20, Code: "dept_admin_5", Name: "研发部门管理员", DeptID: 5}
+	targetAdmin := &entity.Role{ID: 21, Code: "dept_admin_6", Name: "平台部门管理员", DeptID: 6}
+
+	tests := []struct {
+		name          string
+		target        uint
+		roles         []*entity.Role
+		wantErr       bool
+		wantAdminRole string
+		wantMerge     [2]uint
+		wantRename    *entity.Role
+	}{
+		{name: "合并到自身", target: 5, wantErr: true},
+		{name: "合并到下级部门", target: 7, wantErr: true},
+		{name: "目标部门不存在", target: 8, wantErr: true},
+		{name: "源部门没有管理员角色", target: 6},
+		{
+			name:          "并入目标部门的管理员角色",
+			target:        6,
+			roles:         []*entity.Role{sourceAdmin, targetAdmin},
+			wantAdminRole: DeptAdminRoleMerge,
+			wantMerge:     [2]uint{20, 21},
+		},
+		{
+			name:          "改为目标部门的管理员角色",
+			target:        6,
+			roles:         []*entity.Role{sourceAdmin},
+			wantAdminRole: DeptAdminRoleRename,
+			wantRename:    &entity.Role{ID: 20, Code: "dept_admin_6", Name: "平台部门管理员", Description: "平台部门的管理员角色", DeptID: 6},
+		},
+		{
+			name:          "目标名称已被占用时保留原名称",
+			target:        6,
+			roles:         []*entity.Role{sourceAdmin, {ID: 30, Code: "other", Name: "平台部门管理员"}},
+			wantAdminRole: DeptAdminRoleRename,
+			wantRename:    &entity.Role{ID: 20, Code: "dept_admin_6", Name: "研发部门管理员", DeptID: 6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewDepartmentReorgService(&fakeReorgRepo{rules: rules}, depts, &fakeRoleRepo{roles: tt.roles}, nil, nil)
+			preview, plan, err := s.planMerge(5, tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("planMerge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if preview.AdminRole != tt.wantAdminRole {
+				t.Errorf("AdminRole = %q, want %q", preview.AdminRole, tt.wantAdminRole)
+			}
+			if got := [2]uint{plan.MergeRoleID, plan.MergeIntoRoleID}; got != tt.wantMerge {
+				t.Errorf("合并角色 = %v, want %v", got, tt.wantMerge)
+			}
+			if !reflect.DeepEqual(plan.RenameRole, tt.wantRename) {
+				t.Errorf("RenameRole = %+v, want %+v", plan.RenameRole, tt.wantRename)
+			}
+
+			// 改写后与已有策略重复的删除，其余的原地更新
+			if want := []uint{1, 3}; !reflect.DeepEqual(plan.DeletedRuleIDs, want) {
+				t.Errorf("DeletedRuleIDs = %v, want %v", plan.DeletedRuleIDs, want)
+			}
+			wantUpdated := []*repository.CasbinRule{testRule(5, "p", "dept_admin_6", "/api/v1/dept/6", "GET", "6", "allow")}
+			if !reflect.DeepEqual(plan.UpdatedRules, wantUpdated) {
+				t.Errorf("UpdatedRules = %v, want %v", testRuleValues(plan.UpdatedRules), testRuleValues(wantUpdated))
+			}
+			if len(preview.PolicyChanges) != 3 || preview.PolicyChanges[0].After != nil || preview.PolicyChanges[2].After == nil {
+				t.Errorf("PolicyChanges = %+v", preview.PolicyChanges)
+			}
+		})
+	}
+}
+
+// testRuleValues 便于在失败信息中查看策略
+func testRuleValues(rules []*repository.CasbinRule) [][]string {
+	values := make([][]string, 0, len(rules))
+	for _, rule := range rules {
+		values = append(values, casbinRuleValues(rule))
+	}
+	return values
+}
@@ -2,7 +2,7 @@ package service
 
 import (
 	"errors"
-	"strings"
+	"sort"
 
 	"mcprapi/backend/internal/domain/entity"
 	"mcprapi/backend/internal/domain/repository"
@@ -11,6 +11,7 @@ import (
 // DepartmentService 部门服务
 type DepartmentService struct {
 	deptRepo repository.DepartmentRepository
+	deleter  EntityDeleter
 }
 
 // NewDepartmentService 创建部门服务
@@ -20,6 +21,11 @@ func NewDepartmentService(deptRepo repository.DepartmentRepository) *DepartmentS
 	}
 }
 
+// SetDeleter 设置级联删除，未设置时只删除部门本身
+func (s *DepartmentService) SetDeleter(deleter EntityDeleter) {
+	s.deleter = deleter
+}
+
 // CreateDepartmentRequest 创建部门请求
 type CreateDepartmentRequest struct {
 	Name     string `json:"name" binding:"required"`
@@ -40,6 +46,18 @@ type UpdateDepartmentRequest struct {
 	Status   int    `json:"status" binding:"oneof=0 1"`
 }
 
+// MoveDepartmentRequest 移动部门请求
+type MoveDepartmentRequest struct {
+	ParentID uint `json:"parent_id"`      // 新的父部门ID，0表示顶级部门
+	Sort     *int `json:"sort,omitempty"` // 排序，不传时保持不变
+}
+
+// DepartmentNode 部门树节点
+type DepartmentNode struct {
+	*entity.Department
+	Children []*DepartmentNode `json:"children"`
+}
+
 // CreateDepartment 创建部门
 func (s *DepartmentService) CreateDepartment(req *CreateDepartmentRequest) (*entity.Department, error) {
 	// 检查部门编码是否已存在
@@ -83,7 +101,7 @@ func (s *DepartmentService) CreateDepartment(req *CreateDepartmentRequest) (*ent
 func (s *DepartmentService) UpdateDepartment(req *UpdateDepartmentRequest) (*entity.Department, error) {
 	// 检查部门是否存在
 	dept, err := s.deptRepo.GetByID(req.ID)
-	if err != nil {
+	if err != nil || dept == nil {
 		return nil, errors.New("部门不存在")
 	}
 
@@ -109,6 +127,9 @@ func (s *DepartmentService) UpdateDepartment(req *UpdateDepartmentRequest) (*ent
 		if req.ParentID == req.ID {
 			return nil, errors.New("不能将自己设为父部门")
 		}
+		if err := s.checkNotDescendant(req.ID, req.ParentID); err != nil {
+			return nil, err
+		}
 	}
 
 	// 更新部门
@@ -135,6 +156,11 @@ func (s *DepartmentService) DeleteDepartment(id uint) error {
 		return errors.New("部门不存在")
 	}
 
+	// 按级联和限制规则删除
+	if s.deleter != nil {
+		return s.deleter.Delete(DeleteTargetDepartment, id)
+	}
+
 	// 检查是否有子部门
 	depts, err := s.deptRepo.List(id)
 	if err != nil {
@@ -185,34 +211,13 @@ func (s *DepartmentService) Get(id uint) (*entity.Department, error) {
 
 // List 获取部门列表（支持分页和查询）
 func (s *DepartmentService) List(page, size int, query string) ([]*entity.Department, int64, error) {
-	// 这里需要根据实际的repository接口来实现
-	// 暂时返回所有部门
-	departments, err := s.deptRepo.ListAll()
-	if err != nil {
-		return nil, 0, err
-	}
-	
-	// 简单的过滤逻辑
-	var filtered []*entity.Department
-	for _, dept := range departments {
-		if query == "" || strings.Contains(dept.Name, query) || strings.Contains(dept.Code, query) {
-			filtered = append(filtered, dept)
-		}
+	if page <= 0 {
+		page = 1
 	}
-	
-	total := int64(len(filtered))
-	
-	// 简单的分页逻辑
-	start := (page - 1) * size
-	end := start + size
-	if start > len(filtered) {
-		return []*entity.Department{}, total, nil
+	if size <= 0 {
+		size = 10
 	}
-	if end > len(filtered) {
-		end = len(filtered)
-	}
-	
-	return filtered[start:end], total, nil
+	return s.deptRepo.ListPage(page, size, query)
 }
 
 // GetChildren 获取子部门
@@ -220,16 +225,107 @@ func (s *DepartmentService) GetChildren(parentID uint) ([]*entity.Department, er
 	return s.deptRepo.List(parentID)
 }
 
-// GetTree 获取部门树
-func (s *DepartmentService) GetTree() ([]*entity.Department, error) {
-	// 获取所有部门，按层级和排序排列
+// GetTree 获取部门树，同级部门按排序和ID排列，父部门不存在的部门作为顶级部门
+func (s *DepartmentService) GetTree() ([]*DepartmentNode, error) {
 	allDepts, err := s.deptRepo.ListAll()
 	if err != nil {
 		return nil, err
 	}
-	
-	// 简单返回所有部门，前端可以根据ParentID构建树形结构
-	return allDepts, nil
+
+	nodes := make(map[uint]*DepartmentNode, len(allDepts))
+	for _, dept := range allDepts {
+		nodes[dept.ID] = &DepartmentNode{Department: dept, Children: []*DepartmentNode{}}
+	}
+
+	roots := make([]*DepartmentNode, 0)
+	for _, dept := range allDepts {
+		if parent, ok := nodes[dept.ParentID]; ok && dept.ParentID != dept.ID {
+			parent.Children = append(parent.Children, nodes[dept.ID])
+		} else {
+			roots = append(roots, nodes[dept.ID])
+		}
+	}
+
+	var sortNodes func(list []*DepartmentNode)
+	sortNodes = func(list []*DepartmentNode) {
+		sort.SliceStable(list, func(i, j int) bool {
+			if list[i].Sort != list[j].Sort {
+				return list[i].Sort < list[j].Sort
+			}
+			return list[i].ID < list[j].ID
+		})
+		for _, node := range list {
+			sortNodes(node.Children)
+		}
+	}
+	sortNodes(roots)
+	return roots, nil
+}
+
+// MoveDepartment 将部门连同其下级部门移动到新的父部门下，层级按新位置重新计算
+func (s *DepartmentService) MoveDepartment(id uint, req *MoveDepartmentRequest) (*entity.Department, error) {
+	dept, err := s.deptRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if dept == nil {
+		return nil, errors.New("部门不存在")
+	}
+
+	if req.ParentID > 0 {
+		parent, err := s.deptRepo.GetByID(req.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			return nil, errors.New("父部门不存在")
+		}
+		if req.ParentID == id {
+			return nil, errors.New("不能将自己设为父部门")
+		}
+		if err := s.checkNotDescendant(id, req.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.deptRepo.Move(id, req.ParentID); err != nil {
+		return nil, err
+	}
+	dept, err = s.deptRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Sort != nil && dept.Sort != *req.Sort {
+		dept.Sort = *req.Sort
+		if err := s.deptRepo.Update(dept); err != nil {
+			return nil, err
+		}
+	}
+	return dept, nil
+}
+
+// GetAncestors 获取部门的所有上级部门，由根部门到直接父部门排列
+func (s *DepartmentService) GetAncestors(id uint) ([]*entity.Department, error) {
+	return s.deptRepo.ListAncestors(id)
+}
+
+// GetDescendants 获取部门的所有下级部门
+func (s *DepartmentService) GetDescendants(id uint) ([]*entity.Department, error) {
+	return s.deptRepo.ListDescendants(id)
+}
+
+// checkNotDescendant 检查parentID不是部门的下级部门，防止形成循环
+func (s *DepartmentService) checkNotDescendant(id, parentID uint) error {
+	descendants, err := s.deptRepo.ListDescendants(id)
+	if err != nil {
+		return err
+	}
+	for _, descendant := range descendants {
+		if descendant.ID == parentID {
+			return errors.New("不能将下级部门设为父部门")
+		}
+	}
+	return nil
 }
 
 // GetTotalCount 获取部门总数
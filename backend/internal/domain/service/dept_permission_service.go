@@ -15,6 +15,12 @@ type DeptPermissionService struct {
 	roleRepo       repository.RoleRepository
 	deptRepo       repository.DepartmentRepository
 	casbinEnforcer *casbinx.Enforcer
+	grants         SystemGrantApplier
+}
+
+// SystemGrantApplier 将授予集合中已登记的系统API授予角色
+type SystemGrantApplier interface {
+	ApplySystemGrant(roleID uint, grant string, deptID uint) error
 }
 
 // NewDeptPermissionService 创建部门权限管理服务
@@ -51,6 +57,11 @@ type CheckDeptPermissionRequest struct {
 	Action string `json:"action" binding:"required"` // read, write, admin
 }
 
+// SetSystemGrantApplier 设置部门管理员角色的系统API授权
+func (s *DeptPermissionService) SetSystemGrantApplier(grants SystemGrantApplier) {
+	s.grants = grants
+}
+
 // GrantDeptAdmin 授予用户部门管理员权限
 func (s *DeptPermissionService) GrantDeptAdmin(req *GrantDeptAdminRequest) error {
 	// 检查用户是否存在
@@ -87,45 +98,20 @@ func (s *DeptPermissionService) GrantDeptAdmin(req *GrantDeptAdminRequest) error
 		}
 	}
 
+	// 按API ID授予已登记的部门管理系统API，限定在该部门；先授权再分配角色，授权失败时用户不会获得空角色
+	if s.grants == nil {
+		return errors.New("未配置系统API授权")
+	}
+	if err := s.grants.ApplySystemGrant(deptAdminRole.ID, SystemGrantDeptAdmin, req.DeptID); err != nil {
+		return fmt.Errorf("添加权限策略失败: %v", err)
+	}
+
 	// 为用户分配部门管理员角色
 	userRoleCode := fmt.Sprintf("user_%d", req.UserID)
 	if _, err := s.casbinEnforcer.AddRoleForUser(userRoleCode, deptAdminRoleCode); err != nil {
 		return fmt.Errorf("分配角色失败: %v", err)
 	}
 
-	// 添加部门管理员权限策略
-	policies := [][]string{
-		// 用户管理权限
-		{deptAdminRoleCode, fmt.Sprintf("/api/v1/dept/%d/users", req.DeptID), "GET", fmt.Sprintf("%d", req.DeptID), "allow"},
-		{deptAdminRoleCode, fmt.Sprintf("/api/v1/dept/%d/users", req.DeptID), "POST", fmt.Sprintf("%d", req.DeptID), "allow"},
-		{deptAdminRoleCode, fmt.Sprintf("/api/v1/dept/%d/users/*", req.DeptID), "PUT", fmt.Sprintf("%d", req.DeptID), "allow"},
-		{deptAdminRoleCode, fmt.Sprintf("/api/v1/dept/%d/users/*", req.DeptID), "DELETE", fmt.Sprintf("%d", req.DeptID), "allow"},
-
-		// 角色管理权限
-		{deptAdminRoleCode, fmt.Sprintf("/api/v1/dept/%d/roles", req.DeptID), "GET", fmt.Sprintf("%d", req.DeptID), "allow"},
-		{deptAdminRoleCode, fmt.Sprintf("/api/v1/dept/%d/roles", req.DeptID), "POST", fmt.Sprintf("%d", req.DeptID), "allow"},
-		{deptAdminRoleCode, fmt.Sprintf("/api/v1/dept/%d/roles/*", req.DeptID), "PUT", fmt.Sprintf("%d", req.DeptID), "allow"},
-		{deptAdminRoleCode, fmt.Sprintf("/api/v1/dept/%d/roles/*", req.DeptID), "DELETE", fmt.Sprintf("%d", req.DeptID), "allow"},
-
-		// 业务线管理权限
-		{deptAdminRoleCode, fmt.Sprintf("/api/v1/dept/%d/businesses", req.DeptID), "GET", fmt.Sprintf("%d", req.DeptID), "allow"},
-		{deptAdminRoleCode, fmt.Sprintf("/api/v1/dept/%d/businesses", req.DeptID), "POST", fmt.Sprintf("%d", req.DeptID), "allow"},
-		{deptAdminRoleCode, fmt.Sprintf("/api/v1/dept/%d/businesses/*", req.DeptID), "PUT", fmt.Sprintf("%d", req.DeptID), "allow"},
-		{deptAdminRoleCode, fmt.Sprintf("/api/v1/dept/%d/businesses/*", req.DeptID), "DELETE", fmt.Sprintf("%d", req.DeptID), "allow"},
-
-		// API管理权限（通过业务线）
-		{deptAdminRoleCode, "/api/v1/business/*/apis", "GET", fmt.Sprintf("%d", req.DeptID), "allow"},
-		{deptAdminRoleCode, "/api/v1/business/*/apis", "POST", fmt.Sprintf("%d", req.DeptID), "allow"},
-		{deptAdminRoleCode, "/api/v1/business/*/apis/*", "PUT", fmt.Sprintf("%d", req.DeptID), "allow"},
-		{deptAdminRoleCode, "/api/v1/business/*/apis/*", "DELETE", fmt.Sprintf("%d", req.DeptID), "allow"},
-	}
-
-	for _, policy := range policies {
-		if _, err := s.casbinEnforcer.AddPolicyWithDept(policy[0], policy[1], policy[2], policy[3], policy[4]); err != nil {
-			return fmt.Errorf("添加权限策略失败: %v", err)
-		}
-	}
-
 	// 同步策略到内存（安全方式）
 	return s.casbinEnforcer.SyncPolicyToMemory()
 }
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/internal/pkg/logger"
+)
+
+// ImpersonationConfig 代登录配置
+type ImpersonationConfig struct {
+	TTL          time.Duration `mapstructure:"ttl"`           // 代登录令牌最长有效期
+	AllowedRoles []string      `mapstructure:"allowed_roles"` // 可以发起代登录的角色，默认admin
+	ReadOnly     bool          `mapstructure:"read_only"`     // 只允许GET/HEAD/OPTIONS请求
+	Blocked      []string      `mapstructure:"blocked"`       // 代登录期间禁止的请求，格式为"METHOD 路径"，路径末尾的*匹配任意后缀，中间的*匹配单个路径段
+}
+
+// ImpersonateRequest 发起代登录请求
+type ImpersonateRequest struct {
+	Reason     string `json:"reason" binding:"required"` // 代登录原因，如工单号
+	TTLMinutes int    `json:"ttl_minutes"`               // 有效期（分钟），不超过配置的最长有效期
+}
+
+// ImpersonateResponse 发起代登录响应
+type ImpersonateResponse struct {
+	Token     string       `json:"token"`
+	ExpiresAt int64        `json:"expires_at"`
+	SessionID string       `json:"session_id"`
+	User      *entity.User `json:"user"`
+	Actor     *Actor       `json:"actor"`
+}
+
+// ImpersonationLogListRequest 代登录日志查询请求
+type ImpersonationLogListRequest struct {
+	ActorID   uint   `form:"actor_id"`
+	UserID    uint   `form:"user_id"`
+	SessionID string `form:"session_id"`
+	Page      int    `form:"page"`
+	PageSize  int    `form:"page_size"`
+}
+
+// ImpersonationLogListResponse 代登录日志列表响应
+type ImpersonationLogListResponse struct {
+	Total int64                      `json:"total"`
+	Items []*entity.ImpersonationLog `json:"items"`
+}
+
+// impersonationRule 代登录期间禁止的请求规则
+type impersonationRule struct {
+	method string
+	path   string
+}
+
+// ImpersonationService 管理员代登录服务
+type ImpersonationService struct {
+	cfg         ImpersonationConfig
+	rules       []impersonationRule
+	authService *AuthService
+	sessions    *SessionService
+	userRepo    repository.UserRepository
+	logRepo     repository.ImpersonationLogRepository
+	logger      *logger.Logger
+}
+
+// NewImpersonationService 创建代登录服务
+func NewImpersonationService(cfg ImpersonationConfig, authService *AuthService, sessions *SessionService, userRepo repository.UserRepository,
+	logRepo repository.ImpersonationLogRepository, logger *logger.Logger) *ImpersonationService {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 30 * time.Minute
+	}
+	if len(cfg.AllowedRoles) == 0 {
+		cfg.AllowedRoles = []string{"admin"}
+	}
+
+	var rules []impersonationRule
+	for _, item := range cfg.Blocked {
+		fields := strings.Fields(item)
+		if len(fields) != 2 {
+			logger.Warn("忽略无效的代登录限制规则: %s", item)
+			continue
+		}
+		rules = append(rules, impersonationRule{method: strings.ToUpper(fields[0]), path: fields[1]})
+	}
+
+	return &ImpersonationService{
+		cfg:         cfg,
+		rules:       rules,
+		authService: authService,
+		sessions:    sessions,
+		userRepo:    userRepo,
+		logRepo:     logRepo,
+		logger:      logger,
+	}
+}
+
+// Start 管理员代登录目标用户，返回携带act声明的令牌；不能代登录自己或同样可以代登录的用户
+func (s *ImpersonationService) Start(actorID, targetID uint, ip string, req *ImpersonateRequest) (*ImpersonateResponse, error) {
+	if actorID == targetID {
+		return nil, errors.New("不能代登录自己")
+	}
+
+	actor, err := s.userRepo.GetByID(actorID)
+	if err != nil {
+		return nil, err
+	}
+	if actor == nil {
+		return nil, errors.New("用户不存在")
+	}
+	allowed, err := s.canImpersonate(actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, errors.New("没有代登录权限")
+	}
+
+	target, err := s.userRepo.GetByID(targetID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, errors.New("目标用户不存在")
+	}
+	if target.Status != 1 {
+		return nil, errors.New("目标用户已禁用")
+	}
+	privileged, err := s.canImpersonate(targetID)
+	if err != nil {
+		return nil, err
+	}
+	if privileged {
+		return nil, errors.New("不能代登录管理员")
+	}
+
+	ttl := s.cfg.TTL
+	if req.TTLMinutes > 0 && time.Duration(req.TTLMinutes)*time.Minute < ttl {
+		ttl = time.Duration(req.TTLMinutes) * time.Minute
+	}
+	token, claims, err := s.authService.GenerateImpersonationToken(target, actor, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Record(&entity.ImpersonationLog{
+		SessionID:     claims.Id,
+		ActorID:       actor.ID,
+		ActorUsername: actor.Username,
+		UserID:        target.ID,
+		Username:      target.Username,
+		Action:        entity.ImpersonationActionStart,
+		Reason:        truncateString(req.Reason, 255),
+		IP:            ip,
+	})
+
+	return &ImpersonateResponse{
+		Token:     token,
+		ExpiresAt: claims.ExpiresAt,
+		SessionID: claims.Id,
+		User:      target,
+		Actor:     claims.Act,
+	}, nil
+}
+
+// Stop 结束代登录，终止代登录令牌对应的会话
+func (s *ImpersonationService) Stop(sessionID string, actor *Actor, userID uint, username, ip string) error {
+	if actor == nil {
+		return errors.New("当前令牌不是代登录令牌")
+	}
+	if sessionID != "" {
+		if err := s.sessions.Revoke(sessionID); err != nil {
+			return err
+		}
+	}
+
+	s.Record(&entity.ImpersonationLog{
+		SessionID:     sessionID,
+		ActorID:       actor.UserID,
+		ActorUsername: actor.Username,
+		UserID:        userID,
+		Username:      username,
+		Action:        entity.ImpersonationActionStop,
+		IP:            ip,
+	})
+	return nil
+}
+
+// IsBlocked 代登录期间是否禁止该请求
+func (s *ImpersonationService) IsBlocked(method, requestPath string) bool {
+	method = strings.ToUpper(method)
+	if s.cfg.ReadOnly && method != "GET" && method != "HEAD" && method != "OPTIONS" {
+		return true
+	}
+
+	for _, rule := range s.rules {
+		if rule.method != "*" && rule.method != method {
+			continue
+		}
+		if matched, _ := path.Match(rule.path, requestPath); matched {
+			return true
+		}
+		if strings.HasSuffix(rule.path, "*") && strings.HasPrefix(requestPath, strings.TrimSuffix(rule.path, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Record 写入代登录日志，失败时只记录日志
+func (s *ImpersonationService) Record(log *entity.ImpersonationLog) {
+	log.Path = truncateString(log.Path, 500)
+	if err := s.logRepo.Create(log); err != nil {
+		s.logger.Error("记录代登录日志失败: %v", err)
+	}
+}
+
+// ListLogs 分页查询代登录日志
+func (s *ImpersonationService) ListLogs(req *ImpersonationLogListRequest) (*ImpersonationLogListResponse, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+
+	logs, total, err := s.logRepo.List(&repository.ImpersonationLogQuery{
+		ActorID:   req.ActorID,
+		UserID:    req.UserID,
+		SessionID: req.SessionID,
+		Page:      req.Page,
+		PageSize:  req.PageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ImpersonationLogListResponse{Total: total, Items: logs}, nil
+}
+
+// canImpersonate 用户是否拥有可以发起代登录的角色
+func (s *ImpersonationService) canImpersonate(userID uint) (bool, error) {
+	roles, err := s.userRepo.GetUserRoles(userID)
+	if err != nil {
+		return false, err
+	}
+	for _, role := range roles {
+		if containsString(s.cfg.AllowedRoles, role) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,685 @@
+package service
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/internal/pkg/encrypt"
+	"mcprapi/backend/internal/pkg/logger"
+	"mcprapi/backend/pkg/ldapx"
+)
+
+// LDAPSyncConfig LDAP同步配置
+type LDAPSyncConfig struct {
+	RootDeptCode    string            `mapstructure:"root_dept_code"`    // 顶级OU挂载的部门编码，为空时作为顶级部门
+	DefaultDeptCode string            `mapstructure:"default_dept_code"` // 用户所在OU未同步时使用的部门编码
+	GroupRoles      map[string]string `mapstructure:"group_roles"`       // LDAP组名 -> 角色编码
+	Interval        time.Duration     `mapstructure:"interval"`          // 定时同步间隔，0表示不定时同步
+}
+
+// LDAPService LDAP认证与目录同步服务
+type LDAPService struct {
+	client   *ldapx.Client
+	cfg      LDAPSyncConfig
+	userRepo repository.UserRepository
+	deptRepo repository.DepartmentRepository
+	roleRepo repository.RoleRepository
+	sessions SessionRevoker
+	audit    AuditRecorder
+	logger   *logger.Logger
+
+	syncMu     sync.Mutex // 保证同一时间只有一个同步任务
+	reportMu   sync.RWMutex
+	lastReport *LDAPSyncReport
+}
+
+// NewLDAPService 创建LDAP服务
+func NewLDAPService(client *ldapx.Client, cfg LDAPSyncConfig, userRepo repository.UserRepository, deptRepo repository.DepartmentRepository, roleRepo repository.RoleRepository, logger *logger.Logger) *LDAPService {
+	// viper会将map的键转为小写，组名统一按小写匹配
+	groupRoles := make(map[string]string, len(cfg.GroupRoles))
+	for group, role := range cfg.GroupRoles {
+		groupRoles[strings.ToLower(group)] = role
+	}
+	cfg.GroupRoles = groupRoles
+
+	return &LDAPService{
+		client:   client,
+		cfg:      cfg,
+		userRepo: userRepo,
+		deptRepo: deptRepo,
+		roleRepo: roleRepo,
+		logger:   logger,
+	}
+}
+
+// SetSessionRevoker 设置会话终止，同步停用用户时终止其会话
+func (s *LDAPService) SetSessionRevoker(sessions SessionRevoker) {
+	s.sessions = sessions
+}
+
+// SetAuditRecorder 设置审计记录，定时同步和登录时刷新角色的修改写入审计日志
+func (s *LDAPService) SetAuditRecorder(audit AuditRecorder) {
+	s.audit = audit
+}
+
+// LDAPRoleChange 用户角色变更
+type LDAPRoleChange struct {
+	Username string   `json:"username"`
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+}
+
+// LDAPSyncReport 同步报告
+type LDAPSyncReport struct {
+	DryRun           bool             `json:"dry_run"`
+	StartedAt        time.Time        `json:"started_at"`
+	FinishedAt       time.Time        `json:"finished_at"`
+	DeptsCreated     []string         `json:"depts_created"`
+	DeptsUpdated     []string         `json:"depts_updated"`
+	DeptsDisabled    []string         `json:"depts_disabled"`
+	UsersCreated     []string         `json:"users_created"`
+	UsersUpdated     []string         `json:"users_updated"`
+	UsersDeactivated []string         `json:"users_deactivated"`
+	RoleChanges      []LDAPRoleChange `json:"role_changes"`
+	Errors           []string         `json:"errors"`
+}
+
+// ldapSyncState 同步过程中的本地数据快照
+type ldapSyncState struct {
+	deptByDN   map[string]*entity.Department
+	userByDN   map[string]*entity.User
+	userByName map[string]*entity.User
+	userByMail map[string]*entity.User
+	roleByCode map[string]*entity.Role
+}
+
+// Authenticate 实现ExternalAuthenticator，LDAP认证成功后同步用户信息和角色
+func (s *LDAPService) Authenticate(username, password string) (*entity.User, error) {
+	dirUser, err := s.client.Authenticate(username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByUsername(dirUser.Username)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil && user.Source != entity.SourceLDAP {
+		return nil, errors.New("本地账号不能通过LDAP登录")
+	}
+
+	depts, err := s.deptRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	deptByDN := make(map[string]*entity.Department)
+	for _, dept := range depts {
+		if dept.Source == entity.SourceLDAP && dept.ExternalID != "" {
+			deptByDN[dept.ExternalID] = dept
+		}
+	}
+
+	deptID, err := s.resolveUserDept(dirUser, deptByDN, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		if existing, err := s.userRepo.GetByEmail(s.userEmail(dirUser)); err != nil {
+			return nil, err
+		} else if existing != nil {
+			return nil, errors.New("邮箱已被其他用户使用")
+		}
+		user, err = s.newUser(dirUser, deptID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.userRepo.Create(user); err != nil {
+			return nil, err
+		}
+	} else if s.updateUser(user, dirUser, deptID) {
+		if err := s.userRepo.Update(user); err != nil {
+			return nil, err
+		}
+	}
+
+	// 登录时按组成员关系刷新角色
+	if len(s.cfg.GroupRoles) > 0 {
+		groups, err := s.client.GroupsOf(dirUser.DN)
+		if err != nil {
+			return nil, err
+		}
+		roles, err := s.roleRepo.ListAll()
+		if err != nil {
+			return nil, err
+		}
+		roleByCode := make(map[string]*entity.Role, len(roles))
+		for _, role := range roles {
+			roleByCode[role.Code] = role
+		}
+		change, err := s.applyRoles(user, s.desiredRoles(groups, dirUser.DN), roleByCode, false)
+		if err != nil {
+			return nil, err
+		}
+		if change != nil && s.audit != nil {
+			s.audit.Record(&AuditEvent{
+				Action:     "ldap.assign_roles",
+				TargetType: AuditTargetUserRole,
+				TargetIDs:  []string{strconv.FormatUint(uint64(user.ID), 10)},
+				Detail:     change,
+			})
+		}
+	}
+
+	return user, nil
+}
+
+// Sync 同步目录，dryRun为true时只生成报告不写入数据
+func (s *LDAPService) Sync(dryRun bool) (*LDAPSyncReport, error) {
+	if !s.syncMu.TryLock() {
+		return nil, errors.New("LDAP同步正在进行中")
+	}
+	defer s.syncMu.Unlock()
+
+	report := &LDAPSyncReport{DryRun: dryRun, StartedAt: time.Now()}
+
+	ous, err := s.client.ListOUs()
+	if err != nil {
+		return nil, err
+	}
+	dirUsers, err := s.client.ListUsers()
+	if err != nil {
+		return nil, err
+	}
+	var groups []*ldapx.Group
+	if len(s.cfg.GroupRoles) > 0 {
+		if groups, err = s.client.ListGroups(); err != nil {
+			return nil, err
+		}
+	}
+
+	state, err := s.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.syncDepartments(ous, state, report, dryRun); err != nil {
+		return nil, err
+	}
+	if err := s.syncUsers(dirUsers, groups, state, report, dryRun); err != nil {
+		return nil, err
+	}
+
+	report.FinishedAt = time.Now()
+	if !dryRun {
+		s.reportMu.Lock()
+		s.lastReport = report
+		s.reportMu.Unlock()
+	}
+	return report, nil
+}
+
+// LastReport 获取最近一次实际执行的同步报告
+func (s *LDAPService) LastReport() *LDAPSyncReport {
+	s.reportMu.RLock()
+	defer s.reportMu.RUnlock()
+	return s.lastReport
+}
+
+// Start 启动定时同步，ctx取消后停止
+func (s *LDAPService) Start(ctx context.Context) {
+	if s.cfg.Interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report, err := s.Sync(false)
+				if err != nil {
+					s.logger.Error("LDAP定时同步失败: %v", err)
+					s.record(nil, err)
+					continue
+				}
+				if report.changed() {
+					s.record(report, nil)
+				}
+				s.logger.Info("LDAP定时同步完成: 新增部门%d 新增用户%d 更新用户%d 停用用户%d 错误%d",
+					len(report.DeptsCreated), len(report.UsersCreated), len(report.UsersUpdated),
+					len(report.UsersDeactivated), len(report.Errors))
+			}
+		}
+	}()
+}
+
+// record 记录定时同步的结果
+func (s *LDAPService) record(report *LDAPSyncReport, err error) {
+	if s.audit == nil {
+		return
+	}
+	event := &AuditEvent{Action: "ldap.sync", TargetType: AuditTargetLDAP, Err: err}
+	if report != nil {
+		event.Detail = report
+	}
+	s.audit.Record(event)
+}
+
+// changed 同步是否修改了数据或出现错误
+func (r *LDAPSyncReport) changed() bool {
+	return len(r.DeptsCreated)+len(r.DeptsUpdated)+len(r.DeptsDisabled)+len(r.UsersCreated)+len(r.UsersUpdated)+
+		len(r.UsersDeactivated)+len(r.RoleChanges)+len(r.Errors) > 0
+}
+
+// loadState 加载本地部门、用户和角色
+func (s *LDAPService) loadState() (*ldapSyncState, error) {
+	state := &ldapSyncState{
+		deptByDN:   make(map[string]*entity.Department),
+		userByDN:   make(map[string]*entity.User),
+		userByName: make(map[string]*entity.User),
+		userByMail: make(map[string]*entity.User),
+		roleByCode: make(map[string]*entity.Role),
+	}
+
+	depts, err := s.deptRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, dept := range depts {
+		if dept.Source == entity.SourceLDAP && dept.ExternalID != "" {
+			state.deptByDN[dept.ExternalID] = dept
+		}
+	}
+
+	users, err := s.userRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if user.Source == entity.SourceLDAP && user.ExternalID != "" {
+			state.userByDN[user.ExternalID] = user
+		}
+		state.userByName[user.Username] = user
+		state.userByMail[strings.ToLower(user.Email)] = user
+	}
+
+	roles, err := s.roleRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		state.roleByCode[role.Code] = role
+	}
+
+	return state, nil
+}
+
+// syncDepartments 将OU同步为部门，保持上下级关系
+func (s *LDAPService) syncDepartments(ous []*ldapx.OU, state *ldapSyncState, report *LDAPSyncReport, dryRun bool) error {
+	var rootDept *entity.Department
+	if s.cfg.RootDeptCode != "" {
+		dept, err := s.deptRepo.GetByCode(s.cfg.RootDeptCode)
+		if err != nil {
+			return err
+		}
+		if dept == nil {
+			return fmt.Errorf("根部门不存在: %s", s.cfg.RootDeptCode)
+		}
+		rootDept = dept
+	}
+
+	ouByDN := make(map[string]*ldapx.OU, len(ous))
+	for _, ou := range ous {
+		ouByDN[ou.DN] = ou
+	}
+
+	// 按DN排序保证结果稳定，父级在ensure中递归优先处理
+	sort.Slice(ous, func(i, j int) bool { return ous[i].DN < ous[j].DN })
+
+	done := make(map[string]bool, len(ous))
+	var ensure func(ou *ldapx.OU) error
+	ensure = func(ou *ldapx.OU) error {
+		if done[ou.DN] {
+			return nil
+		}
+		done[ou.DN] = true
+
+		parentID, level := uint(0), 1
+		if parent, ok := ouByDN[ou.ParentDN]; ok {
+			if err := ensure(parent); err != nil {
+				return err
+			}
+			parentDept := state.deptByDN[parent.DN]
+			parentID, level = parentDept.ID, parentDept.Level+1
+		} else if rootDept != nil {
+			parentID, level = rootDept.ID, rootDept.Level+1
+		}
+
+		dept, exists := state.deptByDN[ou.DN]
+		if !exists {
+			dept = &entity.Department{
+				Name:       ou.Name,
+				Code:       ldapDeptCode(ou.DN),
+				ParentID:   parentID,
+				Level:      level,
+				Status:     1,
+				Source:     entity.SourceLDAP,
+				ExternalID: ou.DN,
+			}
+			if !dryRun {
+				if err := s.deptRepo.Create(dept); err != nil {
+					return fmt.Errorf("创建部门 %s 失败: %v", ou.DN, err)
+				}
+			}
+			state.deptByDN[ou.DN] = dept
+			report.DeptsCreated = append(report.DeptsCreated, ou.DN)
+			return nil
+		}
+
+		if dept.Name == ou.Name && dept.ParentID == parentID && dept.Level == level && dept.Status == 1 {
+			return nil
+		}
+		dept.Name = ou.Name
+		dept.ParentID = parentID
+		dept.Level = level
+		dept.Status = 1
+		if !dryRun {
+			if err := s.deptRepo.Update(dept); err != nil {
+				return fmt.Errorf("更新部门 %s 失败: %v", ou.DN, err)
+			}
+		}
+		report.DeptsUpdated = append(report.DeptsUpdated, ou.DN)
+		return nil
+	}
+
+	for _, ou := range ous {
+		if err := ensure(ou); err != nil {
+			return err
+		}
+	}
+
+	// 目录中已删除的OU对应的部门停用，保留数据以免影响已有权限
+	for dn, dept := range state.deptByDN {
+		if _, ok := ouByDN[dn]; ok || dept.Status != 1 {
+			continue
+		}
+		dept.Status = 0
+		if !dryRun {
+			if err := s.deptRepo.Update(dept); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("停用部门 %s 失败: %v", dn, err))
+				continue
+			}
+		}
+		report.DeptsDisabled = append(report.DeptsDisabled, dn)
+	}
+	sort.Strings(report.DeptsDisabled)
+
+	return nil
+}
+
+// syncUsers 同步用户、角色，并停用目录中已删除的用户
+func (s *LDAPService) syncUsers(dirUsers []*ldapx.User, groups []*ldapx.Group, state *ldapSyncState, report *LDAPSyncReport, dryRun bool) error {
+	sort.Slice(dirUsers, func(i, j int) bool { return dirUsers[i].DN < dirUsers[j].DN })
+
+	active := make(map[string]bool, len(dirUsers))
+	for _, dirUser := range dirUsers {
+		if dirUser.Disabled {
+			continue
+		}
+
+		user := state.userByDN[dirUser.DN]
+		if user == nil {
+			if existing, ok := state.userByName[dirUser.Username]; ok {
+				if existing.Source != entity.SourceLDAP {
+					// 不自动接管同名本地账号，避免目录用户获得本地账号的权限
+					report.Errors = append(report.Errors, fmt.Sprintf("用户 %s: 本地已存在同名账号", dirUser.Username))
+					continue
+				}
+				user = existing
+			}
+		}
+
+		deptID, err := s.resolveUserDept(dirUser, state.deptByDN, user)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("用户 %s: %v", dirUser.Username, err))
+			continue
+		}
+
+		email := strings.ToLower(s.userEmail(dirUser))
+		if owner, ok := state.userByMail[email]; ok && (user == nil || owner.ID != user.ID) {
+			report.Errors = append(report.Errors, fmt.Sprintf("用户 %s: 邮箱 %s 已被 %s 使用", dirUser.Username, email, owner.Username))
+			continue
+		}
+
+		if user == nil {
+			user, err = s.newUser(dirUser, deptID)
+			if err != nil {
+				return err
+			}
+			if !dryRun {
+				if err := s.userRepo.Create(user); err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("创建用户 %s 失败: %v", dirUser.Username, err))
+					continue
+				}
+			}
+			state.userByName[user.Username] = user
+			state.userByMail[email] = user
+			report.UsersCreated = append(report.UsersCreated, user.Username)
+		} else if s.updateUser(user, dirUser, deptID) {
+			if !dryRun {
+				if err := s.userRepo.Update(user); err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("更新用户 %s 失败: %v", dirUser.Username, err))
+					continue
+				}
+			}
+			state.userByMail[email] = user
+			report.UsersUpdated = append(report.UsersUpdated, user.Username)
+		}
+		active[dirUser.DN] = true
+
+		if len(s.cfg.GroupRoles) > 0 {
+			change, err := s.applyRoles(user, s.desiredRoles(groups, dirUser.DN), state.roleByCode, dryRun)
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("同步用户 %s 角色失败: %v", user.Username, err))
+				continue
+			}
+			if change != nil {
+				report.RoleChanges = append(report.RoleChanges, *change)
+			}
+		}
+	}
+
+	// 目录中已删除或禁用的用户停用，并终止其会话使已签发的令牌立即失效
+	for dn, user := range state.userByDN {
+		if active[dn] || user.Status != 1 {
+			continue
+		}
+		user.Status = 0
+		if !dryRun {
+			if err := s.userRepo.Update(user); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("停用用户 %s 失败: %v", user.Username, err))
+				continue
+			}
+			if s.sessions != nil {
+				if _, err := s.sessions.RevokeAll(user.ID, ""); err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("终止用户 %s 的会话失败: %v", user.Username, err))
+				}
+			}
+		}
+		report.UsersDeactivated = append(report.UsersDeactivated, user.Username)
+	}
+	sort.Strings(report.UsersDeactivated)
+
+	return nil
+}
+
+// resolveUserDept 确定用户所属部门：所在OU > 默认部门 > 原部门
+func (s *LDAPService) resolveUserDept(dirUser *ldapx.User, deptByDN map[string]*entity.Department, user *entity.User) (uint, error) {
+	if dept, ok := deptByDN[dirUser.ParentDN]; ok {
+		return dept.ID, nil
+	}
+	if s.cfg.DefaultDeptCode != "" {
+		dept, err := s.deptRepo.GetByCode(s.cfg.DefaultDeptCode)
+		if err != nil {
+			return 0, err
+		}
+		if dept != nil {
+			return dept.ID, nil
+		}
+	}
+	if user != nil && user.DeptID > 0 {
+		return user.DeptID, nil
+	}
+	return 0, errors.New("无法确定用户所属部门")
+}
+
+// desiredRoles 根据组成员关系计算用户应有的角色编码
+func (s *LDAPService) desiredRoles(groups []*ldapx.Group, userDN string) map[string]bool {
+	roles := make(map[string]bool)
+	for _, group := range groups {
+		roleCode, ok := s.cfg.GroupRoles[strings.ToLower(group.Name)]
+		if !ok {
+			continue
+		}
+		for _, member := range group.Members {
+			if member == userDN {
+				roles[roleCode] = true
+				break
+			}
+		}
+	}
+	return roles
+}
+
+// applyRoles 只调整由组映射管理的角色，保留手工分配的其他角色
+func (s *LDAPService) applyRoles(user *entity.User, desired map[string]bool, roleByCode map[string]*entity.Role, dryRun bool) (*LDAPRoleChange, error) {
+	managed := make(map[string]bool, len(s.cfg.GroupRoles))
+	for _, roleCode := range s.cfg.GroupRoles {
+		managed[roleCode] = true
+	}
+
+	var current []string
+	if user.ID > 0 {
+		var err error
+		if current, err = s.userRepo.GetUserRoles(user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	change := &LDAPRoleChange{Username: user.Username}
+	next := make(map[string]bool)
+	for _, roleCode := range current {
+		if managed[roleCode] && !desired[roleCode] {
+			change.Removed = append(change.Removed, roleCode)
+			continue
+		}
+		next[roleCode] = true
+	}
+	for roleCode := range desired {
+		if !next[roleCode] {
+			if _, ok := roleByCode[roleCode]; !ok {
+				return nil, fmt.Errorf("角色不存在: %s", roleCode)
+			}
+			change.Added = append(change.Added, roleCode)
+			next[roleCode] = true
+		}
+	}
+
+	if len(change.Added) == 0 && len(change.Removed) == 0 {
+		return nil, nil
+	}
+	sort.Strings(change.Added)
+	sort.Strings(change.Removed)
+
+	if !dryRun {
+		roleIDs := make([]uint, 0, len(next))
+		for roleCode := range next {
+			if role, ok := roleByCode[roleCode]; ok {
+				roleIDs = append(roleIDs, role.ID)
+			}
+		}
+		if err := s.userRepo.AssignRoles(user.ID, roleIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	return change, nil
+}
+
+// newUser 根据目录用户构造本地用户
+func (s *LDAPService) newUser(dirUser *ldapx.User, deptID uint) (*entity.User, error) {
+	password, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	passwordHash, err := encrypt.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	name := dirUser.Name
+	if name == "" {
+		name = dirUser.Username
+	}
+
+	return &entity.User{
+		Username:   dirUser.Username,
+		Name:       name,
+		Email:      s.userEmail(dirUser),
+		Password:   passwordHash, // 随机密码，LDAP用户始终通过目录认证
+		DeptID:     deptID,
+		Status:     1,
+		Source:     entity.SourceLDAP,
+		ExternalID: dirUser.DN,
+	}, nil
+}
+
+// updateUser 用目录数据更新本地用户，返回是否有变化；不修改状态，已停用的用户需管理员重新启用
+func (s *LDAPService) updateUser(user *entity.User, dirUser *ldapx.User, deptID uint) bool {
+	name := dirUser.Name
+	if name == "" {
+		name = dirUser.Username
+	}
+	email := s.userEmail(dirUser)
+
+	if user.Name == name && user.Email == email && user.DeptID == deptID &&
+		user.Source == entity.SourceLDAP && user.ExternalID == dirUser.DN {
+		return false
+	}
+
+	user.Name = name
+	user.Email = email
+	user.DeptID = deptID
+	user.Source = entity.SourceLDAP
+	user.ExternalID = dirUser.DN
+	return true
+}
+
+// userEmail 目录中没有邮箱时生成占位邮箱，满足唯一索引
+func (s *LDAPService) userEmail(dirUser *ldapx.User) string {
+	if dirUser.Email != "" {
+		return dirUser.Email
+	}
+	return dirUser.Username + "@ldap.local"
+}
+
+// ldapDeptCode 根据OU的DN生成稳定的部门编码
+func ldapDeptCode(dn string) string {
+	sum := sha1.Sum([]byte(dn))
+	return "ldap_" + hex.EncodeToString(sum[:])[:16]
+}
@@ -0,0 +1,401 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/internal/infrastructure/cache"
+	"mcprapi/backend/internal/pkg/logger"
+)
+
+// 登录防护在Redis中的键前缀
+const (
+	loginFailUserKeyPrefix  = "login:fail:user:"  // 用户名失败滑动窗口
+	loginFailIPKeyPrefix    = "login:fail:ip:"    // IP失败滑动窗口
+	loginLockUserKeyPrefix  = "login:lock:user:"  // 用户名锁定
+	loginLockIPKeyPrefix    = "login:lock:ip:"    // IP锁定
+	loginDelayUserKeyPrefix = "login:delay:user:" // 用户名递增延迟
+)
+
+// LoginGuardConfig 登录防暴力破解配置
+type LoginGuardConfig struct {
+	Window               time.Duration `mapstructure:"window"`                 // 失败次数统计的滑动窗口
+	UserMaxFailures      int           `mapstructure:"user_max_failures"`      // 同一用户名窗口内最大失败次数，达到后锁定
+	IPMaxFailures        int           `mapstructure:"ip_max_failures"`        // 同一IP窗口内最大失败次数，达到后锁定
+	LockoutDuration      time.Duration `mapstructure:"lockout_duration"`       // 锁定时长
+	DelayAfter           int           `mapstructure:"delay_after"`            // 失败次数达到后开始递增延迟
+	DelayBase            time.Duration `mapstructure:"delay_base"`             // 首次延迟，之后每次失败翻倍
+	DelayMax             time.Duration `mapstructure:"delay_max"`              // 最大延迟
+	HistoryRetentionDays int           `mapstructure:"history_retention_days"` // 登录历史保留天数，0表示不清理
+	NotifyWebhook        string        `mapstructure:"notify_webhook"`         // 锁定通知地址（POST JSON），为空时只记录日志
+}
+
+// LockoutEvent 锁定事件
+type LockoutEvent struct {
+	Scope     string    `json:"scope"` // user 或 ip
+	UserID    uint      `json:"user_id,omitempty"`
+	Username  string    `json:"username,omitempty"`
+	IP        string    `json:"ip"`
+	Failures  int64     `json:"failures"`
+	LockedAt  time.Time `json:"locked_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LockoutNotifier 锁定通知
+type LockoutNotifier interface {
+	NotifyLockout(event *LockoutEvent)
+}
+
+// LoginLockStatus 用户锁定状态
+type LoginLockStatus struct {
+	Locked           bool  `json:"locked"`
+	RemainingSeconds int64 `json:"remaining_seconds"`
+	RecentFailures   int64 `json:"recent_failures"` // 滑动窗口内的失败次数
+}
+
+// LoginAttemptListRequest 登录历史查询请求
+type LoginAttemptListRequest struct {
+	Page     int `form:"page"`
+	PageSize int `form:"page_size"`
+}
+
+// LoginAttemptListResponse 登录历史响应
+type LoginAttemptListResponse struct {
+	Lock  *LoginLockStatus       `json:"lock"`
+	Total int64                  `json:"total"`
+	Items []*entity.LoginAttempt `json:"items"`
+}
+
+// LoginGuardService 登录防暴力破解服务
+type LoginGuardService struct {
+	cfg         LoginGuardConfig
+	attemptRepo repository.LoginAttemptRepository
+	userRepo    repository.UserRepository
+	cache       *cache.Redis
+	logger      *logger.Logger
+	notifiers   []LockoutNotifier
+}
+
+// NewLoginGuardService 创建登录防暴力破解服务，未配置的参数使用默认值
+func NewLoginGuardService(cfg LoginGuardConfig, attemptRepo repository.LoginAttemptRepository, userRepo repository.UserRepository, cache *cache.Redis, logger *logger.Logger) *LoginGuardService {
+	if cfg.Window <= 0 {
+		cfg.Window = 15 * time.Minute
+	}
+	if cfg.UserMaxFailures <= 0 {
+		cfg.UserMaxFailures = 5
+	}
+	if cfg.IPMaxFailures <= 0 {
+		cfg.IPMaxFailures = 20
+	}
+	if cfg.LockoutDuration <= 0 {
+		cfg.LockoutDuration = 15 * time.Minute
+	}
+	if cfg.DelayAfter <= 0 {
+		cfg.DelayAfter = 3
+	}
+	if cfg.DelayBase <= 0 {
+		cfg.DelayBase = time.Second
+	}
+	if cfg.DelayMax <= 0 {
+		cfg.DelayMax = 30 * time.Second
+	}
+
+	s := &LoginGuardService{
+		cfg:         cfg,
+		attemptRepo: attemptRepo,
+		userRepo:    userRepo,
+		cache:       cache,
+		logger:      logger,
+		notifiers:   []LockoutNotifier{&logLockoutNotifier{logger: logger}},
+	}
+	if cfg.NotifyWebhook != "" {
+		s.AddNotifier(&webhookLockoutNotifier{url: cfg.NotifyWebhook, logger: logger, client: &http.Client{Timeout: 5 * time.Second}})
+	}
+	return s
+}
+
+// AddNotifier 添加锁定通知
+func (s *LoginGuardService) AddNotifier(notifier LockoutNotifier) {
+	s.notifiers = append(s.notifiers, notifier)
+}
+
+// Check 校验用户名和IP是否处于锁定或延迟中，Redis不可用时放行
+func (s *LoginGuardService) Check(req *LoginRequest) error {
+	username := normalizeLoginName(req.Username)
+
+	if ttl := s.ttl(loginLockUserKeyPrefix + username); ttl > 0 {
+		return fmt.Errorf("账号已被临时锁定，请在%d分钟后重试", ceilUnit(ttl, time.Minute))
+	}
+	if req.ClientIP != "" {
+		if ttl := s.ttl(loginLockIPKeyPrefix + req.ClientIP); ttl > 0 {
+			return fmt.Errorf("登录失败次数过多，请在%d分钟后重试", ceilUnit(ttl, time.Minute))
+		}
+	}
+	if ttl := s.ttl(loginDelayUserKeyPrefix + username); ttl > 0 {
+		return fmt.Errorf("登录过于频繁，请在%d秒后重试", ceilUnit(ttl, time.Second))
+	}
+	return nil
+}
+
+// Fail 记录失败，达到阈值后递增延迟或锁定
+func (s *LoginGuardService) Fail(req *LoginRequest, user *entity.User, reason string) {
+	s.record(req, user, false, reason)
+
+	now := time.Now()
+	username := normalizeLoginName(req.Username)
+
+	failures, err := s.cache.AddWindowEvent(loginFailUserKeyPrefix+username, now, s.cfg.Window)
+	if err != nil {
+		s.logger.Warn("记录登录失败次数失败: %v", err)
+		return
+	}
+	if failures >= int64(s.cfg.UserMaxFailures) {
+		event := &LockoutEvent{Scope: "user", Username: req.Username, IP: req.ClientIP, Failures: failures, LockedAt: now, ExpiresAt: now.Add(s.cfg.LockoutDuration)}
+		if user != nil {
+			event.UserID = user.ID
+		}
+		s.lock(loginLockUserKeyPrefix+username, event)
+	} else if failures >= int64(s.cfg.DelayAfter) {
+		if err := s.cache.Set(loginDelayUserKeyPrefix+username, now.Unix(), s.delay(failures)); err != nil {
+			s.logger.Warn("设置登录延迟失败: %v", err)
+		}
+	}
+
+	if req.ClientIP == "" {
+		return
+	}
+	ipFailures, err := s.cache.AddWindowEvent(loginFailIPKeyPrefix+req.ClientIP, now, s.cfg.Window)
+	if err != nil {
+		s.logger.Warn("记录登录失败次数失败: %v", err)
+		return
+	}
+	if ipFailures >= int64(s.cfg.IPMaxFailures) {
+		s.lock(loginLockIPKeyPrefix+req.ClientIP, &LockoutEvent{Scope: "ip", IP: req.ClientIP, Failures: ipFailures, LockedAt: now, ExpiresAt: now.Add(s.cfg.LockoutDuration)})
+	}
+}
+
+// Succeed 记录成功并清除该用户名的失败计数
+func (s *LoginGuardService) Succeed(req *LoginRequest, user *entity.User) {
+	s.record(req, user, true, "")
+	s.clearUser(normalizeLoginName(req.Username))
+}
+
+// Unlock 管理员解除用户锁定
+func (s *LoginGuardService) Unlock(userID uint) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("用户不存在")
+	}
+	return s.clearUser(normalizeLoginName(user.Username))
+}
+
+// UnlockIP 管理员解除IP锁定
+func (s *LoginGuardService) UnlockIP(ip string) error {
+	if ip == "" {
+		return errors.New("IP不能为空")
+	}
+	if err := s.cache.Delete(loginLockIPKeyPrefix + ip); err != nil {
+		return err
+	}
+	return s.cache.Delete(loginFailIPKeyPrefix + ip)
+}
+
+// GetLockStatus 获取用户锁定状态
+func (s *LoginGuardService) GetLockStatus(userID uint) (*LoginLockStatus, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	username := normalizeLoginName(user.Username)
+	status := &LoginLockStatus{}
+	if ttl := s.ttl(loginLockUserKeyPrefix + username); ttl > 0 {
+		status.Locked = true
+		status.RemainingSeconds = ceilUnit(ttl, time.Second)
+	}
+	status.RecentFailures, _ = s.cache.CountWindowEvents(loginFailUserKeyPrefix+username, time.Now(), s.cfg.Window)
+	return status, nil
+}
+
+// ListAttempts 分页获取用户的登录历史
+func (s *LoginGuardService) ListAttempts(userID uint, req *LoginAttemptListRequest) (*LoginAttemptListResponse, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+
+	lock, err := s.GetLockStatus(userID)
+	if err != nil {
+		return nil, err
+	}
+	attempts, total, err := s.attemptRepo.ListByUser(userID, req.Page, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginAttemptListResponse{Lock: lock, Total: total, Items: attempts}, nil
+}
+
+// Start 启动登录历史定期清理，ctx取消后停止
+func (s *LoginGuardService) Start(ctx context.Context) {
+	if s.cfg.HistoryRetentionDays <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+
+		for {
+			cutoff := time.Now().AddDate(0, 0, -s.cfg.HistoryRetentionDays)
+			if deleted, err := s.attemptRepo.DeleteBefore(cutoff); err != nil {
+				s.logger.Error("清理登录历史失败: %v", err)
+			} else if deleted > 0 {
+				s.logger.Info("清理登录历史%d条", deleted)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// lock 设置锁定并发送通知
+func (s *LoginGuardService) lock(key string, event *LockoutEvent) {
+	// 锁定期间的重复失败不再重复通知
+	ok, err := s.cache.SetNX(key, event.LockedAt.Unix(), s.cfg.LockoutDuration)
+	if err != nil {
+		s.logger.Warn("设置登录锁定失败: %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	for _, notifier := range s.notifiers {
+		notifier.NotifyLockout(event)
+	}
+}
+
+// clearUser 清除用户名的锁定、延迟和失败计数
+func (s *LoginGuardService) clearUser(username string) error {
+	for _, prefix := range []string{loginLockUserKeyPrefix, loginDelayUserKeyPrefix, loginFailUserKeyPrefix} {
+		if err := s.cache.Delete(prefix + username); err != nil {
+			s.logger.Warn("清除登录限制失败: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// record 写入登录历史，失败时只记录日志
+func (s *LoginGuardService) record(req *LoginRequest, user *entity.User, success bool, reason string) {
+	attempt := &entity.LoginAttempt{
+		Username:  truncateString(req.Username, 50),
+		IP:        req.ClientIP,
+		UserAgent: truncateString(req.UserAgent, 500),
+		Success:   success,
+		Reason:    truncateString(reason, 255),
+	}
+	if user != nil {
+		attempt.UserID = user.ID
+	}
+	if err := s.attemptRepo.Create(attempt); err != nil {
+		s.logger.Warn("记录登录历史失败: %v", err)
+	}
+}
+
+// delay 计算递增延迟：DelayBase * 2^(failures-DelayAfter)，不超过DelayMax
+func (s *LoginGuardService) delay(failures int64) time.Duration {
+	delay := s.cfg.DelayBase
+	for i := int64(s.cfg.DelayAfter); i < failures && delay < s.cfg.DelayMax; i++ {
+		delay *= 2
+	}
+	if delay > s.cfg.DelayMax {
+		delay = s.cfg.DelayMax
+	}
+	return delay
+}
+
+// ttl 获取键的剩余有效期，出错时视为不存在
+func (s *LoginGuardService) ttl(key string) time.Duration {
+	ttl, err := s.cache.TTL(key)
+	if err != nil {
+		s.logger.Warn("查询登录限制失败: %v", err)
+		return 0
+	}
+	return ttl
+}
+
+// logLockoutNotifier 记录锁定日志
+type logLockoutNotifier struct {
+	logger *logger.Logger
+}
+
+// NotifyLockout 记录锁定日志
+func (n *logLockoutNotifier) NotifyLockout(event *LockoutEvent) {
+	n.logger.Warn("登录锁定: scope=%s username=%s ip=%s failures=%d until=%s",
+		event.Scope, event.Username, event.IP, event.Failures, event.ExpiresAt.Format(time.RFC3339))
+}
+
+// webhookLockoutNotifier 异步推送锁定事件
+type webhookLockoutNotifier struct {
+	url    string
+	client *http.Client
+	logger *logger.Logger
+}
+
+// NotifyLockout 异步推送锁定事件，不阻塞登录请求
+func (n *webhookLockoutNotifier) NotifyLockout(event *LockoutEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	go func() {
+		resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			n.logger.Warn("发送锁定通知失败: %v", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			n.logger.Warn("发送锁定通知失败: HTTP %d", resp.StatusCode)
+		}
+	}()
+}
+
+// normalizeLoginName 用户名统一小写后作为计数键
+func normalizeLoginName(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// ceilUnit 按单位向上取整
+func ceilUnit(d, unit time.Duration) int64 {
+	return int64((d + unit - 1) / unit)
+}
+
+// truncateString 按字符截断字符串
+func truncateString(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
@@ -0,0 +1,477 @@
+package service
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"path"
+	"strings"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/internal/infrastructure/cache"
+	"mcprapi/backend/internal/pkg/encrypt"
+	"mcprapi/backend/pkg/totp"
+)
+
+const (
+	// mfaChallengeKeyPrefix 登录二次验证凭证在Redis中的键前缀
+	mfaChallengeKeyPrefix = "mfa:challenge:"
+	// mfaChallengeTTL 二次验证凭证有效期
+	mfaChallengeTTL = 5 * time.Minute
+	// mfaMaxAttempts 单个凭证允许的验证失败次数
+	mfaMaxAttempts = 5
+	// mfaRecoveryCodeCount 每次生成的恢复码数量
+	mfaRecoveryCodeCount = 10
+	// mfaRecoveryCodeBytes 每个恢复码的随机字节数，80位熵保证只保存摘要时也无法被穷举
+	mfaRecoveryCodeBytes = 10
+	// mfaSkew 允许的时钟偏差（周期数）
+	mfaSkew = 1
+)
+
+// mfaChallenge Redis中保存的二次验证凭证
+type mfaChallenge struct {
+	UserID   uint         `json:"user_id"`
+	Attempts int          `json:"attempts"`
+	Origin   *LoginOrigin `json:"origin,omitempty"` // 登录请求的来源，验证结果计入登录防护
+}
+
+// MFAConfig 多因素认证配置
+type MFAConfig struct {
+	Issuer        string   `mapstructure:"issuer"`         // 验证器应用中显示的名称
+	EncryptionKey string   `mapstructure:"encryption_key"` // TOTP密钥加密密钥
+	RequiredRoles []string `mapstructure:"required_roles"` // 必须启用MFA的角色编码，支持通配符，如 dept_admin_*
+}
+
+// MFAEnrollResponse 绑定验证器响应
+type MFAEnrollResponse struct {
+	Secret          string `json:"secret"`           // 无法扫码时手动输入
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth://地址，前端渲染为二维码
+}
+
+// MFAStatus 多因素认证状态
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	Required               bool       `json:"required"` // 用户角色要求必须启用
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+	EnabledAt              *time.Time `json:"enabled_at"`
+}
+
+// MFACodeRequest 验证码请求
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFAChallengeRequest 登录时使用二次验证凭证的请求
+type MFAChallengeRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+// MFAVerifyRequest 登录二次验证请求，code和recovery_code二选一
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// MFAVerifyResponse 登录二次验证响应，首次绑定时返回恢复码
+type MFAVerifyResponse struct {
+	*LoginResponse
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// MFAService 多因素认证服务
+type MFAService struct {
+	mfaRepo     repository.UserMFARepository
+	userRepo    repository.UserRepository
+	authService *AuthService
+	cache       *cache.Redis
+	config      MFAConfig
+}
+
+// NewMFAService 创建多因素认证服务
+func NewMFAService(mfaRepo repository.UserMFARepository, userRepo repository.UserRepository, authService *AuthService, cache *cache.Redis, config MFAConfig) *MFAService {
+	if config.Issuer == "" {
+		config.Issuer = "mcprapi"
+	}
+	return &MFAService{
+		mfaRepo:     mfaRepo,
+		userRepo:    userRepo,
+		authService: authService,
+		cache:       cache,
+		config:      config,
+	}
+}
+
+// Challenge 密码校验通过后检查是否需要二次验证，需要时返回二次验证凭证而不是登录令牌
+func (s *MFAService) Challenge(user *entity.User, origin *LoginOrigin) (*LoginResponse, error) {
+	mfa, err := s.mfaRepo.GetByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := mfa != nil && mfa.Enabled
+	if !enabled {
+		required, err := s.IsRequired(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !required {
+			return nil, nil
+		}
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cache.Set(mfaChallengeKeyPrefix+token, &mfaChallenge{UserID: user.ID, Origin: origin}, mfaChallengeTTL); err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		MFARequired:       true,
+		MFAEnrollRequired: !enabled,
+		MFAToken:          token,
+		ExpiresAt:         time.Now().Add(mfaChallengeTTL).Unix(),
+	}, nil
+}
+
+// EnrollWithChallenge 登录时角色要求MFA但尚未绑定，凭二次验证凭证绑定验证器
+func (s *MFAService) EnrollWithChallenge(req *MFAChallengeRequest) (*MFAEnrollResponse, error) {
+	var challenge mfaChallenge
+	if err := s.cache.Get(mfaChallengeKeyPrefix+req.MFAToken, &challenge); err != nil {
+		return nil, errors.New("验证凭证无效或已过期，请重新登录")
+	}
+	return s.Enroll(challenge.UserID)
+}
+
+// VerifyLogin 校验二次验证并签发登录令牌；未启用MFA时校验通过即完成绑定
+// 验证失败计入登录失败次数，用户名或IP被锁定后凭证不能继续使用
+func (s *MFAService) VerifyLogin(req *MFAVerifyRequest) (*MFAVerifyResponse, error) {
+	if req.Code == "" && req.RecoveryCode == "" {
+		return nil, errors.New("验证码不能为空")
+	}
+
+	key := mfaChallengeKeyPrefix + req.MFAToken
+	var challenge mfaChallenge
+	err := s.cache.Update(key, &challenge, func() error {
+		challenge.Attempts++
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New("验证凭证无效或已过期，请重新登录")
+	}
+	if challenge.Attempts > mfaMaxAttempts {
+		_ = s.cache.Delete(key)
+		return nil, errors.New("验证失败次数过多，请重新登录")
+	}
+
+	user, err := s.userRepo.GetByID(challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.Status != 1 {
+		return nil, errors.New("用户不存在或已禁用")
+	}
+	if err := s.authService.checkLogin(user, challenge.Origin); err != nil {
+		return nil, err
+	}
+
+	mfa, err := s.mfaRepo.GetByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, errors.New("请先绑定验证器")
+	}
+
+	resp := &MFAVerifyResponse{}
+	if mfa.Enabled {
+		if req.RecoveryCode != "" {
+			err = s.useRecoveryCode(mfa, req.RecoveryCode)
+		} else {
+			err = s.verifyCode(mfa, req.Code)
+		}
+	} else {
+		// 登录时强制绑定：首次验证通过即启用
+		resp.RecoveryCodes, err = s.activate(mfa, req.Code)
+	}
+	if err != nil {
+		s.authService.loginFailed(user, challenge.Origin, "二次验证失败: "+err.Error())
+		return nil, err
+	}
+
+	// 凭证只能使用一次
+	if err := s.cache.Delete(key); err != nil {
+		return nil, err
+	}
+
+	resp.LoginResponse, err = s.authService.finishPasswordLogin(user, challenge.Origin)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetStatus 获取用户的多因素认证状态
+func (s *MFAService) GetStatus(userID uint) (*MFAStatus, error) {
+	mfa, err := s.mfaRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	required, err := s.IsRequired(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MFAStatus{Required: required}
+	if mfa != nil && mfa.Enabled {
+		status.Enabled = true
+		status.EnabledAt = mfa.EnabledAt
+		status.RecoveryCodesRemaining = len(decodeRecoveryCodes(mfa.RecoveryCodes))
+	}
+	return status, nil
+}
+
+// Enroll 生成新的TOTP密钥，验证一次验证码后才会启用
+func (s *MFAService) Enroll(userID uint) (*MFAEnrollResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	mfa, err := s.mfaRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa != nil && mfa.Enabled {
+		return nil, errors.New("已启用多因素认证，请先停用")
+	}
+	if mfa == nil {
+		mfa = &entity.UserMFA{UserID: userID}
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if mfa.Secret, err = encrypt.EncryptString(secret, s.config.EncryptionKey); err != nil {
+		return nil, err
+	}
+	mfa.LastStep = 0
+	mfa.RecoveryCodes = ""
+	if err := s.mfaRepo.Save(mfa); err != nil {
+		return nil, err
+	}
+
+	return &MFAEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.config.Issuer, user.Username, secret),
+	}, nil
+}
+
+// Activate 验证验证码并启用多因素认证，返回恢复码（只展示一次）
+func (s *MFAService) Activate(userID uint, req *MFACodeRequest) ([]string, error) {
+	mfa, err := s.mfaRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, errors.New("请先绑定验证器")
+	}
+	if mfa.Enabled {
+		return nil, errors.New("已启用多因素认证")
+	}
+	return s.activate(mfa, req.Code)
+}
+
+// Disable 停用多因素认证，角色要求启用时不允许停用
+func (s *MFAService) Disable(userID uint, req *MFACodeRequest) error {
+	required, err := s.IsRequired(userID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return errors.New("当前角色要求必须启用多因素认证")
+	}
+
+	mfa, err := s.mfaRepo.GetByUserID(userID)
+	if err != nil {
+		return err
+	}
+	if mfa == nil || !mfa.Enabled {
+		return errors.New("未启用多因素认证")
+	}
+	if err := s.verifyCode(mfa, req.Code); err != nil {
+		return err
+	}
+	return s.mfaRepo.DeleteByUserID(userID)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部失效
+func (s *MFAService) RegenerateRecoveryCodes(userID uint, req *MFACodeRequest) ([]string, error) {
+	mfa, err := s.mfaRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil || !mfa.Enabled {
+		return nil, errors.New("未启用多因素认证")
+	}
+	if err := s.verifyCode(mfa, req.Code); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.newRecoveryCodes(mfa)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.Save(mfa); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Reset 管理员重置用户的多因素认证，用户下次登录时需重新绑定
+func (s *MFAService) Reset(userID uint) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("用户不存在")
+	}
+	return s.mfaRepo.DeleteByUserID(userID)
+}
+
+// IsRequired 用户是否拥有要求启用MFA的角色
+func (s *MFAService) IsRequired(userID uint) (bool, error) {
+	if len(s.config.RequiredRoles) == 0 {
+		return false, nil
+	}
+	roles, err := s.userRepo.GetUserRoles(userID)
+	if err != nil {
+		return false, err
+	}
+	for _, role := range roles {
+		for _, pattern := range s.config.RequiredRoles {
+			if matched, _ := path.Match(pattern, role); matched {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// activate 校验首个验证码后启用，并生成恢复码
+func (s *MFAService) activate(mfa *entity.UserMFA, code string) ([]string, error) {
+	step, err := s.validate(mfa, code)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := s.newRecoveryCodes(mfa)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	mfa.Enabled = true
+	mfa.EnabledAt = &now
+	mfa.LastStep = step
+	if err := s.mfaRepo.Save(mfa); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifyCode 校验验证码并记录时间步，同一验证码不能重复使用
+func (s *MFAService) verifyCode(mfa *entity.UserMFA, code string) error {
+	step, err := s.validate(mfa, code)
+	if err != nil {
+		return err
+	}
+	mfa.LastStep = step
+	return s.mfaRepo.Save(mfa)
+}
+
+// validate 解密密钥并校验验证码
+func (s *MFAService) validate(mfa *entity.UserMFA, code string) (int64, error) {
+	secret, err := encrypt.DecryptString(mfa.Secret, s.config.EncryptionKey)
+	if err != nil {
+		return 0, errors.New("多因素认证密钥无法解密，请联系管理员重置")
+	}
+	step, ok := totp.Validate(secret, code, time.Now(), mfaSkew)
+	if !ok || step <= mfa.LastStep {
+		return 0, errors.New("验证码错误")
+	}
+	return step, nil
+}
+
+// useRecoveryCode 使用恢复码，每个恢复码只能使用一次
+func (s *MFAService) useRecoveryCode(mfa *entity.UserMFA, code string) error {
+	hashed := encrypt.GenerateHash(normalizeRecoveryCode(code))
+	hashes := decodeRecoveryCodes(mfa.RecoveryCodes)
+	for i, h := range hashes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hashed)) == 1 {
+			hashes = append(hashes[:i], hashes[i+1:]...)
+			data, err := json.Marshal(hashes)
+			if err != nil {
+				return err
+			}
+			mfa.RecoveryCodes = string(data)
+			return s.mfaRepo.Save(mfa)
+		}
+	}
+	return errors.New("恢复码错误")
+}
+
+// newRecoveryCodes 生成恢复码，只保存哈希
+func (s *MFAService) newRecoveryCodes(mfa *entity.UserMFA) ([]string, error) {
+	codes := make([]string, 0, mfaRecoveryCodeCount)
+	hashes := make([]string, 0, mfaRecoveryCodeCount)
+	for i := 0; i < mfaRecoveryCodeCount; i++ {
+		raw, err := randomToken(mfaRecoveryCodeBytes)
+		if err != nil {
+			return nil, err
+		}
+		code := formatRecoveryCode(raw)
+		codes = append(codes, code)
+		hashes = append(hashes, encrypt.GenerateHash(normalizeRecoveryCode(code)))
+	}
+
+	data, err := json.Marshal(hashes)
+	if err != nil {
+		return nil, err
+	}
+	mfa.RecoveryCodes = string(data)
+	return codes, nil
+}
+
+// formatRecoveryCode 每5个字符以连字符分隔，便于抄写
+func formatRecoveryCode(raw string) string {
+	groups := make([]string, 0, (len(raw)+4)/5)
+	for len(raw) > 5 {
+		groups = append(groups, raw[:5])
+		raw = raw[5:]
+	}
+	return strings.Join(append(groups, raw), "-")
+}
+
+// normalizeRecoveryCode 忽略大小写、空格和连字符
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// decodeRecoveryCodes 解析恢复码哈希
+func decodeRecoveryCodes(data string) []string {
+	var hashes []string
+	if data != "" {
+		_ = json.Unmarshal([]byte(data), &hashes)
+	}
+	return hashes
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/pkg/encrypt"
+	"mcprapi/backend/pkg/totp"
+)
+
+// fakeMFARepo 内存中的多因素认证配置仓库
+type fakeMFARepo struct {
+	saved int
+}
+
+func (r *fakeMFARepo) Save(mfa *entity.UserMFA) error {
+	r.saved++
+	return nil
+}
+
+func (r *fakeMFARepo) GetByUserID(userID uint) (*entity.UserMFA, error) {
+	return nil, nil
+}
+
+func (r *fakeMFARepo) DeleteByUserID(userID uint) error {
+	return nil
+}
+
+const testMFAEncryptionKey = "0123456789abcdef0123456789abcdef"
+
+func newTestMFAService(repo *fakeMFARepo) *MFAService {
+	return &MFAService{mfaRepo: repo, config: MFAConfig{EncryptionKey: testMFAEncryptionKey}}
+}
+
+func TestMFAVerifyCodeRejectsReplay(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := encrypt.EncryptString(secret, testMFAEncryptionKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		lastStep func(current int64) int64
+		wantErr  bool
+	}{
+		{"首次使用", func(current int64) int64 { return 0 }, false},
+		{"上一周期已使用", func(current int64) int64 { return current - 1 }, false},
+		{"当前周期已使用", func(current int64) int64 { return current }, true},
+		{"之后的周期已使用", func(current int64) int64 { return current + 1 }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := totp.Step(time.Now())
+			code, err := totp.Code(secret, current)
+			if err != nil {
+				t.Fatal(err)
+			}
+			repo := &fakeMFARepo{}
+			mfa := &entity.UserMFA{Secret: encrypted, LastStep: tt.lastStep(current)}
+
+			err = newTestMFAService(repo).verifyCode(mfa, code)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verifyCode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (mfa.LastStep < current || repo.saved != 1) {
+				t.Errorf("验证通过后应记录时间步: last_step=%d current=%d saved=%d", mfa.LastStep, current, repo.saved)
+			}
+		})
+	}
+}
+
+func TestMFARecoveryCodes(t *testing.T) {
+	repo := &fakeMFARepo{}
+	s := newTestMFAService(repo)
+	mfa := &entity.UserMFA{}
+
+	codes, err := s.newRecoveryCodes(mfa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != mfaRecoveryCodeCount {
+		t.Fatalf("生成了%d个恢复码，want %d", len(codes), mfaRecoveryCodeCount)
+	}
+	format := regexp.MustCompile(`^[0-9a-f]{5}(-[0-9a-f]{5}){3}$`)
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("恢复码格式错误: %s", code)
+		}
+		if seen[code] {
+			t.Errorf("恢复码重复: %s", code)
+		}
+		seen[code] = true
+	}
+	if strings.Contains(mfa.RecoveryCodes, codes[0]) {
+		t.Fatal("恢复码应只保存哈希")
+	}
+
+	tests := []struct {
+		name    string
+		code    string
+		wantErr bool
+	}{
+		{"原样输入", codes[0], false},
+		{"重复使用", codes[0], true},
+		{"大写且无连字符", strings.ToUpper(strings.ReplaceAll(codes[1], "-", "")), false},
+		{"连字符替换为空格", " " + strings.ReplaceAll(codes[2], "-", " ") + " ", false},
+		{"错误的恢复码", "00000-00000-00000-00000", true},
+		{"空恢复码", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.useRecoveryCode(mfa, tt.code)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("useRecoveryCode(%q) error = %v, wantErr %v", tt.code, err, tt.wantErr)
+			}
+		})
+	}
+	if remaining := len(decodeRecoveryCodes(mfa.RecoveryCodes)); remaining != mfaRecoveryCodeCount-3 {
+		t.Errorf("剩余%d个恢复码，want %d", remaining, mfaRecoveryCodeCount-3)
+	}
+}
+
+func TestMFALegacyRecoveryCode(t *testing.T) {
+	// 调整长度前生成的10位恢复码仍然可以使用
+	legacy := "a1b2c-3d4e5"
+	data, _ := json.Marshal([]string{encrypt.GenerateHash(normalizeRecoveryCode(legacy))})
+	mfa := &entity.UserMFA{RecoveryCodes: string(data)}
+
+	s := newTestMFAService(&fakeMFARepo{})
+	if err := s.useRecoveryCode(mfa, legacy); err != nil {
+		t.Fatalf("useRecoveryCode() error = %v", err)
+	}
+	if err := s.useRecoveryCode(mfa, legacy); err == nil {
+		t.Fatal("恢复码只能使用一次")
+	}
+}
+
+func TestFormatRecoveryCode(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"0123456789abcdef0123", "01234-56789-abcde-f0123"},
+		{"0123456789", "01234-56789"},
+		{"0123", "0123"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := formatRecoveryCode(tt.raw); got != tt.want {
+			t.Errorf("formatRecoveryCode(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,783 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/internal/infrastructure/cache"
+	"mcprapi/backend/internal/pkg/encrypt"
+)
+
+// OAuth2授权类型
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// OAuth2 scope定义
+const (
+	ScopeOpenID     = "openid"
+	ScopeProfile    = "profile"
+	ScopeRolePrefix = "role:" // role:<角色编码>，令牌只能使用该角色的权限
+)
+
+// oauthCodeKeyPrefix 授权码在Redis中的键前缀
+const oauthCodeKeyPrefix = "oauth:code:"
+
+// OAuthError OAuth2标准错误（RFC 6749 5.2）
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// Error 实现error接口
+func (e *OAuthError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+func newOAuthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// ParseScopes 解析空格分隔的scope字符串
+func ParseScopes(scope string) []string {
+	return strings.Fields(scope)
+}
+
+// FilterRolesByScope 按scope过滤角色，scope为空表示不限制
+func FilterRolesByScope(roles []string, scope string) []string {
+	if scope == "" {
+		return roles
+	}
+
+	allowed := make(map[string]bool)
+	for _, s := range ParseScopes(scope) {
+		if strings.HasPrefix(s, ScopeRolePrefix) {
+			allowed[strings.TrimPrefix(s, ScopeRolePrefix)] = true
+		}
+	}
+
+	filtered := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if allowed[role] {
+			filtered = append(filtered, role)
+		}
+	}
+	return filtered
+}
+
+// OAuthService OAuth2授权服务
+type OAuthService struct {
+	oauthRepo    repository.OAuthRepository
+	userRepo     repository.UserRepository
+	roleRepo     repository.RoleRepository
+	businessRepo repository.BusinessRepository
+	authService  *AuthService
+	cache        *cache.Redis
+	tokenTTL     time.Duration
+	codeTTL      time.Duration
+}
+
+// NewOAuthService 创建OAuth2授权服务
+func NewOAuthService(
+	oauthRepo repository.OAuthRepository,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	businessRepo repository.BusinessRepository,
+	authService *AuthService,
+	cache *cache.Redis,
+	tokenTTL time.Duration,
+	codeTTL time.Duration,
+) *OAuthService {
+	return &OAuthService{
+		oauthRepo:    oauthRepo,
+		userRepo:     userRepo,
+		roleRepo:     roleRepo,
+		businessRepo: businessRepo,
+		authService:  authService,
+		cache:        cache,
+		tokenTTL:     tokenTTL,
+		codeTTL:      codeTTL,
+	}
+}
+
+// CreateOAuthClientRequest 注册客户端请求
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	Description  string   `json:"description"`
+	BusinessID   uint     `json:"business_id" binding:"required"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	GrantTypes   []string `json:"grant_types" binding:"required,min=1"`
+	Public       bool     `json:"public"`
+}
+
+// UpdateOAuthClientRequest 更新客户端请求
+type UpdateOAuthClientRequest struct {
+	ID           uint     `json:"id" binding:"required"`
+	Name         string   `json:"name" binding:"required"`
+	Description  string   `json:"description"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	Status       int      `json:"status" binding:"oneof=0 1"`
+}
+
+// OAuthClientWithSecret 包含明文密钥的客户端，密钥仅在创建和重置时返回一次
+type OAuthClientWithSecret struct {
+	*entity.OAuthClient
+	PlainSecret string `json:"client_secret,omitempty"`
+}
+
+// CreateClient 注册客户端
+func (s *OAuthService) CreateClient(req *CreateOAuthClientRequest) (*OAuthClientWithSecret, error) {
+	business, err := s.businessRepo.GetByID(req.BusinessID)
+	if err != nil || business == nil {
+		return nil, errors.New("业务线不存在")
+	}
+
+	for _, grantType := range req.GrantTypes {
+		if grantType != GrantTypeAuthorizationCode && grantType != GrantTypeClientCredentials {
+			return nil, fmt.Errorf("不支持的授权类型: %s", grantType)
+		}
+		if grantType == GrantTypeClientCredentials && req.Public {
+			return nil, errors.New("公共客户端不支持client_credentials模式")
+		}
+		if grantType == GrantTypeAuthorizationCode && len(req.RedirectURIs) == 0 {
+			return nil, errors.New("授权码模式必须配置回调地址")
+		}
+	}
+	if err := validateRedirectURIs(req.RedirectURIs); err != nil {
+		return nil, err
+	}
+
+	roleIDs, err := s.resolveScopeRoles(req.Scopes, business)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &entity.OAuthClient{
+		ClientID:     strings.ReplaceAll(uuid.New().String(), "-", ""),
+		Name:         req.Name,
+		Description:  req.Description,
+		BusinessID:   business.ID,
+		RedirectURIs: strings.Join(req.RedirectURIs, " "),
+		Scopes:       strings.Join(req.Scopes, " "),
+		GrantTypes:   strings.Join(req.GrantTypes, " "),
+		Public:       req.Public,
+		Status:       1,
+	}
+
+	var plainSecret string
+	if !req.Public {
+		plainSecret, err = randomToken(32)
+		if err != nil {
+			return nil, err
+		}
+		client.ClientSecret = encrypt.GenerateHash(plainSecret)
+	}
+
+	// client_credentials模式需要一个服务账号承载角色
+	if containsString(req.GrantTypes, GrantTypeClientCredentials) {
+		serviceUser, err := s.createServiceUser(client, business, roleIDs)
+		if err != nil {
+			return nil, err
+		}
+		client.ServiceUserID = serviceUser.ID
+	}
+
+	if err := s.oauthRepo.CreateClient(client); err != nil {
+		return nil, err
+	}
+
+	return &OAuthClientWithSecret{OAuthClient: client, PlainSecret: plainSecret}, nil
+}
+
+// UpdateClient 更新客户端
+func (s *OAuthService) UpdateClient(req *UpdateOAuthClientRequest) (*entity.OAuthClient, error) {
+	client, err := s.oauthRepo.GetClientByID(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, errors.New("客户端不存在")
+	}
+
+	if containsString(ParseScopes(client.GrantTypes), GrantTypeAuthorizationCode) && len(req.RedirectURIs) == 0 {
+		return nil, errors.New("授权码模式必须配置回调地址")
+	}
+	if err := validateRedirectURIs(req.RedirectURIs); err != nil {
+		return nil, err
+	}
+
+	business, err := s.businessRepo.GetByID(client.BusinessID)
+	if err != nil || business == nil {
+		return nil, errors.New("业务线不存在")
+	}
+
+	roleIDs, err := s.resolveScopeRoles(req.Scopes, business)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Name = req.Name
+	client.Description = req.Description
+	client.RedirectURIs = strings.Join(req.RedirectURIs, " ")
+	client.Scopes = strings.Join(req.Scopes, " ")
+	client.Status = req.Status
+
+	// 同步服务账号的角色和状态
+	if client.ServiceUserID > 0 {
+		serviceUser, err := s.userRepo.GetByID(client.ServiceUserID)
+		if err != nil {
+			return nil, err
+		}
+		if serviceUser != nil {
+			serviceUser.Status = req.Status
+			if err := s.userRepo.Update(serviceUser); err != nil {
+				return nil, err
+			}
+			if err := s.userRepo.AssignRoles(serviceUser.ID, roleIDs); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := s.oauthRepo.UpdateClient(client); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// DeleteClient 删除客户端
+func (s *OAuthService) DeleteClient(id uint) error {
+	client, err := s.oauthRepo.GetClientByID(id)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		return errors.New("客户端不存在")
+	}
+
+	if err := s.oauthRepo.DeleteConsentsByClient(client.ClientID); err != nil {
+		return err
+	}
+
+	// 删除服务账号及其角色
+	if client.ServiceUserID > 0 {
+		if err := s.userRepo.AssignRoles(client.ServiceUserID, nil); err != nil {
+			return err
+		}
+		if err := s.userRepo.Delete(client.ServiceUserID); err != nil {
+			return err
+		}
+	}
+
+	return s.oauthRepo.DeleteClient(id)
+}
+
+// RotateClientSecret 重置客户端密钥
+func (s *OAuthService) RotateClientSecret(id uint) (*OAuthClientWithSecret, error) {
+	client, err := s.oauthRepo.GetClientByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, errors.New("客户端不存在")
+	}
+	if client.Public {
+		return nil, errors.New("公共客户端没有密钥")
+	}
+
+	plainSecret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	client.ClientSecret = encrypt.GenerateHash(plainSecret)
+
+	if err := s.oauthRepo.UpdateClient(client); err != nil {
+		return nil, err
+	}
+
+	return &OAuthClientWithSecret{OAuthClient: client, PlainSecret: plainSecret}, nil
+}
+
+// GetClient 获取客户端
+func (s *OAuthService) GetClient(id uint) (*entity.OAuthClient, error) {
+	return s.oauthRepo.GetClientByID(id)
+}
+
+// ListClients 获取客户端列表
+func (s *OAuthService) ListClients(businessID uint) ([]*entity.OAuthClient, error) {
+	return s.oauthRepo.ListClients(businessID)
+}
+
+// AuthorizeRequest 授权请求（授权码模式）
+type AuthorizeRequest struct {
+	ResponseType        string `json:"response_type" form:"response_type" binding:"required"`
+	ClientID            string `json:"client_id" form:"client_id" binding:"required"`
+	RedirectURI         string `json:"redirect_uri" form:"redirect_uri"`
+	Scope               string `json:"scope" form:"scope"`
+	State               string `json:"state" form:"state"`
+	CodeChallenge       string `json:"code_challenge" form:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" form:"code_challenge_method"`
+}
+
+// ScopeInfo scope说明
+type ScopeInfo struct {
+	Scope       string `json:"scope"`
+	Description string `json:"description"`
+}
+
+// AuthorizeInfo 授权确认页信息
+type AuthorizeInfo struct {
+	ClientID     string      `json:"client_id"`
+	ClientName   string      `json:"client_name"`
+	BusinessName string      `json:"business_name"`
+	RedirectURI  string      `json:"redirect_uri"`
+	Scopes       []ScopeInfo `json:"scopes"`
+	Consented    bool        `json:"consented"` // 用户是否已同意过全部scope
+}
+
+// ApproveAuthorizeRequest 用户确认授权请求
+type ApproveAuthorizeRequest struct {
+	AuthorizeRequest
+	Approve bool `json:"approve"`
+}
+
+// oauthCode 授权码携带的数据
+type oauthCode struct {
+	ClientID      string `json:"client_id"`
+	UserID        uint   `json:"user_id"`
+	RedirectURI   string `json:"redirect_uri"`
+	Scope         string `json:"scope"`
+	CodeChallenge string `json:"code_challenge"`
+}
+
+// GetAuthorizeInfo 校验授权请求并返回确认页信息
+func (s *OAuthService) GetAuthorizeInfo(userID uint, req *AuthorizeRequest) (*AuthorizeInfo, error) {
+	client, redirectURI, scopes, err := s.validateAuthorizeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &AuthorizeInfo{
+		ClientID:    client.ClientID,
+		ClientName:  client.Name,
+		RedirectURI: redirectURI,
+		Scopes:      s.describeScopes(scopes),
+	}
+
+	if business, err := s.businessRepo.GetByID(client.BusinessID); err == nil && business != nil {
+		info.BusinessName = business.Name
+	}
+
+	consent, err := s.oauthRepo.GetConsent(userID, client.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if consent != nil {
+		granted := ParseScopes(consent.Scopes)
+		info.Consented = true
+		for _, scope := range scopes {
+			if !containsString(granted, scope) {
+				info.Consented = false
+				break
+			}
+		}
+	}
+
+	return info, nil
+}
+
+// ApproveAuthorize 处理用户的授权确认，返回需要跳转的回调地址
+func (s *OAuthService) ApproveAuthorize(userID uint, req *ApproveAuthorizeRequest) (string, error) {
+	client, redirectURI, scopes, err := s.validateAuthorizeRequest(&req.AuthorizeRequest)
+	if err != nil {
+		return "", err
+	}
+
+	callback, err := url.Parse(redirectURI)
+	if err != nil {
+		return "", errors.New("无效的回调地址")
+	}
+	query := callback.Query()
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+
+	if !req.Approve {
+		query.Set("error", "access_denied")
+		callback.RawQuery = query.Encode()
+		return callback.String(), nil
+	}
+
+	// 记录用户授权
+	consent, err := s.oauthRepo.GetConsent(userID, client.ClientID)
+	if err != nil {
+		return "", err
+	}
+	if consent == nil {
+		consent = &entity.OAuthConsent{UserID: userID, ClientID: client.ClientID}
+	}
+	granted := ParseScopes(consent.Scopes)
+	for _, scope := range scopes {
+		if !containsString(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	consent.Scopes = strings.Join(granted, " ")
+	if err := s.oauthRepo.SaveConsent(consent); err != nil {
+		return "", err
+	}
+
+	code, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	data := &oauthCode{
+		ClientID:      client.ClientID,
+		UserID:        userID,
+		RedirectURI:   redirectURI,
+		Scope:         strings.Join(scopes, " "),
+		CodeChallenge: req.CodeChallenge,
+	}
+	if err := s.cache.Set(oauthCodeKeyPrefix+code, data, s.codeTTL); err != nil {
+		return "", fmt.Errorf("保存授权码失败: %v", err)
+	}
+
+	query.Set("code", code)
+	callback.RawQuery = query.Encode()
+	return callback.String(), nil
+}
+
+// TokenRequest 令牌请求
+type TokenRequest struct {
+	GrantType    string `form:"grant_type" json:"grant_type"`
+	ClientID     string `form:"client_id" json:"client_id"`
+	ClientSecret string `form:"client_secret" json:"client_secret"`
+	Code         string `form:"code" json:"code"`
+	RedirectURI  string `form:"redirect_uri" json:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier" json:"code_verifier"`
+	Scope        string `form:"scope" json:"scope"`
+}
+
+// TokenResponse 令牌响应（RFC 6749 5.1）
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// Token 签发访问令牌
+func (s *OAuthService) Token(req *TokenRequest) (*TokenResponse, error) {
+	switch req.GrantType {
+	case GrantTypeClientCredentials:
+		return s.clientCredentialsToken(req)
+	case GrantTypeAuthorizationCode:
+		return s.authorizationCodeToken(req)
+	case "":
+		return nil, newOAuthError("invalid_request", "缺少grant_type")
+	default:
+		return nil, newOAuthError("unsupported_grant_type", req.GrantType)
+	}
+}
+
+// clientCredentialsToken client_credentials模式
+func (s *OAuthService) clientCredentialsToken(req *TokenRequest) (*TokenResponse, error) {
+	client, err := s.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !containsString(ParseScopes(client.GrantTypes), GrantTypeClientCredentials) || client.ServiceUserID == 0 {
+		return nil, newOAuthError("unauthorized_client", "客户端未开通client_credentials模式")
+	}
+
+	scopes, err := narrowScopes(req.Scope, ParseScopes(client.Scopes))
+	if err != nil {
+		return nil, err
+	}
+
+	serviceUser, err := s.userRepo.GetByID(client.ServiceUserID)
+	if err != nil {
+		return nil, err
+	}
+	if serviceUser == nil || serviceUser.Status != 1 {
+		return nil, newOAuthError("invalid_client", "服务账号不存在或已禁用")
+	}
+
+	return s.issueToken(serviceUser, client, scopes)
+}
+
+// authorizationCodeToken authorization_code模式
+func (s *OAuthService) authorizationCodeToken(req *TokenRequest) (*TokenResponse, error) {
+	if req.Code == "" || req.CodeVerifier == "" {
+		return nil, newOAuthError("invalid_request", "缺少code或code_verifier")
+	}
+
+	client, err := s.oauthRepo.GetClientByClientID(req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil || client.Status != 1 {
+		return nil, newOAuthError("invalid_client", "客户端不存在或已禁用")
+	}
+	// 机密客户端必须校验密钥，公共客户端仅依赖PKCE
+	if !client.Public {
+		if _, err := s.authenticateClient(req.ClientID, req.ClientSecret); err != nil {
+			return nil, err
+		}
+	}
+
+	// 授权码只能使用一次，读取和删除须原子完成，避免并发请求重复兑换
+	var data oauthCode
+	if err := s.cache.GetDel(oauthCodeKeyPrefix+req.Code, &data); err != nil {
+		return nil, newOAuthError("invalid_grant", "授权码无效或已过期")
+	}
+
+	if data.ClientID != client.ClientID {
+		return nil, newOAuthError("invalid_grant", "授权码与客户端不匹配")
+	}
+	if data.RedirectURI != req.RedirectURI {
+		return nil, newOAuthError("invalid_grant", "redirect_uri不匹配")
+	}
+	if !verifyCodeChallenge(req.CodeVerifier, data.CodeChallenge) {
+		return nil, newOAuthError("invalid_grant", "code_verifier校验失败")
+	}
+
+	user, err := s.userRepo.GetByID(data.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.Status != 1 {
+		return nil, newOAuthError("invalid_grant", "用户不存在或已禁用")
+	}
+
+	return s.issueToken(user, client, ParseScopes(data.Scope))
+}
+
+// issueToken 使用AuthService的声明格式签发令牌，使现有中间件可以直接校验
+func (s *OAuthService) issueToken(user *entity.User, client *entity.OAuthClient, scopes []string) (*TokenResponse, error) {
+	scope := strings.Join(scopes, " ")
+	// scope为空时表示不限制角色，OAuth2令牌必须显式携带scope
+	if scope == "" {
+		scope = ScopeOpenID
+	}
+
+	token, _, err := s.authService.GenerateOAuthToken(user, client.ClientID, scope, s.tokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.tokenTTL.Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// authenticateClient 校验客户端凭证
+func (s *OAuthService) authenticateClient(clientID, clientSecret string) (*entity.OAuthClient, error) {
+	if clientID == "" || clientSecret == "" {
+		return nil, newOAuthError("invalid_client", "缺少客户端凭证")
+	}
+
+	client, err := s.oauthRepo.GetClientByClientID(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil || client.Status != 1 || client.Public {
+		return nil, newOAuthError("invalid_client", "客户端认证失败")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(encrypt.GenerateHash(clientSecret)), []byte(client.ClientSecret)) != 1 {
+		return nil, newOAuthError("invalid_client", "客户端认证失败")
+	}
+
+	return client, nil
+}
+
+// validateAuthorizeRequest 校验授权请求，返回客户端、回调地址和scope
+func (s *OAuthService) validateAuthorizeRequest(req *AuthorizeRequest) (*entity.OAuthClient, string, []string, error) {
+	if req.ResponseType != "code" {
+		return nil, "", nil, newOAuthError("unsupported_response_type", "仅支持response_type=code")
+	}
+
+	client, err := s.oauthRepo.GetClientByClientID(req.ClientID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if client == nil || client.Status != 1 {
+		return nil, "", nil, newOAuthError("invalid_client", "客户端不存在或已禁用")
+	}
+	if !containsString(ParseScopes(client.GrantTypes), GrantTypeAuthorizationCode) {
+		return nil, "", nil, newOAuthError("unauthorized_client", "客户端未开通授权码模式")
+	}
+
+	redirectURIs := ParseScopes(client.RedirectURIs)
+	redirectURI := req.RedirectURI
+	if redirectURI == "" && len(redirectURIs) == 1 {
+		redirectURI = redirectURIs[0]
+	}
+	if !containsString(redirectURIs, redirectURI) {
+		return nil, "", nil, newOAuthError("invalid_request", "redirect_uri未注册")
+	}
+
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return nil, "", nil, newOAuthError("invalid_request", "授权码模式必须使用PKCE(S256)")
+	}
+
+	scopes, err := narrowScopes(req.Scope, ParseScopes(client.Scopes))
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return client, redirectURI, scopes, nil
+}
+
+// resolveScopeRoles 校验scope并返回其中角色对应的角色ID，角色必须属于业务线所在部门
+func (s *OAuthService) resolveScopeRoles(scopes []string, business *entity.Business) ([]uint, error) {
+	var roleIDs []uint
+	for _, scope := range scopes {
+		switch {
+		case scope == ScopeOpenID || scope == ScopeProfile:
+		case strings.HasPrefix(scope, ScopeRolePrefix):
+			role, err := s.roleRepo.GetByCode(strings.TrimPrefix(scope, ScopeRolePrefix))
+			if err != nil {
+				return nil, err
+			}
+			if role == nil {
+				return nil, fmt.Errorf("scope对应的角色不存在: %s", scope)
+			}
+			if role.DeptID != business.DeptID {
+				return nil, fmt.Errorf("scope对应的角色不属于业务线所在部门: %s", scope)
+			}
+			roleIDs = append(roleIDs, role.ID)
+		default:
+			return nil, fmt.Errorf("不支持的scope: %s", scope)
+		}
+	}
+	return roleIDs, nil
+}
+
+// createServiceUser 为客户端创建服务账号
+func (s *OAuthService) createServiceUser(client *entity.OAuthClient, business *entity.Business, roleIDs []uint) (*entity.User, error) {
+	password, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	passwordHash, err := encrypt.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &entity.User{
+		Username: "oauth_" + client.ClientID,
+		Name:     client.Name,
+		Email:    client.ClientID + "@oauth.local",
+		Password: passwordHash, // 随机密码，服务账号不能通过密码登录
+		DeptID:   business.DeptID,
+		Status:   1,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, fmt.Errorf("创建服务账号失败: %v", err)
+	}
+
+	if len(roleIDs) > 0 {
+		if err := s.userRepo.AssignRoles(user.ID, roleIDs); err != nil {
+			return nil, fmt.Errorf("分配服务账号角色失败: %v", err)
+		}
+	}
+
+	return user, nil
+}
+
+// describeScopes 生成scope说明
+func (s *OAuthService) describeScopes(scopes []string) []ScopeInfo {
+	infos := make([]ScopeInfo, 0, len(scopes))
+	for _, scope := range scopes {
+		info := ScopeInfo{Scope: scope}
+		switch {
+		case scope == ScopeOpenID:
+			info.Description = "确认您的身份"
+		case scope == ScopeProfile:
+			info.Description = "读取您的基本信息"
+		case strings.HasPrefix(scope, ScopeRolePrefix):
+			code := strings.TrimPrefix(scope, ScopeRolePrefix)
+			info.Description = "以角色 " + code + " 的权限访问API"
+			if role, err := s.roleRepo.GetByCode(code); err == nil && role != nil {
+				info.Description = "以角色「" + role.Name + "」的权限访问API"
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// narrowScopes 校验请求的scope是否在允许范围内，未指定时使用全部允许的scope
+func narrowScopes(requested string, allowed []string) ([]string, error) {
+	scopes := ParseScopes(requested)
+	if len(scopes) == 0 {
+		return allowed, nil
+	}
+	for _, scope := range scopes {
+		if !containsString(allowed, scope) {
+			return nil, newOAuthError("invalid_scope", scope)
+		}
+	}
+	return scopes, nil
+}
+
+// verifyCodeChallenge 校验PKCE code_verifier（S256）
+func verifyCodeChallenge(verifier, challenge string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// validateRedirectURIs 校验回调地址格式
+func validateRedirectURIs(uris []string) error {
+	for _, uri := range uris {
+		parsed, err := url.Parse(uri)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Fragment != "" {
+			return fmt.Errorf("无效的回调地址: %s", uri)
+		}
+	}
+	return nil
+}
+
+// randomToken 生成指定字节数的随机十六进制字符串
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// containsString 判断切片中是否包含指定字符串
+func containsString(list []string, target string) bool {
+	for _, item := range list {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package service
+
+import "testing"
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	// RFC 7636附录B的示例
+	const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	const challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		want      bool
+	}{
+		{"匹配", verifier, challenge, true},
+		{"verifier错误", verifier + "x", challenge, false},
+		{"plain方式的challenge", verifier, verifier, false},
+		{"challenge带填充", verifier, challenge + "=", false},
+		{"challenge为空", verifier, "", false},
+		{"verifier为空", "", challenge, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyCodeChallenge(tt.verifier, tt.challenge); got != tt.want {
+				t.Errorf("verifyCodeChallenge(%q, %q) = %v, want %v", tt.verifier, tt.challenge, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIMethods 文档中可能出现的操作方法，按输出顺序排列
+var openAPIMethods = []string{"get", "post", "put", "patch", "delete", "head", "options", "trace"}
+
+// openAPIPathParam 路径参数{id}，转换为Casbin keyMatch2使用的:id
+var openAPIPathParam = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// apiSpec 解析后的OpenAPI/Swagger文档
+type apiSpec struct {
+	Format     string // 文档格式和版本，如openapi 3.0.1、swagger 2.0
+	Title      string
+	BasePath   string // Swagger 2的basePath或OpenAPI 3第一个server的路径
+	Operations []*apiSpecOperation
+}
+
+// apiSpecOperation 文档中的一个操作
+type apiSpecOperation struct {
+	Method      string // 大写的HTTP方法
+	Path        string // 不含basePath，路径参数已转换为:name
+	Summary     string
+	Description string
+	OperationID string
+	Tag         string // 第一个标签，用于映射API分类
+	Deprecated  bool
+}
+
+// parseAPISpec 解析OpenAPI 3或Swagger 2文档，支持JSON和YAML
+func parseAPISpec(data []byte) (*apiSpec, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("接口文档为空")
+	}
+
+	var doc map[string]interface{}
+	if data[0] == '{' {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("解析JSON接口文档失败: %v", err)
+		}
+	} else if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("解析YAML接口文档失败: %v", err)
+	}
+
+	spec := &apiSpec{}
+	info, _ := doc["info"].(map[string]interface{})
+	spec.Title = specString(info, "title")
+
+	switch {
+	case specString(doc, "openapi") != "":
+		version := specString(doc, "openapi")
+		if !strings.HasPrefix(version, "3.") {
+			return nil, fmt.Errorf("不支持的OpenAPI版本: %s", version)
+		}
+		spec.Format = "openapi " + version
+		spec.BasePath = openAPIServerPath(doc)
+	case specString(doc, "swagger") != "":
+		version := specString(doc, "swagger")
+		if version != "2.0" {
+			return nil, fmt.Errorf("不支持的Swagger版本: %s", version)
+		}
+		spec.Format = "swagger " + version
+		spec.BasePath = strings.TrimRight(specString(doc, "basePath"), "/")
+	default:
+		return nil, errors.New("无法识别的接口文档，需要OpenAPI 3或Swagger 2格式")
+	}
+
+	paths, ok := doc["paths"].(map[string]interface{})
+	if !ok || len(paths) == 0 {
+		return nil, errors.New("接口文档中没有paths")
+	}
+
+	// 按路径排序，保证预览结果稳定
+	pathKeys := make([]string, 0, len(paths))
+	for path := range paths {
+		pathKeys = append(pathKeys, path)
+	}
+	sort.Strings(pathKeys)
+
+	for _, path := range pathKeys {
+		item, ok := paths[path].(map[string]interface{})
+		if !ok || !strings.HasPrefix(path, "/") {
+			continue
+		}
+		for _, method := range openAPIMethods {
+			op, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			operation := &apiSpecOperation{
+				Method:      strings.ToUpper(method),
+				Path:        openAPIPathParam.ReplaceAllString(path, ":$1"),
+				Summary:     specString(op, "summary"),
+				Description: specString(op, "description"),
+				OperationID: specString(op, "operationId"),
+			}
+			if tags, ok := op["tags"].([]interface{}); ok && len(tags) > 0 {
+				operation.Tag, _ = tags[0].(string)
+			}
+			operation.Deprecated, _ = op["deprecated"].(bool)
+			spec.Operations = append(spec.Operations, operation)
+		}
+	}
+
+	if len(spec.Operations) == 0 {
+		return nil, errors.New("接口文档中没有任何操作")
+	}
+	return spec, nil
+}
+
+// openAPIServerPath OpenAPI 3第一个server地址的路径部分，地址中的变量使用默认值替换
+func openAPIServerPath(doc map[string]interface{}) string {
+	servers, _ := doc["servers"].([]interface{})
+	if len(servers) == 0 {
+		return ""
+	}
+	server, _ := servers[0].(map[string]interface{})
+	rawURL := specString(server, "url")
+	if variables, ok := server["variables"].(map[string]interface{}); ok {
+		for name, v := range variables {
+			variable, _ := v.(map[string]interface{})
+			rawURL = strings.ReplaceAll(rawURL, "{"+name+"}", specString(variable, "default"))
+		}
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(u.Path, "/")
+}
+
+// specString 读取文档对象中的字符串字段
+func specString(obj map[string]interface{}, key string) string {
+	if obj == nil {
+		return ""
+	}
+	switch v := obj[key].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		// YAML中未加引号的swagger: 2.0会被解析为数字
+		return fmt.Sprintf("%.1f", v)
+	}
+	return ""
+}
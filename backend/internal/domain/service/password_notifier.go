@@ -0,0 +1,91 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"mcprapi/backend/internal/pkg/logger"
+)
+
+// PasswordResetNotice 密码重置通知内容
+type PasswordResetNotice struct {
+	UserID    uint      `json:"user_id"`
+	Username  string    `json:"username"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+	ResetURL  string    `json:"reset_url,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PasswordResetNotifier 密码重置令牌的下发渠道，可替换为邮件、短信等实现
+type PasswordResetNotifier interface {
+	// Channel 渠道名称
+	Channel() string
+	// NotifyPasswordReset 将重置令牌发送给用户
+	NotifyPasswordReset(notice *PasswordResetNotice) error
+}
+
+// LogPasswordResetNotifier 将重置令牌写入服务日志，仅用于本地开发
+type LogPasswordResetNotifier struct {
+	logger *logger.Logger
+}
+
+// NewLogPasswordResetNotifier 创建日志通知
+func NewLogPasswordResetNotifier(logger *logger.Logger) *LogPasswordResetNotifier {
+	return &LogPasswordResetNotifier{logger: logger}
+}
+
+// Channel 渠道名称
+func (n *LogPasswordResetNotifier) Channel() string {
+	return "log"
+}
+
+// NotifyPasswordReset 将重置令牌写入日志
+func (n *LogPasswordResetNotifier) NotifyPasswordReset(notice *PasswordResetNotice) error {
+	n.logger.Info("密码重置: user=%s email=%s token=%s url=%s expires_at=%s",
+		notice.Username, notice.Email, notice.Token, notice.ResetURL, notice.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// FilePasswordResetNotifier 将重置通知以JSON Lines追加到文件，供本地查看或其他程序投递
+type FilePasswordResetNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFilePasswordResetNotifier 创建文件通知
+func NewFilePasswordResetNotifier(path string) *FilePasswordResetNotifier {
+	return &FilePasswordResetNotifier{path: path}
+}
+
+// Channel 渠道名称
+func (n *FilePasswordResetNotifier) Channel() string {
+	return "file"
+}
+
+// NotifyPasswordReset 追加写入一行通知，文件仅所有者可读写
+func (n *FilePasswordResetNotifier) NotifyPasswordReset(notice *PasswordResetNotice) error {
+	data, err := json.Marshal(notice)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(n.path), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(n.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,467 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/internal/infrastructure/cache"
+	"mcprapi/backend/internal/pkg/encrypt"
+	"mcprapi/backend/internal/pkg/logger"
+)
+
+// 密码令牌在Redis中的键前缀
+const (
+	passwordChangeKeyPrefix    = "password:change:"     // 登录时强制修改密码的凭证
+	passwordResetKeyPrefix     = "password:reset:"      // 管理员发起的重置令牌
+	passwordResetUserKeyPrefix = "password:reset:user:" // 用户当前有效的重置令牌，重新发起时作废旧令牌
+	passwordChangeTTL          = 10 * time.Minute
+)
+
+// PasswordPolicy 密码策略配置
+type PasswordPolicy struct {
+	MinLength        int  `mapstructure:"min_length" json:"min_length"`
+	MaxLength        int  `mapstructure:"max_length" json:"max_length"`
+	RequireUpper     bool `mapstructure:"require_upper" json:"require_upper"`
+	RequireLower     bool `mapstructure:"require_lower" json:"require_lower"`
+	RequireDigit     bool `mapstructure:"require_digit" json:"require_digit"`
+	RequireSymbol    bool `mapstructure:"require_symbol" json:"require_symbol"`
+	DisallowUsername bool `mapstructure:"disallow_username" json:"disallow_username"` // 密码中不能包含用户名
+	HistoryCount     int  `mapstructure:"history_count" json:"history_count"`         // 不能与当前密码及最近N次使用过的密码相同
+	MaxAgeDays       int  `mapstructure:"max_age_days" json:"max_age_days"`           // 密码有效期（天），0表示不过期
+}
+
+// PasswordResetConfig 密码重置配置
+type PasswordResetConfig struct {
+	TokenTTL time.Duration `mapstructure:"token_ttl"` // 重置令牌有效期
+	URL      string        `mapstructure:"url"`       // 重置页面地址，令牌拼接在末尾
+}
+
+// PasswordStatus 密码状态
+type PasswordStatus struct {
+	ChangedAt  *time.Time `json:"changed_at"`
+	ExpiresAt  *time.Time `json:"expires_at"` // 未启用有效期时为空
+	MustChange bool       `json:"must_change"`
+}
+
+// ChangePasswordRequest 修改密码请求
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ChangeExpiredPasswordRequest 登录时修改过期密码请求
+type ChangeExpiredPasswordRequest struct {
+	PasswordChangeToken string `json:"password_change_token" binding:"required"`
+	NewPassword         string `json:"new_password" binding:"required"`
+}
+
+// ResetPasswordRequest 使用重置令牌设置新密码请求
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// PasswordResetResult 管理员发起重置的结果，令牌只通过通知渠道下发
+type PasswordResetResult struct {
+	UserID    uint   `json:"user_id"`
+	Channel   string `json:"channel"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// passwordToken 密码令牌内容
+type passwordToken struct {
+	UserID uint         `json:"user_id"`
+	Origin *LoginOrigin `json:"origin,omitempty"` // 登录时修改密码的凭证保存登录请求的来源
+}
+
+// PasswordService 密码策略和密码生命周期服务
+type PasswordService struct {
+	policy      PasswordPolicy
+	resetConfig PasswordResetConfig
+	userRepo    repository.UserRepository
+	historyRepo repository.PasswordHistoryRepository
+	authService *AuthService
+	cache       *cache.Redis
+	notifier    PasswordResetNotifier
+	sessions    SessionRevoker
+	logger      *logger.Logger
+}
+
+// NewPasswordService 创建密码服务，未配置的参数使用默认值
+func NewPasswordService(policy PasswordPolicy, resetConfig PasswordResetConfig, userRepo repository.UserRepository, historyRepo repository.PasswordHistoryRepository,
+	authService *AuthService, cache *cache.Redis, notifier PasswordResetNotifier, logger *logger.Logger) *PasswordService {
+	if policy.MinLength <= 0 {
+		policy.MinLength = 8
+	}
+	if policy.MaxLength <= 0 {
+		policy.MaxLength = 128
+	}
+	if resetConfig.TokenTTL <= 0 {
+		resetConfig.TokenTTL = 30 * time.Minute
+	}
+	if notifier == nil {
+		notifier = NewLogPasswordResetNotifier(logger)
+	}
+
+	return &PasswordService{
+		policy:      policy,
+		resetConfig: resetConfig,
+		userRepo:    userRepo,
+		historyRepo: historyRepo,
+		authService: authService,
+		cache:       cache,
+		notifier:    notifier,
+		logger:      logger,
+	}
+}
+
+// SetSessionRevoker 设置会话终止，密码修改和重置后终止用户的会话
+func (s *PasswordService) SetSessionRevoker(sessions SessionRevoker) {
+	s.sessions = sessions
+}
+
+// GetPolicy 获取密码策略，供客户端展示规则
+func (s *PasswordService) GetPolicy() *PasswordPolicy {
+	policy := s.policy
+	return &policy
+}
+
+// ValidatePassword 按密码策略校验复杂度
+func (s *PasswordService) ValidatePassword(username, password string) error {
+	var problems []string
+
+	length := utf8.RuneCountInString(password)
+	if length < s.policy.MinLength {
+		problems = append(problems, fmt.Sprintf("长度不能少于%d位", s.policy.MinLength))
+	}
+	if length > s.policy.MaxLength {
+		problems = append(problems, fmt.Sprintf("长度不能超过%d位", s.policy.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+	if s.policy.RequireUpper && !hasUpper {
+		problems = append(problems, "必须包含大写字母")
+	}
+	if s.policy.RequireLower && !hasLower {
+		problems = append(problems, "必须包含小写字母")
+	}
+	if s.policy.RequireDigit && !hasDigit {
+		problems = append(problems, "必须包含数字")
+	}
+	if s.policy.RequireSymbol && !hasSymbol {
+		problems = append(problems, "必须包含特殊字符")
+	}
+	if s.policy.DisallowUsername && len(username) >= 3 && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		problems = append(problems, "不能包含用户名")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("密码不符合要求：" + strings.Join(problems, "，"))
+	}
+	return nil
+}
+
+// Challenge 登录时密码已过期或被要求修改，返回修改密码凭证而不是登录令牌
+func (s *PasswordService) Challenge(user *entity.User, origin *LoginOrigin) (*LoginResponse, error) {
+	if !s.requiresChange(user) {
+		return nil, nil
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cache.Set(passwordChangeKeyPrefix+token, &passwordToken{UserID: user.ID, Origin: origin}, passwordChangeTTL); err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		PasswordChangeRequired: true,
+		PasswordChangeToken:    token,
+		ExpiresAt:              time.Now().Add(passwordChangeTTL).Unix(),
+	}, nil
+}
+
+// ChangeExpiredPassword 凭修改密码凭证设置新密码并完成登录，用户已有的会话全部终止
+func (s *PasswordService) ChangeExpiredPassword(req *ChangeExpiredPasswordRequest) (*LoginResponse, error) {
+	user, token, err := s.useToken(passwordChangeKeyPrefix+req.PasswordChangeToken, req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+	return s.authService.loginSucceeded(user, token.Origin)
+}
+
+// ChangePassword 用户修改自己的密码，除sessionID对应的当前会话外其他会话全部终止，返回新的登录令牌
+func (s *PasswordService) ChangePassword(userID uint, sessionID string, req *ChangePasswordRequest) (*LoginResponse, error) {
+	user, err := s.getLocalUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if ok, _ := encrypt.CheckPassword(req.OldPassword, user.Password); !ok {
+		return nil, errors.New("原密码错误")
+	}
+	if err := s.setPassword(user, req.NewPassword); err != nil {
+		return nil, err
+	}
+	if err := s.revokeSessions(user, sessionID); err != nil {
+		return nil, err
+	}
+	return s.authService.completeLogin(user)
+}
+
+// GetStatus 获取用户的密码状态
+func (s *PasswordService) GetStatus(userID uint) (*PasswordStatus, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	status := &PasswordStatus{
+		ChangedAt:  user.PasswordChangedAt,
+		MustChange: user.MustChangePassword,
+	}
+	if expiresAt, ok := s.expiresAt(user); ok {
+		status.ExpiresAt = &expiresAt
+	}
+	return status, nil
+}
+
+// RequestReset 管理员为用户发起密码重置，一次性令牌通过通知渠道下发，之前的重置令牌作废
+func (s *PasswordService) RequestReset(userID uint) (*PasswordResetResult, error) {
+	user, err := s.getLocalUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous string
+	if err := s.cache.Get(passwordResetUserKeyPrefix+fmt.Sprint(user.ID), &previous); err == nil && previous != "" {
+		if err := s.cache.Delete(passwordResetKeyPrefix + previous); err != nil {
+			return nil, err
+		}
+	}
+
+	ttl := s.resetConfig.TokenTTL
+	if err := s.cache.Set(passwordResetKeyPrefix+token, &passwordToken{UserID: user.ID}, ttl); err != nil {
+		return nil, err
+	}
+	if err := s.cache.Set(passwordResetUserKeyPrefix+fmt.Sprint(user.ID), token, ttl); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	notice := &PasswordResetNotice{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Name:      user.Name,
+		Email:     user.Email,
+		Token:     token,
+		ExpiresAt: expiresAt,
+	}
+	if s.resetConfig.URL != "" {
+		notice.ResetURL = s.resetConfig.URL + url.QueryEscape(token)
+	}
+	if err := s.notifier.NotifyPasswordReset(notice); err != nil {
+		s.cache.Delete(passwordResetKeyPrefix + token)
+		return nil, fmt.Errorf("发送重置通知失败: %v", err)
+	}
+
+	return &PasswordResetResult{
+		UserID:    user.ID,
+		Channel:   s.notifier.Channel(),
+		ExpiresAt: expiresAt.Unix(),
+	}, nil
+}
+
+// ResetPassword 使用重置令牌设置新密码，令牌只能使用一次，用户已有的会话全部终止
+func (s *PasswordService) ResetPassword(req *ResetPasswordRequest) error {
+	user, _, err := s.useToken(passwordResetKeyPrefix+req.Token, req.NewPassword)
+	if err != nil {
+		return err
+	}
+	return s.cache.Delete(passwordResetUserKeyPrefix + fmt.Sprint(user.ID))
+}
+
+// SetPassword 由身份源（如SCIM）为用户设置密码：校验策略和历史密码，用户已有的会话全部终止
+func (s *PasswordService) SetPassword(userID uint, password string) error {
+	user, err := s.getLocalUser(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.setPassword(user, password); err != nil {
+		return err
+	}
+	return s.revokeSessions(user, "")
+}
+
+// ExpirePassword 管理员要求用户下次登录时修改密码
+func (s *PasswordService) ExpirePassword(userID uint) error {
+	user, err := s.getLocalUser(userID)
+	if err != nil {
+		return err
+	}
+	user.MustChangePassword = true
+	return s.userRepo.Update(user)
+}
+
+// useToken 凭一次性令牌设置新密码并终止用户的所有会话；新密码不符合要求时令牌保留以便重试，校验通过后原子地作废令牌
+func (s *PasswordService) useToken(key, password string) (*entity.User, *passwordToken, error) {
+	var token passwordToken
+	if err := s.cache.Get(key, &token); err != nil {
+		return nil, nil, errors.New("令牌无效或已过期")
+	}
+
+	user, err := s.getLocalUser(token.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkNewPassword(user, password); err != nil {
+		return nil, nil, err
+	}
+
+	// 并发使用同一令牌时只有一个请求能取到
+	if err := s.cache.GetDel(key, &token); err != nil {
+		if cache.IsNotFound(err) {
+			return nil, nil, errors.New("令牌无效或已过期")
+		}
+		return nil, nil, err
+	}
+	if err := s.applyPassword(user, password); err != nil {
+		return nil, nil, err
+	}
+	if err := s.revokeSessions(user, ""); err != nil {
+		return nil, nil, err
+	}
+	return user, &token, nil
+}
+
+// setPassword 校验策略和历史密码后更新密码
+func (s *PasswordService) setPassword(user *entity.User, password string) error {
+	if err := s.checkNewPassword(user, password); err != nil {
+		return err
+	}
+	return s.applyPassword(user, password)
+}
+
+// checkNewPassword 校验新密码的复杂度，且不能与当前密码及历史密码相同
+func (s *PasswordService) checkNewPassword(user *entity.User, password string) error {
+	if err := s.ValidatePassword(user.Username, password); err != nil {
+		return err
+	}
+
+	if s.policy.HistoryCount > 0 {
+		if ok, _ := encrypt.CheckPassword(password, user.Password); ok {
+			return errors.New("新密码不能与当前密码相同")
+		}
+		histories, err := s.historyRepo.ListRecent(user.ID, s.policy.HistoryCount)
+		if err != nil {
+			return err
+		}
+		for _, history := range histories {
+			if ok, _ := encrypt.CheckPassword(password, history.Password); ok {
+				return fmt.Errorf("新密码不能与最近%d次使用过的密码相同", s.policy.HistoryCount)
+			}
+		}
+	}
+	return nil
+}
+
+// applyPassword 更新密码并记录历史密码，同时递增Token版本号使权限校验接口拒绝旧令牌；会话由调用方终止
+func (s *PasswordService) applyPassword(user *entity.User, password string) error {
+	passwordHash, err := encrypt.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	if s.policy.HistoryCount > 0 && user.Password != "" {
+		if err := s.historyRepo.Create(&entity.PasswordHistory{UserID: user.ID, Password: user.Password}); err != nil {
+			return err
+		}
+		if err := s.historyRepo.Prune(user.ID, s.policy.HistoryCount); err != nil {
+			s.logger.Warn("清理历史密码失败: %v", err)
+		}
+	}
+
+	now := time.Now()
+	user.Password = passwordHash
+	user.PasswordChangedAt = &now
+	user.MustChangePassword = false
+	user.TokenVersion++
+	return s.userRepo.Update(user)
+}
+
+// revokeSessions 密码变更后终止用户的会话，exceptID不为空时保留该会话
+func (s *PasswordService) revokeSessions(user *entity.User, exceptID string) error {
+	if s.sessions == nil {
+		return nil
+	}
+	if _, err := s.sessions.RevokeAll(user.ID, exceptID); err != nil {
+		return fmt.Errorf("密码已修改，但终止会话失败: %v", err)
+	}
+	return nil
+}
+
+// requiresChange 密码被要求修改或已过期；外部目录账号的密码不由本系统管理
+func (s *PasswordService) requiresChange(user *entity.User) bool {
+	if user.Source == entity.SourceLDAP {
+		return false
+	}
+	if user.MustChangePassword {
+		return true
+	}
+	expiresAt, ok := s.expiresAt(user)
+	return ok && time.Now().After(expiresAt)
+}
+
+// expiresAt 计算密码过期时间，从未修改过密码的按创建时间计算
+func (s *PasswordService) expiresAt(user *entity.User) (time.Time, bool) {
+	if s.policy.MaxAgeDays <= 0 || user.Source == entity.SourceLDAP {
+		return time.Time{}, false
+	}
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return changedAt.AddDate(0, 0, s.policy.MaxAgeDays), true
+}
+
+// getLocalUser 获取密码由本系统管理的用户
+func (s *PasswordService) getLocalUser(userID uint) (*entity.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("用户不存在")
+	}
+	if user.Source == entity.SourceLDAP {
+		return nil, errors.New("LDAP账号请在目录服务中修改密码")
+	}
+	return user, nil
+}
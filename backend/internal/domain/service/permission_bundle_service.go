@@ -0,0 +1,831 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/internal/pkg/logger"
+	"mcprapi/backend/pkg/casbinx"
+)
+
+// PermissionBundleConfig 权限模板配置
+type PermissionBundleConfig struct {
+	ReapplyInterval time.Duration `mapstructure:"reapply_interval"` // 定期按最新版本重新应用所有模板的间隔，0表示只支持手动重新应用
+}
+
+// PermissionBundleRule 权限模板规则，命中任一规则的API都属于模板
+type PermissionBundleRule struct {
+	Type            string   `json:"type" binding:"required,oneof=api category business pattern"`
+	APIID           uint     `json:"api_id,omitempty"`           // api规则的API ID
+	CategoryID      uint     `json:"category_id,omitempty"`      // category规则的分类ID
+	IncludeChildren bool     `json:"include_children,omitempty"` // category规则是否包含所有子分类下的API
+	BusinessID      uint     `json:"business_id,omitempty"`      // business规则的业务线ID
+	Path            string   `json:"path,omitempty"`             // pattern规则的keyMatch2路径模式，如/order/api/v1/*
+	Methods         []string `json:"methods,omitempty"`          // 限定的HTTP方法，为空表示全部，如只读模板为["GET"]
+}
+
+// CreatePermissionBundleRequest 创建权限模板请求
+type CreatePermissionBundleRequest struct {
+	Name        string                  `json:"name" binding:"required,max=100"`
+	Code        string                  `json:"code" binding:"required,max=100"`
+	Description string                  `json:"description" binding:"max=200"`
+	Rules       []*PermissionBundleRule `json:"rules" binding:"required,min=1,dive"`
+}
+
+// UpdatePermissionBundleRequest 更新权限模板请求，规则变化时生成新版本
+type UpdatePermissionBundleRequest struct {
+	Name        string                  `json:"name" binding:"required,max=100"`
+	Description string                  `json:"description" binding:"max=200"`
+	Rules       []*PermissionBundleRule `json:"rules" binding:"required,min=1,dive"`
+	Comment     string                  `json:"comment" binding:"max=200"` // 版本说明
+}
+
+// ApplyPermissionBundleRequest 应用权限模板请求
+type ApplyPermissionBundleRequest struct {
+	RoleIDs []uint `json:"role_ids" binding:"required,min=1"`
+	Version int    `json:"version"`           // 应用的版本，0表示最新版本
+	DeptID  uint   `json:"dept_id,omitempty"` // 策略的部门维度，0表示全部门
+	Prune   bool   `json:"prune"`             // 删除模板之前生成、但已不再属于模板的策略
+}
+
+// PermissionBundleDetail 权限模板详情
+type PermissionBundleDetail struct {
+	*entity.PermissionBundle
+	Rules []*PermissionBundleRule     `json:"rules"`
+	Roles []*PermissionBundleRoleInfo `json:"roles"`
+}
+
+// PermissionBundleRoleInfo 应用了权限模板的角色
+type PermissionBundleRoleInfo struct {
+	RoleID    uint      `json:"role_id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	Dept      string    `json:"dept"`
+	AppliedAt time.Time `json:"applied_at"`
+	Outdated  bool      `json:"outdated"` // 应用的不是最新版本
+}
+
+// PermissionBundleVersionInfo 权限模板版本
+type PermissionBundleVersionInfo struct {
+	*entity.PermissionBundleVersion
+	Rules []*PermissionBundleRule `json:"rules"`
+}
+
+// RolePermissionBundleInfo 角色应用的权限模板
+type RolePermissionBundleInfo struct {
+	*entity.PermissionBundle
+	AppliedVersion int       `json:"applied_version"`
+	Dept           string    `json:"dept"`
+	AppliedAt      time.Time `json:"applied_at"`
+}
+
+// PermissionBundleApplyResult 权限模板在一个角色上的应用结果
+type PermissionBundleApplyResult struct {
+	RoleID   uint   `json:"role_id"`
+	RoleCode string `json:"role_code"`
+	Version  int    `json:"version"`
+	APIs     int    `json:"apis"`    // 模板当前包含的API数
+	Added    int    `json:"added"`   // 新增的策略数
+	Removed  int    `json:"removed"` // 删除的策略数
+	Error    string `json:"error,omitempty"`
+}
+
+// PermissionBundleService 权限模板服务：模板按规则解析为API，应用到角色时生成策略，重新应用时补齐新加入分类或业务线的API
+type PermissionBundleService struct {
+	cfg           PermissionBundleConfig
+	bundleRepo    repository.PermissionBundleRepository
+	apiRepo       repository.APIRepository
+	roleRepo      repository.RoleRepository
+	businessRepo  repository.BusinessRepository
+	enforcer      *casbinx.Enforcer
+	groupCompiler APIGroupCompiler
+	audit         AuditRecorder
+	logger        *logger.Logger
+
+	// 应用会读写角色的策略，串行执行
+	mu sync.Mutex
+}
+
+// NewPermissionBundleService 创建权限模板服务
+func NewPermissionBundleService(cfg PermissionBundleConfig, bundleRepo repository.PermissionBundleRepository, apiRepo repository.APIRepository,
+	roleRepo repository.RoleRepository, businessRepo repository.BusinessRepository, enforcer *casbinx.Enforcer, logger *logger.Logger) *PermissionBundleService {
+	return &PermissionBundleService{
+		cfg:          cfg,
+		bundleRepo:   bundleRepo,
+		apiRepo:      apiRepo,
+		roleRepo:     roleRepo,
+		businessRepo: businessRepo,
+		enforcer:     enforcer,
+		logger:       logger,
+	}
+}
+
+// SetAPIGroupCompiler 设置API组策略编译，删除模板策略后恢复仍由API组授予的策略
+func (s *PermissionBundleService) SetAPIGroupCompiler(compiler APIGroupCompiler) {
+	s.groupCompiler = compiler
+}
+
+// SetAuditRecorder 设置审计记录，后台重新应用模板修改的策略写入审计日志
+func (s *PermissionBundleService) SetAuditRecorder(audit AuditRecorder) {
+	s.audit = audit
+}
+
+// ListBundles 获取所有权限模板
+func (s *PermissionBundleService) ListBundles() ([]*entity.PermissionBundle, error) {
+	return s.bundleRepo.List()
+}
+
+// GetBundle 获取权限模板详情，包括当前版本的规则和应用了模板的角色
+func (s *PermissionBundleService) GetBundle(id uint) (*PermissionBundleDetail, error) {
+	bundle, err := s.getBundle(id)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.versionRules(bundle.ID, bundle.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	applications, err := s.bundleRepo.ListApplications(id)
+	if err != nil {
+		return nil, err
+	}
+	detail := &PermissionBundleDetail{
+		PermissionBundle: bundle,
+		Rules:            rules,
+		Roles:            make([]*PermissionBundleRoleInfo, 0, len(applications)),
+	}
+	for _, application := range applications {
+		role, err := s.roleRepo.GetByID(application.RoleID)
+		if err != nil || role == nil {
+			continue
+		}
+		detail.Roles = append(detail.Roles, &PermissionBundleRoleInfo{
+			RoleID:    role.ID,
+			Code:      role.Code,
+			Name:      role.Name,
+			Version:   application.Version,
+			Dept:      application.Dept,
+			AppliedAt: application.AppliedAt,
+			Outdated:  application.Version < bundle.Version,
+		})
+	}
+	return detail, nil
+}
+
+// ListVersions 获取权限模板的所有版本
+func (s *PermissionBundleService) ListVersions(id uint) ([]*PermissionBundleVersionInfo, error) {
+	if _, err := s.getBundle(id); err != nil {
+		return nil, err
+	}
+	versions, err := s.bundleRepo.ListVersions(id)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]*PermissionBundleVersionInfo, 0, len(versions))
+	for _, version := range versions {
+		var rules []*PermissionBundleRule
+		if err := json.Unmarshal([]byte(version.Rules), &rules); err != nil {
+			return nil, fmt.Errorf("解析模板版本%d的规则失败: %v", version.Version, err)
+		}
+		infos = append(infos, &PermissionBundleVersionInfo{PermissionBundleVersion: version, Rules: rules})
+	}
+	return infos, nil
+}
+
+// CreateBundle 创建权限模板，规则保存为版本1
+func (s *PermissionBundleService) CreateBundle(req *CreatePermissionBundleRequest, operator string) (*entity.PermissionBundle, error) {
+	code := strings.TrimSpace(req.Code)
+	if !apiGroupCodePattern.MatchString(code) {
+		return nil, errors.New("模板编码只能包含小写字母、数字和._:-")
+	}
+	existing, err := s.bundleRepo.GetByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("模板编码已存在")
+	}
+	rules, err := s.encodeRules(req.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &entity.PermissionBundle{
+		Name:        req.Name,
+		Code:        code,
+		Description: req.Description,
+		Version:     1,
+	}
+	version := &entity.PermissionBundleVersion{
+		Version:   1,
+		Rules:     rules,
+		Comment:   "创建",
+		CreatedBy: operator,
+	}
+	if err := s.bundleRepo.Create(bundle, version); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// UpdateBundle 更新权限模板，规则变化时生成新版本；已应用的角色在重新应用后才使用新版本
+func (s *PermissionBundleService) UpdateBundle(id uint, req *UpdatePermissionBundleRequest, operator string) (*entity.PermissionBundle, error) {
+	bundle, err := s.getBundle(id)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.encodeRules(req.Rules)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.bundleRepo.GetVersion(bundle.ID, bundle.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle.Name = req.Name
+	bundle.Description = req.Description
+	var version *entity.PermissionBundleVersion
+	if current == nil || current.Rules != rules {
+		bundle.Version++
+		version = &entity.PermissionBundleVersion{
+			Version:   bundle.Version,
+			Rules:     rules,
+			Comment:   req.Comment,
+			CreatedBy: operator,
+		}
+	}
+	if err := s.bundleRepo.Update(bundle, version); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// DeleteBundle 删除权限模板，已应用到角色的模板需先撤销
+func (s *PermissionBundleService) DeleteBundle(id uint) error {
+	if _, err := s.getBundle(id); err != nil {
+		return err
+	}
+	applications, err := s.bundleRepo.ListApplications(id)
+	if err != nil {
+		return err
+	}
+	if len(applications) > 0 {
+		return fmt.Errorf("模板已应用到%d个角色，请先撤销", len(applications))
+	}
+	return s.bundleRepo.Delete(id)
+}
+
+// Resolve 按指定版本的规则解析模板当前包含的API，version为0时使用最新版本
+func (s *PermissionBundleService) Resolve(id uint, version int) ([]*entity.API, error) {
+	bundle, err := s.getBundle(id)
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		version = bundle.Version
+	}
+	rules, err := s.versionRules(bundle.ID, version)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolve(rules)
+}
+
+// Apply 将权限模板应用到角色：为模板包含的API生成策略，prune为true时删除模板之前生成、但已不再属于模板的策略
+func (s *PermissionBundleService) Apply(id uint, req *ApplyPermissionBundleRequest) ([]*PermissionBundleApplyResult, error) {
+	bundle, err := s.getBundle(id)
+	if err != nil {
+		return nil, err
+	}
+	version := req.Version
+	if version == 0 {
+		version = bundle.Version
+	}
+	dept := "*"
+	if req.DeptID > 0 {
+		dept = strconv.FormatUint(uint64(req.DeptID), 10)
+	}
+
+	roles := make([]*entity.Role, 0, len(req.RoleIDs))
+	for _, roleID := range req.RoleIDs {
+		role, err := s.roleRepo.GetByID(roleID)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return nil, fmt.Errorf("角色不存在: %d", roleID)
+		}
+		roles = append(roles, role)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	apis, err := s.resolveVersion(bundle.ID, version)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*PermissionBundleApplyResult, 0, len(roles))
+	for _, role := range roles {
+		result, err := s.apply(bundle.ID, version, role, dept, apis, req.Prune)
+		if err != nil {
+			return nil, fmt.Errorf("应用模板到角色%s失败: %v", role.Code, err)
+		}
+		results = append(results, result)
+	}
+	return results, s.enforcer.SyncPolicyToMemory()
+}
+
+// Reapply 按最新版本重新应用到所有已应用该模板的角色，补齐新加入分类、业务线或匹配路径模式的API，删除已不再属于模板的策略
+func (s *PermissionBundleService) Reapply(id uint) ([]*PermissionBundleApplyResult, error) {
+	bundle, err := s.getBundle(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results, err := s.reapply(bundle)
+	if err != nil {
+		return nil, err
+	}
+	return results, s.enforcer.SyncPolicyToMemory()
+}
+
+// Revoke 撤销角色上的权限模板，删除模板生成的策略；其他模板或API组仍授予的策略保留
+func (s *PermissionBundleService) Revoke(id, roleID uint) error {
+	application, err := s.bundleRepo.GetApplication(id, roleID)
+	if err != nil {
+		return err
+	}
+	if application == nil {
+		return errors.New("角色未应用该模板")
+	}
+	role, err := s.roleRepo.GetByID(roleID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if role != nil {
+		policies, err := s.bundleRepo.ListPolicies(id, roleID)
+		if err != nil {
+			return err
+		}
+		others, err := s.otherBundlePolicies(id, roleID)
+		if err != nil {
+			return err
+		}
+		for _, policy := range policies {
+			if others[bundlePolicyKey(policy)] {
+				continue
+			}
+			if _, err := s.enforcer.RemovePolicyWithDept(role.Code, policy.Path, policy.Method, policy.Dept, "allow"); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.bundleRepo.DeleteApplication(id, roleID); err != nil {
+		return err
+	}
+	if err := s.restoreGroupPolicies(roleID); err != nil {
+		return err
+	}
+	return s.enforcer.SyncPolicyToMemory()
+}
+
+// GetRoleBundles 获取角色应用的权限模板
+func (s *PermissionBundleService) GetRoleBundles(roleID uint) ([]*RolePermissionBundleInfo, error) {
+	applications, err := s.bundleRepo.ListApplicationsByRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]*RolePermissionBundleInfo, 0, len(applications))
+	for _, application := range applications {
+		bundle, err := s.bundleRepo.GetByID(application.BundleID)
+		if err != nil {
+			return nil, err
+		}
+		if bundle == nil {
+			continue
+		}
+		infos = append(infos, &RolePermissionBundleInfo{
+			PermissionBundle: bundle,
+			AppliedVersion:   application.Version,
+			Dept:             application.Dept,
+			AppliedAt:        application.AppliedAt,
+		})
+	}
+	return infos, nil
+}
+
+// Start 启动后台任务：定期按最新版本重新应用所有模板
+func (s *PermissionBundleService) Start(ctx context.Context) {
+	if s.cfg.ReapplyInterval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(s.cfg.ReapplyInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.reapplyAll()
+			}
+		}
+	}()
+}
+
+// CategoryReferences 返回当前版本规则引用了该分类的模板编码
+func (s *PermissionBundleService) CategoryReferences(categoryID uint) ([]string, error) {
+	bundles, err := s.bundleRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	var codes []string
+	for _, bundle := range bundles {
+		rules, err := s.versionRules(bundle.ID, bundle.Version)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range rules {
+			if rule.Type == entity.BundleRuleCategory && rule.CategoryID == categoryID {
+				codes = append(codes, bundle.Code)
+				break
+			}
+		}
+	}
+	return codes, nil
+}
+
+// CategoryTreeChanged 分类移动后，包含子分类的规则命中的API可能变化，后台重新应用所有模板
+func (s *PermissionBundleService) CategoryTreeChanged() {
+	go s.reapplyAll()
+}
+
+// reapplyAll 重新应用所有模板，只记录有变化的角色
+func (s *PermissionBundleService) reapplyAll() {
+	bundles, err := s.bundleRepo.List()
+	if err != nil {
+		s.logger.Error("获取权限模板失败: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := false
+	var events []*AuditEvent
+	for _, bundle := range bundles {
+		results, err := s.reapply(bundle)
+		if err != nil {
+			s.logger.Error("重新应用权限模板%s失败: %v", bundle.Code, err)
+			continue
+		}
+		for _, result := range results {
+			event := &AuditEvent{
+				Action:     "permission_bundle.reapply",
+				TargetType: AuditTargetRolePermission,
+				TargetIDs:  []string{strconv.FormatUint(uint64(result.RoleID), 10)},
+				Detail:     map[string]interface{}{"bundle_id": bundle.ID, "bundle_code": bundle.Code, "result": result},
+			}
+			if result.Error != "" {
+				s.logger.Error("重新应用权限模板%s到角色%s失败: %s", bundle.Code, result.RoleCode, result.Error)
+				event.Err = errors.New(result.Error)
+				events = append(events, event)
+				continue
+			}
+			if result.Added > 0 || result.Removed > 0 {
+				changed = true
+				s.logger.Info("重新应用权限模板%s到角色%s: version=%d added=%d removed=%d",
+					bundle.Code, result.RoleCode, result.Version, result.Added, result.Removed)
+				events = append(events, event)
+			}
+		}
+	}
+	if changed {
+		if err := s.enforcer.SyncPolicyToMemory(); err != nil {
+			s.logger.Error("同步策略失败: %v", err)
+		}
+	}
+
+	// 策略同步后记录，快照为修改后的策略
+	if s.audit != nil {
+		for _, event := range events {
+			s.audit.Record(event)
+		}
+	}
+}
+
+// reapply 按最新版本重新应用到所有已应用该模板的角色，单个角色失败不影响其他角色；调用方持有锁
+func (s *PermissionBundleService) reapply(bundle *entity.PermissionBundle) ([]*PermissionBundleApplyResult, error) {
+	applications, err := s.bundleRepo.ListApplications(bundle.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(applications) == 0 {
+		return []*PermissionBundleApplyResult{}, nil
+	}
+	apis, err := s.resolveVersion(bundle.ID, bundle.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*PermissionBundleApplyResult, 0, len(applications))
+	for _, application := range applications {
+		role, err := s.roleRepo.GetByID(application.RoleID)
+		if err != nil || role == nil {
+			continue
+		}
+		result, err := s.apply(bundle.ID, bundle.Version, role, application.Dept, apis, true)
+		if err != nil {
+			result = &PermissionBundleApplyResult{RoleID: role.ID, RoleCode: role.Code, Version: bundle.Version, Error: err.Error()}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// apply 为角色生成模板包含的API的策略并记录归属，调用方持有锁并负责同步策略
+func (s *PermissionBundleService) apply(bundleID uint, version int, role *entity.Role, dept string, apis []*entity.API, prune bool) (*PermissionBundleApplyResult, error) {
+	result := &PermissionBundleApplyResult{RoleID: role.ID, RoleCode: role.Code, Version: version, APIs: len(apis)}
+
+	previous, err := s.bundleRepo.ListPolicies(bundleID, role.ID)
+	if err != nil {
+		return nil, err
+	}
+	owned := make(map[string]bool, len(previous))
+	for _, policy := range previous {
+		owned[bundlePolicyKey(policy)] = true
+	}
+
+	// 只记录由模板生成的策略，角色已拥有的策略撤销时保留
+	desired := make(map[string]bool, len(apis))
+	policies := make([]*entity.PermissionBundlePolicy, 0, len(apis))
+	for _, api := range apis {
+		policy := &entity.PermissionBundlePolicy{RoleID: role.ID, BundleID: bundleID, Path: api.Path, Method: api.Method, Dept: dept}
+		key := bundlePolicyKey(policy)
+		if desired[key] {
+			continue
+		}
+		desired[key] = true
+
+		added, err := s.enforcer.AddPolicyWithDept(role.Code, api.Path, api.Method, dept, "allow")
+		if err != nil {
+			return nil, err
+		}
+		if added {
+			result.Added++
+		}
+		if added || owned[key] {
+			policies = append(policies, policy)
+		}
+	}
+
+	var others map[string]bool
+	for _, policy := range previous {
+		key := bundlePolicyKey(policy)
+		if desired[key] {
+			continue
+		}
+		if !prune {
+			policies = append(policies, policy)
+			continue
+		}
+		if others == nil {
+			if others, err = s.otherBundlePolicies(bundleID, role.ID); err != nil {
+				return nil, err
+			}
+		}
+		if others[key] {
+			continue
+		}
+		if _, err := s.enforcer.RemovePolicyWithDept(role.Code, policy.Path, policy.Method, policy.Dept, "allow"); err != nil {
+			return nil, err
+		}
+		result.Removed++
+	}
+
+	application, err := s.bundleRepo.GetApplication(bundleID, role.ID)
+	if err != nil {
+		return nil, err
+	}
+	if application == nil {
+		application = &entity.RolePermissionBundle{RoleID: role.ID, BundleID: bundleID}
+	}
+	application.Version = version
+	application.Dept = dept
+	application.AppliedAt = time.Now()
+	if err := s.bundleRepo.SaveApplication(application, policies); err != nil {
+		return nil, err
+	}
+
+	if result.Removed > 0 {
+		if err := s.restoreGroupPolicies(role.ID); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// otherBundlePolicies 角色上由其他模板生成的策略
+func (s *PermissionBundleService) otherBundlePolicies(bundleID, roleID uint) (map[string]bool, error) {
+	policies, err := s.bundleRepo.ListPoliciesByRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+	others := make(map[string]bool, len(policies))
+	for _, policy := range policies {
+		if policy.BundleID != bundleID {
+			others[bundlePolicyKey(policy)] = true
+		}
+	}
+	return others, nil
+}
+
+// restoreGroupPolicies 删除策略后恢复仍由API组授予的策略
+func (s *PermissionBundleService) restoreGroupPolicies(roleID uint) error {
+	if s.groupCompiler == nil {
+		return nil
+	}
+	return s.groupCompiler.CompileRole(roleID)
+}
+
+// resolveVersion 解析模板指定版本当前包含的API
+func (s *PermissionBundleService) resolveVersion(bundleID uint, version int) ([]*entity.API, error) {
+	rules, err := s.versionRules(bundleID, version)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolve(rules)
+}
+
+// resolve 返回命中任一规则的已启用、未下线的API
+func (s *PermissionBundleService) resolve(rules []*PermissionBundleRule) ([]*entity.API, error) {
+	all, err := s.apiRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// 包含子分类的分类规则按当前分类树展开
+	subtrees := make(map[*PermissionBundleRule]map[uint]bool)
+	var categories []*entity.APICategory
+	for _, rule := range rules {
+		if rule.Type != entity.BundleRuleCategory || !rule.IncludeChildren {
+			continue
+		}
+		if categories == nil {
+			if categories, err = s.apiRepo.ListCategories(); err != nil {
+				return nil, err
+			}
+		}
+		subtree := map[uint]bool{rule.CategoryID: true}
+		for _, id := range apiCategoryDescendantIDs(categories, rule.CategoryID) {
+			subtree[id] = true
+		}
+		subtrees[rule] = subtree
+	}
+
+	now := time.Now()
+	apis := make([]*entity.API, 0)
+	for _, api := range all {
+		if api.Status != 1 || apiEffectiveLifecycle(api, now) == entity.APILifecycleRetired {
+			continue
+		}
+		for _, rule := range rules {
+			if matchBundleRule(rule, api, subtrees[rule]) {
+				apis = append(apis, api)
+				break
+			}
+		}
+	}
+	return apis, nil
+}
+
+// versionRules 获取模板指定版本的规则
+func (s *PermissionBundleService) versionRules(bundleID uint, version int) ([]*PermissionBundleRule, error) {
+	v, err := s.bundleRepo.GetVersion(bundleID, version)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, fmt.Errorf("模板版本不存在: %d", version)
+	}
+	var rules []*PermissionBundleRule
+	if err := json.Unmarshal([]byte(v.Rules), &rules); err != nil {
+		return nil, fmt.Errorf("解析模板版本%d的规则失败: %v", version, err)
+	}
+	return rules, nil
+}
+
+// encodeRules 校验并规范化规则，返回JSON
+func (s *PermissionBundleService) encodeRules(rules []*PermissionBundleRule) (string, error) {
+	normalized := make([]*PermissionBundleRule, 0, len(rules))
+	for i, rule := range rules {
+		r := &PermissionBundleRule{Type: rule.Type}
+		for _, method := range rule.Methods {
+			method = strings.ToUpper(strings.TrimSpace(method))
+			if !apiImportMethods[method] {
+				return "", fmt.Errorf("第%d条规则: 不支持的方法%s", i+1, method)
+			}
+			if !containsString(r.Methods, method) {
+				r.Methods = append(r.Methods, method)
+			}
+		}
+
+		switch rule.Type {
+		case entity.BundleRuleAPI:
+			api, err := s.apiRepo.GetByID(rule.APIID)
+			if err != nil {
+				return "", err
+			}
+			if api == nil {
+				return "", fmt.Errorf("第%d条规则: API不存在", i+1)
+			}
+			r.APIID = rule.APIID
+		case entity.BundleRuleCategory:
+			category, err := s.apiRepo.GetCategoryByID(rule.CategoryID)
+			if err != nil || category == nil {
+				return "", fmt.Errorf("第%d条规则: 分类不存在", i+1)
+			}
+			r.CategoryID = rule.CategoryID
+			r.IncludeChildren = rule.IncludeChildren
+		case entity.BundleRuleBusiness:
+			business, err := s.businessRepo.GetByID(rule.BusinessID)
+			if err != nil || business == nil {
+				return "", fmt.Errorf("第%d条规则: 业务线不存在", i+1)
+			}
+			r.BusinessID = rule.BusinessID
+		case entity.BundleRulePattern:
+			path := strings.TrimSpace(rule.Path)
+			if !strings.HasPrefix(path, "/") || len(path) > 200 {
+				return "", fmt.Errorf("第%d条规则: 路径模式必须以/开头且不超过200个字符", i+1)
+			}
+			r.Path = path
+		default:
+			return "", fmt.Errorf("第%d条规则: 不支持的类型%s", i+1, rule.Type)
+		}
+		normalized = append(normalized, r)
+	}
+
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// getBundle 获取权限模板，不存在时返回错误
+func (s *PermissionBundleService) getBundle(id uint) (*entity.PermissionBundle, error) {
+	bundle, err := s.bundleRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if bundle == nil {
+		return nil, errors.New("权限模板不存在")
+	}
+	return bundle, nil
+}
+
+// matchBundleRule API是否命中模板规则，subtree为包含子分类的分类规则展开后的分类ID
+func matchBundleRule(rule *PermissionBundleRule, api *entity.API, subtree map[uint]bool) bool {
+	if len(rule.Methods) > 0 && !containsString(rule.Methods, api.Method) {
+		return false
+	}
+	switch rule.Type {
+	case entity.BundleRuleAPI:
+		return api.ID == rule.APIID
+	case entity.BundleRuleCategory:
+		if subtree != nil {
+			return subtree[api.CategoryID]
+		}
+		return api.CategoryID == rule.CategoryID
+	case entity.BundleRuleBusiness:
+		return api.BusinessID == rule.BusinessID
+	case entity.BundleRulePattern:
+		return matchRestrictedAPI(rule.Path, "*", api.Path, api.Method)
+	}
+	return false
+}
+
+// bundlePolicyKey 模板策略的唯一键
+func bundlePolicyKey(policy *entity.PermissionBundlePolicy) string {
+	return policy.Method + " " + policy.Path + " " + policy.Dept
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"mcprapi/backend/internal/infrastructure/cache"
+)
+
+// 扫码登录状态
+const (
+	QRCodeStatusPending   = "pending"   // 等待扫码
+	QRCodeStatusScanned   = "scanned"   // 已扫码，等待手机端确认
+	QRCodeStatusConfirmed = "confirmed" // 已确认，浏览器可领取令牌
+	QRCodeStatusExpired   = "expired"   // 已过期或已被领取
+	QRCodeStatusCancelled = "cancelled" // 手机端取消
+)
+
+const (
+	// qrCodeKeyPrefix 扫码会话在Redis中的键前缀
+	qrCodeKeyPrefix = "qrcode:session:"
+	// qrCodeTokenKeyPrefix 确认后签发的登录令牌键前缀，只能领取一次
+	qrCodeTokenKeyPrefix = "qrcode:token:"
+	// qrCodeRetention 过期后会话继续保留的时间，用于向浏览器返回明确的终态
+	qrCodeRetention = time.Minute
+	// qrCodePollInterval 长轮询检查状态的间隔
+	qrCodePollInterval = 500 * time.Millisecond
+)
+
+// qrCodeSession Redis中保存的扫码会话
+type qrCodeSession struct {
+	ID        string `json:"id"`
+	Secret    string `json:"secret"` // 浏览器轮询凭证，不放入二维码
+	Status    string `json:"status"`
+	UserID    uint   `json:"user_id,omitempty"` // 扫码的用户
+	Username  string `json:"username,omitempty"`
+	Name      string `json:"name,omitempty"`
+	ClientIP  string `json:"client_ip"`
+	UserAgent string `json:"user_agent"`
+	CreatedAt int64  `json:"created_at"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// currentStatus 计算会话当前状态，超时未确认的会话视为过期
+func (q *qrCodeSession) currentStatus() string {
+	if (q.Status == QRCodeStatusPending || q.Status == QRCodeStatusScanned) && time.Now().Unix() >= q.ExpiresAt {
+		return QRCodeStatusExpired
+	}
+	return q.Status
+}
+
+// GenerateQRCodeRequest 生成二维码请求，由处理器填充浏览器信息
+type GenerateQRCodeRequest struct {
+	ClientIP  string
+	UserAgent string
+}
+
+// GenerateQRCodeResponse 生成二维码响应
+type GenerateQRCodeResponse struct {
+	QRCode    string `json:"qr_code"` // 二维码内容
+	Secret    string `json:"secret"`  // 查询状态时携带，只保存在发起登录的浏览器中
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// QRCodeStatusResponse 二维码状态响应
+type QRCodeStatusResponse struct {
+	Status    string         `json:"status"`
+	ExpiresAt int64          `json:"expires_at,omitempty"`
+	Username  string         `json:"username,omitempty"` // 已扫码时返回扫码用户，便于浏览器提示
+	Name      string         `json:"name,omitempty"`
+	Login     *LoginResponse `json:"login,omitempty"` // 确认后返回一次登录结果
+}
+
+// QRCodeScanResponse 手机端扫码响应，用于展示待登录的浏览器信息
+type QRCodeScanResponse struct {
+	ClientIP  string `json:"client_ip"`
+	UserAgent string `json:"user_agent"`
+	CreatedAt int64  `json:"created_at"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// QRCodeService 扫码登录服务
+type QRCodeService struct {
+	authService *AuthService
+	cache       *cache.Redis
+	ttl         time.Duration
+}
+
+// NewQRCodeService 创建扫码登录服务
+func NewQRCodeService(authService *AuthService, cache *cache.Redis, ttl time.Duration) *QRCodeService {
+	return &QRCodeService{
+		authService: authService,
+		cache:       cache,
+		ttl:         ttl,
+	}
+}
+
+// Generate 生成扫码登录二维码
+func (s *QRCodeService) Generate(req *GenerateQRCodeRequest) (*GenerateQRCodeResponse, error) {
+	secret, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &qrCodeSession{
+		ID:        uuid.New().String(),
+		Secret:    secret,
+		Status:    QRCodeStatusPending,
+		ClientIP:  req.ClientIP,
+		UserAgent: req.UserAgent,
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(s.ttl).Unix(),
+	}
+	if err := s.cache.Set(qrCodeKeyPrefix+session.ID, session, s.ttl+qrCodeRetention); err != nil {
+		return nil, err
+	}
+
+	return &GenerateQRCodeResponse{
+		QRCode:    session.ID,
+		Secret:    secret,
+		ExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// Check 浏览器查询二维码状态，确认后领取登录令牌
+func (s *QRCodeService) Check(id, secret string) (*QRCodeStatusResponse, error) {
+	var session qrCodeSession
+	if err := s.cache.Get(qrCodeKeyPrefix+id, &session); err != nil {
+		if cache.IsNotFound(err) {
+			return &QRCodeStatusResponse{Status: QRCodeStatusExpired}, nil
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(session.Secret)) != 1 {
+		return nil, errors.New("二维码不存在")
+	}
+
+	resp := &QRCodeStatusResponse{
+		Status:    session.currentStatus(),
+		ExpiresAt: session.ExpiresAt,
+		Username:  session.Username,
+		Name:      session.Name,
+	}
+	if resp.Status != QRCodeStatusConfirmed {
+		return resp, nil
+	}
+
+	// 令牌只能领取一次，领取后会话结束；已确认但令牌尚未写入时按已扫码返回，浏览器继续等待
+	var login LoginResponse
+	if err := s.cache.GetDel(qrCodeTokenKeyPrefix+id, &login); err != nil {
+		if cache.IsNotFound(err) {
+			resp.Status = QRCodeStatusScanned
+			return resp, nil
+		}
+		return nil, err
+	}
+	if err := s.cache.Delete(qrCodeKeyPrefix + id); err != nil {
+		return nil, err
+	}
+	resp.Login = &login
+	return resp, nil
+}
+
+// Wait 长轮询：等待状态不同于lastStatus或超时后返回当前状态
+func (s *QRCodeService) Wait(ctx context.Context, id, secret, lastStatus string, timeout time.Duration) (*QRCodeStatusResponse, error) {
+	deadline := time.Now().Add(timeout)
+	ticker := time.NewTicker(qrCodePollInterval)
+	defer ticker.Stop()
+
+	for {
+		resp, err := s.Check(id, secret)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Status != lastStatus || isQRCodeFinal(resp.Status) || !time.Now().Before(deadline) {
+			return resp, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return resp, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan 手机端扫码，记录扫码用户并返回待登录的浏览器信息
+func (s *QRCodeService) Scan(id string, userID uint) (*QRCodeScanResponse, error) {
+	user, err := s.authService.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.Status != 1 {
+		return nil, errors.New("用户不存在或已禁用")
+	}
+
+	var session qrCodeSession
+	err = s.update(id, &session, func() error {
+		switch session.currentStatus() {
+		case QRCodeStatusPending:
+		case QRCodeStatusScanned:
+			if session.UserID != userID {
+				return errors.New("二维码已被其他用户扫描")
+			}
+		default:
+			return errors.New("二维码已失效")
+		}
+		session.Status = QRCodeStatusScanned
+		session.UserID = user.ID
+		session.Username = user.Username
+		session.Name = user.Name
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &QRCodeScanResponse{
+		ClientIP:  session.ClientIP,
+		UserAgent: session.UserAgent,
+		CreatedAt: session.CreatedAt,
+		ExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// Confirm 手机端确认登录，状态变更提交后再为扫码用户签发令牌供浏览器领取，
+// 并发确认时只有状态变更成功的请求签发令牌
+func (s *QRCodeService) Confirm(id string, userID uint) error {
+	user, err := s.authService.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.Status != 1 {
+		return errors.New("用户不存在或已禁用")
+	}
+
+	var session qrCodeSession
+	err = s.update(id, &session, func() error {
+		if session.currentStatus() != QRCodeStatusScanned {
+			return errors.New("二维码未扫描或已失效")
+		}
+		if session.UserID != userID {
+			return errors.New("只能由扫码用户确认登录")
+		}
+		session.Status = QRCodeStatusConfirmed
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 与密码登录一样需要二次验证，浏览器领取到的是二次验证凭证
+	origin := &LoginOrigin{ClientIP: session.ClientIP, UserAgent: session.UserAgent}
+	login, err := s.authService.externalLogin(user, origin)
+	if err == nil {
+		// 令牌与会话同时过期，浏览器需在保留期内领取
+		err = s.cache.Set(qrCodeTokenKeyPrefix+id, login, s.ttl+qrCodeRetention)
+	}
+	if err != nil {
+		// 签发失败时结束会话，浏览器不再等待令牌
+		s.fail(id)
+		return err
+	}
+	return nil
+}
+
+// fail 确认后签发令牌失败时将会话标记为已取消
+func (s *QRCodeService) fail(id string) {
+	var session qrCodeSession
+	_ = s.update(id, &session, func() error {
+		session.Status = QRCodeStatusCancelled
+		return nil
+	})
+}
+
+// Cancel 手机端取消登录
+func (s *QRCodeService) Cancel(id string, userID uint) error {
+	var session qrCodeSession
+	return s.update(id, &session, func() error {
+		status := session.currentStatus()
+		if status != QRCodeStatusPending && status != QRCodeStatusScanned {
+			return errors.New("二维码已失效")
+		}
+		if status == QRCodeStatusScanned && session.UserID != userID {
+			return errors.New("只能由扫码用户取消登录")
+		}
+		session.Status = QRCodeStatusCancelled
+		return nil
+	})
+}
+
+// update 原子更新会话状态
+func (s *QRCodeService) update(id string, session *qrCodeSession, fn func() error) error {
+	err := s.cache.Update(qrCodeKeyPrefix+id, session, fn)
+	if cache.IsNotFound(err) {
+		return errors.New("二维码已失效")
+	}
+	if cache.IsConflict(err) {
+		return errors.New("二维码状态已变化，请重试")
+	}
+	return err
+}
+
+// isQRCodeFinal 是否为终态
+func isQRCodeFinal(status string) bool {
+	return status == QRCodeStatusConfirmed || status == QRCodeStatusExpired || status == QRCodeStatusCancelled
+}
@@ -17,6 +17,19 @@ type RoleService struct {
 	apiRepo        repository.APIRepository
 	userRepo       repository.UserRepository
 	casbinEnforcer *casbinx.Enforcer
+	groupCompiler  APIGroupCompiler
+	deleter        EntityDeleter
+	systemAPIs     SystemAPIGrants
+}
+
+// SystemAPIGrants 按授予集合查找已登记的系统API
+type SystemAPIGrants interface {
+	GrantAPIIDs(grant string) ([]uint, error)
+}
+
+// APIGroupCompiler 按角色被授予的API组重新生成组内策略，角色权限被整体替换后调用
+type APIGroupCompiler interface {
+	CompileRole(roleID uint) error
 }
 
 // NewRoleService 创建角色服务
@@ -30,6 +43,29 @@ func NewRoleService(roleRepo repository.RoleRepository, deptRepo repository.Depa
 	}
 }
 
+// SetAPIGroupCompiler 设置API组策略编译，未设置时替换角色权限会丢失组内策略
+func (s *RoleService) SetAPIGroupCompiler(compiler APIGroupCompiler) {
+	s.groupCompiler = compiler
+}
+
+// SetDeleter 设置级联删除，未设置时只删除角色本身
+func (s *RoleService) SetDeleter(deleter EntityDeleter) {
+	s.deleter = deleter
+}
+
+// SetSystemAPIs 设置系统API查找，未设置时新建角色没有默认权限
+func (s *RoleService) SetSystemAPIs(systemAPIs SystemAPIGrants) {
+	s.systemAPIs = systemAPIs
+}
+
+// restoreGroupPolicies 角色权限被整体替换后，恢复其API组生成的策略
+func (s *RoleService) restoreGroupPolicies(roleID uint) error {
+	if s.groupCompiler == nil {
+		return nil
+	}
+	return s.groupCompiler.CompileRole(roleID)
+}
+
 // CreateRoleRequest 创建角色请求
 type CreateRoleRequest struct {
 	Name           string `json:"name" binding:"required"`
@@ -123,7 +159,7 @@ func (s *RoleService) CreateRole(req *CreateRoleRequest) (*entity.Role, error) {
 	}
 
 	// 为新创建的角色添加默认权限（不使用SavePolicy避免清空策略表）
-	err = s.addDefaultPermissionsToRoleWithoutSave(role.Code, role.DeptID)
+	err = s.ApplySystemGrant(role.ID, SystemGrantRoleDefault, role.DeptID)
 	if err != nil {
 		// 如果添加权限失败，记录错误但不回滚角色创建
 		// 可以考虑添加日志记录
@@ -199,6 +235,11 @@ func (s *RoleService) DeleteRole(id uint) error {
 		return errors.New("角色不存在")
 	}
 
+	// 按级联和限制规则删除，同时删除用户关联和策略
+	if s.deleter != nil {
+		return s.deleter.Delete(DeleteTargetRole, id)
+	}
+
 	// 删除角色
 	return s.roleRepo.Delete(id)
 }
@@ -295,19 +336,8 @@ func (s *RoleService) UpdateRolePermissions(req *UpdateRolePermissionsRequest) e
 		return errors.New("角色不存在")
 	}
 
-	// 选择性清除角色权限，保留指定的API权限
-	s.deleteRolePermissionsExcept(role.Code, []string{
-		"/api/v1/apis*",
-		"/api/v1/apis/*",
-		"/api/v1/business/*",
-		"/api/v1/business*",
-		"/api/v1/departments*",
-		"/api/v1/departments/*",
-		"/api/v1/role",
-		"/api/v1/role/*",
-		"/api/v1/user",
-		"/api/v1/user/*",
-	})
+	// 清除角色原有的权限，默认权限已按系统API登记，需随请求一起提交
+	s.deleteRolePermissions(role.Code)
 
 	// 添加新权限
 	for _, perm := range req.Permissions {
@@ -316,44 +346,32 @@ func (s *RoleService) UpdateRolePermissions(req *UpdateRolePermissionsRequest) e
 			return err
 		}
 	}
+	if err := s.restoreGroupPolicies(role.ID); err != nil {
+		return err
+	}
 
 	// 同步策略到内存（安全方式）
 	return s.casbinEnforcer.SyncPolicyToMemory()
 }
 
-// addDefaultPermissionsToRoleWithoutSave 为角色添加默认权限（不保存策略，使用安全同步）
-func (s *RoleService) addDefaultPermissionsToRoleWithoutSave(roleCode string, deptID uint) error {
-	// 定义默认权限列表
-	defaultPermissions := []struct {
-		path   string
-		method string
-	}{
-		{"/api/v1/apis*", "*"},
-		{"/api/v1/apis/*", "*"},
-		{"/api/v1/business/*", "*"},
-		{"/api/v1/business*", "*"},
-		{"/api/v1/departments*", "GET"},
-		{"/api/v1/departments/*", "GET"},
-		{"/api/v1/role", "*"},
-		{"/api/v1/role/*", "*"},
-		{"/api/v1/user", "*"},
-		{"/api/v1/user/*", "*"},
-	}
-
-	// 将部门ID转换为字符串
-	deptIDStr := fmt.Sprintf("%d", deptID)
-
-	// 为角色添加每个默认权限
-	for _, perm := range defaultPermissions {
-		// 使用选择的部门ID而不是"*"
-		_, err := s.casbinEnforcer.AddPolicyWithDeptAndSync(roleCode, perm.path, perm.method, deptIDStr, "allow")
-		if err != nil {
-			return fmt.Errorf("failed to add permission %s %s: %v", perm.path, perm.method, err)
-		}
+// ApplySystemGrant 将授予集合中已登记的系统API按API ID授予角色，权限限定在deptID部门，替换角色原有的权限
+func (s *RoleService) ApplySystemGrant(roleID uint, grant string, deptID uint) error {
+	if s.systemAPIs == nil {
+		return errors.New("未配置系统API登记")
+	}
+	apiIDs, err := s.systemAPIs.GrantAPIIDs(grant)
+	if err != nil {
+		return err
+	}
+	if len(apiIDs) == 0 {
+		return errors.New("没有已登记的系统API，请启用system_api.enabled")
 	}
 
-	// 同步策略到内存（安全方式）
-	return s.casbinEnforcer.SyncPolicyToMemory()
+	return s.UpdateRolePermissionsByAPIIDs(&UpdateRolePermissionsByAPIIDsRequest{
+		RoleID: roleID,
+		APIIDs: apiIDs,
+		DeptID: deptID,
+	})
 }
 
 // GetUserAccessibleRoles 获取用户可访问的角色
@@ -388,35 +406,22 @@ func (s *RoleService) GetUserAccessibleRoles(userID uint) ([]*entity.Role, error
 	return s.roleRepo.ListByDept(user.DeptID)
 }
 
-// deleteRolePermissionsExcept 删除角色权限，但保留指定的路径
-func (s *RoleService) deleteRolePermissionsExcept(roleCode string, preservePaths []string) {
+// deleteRolePermissions 删除角色的所有权限
+func (s *RoleService) deleteRolePermissions(roleCode string) {
 	// 获取角色的所有权限
 	policies := s.casbinEnforcer.GetFilteredPolicy(0, roleCode)
 
-	// 删除不在保留列表中的权限
 	for _, policy := range policies {
 		if len(policy) >= 3 {
 			path := policy[1]
 			method := policy[2]
 
-			// 检查是否在保留列表中
-			shouldPreserve := false
-			for _, preservePath := range preservePaths {
-				if path == preservePath {
-					shouldPreserve = true
-					break
-				}
-			}
-
-			// 如果不在保留列表中，则删除
-			if !shouldPreserve {
-				if len(policy) >= 5 {
-					// 包含部门信息的权限
-					s.casbinEnforcer.RemovePolicyWithDept(roleCode, path, method, policy[3], policy[4])
-				} else if len(policy) >= 4 {
-					// 不包含部门信息的权限
-					s.casbinEnforcer.RemovePolicy(roleCode, path, method)
-				}
+			if len(policy) >= 5 {
+				// 包含部门信息的权限
+				s.casbinEnforcer.RemovePolicyWithDept(roleCode, path, method, policy[3], policy[4])
+			} else if len(policy) >= 4 {
+				// 不包含部门信息的权限
+				s.casbinEnforcer.RemovePolicy(roleCode, path, method)
 			}
 		}
 	}
@@ -465,19 +470,8 @@ func (s *RoleService) UpdateRolePermissionsByAPIIDs(req *UpdateRolePermissionsBy
 		return errors.New("角色不存在")
 	}
 
-	// 选择性清除角色权限，保留指定的API权限
-	s.deleteRolePermissionsExcept(role.Code, []string{
-		"/api/v1/apis*",
-		"/api/v1/apis/*",
-		"/api/v1/business/*",
-		"/api/v1/business*",
-		"/api/v1/departments*",
-		"/api/v1/departments/*",
-		"/api/v1/role",
-		"/api/v1/role/*",
-		"/api/v1/user",
-		"/api/v1/user/*",
-	})
+	// 清除角色原有的权限，默认权限已按系统API登记，需随请求一起提交
+	s.deleteRolePermissions(role.Code)
 
 	// 确定部门参数
 	deptParam := "*" // 默认为全部门
@@ -498,6 +492,29 @@ func (s *RoleService) UpdateRolePermissionsByAPIIDs(req *UpdateRolePermissionsBy
 			return err
 		}
 	}
+	if err := s.restoreGroupPolicies(role.ID); err != nil {
+		return err
+	}
+
+	// 同步策略到内存（安全方式）
+	return s.casbinEnforcer.SyncPolicyToMemory()
+}
+
+// GrantAPIs 为角色追加API权限，不影响角色已有的权限
+func (s *RoleService) GrantAPIs(roleID uint, apis []*entity.API) error {
+	role, err := s.roleRepo.GetByID(roleID)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return errors.New("角色不存在")
+	}
+
+	for _, api := range apis {
+		if _, err := s.casbinEnforcer.AddPolicyWithDept(role.Code, api.Path, api.Method, "*", "allow"); err != nil {
+			return err
+		}
+	}
 
 	// 同步策略到内存（安全方式）
 	return s.casbinEnforcer.SyncPolicyToMemory()
@@ -605,19 +622,8 @@ func (s *RoleService) AssignRolePermissions(req *AssignRolePermissionsRequest) e
 		return errors.New("角色不属于指定部门")
 	}
 
-	// 选择性清除角色权限，保留指定的API权限
-	s.deleteRolePermissionsExcept(role.Code, []string{
-		"/api/v1/apis*",
-		"/api/v1/apis/*",
-		"/api/v1/business/*",
-		"/api/v1/business*",
-		"/api/v1/departments*",
-		"/api/v1/departments/*",
-		"/api/v1/role",
-		"/api/v1/role/*",
-		"/api/v1/user",
-		"/api/v1/user/*",
-	})
+	// 清除角色原有的权限，默认权限已按系统API登记，需随请求一起提交
+	s.deleteRolePermissions(role.Code)
 
 	// 添加新权限（带部门信息）
 	for _, perm := range req.Permissions {
@@ -632,6 +638,10 @@ func (s *RoleService) AssignRolePermissions(req *AssignRolePermissionsRequest) e
 		}
 	}
 
+	if err := s.restoreGroupPolicies(role.ID); err != nil {
+		return err
+	}
+
 	// 不保存策略到存储，避免清空策略表
 	return nil
 }
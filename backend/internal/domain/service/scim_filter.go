@@ -0,0 +1,270 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// scimFilter SCIM过滤表达式（RFC 7644 3.4.2.2）的子集：
+// 支持 eq ne co sw ew gt ge lt le pr 比较以及 and、or、not 和括号
+type scimFilter interface {
+	match(attrs scimAttrGetter) bool
+}
+
+// scimAttrGetter 按属性路径（小写）获取资源的属性值
+type scimAttrGetter func(path string) []string
+
+type scimAndFilter struct{ left, right scimFilter }
+
+type scimOrFilter struct{ left, right scimFilter }
+
+type scimNotFilter struct{ inner scimFilter }
+
+type scimCompareFilter struct {
+	path  string
+	op    string
+	value string
+}
+
+func (f *scimAndFilter) match(attrs scimAttrGetter) bool {
+	return f.left.match(attrs) && f.right.match(attrs)
+}
+
+func (f *scimOrFilter) match(attrs scimAttrGetter) bool {
+	return f.left.match(attrs) || f.right.match(attrs)
+}
+
+func (f *scimNotFilter) match(attrs scimAttrGetter) bool {
+	return !f.inner.match(attrs)
+}
+
+func (f *scimCompareFilter) match(attrs scimAttrGetter) bool {
+	values := attrs(f.path)
+	if f.op == "pr" {
+		for _, v := range values {
+			if v != "" {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, v := range values {
+		if compareSCIMValue(v, f.op, f.value) {
+			return true
+		}
+	}
+	// ne对缺失的属性也成立
+	return f.op == "ne" && len(values) == 0
+}
+
+// compareSCIMValue 比较属性值，字符串按不区分大小写处理
+func compareSCIMValue(actual, op, expected string) bool {
+	a, e := strings.ToLower(actual), strings.ToLower(expected)
+	switch op {
+	case "eq":
+		return a == e
+	case "ne":
+		return a != e
+	case "co":
+		return strings.Contains(a, e)
+	case "sw":
+		return strings.HasPrefix(a, e)
+	case "ew":
+		return strings.HasSuffix(a, e)
+	}
+
+	// 有序比较优先按数字处理
+	af, errA := strconv.ParseFloat(actual, 64)
+	ef, errE := strconv.ParseFloat(expected, 64)
+	if errA == nil && errE == nil {
+		switch op {
+		case "gt":
+			return af > ef
+		case "ge":
+			return af >= ef
+		case "lt":
+			return af < ef
+		case "le":
+			return af <= ef
+		}
+		return false
+	}
+	switch op {
+	case "gt":
+		return a > e
+	case "ge":
+		return a >= e
+	case "lt":
+		return a < e
+	case "le":
+		return a <= e
+	}
+	return false
+}
+
+// parseSCIMFilter 解析过滤表达式，空表达式返回nil
+func parseSCIMFilter(expr string) (scimFilter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+
+	tokens, err := tokenizeSCIMFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &scimFilterParser{tokens: tokens}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("无法解析的过滤条件: %s", p.tokens[p.pos])
+	}
+	return filter, nil
+}
+
+// scimFilterParser 递归下降解析器，and优先级高于or
+type scimFilterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *scimFilterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *scimFilterParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *scimFilterParser) parseOr() (scimFilter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &scimOrFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *scimFilterParser) parseAnd() (scimFilter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &scimAndFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *scimFilterParser) parseUnary() (scimFilter, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("过滤条件不完整")
+	case strings.EqualFold(token, "not"):
+		if p.next() != "(" {
+			return nil, fmt.Errorf("not后必须是括号表达式")
+		}
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("缺少右括号")
+		}
+		return &scimNotFilter{inner: inner}, nil
+	case token == "(":
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("缺少右括号")
+		}
+		return inner, nil
+	}
+
+	path := strings.ToLower(token)
+	op := strings.ToLower(p.next())
+	switch op {
+	case "pr":
+		return &scimCompareFilter{path: path, op: op}, nil
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+		value := p.next()
+		if value == "" {
+			return nil, fmt.Errorf("%s %s 缺少比较值", token, op)
+		}
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("无效的字符串: %s", value)
+			}
+			value = unquoted
+		}
+		return &scimCompareFilter{path: path, op: op, value: value}, nil
+	default:
+		return nil, fmt.Errorf("不支持的比较运算符: %s", op)
+	}
+}
+
+// tokenizeSCIMFilter 将过滤表达式切分为词法单元
+func tokenizeSCIMFilter(expr string) ([]string, error) {
+	var tokens []string
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, string(r))
+			i++
+		case r == '"':
+			j := i + 1
+			for ; j < len(runes); j++ {
+				if runes[j] == '\\' {
+					j++
+					continue
+				}
+				if runes[j] == '"' {
+					break
+				}
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("字符串缺少结束引号")
+			}
+			tokens = append(tokens, string(runes[i:j+1]))
+			i = j + 1
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && runes[j] != '(' && runes[j] != ')' {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		}
+	}
+	return tokens, nil
+}
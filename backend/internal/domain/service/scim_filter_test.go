@@ -0,0 +1,100 @@
+package service
+
+import "testing"
+
+// testSCIMAttrs 按属性路径返回固定的属性值
+func testSCIMAttrs(values map[string][]string) scimAttrGetter {
+	return func(path string) []string {
+		return values[path]
+	}
+}
+
+func TestSCIMFilterMatch(t *testing.T) {
+	attrs := testSCIMAttrs(map[string][]string{
+		"username":          {"Alice"},
+		"displayname":       {"Alice Liu"},
+		"emails.value":      {"alice@corp.example", "alice@home.example"},
+		"active":            {"true"},
+		"meta.lastmodified": {"2024-05-01T08:00:00Z"},
+		"age":               {"30"},
+		"externalid":        {""},
+	})
+
+	tests := []struct {
+		name   string
+		filter string
+		want   bool
+	}{
+		{"eq不区分大小写", `userName eq "alice"`, true},
+		{"属性名不区分大小写", `USERNAME EQ "Alice"`, true},
+		{"eq不匹配", `userName eq "bob"`, false},
+		{"ne", `userName ne "bob"`, true},
+		{"ne对缺失的属性成立", `title ne "manager"`, true},
+		{"eq对缺失的属性不成立", `title eq "manager"`, false},
+		{"co", `displayName co "ce l"`, true},
+		{"sw", `displayName sw "ALI"`, true},
+		{"ew", `displayName ew "liu"`, true},
+		{"多值属性任一匹配", `emails.value ew "@home.example"`, true},
+		{"pr", `userName pr`, true},
+		{"pr对空值不成立", `externalId pr`, false},
+		{"pr对缺失的属性不成立", `title pr`, false},
+		{"数字按数值比较", `age gt 4`, true},
+		{"数字le", `age le 30`, true},
+		{"时间按字符串比较", `meta.lastModified ge "2024-01-01T00:00:00Z"`, true},
+		{"时间lt", `meta.lastModified lt "2024-01-01T00:00:00Z"`, false},
+		{"未加引号的布尔值", `active eq true`, true},
+		{"and", `userName eq "alice" and active eq false`, false},
+		{"or", `userName eq "bob" or active eq true`, true},
+		{"and优先于or", `userName eq "bob" and active eq true or displayName sw "alice"`, true},
+		{"括号改变优先级", `userName eq "bob" and (active eq true or displayName sw "alice")`, false},
+		{"not", `not (userName eq "bob")`, true},
+		{"not嵌套表达式", `not (userName eq "alice" or userName eq "bob")`, false},
+		{"字符串包含转义的引号", `displayName eq "Alice \"Liu\""`, false},
+		{"字符串包含括号和空格", `displayName eq "Alice (Liu)"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := parseSCIMFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("parseSCIMFilter(%q) error = %v", tt.filter, err)
+			}
+			if got := filter.match(attrs); got != tt.want {
+				t.Errorf("parseSCIMFilter(%q).match() = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSCIMFilterErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+	}{
+		{"字符串缺少结束引号", `userName eq "alice`},
+		{"缺少比较值", `userName eq`},
+		{"不支持的运算符", `userName like "alice"`},
+		{"缺少运算符", `userName`},
+		{"缺少右括号", `(userName eq "alice"`},
+		{"多余的右括号", `userName eq "alice")`},
+		{"not后不是括号", `not userName eq "alice"`},
+		{"and后不完整", `userName eq "alice" and`},
+		{"无效的转义", `userName eq "\q"`},
+		{"两个条件之间缺少逻辑运算符", `userName eq "alice" active eq true`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if filter, err := parseSCIMFilter(tt.filter); err == nil {
+				t.Errorf("parseSCIMFilter(%q) = %#v, want error", tt.filter, filter)
+			}
+		})
+	}
+}
+
+func TestParseSCIMFilterEmpty(t *testing.T) {
+	for _, expr := range []string{"", "   "} {
+		filter, err := parseSCIMFilter(expr)
+		if err != nil || filter != nil {
+			t.Errorf("parseSCIMFilter(%q) = (%v, %v), want (nil, nil)", expr, filter, err)
+		}
+	}
+}
@@ -0,0 +1,1180 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// SCIM 2.0 schema定义（RFC 7643/7644）
+const (
+	SCIMSchemaUser           = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMSchemaEnterpriseUser = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	SCIMSchemaGroup          = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIMSchemaListResponse   = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMSchemaPatchOp        = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMSchemaError          = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// scimRoleCodePrefix 通过SCIM创建的角色编码前缀，只有这些角色允许通过SCIM删除或改名
+const scimRoleCodePrefix = "scim_"
+
+// scimMaxCount 单页最大返回数量
+const scimMaxCount = 200
+
+// scimMemberFilter 匹配 members[value eq "123"] 形式的路径
+var scimMemberFilter = regexp.MustCompile(`(?i)^members\[value\s+eq\s+"([^"]*)"\]$`)
+
+// scimRoleCodeSanitizer 用于从组名生成角色编码
+var scimRoleCodeSanitizer = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// SCIMError SCIM标准错误响应
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// Error 实现error接口
+func (e *SCIMError) Error() string {
+	return e.Detail
+}
+
+// HTTPStatus 返回HTTP状态码
+func (e *SCIMError) HTTPStatus() int {
+	status, _ := strconv.Atoi(e.Status)
+	return status
+}
+
+// NewSCIMError 创建SCIM错误
+func NewSCIMError(status int, scimType, detail string) *SCIMError {
+	return &SCIMError{
+		Schemas:  []string{SCIMSchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+// SCIMMeta 资源元数据
+type SCIMMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Location     string `json:"location,omitempty"`
+}
+
+// SCIMName 用户姓名
+type SCIMName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// SCIMMultiValue 多值属性
+type SCIMMultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// SCIMEnterpriseUser 企业用户扩展，department映射为部门编码或名称
+type SCIMEnterpriseUser struct {
+	Department string `json:"department,omitempty"`
+}
+
+// SCIMUser SCIM用户资源
+type SCIMUser struct {
+	Schemas     []string            `json:"schemas"`
+	ID          string              `json:"id,omitempty"`
+	ExternalID  string              `json:"externalId,omitempty"`
+	UserName    string              `json:"userName"`
+	Name        *SCIMName           `json:"name,omitempty"`
+	DisplayName string              `json:"displayName,omitempty"`
+	Emails      []SCIMMultiValue    `json:"emails,omitempty"`
+	Active      *bool               `json:"active,omitempty"`
+	Password    string              `json:"password,omitempty"` // 只写属性，不会返回
+	Groups      []SCIMMultiValue    `json:"groups,omitempty"`
+	Enterprise  *SCIMEnterpriseUser `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	Meta        *SCIMMeta           `json:"meta,omitempty"`
+}
+
+// SCIMGroup SCIM组资源，对应系统角色
+type SCIMGroup struct {
+	Schemas     []string         `json:"schemas"`
+	ID          string           `json:"id,omitempty"`
+	DisplayName string           `json:"displayName"`
+	Members     []SCIMMultiValue `json:"members,omitempty"`
+	Meta        *SCIMMeta        `json:"meta,omitempty"`
+}
+
+// SCIMListResponse 列表响应
+type SCIMListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// SCIMPatchOperation PATCH操作
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// SCIMPatchRequest PATCH请求
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+// SCIMListRequest 列表查询参数
+type SCIMListRequest struct {
+	Filter     string `form:"filter"`
+	StartIndex int    `form:"startIndex"`
+	Count      int    `form:"count"`
+}
+
+// SCIMService SCIM 2.0 用户和组同步服务
+type SCIMService struct {
+	userService     *UserService
+	roleService     *RoleService
+	userRepo        repository.UserRepository
+	deptRepo        repository.DepartmentRepository
+	roleRepo        repository.RoleRepository
+	sessions        SessionRevoker
+	passwords       PasswordSetter
+	defaultDeptCode string
+}
+
+// PasswordSetter 为已有用户设置密码，负责密码策略、历史密码、Token版本号和终止会话
+type PasswordSetter interface {
+	SetPassword(userID uint, password string) error
+}
+
+// NewSCIMService 创建SCIM服务
+func NewSCIMService(userService *UserService, roleService *RoleService, userRepo repository.UserRepository, deptRepo repository.DepartmentRepository, roleRepo repository.RoleRepository, defaultDeptCode string) *SCIMService {
+	return &SCIMService{
+		userService:     userService,
+		roleService:     roleService,
+		userRepo:        userRepo,
+		deptRepo:        deptRepo,
+		roleRepo:        roleRepo,
+		defaultDeptCode: defaultDeptCode,
+	}
+}
+
+// SetSessionRevoker 设置会话终止，用户被停用、删除或修改密码后终止其会话
+func (s *SCIMService) SetSessionRevoker(sessions SessionRevoker) {
+	s.sessions = sessions
+}
+
+// SetPasswordSetter 设置修改已有用户密码时使用的密码服务
+func (s *SCIMService) SetPasswordSetter(passwords PasswordSetter) {
+	s.passwords = passwords
+}
+
+// ListUsers 查询用户
+func (s *SCIMService) ListUsers(req *SCIMListRequest) (*SCIMListResponse, error) {
+	filter, err := parseSCIMFilter(req.Filter)
+	if err != nil {
+		return nil, NewSCIMError(http.StatusBadRequest, "invalidFilter", err.Error())
+	}
+
+	users, err := s.userRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	lookup, err := s.newLookup()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	var resources []interface{}
+	for _, user := range users {
+		resource, err := s.toSCIMUser(user, lookup)
+		if err != nil {
+			return nil, err
+		}
+		if filter != nil && !filter.match(scimUserAttrs(resource)) {
+			continue
+		}
+		resources = append(resources, resource)
+	}
+
+	return paginateSCIM(resources, req), nil
+}
+
+// GetUser 获取用户
+func (s *SCIMService) GetUser(id string) (*SCIMUser, error) {
+	user, err := s.findUser(id)
+	if err != nil {
+		return nil, err
+	}
+	lookup, err := s.newLookup()
+	if err != nil {
+		return nil, err
+	}
+	return s.toSCIMUser(user, lookup)
+}
+
+// CreateUser 创建用户
+func (s *SCIMService) CreateUser(resource *SCIMUser) (*SCIMUser, error) {
+	if resource.UserName == "" {
+		return nil, NewSCIMError(http.StatusBadRequest, "invalidValue", "userName不能为空")
+	}
+	if existing, err := s.userRepo.GetByUsername(resource.UserName); err != nil {
+		return nil, err
+	} else if existing != nil {
+		return nil, NewSCIMError(http.StatusConflict, "uniqueness", "userName已存在")
+	}
+
+	email := scimPrimaryEmail(resource, resource.UserName)
+	if existing, err := s.userRepo.GetByEmail(email); err != nil {
+		return nil, err
+	} else if existing != nil {
+		return nil, NewSCIMError(http.StatusConflict, "uniqueness", "邮箱已存在")
+	}
+
+	dept, err := s.resolveDept(resource, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	password := resource.Password
+	generated := password == ""
+	if generated {
+		if password, err = randomToken(32); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := s.userService.CreateUser(&CreateUserRequest{
+		Username: resource.UserName,
+		Name:     scimDisplayName(resource),
+		Email:    email,
+		Password: password,
+		DeptID:   dept.ID,
+
+		SkipPasswordPolicy: generated,
+	})
+	if err != nil {
+		return nil, NewSCIMError(http.StatusBadRequest, "invalidValue", err.Error())
+	}
+
+	user.Source = entity.SourceSCIM
+	user.ExternalID = resource.ExternalID
+	if resource.Active != nil && !*resource.Active {
+		user.Status = 0
+	}
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return s.GetUser(strconv.FormatUint(uint64(user.ID), 10))
+}
+
+// ReplaceUser 全量更新用户（PUT）
+func (s *SCIMService) ReplaceUser(id string, resource *SCIMUser) (*SCIMUser, error) {
+	user, err := s.findUser(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.saveUser(user, resource)
+}
+
+// PatchUser 部分更新用户（PATCH）
+func (s *SCIMService) PatchUser(id string, req *SCIMPatchRequest) (*SCIMUser, error) {
+	user, err := s.findUser(id)
+	if err != nil {
+		return nil, err
+	}
+	lookup, err := s.newLookup()
+	if err != nil {
+		return nil, err
+	}
+	resource, err := s.toSCIMUser(user, lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range req.Operations {
+		if err := applySCIMUserPatch(resource, strings.ToLower(op.Op), op.Path, op.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.saveUser(user, resource)
+}
+
+// DeleteUser 删除用户并终止其会话
+func (s *SCIMService) DeleteUser(id string) error {
+	user, err := s.findUser(id)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.AssignRoles(user.ID, nil); err != nil {
+		return err
+	}
+	if err := s.userService.DeleteUser(user.ID); err != nil {
+		return err
+	}
+	return s.revokeSessions(user.ID)
+}
+
+// ListGroups 查询组
+func (s *SCIMService) ListGroups(req *SCIMListRequest) (*SCIMListResponse, error) {
+	filter, err := parseSCIMFilter(req.Filter)
+	if err != nil {
+		return nil, NewSCIMError(http.StatusBadRequest, "invalidFilter", err.Error())
+	}
+
+	roles, err := s.roleRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].ID < roles[j].ID })
+
+	var resources []interface{}
+	for _, role := range roles {
+		resource, err := s.toSCIMGroup(role)
+		if err != nil {
+			return nil, err
+		}
+		if filter != nil && !filter.match(scimGroupAttrs(resource)) {
+			continue
+		}
+		resources = append(resources, resource)
+	}
+
+	return paginateSCIM(resources, req), nil
+}
+
+// GetGroup 获取组
+func (s *SCIMService) GetGroup(id string) (*SCIMGroup, error) {
+	role, err := s.findRole(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toSCIMGroup(role)
+}
+
+// CreateGroup 创建组，在默认部门下创建一个没有任何权限的角色
+func (s *SCIMService) CreateGroup(resource *SCIMGroup) (*SCIMGroup, error) {
+	if resource.DisplayName == "" {
+		return nil, NewSCIMError(http.StatusBadRequest, "invalidValue", "displayName不能为空")
+	}
+	if existing, err := s.roleRepo.GetByName(resource.DisplayName); err != nil {
+		return nil, err
+	} else if existing != nil {
+		return nil, NewSCIMError(http.StatusConflict, "uniqueness", "displayName已存在")
+	}
+
+	dept, err := s.resolveDept(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	code, err := s.newRoleCode(resource.DisplayName)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &entity.Role{
+		Name:        resource.DisplayName,
+		Code:        code,
+		Description: "由SCIM同步创建",
+		DeptID:      dept.ID,
+		Status:      1,
+	}
+	if err := s.roleRepo.Create(role); err != nil {
+		return nil, err
+	}
+
+	if err := s.setMembers(role, scimMemberIDs(resource.Members)); err != nil {
+		return nil, err
+	}
+
+	return s.toSCIMGroup(role)
+}
+
+// ReplaceGroup 全量更新组（PUT）
+func (s *SCIMService) ReplaceGroup(id string, resource *SCIMGroup) (*SCIMGroup, error) {
+	role, err := s.findRole(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.renameRole(role, resource.DisplayName); err != nil {
+		return nil, err
+	}
+	if err := s.setMembers(role, scimMemberIDs(resource.Members)); err != nil {
+		return nil, err
+	}
+	return s.toSCIMGroup(role)
+}
+
+// PatchGroup 部分更新组（PATCH），主要用于增减成员
+func (s *SCIMService) PatchGroup(id string, req *SCIMPatchRequest) (*SCIMGroup, error) {
+	role, err := s.findRole(id)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.toSCIMGroup(role)
+	if err != nil {
+		return nil, err
+	}
+
+	displayName := current.DisplayName
+	members := make(map[uint]bool)
+	for _, memberID := range scimMemberIDs(current.Members) {
+		members[memberID] = true
+	}
+
+	for _, op := range req.Operations {
+		if err := applySCIMGroupPatch(strings.ToLower(op.Op), op.Path, op.Value, &displayName, members); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.renameRole(role, displayName); err != nil {
+		return nil, err
+	}
+	memberIDs := make([]uint, 0, len(members))
+	for memberID := range members {
+		memberIDs = append(memberIDs, memberID)
+	}
+	if err := s.setMembers(role, memberIDs); err != nil {
+		return nil, err
+	}
+	return s.toSCIMGroup(role)
+}
+
+// DeleteGroup 删除组，只允许删除通过SCIM创建的角色
+func (s *SCIMService) DeleteGroup(id string) error {
+	role, err := s.findRole(id)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(role.Code, scimRoleCodePrefix) {
+		return NewSCIMError(http.StatusForbidden, "mutability", "只能删除通过SCIM创建的组")
+	}
+
+	if err := s.setMembers(role, nil); err != nil {
+		return err
+	}
+	return s.roleService.DeleteRole(role.ID)
+}
+
+// saveUser 将SCIM用户资源写回本地用户
+func (s *SCIMService) saveUser(user *entity.User, resource *SCIMUser) (*SCIMUser, error) {
+	if resource.UserName == "" {
+		return nil, NewSCIMError(http.StatusBadRequest, "invalidValue", "userName不能为空")
+	}
+	if existing, err := s.userRepo.GetByUsername(resource.UserName); err != nil {
+		return nil, err
+	} else if existing != nil && existing.ID != user.ID {
+		return nil, NewSCIMError(http.StatusConflict, "uniqueness", "userName已存在")
+	}
+
+	email := scimPrimaryEmail(resource, resource.UserName)
+	if existing, err := s.userRepo.GetByEmail(email); err != nil {
+		return nil, err
+	} else if existing != nil && existing.ID != user.ID {
+		return nil, NewSCIMError(http.StatusConflict, "uniqueness", "邮箱已存在")
+	}
+
+	dept, err := s.resolveDept(resource, user)
+	if err != nil {
+		return nil, err
+	}
+
+	status := user.Status
+	if resource.Active != nil {
+		status = 0
+		if *resource.Active {
+			status = 1
+		}
+	}
+
+	// 先设置密码，密码不符合策略时不修改其他属性；UpdateUser会重新读取用户，保留新密码和Token版本号
+	if resource.Password != "" {
+		if s.passwords == nil {
+			return nil, errors.New("未配置密码服务，无法修改密码")
+		}
+		if err := s.passwords.SetPassword(user.ID, resource.Password); err != nil {
+			return nil, NewSCIMError(http.StatusBadRequest, "invalidValue", err.Error())
+		}
+	}
+
+	wasActive := user.Status == 1
+	updated, err := s.userService.UpdateUser(&UpdateUserRequest{
+		ID:       user.ID,
+		Username: resource.UserName,
+		Name:     scimDisplayName(resource),
+		Email:    email,
+		Avatar:   user.Avatar,
+		DeptID:   dept.ID,
+		Status:   status,
+	})
+	if err != nil {
+		return nil, NewSCIMError(http.StatusBadRequest, "invalidValue", err.Error())
+	}
+
+	updated.ExternalID = resource.ExternalID
+	if updated.Source == "" || updated.Source == entity.SourceLocal {
+		updated.Source = entity.SourceSCIM
+	}
+	if err := s.userRepo.Update(updated); err != nil {
+		return nil, err
+	}
+	// 停用时终止已有的会话，已签发的令牌立即失效；修改密码时已由密码服务终止
+	if wasActive && status != 1 {
+		if err := s.revokeSessions(updated.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetUser(strconv.FormatUint(uint64(updated.ID), 10))
+}
+
+// revokeSessions 终止用户的所有会话
+func (s *SCIMService) revokeSessions(userID uint) error {
+	if s.sessions == nil {
+		return nil
+	}
+	if _, err := s.sessions.RevokeAll(userID, ""); err != nil {
+		return fmt.Errorf("终止用户会话失败: %v", err)
+	}
+	return nil
+}
+
+// resolveDept 根据企业扩展中的department确定部门，未指定时保持原部门或使用默认部门
+func (s *SCIMService) resolveDept(resource *SCIMUser, user *entity.User) (*entity.Department, error) {
+	var department string
+	if resource != nil && resource.Enterprise != nil {
+		department = resource.Enterprise.Department
+	}
+
+	if department == "" {
+		if user != nil {
+			if dept, err := s.deptRepo.GetByID(user.DeptID); err != nil {
+				return nil, err
+			} else if dept != nil {
+				return dept, nil
+			}
+		}
+		if s.defaultDeptCode == "" {
+			return nil, NewSCIMError(http.StatusBadRequest, "invalidValue", "未指定部门且未配置默认部门")
+		}
+		department = s.defaultDeptCode
+	}
+
+	dept, err := s.deptRepo.GetByCode(department)
+	if err != nil {
+		return nil, err
+	}
+	if dept != nil {
+		return dept, nil
+	}
+
+	// 按名称匹配
+	depts, err := s.deptRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range depts {
+		if d.Name == department {
+			return d, nil
+		}
+	}
+	return nil, NewSCIMError(http.StatusBadRequest, "invalidValue", "部门不存在: "+department)
+}
+
+// renameRole 修改角色名称，只允许修改通过SCIM创建的角色
+func (s *SCIMService) renameRole(role *entity.Role, displayName string) error {
+	if displayName == "" || displayName == role.Name {
+		return nil
+	}
+	if !strings.HasPrefix(role.Code, scimRoleCodePrefix) {
+		return NewSCIMError(http.StatusBadRequest, "mutability", "只能修改通过SCIM创建的组名称")
+	}
+	if existing, err := s.roleRepo.GetByName(displayName); err != nil {
+		return err
+	} else if existing != nil && existing.ID != role.ID {
+		return NewSCIMError(http.StatusConflict, "uniqueness", "displayName已存在")
+	}
+
+	role.Name = displayName
+	return s.roleRepo.Update(role)
+}
+
+// setMembers 将角色成员调整为指定用户集合
+func (s *SCIMService) setMembers(role *entity.Role, userIDs []uint) error {
+	desired := make(map[uint]bool, len(userIDs))
+	for _, userID := range userIDs {
+		user, err := s.userRepo.GetByID(userID)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return NewSCIMError(http.StatusBadRequest, "invalidValue", fmt.Sprintf("成员不存在: %d", userID))
+		}
+		desired[userID] = true
+	}
+
+	current, err := s.userRepo.GetUsersByRole(role.ID)
+	if err != nil {
+		return err
+	}
+	existing := make(map[uint]bool, len(current))
+	for _, user := range current {
+		existing[user.ID] = true
+		if !desired[user.ID] {
+			if err := s.toggleRole(user.ID, role, false); err != nil {
+				return err
+			}
+		}
+	}
+	for userID := range desired {
+		if !existing[userID] {
+			if err := s.toggleRole(userID, role, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// toggleRole 为用户添加或移除单个角色，保留其他角色
+func (s *SCIMService) toggleRole(userID uint, role *entity.Role, member bool) error {
+	codes, err := s.userRepo.GetUserRoles(userID)
+	if err != nil {
+		return err
+	}
+
+	roleIDs := make([]uint, 0, len(codes)+1)
+	for _, code := range codes {
+		if code == role.Code {
+			continue
+		}
+		r, err := s.roleRepo.GetByCode(code)
+		if err != nil {
+			return err
+		}
+		if r != nil {
+			roleIDs = append(roleIDs, r.ID)
+		}
+	}
+	if member {
+		roleIDs = append(roleIDs, role.ID)
+	}
+
+	return s.userService.AssignRoles(&AssignRolesRequest{UserID: userID, RoleIDs: roleIDs})
+}
+
+// newRoleCode 根据组名生成唯一的角色编码
+func (s *SCIMService) newRoleCode(displayName string) (string, error) {
+	slug := strings.Trim(scimRoleCodeSanitizer.ReplaceAllString(strings.ToLower(displayName), "_"), "_")
+	if len(slug) > 30 {
+		slug = slug[:30]
+	}
+	code := scimRoleCodePrefix + slug
+	for i := 0; i < 5; i++ {
+		if slug != "" {
+			existing, err := s.roleRepo.GetByCode(code)
+			if err != nil {
+				return "", err
+			}
+			if existing == nil {
+				return code, nil
+			}
+		}
+		suffix, err := randomToken(3)
+		if err != nil {
+			return "", err
+		}
+		code = strings.TrimSuffix(scimRoleCodePrefix+slug, "_") + "_" + suffix
+		slug = strings.TrimPrefix(code, scimRoleCodePrefix)
+	}
+	return "", fmt.Errorf("无法生成唯一的角色编码")
+}
+
+// findUser 根据SCIM ID查找用户
+func (s *SCIMService) findUser(id string) (*entity.User, error) {
+	userID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, NewSCIMError(http.StatusNotFound, "", "用户不存在")
+	}
+	user, err := s.userRepo.GetByID(uint(userID))
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, NewSCIMError(http.StatusNotFound, "", "用户不存在")
+	}
+	return user, nil
+}
+
+// findRole 根据SCIM ID查找角色
+func (s *SCIMService) findRole(id string) (*entity.Role, error) {
+	roleID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, NewSCIMError(http.StatusNotFound, "", "组不存在")
+	}
+	role, err := s.roleRepo.GetByID(uint(roleID))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, NewSCIMError(http.StatusNotFound, "", "组不存在")
+	}
+	return role, nil
+}
+
+// scimLookup 转换资源时使用的部门和角色索引
+type scimLookup struct {
+	deptByID   map[uint]*entity.Department
+	roleByCode map[string]*entity.Role
+}
+
+// newLookup 加载部门和角色索引
+func (s *SCIMService) newLookup() (*scimLookup, error) {
+	lookup := &scimLookup{
+		deptByID:   make(map[uint]*entity.Department),
+		roleByCode: make(map[string]*entity.Role),
+	}
+
+	depts, err := s.deptRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, dept := range depts {
+		lookup.deptByID[dept.ID] = dept
+	}
+
+	roles, err := s.roleRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		lookup.roleByCode[role.Code] = role
+	}
+	return lookup, nil
+}
+
+// toSCIMUser 转换为SCIM用户资源
+func (s *SCIMService) toSCIMUser(user *entity.User, lookup *scimLookup) (*SCIMUser, error) {
+	id := strconv.FormatUint(uint64(user.ID), 10)
+	active := user.Status == 1
+
+	resource := &SCIMUser{
+		Schemas:     []string{SCIMSchemaUser, SCIMSchemaEnterpriseUser},
+		ID:          id,
+		UserName:    user.Username,
+		Name:        &SCIMName{Formatted: user.Name},
+		DisplayName: user.Name,
+		Emails:      []SCIMMultiValue{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta: &SCIMMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt.UTC().Format(time.RFC3339),
+			LastModified: user.UpdatedAt.UTC().Format(time.RFC3339),
+			Location:     "/scim/v2/Users/" + id,
+		},
+	}
+	// LDAP用户的ExternalID为DN，不作为SCIM的externalId暴露
+	if user.Source == entity.SourceSCIM {
+		resource.ExternalID = user.ExternalID
+	}
+	if dept, ok := lookup.deptByID[user.DeptID]; ok {
+		resource.Enterprise = &SCIMEnterpriseUser{Department: dept.Code}
+	}
+
+	codes, err := s.userRepo.GetUserRoles(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		if role, ok := lookup.roleByCode[code]; ok {
+			roleID := strconv.FormatUint(uint64(role.ID), 10)
+			resource.Groups = append(resource.Groups, SCIMMultiValue{
+				Value:   roleID,
+				Display: role.Name,
+				Ref:     "/scim/v2/Groups/" + roleID,
+			})
+		}
+	}
+
+	return resource, nil
+}
+
+// toSCIMGroup 转换为SCIM组资源
+func (s *SCIMService) toSCIMGroup(role *entity.Role) (*SCIMGroup, error) {
+	id := strconv.FormatUint(uint64(role.ID), 10)
+	resource := &SCIMGroup{
+		Schemas:     []string{SCIMSchemaGroup},
+		ID:          id,
+		DisplayName: role.Name,
+		Meta: &SCIMMeta{
+			ResourceType: "Group",
+			Created:      role.CreatedAt.UTC().Format(time.RFC3339),
+			LastModified: role.UpdatedAt.UTC().Format(time.RFC3339),
+			Location:     "/scim/v2/Groups/" + id,
+		},
+	}
+
+	users, err := s.userRepo.GetUsersByRole(role.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		userID := strconv.FormatUint(uint64(user.ID), 10)
+		resource.Members = append(resource.Members, SCIMMultiValue{
+			Value:   userID,
+			Display: user.Username,
+			Ref:     "/scim/v2/Users/" + userID,
+		})
+	}
+	return resource, nil
+}
+
+// applySCIMUserPatch 将单个PATCH操作应用到用户资源，不支持的属性会被忽略
+func applySCIMUserPatch(resource *SCIMUser, op, path string, value json.RawMessage) error {
+	if op != "add" && op != "replace" && op != "remove" {
+		return NewSCIMError(http.StatusBadRequest, "invalidSyntax", "不支持的操作: "+op)
+	}
+
+	// 无path时value为属性对象
+	if path == "" {
+		if op == "remove" {
+			return NewSCIMError(http.StatusBadRequest, "noTarget", "remove操作必须指定path")
+		}
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(value, &attrs); err != nil {
+			return NewSCIMError(http.StatusBadRequest, "invalidValue", "value必须是对象")
+		}
+		for key, attrValue := range attrs {
+			if err := applySCIMUserPatch(resource, op, key, attrValue); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	lowerPath := strings.ToLower(path)
+	enterprisePrefix := strings.ToLower(SCIMSchemaEnterpriseUser)
+
+	var str string
+	if op != "remove" {
+		str = scimStringValue(value)
+	}
+
+	switch {
+	case lowerPath == "username":
+		resource.UserName = str
+	case lowerPath == "displayname":
+		resource.DisplayName = str
+	case lowerPath == "externalid":
+		resource.ExternalID = str
+	case lowerPath == "password":
+		resource.Password = str
+	case lowerPath == "active":
+		active := false
+		if op != "remove" {
+			parsed, err := scimBoolValue(value)
+			if err != nil {
+				return err
+			}
+			active = parsed
+		}
+		resource.Active = &active
+	case lowerPath == "name":
+		resource.Name = &SCIMName{}
+		if op != "remove" {
+			if err := json.Unmarshal(value, resource.Name); err != nil {
+				return NewSCIMError(http.StatusBadRequest, "invalidValue", "name格式错误")
+			}
+		}
+		resource.DisplayName = ""
+	case strings.HasPrefix(lowerPath, "name."):
+		if resource.Name == nil {
+			resource.Name = &SCIMName{}
+		}
+		switch strings.TrimPrefix(lowerPath, "name.") {
+		case "formatted":
+			resource.Name.Formatted = str
+		case "givenname":
+			resource.Name.GivenName = str
+		case "familyname":
+			resource.Name.FamilyName = str
+		}
+		resource.DisplayName = ""
+	case lowerPath == "emails":
+		var emails []SCIMMultiValue
+		if op != "remove" {
+			if err := json.Unmarshal(value, &emails); err != nil {
+				return NewSCIMError(http.StatusBadRequest, "invalidValue", "emails格式错误")
+			}
+		}
+		if op == "add" {
+			// 新增邮箱作为主邮箱
+			resource.Emails = append(emails, resource.Emails...)
+		} else {
+			resource.Emails = emails
+		}
+	case strings.HasPrefix(lowerPath, "emails"):
+		// emails[type eq "work"].value 或 emails.value
+		resource.Emails = []SCIMMultiValue{{Value: str, Type: "work", Primary: true}}
+	case lowerPath == enterprisePrefix:
+		resource.Enterprise = &SCIMEnterpriseUser{}
+		if op != "remove" {
+			if err := json.Unmarshal(value, resource.Enterprise); err != nil {
+				return NewSCIMError(http.StatusBadRequest, "invalidValue", "企业扩展格式错误")
+			}
+		}
+	case lowerPath == enterprisePrefix+":department" || lowerPath == "department":
+		resource.Enterprise = &SCIMEnterpriseUser{Department: str}
+	}
+	return nil
+}
+
+// applySCIMGroupPatch 将单个PATCH操作应用到组
+func applySCIMGroupPatch(op, path string, value json.RawMessage, displayName *string, members map[uint]bool) error {
+	if op != "add" && op != "replace" && op != "remove" {
+		return NewSCIMError(http.StatusBadRequest, "invalidSyntax", "不支持的操作: "+op)
+	}
+
+	lowerPath := strings.ToLower(path)
+	switch {
+	case lowerPath == "":
+		if op == "remove" {
+			return NewSCIMError(http.StatusBadRequest, "noTarget", "remove操作必须指定path")
+		}
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(value, &attrs); err != nil {
+			return NewSCIMError(http.StatusBadRequest, "invalidValue", "value必须是对象")
+		}
+		for key, attrValue := range attrs {
+			if err := applySCIMGroupPatch(op, key, attrValue, displayName, members); err != nil {
+				return err
+			}
+		}
+	case lowerPath == "displayname":
+		if op != "remove" {
+			*displayName = scimStringValue(value)
+		}
+	case lowerPath == "members":
+		var values []SCIMMultiValue
+		if len(value) > 0 && string(value) != "null" {
+			if err := json.Unmarshal(value, &values); err != nil {
+				return NewSCIMError(http.StatusBadRequest, "invalidValue", "members格式错误")
+			}
+		}
+		ids := scimMemberIDs(values)
+		switch op {
+		case "replace":
+			for id := range members {
+				delete(members, id)
+			}
+			fallthrough
+		case "add":
+			for _, id := range ids {
+				members[id] = true
+			}
+		case "remove":
+			if len(values) == 0 {
+				for id := range members {
+					delete(members, id)
+				}
+			}
+			for _, id := range ids {
+				delete(members, id)
+			}
+		}
+	case scimMemberFilter.MatchString(path):
+		if op != "remove" {
+			return NewSCIMError(http.StatusBadRequest, "invalidPath", "成员过滤路径只支持remove操作")
+		}
+		match := scimMemberFilter.FindStringSubmatch(path)
+		if id, err := strconv.ParseUint(match[1], 10, 32); err == nil {
+			delete(members, uint(id))
+		}
+	default:
+		return NewSCIMError(http.StatusBadRequest, "invalidPath", "不支持的路径: "+path)
+	}
+	return nil
+}
+
+// paginateSCIM 按startIndex和count分页（startIndex从1开始）
+func paginateSCIM(resources []interface{}, req *SCIMListRequest) *SCIMListResponse {
+	startIndex := req.StartIndex
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count := req.Count
+	if count <= 0 || count > scimMaxCount {
+		count = scimMaxCount
+	}
+
+	page := []interface{}{}
+	if startIndex <= len(resources) {
+		end := startIndex - 1 + count
+		if end > len(resources) {
+			end = len(resources)
+		}
+		page = resources[startIndex-1 : end]
+	}
+
+	return &SCIMListResponse{
+		Schemas:      []string{SCIMSchemaListResponse},
+		TotalResults: len(resources),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	}
+}
+
+// scimUserAttrs 用户资源的过滤属性
+func scimUserAttrs(resource *SCIMUser) scimAttrGetter {
+	return func(path string) []string {
+		switch path {
+		case "id":
+			return []string{resource.ID}
+		case "username":
+			return []string{resource.UserName}
+		case "externalid":
+			return []string{resource.ExternalID}
+		case "displayname", "name.formatted":
+			return []string{resource.DisplayName}
+		case "emails", "emails.value":
+			values := make([]string, 0, len(resource.Emails))
+			for _, email := range resource.Emails {
+				values = append(values, email.Value)
+			}
+			return values
+		case "active":
+			return []string{strconv.FormatBool(resource.Active != nil && *resource.Active)}
+		case "groups", "groups.value":
+			values := make([]string, 0, len(resource.Groups))
+			for _, group := range resource.Groups {
+				values = append(values, group.Value)
+			}
+			return values
+		case "groups.display":
+			values := make([]string, 0, len(resource.Groups))
+			for _, group := range resource.Groups {
+				values = append(values, group.Display)
+			}
+			return values
+		case "department", strings.ToLower(SCIMSchemaEnterpriseUser) + ":department":
+			if resource.Enterprise != nil {
+				return []string{resource.Enterprise.Department}
+			}
+		case "meta.lastmodified":
+			return []string{resource.Meta.LastModified}
+		case "meta.created":
+			return []string{resource.Meta.Created}
+		}
+		return nil
+	}
+}
+
+// scimGroupAttrs 组资源的过滤属性
+func scimGroupAttrs(resource *SCIMGroup) scimAttrGetter {
+	return func(path string) []string {
+		switch path {
+		case "id":
+			return []string{resource.ID}
+		case "displayname":
+			return []string{resource.DisplayName}
+		case "members", "members.value":
+			values := make([]string, 0, len(resource.Members))
+			for _, member := range resource.Members {
+				values = append(values, member.Value)
+			}
+			return values
+		case "meta.lastmodified":
+			return []string{resource.Meta.LastModified}
+		}
+		return nil
+	}
+}
+
+// scimDisplayName 计算用户姓名
+func scimDisplayName(resource *SCIMUser) string {
+	if resource.DisplayName != "" {
+		return resource.DisplayName
+	}
+	if resource.Name != nil {
+		if resource.Name.Formatted != "" {
+			return resource.Name.Formatted
+		}
+		if full := strings.TrimSpace(resource.Name.GivenName + " " + resource.Name.FamilyName); full != "" {
+			return full
+		}
+	}
+	return resource.UserName
+}
+
+// scimPrimaryEmail 获取主邮箱，没有邮箱时生成占位邮箱以满足唯一索引
+func scimPrimaryEmail(resource *SCIMUser, username string) string {
+	for _, email := range resource.Emails {
+		if email.Primary && email.Value != "" {
+			return email.Value
+		}
+	}
+	for _, email := range resource.Emails {
+		if email.Value != "" {
+			return email.Value
+		}
+	}
+	return username + "@scim.local"
+}
+
+// scimMemberIDs 解析成员ID，忽略无效值
+func scimMemberIDs(values []SCIMMultiValue) []uint {
+	ids := make([]uint, 0, len(values))
+	for _, value := range values {
+		if id, err := strconv.ParseUint(value.Value, 10, 32); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// scimStringValue 解析字符串值，兼容非字符串的JSON值
+func scimStringValue(value json.RawMessage) string {
+	var str string
+	if err := json.Unmarshal(value, &str); err == nil {
+		return str
+	}
+	return strings.Trim(string(value), `"`)
+}
+
+// scimBoolValue 解析布尔值，兼容部分IdP发送的"True"/"False"字符串
+func scimBoolValue(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	parsed, err := strconv.ParseBool(scimStringValue(value))
+	if err != nil {
+		return false, NewSCIMError(http.StatusBadRequest, "invalidValue", "active必须是布尔值")
+	}
+	return parsed, nil
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/infrastructure/cache"
+)
+
+// 会话在Redis中的键前缀
+const (
+	sessionKeyPrefix     = "session:"      // 会话详情，有效期与Token一致
+	sessionUserKeyPrefix = "session:user:" // 用户的会话索引（有序集合，分数为过期时间）
+	// sessionChildKeyPrefix 受限令牌签发的下级会话索引（有序集合，分数为过期时间），上级会话终止时一并终止
+	sessionChildKeyPrefix = "session:children:"
+	// sessionTouchInterval 最近活跃时间的更新间隔，避免每个请求都写Redis
+	sessionTouchInterval = time.Minute
+)
+
+// Session 登录会话
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     uint      `json:"user_id"`
+	Username   string    `json:"username"`
+	ClientID   string    `json:"client_id,omitempty"`  // OAuth2客户端签发的Token
+	Actor      *Actor    `json:"actor,omitempty"`      // 管理员代登录的会话
+	Restricted bool      `json:"restricted,omitempty"` // 受限令牌的会话
+	ParentID   string    `json:"parent_id,omitempty"`  // 由受限令牌签发时为签发令牌的会话
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	IssuedAt   time.Time `json:"issued_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // 是否为发起请求的会话
+}
+
+// SessionRevoker 终止用户的会话，用户修改密码或被停用后已签发的令牌立即失效
+type SessionRevoker interface {
+	RevokeAll(userID uint, exceptID string) (int, error)
+}
+
+// SessionService 会话管理服务
+type SessionService struct {
+	cache *cache.Redis
+}
+
+// NewSessionService 创建会话管理服务
+func NewSessionService(cache *cache.Redis) *SessionService {
+	return &SessionService{
+		cache: cache,
+	}
+}
+
+// Create 为即将签发的Token创建会话，返回会话ID；parentID不为空时新会话随该会话一起终止
+func (s *SessionService) Create(user *entity.User, claims *Claims, parentID string) (string, error) {
+	expiresAt := time.Unix(claims.ExpiresAt, 0)
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return "", errors.New("Token已过期")
+	}
+	if parentID != "" {
+		exists, err := s.cache.Exists(sessionKeyPrefix + parentID)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return "", errors.New("当前会话已失效")
+		}
+	}
+
+	now := time.Now()
+	session := &Session{
+		ID:         uuid.New().String(),
+		UserID:     user.ID,
+		Username:   user.Username,
+		ClientID:   claims.ClientID,
+		Actor:      claims.Act,
+		Restricted: claims.Rst != nil,
+		ParentID:   parentID,
+		IssuedAt:   now,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	}
+	if err := s.cache.Set(sessionKeyPrefix+session.ID, session, ttl); err != nil {
+		return "", err
+	}
+
+	indexKey := userSessionKey(user.ID)
+	if err := s.cache.ZAdd(indexKey, session.ID, float64(expiresAt.Unix())); err != nil {
+		return "", err
+	}
+	if err := s.cache.ExtendExpire(indexKey, ttl); err != nil {
+		return "", err
+	}
+
+	if parentID != "" {
+		childKey := sessionChildKey(parentID)
+		if err := s.cache.ZAdd(childKey, session.ID, float64(expiresAt.Unix())); err != nil {
+			return "", err
+		}
+		if err := s.cache.ExtendExpire(childKey, ttl); err != nil {
+			return "", err
+		}
+	}
+	return session.ID, nil
+}
+
+// Touch 校验会话是否有效并更新最近活跃时间、IP和设备；会话已终止或过期时返回false
+func (s *SessionService) Touch(sessionID, ip, userAgent string) (bool, error) {
+	var session Session
+	if err := s.cache.Get(sessionKeyPrefix+sessionID, &session); err != nil {
+		if cache.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	// 上级会话终止后下级会话随之失效，避免终止上级会话与签发下级会话并发时遗漏
+	if session.ParentID != "" {
+		exists, err := s.cache.Exists(sessionKeyPrefix + session.ParentID)
+		if err != nil {
+			return false, err
+		}
+		if !exists {
+			return false, nil
+		}
+	}
+
+	if time.Since(session.LastSeenAt) < sessionTouchInterval && session.IP == ip && (userAgent == "" || session.UserAgent == userAgent) {
+		return true, nil
+	}
+
+	err := s.cache.Update(sessionKeyPrefix+sessionID, &session, func() error {
+		session.LastSeenAt = time.Now()
+		if ip != "" {
+			session.IP = ip
+		}
+		if userAgent != "" {
+			session.UserAgent = truncateString(userAgent, 500)
+			session.Device = describeDevice(userAgent)
+		}
+		return nil
+	})
+	switch {
+	case err == nil, cache.IsConflict(err):
+		// 并发请求同时更新时保留先写入的结果
+		return true, nil
+	case cache.IsNotFound(err):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// List 获取用户的有效会话，按最近活跃时间倒序；currentID对应的会话标记为当前会话
+func (s *SessionService) List(userID uint, currentID string) ([]*Session, error) {
+	indexKey := userSessionKey(userID)
+	now := float64(time.Now().Unix())
+	if err := s.cache.ZRemRangeByScore(indexKey, math.Inf(-1), now); err != nil {
+		return nil, err
+	}
+	ids, err := s.cache.ZRangeByScore(indexKey, now, math.Inf(1))
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*Session, 0, len(ids))
+	var stale []string
+	for _, id := range ids {
+		var session Session
+		if err := s.cache.Get(sessionKeyPrefix+id, &session); err != nil {
+			if cache.IsNotFound(err) {
+				stale = append(stale, id)
+				continue
+			}
+			return nil, err
+		}
+		session.Current = session.ID == currentID
+		sessions = append(sessions, &session)
+	}
+	if err := s.cache.ZRem(indexKey, stale...); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+// Revoke 终止会话及其签发的下级会话，对应的Token立即失效
+func (s *SessionService) Revoke(sessionID string) error {
+	var session Session
+	if err := s.cache.GetDel(sessionKeyPrefix+sessionID, &session); err != nil {
+		if cache.IsNotFound(err) {
+			return s.revokeChildren(sessionID)
+		}
+		return err
+	}
+	if err := s.cache.ZRem(userSessionKey(session.UserID), sessionID); err != nil {
+		return err
+	}
+	return s.revokeChildren(sessionID)
+}
+
+// revokeChildren 终止由会话签发的下级会话
+func (s *SessionService) revokeChildren(parentID string) error {
+	childKey := sessionChildKey(parentID)
+	ids, err := s.cache.ZRangeByScore(childKey, math.Inf(-1), math.Inf(1))
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := s.Revoke(id); err != nil {
+			return err
+		}
+	}
+	return s.cache.Delete(childKey)
+}
+
+// RevokeForUser 终止用户自己的会话，不能终止其他用户的会话
+func (s *SessionService) RevokeForUser(userID uint, sessionID string) error {
+	var session Session
+	if err := s.cache.Get(sessionKeyPrefix+sessionID, &session); err != nil {
+		if cache.IsNotFound(err) {
+			return errors.New("会话不存在或已失效")
+		}
+		return err
+	}
+	if session.UserID != userID {
+		return errors.New("会话不存在或已失效")
+	}
+	return s.Revoke(sessionID)
+}
+
+// RevokeAll 终止用户的所有会话，exceptID不为空时保留该会话，返回终止的会话数
+func (s *SessionService) RevokeAll(userID uint, exceptID string) (int, error) {
+	ids, err := s.cache.ZRangeByScore(userSessionKey(userID), math.Inf(-1), math.Inf(1))
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, id := range ids {
+		if id == exceptID {
+			continue
+		}
+		if err := s.Revoke(id); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// userSessionKey 用户会话索引的键
+func userSessionKey(userID uint) string {
+	return fmt.Sprintf("%s%d", sessionUserKeyPrefix, userID)
+}
+
+// sessionChildKey 下级会话索引的键
+func sessionChildKey(parentID string) string {
+	return sessionChildKeyPrefix + parentID
+}
+
+// describeDevice 从User-Agent中识别客户端和操作系统，用于会话列表展示
+func describeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	client := "未知客户端"
+	for _, c := range []struct{ token, name string }{
+		{"edg/", "Edge"},
+		{"opr/", "Opera"},
+		{"micromessenger", "微信"},
+		{"chrome/", "Chrome"},
+		{"firefox/", "Firefox"},
+		{"safari/", "Safari"},
+		{"curl/", "curl"},
+		{"postman", "Postman"},
+		{"okhttp", "OkHttp"},
+		{"python-requests", "Python"},
+		{"go-http-client", "Go"},
+	} {
+		if strings.Contains(ua, c.token) {
+			client = c.name
+			break
+		}
+	}
+
+	for _, o := range []struct{ token, name string }{
+		{"android", "Android"},
+		{"iphone", "iOS"},
+		{"ipad", "iPadOS"},
+		{"windows", "Windows"},
+		{"mac os x", "macOS"},
+		{"linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.token) {
+			return client + " / " + o.name
+		}
+	}
+	return client
+}
@@ -0,0 +1,421 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/internal/infrastructure/cache"
+	"mcprapi/backend/internal/infrastructure/sso"
+	"mcprapi/backend/internal/pkg/encrypt"
+)
+
+// ssoStateKeyPrefix 外部登录state在Redis中的键前缀
+const ssoStateKeyPrefix = "sso:state:"
+
+// ssoStateTTL 外部登录state有效期
+const ssoStateTTL = 10 * time.Minute
+
+// usernameSanitizer 用于清理外部用户名中的非法字符
+var usernameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_.\-]`)
+
+// SSOService 外部身份登录服务
+type SSOService struct {
+	providers    map[string]sso.Provider
+	names        []string
+	identityRepo repository.UserIdentityRepository
+	userRepo     repository.UserRepository
+	deptRepo     repository.DepartmentRepository
+	roleRepo     repository.RoleRepository
+	authService  *AuthService
+	cache        *cache.Redis
+}
+
+// NewSSOService 创建外部身份登录服务
+func NewSSOService(
+	providers []sso.Provider,
+	identityRepo repository.UserIdentityRepository,
+	userRepo repository.UserRepository,
+	deptRepo repository.DepartmentRepository,
+	roleRepo repository.RoleRepository,
+	authService *AuthService,
+	cache *cache.Redis,
+) *SSOService {
+	s := &SSOService{
+		providers:    make(map[string]sso.Provider),
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		deptRepo:     deptRepo,
+		roleRepo:     roleRepo,
+		authService:  authService,
+		cache:        cache,
+	}
+	for _, provider := range providers {
+		s.providers[provider.Name()] = provider
+		s.names = append(s.names, provider.Name())
+	}
+	return s
+}
+
+// SSOProviderInfo 登录提供方信息
+type SSOProviderInfo struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// SSOLoginResponse 外部登录响应
+type SSOLoginResponse struct {
+	LoginResponse
+	Provider string `json:"provider"`
+	Created  bool   `json:"created"` // 是否为首次登录自动创建的用户
+	Linked   bool   `json:"linked"`  // 是否为本次新绑定的身份
+}
+
+// ssoState 授权请求上下文
+type ssoState struct {
+	Provider   string `json:"provider"`
+	Nonce      string `json:"nonce"`
+	LinkUserID uint   `json:"link_user_id"` // 非0表示为已登录用户绑定身份
+}
+
+// ListProviders 获取已配置的登录提供方
+func (s *SSOService) ListProviders() []SSOProviderInfo {
+	infos := make([]SSOProviderInfo, 0, len(s.names))
+	for _, name := range s.names {
+		providerType := s.providers[name].Config().Type
+		if providerType == "" {
+			providerType = "oidc"
+		}
+		infos = append(infos, SSOProviderInfo{Name: name, Type: providerType})
+	}
+	return infos
+}
+
+// StartLogin 生成外部登录跳转地址
+func (s *SSOService) StartLogin(ctx context.Context, providerName string) (string, error) {
+	return s.start(ctx, providerName, 0)
+}
+
+// StartLink 为已登录用户生成身份绑定跳转地址
+func (s *SSOService) StartLink(ctx context.Context, providerName string, userID uint) (string, error) {
+	existing, err := s.identityRepo.GetByUserAndProvider(userID, providerName)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return "", errors.New("已绑定该登录方式，请先解绑")
+	}
+	return s.start(ctx, providerName, userID)
+}
+
+// Callback 处理提供方回调，完成登录或身份绑定；用户启用或被要求启用MFA时返回二次验证凭证
+func (s *SSOService) Callback(ctx context.Context, providerName, code, state string, origin *LoginOrigin) (*SSOLoginResponse, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, errors.New("登录提供方不存在")
+	}
+	if code == "" || state == "" {
+		return nil, errors.New("缺少code或state")
+	}
+
+	// state只能使用一次，读取和删除须原子完成，避免并发回调重复使用
+	var data ssoState
+	if err := s.cache.GetDel(ssoStateKeyPrefix+state, &data); err != nil {
+		return nil, errors.New("登录请求无效或已过期")
+	}
+	if data.Provider != providerName {
+		return nil, errors.New("登录请求与提供方不匹配")
+	}
+
+	identity, err := provider.Exchange(ctx, code, data.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.LinkUserID > 0 {
+		return s.link(provider, identity, data.LinkUserID, origin)
+	}
+	return s.login(provider, identity, origin)
+}
+
+// ListIdentities 获取用户已绑定的外部身份
+func (s *SSOService) ListIdentities(userID uint) ([]*entity.UserIdentity, error) {
+	return s.identityRepo.ListByUser(userID)
+}
+
+// Unlink 解绑外部身份
+func (s *SSOService) Unlink(userID uint, providerName string) error {
+	identity, err := s.identityRepo.GetByUserAndProvider(userID, providerName)
+	if err != nil {
+		return err
+	}
+	if identity == nil {
+		return errors.New("未绑定该登录方式")
+	}
+	return s.identityRepo.Delete(identity.ID)
+}
+
+// start 保存state并生成授权地址
+func (s *SSOService) start(ctx context.Context, providerName string, linkUserID uint) (string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", errors.New("登录提供方不存在")
+	}
+
+	state, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	data := &ssoState{Provider: providerName, Nonce: nonce, LinkUserID: linkUserID}
+	if err := s.cache.Set(ssoStateKeyPrefix+state, data, ssoStateTTL); err != nil {
+		return "", fmt.Errorf("保存登录状态失败: %v", err)
+	}
+
+	return provider.AuthCodeURL(ctx, state, nonce)
+}
+
+// login 使用外部身份登录，必要时按邮箱绑定或自动创建用户
+func (s *SSOService) login(provider sso.Provider, identity *sso.Identity, origin *LoginOrigin) (*SSOLoginResponse, error) {
+	cfg := provider.Config()
+	resp := &SSOLoginResponse{Provider: provider.Name()}
+
+	var user *entity.User
+	binding, err := s.identityRepo.GetByProviderSubject(provider.Name(), identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if binding != nil {
+		user, err = s.userRepo.GetByID(binding.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			// 用户已被删除，清理失效的绑定后按新用户处理
+			if err := s.identityRepo.Delete(binding.ID); err != nil {
+				return nil, err
+			}
+			binding = nil
+		}
+	}
+
+	// 按已验证邮箱绑定已有用户
+	if user == nil && cfg.LinkByEmail && identity.Email != "" && identity.EmailVerified {
+		user, err = s.userRepo.GetByEmail(identity.Email)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 自动创建用户
+	if user == nil {
+		if !cfg.AutoProvision {
+			return nil, errors.New("该外部账号未绑定系统用户")
+		}
+		user, err = s.provisionUser(provider, identity)
+		if err != nil {
+			return nil, err
+		}
+		resp.Created = true
+	}
+
+	if user.Status != 1 {
+		return nil, errors.New("用户已禁用")
+	}
+
+	now := time.Now()
+	if binding == nil {
+		binding = &entity.UserIdentity{
+			UserID:   user.ID,
+			Provider: provider.Name(),
+			Subject:  identity.Subject,
+		}
+		resp.Linked = true
+	}
+	binding.Email = identity.Email
+	binding.LastLoginAt = &now
+	if err := s.saveIdentity(binding); err != nil {
+		return nil, err
+	}
+
+	login, err := s.authService.externalLogin(user, origin)
+	if err != nil {
+		return nil, err
+	}
+	resp.LoginResponse = *login
+	return resp, nil
+}
+
+// link 将外部身份绑定到已登录用户
+func (s *SSOService) link(provider sso.Provider, identity *sso.Identity, userID uint, origin *LoginOrigin) (*SSOLoginResponse, error) {
+	binding, err := s.identityRepo.GetByProviderSubject(provider.Name(), identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if binding != nil && binding.UserID != userID {
+		return nil, errors.New("该外部账号已绑定其他用户")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("用户不存在")
+	}
+	if user.Status != 1 {
+		return nil, errors.New("用户已禁用")
+	}
+
+	resp := &SSOLoginResponse{Provider: provider.Name()}
+	now := time.Now()
+	if binding == nil {
+		binding = &entity.UserIdentity{
+			UserID:   userID,
+			Provider: provider.Name(),
+			Subject:  identity.Subject,
+		}
+		resp.Linked = true
+	}
+	binding.Email = identity.Email
+	binding.LastLoginAt = &now
+	if err := s.saveIdentity(binding); err != nil {
+		return nil, err
+	}
+
+	login, err := s.authService.externalLogin(user, origin)
+	if err != nil {
+		return nil, err
+	}
+	resp.LoginResponse = *login
+	return resp, nil
+}
+
+// provisionUser 按外部身份自动创建用户
+func (s *SSOService) provisionUser(provider sso.Provider, identity *sso.Identity) (*entity.User, error) {
+	cfg := provider.Config()
+
+	// 部门映射：优先使用声明中的部门编码，其次使用默认部门
+	var dept *entity.Department
+	var err error
+	if identity.DeptCode != "" {
+		dept, err = s.deptRepo.GetByCode(identity.DeptCode)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if dept == nil && cfg.DefaultDeptCode != "" {
+		dept, err = s.deptRepo.GetByCode(cfg.DefaultDeptCode)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if dept == nil {
+		return nil, errors.New("无法确定用户所属部门")
+	}
+
+	username, err := s.uniqueUsername(provider.Name(), identity)
+	if err != nil {
+		return nil, err
+	}
+
+	email := identity.Email
+	if email == "" {
+		email = username + "@" + provider.Name() + ".sso.local"
+	}
+	if existing, err := s.userRepo.GetByEmail(email); err != nil {
+		return nil, err
+	} else if existing != nil {
+		return nil, errors.New("邮箱已被其他用户使用，请联系管理员绑定账号")
+	}
+
+	name := identity.Name
+	if name == "" {
+		name = username
+	}
+
+	password, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	passwordHash, err := encrypt.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &entity.User{
+		Username: username,
+		Name:     name,
+		Email:    email,
+		Password: passwordHash, // 随机密码，外部用户需通过提供方登录
+		Avatar:   identity.Avatar,
+		DeptID:   dept.ID,
+		Status:   1,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, fmt.Errorf("创建用户失败: %v", err)
+	}
+
+	if cfg.DefaultRole != "" {
+		role, err := s.roleRepo.GetByCode(cfg.DefaultRole)
+		if err != nil {
+			return nil, err
+		}
+		if role != nil {
+			if err := s.userRepo.AssignRoles(user.ID, []uint{role.ID}); err != nil {
+				return nil, fmt.Errorf("分配默认角色失败: %v", err)
+			}
+		}
+	}
+
+	return user, nil
+}
+
+// uniqueUsername 生成不冲突的用户名
+func (s *SSOService) uniqueUsername(providerName string, identity *sso.Identity) (string, error) {
+	base := identity.Username
+	if base == "" && identity.Email != "" {
+		base = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+	if base == "" {
+		base = providerName + "_" + identity.Subject
+	}
+	base = usernameSanitizer.ReplaceAllString(base, "_")
+	if len(base) > 40 {
+		base = base[:40]
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		existing, err := s.userRepo.GetByUsername(candidate)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return candidate, nil
+		}
+		suffix, err := randomToken(2)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "_" + suffix
+	}
+	return "", errors.New("无法生成可用的用户名")
+}
+
+// saveIdentity 保存身份绑定
+func (s *SSOService) saveIdentity(identity *entity.UserIdentity) error {
+	if identity.ID == 0 {
+		return s.identityRepo.Create(identity)
+	}
+	return s.identityRepo.Update(identity)
+}
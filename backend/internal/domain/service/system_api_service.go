@@ -0,0 +1,347 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/internal/pkg/logger"
+)
+
+// systemAPICategoryCode 系统API所属的分类
+const systemAPICategoryCode = "system"
+
+// 系统API的授予集合，新建角色和部门管理员按集合获得已登记的系统API权限
+const (
+	SystemGrantRoleDefault = "role_default" // 新建角色的默认权限
+	SystemGrantDeptAdmin   = "dept_admin"   // 部门管理员角色的权限
+)
+
+// systemAPIGrantRule 按处理函数所属的Handler选取系统API，method为空时包含所有方法
+type systemAPIGrantRule struct {
+	handler string
+	method  string
+}
+
+// systemAPIGrants 各授予集合包含的Handler，路由增删后随启动登记自动生效
+var systemAPIGrants = map[string][]systemAPIGrantRule{
+	SystemGrantRoleDefault: {
+		{handler: "APIHandler"},
+		{handler: "BusinessHandler"},
+		{handler: "DepartmentHandler", method: "GET"},
+		{handler: "RoleHandler"},
+		{handler: "UserHandler"},
+	},
+	SystemGrantDeptAdmin: {
+		{handler: "DeptUserHandler"},
+		{handler: "DeptRoleHandler"},
+		{handler: "DeptBusinessHandler"},
+		{handler: "DeptAPIHandler"},
+	},
+}
+
+// SystemAPIConfig 系统API登记配置
+type SystemAPIConfig struct {
+	Enabled      bool     `mapstructure:"enabled"`       // 是否在启动时登记服务自身的路由
+	DeptCode     string   `mapstructure:"dept_code"`     // 系统业务线所属部门，为空时使用default部门或第一个顶级部门
+	PathPrefixes []string `mapstructure:"path_prefixes"` // 只登记这些前缀下的路由
+}
+
+// SystemRoute 服务自身的一个路由
+type SystemRoute struct {
+	Method  string
+	Path    string
+	Handler string // 处理函数的完整名称
+}
+
+// SystemAPIRegisterResult 系统API登记结果
+type SystemAPIRegisterResult struct {
+	BusinessID uint     `json:"business_id"`
+	Created    int      `json:"created"`
+	Updated    int      `json:"updated"`
+	Unchanged  int      `json:"unchanged"`
+	Deprecated int      `json:"deprecated"`          // 路由已不存在、标记为废弃的API数
+	Conflicts  []string `json:"conflicts,omitempty"` // 路径和方法已被其他业务线登记的路由
+}
+
+// SystemAPIService 将服务自身的路由登记为系统业务线下的API，使其可以通过API ID授权
+type SystemAPIService struct {
+	cfg          SystemAPIConfig
+	apiRepo      repository.APIRepository
+	businessRepo repository.BusinessRepository
+	deptRepo     repository.DepartmentRepository
+	logger       *logger.Logger
+}
+
+// NewSystemAPIService 创建系统API登记服务
+func NewSystemAPIService(cfg SystemAPIConfig, apiRepo repository.APIRepository, businessRepo repository.BusinessRepository,
+	deptRepo repository.DepartmentRepository, logger *logger.Logger) *SystemAPIService {
+	if len(cfg.PathPrefixes) == 0 {
+		cfg.PathPrefixes = []string{"/api/v1/"}
+	}
+	return &SystemAPIService{
+		cfg:          cfg,
+		apiRepo:      apiRepo,
+		businessRepo: businessRepo,
+		deptRepo:     deptRepo,
+		logger:       logger,
+	}
+}
+
+// Register 登记路由：新增的路由创建为API，处理函数变化的更新名称，已不存在的标记为废弃；未启用时跳过
+func (s *SystemAPIService) Register(routes []SystemRoute) (*SystemAPIRegisterResult, error) {
+	if !s.cfg.Enabled {
+		return nil, nil
+	}
+
+	business, err := s.ensureBusiness()
+	if err != nil {
+		return nil, err
+	}
+	categoryID, err := s.ensureCategory()
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.apiRepo.ListByBusiness(business.ID)
+	if err != nil {
+		return nil, err
+	}
+	existingByKey := make(map[string]*entity.API, len(existing))
+	for _, api := range existing {
+		existingByKey[api.Method+" "+api.Path] = api
+	}
+
+	result := &SystemAPIRegisterResult{BusinessID: business.ID}
+	seen := make(map[string]bool, len(routes))
+	for _, route := range s.filterRoutes(routes) {
+		key := route.Method + " " + route.Path
+		seen[key] = true
+		name := truncateString(systemAPIName(route.Handler), 100)
+
+		api := existingByKey[key]
+		if api == nil {
+			other, err := s.apiRepo.GetByPath(route.Path, route.Method)
+			if err != nil {
+				return nil, err
+			}
+			if other != nil {
+				result.Conflicts = append(result.Conflicts, key)
+				continue
+			}
+
+			api = &entity.API{
+				Name:        name,
+				Path:        route.Path,
+				Method:      route.Method,
+				Description: "系统接口",
+				BusinessID:  business.ID,
+				CategoryID:  categoryID,
+				Status:      1,
+				Lifecycle:   entity.APILifecycleActive,
+			}
+			if err := s.apiRepo.Create(api); err != nil {
+				return nil, fmt.Errorf("登记系统API %s失败: %v", key, err)
+			}
+			result.Created++
+			continue
+		}
+
+		if api.Name == name && api.Lifecycle != entity.APILifecycleDeprecated {
+			result.Unchanged++
+			continue
+		}
+		api.Name = name
+		api.Lifecycle = entity.APILifecycleActive
+		api.DeprecatedAt = nil
+		if err := s.apiRepo.Update(api); err != nil {
+			return nil, fmt.Errorf("更新系统API %s失败: %v", key, err)
+		}
+		result.Updated++
+	}
+
+	// 路由已删除的API保留并标记为废弃，已授予的策略由管理员清理
+	now := time.Now()
+	for _, api := range existing {
+		if seen[api.Method+" "+api.Path] || api.Lifecycle == entity.APILifecycleDeprecated {
+			continue
+		}
+		api.Lifecycle = entity.APILifecycleDeprecated
+		api.DeprecatedAt = &now
+		if err := s.apiRepo.Update(api); err != nil {
+			return nil, fmt.Errorf("标记系统API %s %s为废弃失败: %v", api.Method, api.Path, err)
+		}
+		result.Deprecated++
+	}
+
+	return result, nil
+}
+
+// GrantAPIIDs 获取授予集合中已登记且未废弃的系统API ID；未启用系统API登记时返回空
+func (s *SystemAPIService) GrantAPIIDs(grant string) ([]uint, error) {
+	rules, ok := systemAPIGrants[grant]
+	if !ok {
+		return nil, fmt.Errorf("系统API授予集合不存在: %s", grant)
+	}
+
+	business, err := s.businessRepo.GetByCode(entity.SystemBusinessCode)
+	if err != nil || business == nil {
+		return nil, err
+	}
+	apis, err := s.apiRepo.ListByBusiness(business.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint
+	for _, api := range apis {
+		if api.Lifecycle == entity.APILifecycleDeprecated {
+			continue
+		}
+		for _, rule := range rules {
+			if strings.HasPrefix(api.Name, rule.handler+".") && (rule.method == "" || rule.method == api.Method) {
+				ids = append(ids, api.ID)
+				break
+			}
+		}
+	}
+	return ids, nil
+}
+
+// filterRoutes 保留配置前缀下、支持的方法的路由，路径转换为Casbin keyMatch2格式，按路径排序
+func (s *SystemAPIService) filterRoutes(routes []SystemRoute) []SystemRoute {
+	filtered := make([]SystemRoute, 0, len(routes))
+	for _, route := range routes {
+		if !apiImportMethods[route.Method] || !s.matchPrefix(route.Path) {
+			continue
+		}
+		route.Path = systemAPIPath(route.Path)
+		if len(route.Path) > 200 {
+			s.logger.Warn("系统路由路径超过200个字符，跳过登记: %s", route.Path)
+			continue
+		}
+		filtered = append(filtered, route)
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		if filtered[i].Path != filtered[j].Path {
+			return filtered[i].Path < filtered[j].Path
+		}
+		return filtered[i].Method < filtered[j].Method
+	})
+	return filtered
+}
+
+// matchPrefix 路径是否在配置的前缀之下
+func (s *SystemAPIService) matchPrefix(path string) bool {
+	for _, prefix := range s.cfg.PathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureBusiness 获取系统业务线，不存在时在配置的部门下创建
+func (s *SystemAPIService) ensureBusiness() (*entity.Business, error) {
+	business, err := s.businessRepo.GetByCode(entity.SystemBusinessCode)
+	if err != nil {
+		return nil, err
+	}
+	if business != nil {
+		return business, nil
+	}
+
+	dept, err := s.systemDept()
+	if err != nil {
+		return nil, err
+	}
+	business = &entity.Business{
+		Name:        "系统接口",
+		Code:        entity.SystemBusinessCode,
+		DeptID:      dept.ID,
+		Description: "权限管理服务自身的接口，启动时自动登记",
+		Owner:       "系统",
+		Status:      1,
+	}
+	if err := s.businessRepo.Create(business); err != nil {
+		return nil, fmt.Errorf("创建系统业务线失败: %v", err)
+	}
+	return business, nil
+}
+
+// systemDept 系统业务线所属部门：配置的部门，其次为default部门，最后为第一个顶级部门
+func (s *SystemAPIService) systemDept() (*entity.Department, error) {
+	codes := []string{"default"}
+	if s.cfg.DeptCode != "" {
+		codes = []string{s.cfg.DeptCode}
+	}
+	for _, code := range codes {
+		dept, err := s.deptRepo.GetByCode(code)
+		if err != nil {
+			return nil, err
+		}
+		if dept != nil {
+			return dept, nil
+		}
+	}
+	if s.cfg.DeptCode != "" {
+		return nil, fmt.Errorf("系统业务线所属部门不存在: %s", s.cfg.DeptCode)
+	}
+
+	depts, err := s.deptRepo.List(0)
+	if err != nil {
+		return nil, err
+	}
+	if len(depts) == 0 {
+		return nil, errors.New("没有可用的部门，无法创建系统业务线")
+	}
+	return depts[0], nil
+}
+
+// ensureCategory 获取系统API分类，不存在时创建
+func (s *SystemAPIService) ensureCategory() (uint, error) {
+	category, err := s.apiRepo.GetCategoryByCode(systemAPICategoryCode)
+	if err == nil && category != nil {
+		return category.ID, nil
+	}
+
+	category = &entity.APICategory{
+		Name: "系统管理",
+		Code: systemAPICategoryCode,
+		Sort: 1,
+	}
+	if err := s.apiRepo.CreateCategory(category); err != nil {
+		return 0, fmt.Errorf("创建系统API分类失败: %v", err)
+	}
+	return category.ID, nil
+}
+
+// systemAPIPath 将Gin路由的通配参数*name转换为keyMatch2的*，:name保持不变
+func systemAPIPath(path string) string {
+	if i := strings.Index(path, "/*"); i >= 0 {
+		return path[:i] + "/*"
+	}
+	return path
+}
+
+// systemAPIName 由处理函数名生成API名称，如handler.(*UserHandler).List-fm生成UserHandler.List
+func systemAPIName(handler string) string {
+	name := handler
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(name, "-fm")
+	name = strings.NewReplacer("(*", "", ")", "").Replace(name)
+	if name == "" {
+		return handler
+	}
+	return name
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"mcprapi/backend/internal/domain/entity"
+)
+
+func (r *fakeBusinessRepo) GetByCode(code string) (*entity.Business, error) {
+	for _, business := range r.businesses {
+		if business.Code == code {
+			return business, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestSystemAPIGrantAPIIDs(t *testing.T) {
+	businesses := &fakeBusinessRepo{businesses: map[uint]*entity.Business{
+		1: {ID: 1, Code: entity.SystemBusinessCode},
+		2: {ID: 2, Code: "orders"},
+	}}
+	apis := &fakeAPIRepo{byBusiness: map[uint][]*entity.API{
+		1: {
+			{ID: 1, Name: "UserHandler.List", Path: "/api/v1/user/list", Method: "GET"},
+			{ID: 2, Name: "UserHandler.Delete", Path: "/api/v1/user/:id", Method: "DELETE"},
+			{ID: 3, Name: "DepartmentHandler.List", Path: "/api/v1/department/list", Method: "GET"},
+			{ID: 4, Name: "DepartmentHandler.Create", Path: "/api/v1/department", Method: "POST"},
+			{ID: 5, Name: "DeptUserHandler.ListDeptUsers", Path: "/api/v1/dept-management/:dept_id/users", Method: "GET"},
+			{ID: 6, Name: "UserHandler.Removed", Path: "/api/v1/user/old", Method: "GET", Lifecycle: entity.APILifecycleDeprecated},
+			{ID: 7, Name: "UserHandlerExtra.List", Path: "/api/v1/extra", Method: "GET"},
+			{ID: 8, Name: "AuditHandler.List", Path: "/api/v1/audit/logs", Method: "GET"},
+		},
+		2: {{ID: 9, Name: "UserHandler.List", Path: "/orders/users", Method: "GET"}},
+	}}
+	s := NewSystemAPIService(SystemAPIConfig{Enabled: true}, apis, businesses, nil, nil)
+
+	tests := []struct {
+		grant   string
+		want    []uint
+		wantErr bool
+	}{
+		{grant: SystemGrantRoleDefault, want: []uint{1, 2, 3}},
+		{grant: SystemGrantDeptAdmin, want: []uint{5}},
+		{grant: "unknown", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.grant, func(t *testing.T) {
+			got, err := s.GrantAPIIDs(tt.grant)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GrantAPIIDs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GrantAPIIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin/v2/util"
+
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// TokenScopeConfig 受限令牌配置
+type TokenScopeConfig struct {
+	DefaultTTL time.Duration `mapstructure:"default_ttl"` // 未指定有效期时的默认有效期
+	MaxTTL     time.Duration `mapstructure:"max_ttl"`     // 最长有效期
+	MaxRules   int           `mapstructure:"max_rules"`   // 单个令牌最多可包含的API和业务线数量
+}
+
+// TokenRestriction 受限令牌的访问范围（rst声明），令牌的实际权限为用户角色权限与该范围的交集
+// APIs和BusinessIDs满足其一即在范围内
+type TokenRestriction struct {
+	APIs        []APIRule `json:"apis,omitempty"`         // 允许访问的API
+	BusinessIDs []uint    `json:"business_ids,omitempty"` // 允许访问的业务线，请求须命中该业务线下登记的API
+}
+
+// APIRule 受限令牌允许访问的API，路径支持:id和*通配（与Casbin策略的keyMatch2一致），方法为*表示任意方法
+type APIRule struct {
+	Path   string `json:"path"`
+	Method string `json:"method"`
+}
+
+// ScopedTokenRequest 签发受限令牌请求
+type ScopedTokenRequest struct {
+	APIs        []APIRule `json:"apis"`
+	BusinessIDs []uint    `json:"business_ids"`
+	TTLMinutes  int       `json:"ttl_minutes"` // 有效期（分钟），不超过配置的最长有效期
+}
+
+// ParentToken 发起签发的当前令牌
+type ParentToken struct {
+	SessionID   string
+	ExpiresAt   int64
+	Restriction *TokenRestriction // 当前令牌不受限时为nil
+}
+
+// ScopedTokenResponse 签发受限令牌响应
+type ScopedTokenResponse struct {
+	Token       string            `json:"token"`
+	ExpiresAt   int64             `json:"expires_at"`
+	SessionID   string            `json:"session_id,omitempty"`
+	Restriction *TokenRestriction `json:"restriction"`
+}
+
+// TokenScopeService 受限令牌服务，为MCP Agent等调用方签发只能访问部分API的令牌
+type TokenScopeService struct {
+	cfg          TokenScopeConfig
+	authService  *AuthService
+	userRepo     repository.UserRepository
+	businessRepo repository.BusinessRepository
+}
+
+// NewTokenScopeService 创建受限令牌服务
+func NewTokenScopeService(cfg TokenScopeConfig, authService *AuthService, userRepo repository.UserRepository,
+	businessRepo repository.BusinessRepository) *TokenScopeService {
+	if cfg.DefaultTTL <= 0 {
+		cfg.DefaultTTL = 24 * time.Hour
+	}
+	if cfg.MaxTTL <= 0 {
+		cfg.MaxTTL = 30 * 24 * time.Hour
+	}
+	if cfg.DefaultTTL > cfg.MaxTTL {
+		cfg.DefaultTTL = cfg.MaxTTL
+	}
+	if cfg.MaxRules <= 0 {
+		cfg.MaxRules = 50
+	}
+
+	return &TokenScopeService{
+		cfg:          cfg,
+		authService:  authService,
+		userRepo:     userRepo,
+		businessRepo: businessRepo,
+	}
+}
+
+// Issue 为用户签发受限令牌；当前令牌已受限时，新令牌只能进一步缩小范围，
+// 有效期不超过当前令牌的剩余有效期，且当前令牌的会话终止时一并失效
+func (s *TokenScopeService) Issue(userID uint, current *ParentToken, req *ScopedTokenRequest) (*ScopedTokenResponse, error) {
+	var parent *TokenRestriction
+	if current != nil {
+		parent = current.Restriction
+	}
+	if len(req.APIs) == 0 && len(req.BusinessIDs) == 0 {
+		return nil, errors.New("至少需要指定一个API或业务线")
+	}
+	if len(req.APIs)+len(req.BusinessIDs) > s.cfg.MaxRules {
+		return nil, fmt.Errorf("API和业务线数量不能超过%d个", s.cfg.MaxRules)
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("用户不存在")
+	}
+	if user.Status != 1 {
+		return nil, errors.New("用户已禁用")
+	}
+
+	restriction := &TokenRestriction{}
+	for _, rule := range req.APIs {
+		rule.Path = strings.TrimSpace(rule.Path)
+		rule.Method = strings.ToUpper(strings.TrimSpace(rule.Method))
+		if rule.Method == "" {
+			rule.Method = "*"
+		}
+		if !strings.HasPrefix(rule.Path, "/") {
+			return nil, fmt.Errorf("无效的API路径: %s", rule.Path)
+		}
+
+		// 新令牌的每个API都必须在用户当前的权限和当前令牌的范围之内
+		resp, err := s.authService.CheckPermission(&CheckPermissionRequest{
+			UserID:      fmt.Sprintf("%d", user.ID),
+			APIPath:     rule.Path,
+			Method:      rule.Method,
+			Restriction: parent,
+			quiet:       true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !resp.Allowed {
+			return nil, fmt.Errorf("无权访问API: %s %s", rule.Method, rule.Path)
+		}
+		restriction.APIs = append(restriction.APIs, rule)
+	}
+
+	seen := make(map[uint]bool, len(req.BusinessIDs))
+	for _, id := range req.BusinessIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if err := s.checkBusiness(user.ID, user.DeptID, id, parent); err != nil {
+			return nil, err
+		}
+		restriction.BusinessIDs = append(restriction.BusinessIDs, id)
+	}
+
+	ttl := s.cfg.DefaultTTL
+	if req.TTLMinutes > 0 {
+		ttl = time.Duration(req.TTLMinutes) * time.Minute
+	}
+	if ttl > s.cfg.MaxTTL {
+		ttl = s.cfg.MaxTTL
+	}
+	parentSession := ""
+	if parent != nil {
+		remaining := time.Until(time.Unix(current.ExpiresAt, 0))
+		if remaining <= 0 {
+			return nil, errors.New("当前令牌已过期")
+		}
+		if ttl > remaining {
+			ttl = remaining
+		}
+		parentSession = current.SessionID
+	}
+
+	token, claims, err := s.authService.GenerateRestrictedToken(user, restriction, ttl, parentSession)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScopedTokenResponse{
+		Token:       token,
+		ExpiresAt:   claims.ExpiresAt,
+		SessionID:   claims.Id,
+		Restriction: restriction,
+	}, nil
+}
+
+// checkBusiness 校验业务线可以加入受限令牌：业务线启用且属于用户所在部门（管理员不限部门），且在当前令牌的范围之内
+func (s *TokenScopeService) checkBusiness(userID, deptID, businessID uint, parent *TokenRestriction) error {
+	business, err := s.businessRepo.GetByID(businessID)
+	if err != nil {
+		return err
+	}
+	if business == nil || business.Status != 1 {
+		return fmt.Errorf("业务线不存在或已禁用: %d", businessID)
+	}
+
+	if parent != nil && !containsUint(parent.BusinessIDs, businessID) {
+		return fmt.Errorf("业务线超出当前令牌的访问范围: %d", businessID)
+	}
+
+	if business.DeptID == deptID {
+		return nil
+	}
+	roles, err := s.userRepo.GetUserRoles(userID)
+	if err != nil {
+		return err
+	}
+	if !containsString(roles, "admin") {
+		return fmt.Errorf("无权访问业务线: %d", businessID)
+	}
+	return nil
+}
+
+// AllowedByRestriction 请求是否在令牌的访问范围之内，restriction为nil表示不受限
+func (s *AuthService) AllowedByRestriction(restriction *TokenRestriction, apiPath, method string) (bool, error) {
+	if restriction == nil {
+		return true, nil
+	}
+	method = strings.ToUpper(method)
+
+	for _, rule := range restriction.APIs {
+		if matchRestrictedAPI(rule.Path, rule.Method, apiPath, method) {
+			return true, nil
+		}
+	}
+
+	for _, businessID := range restriction.BusinessIDs {
+		apis, err := s.apiRepo.ListByBusiness(businessID)
+		if err != nil {
+			return false, err
+		}
+		for _, api := range apis {
+			if api.Status == 1 && matchRestrictedAPI(api.Path, api.Method, apiPath, method) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// matchRestrictedAPI 请求是否命中API规则，匹配方式与Casbin策略相同
+func matchRestrictedAPI(rulePath, ruleMethod, apiPath, method string) bool {
+	if ruleMethod != "*" && !strings.EqualFold(ruleMethod, method) {
+		return false
+	}
+	return rulePath == apiPath || util.KeyMatch2(apiPath, rulePath)
+}
+
+// containsUint 切片中是否包含指定值
+func containsUint(items []uint, target uint) bool {
+	for _, item := range items {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/pkg/casbinx"
+)
+
+// fakeUserRepo 内存中的用户仓库，只实现测试用到的方法
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[uint]*entity.User
+	roles map[uint][]string
+}
+
+func (r *fakeUserRepo) GetByID(id uint) (*entity.User, error) {
+	return r.users[id], nil
+}
+
+func (r *fakeUserRepo) GetUserRoles(userID uint) ([]string, error) {
+	return r.roles[userID], nil
+}
+
+// fakeAPIRepo 内存中的API仓库，只实现测试用到的方法
+type fakeAPIRepo struct {
+	repository.APIRepository
+	byBusiness map[uint][]*entity.API
+}
+
+func (r *fakeAPIRepo) ListByBusiness(businessID uint) ([]*entity.API, error) {
+	return r.byBusiness[businessID], nil
+}
+
+// fakeBusinessRepo 内存中的业务线仓库，只实现测试用到的方法
+type fakeBusinessRepo struct {
+	repository.BusinessRepository
+	businesses map[uint]*entity.Business
+}
+
+func (r *fakeBusinessRepo) GetByID(id uint) (*entity.Business, error) {
+	return r.businesses[id], nil
+}
+
+// newTestEnforcer 创建只在内存中保存策略的执行器，policies为sub, obj, act, dept, eft
+func newTestEnforcer(t *testing.T, policies ...[]string) *casbinx.Enforcer {
+	t.Helper()
+	enforcer, err := casbinx.NewEnforcer("../../../configs/casbin_model.conf", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range policies {
+		if _, err := enforcer.AddPolicyWithDept(p[0], p[1], p[2], p[3], p[4]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return enforcer
+}
+
+// newTestScopeAuth 用户1属于部门5，拥有dev和ops角色；业务线7属于部门5，业务线8属于部门6
+func newTestScopeAuth(t *testing.T) (*AuthService, *fakeUserRepo, *fakeBusinessRepo) {
+	t.Helper()
+	users := &fakeUserRepo{
+		users: map[uint]*entity.User{1: {ID: 1, Username: "alice", DeptID: 5, Status: 1}},
+		roles: map[uint][]string{1: {"dev", "ops"}},
+	}
+	apis := &fakeAPIRepo{byBusiness: map[uint][]*entity.API{
+		7: {
+			{Path: "/biz/reports", Method: "GET", Status: 1},
+			{Path: "/biz/disabled", Method: "GET", Status: 0},
+		},
+	}}
+	businesses := &fakeBusinessRepo{businesses: map[uint]*entity.Business{
+		7: {ID: 7, DeptID: 5, Status: 1},
+		8: {ID: 8, DeptID: 6, Status: 1},
+		9: {ID: 9, DeptID: 5, Status: 0},
+	}}
+	enforcer := newTestEnforcer(t,
+		[]string{"dev", "/biz/orders/*", "GET", "*", "allow"},
+		[]string{"dev", "/biz/orders/:id", "DELETE", "*", "allow"},
+		[]string{"ops", "/biz/reports", "GET", "*", "allow"},
+		[]string{"ops", "/biz/disabled", "GET", "*", "allow"},
+		[]string{"ops", "/biz/exports", "*", "*", "allow"},
+	)
+	return &AuthService{userRepo: users, apiRepo: apis, casbin: enforcer, jwtSecret: "test"}, users, businesses
+}
+
+func TestMatchRestrictedAPI(t *testing.T) {
+	tests := []struct {
+		rulePath, ruleMethod, path, method string
+		want                               bool
+	}{
+		{"/biz/orders", "GET", "/biz/orders", "GET", true},
+		{"/biz/orders", "GET", "/biz/orders", "get", true},
+		{"/biz/orders", "GET", "/biz/orders", "POST", false},
+		{"/biz/orders", "*", "/biz/orders", "DELETE", true},
+		{"/biz/orders/:id", "GET", "/biz/orders/42", "GET", true},
+		{"/biz/orders/:id", "GET", "/biz/orders/42/items", "GET", false},
+		{"/biz/orders/*", "GET", "/biz/orders/42/items", "GET", true},
+		{"/biz/orders", "GET", "/biz/orders/42", "GET", false},
+	}
+	for _, tt := range tests {
+		if got := matchRestrictedAPI(tt.rulePath, tt.ruleMethod, tt.path, tt.method); got != tt.want {
+			t.Errorf("matchRestrictedAPI(%s %s, %s %s) = %v, want %v", tt.ruleMethod, tt.rulePath, tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCheckPermissionIntersectsRolesScopeAndRestriction(t *testing.T) {
+	s, _, _ := newTestScopeAuth(t)
+
+	tests := []struct {
+		name        string
+		path        string
+		method      string
+		scope       string
+		restriction *TokenRestriction
+		want        bool
+	}{
+		{"不受限", "/biz/orders/1", "GET", "", nil, true},
+		{"角色没有权限", "/biz/admin", "GET", "", nil, false},
+		{"受限令牌范围内", "/biz/orders/1", "GET", "", &TokenRestriction{APIs: []APIRule{{"/biz/orders/:id", "GET"}}}, true},
+		{"受限令牌范围外的方法", "/biz/orders/1", "DELETE", "", &TokenRestriction{APIs: []APIRule{{"/biz/orders/:id", "GET"}}}, false},
+		{"受限令牌任意方法", "/biz/orders/1", "DELETE", "", &TokenRestriction{APIs: []APIRule{{"/biz/orders/:id", "*"}}}, true},
+		{"受限令牌不能扩大角色权限", "/biz/admin", "GET", "", &TokenRestriction{APIs: []APIRule{{"/biz/admin", "GET"}}}, false},
+		{"空的受限范围", "/biz/orders/1", "GET", "", &TokenRestriction{}, false},
+		{"业务线下的API", "/biz/reports", "GET", "", &TokenRestriction{BusinessIDs: []uint{7}}, true},
+		{"业务线下已禁用的API", "/biz/disabled", "GET", "", &TokenRestriction{BusinessIDs: []uint{7}}, false},
+		{"不在业务线下的API", "/biz/orders/1", "GET", "", &TokenRestriction{BusinessIDs: []uint{7}}, false},
+		{"scope授予的角色", "/biz/reports", "GET", "role:ops", nil, true},
+		{"scope未授予的角色", "/biz/orders/1", "GET", "role:ops", nil, false},
+		{"scope不包含角色", "/biz/reports", "GET", "openid profile", nil, false},
+		{"scope与受限范围同时生效", "/biz/reports", "GET", "role:dev", &TokenRestriction{APIs: []APIRule{{"/biz/reports", "GET"}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.CheckPermission(&CheckPermissionRequest{
+				UserID:      "1",
+				APIPath:     tt.path,
+				Method:      tt.method,
+				Scope:       tt.scope,
+				Restriction: tt.restriction,
+				quiet:       true,
+			})
+			if err != nil {
+				t.Fatalf("CheckPermission() error = %v", err)
+			}
+			if resp.Allowed != tt.want {
+				t.Errorf("CheckPermission(%s %s) allowed = %v, want %v", tt.method, tt.path, resp.Allowed, tt.want)
+			}
+			if resp.Restricted != (tt.restriction != nil) {
+				t.Errorf("CheckPermission() restricted = %v, want %v", resp.Restricted, tt.restriction != nil)
+			}
+		})
+	}
+}
+
+func TestTokenScopeIssueNarrowsRestriction(t *testing.T) {
+	s, users, businesses := newTestScopeAuth(t)
+	scope := NewTokenScopeService(TokenScopeConfig{MaxRules: 3}, s, users, businesses)
+
+	tests := []struct {
+		name    string
+		parent  *TokenRestriction
+		req     *ScopedTokenRequest
+		want    *TokenRestriction
+		wantErr bool
+	}{
+		{
+			name: "角色权限内的API",
+			req:  &ScopedTokenRequest{APIs: []APIRule{{" /biz/orders/:id ", "get"}}},
+			want: &TokenRestriction{APIs: []APIRule{{"/biz/orders/:id", "GET"}}},
+		},
+		{
+			name: "未指定方法时为任意方法",
+			req:  &ScopedTokenRequest{APIs: []APIRule{{"/biz/exports", ""}}},
+			want: &TokenRestriction{APIs: []APIRule{{"/biz/exports", "*"}}},
+		},
+		{
+			name:    "任意方法需要角色拥有全部方法的权限",
+			req:     &ScopedTokenRequest{APIs: []APIRule{{"/biz/reports", "*"}}},
+			wantErr: true,
+		},
+		{
+			name:    "角色没有权限的API",
+			req:     &ScopedTokenRequest{APIs: []APIRule{{"/biz/admin", "GET"}}},
+			wantErr: true,
+		},
+		{
+			name:   "在当前令牌范围内",
+			parent: &TokenRestriction{APIs: []APIRule{{"/biz/orders/:id", "*"}}},
+			req:    &ScopedTokenRequest{APIs: []APIRule{{"/biz/orders/:id", "DELETE"}}},
+			want:   &TokenRestriction{APIs: []APIRule{{"/biz/orders/:id", "DELETE"}}},
+		},
+		{
+			name:    "超出当前令牌范围",
+			parent:  &TokenRestriction{APIs: []APIRule{{"/biz/orders/:id", "GET"}}},
+			req:     &ScopedTokenRequest{APIs: []APIRule{{"/biz/orders/:id", "DELETE"}}},
+			wantErr: true,
+		},
+		{
+			name: "本部门的业务线，重复的只保留一个",
+			req:  &ScopedTokenRequest{BusinessIDs: []uint{7, 7}},
+			want: &TokenRestriction{BusinessIDs: []uint{7}},
+		},
+		{
+			name:    "其他部门的业务线",
+			req:     &ScopedTokenRequest{BusinessIDs: []uint{8}},
+			wantErr: true,
+		},
+		{
+			name:    "已禁用的业务线",
+			req:     &ScopedTokenRequest{BusinessIDs: []uint{9}},
+			wantErr: true,
+		},
+		{
+			name:    "业务线超出当前令牌范围",
+			parent:  &TokenRestriction{BusinessIDs: []uint{8}},
+			req:     &ScopedTokenRequest{BusinessIDs: []uint{7}},
+			wantErr: true,
+		},
+		{
+			name:    "没有指定范围",
+			req:     &ScopedTokenRequest{},
+			wantErr: true,
+		},
+		{
+			name:    "超过数量上限",
+			req:     &ScopedTokenRequest{APIs: []APIRule{{"/biz/reports", "GET"}, {"/biz/orders/:id", "GET"}}, BusinessIDs: []uint{7, 7}},
+			wantErr: true,
+		},
+		{
+			name:    "无效的路径",
+			req:     &ScopedTokenRequest{APIs: []APIRule{{"biz/reports", "GET"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := &ParentToken{ExpiresAt: time.Now().Add(time.Hour).Unix(), Restriction: tt.parent}
+			resp, err := scope.Issue(1, current, tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Issue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(resp.Restriction, tt.want) {
+				t.Errorf("Issue() restriction = %+v, want %+v", resp.Restriction, tt.want)
+			}
+			if resp.Token == "" {
+				t.Error("Issue() 没有返回令牌")
+			}
+		})
+	}
+}
+
+// fakeSessionTracker 记录创建会话时的上级会话
+type fakeSessionTracker struct {
+	SessionTracker
+	parents []string
+}
+
+func (r *fakeSessionTracker) Create(user *entity.User, claims *Claims, parentID string) (string, error) {
+	r.parents = append(r.parents, parentID)
+	return "child", nil
+}
+
+func TestTokenScopeIssueBoundToParent(t *testing.T) {
+	restricted := &TokenRestriction{APIs: []APIRule{{"/biz/orders/:id", "*"}}}
+	tests := []struct {
+		name        string
+		current     *ParentToken
+		ttlMinutes  int
+		wantErr     bool
+		wantTTL     time.Duration
+		wantSession string
+	}{
+		{
+			name:       "不受限的令牌签发时不受自身有效期限制",
+			current:    &ParentToken{SessionID: "login", ExpiresAt: time.Now().Add(10 * time.Minute).Unix()},
+			ttlMinutes: 60,
+			wantTTL:    time.Hour,
+		},
+		{
+			name:        "受限令牌签发的令牌不超过自身剩余有效期",
+			current:     &ParentToken{SessionID: "parent", ExpiresAt: time.Now().Add(10 * time.Minute).Unix(), Restriction: restricted},
+			ttlMinutes:  60,
+			wantTTL:     10 * time.Minute,
+			wantSession: "parent",
+		},
+		{
+			name:        "剩余有效期更长时按请求的有效期",
+			current:     &ParentToken{SessionID: "parent", ExpiresAt: time.Now().Add(2 * time.Hour).Unix(), Restriction: restricted},
+			ttlMinutes:  60,
+			wantTTL:     time.Hour,
+			wantSession: "parent",
+		},
+		{
+			name:       "已过期的受限令牌",
+			current:    &ParentToken{SessionID: "parent", ExpiresAt: time.Now().Add(-time.Minute).Unix(), Restriction: restricted},
+			ttlMinutes: 60,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, users, businesses := newTestScopeAuth(t)
+			sessions := &fakeSessionTracker{}
+			s.SetSessionTracker(sessions)
+			scope := NewTokenScopeService(TokenScopeConfig{}, s, users, businesses)
+
+			req := &ScopedTokenRequest{APIs: []APIRule{{"/biz/orders/:id", "GET"}}, TTLMinutes: tt.ttlMinutes}
+			resp, err := scope.Issue(1, tt.current, req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Issue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(sessions.parents) != 0 {
+					t.Error("签发失败时不应创建会话")
+				}
+				return
+			}
+			if ttl := time.Until(time.Unix(resp.ExpiresAt, 0)); ttl > tt.wantTTL || ttl < tt.wantTTL-5*time.Second {
+				t.Errorf("Issue() 有效期 = %v, want %v", ttl, tt.wantTTL)
+			}
+			if len(sessions.parents) != 1 || sessions.parents[0] != tt.wantSession {
+				t.Errorf("上级会话 = %q, want %q", sessions.parents, tt.wantSession)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"errors"
+	"time"
 
 	"mcprapi/backend/internal/domain/entity"
 	"mcprapi/backend/internal/domain/repository"
@@ -13,6 +14,13 @@ type UserService struct {
 	userRepo repository.UserRepository
 	deptRepo repository.DepartmentRepository
 	roleRepo repository.RoleRepository
+
+	passwordValidator PasswordValidator
+}
+
+// PasswordValidator 密码复杂度校验
+type PasswordValidator interface {
+	ValidatePassword(username, password string) error
 }
 
 // NewUserService 创建用户服务
@@ -24,6 +32,11 @@ func NewUserService(userRepo repository.UserRepository, deptRepo repository.Depa
 	}
 }
 
+// SetPasswordValidator 设置创建用户时的密码复杂度校验
+func (s *UserService) SetPasswordValidator(validator PasswordValidator) {
+	s.passwordValidator = validator
+}
+
 // CreateUserRequest 创建用户请求
 type CreateUserRequest struct {
 	Username string `json:"username" binding:"required"`
@@ -33,6 +46,8 @@ type CreateUserRequest struct {
 	Avatar   string `json:"avatar"` // 头像URL，可选
 	DeptID   uint   `json:"dept_id" binding:"required"`
 	RoleIDs  []uint `json:"role_ids"`
+
+	SkipPasswordPolicy bool `json:"-"` // 系统生成的随机密码不做复杂度校验
 }
 
 // UpdateUserRequest 更新用户请求
@@ -60,15 +75,31 @@ func (s *UserService) CreateUser(req *CreateUserRequest) (*entity.User, error) {
 		return nil, errors.New("用户名已存在")
 	}
 
+	// 校验密码复杂度
+	if s.passwordValidator != nil && !req.SkipPasswordPolicy {
+		if err := s.passwordValidator.ValidatePassword(req.Username, req.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	// 加密密码
+	passwordHash, err := encrypt.HashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
 	// 创建用户
+	now := time.Now()
 	user := &entity.User{
 		Username: req.Username,
 		Name:     req.Name,
 		Email:    req.Email,
-		Password: encrypt.GenerateHash(req.Password), // 加密密码
+		Password: passwordHash,
 		Avatar:   req.Avatar, // 头像URL
 		DeptID:   req.DeptID,
 		Status:   1, // 默认启用
+
+		PasswordChangedAt: &now,
 	}
 
 	err = s.userRepo.Create(user)
@@ -211,6 +242,55 @@ func (s *UserService) ListAllUsers() ([]*entity.User, error) {
 	return s.userRepo.ListAll()
 }
 
+// LegacyPasswordUser 仍使用旧密码哈希的用户
+type LegacyPasswordUser struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Source   string `json:"source"`
+	Status   int    `json:"status"`
+	Scheme   string `json:"scheme"`
+}
+
+// LegacyPasswordReport 密码哈希迁移报告
+type LegacyPasswordReport struct {
+	Total    int                   `json:"total"`
+	Schemes  map[string]int        `json:"schemes"` // 各哈希算法的账号数量
+	Legacy   int                   `json:"legacy"`
+	Accounts []*LegacyPasswordUser `json:"accounts"` // 旧版SHA-256或无法识别格式的账号，需登录一次或重置密码
+}
+
+// GetLegacyPasswordReport 统计仍使用旧版密码哈希的账号
+func (s *UserService) GetLegacyPasswordReport() (*LegacyPasswordReport, error) {
+	users, err := s.userRepo.ListAll()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &LegacyPasswordReport{
+		Total:    len(users),
+		Schemes:  make(map[string]int),
+		Accounts: []*LegacyPasswordUser{},
+	}
+	for _, user := range users {
+		scheme := encrypt.PasswordScheme(user.Password)
+		report.Schemes[scheme]++
+		if scheme != encrypt.SchemeLegacy && scheme != encrypt.SchemeUnknown {
+			continue
+		}
+		report.Legacy++
+		report.Accounts = append(report.Accounts, &LegacyPasswordUser{
+			ID:       user.ID,
+			Username: user.Username,
+			Name:     user.Name,
+			Source:   user.Source,
+			Status:   user.Status,
+			Scheme:   scheme,
+		})
+	}
+	return report, nil
+}
+
 // AssignRolesRequest 分配角色请求
 type AssignRolesRequest struct {
 	UserID  uint   `json:"user_id" binding:"required"`
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// userStatusCacheTTL 用户状态的缓存时间，停用或删除的用户最迟在此时间后被拒绝
+const userStatusCacheTTL = 30 * time.Second
+
+// userStatusEntry 缓存的用户状态
+type userStatusEntry struct {
+	active       bool
+	tokenVersion int
+	expiresAt    time.Time
+}
+
+// UserStatusService 用户状态查询，供JWT中间件拒绝已停用或删除的用户，结果按userStatusCacheTTL缓存
+// 停用账号时应同时终止用户的会话，缓存只用于兜底没有会话的令牌和未走停用流程的状态修改
+type UserStatusService struct {
+	userRepo repository.UserRepository
+
+	mu      sync.Mutex
+	entries map[uint]userStatusEntry
+}
+
+// NewUserStatusService 创建用户状态查询服务
+func NewUserStatusService(userRepo repository.UserRepository) *UserStatusService {
+	return &UserStatusService{
+		userRepo: userRepo,
+		entries:  make(map[uint]userStatusEntry),
+	}
+}
+
+// Active 用户是否存在且处于启用状态
+func (s *UserStatusService) Active(userID uint) (bool, error) {
+	entry, err := s.load(userID)
+	if err != nil {
+		return false, err
+	}
+	return entry.active, nil
+}
+
+// TokenVersion 用户当前的Token版本号，用于校验没有会话的令牌；用户不存在时返回0
+func (s *UserStatusService) TokenVersion(userID uint) (int, error) {
+	entry, err := s.load(userID)
+	if err != nil {
+		return 0, err
+	}
+	return entry.tokenVersion, nil
+}
+
+// load 获取用户状态，缓存过期时重新查询
+func (s *UserStatusService) load(userID uint) (userStatusEntry, error) {
+	now := time.Now()
+	s.mu.Lock()
+	entry, ok := s.entries[userID]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry, nil
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return userStatusEntry{}, err
+	}
+	entry = userStatusEntry{expiresAt: now.Add(userStatusCacheTTL)}
+	if user != nil {
+		entry.active = user.Status == 1
+		entry.tokenVersion = user.TokenVersion
+	}
+
+	s.mu.Lock()
+	s.entries[userID] = entry
+	s.mu.Unlock()
+	return entry, nil
+}
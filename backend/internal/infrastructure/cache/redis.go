@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -149,4 +150,144 @@ func (r *Redis) HDelete(key, field string) error {
 // HExists 检查哈希表字段是否存在
 func (r *Redis) HExists(key, field string) (bool, error) {
 	return r.client.HExists(r.ctx, key, field).Result()
-}
\ No newline at end of file
+}
+
+// GetDel 获取并删除缓存，保证同一个值只会被读取一次
+func (r *Redis) GetDel(key string, value interface{}) error {
+	var get *redis.StringCmd
+	_, err := r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(r.ctx, key)
+		pipe.Del(r.ctx, key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := get.Bytes()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+// Update 乐观锁方式读取并更新缓存，保留原有过期时间
+// value为读取目标，fn修改value后写回；fn返回错误时放弃写入
+func (r *Redis) Update(key string, value interface{}, fn func() error) error {
+	return r.client.Watch(r.ctx, func(tx *redis.Tx) error {
+		data, err := tx.Get(r.ctx, key).Bytes()
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, value); err != nil {
+			return err
+		}
+		ttl, err := tx.PTTL(r.ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		if ttl <= 0 {
+			return redis.Nil
+		}
+
+		if err := fn(); err != nil {
+			return err
+		}
+		data, err = json.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(r.ctx, key, data, ttl)
+			return nil
+		})
+		return err
+	}, key)
+}
+
+// IsNotFound 判断是否为缓存不存在的错误
+func IsNotFound(err error) bool {
+	return errors.Is(err, redis.Nil)
+}
+
+// IsConflict 判断是否为Update期间键被并发修改的错误
+func IsConflict(err error) bool {
+	return errors.Is(err, redis.TxFailedErr)
+}
+
+// AddWindowEvent 在滑动窗口中记录一次事件，返回窗口内的事件数
+func (r *Redis) AddWindowEvent(key string, at time.Time, window time.Duration) (int64, error) {
+	var count *redis.IntCmd
+	_, err := r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(r.ctx, key, "-inf", fmt.Sprintf("(%d", at.Add(-window).UnixNano()))
+		pipe.ZAdd(r.ctx, key, &redis.Z{Score: float64(at.UnixNano()), Member: at.UnixNano()})
+		count = pipe.ZCard(r.ctx, key)
+		pipe.Expire(r.ctx, key, window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+// CountWindowEvents 统计滑动窗口内的事件数
+func (r *Redis) CountWindowEvents(key string, at time.Time, window time.Duration) (int64, error) {
+	return r.client.ZCount(r.ctx, key, fmt.Sprintf("%d", at.Add(-window).UnixNano()), "+inf").Result()
+}
+
+// TTL 获取缓存剩余有效期，不存在时返回0
+func (r *Redis) TTL(key string) (time.Duration, error) {
+	ttl, err := r.client.PTTL(r.ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// ZAdd 向有序集合添加成员
+func (r *Redis) ZAdd(key, member string, score float64) error {
+	return r.client.ZAdd(r.ctx, key, &redis.Z{Score: score, Member: member}).Err()
+}
+
+// ZRangeByScore 按分数范围获取有序集合成员，按分数升序
+func (r *Redis) ZRangeByScore(key string, min, max float64) ([]string, error) {
+	return r.client.ZRangeByScore(r.ctx, key, &redis.ZRangeBy{
+		Min: fmt.Sprintf("%f", min),
+		Max: fmt.Sprintf("%f", max),
+	}).Result()
+}
+
+// ZRem 从有序集合删除成员
+func (r *Redis) ZRem(key string, members ...string) error {
+	if len(members) == 0 {
+		return nil
+	}
+	values := make([]interface{}, len(members))
+	for i, member := range members {
+		values[i] = member
+	}
+	return r.client.ZRem(r.ctx, key, values...).Err()
+}
+
+// ZRemRangeByScore 删除分数范围内的有序集合成员
+func (r *Redis) ZRemRangeByScore(key string, min, max float64) error {
+	return r.client.ZRemRangeByScore(r.ctx, key, fmt.Sprintf("%f", min), fmt.Sprintf("%f", max)).Err()
+}
+
+// ExtendExpire 仅当新的有效期更长时延长缓存有效期
+func (r *Redis) ExtendExpire(key string, expiration time.Duration) error {
+	ttl, err := r.client.PTTL(r.ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	// -1表示永不过期，无需处理
+	if ttl == -1 || ttl >= expiration {
+		return nil
+	}
+	return r.client.Expire(r.ctx, key, expiration).Err()
+}
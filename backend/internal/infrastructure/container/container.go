@@ -1,6 +1,10 @@
 package container
 
 import (
+	"fmt"
+	"os"
+	"time"
+
 	"github.com/spf13/viper"
 	"gorm.io/gorm"
 
@@ -8,9 +12,12 @@ import (
 	"mcprapi/backend/internal/domain/service"
 	"mcprapi/backend/internal/infrastructure/cache"
 	"mcprapi/backend/internal/infrastructure/database"
+	"mcprapi/backend/internal/infrastructure/sso"
 	repo "mcprapi/backend/internal/infrastructure/repository"
+	"mcprapi/backend/internal/pkg/encrypt"
 	"mcprapi/backend/internal/pkg/logger"
 	"mcprapi/backend/pkg/casbinx"
+	"mcprapi/backend/pkg/ldapx"
 )
 
 // Container 依赖注入容器
@@ -36,18 +43,55 @@ type Container struct {
 	APIRepository        repository.APIRepository
 	DepartmentRepository repository.DepartmentRepository
 	BusinessRepository   repository.BusinessRepository
+	OAuthRepository      repository.OAuthRepository
+	IdentityRepository   repository.UserIdentityRepository
+	MFARepository        repository.UserMFARepository
+	AttemptRepository    repository.LoginAttemptRepository
+	PasswordHistoryRepo  repository.PasswordHistoryRepository
+	ImpersonationLogRepo repository.ImpersonationLogRepository
+	AuditLogRepository   repository.AuditLogRepository
+	AuthzDecisionRepo    repository.AuthzDecisionRepository
+	AuditTrailRepo       repository.AuditTrailRepository
+	APISpecSourceRepo    repository.APISpecSourceRepository
+	APIGroupRepo         repository.APIGroupRepository
+	PermissionBundleRepo repository.PermissionBundleRepository
+	DepartmentReorgRepo  repository.DepartmentReorgRepository
+	CascadeRepository    repository.CascadeRepository
 
 	// 服务
 	AuthService           *service.AuthService
 	UserService           *service.UserService
 	RoleService           *service.RoleService
 	APIService            *service.APIService
+	APIImportService      *service.APIImportService
+	APISyncService        *service.APISyncService
+	APILifecycleService   *service.APILifecycleService
+	APIGroupService       *service.APIGroupService
+	BundleService         *service.PermissionBundleService
+	SystemAPIService      *service.SystemAPIService
 	DepartmentService     *service.DepartmentService
+	DeptReorgService      *service.DepartmentReorgService
+	CascadeService        *service.CascadeService
 	BusinessService       *service.BusinessService
 	CasbinService         *service.CasbinService
 	DeptPermissionService *service.DeptPermissionService
 	DashboardService      *service.DashboardService
 	InitService           *service.InitService
+	OAuthService          *service.OAuthService
+	SSOService            *service.SSOService
+	LDAPService           *service.LDAPService // 未启用LDAP时为nil
+	SCIMService           *service.SCIMService
+	QRCodeService         *service.QRCodeService
+	MFAService            *service.MFAService
+	LoginGuardService     *service.LoginGuardService
+	PasswordService       *service.PasswordService
+	SessionService        *service.SessionService
+	UserStatusService     *service.UserStatusService
+	ImpersonationService  *service.ImpersonationService
+	TokenScopeService     *service.TokenScopeService
+	AuditService          *service.AuditService
+	DecisionLogger        *service.DecisionLogger
+	AuditTrailService     *service.AuditTrailService
 }
 
 // New 创建依赖注入容器
@@ -62,6 +106,11 @@ func New(config *viper.Viper) (*Container, error) {
 		return nil, err
 	}
 
+	// 初始化密码哈希算法（数据库初始化时会创建默认用户）
+	if err := container.initPasswordHasher(); err != nil {
+		return nil, err
+	}
+
 	// 初始化数据库
 	if err := container.initDB(); err != nil {
 		return nil, err
@@ -80,8 +129,15 @@ func New(config *viper.Viper) (*Container, error) {
 	// 初始化仓库
 	container.initRepository()
 
+	// 补齐部门的物化路径（旧数据或直接写库创建的部门没有路径）
+	if err := container.DepartmentRepository.RebuildPaths(); err != nil {
+		container.Logger.Error("重建部门路径失败: %v", err)
+	}
+
 	// 初始化服务
-	container.initService()
+	if err := container.initService(); err != nil {
+		return nil, err
+	}
 
 	return container, nil
 }
@@ -106,6 +162,27 @@ func (c *Container) initLogger() error {
 	return nil
 }
 
+// initPasswordHasher 初始化密码哈希算法
+func (c *Container) initPasswordHasher() error {
+	switch scheme := c.Config.GetString("password.hasher"); scheme {
+	case "", encrypt.SchemeArgon2id:
+		var params encrypt.Argon2idHasher
+		if err := c.Config.UnmarshalKey("password.argon2id", &params); err != nil {
+			return fmt.Errorf("解析argon2id配置失败: %v", err)
+		}
+		encrypt.SetPasswordHasher(encrypt.NewArgon2idHasher(params))
+	case encrypt.SchemeBcrypt:
+		var params encrypt.BcryptHasher
+		if err := c.Config.UnmarshalKey("password.bcrypt", &params); err != nil {
+			return fmt.Errorf("解析bcrypt配置失败: %v", err)
+		}
+		encrypt.SetPasswordHasher(encrypt.NewBcryptHasher(params))
+	default:
+		return fmt.Errorf("不支持的密码哈希算法: %s", scheme)
+	}
+	return nil
+}
+
 // initDB 初始化数据库
 func (c *Container) initDB() error {
 	// 获取数据库配置
@@ -180,21 +257,315 @@ func (c *Container) initRepository() {
 	c.APIRepository = repo.NewAPIRepository(c.DB)
 	c.DepartmentRepository = repo.NewDepartmentRepository(c.DB)
 	c.BusinessRepository = repo.NewBusinessRepository(c.DB)
+	c.OAuthRepository = repo.NewOAuthRepository(c.DB)
+	c.IdentityRepository = repo.NewUserIdentityRepository(c.DB)
+	c.MFARepository = repo.NewUserMFARepository(c.DB)
+	c.AttemptRepository = repo.NewLoginAttemptRepository(c.DB)
+	c.PasswordHistoryRepo = repo.NewPasswordHistoryRepository(c.DB)
+	c.ImpersonationLogRepo = repo.NewImpersonationLogRepository(c.DB)
+	c.AuditLogRepository = repo.NewAuditLogRepository(c.DB)
+	c.AuthzDecisionRepo = repo.NewAuthzDecisionRepository(c.DB)
+	c.AuditTrailRepo = repo.NewAuditTrailRepository(c.DB)
+	c.APISpecSourceRepo = repo.NewAPISpecSourceRepository(c.DB)
+	c.APIGroupRepo = repo.NewAPIGroupRepository(c.DB)
+	c.PermissionBundleRepo = repo.NewPermissionBundleRepository(c.DB)
+	c.DepartmentReorgRepo = repo.NewDepartmentReorgRepository(c.DB)
+	c.CascadeRepository = repo.NewCascadeRepository(c.DB)
 }
 
 // initService 初始化服务
-func (c *Container) initService() {
+func (c *Container) initService() error {
 	jwtSecret := c.Config.GetString("jwt.secret")
 	c.AuthService = service.NewAuthService(c.UserRepository, c.RoleRepository, c.APIRepository, c.Enforcer, jwtSecret)
+	c.SessionService = service.NewSessionService(c.Redis)
+	c.AuthService.SetSessionTracker(c.SessionService)
+	c.UserStatusService = service.NewUserStatusService(c.UserRepository)
 	c.UserService = service.NewUserService(c.UserRepository, c.DepartmentRepository, c.RoleRepository)
 	c.RoleService = service.NewRoleService(c.RoleRepository, c.DepartmentRepository, c.APIRepository, c.UserRepository, c.Enforcer)
 	c.APIService = service.NewAPIService(c.APIRepository, c.BusinessRepository, c.UserRepository, c.DepartmentRepository)
+	c.APIImportService = service.NewAPIImportService(c.APIRepository, c.BusinessRepository, c.DepartmentRepository, c.RoleRepository, c.RoleService)
+
+	// 服务自身路由登记为系统API
+	var systemAPIConfig service.SystemAPIConfig
+	if err := c.Config.UnmarshalKey("system_api", &systemAPIConfig); err != nil {
+		c.Logger.Error("解析系统API配置失败: %v", err)
+	}
+	c.SystemAPIService = service.NewSystemAPIService(systemAPIConfig, c.APIRepository, c.BusinessRepository, c.DepartmentRepository, c.Logger)
+	c.RoleService.SetSystemAPIs(c.SystemAPIService)
 	c.DepartmentService = service.NewDepartmentService(c.DepartmentRepository)
+	c.DeptReorgService = service.NewDepartmentReorgService(c.DepartmentReorgRepo, c.DepartmentRepository, c.RoleRepository, c.APIRepository, c.Enforcer)
 	c.BusinessService = service.NewBusinessService(c.BusinessRepository, c.DepartmentRepository)
 	c.CasbinService = service.NewCasbinService(c.Enforcer, c.DB)
 	c.DeptPermissionService = service.NewDeptPermissionService(c.UserRepository, c.RoleRepository, c.DepartmentRepository, c.Enforcer)
+	c.DeptPermissionService.SetSystemGrantApplier(c.RoleService)
 	c.DashboardService = service.NewDashboardService(c.APIService, c.BusinessService, c.DepartmentService, c.UserService, c.APIRepository)
 	c.InitService = service.NewInitService(c.DB, c.Enforcer)
+	c.AuditService = service.NewAuditService(c.AuditLogRepository, c.UserRepository, c.RoleRepository, c.APIRepository, c.DepartmentRepository,
+		c.BusinessRepository, c.APISpecSourceRepo, c.APIGroupRepo, c.PermissionBundleRepo, c.OAuthRepository, c.CasbinService, c.Enforcer, c.Logger)
+
+	// API组按组授权，角色权限被整体替换后恢复组内策略
+	c.APIGroupService = service.NewAPIGroupService(c.APIGroupRepo, c.APIRepository, c.RoleRepository, c.BusinessRepository, c.Enforcer)
+	c.RoleService.SetAPIGroupCompiler(c.APIGroupService)
+
+	// 权限模板
+	var bundleConfig service.PermissionBundleConfig
+	if err := c.Config.UnmarshalKey("permission_bundle", &bundleConfig); err != nil {
+		c.Logger.Error("解析权限模板配置失败: %v", err)
+	}
+	c.BundleService = service.NewPermissionBundleService(bundleConfig, c.PermissionBundleRepo, c.APIRepository, c.RoleRepository,
+		c.BusinessRepository, c.Enforcer, c.Logger)
+	c.BundleService.SetAPIGroupCompiler(c.APIGroupService)
+	c.BundleService.SetAuditRecorder(c.AuditService)
+	c.APIService.SetAPICategoryReferrer(c.BundleService)
+
+	// 删除部门、业务线、角色和API时按级联和限制规则处理关联数据
+	var cascadeConfig service.CascadeConfig
+	if err := c.Config.UnmarshalKey("cascade", &cascadeConfig); err != nil {
+		c.Logger.Error("解析级联删除配置失败: %v", err)
+	}
+	c.CascadeService = service.NewCascadeService(cascadeConfig, c.CascadeRepository, c.DepartmentRepository, c.BusinessRepository,
+		c.RoleRepository, c.APIRepository, c.UserRepository, c.APIGroupRepo, c.Enforcer, c.Logger)
+	c.CascadeService.SetAuditRecorder(c.AuditService)
+	c.DepartmentService.SetDeleter(c.CascadeService)
+	c.BusinessService.SetDeleter(c.CascadeService)
+	c.RoleService.SetDeleter(c.CascadeService)
+	c.APIService.SetDeleter(c.CascadeService)
+
+	// 接口文档持续同步
+	var apiSyncConfig service.APISyncConfig
+	if err := c.Config.UnmarshalKey("api_sync", &apiSyncConfig); err != nil {
+		c.Logger.Error("解析接口文档同步配置失败: %v", err)
+	}
+	c.APISyncService = service.NewAPISyncService(apiSyncConfig, c.APISpecSourceRepo, c.BusinessRepository, c.DepartmentRepository,
+		c.APIRepository, c.APIImportService, c.CasbinService, c.Logger)
+
+	// OAuth2令牌和授权码有效期（秒）
+	oauthTokenTTL := c.Config.GetInt("oauth.access_token_ttl")
+	if oauthTokenTTL <= 0 {
+		oauthTokenTTL = 3600
+	}
+	oauthCodeTTL := c.Config.GetInt("oauth.code_ttl")
+	if oauthCodeTTL <= 0 {
+		oauthCodeTTL = 600
+	}
+	c.OAuthService = service.NewOAuthService(c.OAuthRepository, c.UserRepository, c.RoleRepository, c.BusinessRepository, c.AuthService, c.Redis,
+		time.Duration(oauthTokenTTL)*time.Second, time.Duration(oauthCodeTTL)*time.Second)
+
+	c.SSOService = service.NewSSOService(c.initSSOProviders(), c.IdentityRepository, c.UserRepository, c.DepartmentRepository, c.RoleRepository, c.AuthService, c.Redis)
+
+	// 扫码登录二维码有效期（秒）
+	qrCodeTTL := c.Config.GetInt("qrcode.ttl")
+	if qrCodeTTL <= 0 {
+		qrCodeTTL = 120
+	}
+	c.QRCodeService = service.NewQRCodeService(c.AuthService, c.Redis, time.Duration(qrCodeTTL)*time.Second)
+
+	// 多因素认证，未配置加密密钥时使用JWT密钥加密TOTP密钥
+	var mfaConfig service.MFAConfig
+	if err := c.Config.UnmarshalKey("mfa", &mfaConfig); err != nil {
+		c.Logger.Error("解析MFA配置失败: %v", err)
+	}
+	if mfaConfig.EncryptionKey == "" {
+		mfaConfig.EncryptionKey = jwtSecret
+	}
+	c.MFAService = service.NewMFAService(c.MFARepository, c.UserRepository, c.AuthService, c.Redis, mfaConfig)
+	c.AuthService.SetLoginChallenger(c.MFAService)
+
+	// 登录防暴力破解
+	var loginGuardConfig service.LoginGuardConfig
+	if err := c.Config.UnmarshalKey("login_guard", &loginGuardConfig); err != nil {
+		c.Logger.Error("解析登录防护配置失败: %v", err)
+	}
+	c.LoginGuardService = service.NewLoginGuardService(loginGuardConfig, c.AttemptRepository, c.UserRepository, c.Redis, c.Logger)
+	c.AuthService.SetLoginGuard(c.LoginGuardService)
+
+	c.initPasswordPolicy()
+	c.initDecisionLogger()
+	if err := c.initAuditTrail(); err != nil {
+		return err
+	}
+	c.initAPILifecycle()
+
+	// 管理员代登录
+	var impersonationConfig service.ImpersonationConfig
+	if err := c.Config.UnmarshalKey("impersonation", &impersonationConfig); err != nil {
+		c.Logger.Error("解析代登录配置失败: %v", err)
+	}
+	c.ImpersonationService = service.NewImpersonationService(impersonationConfig, c.AuthService, c.SessionService, c.UserRepository, c.ImpersonationLogRepo, c.Logger)
+
+	// 受限令牌
+	var tokenScopeConfig service.TokenScopeConfig
+	if err := c.Config.UnmarshalKey("token_scope", &tokenScopeConfig); err != nil {
+		c.Logger.Error("解析受限令牌配置失败: %v", err)
+	}
+	c.TokenScopeService = service.NewTokenScopeService(tokenScopeConfig, c.AuthService, c.UserRepository, c.BusinessRepository)
+
+	c.SCIMService = service.NewSCIMService(c.UserService, c.RoleService, c.UserRepository, c.DepartmentRepository, c.RoleRepository, c.Config.GetString("scim.default_dept_code"))
+	c.SCIMService.SetSessionRevoker(c.SessionService)
+	c.SCIMService.SetPasswordSetter(c.PasswordService)
+
+	c.initLDAP()
+	return nil
+}
+
+// initPasswordPolicy 初始化密码策略、过期强制修改和密码重置
+func (c *Container) initPasswordPolicy() {
+	var policy service.PasswordPolicy
+	if err := c.Config.UnmarshalKey("password.policy", &policy); err != nil {
+		c.Logger.Error("解析密码策略配置失败: %v", err)
+	}
+	var resetConfig service.PasswordResetConfig
+	if err := c.Config.UnmarshalKey("password.reset", &resetConfig); err != nil {
+		c.Logger.Error("解析密码重置配置失败: %v", err)
+	}
+
+	// 重置令牌下发渠道：log写入服务日志，file追加到文件
+	var notifier service.PasswordResetNotifier
+	switch channel := c.Config.GetString("password.reset.notifier"); channel {
+	case "", "log":
+		notifier = service.NewLogPasswordResetNotifier(c.Logger)
+	case "file":
+		filePath := c.Config.GetString("password.reset.file_path")
+		if filePath == "" {
+			filePath = "logs/password_reset.jsonl"
+		}
+		notifier = service.NewFilePasswordResetNotifier(filePath)
+	default:
+		c.Logger.Error("不支持的密码重置通知渠道: %s，使用日志通知", channel)
+		notifier = service.NewLogPasswordResetNotifier(c.Logger)
+	}
+
+	c.PasswordService = service.NewPasswordService(policy, resetConfig, c.UserRepository, c.PasswordHistoryRepo, c.AuthService, c.Redis, notifier, c.Logger)
+	c.UserService.SetPasswordValidator(c.PasswordService)
+	c.AuthService.SetPasswordChallenger(c.PasswordService)
+	c.PasswordService.SetSessionRevoker(c.SessionService)
+}
+
+// initDecisionLogger 初始化授权决策日志，未配置输出时不记录
+func (c *Container) initDecisionLogger() {
+	var cfg service.DecisionLogConfig
+	if err := c.Config.UnmarshalKey("decision_log", &cfg); err != nil {
+		c.Logger.Error("解析授权决策日志配置失败: %v", err)
+	}
+
+	var sinks []service.DecisionSink
+	for _, name := range cfg.Sinks {
+		switch name {
+		case "db":
+			sinks = append(sinks, service.NewDBDecisionSink(c.AuthzDecisionRepo))
+		case "file":
+			filePath := cfg.File.Path
+			if filePath == "" {
+				filePath = "logs/authz_decisions.jsonl"
+			}
+			sinks = append(sinks, service.NewFileDecisionSink(filePath, cfg.File.MaxSizeMB, cfg.File.MaxBackups))
+		case "stdout":
+			sinks = append(sinks, service.NewWriterDecisionSink("stdout", os.Stdout))
+		default:
+			c.Logger.Error("不支持的授权决策日志输出: %s", name)
+		}
+	}
+
+	c.DecisionLogger = service.NewDecisionLogger(cfg, sinks, c.AuthzDecisionRepo, c.Logger)
+	c.AuthService.SetDecisionLogger(c.DecisionLogger)
+}
+
+// initAuditTrail 初始化防篡改审计链，审计日志写入后同时追加到审计链；签名密钥无效或无法保证审计链只追加时启动失败
+func (c *Container) initAuditTrail() error {
+	var cfg service.AuditTrailConfig
+	if err := c.Config.UnmarshalKey("audit_trail", &cfg); err != nil {
+		return fmt.Errorf("解析审计链配置失败: %v", err)
+	}
+
+	jwtSecret := c.Config.GetString("jwt.secret")
+	trailService, err := service.NewAuditTrailService(cfg, c.AuditTrailRepo, jwtSecret, c.Logger)
+	if err != nil {
+		return fmt.Errorf("初始化审计链失败: %v", err)
+	}
+	c.AuditTrailService = trailService
+	c.AuditService.SetTrail(c.AuditTrailService)
+
+	// 数据库层禁止修改和删除审计链记录
+	if err := c.AuditTrailRepo.EnsureAppendOnly(); err != nil {
+		return fmt.Errorf("创建审计链只追加触发器失败: %v", err)
+	}
+	return nil
+}
+
+// initAPILifecycle 初始化API生命周期，权限校验拒绝已下线的API
+func (c *Container) initAPILifecycle() {
+	var cfg service.APILifecycleConfig
+	if err := c.Config.UnmarshalKey("api_lifecycle", &cfg); err != nil {
+		c.Logger.Error("解析API生命周期配置失败: %v", err)
+	}
+
+	// 下线提醒渠道：log写入服务日志，file追加到文件
+	var notifier service.APISunsetNotifier
+	switch channel := c.Config.GetString("api_lifecycle.notifier"); channel {
+	case "", "log":
+		notifier = service.NewLogAPISunsetNotifier(c.Logger)
+	case "file":
+		filePath := c.Config.GetString("api_lifecycle.file_path")
+		if filePath == "" {
+			filePath = "logs/api_sunset.jsonl"
+		}
+		notifier = service.NewFileAPISunsetNotifier(filePath)
+	default:
+		c.Logger.Error("不支持的API下线提醒渠道: %s，使用日志通知", channel)
+		notifier = service.NewLogAPISunsetNotifier(c.Logger)
+	}
+
+	c.APILifecycleService = service.NewAPILifecycleService(cfg, c.APIRepository, c.BusinessRepository, notifier, c.Logger)
+	c.AuthService.SetAPILifecycle(c.APILifecycleService)
+}
+
+// initLDAP 初始化LDAP认证和目录同步，未启用时跳过
+func (c *Container) initLDAP() {
+	if !c.Config.GetBool("ldap.enabled") {
+		return
+	}
+
+	var ldapConfig ldapx.Config
+	if err := c.Config.UnmarshalKey("ldap", &ldapConfig); err != nil {
+		c.Logger.Error("解析LDAP配置失败: %v", err)
+		return
+	}
+	client, err := ldapx.NewClient(ldapConfig)
+	if err != nil {
+		c.Logger.Error("初始化LDAP客户端失败: %v", err)
+		return
+	}
+
+	var syncConfig service.LDAPSyncConfig
+	if err := c.Config.UnmarshalKey("ldap.sync", &syncConfig); err != nil {
+		c.Logger.Error("解析LDAP同步配置失败: %v", err)
+		return
+	}
+
+	c.LDAPService = service.NewLDAPService(client, syncConfig, c.UserRepository, c.DepartmentRepository, c.RoleRepository, c.Logger)
+	c.LDAPService.SetSessionRevoker(c.SessionService)
+	c.LDAPService.SetAuditRecorder(c.AuditService)
+	c.AuthService.SetExternalAuthenticator(c.LDAPService)
+}
+
+// initSSOProviders 初始化外部登录提供方，配置错误的提供方会被跳过
+func (c *Container) initSSOProviders() []sso.Provider {
+	var configs []sso.ProviderConfig
+	if err := c.Config.UnmarshalKey("sso.providers", &configs); err != nil {
+		c.Logger.Error("解析外部登录配置失败: %v", err)
+		return nil
+	}
+
+	var providers []sso.Provider
+	for _, cfg := range configs {
+		provider, err := sso.NewProvider(cfg)
+		if err != nil {
+			c.Logger.Error("初始化外部登录提供方失败: %v", err)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return providers
 }
 
 // Close 关闭容器
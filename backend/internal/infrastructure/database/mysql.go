@@ -114,6 +114,27 @@ func AutoMigrate(db *gorm.DB) error {
 		&entity.Business{},
 		&entity.API{},
 		&entity.APICategory{},
+		&entity.OAuthClient{},
+		&entity.OAuthConsent{},
+		&entity.UserIdentity{},
+		&entity.UserMFA{},
+		&entity.LoginAttempt{},
+		&entity.PasswordHistory{},
+		&entity.ImpersonationLog{},
+		&entity.AuditLog{},
+		&entity.AuthzDecision{},
+		&entity.AuditTrailEntry{},
+		&entity.AuditCheckpoint{},
+		&entity.AuditSigningKey{},
+		&entity.APISpecSource{},
+		&entity.APIGroup{},
+		&entity.APIGroupMember{},
+		&entity.RoleAPIGroup{},
+		&entity.APIGroupPolicy{},
+		&entity.PermissionBundle{},
+		&entity.PermissionBundleVersion{},
+		&entity.RolePermissionBundle{},
+		&entity.PermissionBundlePolicy{},
 	)
 }
 
@@ -162,12 +183,16 @@ func InitData(db *gorm.DB) error {
 			return err
 		}
 
-		// 创建默认用户
+		// 创建默认用户，初始密码为123456
+		defaultPassword, err := encrypt.HashPassword("123456")
+		if err != nil {
+			return err
+		}
 		admin := &entity.User{
 			Username: "admin",
 			Name:     "管理员",
 			Email:    "admin@example.com",
-			Password: encrypt.GenerateHash("123456"), // 使用123456作为密码
+			Password: defaultPassword,
 			DeptID:   department.ID,
 			Status:   1,
 		}
@@ -180,7 +205,7 @@ func InitData(db *gorm.DB) error {
 			Username: "member",
 			Name:     "普通用户",
 			Email:    "member@example.com",
-			Password: encrypt.GenerateHash("123456"), // 使用123456作为密码
+			Password: defaultPassword,
 			DeptID:   department.ID,
 			Status:   1,
 		}
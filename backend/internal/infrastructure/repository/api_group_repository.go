@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// APIGroupRepositoryImpl API组仓库实现
+type APIGroupRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewAPIGroupRepository 创建API组仓库
+func NewAPIGroupRepository(db *gorm.DB) repository.APIGroupRepository {
+	return &APIGroupRepositoryImpl{db: db}
+}
+
+// Create 创建API组
+func (r *APIGroupRepositoryImpl) Create(group *entity.APIGroup) error {
+	return r.db.Create(group).Error
+}
+
+// Update 更新API组
+func (r *APIGroupRepositoryImpl) Update(group *entity.APIGroup) error {
+	return r.db.Save(group).Error
+}
+
+// Delete 删除API组及其成员和角色授权
+func (r *APIGroupRepositoryImpl) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", id).Delete(&entity.APIGroupMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", id).Delete(&entity.RoleAPIGroup{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entity.APIGroup{}, id).Error
+	})
+}
+
+// GetByID 根据ID获取API组
+func (r *APIGroupRepositoryImpl) GetByID(id uint) (*entity.APIGroup, error) {
+	var group entity.APIGroup
+	if err := r.db.First(&group, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetByCode 根据编码获取API组
+func (r *APIGroupRepositoryImpl) GetByCode(code string) (*entity.APIGroup, error) {
+	var group entity.APIGroup
+	if err := r.db.Where("code = ?", code).First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// List 获取API组列表
+func (r *APIGroupRepositoryImpl) List(businessID uint) ([]*entity.APIGroup, error) {
+	var groups []*entity.APIGroup
+	db := r.db
+	if businessID > 0 {
+		db = db.Where("business_id = ?", businessID)
+	}
+	if err := db.Order("code").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// ListAPIs 获取API组内的API
+func (r *APIGroupRepositoryImpl) ListAPIs(groupID uint) ([]*entity.API, error) {
+	var apis []*entity.API
+	err := r.db.Joins("JOIN api_group_members ON api_group_members.api_id = apis.id").
+		Where("api_group_members.group_id = ?", groupID).
+		Order("apis.path, apis.method").
+		Find(&apis).Error
+	if err != nil {
+		return nil, err
+	}
+	return apis, nil
+}
+
+// AddAPIs 向API组添加API
+func (r *APIGroupRepositoryImpl) AddAPIs(groupID uint, apiIDs []uint) error {
+	if len(apiIDs) == 0 {
+		return nil
+	}
+	members := make([]*entity.APIGroupMember, 0, len(apiIDs))
+	for _, apiID := range apiIDs {
+		members = append(members, &entity.APIGroupMember{GroupID: groupID, APIID: apiID})
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&members).Error
+}
+
+// RemoveAPIs 从API组移除API
+func (r *APIGroupRepositoryImpl) RemoveAPIs(groupID uint, apiIDs []uint) error {
+	if len(apiIDs) == 0 {
+		return nil
+	}
+	return r.db.Where("group_id = ? AND api_id IN ?", groupID, apiIDs).Delete(&entity.APIGroupMember{}).Error
+}
+
+// ListGrantsByRole 获取角色被授予的API组
+func (r *APIGroupRepositoryImpl) ListGrantsByRole(roleID uint) ([]*entity.RoleAPIGroup, error) {
+	var grants []*entity.RoleAPIGroup
+	if err := r.db.Where("role_id = ?", roleID).Order("group_id").Find(&grants).Error; err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// ListGrantsByGroup 获取被授予API组的角色
+func (r *APIGroupRepositoryImpl) ListGrantsByGroup(groupID uint) ([]*entity.RoleAPIGroup, error) {
+	var grants []*entity.RoleAPIGroup
+	if err := r.db.Where("group_id = ?", groupID).Order("role_id").Find(&grants).Error; err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// SetRoleGrants 替换角色被授予的API组
+func (r *APIGroupRepositoryImpl) SetRoleGrants(roleID uint, grants []*entity.RoleAPIGroup) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", roleID).Delete(&entity.RoleAPIGroup{}).Error; err != nil {
+			return err
+		}
+		if len(grants) == 0 {
+			return nil
+		}
+		return tx.Create(&grants).Error
+	})
+}
+
+// ListPolicies 获取由API组编译生成的角色策略
+func (r *APIGroupRepositoryImpl) ListPolicies(roleID uint) ([]*entity.APIGroupPolicy, error) {
+	var policies []*entity.APIGroupPolicy
+	if err := r.db.Where("role_id = ?", roleID).Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// SetPolicies 替换由API组编译生成的角色策略记录
+func (r *APIGroupRepositoryImpl) SetPolicies(roleID uint, policies []*entity.APIGroupPolicy) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", roleID).Delete(&entity.APIGroupPolicy{}).Error; err != nil {
+			return err
+		}
+		if len(policies) == 0 {
+			return nil
+		}
+		return tx.Create(&policies).Error
+	})
+}
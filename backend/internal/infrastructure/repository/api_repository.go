@@ -156,6 +156,15 @@ func (r *APIRepositoryImpl) ListByBusiness(businessID uint) ([]*entity.API, erro
 	return apis, nil
 }
 
+// ListByLifecycle 获取处于指定生命周期的API列表
+func (r *APIRepositoryImpl) ListByLifecycle(lifecycles ...string) ([]*entity.API, error) {
+	var apis []*entity.API
+	if err := r.db.Where("lifecycle IN ?", lifecycles).Find(&apis).Error; err != nil {
+		return nil, err
+	}
+	return apis, nil
+}
+
 // ListCategories 获取API分类列表
 func (r *APIRepositoryImpl) ListCategories() ([]*entity.APICategory, error) {
 	var categories []*entity.APICategory
@@ -240,6 +249,54 @@ func (r *APIRepositoryImpl) GetAPIsByCategoryID(categoryID uint) ([]*entity.API,
 	return apis, nil
 }
 
+// CountByCategory 按分类ID统计API数量
+func (r *APIRepositoryImpl) CountByCategory() (map[uint]int64, error) {
+	var rows []struct {
+		CategoryID uint
+		Count      int64
+	}
+	if err := r.db.Model(&entity.API{}).
+		Select("category_id, count(*) as count").
+		Group("category_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.CategoryID] = row.Count
+	}
+	return counts, nil
+}
+
+// SortCategories 批量设置分类的父分类和排序
+func (r *APIRepositoryImpl) SortCategories(parentID uint, sorts map[uint]int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for id, sort := range sorts {
+			if err := tx.Model(&entity.APICategory{}).Where("id = ?", id).
+				Updates(map[string]interface{}{"parent_id": parentID, "sort": sort}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteCategoryAndReassign 删除分类，并将其API和子分类转移到目标分类
+func (r *APIRepositoryImpl) DeleteCategoryAndReassign(id, targetID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.API{}).Where("category_id = ?", id).
+			Update("category_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&entity.APICategory{}).Where("parent_id = ?", id).
+			Update("parent_id", targetID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entity.APICategory{}, id).Error
+	})
+}
+
 // ListWithDepartmentFilter 根据部门过滤获取API列表
 func (r *APIRepositoryImpl) ListWithDepartmentFilter(page, pageSize int, query string, businessIDs []uint, category string) ([]*entity.API, int64, error) {
 	var apis []*entity.API
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// APISpecSourceRepositoryImpl 接口文档来源仓库实现
+type APISpecSourceRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewAPISpecSourceRepository 创建接口文档来源仓库
+func NewAPISpecSourceRepository(db *gorm.DB) repository.APISpecSourceRepository {
+	return &APISpecSourceRepositoryImpl{db: db}
+}
+
+// Create 创建来源
+func (r *APISpecSourceRepositoryImpl) Create(source *entity.APISpecSource) error {
+	return r.db.Create(source).Error
+}
+
+// Update 更新来源
+func (r *APISpecSourceRepositoryImpl) Update(source *entity.APISpecSource) error {
+	return r.db.Save(source).Error
+}
+
+// Delete 删除来源
+func (r *APISpecSourceRepositoryImpl) Delete(id uint) error {
+	return r.db.Delete(&entity.APISpecSource{}, id).Error
+}
+
+// GetByBusiness 获取业务线的来源
+func (r *APISpecSourceRepositoryImpl) GetByBusiness(businessID uint) (*entity.APISpecSource, error) {
+	var source entity.APISpecSource
+	if err := r.db.Where("business_id = ?", businessID).First(&source).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &source, nil
+}
+
+// List 获取所有来源
+func (r *APISpecSourceRepositoryImpl) List() ([]*entity.APISpecSource, error) {
+	var sources []*entity.APISpecSource
+	if err := r.db.Order("business_id").Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// AuditLogRepositoryImpl 审计日志仓库实现
+type AuditLogRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository 创建审计日志仓库
+func NewAuditLogRepository(db *gorm.DB) repository.AuditLogRepository {
+	return &AuditLogRepositoryImpl{db: db}
+}
+
+// Create 创建日志
+func (r *AuditLogRepositoryImpl) Create(log *entity.AuditLog) error {
+	return r.db.Create(log).Error
+}
+
+// List 分页查询日志
+func (r *AuditLogRepositoryImpl) List(query *repository.AuditLogQuery) ([]*entity.AuditLog, int64, error) {
+	db := r.db.Model(&entity.AuditLog{})
+	if query.ActorID > 0 {
+		db = db.Where("actor_id = ?", query.ActorID)
+	}
+	if query.ActorUsername != "" {
+		db = db.Where("actor_username = ?", query.ActorUsername)
+	}
+	if query.Action != "" {
+		db = db.Where("action = ?", query.Action)
+	}
+	if query.TargetType != "" {
+		db = db.Where("target_type = ?", query.TargetType)
+	}
+	if query.TargetID != "" {
+		db = db.Where("target_id = ?", query.TargetID)
+	}
+	if query.DeptID > 0 {
+		db = db.Where("dept_id = ?", query.DeptID)
+	}
+	if query.Result != "" {
+		db = db.Where("result = ?", query.Result)
+	}
+	if query.RequestID != "" {
+		db = db.Where("request_id = ?", query.RequestID)
+	}
+	if query.StartTime != nil {
+		db = db.Where("created_at >= ?", *query.StartTime)
+	}
+	if query.EndTime != nil {
+		db = db.Where("created_at < ?", *query.EndTime)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []*entity.AuditLog
+	err := db.Order("id DESC").Offset((query.Page - 1) * query.PageSize).Limit(query.PageSize).Find(&logs).Error
+	return logs, total, err
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// auditTrailAppendRetries 并发追加导致序号冲突时的重试次数
+const auditTrailAppendRetries = 3
+
+// AuditTrailRepositoryImpl 防篡改审计链仓库实现
+type AuditTrailRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewAuditTrailRepository 创建防篡改审计链仓库
+func NewAuditTrailRepository(db *gorm.DB) repository.AuditTrailRepository {
+	return &AuditTrailRepositoryImpl{db: db}
+}
+
+// Append 追加记录；多个实例同时追加时序号主键冲突，重新读取链尾后重试
+func (r *AuditTrailRepositoryImpl) Append(build func(last *entity.AuditTrailEntry) (*entity.AuditTrailEntry, error)) (*entity.AuditTrailEntry, error) {
+	var err error
+	for i := 0; i < auditTrailAppendRetries; i++ {
+		var entry *entity.AuditTrailEntry
+		err = r.db.Transaction(func(tx *gorm.DB) error {
+			var entries []*entity.AuditTrailEntry
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Order("seq DESC").Limit(1).Find(&entries).Error; err != nil {
+				return err
+			}
+			var last *entity.AuditTrailEntry
+			if len(entries) > 0 {
+				last = entries[0]
+			}
+
+			var err error
+			entry, err = build(last)
+			if err != nil {
+				return err
+			}
+			return tx.Create(entry).Error
+		})
+		if err == nil {
+			return entry, nil
+		}
+		if !isDuplicateKey(err) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// Last 获取链尾记录
+func (r *AuditTrailRepositoryImpl) Last() (*entity.AuditTrailEntry, error) {
+	var entries []*entity.AuditTrailEntry
+	if err := r.db.Order("seq DESC").Limit(1).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	return entries[0], nil
+}
+
+// ListAfter 按序号升序获取记录
+func (r *AuditTrailRepositoryImpl) ListAfter(afterSeq uint64, limit int) ([]*entity.AuditTrailEntry, error) {
+	var entries []*entity.AuditTrailEntry
+	err := r.db.Where("seq > ?", afterSeq).Order("seq ASC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+// CreateCheckpoint 创建检查点
+func (r *AuditTrailRepositoryImpl) CreateCheckpoint(checkpoint *entity.AuditCheckpoint) error {
+	return r.db.Create(checkpoint).Error
+}
+
+// LastCheckpoint 获取最近的检查点
+func (r *AuditTrailRepositoryImpl) LastCheckpoint() (*entity.AuditCheckpoint, error) {
+	var checkpoints []*entity.AuditCheckpoint
+	if err := r.db.Order("seq DESC, id DESC").Limit(1).Find(&checkpoints).Error; err != nil {
+		return nil, err
+	}
+	if len(checkpoints) == 0 {
+		return nil, nil
+	}
+	return checkpoints[0], nil
+}
+
+// ListCheckpoints 按序号升序获取所有检查点
+func (r *AuditTrailRepositoryImpl) ListCheckpoints() ([]*entity.AuditCheckpoint, error) {
+	var checkpoints []*entity.AuditCheckpoint
+	err := r.db.Order("seq ASC, id ASC").Find(&checkpoints).Error
+	return checkpoints, err
+}
+
+// SaveSigningKey 登记签名公钥，已登记时跳过
+// 不使用ON DUPLICATE KEY UPDATE，重复时会触发只追加表的UPDATE触发器
+func (r *AuditTrailRepositoryImpl) SaveSigningKey(key *entity.AuditSigningKey) error {
+	var count int64
+	if err := r.db.Model(&entity.AuditSigningKey{}).Where("key_id = ?", key.KeyID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	if err := r.db.Create(key).Error; err != nil && !isDuplicateKey(err) {
+		return err
+	}
+	return nil
+}
+
+// ListSigningKeys 获取所有登记的签名公钥
+func (r *AuditTrailRepositoryImpl) ListSigningKeys() ([]*entity.AuditSigningKey, error) {
+	var keys []*entity.AuditSigningKey
+	err := r.db.Find(&keys).Error
+	return keys, err
+}
+
+// EnsureAppendOnly 为审计链、检查点和签名公钥表创建拒绝UPDATE和DELETE的触发器，已存在时跳过
+func (r *AuditTrailRepositoryImpl) EnsureAppendOnly() error {
+	for _, table := range []string{entity.AuditTrailEntry{}.TableName(), entity.AuditCheckpoint{}.TableName(), entity.AuditSigningKey{}.TableName()} {
+		for _, event := range []string{"UPDATE", "DELETE"} {
+			name := fmt.Sprintf("%s_no_%s", table, strings.ToLower(event))
+
+			var count int64
+			if err := r.db.Raw("SELECT COUNT(*) FROM information_schema.TRIGGERS WHERE TRIGGER_SCHEMA = DATABASE() AND TRIGGER_NAME = ?", name).
+				Scan(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+
+			sql := fmt.Sprintf("CREATE TRIGGER %s BEFORE %s ON %s FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = '%s is append-only'",
+				name, event, table, table)
+			if err := r.db.Exec(sql).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isDuplicateKey 是否为唯一键冲突（MySQL错误1062）
+func isDuplicateKey(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	return strings.Contains(err.Error(), "Duplicate entry") || strings.Contains(err.Error(), "1062")
+}
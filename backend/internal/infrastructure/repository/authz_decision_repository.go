@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// AuthzDecisionRepositoryImpl 授权决策日志仓库实现
+type AuthzDecisionRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewAuthzDecisionRepository 创建授权决策日志仓库
+func NewAuthzDecisionRepository(db *gorm.DB) repository.AuthzDecisionRepository {
+	return &AuthzDecisionRepositoryImpl{db: db}
+}
+
+// CreateBatch 批量写入日志
+func (r *AuthzDecisionRepositoryImpl) CreateBatch(decisions []*entity.AuthzDecision) error {
+	if len(decisions) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(decisions, 500).Error
+}
+
+// List 分页查询日志
+func (r *AuthzDecisionRepositoryImpl) List(query *repository.AuthzDecisionQuery) ([]*entity.AuthzDecision, int64, error) {
+	db := r.db.Model(&entity.AuthzDecision{})
+	if query.UserID > 0 {
+		db = db.Where("user_id = ?", query.UserID)
+	}
+	if query.Path != "" {
+		db = db.Where("path LIKE ?", query.Path+"%")
+	}
+	if query.Method != "" {
+		db = db.Where("method = ?", query.Method)
+	}
+	if query.Allowed != nil {
+		db = db.Where("allowed = ?", *query.Allowed)
+	}
+	if query.Source != "" {
+		db = db.Where("source = ?", query.Source)
+	}
+	if query.RequestID != "" {
+		db = db.Where("request_id = ?", query.RequestID)
+	}
+	if query.StartTime != nil {
+		db = db.Where("created_at >= ?", *query.StartTime)
+	}
+	if query.EndTime != nil {
+		db = db.Where("created_at < ?", *query.EndTime)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var decisions []*entity.AuthzDecision
+	err := db.Order("id DESC").Offset((query.Page - 1) * query.PageSize).Limit(query.PageSize).Find(&decisions).Error
+	return decisions, total, err
+}
+
+// DeleteBefore 删除指定时间之前的日志
+func (r *AuthzDecisionRepositoryImpl) DeleteBefore(before time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", before).Delete(&entity.AuthzDecision{})
+	return result.RowsAffected, result.Error
+}
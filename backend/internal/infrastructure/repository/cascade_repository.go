@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// CascadeRepositoryImpl 级联删除和孤立数据清理仓库实现
+type CascadeRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewCascadeRepository 创建级联删除和孤立数据清理仓库
+func NewCascadeRepository(db *gorm.DB) repository.CascadeRepository {
+	return &CascadeRepositoryImpl{db: db}
+}
+
+// ListRules 获取所有Casbin策略
+func (r *CascadeRepositoryImpl) ListRules() ([]*repository.CasbinRule, error) {
+	var rules []*repository.CasbinRule
+	if err := r.db.Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// CountDependents 统计删除计划会连带删除的关联记录
+func (r *CascadeRepositoryImpl) CountDependents(plan *repository.DeletePlan) (*repository.DeleteDependents, error) {
+	dependents := &repository.DeleteDependents{}
+	if len(plan.RoleIDs) > 0 {
+		if err := r.db.Model(&entity.UserRole{}).Where("role_id IN ?", plan.RoleIDs).Count(&dependents.UserRoles).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(plan.APIIDs) > 0 {
+		if err := r.db.Model(&entity.APIGroupMember{}).Where("api_id IN ?", plan.APIIDs).Count(&dependents.GroupMembers).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if query, args := grantCondition(plan); query != "" {
+		for _, model := range []interface{}{&entity.RoleAPIGroup{}, &entity.RolePermissionBundle{}} {
+			var count int64
+			if err := r.db.Model(model).Where(query, args...).Count(&count).Error; err != nil {
+				return nil, err
+			}
+			dependents.Grants += count
+		}
+	}
+	if query, args := ledgerCondition(plan); query != "" {
+		for _, model := range []interface{}{&entity.APIGroupPolicy{}, &entity.PermissionBundlePolicy{}} {
+			var count int64
+			if err := r.db.Model(model).Where(query, args...).Count(&count).Error; err != nil {
+				return nil, err
+			}
+			dependents.LedgerPolicies += count
+		}
+	}
+	return dependents, nil
+}
+
+// Delete 在一个事务中执行删除计划
+func (r *CascadeRepositoryImpl) Delete(plan *repository.DeletePlan) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if plan.Orphans != nil {
+			if err := deleteOrphans(tx, plan.Orphans); err != nil {
+				return err
+			}
+		}
+
+		// 授予记录和策略记录
+		if query, args := grantCondition(plan); query != "" {
+			for _, model := range []interface{}{&entity.RoleAPIGroup{}, &entity.RolePermissionBundle{}} {
+				if err := tx.Where(query, args...).Delete(model).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if query, args := ledgerCondition(plan); query != "" {
+			for _, model := range []interface{}{&entity.APIGroupPolicy{}, &entity.PermissionBundlePolicy{}} {
+				if err := tx.Where(query, args...).Delete(model).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(plan.RoleIDs) > 0 {
+			if err := tx.Where("role_id IN ?", plan.RoleIDs).Delete(&entity.UserRole{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&entity.Role{}, plan.RoleIDs).Error; err != nil {
+				return err
+			}
+		}
+		if len(plan.APIIDs) > 0 {
+			if err := tx.Where("api_id IN ?", plan.APIIDs).Delete(&entity.APIGroupMember{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&entity.API{}, plan.APIIDs).Error; err != nil {
+				return err
+			}
+		}
+		if len(plan.BusinessIDs) > 0 {
+			if err := tx.Delete(&entity.Business{}, plan.BusinessIDs).Error; err != nil {
+				return err
+			}
+		}
+		if len(plan.DepartmentIDs) > 0 {
+			if err := tx.Delete(&entity.Department{}, plan.DepartmentIDs).Error; err != nil {
+				return err
+			}
+		}
+		if len(plan.RuleIDs) > 0 {
+			if err := tx.Delete(&repository.CasbinRule{}, plan.RuleIDs).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FindOrphans 查找孤立记录
+func (r *CascadeRepositoryImpl) FindOrphans() (*repository.OrphanRecords, error) {
+	orphans := &repository.OrphanRecords{}
+	users := r.db.Model(&entity.User{}).Select("id")
+	roles := r.db.Model(&entity.Role{}).Select("id")
+	groups := r.db.Model(&entity.APIGroup{}).Select("id")
+	bundles := r.db.Model(&entity.PermissionBundle{}).Select("id")
+	apis := r.db.Model(&entity.API{}).Select("id")
+	businesses := r.db.Model(&entity.Business{}).Select("id")
+
+	queries := []struct {
+		model interface{}
+		ids   *[]uint
+		query string
+		args  []interface{}
+	}{
+		{&entity.UserRole{}, &orphans.UserRoleIDs, "user_id NOT IN (?) OR role_id NOT IN (?)", []interface{}{users, roles}},
+		{&entity.APIGroupMember{}, &orphans.GroupMemberIDs, "group_id NOT IN (?) OR api_id NOT IN (?)", []interface{}{groups, apis}},
+		{&entity.RoleAPIGroup{}, &orphans.RoleGroupIDs, "role_id NOT IN (?) OR group_id NOT IN (?)", []interface{}{roles, groups}},
+		{&entity.APIGroupPolicy{}, &orphans.GroupPolicyIDs, "role_id NOT IN (?)", []interface{}{roles}},
+		{&entity.RolePermissionBundle{}, &orphans.RoleBundleIDs, "role_id NOT IN (?) OR bundle_id NOT IN (?)", []interface{}{roles, bundles}},
+		{&entity.PermissionBundlePolicy{}, &orphans.BundlePolicyIDs, "role_id NOT IN (?) OR bundle_id NOT IN (?)", []interface{}{roles, bundles}},
+		{&entity.API{}, &orphans.APIIDs, "business_id > 0 AND business_id NOT IN (?)", []interface{}{businesses}},
+	}
+	for _, q := range queries {
+		if err := r.db.Model(q.model).Where(q.query, q.args...).Order("id").Pluck("id", q.ids).Error; err != nil {
+			return nil, err
+		}
+	}
+	return orphans, nil
+}
+
+// deleteOrphans 删除孤立的关联记录，孤立的API由删除计划按API处理
+func deleteOrphans(tx *gorm.DB, orphans *repository.OrphanRecords) error {
+	deletes := []struct {
+		model interface{}
+		ids   []uint
+	}{
+		{&entity.UserRole{}, orphans.UserRoleIDs},
+		{&entity.APIGroupMember{}, orphans.GroupMemberIDs},
+		{&entity.RoleAPIGroup{}, orphans.RoleGroupIDs},
+		{&entity.APIGroupPolicy{}, orphans.GroupPolicyIDs},
+		{&entity.RolePermissionBundle{}, orphans.RoleBundleIDs},
+		{&entity.PermissionBundlePolicy{}, orphans.BundlePolicyIDs},
+	}
+	for _, d := range deletes {
+		if len(d.ids) == 0 {
+			continue
+		}
+		if err := tx.Delete(d.model, d.ids).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// grantCondition 删除计划涉及的授予记录：被删除角色的和被删除部门上的
+func grantCondition(plan *repository.DeletePlan) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if len(plan.RoleIDs) > 0 {
+		conditions = append(conditions, "role_id IN ?")
+		args = append(args, plan.RoleIDs)
+	}
+	if len(plan.Depts) > 0 {
+		conditions = append(conditions, "dept IN ?")
+		args = append(args, plan.Depts)
+	}
+	return strings.Join(conditions, " OR "), args
+}
+
+// ledgerCondition 删除计划涉及的策略记录：授予记录涉及的以及被删除API的
+func ledgerCondition(plan *repository.DeletePlan) (string, []interface{}) {
+	query, args := grantCondition(plan)
+	conditions := []string{}
+	if query != "" {
+		conditions = append(conditions, query)
+	}
+	for _, endpoint := range plan.Endpoints {
+		conditions = append(conditions, "(path = ? AND method = ?)")
+		args = append(args, endpoint.Path, endpoint.Method)
+	}
+	return strings.Join(conditions, " OR "), args
+}
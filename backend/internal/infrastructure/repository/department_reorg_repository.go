@@ -0,0 +1,268 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// DepartmentReorgRepositoryImpl 部门合并、拆分仓库实现
+type DepartmentReorgRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewDepartmentReorgRepository 创建部门合并、拆分仓库
+func NewDepartmentReorgRepository(db *gorm.DB) repository.DepartmentReorgRepository {
+	return &DepartmentReorgRepositoryImpl{db: db}
+}
+
+// GetContents 获取部门直接拥有的用户、角色、业务线和下级部门
+func (r *DepartmentReorgRepositoryImpl) GetContents(deptID uint) (*repository.DepartmentContents, error) {
+	contents := &repository.DepartmentContents{}
+	if err := r.db.Where("dept_id = ?", deptID).Order("id").Find(&contents.Users).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("dept_id = ?", deptID).Order("id").Find(&contents.Roles).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("dept_id = ?", deptID).Order("id").Find(&contents.Businesses).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("parent_id = ?", deptID).Order("sort, id").Find(&contents.Children).Error; err != nil {
+		return nil, err
+	}
+	if len(contents.Businesses) > 0 {
+		businessIDs := make([]uint, 0, len(contents.Businesses))
+		for _, business := range contents.Businesses {
+			businessIDs = append(businessIDs, business.ID)
+		}
+		if err := r.db.Model(&entity.API{}).Where("business_id IN ?", businessIDs).Count(&contents.APICount).Error; err != nil {
+			return nil, err
+		}
+	}
+	return contents, nil
+}
+
+// ListRules 获取所有Casbin策略
+func (r *DepartmentReorgRepositoryImpl) ListRules() ([]*repository.CasbinRule, error) {
+	var rules []*repository.CasbinRule
+	if err := r.db.Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ListUserRoles 获取用户通过用户角色关联拥有的角色，键为用户ID
+func (r *DepartmentReorgRepositoryImpl) ListUserRoles(userIDs []uint) (map[uint][]*entity.Role, error) {
+	roles := make(map[uint][]*entity.Role, len(userIDs))
+	if len(userIDs) == 0 {
+		return roles, nil
+	}
+	var userRoles []*entity.UserRole
+	if err := r.db.Where("user_id IN ?", userIDs).Order("id").Find(&userRoles).Error; err != nil {
+		return nil, err
+	}
+	if len(userRoles) == 0 {
+		return roles, nil
+	}
+
+	roleIDs := make([]uint, 0, len(userRoles))
+	for _, userRole := range userRoles {
+		roleIDs = append(roleIDs, userRole.RoleID)
+	}
+	var list []*entity.Role
+	if err := r.db.Where("id IN ?", roleIDs).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*entity.Role, len(list))
+	for _, role := range list {
+		byID[role.ID] = role
+	}
+	for _, userRole := range userRoles {
+		if role, ok := byID[userRole.RoleID]; ok {
+			roles[userRole.UserID] = append(roles[userRole.UserID], role)
+		}
+	}
+	return roles, nil
+}
+
+// Merge 在一个事务中执行合并
+func (r *DepartmentReorgRepositoryImpl) Merge(plan *repository.DepartmentMergePlan) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var target entity.Department
+		if err := tx.First(&target, plan.TargetID).Error; err != nil {
+			return err
+		}
+
+		// 用户、角色、业务线归属目标部门，API随业务线转移
+		for _, model := range []interface{}{&entity.User{}, &entity.Role{}, &entity.Business{}} {
+			if err := tx.Model(model).Where("dept_id = ?", plan.SourceID).
+				Update("dept_id", plan.TargetID).Error; err != nil {
+				return err
+			}
+		}
+
+		// 部门管理员角色
+		if plan.RenameRole != nil {
+			if err := tx.Save(plan.RenameRole).Error; err != nil {
+				return err
+			}
+		}
+		if plan.MergeRoleID > 0 {
+			if err := mergeUserRoles(tx, plan.MergeRoleID, plan.MergeIntoRoleID); err != nil {
+				return err
+			}
+			if err := tx.Delete(&entity.Role{}, plan.MergeRoleID).Error; err != nil {
+				return err
+			}
+		}
+
+		// 下级部门移动到目标部门下
+		var children []*entity.Department
+		if err := tx.Where("parent_id = ?", plan.SourceID).Find(&children).Error; err != nil {
+			return err
+		}
+		for _, child := range children {
+			path, level := departmentPath(target.Path, child.ID), target.Level+1
+			if err := tx.Model(child).Updates(map[string]interface{}{
+				"parent_id": target.ID,
+				"path":      path,
+				"level":     level,
+			}).Error; err != nil {
+				return err
+			}
+			if err := rewriteDepartmentSubtree(tx, child.Path, path, level-child.Level); err != nil {
+				return err
+			}
+		}
+
+		// 改写策略
+		for _, id := range plan.DeletedRuleIDs {
+			if err := tx.Delete(&repository.CasbinRule{}, id).Error; err != nil {
+				return err
+			}
+		}
+		if err := updateRules(tx, plan.UpdatedRules); err != nil {
+			return err
+		}
+
+		// API组和权限模板记录的部门维度
+		source, targetDept := departmentDimension(plan.SourceID), departmentDimension(plan.TargetID)
+		for _, model := range []interface{}{
+			&entity.RoleAPIGroup{}, &entity.APIGroupPolicy{},
+			&entity.RolePermissionBundle{}, &entity.PermissionBundlePolicy{},
+		} {
+			if err := tx.Model(model).Where("dept = ?", source).Update("dept", targetDept).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&entity.Department{}, plan.SourceID).Error
+	})
+}
+
+// Split 在一个事务中执行拆分
+func (r *DepartmentReorgRepositoryImpl) Split(plan *repository.DepartmentSplitPlan) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		dept := plan.Department
+		if err := tx.Create(dept).Error; err != nil {
+			return err
+		}
+		parentPath, err := departmentParentPath(tx, dept.ParentID)
+		if err != nil {
+			return err
+		}
+		dept.Path = departmentPath(parentPath, dept.ID)
+		if err := tx.Model(dept).Update("path", dept.Path).Error; err != nil {
+			return err
+		}
+
+		if len(plan.BusinessIDs) > 0 {
+			if err := tx.Model(&entity.Business{}).Where("id IN ? AND dept_id = ?", plan.BusinessIDs, plan.SourceID).
+				Update("dept_id", dept.ID).Error; err != nil {
+				return err
+			}
+		}
+		if len(plan.UserIDs) > 0 {
+			if err := tx.Model(&entity.User{}).Where("id IN ? AND dept_id = ?", plan.UserIDs, plan.SourceID).
+				Update("dept_id", dept.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		// 新部门的管理员角色
+		placeholder := strings.NewReplacer(repository.NewDepartmentPlaceholder, departmentDimension(dept.ID))
+		if role := plan.AdminRole; role != nil {
+			role.Code = placeholder.Replace(role.Code)
+			role.DeptID = dept.ID
+			if err := tx.Create(role).Error; err != nil {
+				return err
+			}
+			if len(plan.AdminUserIDs) > 0 {
+				if err := tx.Model(&entity.UserRole{}).Where("role_id = ? AND user_id IN ?", plan.SourceAdminRoleID, plan.AdminUserIDs).
+					Update("role_id", role.ID).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		// 转移用户在新部门维度上的策略
+		for _, rule := range append(plan.AddedRules, plan.UpdatedRules...) {
+			for _, value := range []*string{&rule.V0, &rule.V1, &rule.V2, &rule.V3, &rule.V4, &rule.V5} {
+				*value = placeholder.Replace(*value)
+			}
+		}
+		for _, rule := range plan.AddedRules {
+			if err := tx.Create(rule).Error; err != nil {
+				return err
+			}
+		}
+		return updateRules(tx, plan.UpdatedRules)
+	})
+}
+
+// updateRules 按ID更新改写后的策略
+func updateRules(tx *gorm.DB, rules []*repository.CasbinRule) error {
+	for _, rule := range rules {
+		if err := tx.Model(&repository.CasbinRule{}).Where("id = ?", rule.ID).Updates(map[string]interface{}{
+			"v0": rule.V0, "v1": rule.V1, "v2": rule.V2, "v3": rule.V3, "v4": rule.V4, "v5": rule.V5,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeUserRoles 将角色的用户关联转移到另一个角色，用户已拥有目标角色时删除原关联
+func mergeUserRoles(tx *gorm.DB, fromRoleID, toRoleID uint) error {
+	var userRoles []*entity.UserRole
+	if err := tx.Where("role_id = ?", fromRoleID).Find(&userRoles).Error; err != nil {
+		return err
+	}
+	for _, userRole := range userRoles {
+		var count int64
+		if err := tx.Model(&entity.UserRole{}).Where("user_id = ? AND role_id = ?", userRole.UserID, toRoleID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			if err := tx.Delete(userRole).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tx.Model(userRole).Update("role_id", toRoleID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// departmentDimension 策略中部门维度的取值
+func departmentDimension(deptID uint) string {
+	return strconv.FormatUint(uint64(deptID), 10)
+}
@@ -2,6 +2,9 @@ package repository
 
 import (
 	"errors"
+	"sort"
+	"strconv"
+	"strings"
 
 	"gorm.io/gorm"
 
@@ -19,14 +22,48 @@ func NewDepartmentRepository(db *gorm.DB) repository.DepartmentRepository {
 	return &DepartmentRepositoryImpl{db: db}
 }
 
-// Create 创建部门
+// Create 创建部门，并按父部门生成物化路径
 func (r *DepartmentRepositoryImpl) Create(department *entity.Department) error {
-	return r.db.Create(department).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(department).Error; err != nil {
+			return err
+		}
+		parentPath, err := departmentParentPath(tx, department.ParentID)
+		if err != nil {
+			return err
+		}
+		department.Path = departmentPath(parentPath, department.ID)
+		return tx.Model(department).Update("path", department.Path).Error
+	})
 }
 
-// Update 更新部门
+// Update 更新部门，父部门变化时同步更新部门及其下级部门的物化路径和层级
 func (r *DepartmentRepositoryImpl) Update(department *entity.Department) error {
-	return r.db.Save(department).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var old entity.Department
+		if err := tx.First(&old, department.ID).Error; err != nil {
+			return err
+		}
+
+		department.Path = old.Path
+		if old.ParentID != department.ParentID || old.Path == "" {
+			parentPath, err := departmentParentPath(tx, department.ParentID)
+			if err != nil {
+				return err
+			}
+			department.Path = departmentPath(parentPath, department.ID)
+			if old.Path != "" && strings.HasPrefix(parentPath, old.Path) {
+				return errors.New("不能将部门移动到自身或其下级部门下")
+			}
+		}
+		if err := tx.Save(department).Error; err != nil {
+			return err
+		}
+		if old.ParentID == department.ParentID {
+			return nil
+		}
+		return rewriteDepartmentSubtree(tx, old.Path, department.Path, department.Level-old.Level)
+	})
 }
 
 // Delete 删除部门
@@ -93,6 +130,146 @@ func (r *DepartmentRepositoryImpl) GetTotalCount() (int64, error) {
 	return count, err
 }
 
+// ListPage 分页获取部门列表
+func (r *DepartmentRepositoryImpl) ListPage(page, pageSize int, query string) ([]*entity.Department, int64, error) {
+	var departments []*entity.Department
+	var total int64
+
+	db := r.db.Model(&entity.Department{})
+	if query != "" {
+		db = db.Where("name LIKE ? OR code LIKE ?", "%"+query+"%", "%"+query+"%")
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := db.Order("id").Offset(offset).Limit(pageSize).Find(&departments).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return departments, total, nil
+}
+
+// ListAncestors 获取部门的所有上级部门，由根部门到直接父部门排列
+func (r *DepartmentRepositoryImpl) ListAncestors(id uint) ([]*entity.Department, error) {
+	department, err := r.GetByID(id)
+	if err != nil || department == nil {
+		return nil, err
+	}
+
+	ids := departmentPathIDs(department.Path)
+	if len(ids) <= 1 {
+		return []*entity.Department{}, nil
+	}
+	ids = ids[:len(ids)-1]
+
+	var ancestors []*entity.Department
+	if err := r.db.Where("id IN ?", ids).Find(&ancestors).Error; err != nil {
+		return nil, err
+	}
+	position := make(map[uint]int, len(ids))
+	for i, ancestorID := range ids {
+		position[ancestorID] = i
+	}
+	sort.Slice(ancestors, func(i, j int) bool {
+		return position[ancestors[i].ID] < position[ancestors[j].ID]
+	})
+	return ancestors, nil
+}
+
+// ListDescendants 获取部门的所有下级部门，按层级和排序排列
+func (r *DepartmentRepositoryImpl) ListDescendants(id uint) ([]*entity.Department, error) {
+	department, err := r.GetByID(id)
+	if err != nil || department == nil || department.Path == "" {
+		return nil, err
+	}
+
+	var descendants []*entity.Department
+	if err := r.db.Where("path LIKE ? AND id <> ?", department.Path+"%", id).
+		Order("level, sort, id").Find(&descendants).Error; err != nil {
+		return nil, err
+	}
+	return descendants, nil
+}
+
+// Move 将部门连同其下级部门移动到新的父部门下，部门层级为父部门层级加一，下级部门的层级随之平移
+func (r *DepartmentRepositoryImpl) Move(id, parentID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var department entity.Department
+		if err := tx.First(&department, id).Error; err != nil {
+			return err
+		}
+
+		parentPath, level := "/", 1
+		if parentID > 0 {
+			var parent entity.Department
+			if err := tx.First(&parent, parentID).Error; err != nil {
+				return err
+			}
+			if parent.ID == id || (department.Path != "" && strings.HasPrefix(parent.Path, department.Path)) {
+				return errors.New("不能将部门移动到自身或其下级部门下")
+			}
+			parentPath, level = parent.Path, parent.Level+1
+		}
+
+		oldPath, oldLevel := department.Path, department.Level
+		path := departmentPath(parentPath, id)
+		if err := tx.Model(&department).Updates(map[string]interface{}{
+			"parent_id": parentID,
+			"path":      path,
+			"level":     level,
+		}).Error; err != nil {
+			return err
+		}
+		return rewriteDepartmentSubtree(tx, oldPath, path, level-oldLevel)
+	})
+}
+
+// RebuildPaths 按父部门关系重建所有部门的物化路径，只更新路径有变化的部门
+func (r *DepartmentRepositoryImpl) RebuildPaths() error {
+	departments, err := r.ListAll()
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[uint]*entity.Department, len(departments))
+	for _, department := range departments {
+		byID[department.ID] = department
+	}
+
+	paths := make(map[uint]string, len(departments))
+	var build func(department *entity.Department, depth int) string
+	build = func(department *entity.Department, depth int) string {
+		if path, ok := paths[department.ID]; ok {
+			return path
+		}
+		parentPath := "/"
+		// 父部门不存在或父子关系成环时，部门作为顶级部门处理
+		if parent, ok := byID[department.ParentID]; ok && depth < len(departments) {
+			parentPath = build(parent, depth+1)
+		}
+		path := departmentPath(parentPath, department.ID)
+		paths[department.ID] = path
+		return path
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, department := range departments {
+			path := build(department, 0)
+			if path == department.Path {
+				continue
+			}
+			if err := tx.Model(&entity.Department{}).Where("id = ?", department.ID).
+				Update("path", path).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 // GetChildren 获取子部门
 func (r *DepartmentRepositoryImpl) GetChildren(parentID uint) ([]*entity.Department, error) {
 	var departments []*entity.Department
@@ -102,6 +279,61 @@ func (r *DepartmentRepositoryImpl) GetChildren(parentID uint) ([]*entity.Departm
 	return departments, nil
 }
 
+// departmentParentPath 获取父部门的物化路径，parentID为0或父部门没有路径时返回根路径
+func departmentParentPath(tx *gorm.DB, parentID uint) (string, error) {
+	if parentID == 0 {
+		return "/", nil
+	}
+	var parent entity.Department
+	if err := tx.First(&parent, parentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", errors.New("父部门不存在")
+		}
+		return "", err
+	}
+	if parent.Path == "" {
+		return "/", nil
+	}
+	return parent.Path, nil
+}
+
+// departmentPath 部门的物化路径
+func departmentPath(parentPath string, id uint) string {
+	return parentPath + strconv.FormatUint(uint64(id), 10) + "/"
+}
+
+// departmentPathIDs 解析物化路径中的部门ID
+func departmentPathIDs(path string) []uint {
+	var ids []uint
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if id, err := strconv.ParseUint(segment, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// rewriteDepartmentSubtree 部门路径由oldPath变为newPath后，更新其下级部门的路径，并将层级平移levelDelta
+func rewriteDepartmentSubtree(tx *gorm.DB, oldPath, newPath string, levelDelta int) error {
+	if oldPath == "" || (oldPath == newPath && levelDelta == 0) {
+		return nil
+	}
+
+	var descendants []*entity.Department
+	if err := tx.Where("path LIKE ? AND path <> ?", oldPath+"%", oldPath).Find(&descendants).Error; err != nil {
+		return err
+	}
+	for _, descendant := range descendants {
+		if err := tx.Model(descendant).Updates(map[string]interface{}{
+			"path":  newPath + strings.TrimPrefix(descendant.Path, oldPath),
+			"level": descendant.Level + levelDelta,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // BusinessRepositoryImpl 业务线仓库实现
 type BusinessRepositoryImpl struct {
 	db *gorm.DB
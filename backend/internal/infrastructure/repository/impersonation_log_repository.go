@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// ImpersonationLogRepositoryImpl 代登录日志仓库实现
+type ImpersonationLogRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewImpersonationLogRepository 创建代登录日志仓库
+func NewImpersonationLogRepository(db *gorm.DB) repository.ImpersonationLogRepository {
+	return &ImpersonationLogRepositoryImpl{db: db}
+}
+
+// Create 创建日志
+func (r *ImpersonationLogRepositoryImpl) Create(log *entity.ImpersonationLog) error {
+	return r.db.Create(log).Error
+}
+
+// List 分页查询日志
+func (r *ImpersonationLogRepositoryImpl) List(query *repository.ImpersonationLogQuery) ([]*entity.ImpersonationLog, int64, error) {
+	db := r.db.Model(&entity.ImpersonationLog{})
+	if query.ActorID > 0 {
+		db = db.Where("actor_id = ?", query.ActorID)
+	}
+	if query.UserID > 0 {
+		db = db.Where("user_id = ?", query.UserID)
+	}
+	if query.SessionID != "" {
+		db = db.Where("session_id = ?", query.SessionID)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []*entity.ImpersonationLog
+	err := db.Order("id DESC").Offset((query.Page - 1) * query.PageSize).Limit(query.PageSize).Find(&logs).Error
+	return logs, total, err
+}
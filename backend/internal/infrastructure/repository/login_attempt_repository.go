@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// LoginAttemptRepositoryImpl 登录尝试记录仓库实现
+type LoginAttemptRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewLoginAttemptRepository 创建登录尝试记录仓库
+func NewLoginAttemptRepository(db *gorm.DB) repository.LoginAttemptRepository {
+	return &LoginAttemptRepositoryImpl{db: db}
+}
+
+// Create 创建登录尝试记录
+func (r *LoginAttemptRepositoryImpl) Create(attempt *entity.LoginAttempt) error {
+	return r.db.Create(attempt).Error
+}
+
+// ListByUser 分页获取用户的登录尝试记录，按时间倒序
+func (r *LoginAttemptRepositoryImpl) ListByUser(userID uint, page, pageSize int) ([]*entity.LoginAttempt, int64, error) {
+	var attempts []*entity.LoginAttempt
+	var total int64
+
+	query := r.db.Model(&entity.LoginAttempt{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&attempts).Error; err != nil {
+		return nil, 0, err
+	}
+	return attempts, total, nil
+}
+
+// DeleteBefore 删除指定时间之前的记录
+func (r *LoginAttemptRepositoryImpl) DeleteBefore(t time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", t).Delete(&entity.LoginAttempt{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// OAuthRepositoryImpl OAuth2仓库实现
+type OAuthRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewOAuthRepository 创建OAuth2仓库
+func NewOAuthRepository(db *gorm.DB) repository.OAuthRepository {
+	return &OAuthRepositoryImpl{db: db}
+}
+
+// CreateClient 创建客户端
+func (r *OAuthRepositoryImpl) CreateClient(client *entity.OAuthClient) error {
+	return r.db.Create(client).Error
+}
+
+// UpdateClient 更新客户端
+func (r *OAuthRepositoryImpl) UpdateClient(client *entity.OAuthClient) error {
+	return r.db.Save(client).Error
+}
+
+// DeleteClient 删除客户端
+func (r *OAuthRepositoryImpl) DeleteClient(id uint) error {
+	return r.db.Delete(&entity.OAuthClient{}, id).Error
+}
+
+// GetClientByID 根据ID获取客户端
+func (r *OAuthRepositoryImpl) GetClientByID(id uint) (*entity.OAuthClient, error) {
+	var client entity.OAuthClient
+	if err := r.db.First(&client, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+// GetClientByClientID 根据client_id获取客户端
+func (r *OAuthRepositoryImpl) GetClientByClientID(clientID string) (*entity.OAuthClient, error) {
+	var client entity.OAuthClient
+	if err := r.db.Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+// ListClients 获取客户端列表
+func (r *OAuthRepositoryImpl) ListClients(businessID uint) ([]*entity.OAuthClient, error) {
+	var clients []*entity.OAuthClient
+
+	db := r.db
+	if businessID > 0 {
+		db = db.Where("business_id = ?", businessID)
+	}
+
+	if err := db.Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// GetConsent 获取用户对客户端的授权记录
+func (r *OAuthRepositoryImpl) GetConsent(userID uint, clientID string) (*entity.OAuthConsent, error) {
+	var consent entity.OAuthConsent
+	if err := r.db.Where("user_id = ? AND client_id = ?", userID, clientID).First(&consent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// SaveConsent 保存用户授权记录
+func (r *OAuthRepositoryImpl) SaveConsent(consent *entity.OAuthConsent) error {
+	return r.db.Save(consent).Error
+}
+
+// DeleteConsentsByClient 删除客户端的所有授权记录
+func (r *OAuthRepositoryImpl) DeleteConsentsByClient(clientID string) error {
+	return r.db.Where("client_id = ?", clientID).Delete(&entity.OAuthConsent{}).Error
+}
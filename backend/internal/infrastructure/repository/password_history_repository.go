@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// PasswordHistoryRepositoryImpl 历史密码仓库实现
+type PasswordHistoryRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewPasswordHistoryRepository 创建历史密码仓库
+func NewPasswordHistoryRepository(db *gorm.DB) repository.PasswordHistoryRepository {
+	return &PasswordHistoryRepositoryImpl{db: db}
+}
+
+// Create 记录历史密码
+func (r *PasswordHistoryRepositoryImpl) Create(history *entity.PasswordHistory) error {
+	return r.db.Create(history).Error
+}
+
+// ListRecent 获取用户最近的历史密码
+func (r *PasswordHistoryRepositoryImpl) ListRecent(userID uint, limit int) ([]*entity.PasswordHistory, error) {
+	var histories []*entity.PasswordHistory
+	if limit <= 0 {
+		return histories, nil
+	}
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Limit(limit).Find(&histories).Error
+	return histories, err
+}
+
+// Prune 只保留用户最近keep条历史密码
+func (r *PasswordHistoryRepositoryImpl) Prune(userID uint, keep int) error {
+	if keep <= 0 {
+		return r.db.Where("user_id = ?", userID).Delete(&entity.PasswordHistory{}).Error
+	}
+
+	var ids []uint
+	if err := r.db.Model(&entity.PasswordHistory{}).Where("user_id = ?", userID).
+		Order("id DESC").Limit(keep).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) < keep {
+		return nil
+	}
+	return r.db.Where("user_id = ? AND id NOT IN ?", userID, ids).Delete(&entity.PasswordHistory{}).Error
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// PermissionBundleRepositoryImpl 权限模板仓库实现
+type PermissionBundleRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewPermissionBundleRepository 创建权限模板仓库
+func NewPermissionBundleRepository(db *gorm.DB) repository.PermissionBundleRepository {
+	return &PermissionBundleRepositoryImpl{db: db}
+}
+
+// Create 创建模板及其第一个版本
+func (r *PermissionBundleRepositoryImpl) Create(bundle *entity.PermissionBundle, version *entity.PermissionBundleVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(bundle).Error; err != nil {
+			return err
+		}
+		version.BundleID = bundle.ID
+		return tx.Create(version).Error
+	})
+}
+
+// Update 更新模板，version不为nil时同时保存新版本
+func (r *PermissionBundleRepositoryImpl) Update(bundle *entity.PermissionBundle, version *entity.PermissionBundleVersion) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(bundle).Error; err != nil {
+			return err
+		}
+		if version == nil {
+			return nil
+		}
+		version.BundleID = bundle.ID
+		return tx.Create(version).Error
+	})
+}
+
+// Delete 删除模板及其所有版本
+func (r *PermissionBundleRepositoryImpl) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("bundle_id = ?", id).Delete(&entity.PermissionBundleVersion{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entity.PermissionBundle{}, id).Error
+	})
+}
+
+// GetByID 根据ID获取模板
+func (r *PermissionBundleRepositoryImpl) GetByID(id uint) (*entity.PermissionBundle, error) {
+	var bundle entity.PermissionBundle
+	if err := r.db.First(&bundle, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &bundle, nil
+}
+
+// GetByCode 根据编码获取模板
+func (r *PermissionBundleRepositoryImpl) GetByCode(code string) (*entity.PermissionBundle, error) {
+	var bundle entity.PermissionBundle
+	if err := r.db.Where("code = ?", code).First(&bundle).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &bundle, nil
+}
+
+// List 获取所有模板
+func (r *PermissionBundleRepositoryImpl) List() ([]*entity.PermissionBundle, error) {
+	var bundles []*entity.PermissionBundle
+	if err := r.db.Order("code").Find(&bundles).Error; err != nil {
+		return nil, err
+	}
+	return bundles, nil
+}
+
+// GetVersion 获取模板的指定版本
+func (r *PermissionBundleRepositoryImpl) GetVersion(bundleID uint, version int) (*entity.PermissionBundleVersion, error) {
+	var v entity.PermissionBundleVersion
+	if err := r.db.Where("bundle_id = ? AND version = ?", bundleID, version).First(&v).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ListVersions 获取模板的所有版本
+func (r *PermissionBundleRepositoryImpl) ListVersions(bundleID uint) ([]*entity.PermissionBundleVersion, error) {
+	var versions []*entity.PermissionBundleVersion
+	if err := r.db.Where("bundle_id = ?", bundleID).Order("version DESC").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// ListApplications 获取模板在各角色上的应用记录
+func (r *PermissionBundleRepositoryImpl) ListApplications(bundleID uint) ([]*entity.RolePermissionBundle, error) {
+	var applications []*entity.RolePermissionBundle
+	if err := r.db.Where("bundle_id = ?", bundleID).Order("role_id").Find(&applications).Error; err != nil {
+		return nil, err
+	}
+	return applications, nil
+}
+
+// ListApplicationsByRole 获取角色应用的模板
+func (r *PermissionBundleRepositoryImpl) ListApplicationsByRole(roleID uint) ([]*entity.RolePermissionBundle, error) {
+	var applications []*entity.RolePermissionBundle
+	if err := r.db.Where("role_id = ?", roleID).Order("bundle_id").Find(&applications).Error; err != nil {
+		return nil, err
+	}
+	return applications, nil
+}
+
+// GetApplication 获取模板在角色上的应用记录
+func (r *PermissionBundleRepositoryImpl) GetApplication(bundleID, roleID uint) (*entity.RolePermissionBundle, error) {
+	var application entity.RolePermissionBundle
+	if err := r.db.Where("bundle_id = ? AND role_id = ?", bundleID, roleID).First(&application).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &application, nil
+}
+
+// SaveApplication 保存应用记录及模板为角色生成的策略记录
+func (r *PermissionBundleRepositoryImpl) SaveApplication(application *entity.RolePermissionBundle, policies []*entity.PermissionBundlePolicy) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(application).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("bundle_id = ? AND role_id = ?", application.BundleID, application.RoleID).
+			Delete(&entity.PermissionBundlePolicy{}).Error; err != nil {
+			return err
+		}
+		if len(policies) == 0 {
+			return nil
+		}
+		return tx.Create(&policies).Error
+	})
+}
+
+// DeleteApplication 删除应用记录及模板为角色生成的策略记录
+func (r *PermissionBundleRepositoryImpl) DeleteApplication(bundleID, roleID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("bundle_id = ? AND role_id = ?", bundleID, roleID).Delete(&entity.PermissionBundlePolicy{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("bundle_id = ? AND role_id = ?", bundleID, roleID).Delete(&entity.RolePermissionBundle{}).Error
+	})
+}
+
+// ListPolicies 获取模板为角色生成的策略记录
+func (r *PermissionBundleRepositoryImpl) ListPolicies(bundleID, roleID uint) ([]*entity.PermissionBundlePolicy, error) {
+	var policies []*entity.PermissionBundlePolicy
+	if err := r.db.Where("bundle_id = ? AND role_id = ?", bundleID, roleID).Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// ListPoliciesByRole 获取所有模板为角色生成的策略记录
+func (r *PermissionBundleRepositoryImpl) ListPoliciesByRole(roleID uint) ([]*entity.PermissionBundlePolicy, error) {
+	var policies []*entity.PermissionBundlePolicy
+	if err := r.db.Where("role_id = ?", roleID).Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
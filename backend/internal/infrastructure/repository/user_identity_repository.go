@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// UserIdentityRepositoryImpl 用户外部身份仓库实现
+type UserIdentityRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewUserIdentityRepository 创建用户外部身份仓库
+func NewUserIdentityRepository(db *gorm.DB) repository.UserIdentityRepository {
+	return &UserIdentityRepositoryImpl{db: db}
+}
+
+// Create 创建身份绑定
+func (r *UserIdentityRepositoryImpl) Create(identity *entity.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+// Update 更新身份绑定
+func (r *UserIdentityRepositoryImpl) Update(identity *entity.UserIdentity) error {
+	return r.db.Save(identity).Error
+}
+
+// Delete 删除身份绑定
+func (r *UserIdentityRepositoryImpl) Delete(id uint) error {
+	return r.db.Delete(&entity.UserIdentity{}, id).Error
+}
+
+// GetByProviderSubject 根据提供方和外部用户标识获取身份绑定
+func (r *UserIdentityRepositoryImpl) GetByProviderSubject(provider, subject string) (*entity.UserIdentity, error) {
+	var identity entity.UserIdentity
+	if err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// GetByUserAndProvider 获取用户在指定提供方的身份绑定
+func (r *UserIdentityRepositoryImpl) GetByUserAndProvider(userID uint, provider string) (*entity.UserIdentity, error) {
+	var identity entity.UserIdentity
+	if err := r.db.Where("user_id = ? AND provider = ?", userID, provider).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// ListByUser 获取用户的所有身份绑定
+func (r *UserIdentityRepositoryImpl) ListByUser(userID uint) ([]*entity.UserIdentity, error) {
+	var identities []*entity.UserIdentity
+	if err := r.db.Where("user_id = ?", userID).Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/repository"
+)
+
+// UserMFARepositoryImpl 用户多因素认证仓库实现
+type UserMFARepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewUserMFARepository 创建用户多因素认证仓库
+func NewUserMFARepository(db *gorm.DB) repository.UserMFARepository {
+	return &UserMFARepositoryImpl{db: db}
+}
+
+// Save 创建或更新多因素认证配置
+func (r *UserMFARepositoryImpl) Save(mfa *entity.UserMFA) error {
+	return r.db.Save(mfa).Error
+}
+
+// GetByUserID 获取用户的多因素认证配置
+func (r *UserMFARepositoryImpl) GetByUserID(userID uint) (*entity.UserMFA, error) {
+	var mfa entity.UserMFA
+	if err := r.db.Where("user_id = ?", userID).First(&mfa).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+// DeleteByUserID 删除用户的多因素认证配置
+func (r *UserMFARepositoryImpl) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&entity.UserMFA{}).Error
+}
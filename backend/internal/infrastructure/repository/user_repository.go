@@ -58,6 +58,18 @@ func (r *UserRepositoryImpl) GetByUsername(username string) (*entity.User, error
 	return &user, nil
 }
 
+// GetByEmail 根据邮箱获取用户
+func (r *UserRepositoryImpl) GetByEmail(email string) (*entity.User, error) {
+	var user entity.User
+	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
 // List 获取用户列表
 func (r *UserRepositoryImpl) List(page, pageSize int, query string) ([]*entity.User, int64, error) {
 	var users []*entity.User
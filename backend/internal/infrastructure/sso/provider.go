@@ -0,0 +1,68 @@
+package sso
+
+import (
+	"context"
+	"fmt"
+)
+
+// Identity 外部身份提供方返回的用户身份
+type Identity struct {
+	Subject       string                 `json:"subject"`
+	Username      string                 `json:"username"`
+	Name          string                 `json:"name"`
+	Email         string                 `json:"email"`
+	EmailVerified bool                   `json:"email_verified"`
+	Avatar        string                 `json:"avatar"`
+	DeptCode      string                 `json:"dept_code"` // 从声明中映射出的部门编码
+	Claims        map[string]interface{} `json:"claims"`
+}
+
+// Provider 外部登录提供方
+type Provider interface {
+	// Name 提供方名称，用于路由和身份绑定
+	Name() string
+
+	// Config 提供方配置
+	Config() *ProviderConfig
+
+	// AuthCodeURL 生成跳转到提供方的授权地址
+	AuthCodeURL(ctx context.Context, state, nonce string) (string, error)
+
+	// Exchange 使用授权码换取并校验用户身份
+	Exchange(ctx context.Context, code, nonce string) (*Identity, error)
+}
+
+// ProviderConfig 登录提供方配置
+type ProviderConfig struct {
+	Name         string   `mapstructure:"name"`
+	Type         string   `mapstructure:"type"` // 目前支持oidc
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"`
+	Scopes       []string `mapstructure:"scopes"`
+
+	// 声明映射
+	UsernameClaim string `mapstructure:"username_claim"` // 默认preferred_username
+	DeptClaim     string `mapstructure:"dept_claim"`     // 部门编码所在的声明
+
+	// 用户开通策略
+	AutoProvision   bool   `mapstructure:"auto_provision"`    // 首次登录时自动创建用户
+	DefaultDeptCode string `mapstructure:"default_dept_code"` // 声明中没有部门时使用的部门编码
+	DefaultRole     string `mapstructure:"default_role"`      // 自动创建用户时分配的角色编码
+	LinkByEmail     bool   `mapstructure:"link_by_email"`     // 按已验证邮箱自动绑定已有用户
+}
+
+// NewProvider 根据配置创建登录提供方
+func NewProvider(cfg ProviderConfig) (Provider, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("登录提供方名称不能为空")
+	}
+
+	switch cfg.Type {
+	case "", "oidc":
+		return NewOIDCProvider(cfg)
+	default:
+		return nil, fmt.Errorf("不支持的登录提供方类型: %s", cfg.Type)
+	}
+}
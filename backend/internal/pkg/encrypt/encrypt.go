@@ -10,16 +10,16 @@ import (
 	"io"
 )
 
-// GenerateHash 生成密码哈希
+// GenerateHash 生成SHA-256摘要，只适用于随机生成的高熵密钥（如OAuth2客户端密钥）
+// 用户密码请使用HashPassword
 func GenerateHash(password string) string {
-	hash := sha256.Sum256([]byte(password))
-	return base64.StdEncoding.EncodeToString(hash[:])
+	return legacyHash(password)
 }
 
-// VerifyPassword 验证密码
+// VerifyPassword 验证密码，兼容所有支持的哈希格式
 func VerifyPassword(password, hash string) bool {
-	hashedPassword := GenerateHash(password)
-	return hashedPassword == hash
+	ok, _ := CheckPassword(password, hash)
+	return ok
 }
 
 // EncryptAES 使用AES-256加密敏感数据
@@ -0,0 +1,258 @@
+package encrypt
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 密码哈希方案
+const (
+	SchemeArgon2id = "argon2id"
+	SchemeBcrypt   = "bcrypt"
+	SchemeLegacy   = "sha256" // 旧版无盐SHA-256，仅用于校验和迁移
+	SchemeUnknown  = "unknown"
+)
+
+// ErrUnsupportedHash 无法识别的密码哈希格式
+var ErrUnsupportedHash = errors.New("无法识别的密码哈希格式")
+
+// PasswordHasher 密码哈希算法，哈希结果为自描述的编码字符串（PHC格式或bcrypt格式）
+type PasswordHasher interface {
+	// Scheme 算法名称
+	Scheme() string
+	// Hash 生成带盐的密码哈希
+	Hash(password string) (string, error)
+	// Verify 校验密码，encoded必须是本算法生成的哈希
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash 哈希参数是否弱于当前配置
+	NeedsRehash(encoded string) bool
+}
+
+// Argon2idHasher argon2id哈希，编码格式：$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+type Argon2idHasher struct {
+	Memory      uint32 `mapstructure:"memory"` // KiB
+	Iterations  uint32 `mapstructure:"iterations"`
+	Parallelism uint8  `mapstructure:"parallelism"`
+	SaltLength  uint32 `mapstructure:"salt_length"`
+	KeyLength   uint32 `mapstructure:"key_length"`
+}
+
+// NewArgon2idHasher 创建argon2id哈希，未设置的参数使用OWASP推荐值
+func NewArgon2idHasher(h Argon2idHasher) *Argon2idHasher {
+	if h.Memory == 0 {
+		h.Memory = 64 * 1024
+	}
+	if h.Iterations == 0 {
+		h.Iterations = 3
+	}
+	if h.Parallelism == 0 {
+		h.Parallelism = 2
+	}
+	if h.SaltLength == 0 {
+		h.SaltLength = 16
+	}
+	if h.KeyLength == 0 {
+		h.KeyLength = 32
+	}
+	return &h
+}
+
+// Scheme 算法名称
+func (h *Argon2idHasher) Scheme() string {
+	return SchemeArgon2id
+}
+
+// Hash 生成密码哈希
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify 校验密码
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	actual := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+// NeedsRehash 参数弱于当前配置时需要重新哈希
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.Memory < h.Memory || params.Iterations < h.Iterations || params.Parallelism < h.Parallelism ||
+		uint32(len(salt)) < h.SaltLength || uint32(len(key)) < h.KeyLength
+}
+
+// decodeArgon2id 解析argon2id的PHC编码
+func decodeArgon2id(encoded string) (*Argon2idHasher, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != SchemeArgon2id {
+		return nil, nil, nil, ErrUnsupportedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, ErrUnsupportedHash
+	}
+
+	params := &Argon2idHasher{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return nil, nil, nil, ErrUnsupportedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, ErrUnsupportedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, ErrUnsupportedHash
+	}
+	return params, salt, key, nil
+}
+
+// BcryptHasher bcrypt哈希，编码格式：$2a$<cost>$<salt+hash>
+type BcryptHasher struct {
+	Cost int `mapstructure:"cost"`
+}
+
+// NewBcryptHasher 创建bcrypt哈希
+func NewBcryptHasher(h BcryptHasher) *BcryptHasher {
+	if h.Cost < bcrypt.MinCost {
+		h.Cost = 12
+	}
+	return &h
+}
+
+// Scheme 算法名称
+func (h *BcryptHasher) Scheme() string {
+	return SchemeBcrypt
+}
+
+// Hash 生成密码哈希
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify 校验密码
+func (h *BcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, ErrUnsupportedHash
+	}
+	return true, nil
+}
+
+// NeedsRehash cost低于当前配置时需要重新哈希
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < h.Cost
+}
+
+var (
+	hasherMu sync.RWMutex
+	// 默认使用argon2id，可在启动时通过SetPasswordHasher替换
+	currentHasher PasswordHasher = NewArgon2idHasher(Argon2idHasher{})
+	// 已知算法，用于校验其他算法生成的历史哈希
+	knownHashers = map[string]PasswordHasher{
+		SchemeArgon2id: NewArgon2idHasher(Argon2idHasher{}),
+		SchemeBcrypt:   NewBcryptHasher(BcryptHasher{}),
+	}
+)
+
+// SetPasswordHasher 设置生成新密码哈希使用的算法
+func SetPasswordHasher(hasher PasswordHasher) {
+	hasherMu.Lock()
+	defer hasherMu.Unlock()
+	currentHasher = hasher
+	knownHashers[hasher.Scheme()] = hasher
+}
+
+// HashPassword 使用当前算法生成密码哈希
+func HashPassword(password string) (string, error) {
+	hasherMu.RLock()
+	hasher := currentHasher
+	hasherMu.RUnlock()
+	return hasher.Hash(password)
+}
+
+// CheckPassword 校验密码，needsRehash表示校验通过但哈希需要升级（旧算法或参数过弱）
+func CheckPassword(password, encoded string) (ok bool, needsRehash bool) {
+	hasherMu.RLock()
+	current := currentHasher
+	hasher := knownHashers[PasswordScheme(encoded)]
+	hasherMu.RUnlock()
+
+	if hasher == nil {
+		// 旧版SHA-256哈希只校验一次，校验通过后由调用方重新哈希
+		if PasswordScheme(encoded) == SchemeLegacy {
+			ok := subtle.ConstantTimeCompare([]byte(legacyHash(password)), []byte(encoded)) == 1
+			return ok, ok
+		}
+		return false, false
+	}
+
+	ok, err := hasher.Verify(password, encoded)
+	if err != nil || !ok {
+		return false, false
+	}
+	return true, hasher.Scheme() != current.Scheme() || current.NeedsRehash(encoded)
+}
+
+// PasswordScheme 识别密码哈希使用的算法
+func PasswordScheme(encoded string) string {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return SchemeArgon2id
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return SchemeBcrypt
+	case isLegacyHash(encoded):
+		return SchemeLegacy
+	default:
+		return SchemeUnknown
+	}
+}
+
+// legacyHash 旧版无盐SHA-256哈希
+func legacyHash(password string) string {
+	hash := sha256.Sum256([]byte(password))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// isLegacyHash 是否为旧版SHA-256哈希（32字节的标准Base64编码）
+func isLegacyHash(encoded string) bool {
+	if len(encoded) != base64.StdEncoding.EncodedLen(sha256.Size) {
+		return false
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	return err == nil && len(data) == sha256.Size
+}
@@ -0,0 +1,185 @@
+package encrypt
+
+import (
+	"testing"
+)
+
+// 测试使用较低的参数以缩短运行时间
+var (
+	testArgon2id     = NewArgon2idHasher(Argon2idHasher{Memory: 1024, Iterations: 2, Parallelism: 1})
+	testWeakArgon2id = NewArgon2idHasher(Argon2idHasher{Memory: 1024, Iterations: 1, Parallelism: 1})
+	testBcrypt       = NewBcryptHasher(BcryptHasher{Cost: 5})
+	testWeakBcrypt   = NewBcryptHasher(BcryptHasher{Cost: 4})
+)
+
+// useHasher 设置当前算法，测试结束后恢复
+func useHasher(t *testing.T, hasher PasswordHasher) {
+	t.Helper()
+	hasherMu.RLock()
+	previous := currentHasher
+	hasherMu.RUnlock()
+	SetPasswordHasher(hasher)
+	t.Cleanup(func() { SetPasswordHasher(previous) })
+}
+
+func mustHash(t *testing.T, hasher PasswordHasher, password string) string {
+	t.Helper()
+	encoded, err := hasher.Hash(password)
+	if err != nil {
+		t.Fatalf("%s哈希失败: %v", hasher.Scheme(), err)
+	}
+	return encoded
+}
+
+func TestCheckPassword(t *testing.T) {
+	const password = "Passw0rd!"
+
+	tests := []struct {
+		name       string
+		current    PasswordHasher
+		encoded    func(t *testing.T) string
+		password   string
+		wantOK     bool
+		wantRehash bool
+	}{
+		{
+			name:     "argon2id当前参数",
+			current:  testArgon2id,
+			encoded:  func(t *testing.T) string { return mustHash(t, testArgon2id, password) },
+			password: password,
+			wantOK:   true,
+		},
+		{
+			name:       "argon2id参数弱于当前配置",
+			current:    testArgon2id,
+			encoded:    func(t *testing.T) string { return mustHash(t, testWeakArgon2id, password) },
+			password:   password,
+			wantOK:     true,
+			wantRehash: true,
+		},
+		{
+			name:     "argon2id密码错误",
+			current:  testArgon2id,
+			encoded:  func(t *testing.T) string { return mustHash(t, testArgon2id, password) },
+			password: "wrong",
+		},
+		{
+			name:       "当前为argon2id时升级bcrypt",
+			current:    testArgon2id,
+			encoded:    func(t *testing.T) string { return mustHash(t, testBcrypt, password) },
+			password:   password,
+			wantOK:     true,
+			wantRehash: true,
+		},
+		{
+			name:     "bcrypt当前cost",
+			current:  testBcrypt,
+			encoded:  func(t *testing.T) string { return mustHash(t, testBcrypt, password) },
+			password: password,
+			wantOK:   true,
+		},
+		{
+			name:       "bcrypt的cost低于当前配置",
+			current:    testBcrypt,
+			encoded:    func(t *testing.T) string { return mustHash(t, testWeakBcrypt, password) },
+			password:   password,
+			wantOK:     true,
+			wantRehash: true,
+		},
+		{
+			name:     "bcrypt密码错误",
+			current:  testBcrypt,
+			encoded:  func(t *testing.T) string { return mustHash(t, testBcrypt, password) },
+			password: "wrong",
+		},
+		{
+			name:       "当前为bcrypt时升级argon2id",
+			current:    testBcrypt,
+			encoded:    func(t *testing.T) string { return mustHash(t, testArgon2id, password) },
+			password:   password,
+			wantOK:     true,
+			wantRehash: true,
+		},
+		{
+			name:       "旧版SHA-256",
+			current:    testArgon2id,
+			encoded:    func(t *testing.T) string { return legacyHash(password) },
+			password:   password,
+			wantOK:     true,
+			wantRehash: true,
+		},
+		{
+			name:     "旧版SHA-256密码错误",
+			current:  testArgon2id,
+			encoded:  func(t *testing.T) string { return legacyHash(password) },
+			password: "wrong",
+		},
+		{
+			name:     "无法识别的格式",
+			current:  testArgon2id,
+			encoded:  func(t *testing.T) string { return password },
+			password: password,
+		},
+		{
+			name:     "损坏的argon2id哈希",
+			current:  testArgon2id,
+			encoded:  func(t *testing.T) string { return "$argon2id$v=19$m=1024,t=2,p=1$!!$!!" },
+			password: password,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useHasher(t, tt.current)
+			ok, rehash := CheckPassword(tt.password, tt.encoded(t))
+			if ok != tt.wantOK || rehash != tt.wantRehash {
+				t.Errorf("CheckPassword() = (%v, %v), want (%v, %v)", ok, rehash, tt.wantOK, tt.wantRehash)
+			}
+		})
+	}
+}
+
+func TestHashPasswordUsesCurrentHasher(t *testing.T) {
+	tests := []struct {
+		name    string
+		current PasswordHasher
+		scheme  string
+	}{
+		{"argon2id", testArgon2id, SchemeArgon2id},
+		{"bcrypt", testBcrypt, SchemeBcrypt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useHasher(t, tt.current)
+			encoded, err := HashPassword("Passw0rd!")
+			if err != nil {
+				t.Fatalf("HashPassword() error = %v", err)
+			}
+			if got := PasswordScheme(encoded); got != tt.scheme {
+				t.Errorf("PasswordScheme() = %s, want %s", got, tt.scheme)
+			}
+			if ok, rehash := CheckPassword("Passw0rd!", encoded); !ok || rehash {
+				t.Errorf("CheckPassword() = (%v, %v), want (true, false)", ok, rehash)
+			}
+		})
+	}
+}
+
+func TestPasswordScheme(t *testing.T) {
+	tests := []struct {
+		encoded string
+		want    string
+	}{
+		{"$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$aGFzaA", SchemeArgon2id},
+		{"$2a$12$abcdefghijklmnopqrstuv", SchemeBcrypt},
+		{"$2b$12$abcdefghijklmnopqrstuv", SchemeBcrypt},
+		{"$2y$12$abcdefghijklmnopqrstuv", SchemeBcrypt},
+		{GenerateHash("secret"), SchemeLegacy},
+		{"plain-text", SchemeUnknown},
+		{"", SchemeUnknown},
+	}
+	for _, tt := range tests {
+		if got := PasswordScheme(tt.encoded); got != tt.want {
+			t.Errorf("PasswordScheme(%q) = %s, want %s", tt.encoded, got, tt.want)
+		}
+	}
+}
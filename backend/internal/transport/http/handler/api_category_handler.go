@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+)
+
+// CategoryTree 获取API分类树
+// @Summary 获取API分类树
+// @Description 按父子关系和排序返回分类树，每个节点包含直接和子树内的API数量
+// @Tags API分类
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.Response{data=[]service.APICategoryNode} "获取成功"
+// @Router /api/category/tree [get]
+func (h *APIHandler) CategoryTree(c *gin.Context) {
+	tree, err := h.apiService.GetAPICategoryTree()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取API分类树成功",
+		Data:    tree,
+	})
+}
+
+// MoveCategory 移动API分类
+// @Summary 移动API分类
+// @Description 将分类连同其子分类移动到新的父分类下，父分类不能是分类自身或其子分类
+// @Tags API分类
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "分类ID"
+// @Param request body service.MoveAPICategoryRequest true "移动请求"
+// @Success 200 {object} dto.Response{data=entity.APICategory} "移动成功"
+// @Router /api/category/{id}/move [post]
+func (h *APIHandler) MoveCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "无效的分类ID",
+		})
+		return
+	}
+
+	var req service.MoveAPICategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	category, err := h.apiService.MoveAPICategory(uint(id), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "移动API分类成功",
+		Data:    category,
+	})
+}
+
+// ReorderCategories 调整同级API分类顺序
+// @Summary 调整同级API分类顺序
+// @Description 按ids的顺序重新排列父分类下的子分类，未列出的原有子分类排在后面
+// @Tags API分类
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.ReorderAPICategoriesRequest true "排序请求"
+// @Success 200 {object} dto.Response{data=[]service.APICategoryNode} "排序成功"
+// @Router /api/category/reorder [put]
+func (h *APIHandler) ReorderCategories(c *gin.Context) {
+	var req service.ReorderAPICategoriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	tree, err := h.apiService.ReorderAPICategories(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "调整API分类顺序成功",
+		Data:    tree,
+	})
+}
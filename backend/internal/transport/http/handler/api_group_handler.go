@@ -0,0 +1,348 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+)
+
+// APIGroupHandler API组处理器
+type APIGroupHandler struct {
+	groupService *service.APIGroupService
+}
+
+// NewAPIGroupHandler 创建API组处理器
+func NewAPIGroupHandler(groupService *service.APIGroupService) *APIGroupHandler {
+	return &APIGroupHandler{
+		groupService: groupService,
+	}
+}
+
+// Register 注册路由
+func (h *APIGroupHandler) Register(router *gin.RouterGroup) {
+	groupRouter := router.Group("/api-group")
+	{
+		groupRouter.GET("/list", h.List)
+		groupRouter.GET("/:id", h.Get)
+		groupRouter.POST("", h.Create)
+		groupRouter.PUT("/:id", h.Update)
+		groupRouter.DELETE("/:id", h.Delete)
+		groupRouter.POST("/:id/apis", h.AddAPIs)
+		groupRouter.DELETE("/:id/apis", h.RemoveAPIs)
+	}
+
+	router.GET("/role/:id/api-groups", h.GetRoleGroups)
+	router.PUT("/role/:id/api-groups", h.SetRoleGroups)
+}
+
+// List 获取API组列表
+// @Summary 获取API组列表
+// @Description 获取API组列表，可按业务线过滤
+// @Tags API组
+// @Produce json
+// @Security ApiKeyAuth
+// @Param business_id query int false "业务线ID"
+// @Success 200 {object} dto.Response{data=[]entity.APIGroup} "获取成功"
+// @Router /api-group/list [get]
+func (h *APIGroupHandler) List(c *gin.Context) {
+	businessID, _ := strconv.ParseUint(c.Query("business_id"), 10, 32)
+
+	groups, err := h.groupService.ListGroups(uint(businessID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取成功",
+		Data:    groups,
+	})
+}
+
+// Get 获取API组详情
+// @Summary 获取API组详情
+// @Description 获取API组及组内的API（含路径中的版本）和被授予该组的角色
+// @Tags API组
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "API组ID"
+// @Success 200 {object} dto.Response{data=service.APIGroupDetail} "获取成功"
+// @Router /api-group/{id} [get]
+func (h *APIGroupHandler) Get(c *gin.Context) {
+	id, ok := h.pathID(c, "无效的API组ID")
+	if !ok {
+		return
+	}
+
+	detail, err := h.groupService.GetGroup(id)
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取成功",
+		Data:    detail,
+	})
+}
+
+// Create 创建API组
+// @Summary 创建API组
+// @Description 创建API组，将同一资源操作的多个API（如/v1、/v2版本）归为一组，如order.read
+// @Tags API组
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.CreateAPIGroupRequest true "API组信息"
+// @Success 200 {object} dto.Response{data=entity.APIGroup} "创建成功"
+// @Router /api-group [post]
+func (h *APIGroupHandler) Create(c *gin.Context) {
+	var req service.CreateAPIGroupRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	group, err := h.groupService.CreateGroup(&req)
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "创建成功",
+		Data:    group,
+	})
+}
+
+// Update 更新API组
+// @Summary 更新API组
+// @Description 更新API组的名称和描述
+// @Tags API组
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "API组ID"
+// @Param request body service.UpdateAPIGroupRequest true "API组信息"
+// @Success 200 {object} dto.Response{data=entity.APIGroup} "更新成功"
+// @Router /api-group/{id} [put]
+func (h *APIGroupHandler) Update(c *gin.Context) {
+	id, ok := h.pathID(c, "无效的API组ID")
+	if !ok {
+		return
+	}
+	var req service.UpdateAPIGroupRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	group, err := h.groupService.UpdateGroup(id, &req)
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "更新成功",
+		Data:    group,
+	})
+}
+
+// Delete 删除API组
+// @Summary 删除API组
+// @Description 删除API组，被授予该组的角色失去组内生成的策略
+// @Tags API组
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "API组ID"
+// @Success 200 {object} dto.Response "删除成功"
+// @Router /api-group/{id} [delete]
+func (h *APIGroupHandler) Delete(c *gin.Context) {
+	id, ok := h.pathID(c, "无效的API组ID")
+	if !ok {
+		return
+	}
+
+	if err := h.groupService.DeleteGroup(id); err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "删除成功",
+	})
+}
+
+// AddAPIs 向API组添加API
+// @Summary 添加API组成员
+// @Description 向API组添加API，被授予该组的角色自动获得新API的策略
+// @Tags API组
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "API组ID"
+// @Param request body service.APIGroupAPIsRequest true "API ID列表"
+// @Success 200 {object} dto.Response{data=service.APIGroupDetail} "添加成功"
+// @Router /api-group/{id}/apis [post]
+func (h *APIGroupHandler) AddAPIs(c *gin.Context) {
+	id, ok := h.pathID(c, "无效的API组ID")
+	if !ok {
+		return
+	}
+	var req service.APIGroupAPIsRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	detail, err := h.groupService.AddAPIs(id, &req)
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "添加成功",
+		Data:    detail,
+	})
+}
+
+// RemoveAPIs 从API组移除API
+// @Summary 移除API组成员
+// @Description 从API组移除API，被授予该组的角色自动撤销相应策略（角色直接拥有的策略保留）
+// @Tags API组
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "API组ID"
+// @Param request body service.APIGroupAPIsRequest true "API ID列表"
+// @Success 200 {object} dto.Response{data=service.APIGroupDetail} "移除成功"
+// @Router /api-group/{id}/apis [delete]
+func (h *APIGroupHandler) RemoveAPIs(c *gin.Context) {
+	id, ok := h.pathID(c, "无效的API组ID")
+	if !ok {
+		return
+	}
+	var req service.APIGroupAPIsRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	detail, err := h.groupService.RemoveAPIs(id, &req)
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "移除成功",
+		Data:    detail,
+	})
+}
+
+// GetRoleGroups 获取角色被授予的API组
+// @Summary 获取角色的API组
+// @Description 获取角色被授予的API组及策略的部门维度
+// @Tags API组
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "角色ID"
+// @Success 200 {object} dto.Response{data=[]service.RoleAPIGroupInfo} "获取成功"
+// @Router /role/{id}/api-groups [get]
+func (h *APIGroupHandler) GetRoleGroups(c *gin.Context) {
+	roleID, ok := h.pathID(c, "无效的角色ID")
+	if !ok {
+		return
+	}
+
+	groups, err := h.groupService.GetRoleGroups(roleID)
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取成功",
+		Data:    groups,
+	})
+}
+
+// SetRoleGroups 设置角色被授予的API组
+// @Summary 设置角色的API组
+// @Description 替换角色被授予的API组，组内的API自动编译为角色的Casbin策略
+// @Tags API组
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "角色ID"
+// @Param request body service.SetRoleAPIGroupsRequest true "API组ID列表"
+// @Success 200 {object} dto.Response{data=[]service.RoleAPIGroupInfo} "设置成功"
+// @Router /role/{id}/api-groups [put]
+func (h *APIGroupHandler) SetRoleGroups(c *gin.Context) {
+	roleID, ok := h.pathID(c, "无效的角色ID")
+	if !ok {
+		return
+	}
+	var req service.SetRoleAPIGroupsRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	groups, err := h.groupService.SetRoleGroups(roleID, &req)
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "设置成功",
+		Data:    groups,
+	})
+}
+
+// pathID 解析路径中的ID
+func (h *APIGroupHandler) pathID(c *gin.Context, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: message,
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// bind 解析JSON请求体
+func (h *APIGroupHandler) bind(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// businessError 返回业务错误
+func (h *APIGroupHandler) businessError(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, dto.Response{
+		Code:    dto.CodeBusinessError,
+		Message: err.Error(),
+	})
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"mcprapi/backend/internal/domain/entity"
 	"mcprapi/backend/internal/domain/service"
 	"mcprapi/backend/internal/transport/http/dto"
 	"mcprapi/backend/internal/transport/middleware"
@@ -36,6 +37,7 @@ func (h *APIHandler) Register(router *gin.RouterGroup) {
 		// - /:id
 		// - /business/:id
 		// - /category/list
+		// - /category/tree
 		// - /category/:id
 		// - POST "" (创建API)
 		// - PUT "/:id" (更新API)
@@ -48,6 +50,8 @@ func (h *APIHandler) Register(router *gin.RouterGroup) {
 		apiRouter.POST("/category", h.CreateCategory)
 		apiRouter.PUT("/category/:id", h.UpdateCategory)
 		apiRouter.DELETE("/category/:id", h.DeleteCategory)
+		apiRouter.POST("/category/:id/move", h.MoveCategory)
+		apiRouter.PUT("/category/reorder", h.ReorderCategories)
 	}
 }
 
@@ -87,11 +91,16 @@ func (h *APIHandler) CheckPermission(c *gin.Context) {
 		return
 	}
 
-	// 构造完整的权限检查请求，自动填充用户ID
+	// 构造完整的权限检查请求，自动填充用户ID；受限令牌按其访问范围裁剪结果
 	req := service.CheckPermissionRequest{
-		UserID:  fmt.Sprintf("%d", userID),
-		APIPath: reqBody.APIPath,
-		Method:  reqBody.Method,
+		UserID:      fmt.Sprintf("%d", userID),
+		APIPath:     reqBody.APIPath,
+		Method:      reqBody.Method,
+		Restriction: middleware.GetCurrentRestriction(c),
+		Scope:       middleware.GetCurrentScope(c),
+		ClientID:    middleware.GetCurrentClientID(c),
+		RequestID:   middleware.GetRequestID(c),
+		ClientIP:    c.ClientIP(),
 	}
 
 	resp, err := h.authService.CheckPermission(&req)
@@ -103,6 +112,8 @@ func (h *APIHandler) CheckPermission(c *gin.Context) {
 		return
 	}
 
+	setLifecycleHeaders(c, resp)
+
 	c.JSON(http.StatusOK, dto.Response{
 		Code:    dto.CodeSuccess,
 		Message: "检查权限成功",
@@ -110,6 +121,19 @@ func (h *APIHandler) CheckPermission(c *gin.Context) {
 	})
 }
 
+// setLifecycleHeaders 被校验的API已废弃或已下线时设置Deprecation（RFC 9745）和Sunset（RFC 8594）响应头，供网关透传给调用方
+func setLifecycleHeaders(c *gin.Context, resp *service.CheckPermissionResponse) {
+	if resp.Lifecycle != entity.APILifecycleDeprecated && resp.Lifecycle != entity.APILifecycleRetired {
+		return
+	}
+	if resp.DeprecatedAt != nil {
+		c.Header("Deprecation", fmt.Sprintf("@%d", resp.DeprecatedAt.Unix()))
+	}
+	if resp.SunsetAt != nil {
+		c.Header("Sunset", resp.SunsetAt.UTC().Format(http.TimeFormat))
+	}
+}
+
 // Create 创建API
 func (h *APIHandler) Create(c *gin.Context) {
 	var req service.CreateAPIRequest
@@ -412,6 +436,16 @@ func (h *APIHandler) UpdateCategory(c *gin.Context) {
 }
 
 // DeleteCategory 删除API分类
+// @Summary 删除API分类
+// @Description 默认存在子分类或API时拒绝删除；mode=reassign时将子分类和API转移到target_id指定的分类（默认父分类）后删除。被权限模板引用的分类不能删除
+// @Tags API分类
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "分类ID"
+// @Param mode query string false "删除方式: block, reassign"
+// @Param target_id query int false "reassign的目标分类ID"
+// @Success 200 {object} dto.Response "删除成功"
+// @Router /api/category/{id} [delete]
 func (h *APIHandler) DeleteCategory(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -422,7 +456,16 @@ func (h *APIHandler) DeleteCategory(c *gin.Context) {
 		return
 	}
 
-	err = h.apiService.DeleteAPICategory(uint(id))
+	var req service.DeleteAPICategoryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	err = h.apiService.DeleteAPICategory(uint(id), &req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, dto.Response{
 			Code:    dto.CodeBusinessError,
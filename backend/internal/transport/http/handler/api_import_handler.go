@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+)
+
+// apiImportMaxSpecSize 上传的接口文档大小上限
+const apiImportMaxSpecSize = 10 << 20
+
+// APIImportHandler 接口文档导入处理器
+type APIImportHandler struct {
+	importService *service.APIImportService
+}
+
+// NewAPIImportHandler 创建接口文档导入处理器
+func NewAPIImportHandler(importService *service.APIImportService) *APIImportHandler {
+	return &APIImportHandler{
+		importService: importService,
+	}
+}
+
+// Register 注册路由
+func (h *APIImportHandler) Register(router *gin.RouterGroup) {
+	router.POST("/api/import/preview", h.Preview)
+	router.POST("/api/import", h.Import)
+}
+
+// Preview 预览接口文档导入结果
+// @Summary 预览接口文档导入
+// @Description 解析OpenAPI 3或Swagger 2文档，与业务线下已登记的API比对，返回新增、变更、删除和冲突的API，不做任何修改。可以上传文件（multipart，字段file）或在JSON请求体的spec中提交文档内容
+// @Tags API
+// @Accept json,mpfd
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.APIImportRequest false "导入参数（JSON）"
+// @Param file formData file false "接口文档文件（multipart）"
+// @Success 200 {object} dto.Response{data=service.APIImportResult} "预览成功"
+// @Router /api/import/preview [post]
+func (h *APIImportHandler) Preview(c *gin.Context) {
+	req, ok := h.bindRequest(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.importService.Preview(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "预览成功",
+		Data:    result,
+	})
+}
+
+// Import 导入接口文档
+// @Summary 导入接口文档
+// @Description 按OpenAPI 3或Swagger 2文档批量登记业务线下的API：路径自动拼接部门代码，第一个标签映射为API分类（不存在时新建），新建的API可自动授权给指定角色，remove_missing为true时删除文档中已不存在的API
+// @Tags API
+// @Accept json,mpfd
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.APIImportRequest false "导入参数（JSON）"
+// @Param file formData file false "接口文档文件（multipart）"
+// @Success 200 {object} dto.Response{data=service.APIImportResult} "导入成功"
+// @Router /api/import [post]
+func (h *APIImportHandler) Import(c *gin.Context) {
+	req, ok := h.bindRequest(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.importService.Import(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "导入成功",
+		Data:    result,
+	})
+}
+
+// bindRequest 解析导入请求：multipart表单从file字段读取文档，否则从JSON请求体的spec读取
+func (h *APIImportHandler) bindRequest(c *gin.Context) (*service.APIImportRequest, bool) {
+	var req service.APIImportRequest
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		if err := c.ShouldBind(&req); err != nil {
+			h.badRequest(c, "参数错误: "+err.Error())
+			return nil, false
+		}
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			h.badRequest(c, "参数错误: 缺少接口文档文件")
+			return nil, false
+		}
+		if fileHeader.Size > apiImportMaxSpecSize {
+			h.badRequest(c, "接口文档不能超过10MB")
+			return nil, false
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			h.badRequest(c, "读取接口文档失败: "+err.Error())
+			return nil, false
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			h.badRequest(c, "读取接口文档失败: "+err.Error())
+			return nil, false
+		}
+		req.Spec = string(data)
+		return &req, true
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, apiImportMaxSpecSize)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.badRequest(c, "参数错误: "+err.Error())
+		return nil, false
+	}
+	if strings.TrimSpace(req.Spec) == "" {
+		h.badRequest(c, "参数错误: 缺少接口文档内容")
+		return nil, false
+	}
+	return &req, true
+}
+
+// badRequest 返回参数错误
+func (h *APIImportHandler) badRequest(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, dto.Response{
+		Code:    dto.CodeInvalidParams,
+		Message: message,
+	})
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+)
+
+// APILifecycleHandler API生命周期处理器
+type APILifecycleHandler struct {
+	lifecycleService *service.APILifecycleService
+}
+
+// NewAPILifecycleHandler 创建API生命周期处理器
+func NewAPILifecycleHandler(lifecycleService *service.APILifecycleService) *APILifecycleHandler {
+	return &APILifecycleHandler{
+		lifecycleService: lifecycleService,
+	}
+}
+
+// Register 注册路由
+func (h *APILifecycleHandler) Register(router *gin.RouterGroup) {
+	router.PUT("/api/:id/lifecycle", h.SetLifecycle)
+}
+
+// SetLifecycle 变更API生命周期
+// @Summary 变更API生命周期
+// @Description 将API标记为正常、废弃或下线。废弃的API仍可访问，权限校验接口返回Deprecation和Sunset响应头，到达下线时间后自动下线并在此之前提醒业务线负责人；下线的API在权限校验中一律拒绝
+// @Tags API
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "API ID"
+// @Param request body service.SetAPILifecycleRequest true "生命周期"
+// @Success 200 {object} dto.Response{data=entity.API} "变更成功"
+// @Router /api/{id}/lifecycle [put]
+func (h *APILifecycleHandler) SetLifecycle(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "无效的API ID",
+		})
+		return
+	}
+
+	var req service.SetAPILifecycleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	api, err := h.lifecycleService.SetLifecycle(uint(id), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "变更成功",
+		Data:    api,
+	})
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+)
+
+// APISyncHandler 接口文档同步处理器
+type APISyncHandler struct {
+	syncService *service.APISyncService
+}
+
+// NewAPISyncHandler 创建接口文档同步处理器
+func NewAPISyncHandler(syncService *service.APISyncService) *APISyncHandler {
+	return &APISyncHandler{
+		syncService: syncService,
+	}
+}
+
+// Register 注册路由
+func (h *APISyncHandler) Register(router *gin.RouterGroup) {
+	router.GET("/business/spec-sources", h.List)
+	router.GET("/business/:id/spec-source", h.Get)
+	router.PUT("/business/:id/spec-source", h.Save)
+	router.DELETE("/business/:id/spec-source", h.Delete)
+	router.POST("/business/:id/spec-source/sync", h.Sync)
+}
+
+// List 获取所有接口文档来源
+// @Summary 获取接口文档来源列表
+// @Description 获取所有业务线的接口文档来源及上次同步状态
+// @Tags 业务线管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.Response{data=[]entity.APISpecSource} "获取成功"
+// @Router /business/spec-sources [get]
+func (h *APISyncHandler) List(c *gin.Context) {
+	sources, err := h.syncService.ListSources()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取成功",
+		Data:    sources,
+	})
+}
+
+// Get 获取业务线的接口文档来源
+// @Summary 获取接口文档来源
+// @Description 获取业务线的接口文档来源和上次同步报告（新增、变更、废弃的API及悬空策略）
+// @Tags 业务线管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "业务线ID"
+// @Success 200 {object} dto.Response{data=service.APISpecSourceDetail} "获取成功"
+// @Router /business/{id}/spec-source [get]
+func (h *APISyncHandler) Get(c *gin.Context) {
+	businessID, ok := h.businessID(c)
+	if !ok {
+		return
+	}
+
+	detail, err := h.syncService.GetSource(businessID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取成功",
+		Data:    detail,
+	})
+}
+
+// Save 设置业务线的接口文档来源
+// @Summary 设置接口文档来源
+// @Description 设置业务线的接口文档来源：spec_dir目录下的文件路径，或允许主机上的http(s)地址
+// @Tags 业务线管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "业务线ID"
+// @Param request body service.SaveAPISpecSourceRequest true "来源信息"
+// @Success 200 {object} dto.Response{data=entity.APISpecSource} "设置成功"
+// @Router /business/{id}/spec-source [put]
+func (h *APISyncHandler) Save(c *gin.Context) {
+	businessID, ok := h.businessID(c)
+	if !ok {
+		return
+	}
+
+	var req service.SaveAPISpecSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	source, err := h.syncService.SaveSource(businessID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "设置成功",
+		Data:    source,
+	})
+}
+
+// Delete 删除业务线的接口文档来源
+// @Summary 删除接口文档来源
+// @Description 删除业务线的接口文档来源，已登记的API不受影响
+// @Tags 业务线管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "业务线ID"
+// @Success 200 {object} dto.Response "删除成功"
+// @Router /business/{id}/spec-source [delete]
+func (h *APISyncHandler) Delete(c *gin.Context) {
+	businessID, ok := h.businessID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.syncService.DeleteSource(businessID); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "删除成功",
+	})
+}
+
+// Sync 立即同步业务线的接口文档
+// @Summary 同步接口文档
+// @Description 立即读取接口文档并与已登记的API比对：新增和更新API，路径变化的API原地更新，文档中已不存在的API标记为废弃，并报告悬空的策略。dry_run为true时只返回报告
+// @Tags 业务线管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "业务线ID"
+// @Param dry_run query bool false "只预览不修改"
+// @Success 200 {object} dto.Response{data=service.APISyncReport} "同步完成"
+// @Router /business/{id}/spec-source/sync [post]
+func (h *APISyncHandler) Sync(c *gin.Context) {
+	businessID, ok := h.businessID(c)
+	if !ok {
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	report, err := h.syncService.Sync(businessID, dryRun)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if report.Error != "" {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: report.Error,
+			Data:    report,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "同步完成",
+		Data:    report,
+	})
+}
+
+// businessID 解析路径中的业务线ID
+func (h *APISyncHandler) businessID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "无效的业务线ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+)
+
+// AuditHandler 审计日志处理器
+type AuditHandler struct {
+	auditService *service.AuditService
+	trailService *service.AuditTrailService
+}
+
+// NewAuditHandler 创建审计日志处理器
+func NewAuditHandler(auditService *service.AuditService, trailService *service.AuditTrailService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+		trailService: trailService,
+	}
+}
+
+// Register 注册管理员路由
+func (h *AuditHandler) Register(router *gin.RouterGroup) {
+	// 查询审计日志
+	router.GET("/audit", h.List)
+	// 校验防篡改审计链
+	router.GET("/audit/verify", h.Verify)
+}
+
+// List 查询审计日志
+// @Summary 查询审计日志
+// @Description 按操作人、操作、目标、部门、结果和时间范围查询管理操作审计日志
+// @Tags 审计
+// @Produce json
+// @Security ApiKeyAuth
+// @Param actor_id query int false "操作人ID"
+// @Param actor query string false "操作人用户名"
+// @Param action query string false "操作，如user.create"
+// @Param target_type query string false "目标类型"
+// @Param target_id query string false "目标ID"
+// @Param dept_id query int false "部门ID"
+// @Param result query string false "结果：success/failure"
+// @Param request_id query string false "请求ID"
+// @Param start_time query string false "开始时间"
+// @Param end_time query string false "结束时间"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} dto.Response{data=service.AuditLogListResponse} "获取成功"
+// @Router /audit [get]
+func (h *AuditHandler) List(c *gin.Context) {
+	var req service.AuditLogListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	resp, err := h.auditService.List(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取审计日志成功",
+		Data:    resp,
+	})
+}
+
+// Verify 校验防篡改审计链
+// @Summary 校验审计链
+// @Description 从第一条记录开始校验哈希链和签名检查点，返回第一个断开的记录序号及原因
+// @Tags 审计
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.Response{data=service.AuditTrailVerifyResult} "校验完成"
+// @Router /audit/verify [get]
+func (h *AuditHandler) Verify(c *gin.Context) {
+	result, err := h.trailService.Verify()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	message := "审计链校验通过"
+	if !result.Valid {
+		message = "审计链校验失败"
+	}
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: message,
+		Data:    result,
+	})
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+)
+
+// CascadeHandler 删除影响和孤立数据处理器
+type CascadeHandler struct {
+	cascadeService *service.CascadeService
+}
+
+// NewCascadeHandler 创建删除影响和孤立数据处理器
+func NewCascadeHandler(cascadeService *service.CascadeService) *CascadeHandler {
+	return &CascadeHandler{
+		cascadeService: cascadeService,
+	}
+}
+
+// Register 注册路由
+func (h *CascadeHandler) Register(router *gin.RouterGroup) {
+	router.GET("/department/:id/delete-impact", h.DepartmentImpact)
+	router.GET("/business/:id/delete-impact", h.BusinessImpact)
+	router.GET("/role/:id/delete-impact", h.RoleImpact)
+	router.GET("/api/:id/delete-impact", h.APIImpact)
+
+	maintenanceRouter := router.Group("/maintenance")
+	{
+		maintenanceRouter.GET("/orphans", h.Orphans)
+		maintenanceRouter.POST("/orphans/cleanup", h.CleanupOrphans)
+	}
+}
+
+// DepartmentImpact 获取删除部门的影响
+// @Summary 获取删除部门的影响
+// @Description 部门存在下级部门、用户、业务线或其他角色时不能删除；删除时级联删除部门管理员角色和部门维度的策略
+// @Tags 删除影响
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "部门ID"
+// @Success 200 {object} dto.Response{data=service.DeleteImpact} "获取成功"
+// @Router /department/{id}/delete-impact [get]
+func (h *CascadeHandler) DepartmentImpact(c *gin.Context) {
+	h.impact(c, service.DeleteTargetDepartment, "无效的部门ID")
+}
+
+// BusinessImpact 获取删除业务线的影响
+// @Summary 获取删除业务线的影响
+// @Description 系统业务线或存在绑定的API组时不能删除；删除时级联删除业务线下的API及其策略
+// @Tags 删除影响
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "业务线ID"
+// @Success 200 {object} dto.Response{data=service.DeleteImpact} "获取成功"
+// @Router /business/{id}/delete-impact [get]
+func (h *CascadeHandler) BusinessImpact(c *gin.Context) {
+	h.impact(c, service.DeleteTargetBusiness, "无效的业务线ID")
+}
+
+// RoleImpact 获取删除角色的影响
+// @Summary 获取删除角色的影响
+// @Description 系统管理员角色不能删除；删除时级联删除用户角色关联、角色的策略、API组和权限模板的授予记录
+// @Tags 删除影响
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "角色ID"
+// @Success 200 {object} dto.Response{data=service.DeleteImpact} "获取成功"
+// @Router /role/{id}/delete-impact [get]
+func (h *CascadeHandler) RoleImpact(c *gin.Context) {
+	h.impact(c, service.DeleteTargetRole, "无效的角色ID")
+}
+
+// APIImpact 获取删除API的影响
+// @Summary 获取删除API的影响
+// @Description 系统API不能删除；删除时级联删除API组成员，没有其他API使用同一路径和方法时删除对应的策略
+// @Tags 删除影响
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "API ID"
+// @Success 200 {object} dto.Response{data=service.DeleteImpact} "获取成功"
+// @Router /api/{id}/delete-impact [get]
+func (h *CascadeHandler) APIImpact(c *gin.Context) {
+	h.impact(c, service.DeleteTargetAPI, "无效的API ID")
+}
+
+// Orphans 查找孤立数据
+// @Summary 查找孤立数据
+// @Description 查找引用的用户、角色、API组、权限模板、API、业务线或部门已不存在的关联记录、API和策略，不做任何修改
+// @Tags 删除影响
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.Response{data=service.OrphanReport} "获取成功"
+// @Router /maintenance/orphans [get]
+func (h *CascadeHandler) Orphans(c *gin.Context) {
+	report, err := h.cascadeService.Orphans()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取成功",
+		Data:    report,
+	})
+}
+
+// CleanupOrphans 清理孤立数据
+// @Summary 清理孤立数据
+// @Description 在一个事务中删除孤立数据并重新加载策略，返回清理的内容
+// @Tags 删除影响
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.Response{data=service.OrphanReport} "清理成功"
+// @Router /maintenance/orphans/cleanup [post]
+func (h *CascadeHandler) CleanupOrphans(c *gin.Context) {
+	report, err := h.cascadeService.CleanupOrphans()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "清理成功",
+		Data:    report,
+	})
+}
+
+// impact 获取删除对象的影响
+func (h *CascadeHandler) impact(c *gin.Context, target, message string) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: message,
+		})
+		return
+	}
+
+	impact, err := h.cascadeService.Impact(target, uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取成功",
+		Data:    impact,
+	})
+}
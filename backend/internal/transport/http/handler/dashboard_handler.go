@@ -31,6 +31,7 @@ func (h *DashboardHandler) RegisterRoutes(r *gin.RouterGroup) {
 	{
 		dashboard.GET("/stats", h.GetDashboardStats)
 		dashboard.GET("/api-category-stats", h.GetAPICategories)
+		dashboard.GET("/api-category-tree", h.GetAPICategoryTree)
 		dashboard.GET("/business-api-stats", h.GetBusinessAPIStats)
 		dashboard.GET("/department-api-stats", h.GetDepartmentAPIStats)
 	}
@@ -81,6 +82,31 @@ func (h *DashboardHandler) GetAPICategories(c *gin.Context) {
 	})
 }
 
+// GetAPICategoryTree 获取API分类树统计
+// @Summary 获取API分类树统计
+// @Description 获取分类树，每个节点包含直接属于该分类和其子树内的API数量
+// @Tags 仪表盘
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.Response{data=[]service.APICategoryNode} "获取成功"
+// @Router /dashboard/api-category-tree [get]
+func (h *DashboardHandler) GetAPICategoryTree(c *gin.Context) {
+	tree, err := h.dashboardService.GetAPICategoryTree()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取API分类树统计成功",
+		Data:    tree,
+	})
+}
+
 // GetBusinessAPIStats 获取业务线API统计
 // @Summary 获取业务线API统计
 // @Description 获取业务线API统计数据，管理员可查看所有业务线，普通用户只能查看自己部门的业务线
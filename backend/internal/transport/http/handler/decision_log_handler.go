@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+)
+
+// DecisionLogHandler 授权决策日志处理器
+type DecisionLogHandler struct {
+	decisionLogger *service.DecisionLogger
+}
+
+// NewDecisionLogHandler 创建授权决策日志处理器
+func NewDecisionLogHandler(decisionLogger *service.DecisionLogger) *DecisionLogHandler {
+	return &DecisionLogHandler{
+		decisionLogger: decisionLogger,
+	}
+}
+
+// Register 注册管理员路由
+func (h *DecisionLogHandler) Register(router *gin.RouterGroup) {
+	// 查询授权决策日志
+	router.GET("/authz/decisions", h.List)
+}
+
+// List 查询授权决策日志
+// @Summary 查询授权决策日志
+// @Description 查询Casbin中间件和权限校验接口的访问决策（需启用db输出），用于排查被拒绝的访问
+// @Tags 审计
+// @Produce json
+// @Security ApiKeyAuth
+// @Param user_id query int false "用户ID"
+// @Param path query string false "路径前缀"
+// @Param method query string false "HTTP方法"
+// @Param allowed query bool false "是否允许"
+// @Param source query string false "来源：middleware/check_permission"
+// @Param request_id query string false "请求ID"
+// @Param start_time query string false "开始时间"
+// @Param end_time query string false "结束时间"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} dto.Response{data=service.AuthzDecisionListResponse} "获取成功"
+// @Router /authz/decisions [get]
+func (h *DecisionLogHandler) List(c *gin.Context) {
+	var req service.AuthzDecisionListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	resp, err := h.decisionLogger.List(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取授权决策日志成功",
+		Data:    resp,
+	})
+}
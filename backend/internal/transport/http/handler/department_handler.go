@@ -43,6 +43,8 @@ func (h *DepartmentHandler) Register(router *gin.RouterGroup) {
 		departmentRouter.GET("/:id/children", h.GetChildren)
 		// 获取部门树
 		departmentRouter.GET("/tree", h.GetTree)
+		// 移动部门
+		departmentRouter.POST("/:id/move", h.Move)
 	}
 }
 
@@ -445,6 +447,13 @@ func (h *DepartmentHandler) GetChildren(c *gin.Context) {
 }
 
 // GetTree 获取部门树
+// @Summary 获取部门树
+// @Description 按父子关系返回嵌套的部门树，同级部门按排序排列
+// @Tags 部门管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.Response{data=[]service.DepartmentNode} "获取成功"
+// @Router /department/tree [get]
 func (h *DepartmentHandler) GetTree(c *gin.Context) {
 	// 获取当前用户
 	userID := middleware.GetCurrentUser(c)
@@ -495,3 +504,88 @@ func (h *DepartmentHandler) GetTree(c *gin.Context) {
 		Data:    tree,
 	})
 }
+
+// Move 移动部门
+// @Summary 移动部门
+// @Description 将部门连同其下级部门移动到新的父部门下，并重新计算路径和层级
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "部门ID"
+// @Param request body service.MoveDepartmentRequest true "移动请求"
+// @Success 200 {object} dto.Response{data=entity.Department} "移动成功"
+// @Failure 400 {object} dto.Response "请求参数错误"
+// @Router /department/{id}/move [post]
+func (h *DepartmentHandler) Move(c *gin.Context) {
+	// 获取当前用户
+	userID := middleware.GetCurrentUser(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, dto.Response{
+			Code:    dto.CodeUnauthorized,
+			Message: "未认证的用户",
+		})
+		return
+	}
+
+	// 检查权限
+	hasPermission, err := h.authService.CheckPermission(&service.CheckPermissionRequest{
+		UserID:  fmt.Sprintf("%d", userID),
+		APIPath: c.Request.URL.Path,
+		Method:  c.Request.Method,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: "权限检查失败",
+			Data:    err.Error(),
+		})
+		return
+	}
+	if !hasPermission.Allowed {
+		c.JSON(http.StatusForbidden, dto.Response{
+			Code:    dto.CodeForbidden,
+			Message: "无权访问该资源",
+		})
+		return
+	}
+
+	// 获取部门ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "无效的部门ID",
+			Data:    err.Error(),
+		})
+		return
+	}
+
+	// 解析请求
+	var req service.MoveDepartmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "无效的请求参数",
+			Data:    err.Error(),
+		})
+		return
+	}
+
+	// 移动部门
+	department, err := h.departmentService.MoveDepartment(uint(id), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: "移动部门失败",
+			Data:    err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "移动部门成功",
+		Data:    department,
+	})
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+)
+
+// DepartmentReorgHandler 部门合并、拆分处理器
+type DepartmentReorgHandler struct {
+	reorgService *service.DepartmentReorgService
+}
+
+// NewDepartmentReorgHandler 创建部门合并、拆分处理器
+func NewDepartmentReorgHandler(reorgService *service.DepartmentReorgService) *DepartmentReorgHandler {
+	return &DepartmentReorgHandler{
+		reorgService: reorgService,
+	}
+}
+
+// Register 注册路由
+func (h *DepartmentReorgHandler) Register(router *gin.RouterGroup) {
+	deptRouter := router.Group("/department")
+	{
+		deptRouter.POST("/:id/merge/preview", h.PreviewMerge)
+		deptRouter.POST("/:id/merge", h.Merge)
+		deptRouter.POST("/:id/split/preview", h.PreviewSplit)
+		deptRouter.POST("/:id/split", h.Split)
+	}
+}
+
+// PreviewMerge 预览部门合并
+// @Summary 预览部门合并
+// @Description 预览将部门合并到目标部门时转移的用户、角色、业务线、下级部门和改写的策略，不做任何修改
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "源部门ID"
+// @Param request body service.MergeDepartmentRequest true "目标部门"
+// @Success 200 {object} dto.Response{data=service.DepartmentMergePreview} "获取成功"
+// @Router /department/{id}/merge/preview [post]
+func (h *DepartmentReorgHandler) PreviewMerge(c *gin.Context) {
+	id, ok := h.pathID(c)
+	if !ok {
+		return
+	}
+	var req service.MergeDepartmentRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	preview, err := h.reorgService.PreviewMerge(id, &req)
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取成功",
+		Data:    preview,
+	})
+}
+
+// Merge 合并部门
+// @Summary 合并部门
+// @Description 在一个事务中将部门的用户、角色（含部门管理员角色）、业务线及其API、下级部门转移到目标部门，改写部门相关的策略后删除源部门
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "源部门ID"
+// @Param request body service.MergeDepartmentRequest true "目标部门"
+// @Success 200 {object} dto.Response{data=service.DepartmentMergePreview} "合并成功"
+// @Router /department/{id}/merge [post]
+func (h *DepartmentReorgHandler) Merge(c *gin.Context) {
+	id, ok := h.pathID(c)
+	if !ok {
+		return
+	}
+	var req service.MergeDepartmentRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	result, err := h.reorgService.Merge(id, &req)
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "合并成功",
+		Data:    result,
+	})
+}
+
+// PreviewSplit 预览部门拆分
+// @Summary 预览部门拆分
+// @Description 预览从部门拆分出子部门时转移的业务线、用户、部门管理员和新部门维度上的策略，不做任何修改
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "源部门ID"
+// @Param request body service.SplitDepartmentRequest true "拆分信息"
+// @Success 200 {object} dto.Response{data=service.DepartmentSplitPreview} "获取成功"
+// @Router /department/{id}/split/preview [post]
+func (h *DepartmentReorgHandler) PreviewSplit(c *gin.Context) {
+	id, ok := h.pathID(c)
+	if !ok {
+		return
+	}
+	var req service.SplitDepartmentRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	preview, err := h.reorgService.PreviewSplit(id, &req)
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取成功",
+		Data:    preview,
+	})
+}
+
+// Split 拆分部门
+// @Summary 拆分部门
+// @Description 在一个事务中于部门下新建子部门，将选定的业务线（连同其API）和用户转移到子部门，用户在源部门维度上的策略复制到子部门，转移的部门管理员改为子部门的管理员
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "源部门ID"
+// @Param request body service.SplitDepartmentRequest true "拆分信息"
+// @Success 200 {object} dto.Response{data=service.DepartmentSplitPreview} "拆分成功"
+// @Router /department/{id}/split [post]
+func (h *DepartmentReorgHandler) Split(c *gin.Context) {
+	id, ok := h.pathID(c)
+	if !ok {
+		return
+	}
+	var req service.SplitDepartmentRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	result, err := h.reorgService.Split(id, &req)
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "拆分成功",
+		Data:    result,
+	})
+}
+
+// pathID 解析路径中的部门ID
+func (h *DepartmentReorgHandler) pathID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "无效的部门ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// bind 解析JSON请求体
+func (h *DepartmentReorgHandler) bind(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// businessError 返回业务错误
+func (h *DepartmentReorgHandler) businessError(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, dto.Response{
+		Code:    dto.CodeBusinessError,
+		Message: err.Error(),
+	})
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+	"mcprapi/backend/internal/transport/middleware"
+)
+
+// ImpersonationHandler 代登录处理器
+type ImpersonationHandler struct {
+	impersonationService *service.ImpersonationService
+}
+
+// NewImpersonationHandler 创建代登录处理器
+func NewImpersonationHandler(impersonationService *service.ImpersonationService) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		impersonationService: impersonationService,
+	}
+}
+
+// Register 注册管理员路由
+func (h *ImpersonationHandler) Register(router *gin.RouterGroup) {
+	// 代登录指定用户
+	router.POST("/user/:id/impersonate", h.Start)
+	// 代登录日志
+	router.GET("/impersonation/logs", h.ListLogs)
+}
+
+// Start 管理员代登录用户
+// @Summary 代登录用户
+// @Description 签发携带act声明的短期令牌，使用该令牌时按目标用户的权限校验，用于复现用户反馈的权限问题
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "目标用户ID"
+// @Param request body service.ImpersonateRequest true "代登录请求"
+// @Success 200 {object} dto.Response{data=service.ImpersonateResponse} "代登录成功"
+// @Router /user/{id}/impersonate [post]
+func (h *ImpersonationHandler) Start(c *gin.Context) {
+	if middleware.GetCurrentActor(c) != nil || middleware.GetCurrentClientID(c) != "" {
+		c.JSON(http.StatusForbidden, dto.Response{
+			Code:    dto.CodeForbidden,
+			Message: "当前令牌不能发起代登录",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	var req service.ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	resp, err := h.impersonationService.Start(middleware.GetCurrentUser(c), uint(id), c.ClientIP(), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "代登录成功",
+		Data:    resp,
+	})
+}
+
+// Stop 结束代登录
+// @Summary 结束代登录
+// @Description 使用代登录令牌调用，终止该令牌对应的会话
+// @Tags 认证
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.Response "结束成功"
+// @Router /auth/impersonation/stop [post]
+func (h *ImpersonationHandler) Stop(c *gin.Context) {
+	err := h.impersonationService.Stop(middleware.GetCurrentSessionID(c), middleware.GetCurrentActor(c),
+		middleware.GetCurrentUser(c), middleware.GetCurrentUsername(c), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "已结束代登录",
+	})
+}
+
+// ListLogs 查询代登录日志
+// @Summary 查询代登录日志
+// @Tags 用户
+// @Produce json
+// @Security ApiKeyAuth
+// @Param actor_id query int false "管理员ID"
+// @Param user_id query int false "被代登录用户ID"
+// @Param session_id query string false "会话ID"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} dto.Response{data=service.ImpersonationLogListResponse} "获取成功"
+// @Router /impersonation/logs [get]
+func (h *ImpersonationHandler) ListLogs(c *gin.Context) {
+	var req service.ImpersonationLogListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	resp, err := h.impersonationService.ListLogs(&req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取代登录日志成功",
+		Data:    resp,
+	})
+}
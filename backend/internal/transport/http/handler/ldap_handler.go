@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+)
+
+// LDAPHandler LDAP目录同步处理器
+type LDAPHandler struct {
+	ldapService *service.LDAPService
+}
+
+// NewLDAPHandler 创建LDAP目录同步处理器
+func NewLDAPHandler(ldapService *service.LDAPService) *LDAPHandler {
+	return &LDAPHandler{
+		ldapService: ldapService,
+	}
+}
+
+// LDAPSyncRequest 手动同步请求
+type LDAPSyncRequest struct {
+	DryRun bool `json:"dry_run"` // 只生成报告，不写入数据
+}
+
+// Register 注册路由
+func (h *LDAPHandler) Register(router *gin.RouterGroup) {
+	ldapRouter := router.Group("/ldap")
+	{
+		// 手动执行同步
+		ldapRouter.POST("/sync", h.Sync)
+		// 获取最近一次同步报告
+		ldapRouter.GET("/sync/report", h.LastReport)
+	}
+}
+
+// Sync 执行目录同步
+// @Summary 执行LDAP目录同步
+// @Description 将LDAP的OU、用户和组同步为部门、用户和角色，dry_run为true时只返回报告
+// @Tags LDAP
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body LDAPSyncRequest false "同步请求"
+// @Success 200 {object} dto.Response{data=service.LDAPSyncReport} "同步成功"
+// @Failure 400 {object} dto.Response "同步失败"
+// @Router /ldap/sync [post]
+func (h *LDAPHandler) Sync(c *gin.Context) {
+	var req LDAPSyncRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, dto.Response{
+				Code:    dto.CodeInvalidParams,
+				Message: "参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	report, err := h.ldapService.Sync(req.DryRun)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "同步完成",
+		Data:    report,
+	})
+}
+
+// LastReport 获取最近一次同步报告
+// @Summary 获取LDAP同步报告
+// @Tags LDAP
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.Response{data=service.LDAPSyncReport} "获取成功"
+// @Failure 404 {object} dto.Response "尚未同步"
+// @Router /ldap/sync/report [get]
+func (h *LDAPHandler) LastReport(c *gin.Context) {
+	report := h.ldapService.LastReport()
+	if report == nil {
+		c.JSON(http.StatusNotFound, dto.Response{
+			Code:    dto.CodeNotFound,
+			Message: "尚未执行过同步",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取同步报告成功",
+		Data:    report,
+	})
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+	"mcprapi/backend/internal/transport/middleware"
+)
+
+// LoginGuardHandler 登录防护处理器
+type LoginGuardHandler struct {
+	loginGuardService *service.LoginGuardService
+}
+
+// NewLoginGuardHandler 创建登录防护处理器
+func NewLoginGuardHandler(loginGuardService *service.LoginGuardService) *LoginGuardHandler {
+	return &LoginGuardHandler{
+		loginGuardService: loginGuardService,
+	}
+}
+
+// Register 注册管理员路由
+func (h *LoginGuardHandler) Register(router *gin.RouterGroup) {
+	// 解除IP锁定
+	router.POST("/user/unlock-ip", h.UnlockIP)
+	// 解除用户锁定
+	router.POST("/user/:id/unlock", h.Unlock)
+	// 用户登录历史
+	router.GET("/user/:id/login-attempts", h.ListUserAttempts)
+}
+
+// UnlockIPRequest 解除IP锁定请求
+type UnlockIPRequest struct {
+	IP string `json:"ip" binding:"required"`
+}
+
+// Unlock 解除用户锁定
+// @Summary 解除用户锁定
+// @Description 清除用户的登录锁定、递增延迟和失败计数
+// @Tags 用户
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} dto.Response "解锁成功"
+// @Router /user/{id}/unlock [post]
+func (h *LoginGuardHandler) Unlock(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	if err := h.loginGuardService.Unlock(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "解除锁定成功",
+	})
+}
+
+// UnlockIP 解除IP锁定
+// @Summary 解除IP锁定
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body UnlockIPRequest true "IP地址"
+// @Success 200 {object} dto.Response "解锁成功"
+// @Router /user/unlock-ip [post]
+func (h *LoginGuardHandler) UnlockIP(c *gin.Context) {
+	var req UnlockIPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.loginGuardService.UnlockIP(req.IP); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "解除锁定成功",
+	})
+}
+
+// ListUserAttempts 获取用户的登录历史
+// @Summary 获取用户登录历史
+// @Description 返回用户当前的锁定状态和分页登录历史（时间、IP、UA、结果）
+// @Tags 用户
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} dto.Response{data=service.LoginAttemptListResponse} "获取成功"
+// @Router /user/{id}/login-attempts [get]
+func (h *LoginGuardHandler) ListUserAttempts(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+	h.listAttempts(c, uint(id))
+}
+
+// ListMyAttempts 获取当前用户的登录历史
+// @Summary 获取我的登录历史
+// @Tags 用户
+// @Produce json
+// @Security ApiKeyAuth
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} dto.Response{data=service.LoginAttemptListResponse} "获取成功"
+// @Router /user/login-attempts [get]
+func (h *LoginGuardHandler) ListMyAttempts(c *gin.Context) {
+	h.listAttempts(c, middleware.GetCurrentUser(c))
+}
+
+// listAttempts 分页查询登录历史
+func (h *LoginGuardHandler) listAttempts(c *gin.Context, userID uint) {
+	var req service.LoginAttemptListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	resp, err := h.loginGuardService.ListAttempts(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取登录历史成功",
+		Data:    resp,
+	})
+}
@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+	"mcprapi/backend/internal/transport/middleware"
+)
+
+// MFAHandler 多因素认证处理器
+type MFAHandler struct {
+	mfaService *service.MFAService
+}
+
+// NewMFAHandler 创建多因素认证处理器
+func NewMFAHandler(mfaService *service.MFAService) *MFAHandler {
+	return &MFAHandler{
+		mfaService: mfaService,
+	}
+}
+
+// Register 注册管理员路由
+func (h *MFAHandler) Register(router *gin.RouterGroup) {
+	// 重置用户的多因素认证
+	router.DELETE("/user/:id/mfa", h.Reset)
+}
+
+// Verify 登录二次验证
+// @Summary 登录二次验证
+// @Description 使用登录返回的mfa_token和验证码（或恢复码）完成登录；首次强制绑定时同时返回恢复码
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body service.MFAVerifyRequest true "二次验证请求"
+// @Success 200 {object} dto.Response{data=service.MFAVerifyResponse} "登录成功"
+// @Failure 401 {object} dto.Response "验证失败"
+// @Router /auth/mfa/verify [post]
+func (h *MFAHandler) Verify(c *gin.Context) {
+	var req service.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	resp, err := h.mfaService.VerifyLogin(&req)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.Response{
+			Code:    dto.CodeUnauthorized,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	message := "登录成功"
+	if resp.PasswordChangeRequired {
+		message = "密码已过期，请修改密码"
+	}
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: message,
+		Data:    resp,
+	})
+}
+
+// EnrollOnLogin 登录时强制绑定验证器
+// @Summary 登录时绑定验证器
+// @Description 角色要求MFA但尚未绑定时，凭mfa_token获取TOTP密钥，再调用二次验证接口完成绑定和登录
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body service.MFAChallengeRequest true "二次验证凭证"
+// @Success 200 {object} dto.Response{data=service.MFAEnrollResponse} "获取成功"
+// @Router /auth/mfa/enroll [post]
+func (h *MFAHandler) EnrollOnLogin(c *gin.Context) {
+	var req service.MFAChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	resp, err := h.mfaService.EnrollWithChallenge(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取验证器密钥成功",
+		Data:    resp,
+	})
+}
+
+// Status 获取当前用户的多因素认证状态
+// @Summary 获取MFA状态
+// @Tags 用户
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.Response{data=service.MFAStatus} "获取成功"
+// @Router /user/mfa [get]
+func (h *MFAHandler) Status(c *gin.Context) {
+	status, err := h.mfaService.GetStatus(middleware.GetCurrentUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取MFA状态成功",
+		Data:    status,
+	})
+}
+
+// Enroll 绑定验证器
+// @Summary 绑定验证器
+// @Description 生成TOTP密钥和otpauth地址，调用激活接口验证后生效
+// @Tags 用户
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.Response{data=service.MFAEnrollResponse} "获取成功"
+// @Router /user/mfa/enroll [post]
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	if !h.allowUserSession(c) {
+		return
+	}
+
+	resp, err := h.mfaService.Enroll(middleware.GetCurrentUser(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取验证器密钥成功",
+		Data:    resp,
+	})
+}
+
+// Activate 激活多因素认证
+// @Summary 激活MFA
+// @Description 验证验证器生成的验证码后启用MFA，返回只展示一次的恢复码
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.MFACodeRequest true "验证码"
+// @Success 200 {object} dto.Response{data=[]string} "启用成功"
+// @Router /user/mfa/activate [post]
+func (h *MFAHandler) Activate(c *gin.Context) {
+	h.withCode(c, func(userID uint, req *service.MFACodeRequest) (interface{}, error) {
+		codes, err := h.mfaService.Activate(userID, req)
+		return gin.H{"recovery_codes": codes}, err
+	}, "启用多因素认证成功")
+}
+
+// Disable 停用多因素认证
+// @Summary 停用MFA
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.MFACodeRequest true "验证码"
+// @Success 200 {object} dto.Response "停用成功"
+// @Router /user/mfa/disable [post]
+func (h *MFAHandler) Disable(c *gin.Context) {
+	h.withCode(c, func(userID uint, req *service.MFACodeRequest) (interface{}, error) {
+		return nil, h.mfaService.Disable(userID, req)
+	}, "停用多因素认证成功")
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+// @Summary 重新生成恢复码
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.MFACodeRequest true "验证码"
+// @Success 200 {object} dto.Response{data=[]string} "生成成功"
+// @Router /user/mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	h.withCode(c, func(userID uint, req *service.MFACodeRequest) (interface{}, error) {
+		codes, err := h.mfaService.RegenerateRecoveryCodes(userID, req)
+		return gin.H{"recovery_codes": codes}, err
+	}, "生成恢复码成功")
+}
+
+// Reset 管理员重置用户的多因素认证
+// @Summary 重置用户MFA
+// @Description 清除用户的验证器和恢复码，用户下次登录时需重新绑定
+// @Tags 用户
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} dto.Response "重置成功"
+// @Router /user/{id}/mfa [delete]
+func (h *MFAHandler) Reset(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	if err := h.mfaService.Reset(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "重置多因素认证成功",
+	})
+}
+
+// withCode 绑定验证码请求并执行操作
+func (h *MFAHandler) withCode(c *gin.Context, fn func(userID uint, req *service.MFACodeRequest) (interface{}, error), message string) {
+	if !h.allowUserSession(c) {
+		return
+	}
+
+	var req service.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	data, err := fn(middleware.GetCurrentUser(c), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: message,
+		Data:    data,
+	})
+}
+
+// allowUserSession OAuth2客户端令牌不能修改用户的多因素认证
+func (h *MFAHandler) allowUserSession(c *gin.Context) bool {
+	if middleware.GetCurrentClientID(c) != "" {
+		c.JSON(http.StatusForbidden, dto.Response{
+			Code:    dto.CodeForbidden,
+			Message: "OAuth2令牌不能修改多因素认证",
+		})
+		return false
+	}
+	return true
+}
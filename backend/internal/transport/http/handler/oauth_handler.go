@@ -0,0 +1,367 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+	"mcprapi/backend/internal/transport/middleware"
+)
+
+// OAuthHandler OAuth2处理器
+type OAuthHandler struct {
+	oauthService *service.OAuthService
+}
+
+// NewOAuthHandler 创建OAuth2处理器
+func NewOAuthHandler(oauthService *service.OAuthService) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: oauthService,
+	}
+}
+
+// Register 注册客户端管理路由
+func (h *OAuthHandler) Register(router *gin.RouterGroup) {
+	clientRouter := router.Group("/oauth/clients")
+	{
+		// 注册客户端
+		clientRouter.POST("", h.CreateClient)
+		// 更新客户端
+		clientRouter.PUT("/:id", h.UpdateClient)
+		// 删除客户端
+		clientRouter.DELETE("/:id", h.DeleteClient)
+		// 获取客户端
+		clientRouter.GET("/:id", h.GetClient)
+		// 获取客户端列表
+		clientRouter.GET("", h.ListClients)
+		// 重置客户端密钥
+		clientRouter.POST("/:id/secret", h.RotateSecret)
+	}
+}
+
+// Token 签发访问令牌
+// @Summary OAuth2令牌端点
+// @Description 支持client_credentials和authorization_code（PKCE）授权类型，请求体为application/x-www-form-urlencoded
+// @Tags OAuth2
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "授权类型"
+// @Success 200 {object} service.TokenResponse "签发成功"
+// @Failure 400 {object} service.OAuthError "请求错误"
+// @Failure 401 {object} service.OAuthError "客户端认证失败"
+// @Router /oauth/token [post]
+func (h *OAuthHandler) Token(c *gin.Context) {
+	var req service.TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, service.OAuthError{Code: "invalid_request", Description: err.Error()})
+		return
+	}
+
+	// 支持HTTP Basic方式传递客户端凭证
+	if clientID, clientSecret, ok := c.Request.BasicAuth(); ok {
+		req.ClientID = clientID
+		req.ClientSecret = clientSecret
+	}
+
+	resp, err := h.oauthService.Token(&req)
+	if err != nil {
+		h.writeOAuthError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetAuthorize 获取授权确认信息
+// @Summary OAuth2授权确认信息
+// @Description 校验授权请求，返回客户端和scope信息供前端展示授权确认页
+// @Tags OAuth2
+// @Produce json
+// @Security ApiKeyAuth
+// @Param response_type query string true "固定为code"
+// @Param client_id query string true "客户端ID"
+// @Param redirect_uri query string false "回调地址"
+// @Param scope query string false "scope"
+// @Param state query string false "state"
+// @Param code_challenge query string true "PKCE code_challenge"
+// @Param code_challenge_method query string true "固定为S256"
+// @Success 200 {object} dto.Response{data=service.AuthorizeInfo} "获取成功"
+// @Failure 400 {object} dto.Response "请求错误"
+// @Router /oauth/authorize [get]
+func (h *OAuthHandler) GetAuthorize(c *gin.Context) {
+	var req service.AuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	info, err := h.oauthService.GetAuthorizeInfo(middleware.GetCurrentUser(c), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取授权信息成功",
+		Data:    info,
+	})
+}
+
+// Authorize 确认或拒绝授权
+// @Summary OAuth2授权确认
+// @Description 当前登录用户确认或拒绝授权，返回携带code或error的回调地址
+// @Tags OAuth2
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.ApproveAuthorizeRequest true "授权确认请求"
+// @Success 200 {object} dto.Response{data=map[string]string} "处理成功"
+// @Failure 400 {object} dto.Response "请求错误"
+// @Router /oauth/authorize [post]
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	// OAuth2令牌不能用于授权其他客户端
+	if middleware.GetCurrentClientID(c) != "" {
+		c.JSON(http.StatusForbidden, dto.Response{
+			Code:    dto.CodeForbidden,
+			Message: "OAuth2令牌不能用于授权",
+		})
+		return
+	}
+	if rejectImpersonation(c) {
+		return
+	}
+
+	var req service.ApproveAuthorizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	redirectURL, err := h.oauthService.ApproveAuthorize(middleware.GetCurrentUser(c), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "授权处理成功",
+		Data:    gin.H{"redirect_url": redirectURL},
+	})
+}
+
+// CreateClient 注册客户端
+// @Summary 注册OAuth2客户端
+// @Description 为业务线注册OAuth2客户端，客户端密钥仅在本次响应中返回
+// @Tags OAuth2
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.CreateOAuthClientRequest true "注册客户端请求"
+// @Success 200 {object} dto.Response{data=service.OAuthClientWithSecret} "注册成功"
+// @Failure 400 {object} dto.Response "参数错误或业务错误"
+// @Router /oauth/clients [post]
+func (h *OAuthHandler) CreateClient(c *gin.Context) {
+	var req service.CreateOAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	client, err := h.oauthService.CreateClient(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "注册客户端成功",
+		Data:    client,
+	})
+}
+
+// UpdateClient 更新客户端
+func (h *OAuthHandler) UpdateClient(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "无效的客户端ID",
+		})
+		return
+	}
+
+	var req service.UpdateOAuthClientRequest
+	req.ID = uint(id)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+	req.ID = uint(id)
+
+	client, err := h.oauthService.UpdateClient(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "更新客户端成功",
+		Data:    client,
+	})
+}
+
+// DeleteClient 删除客户端
+func (h *OAuthHandler) DeleteClient(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "无效的客户端ID",
+		})
+		return
+	}
+
+	if err := h.oauthService.DeleteClient(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "删除客户端成功",
+	})
+}
+
+// GetClient 获取客户端
+func (h *OAuthHandler) GetClient(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "无效的客户端ID",
+		})
+		return
+	}
+
+	client, err := h.oauthService.GetClient(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if client == nil {
+		c.JSON(http.StatusNotFound, dto.Response{
+			Code:    dto.CodeNotFound,
+			Message: "客户端不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取客户端成功",
+		Data:    client,
+	})
+}
+
+// ListClients 获取客户端列表
+func (h *OAuthHandler) ListClients(c *gin.Context) {
+	businessID, _ := strconv.ParseUint(c.DefaultQuery("business_id", "0"), 10, 32)
+
+	clients, err := h.oauthService.ListClients(uint(businessID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取客户端列表成功",
+		Data:    clients,
+	})
+}
+
+// RotateSecret 重置客户端密钥
+func (h *OAuthHandler) RotateSecret(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "无效的客户端ID",
+		})
+		return
+	}
+
+	client, err := h.oauthService.RotateClientSecret(uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "重置客户端密钥成功",
+		Data:    client,
+	})
+}
+
+// writeOAuthError 按RFC 6749格式输出错误
+func (h *OAuthHandler) writeOAuthError(c *gin.Context, err error) {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		c.JSON(http.StatusInternalServerError, service.OAuthError{Code: "server_error", Description: err.Error()})
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == "invalid_client" {
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		status = http.StatusUnauthorized
+	}
+	c.JSON(status, oauthErr)
+}
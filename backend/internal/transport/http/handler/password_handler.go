@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+	"mcprapi/backend/internal/transport/middleware"
+)
+
+// PasswordHandler 密码管理处理器
+type PasswordHandler struct {
+	passwordService *service.PasswordService
+}
+
+// NewPasswordHandler 创建密码管理处理器
+func NewPasswordHandler(passwordService *service.PasswordService) *PasswordHandler {
+	return &PasswordHandler{
+		passwordService: passwordService,
+	}
+}
+
+// Register 注册管理员路由
+func (h *PasswordHandler) Register(router *gin.RouterGroup) {
+	// 发起密码重置，令牌通过通知渠道下发
+	router.POST("/user/:id/password/reset", h.RequestReset)
+	// 要求用户下次登录时修改密码
+	router.POST("/user/:id/password/expire", h.Expire)
+}
+
+// Policy 获取密码策略
+// @Summary 获取密码策略
+// @Tags 认证
+// @Produce json
+// @Success 200 {object} dto.Response{data=service.PasswordPolicy} "获取成功"
+// @Router /auth/password/policy [get]
+func (h *PasswordHandler) Policy(c *gin.Context) {
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取密码策略成功",
+		Data:    h.passwordService.GetPolicy(),
+	})
+}
+
+// ChangeExpired 登录时修改过期密码
+// @Summary 修改过期密码
+// @Description 登录返回password_change_required时，凭password_change_token设置新密码并完成登录
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body service.ChangeExpiredPasswordRequest true "修改密码请求"
+// @Success 200 {object} dto.Response{data=service.LoginResponse} "登录成功"
+// @Router /auth/password/change [post]
+func (h *PasswordHandler) ChangeExpired(c *gin.Context) {
+	var req service.ChangeExpiredPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	resp, err := h.passwordService.ChangeExpiredPassword(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "登录成功",
+		Data:    resp,
+	})
+}
+
+// Reset 使用重置令牌设置新密码
+// @Summary 重置密码
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body service.ResetPasswordRequest true "重置密码请求"
+// @Success 200 {object} dto.Response "重置成功"
+// @Router /auth/password/reset [post]
+func (h *PasswordHandler) Reset(c *gin.Context) {
+	var req service.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.passwordService.ResetPassword(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "重置密码成功，请使用新密码登录",
+	})
+}
+
+// Status 获取当前用户的密码状态
+// @Summary 获取密码状态
+// @Tags 用户
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.Response{data=service.PasswordStatus} "获取成功"
+// @Router /user/password [get]
+func (h *PasswordHandler) Status(c *gin.Context) {
+	status, err := h.passwordService.GetStatus(middleware.GetCurrentUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取密码状态成功",
+		Data:    status,
+	})
+}
+
+// Change 修改当前用户的密码
+// @Summary 修改密码
+// @Description 修改成功后除当前会话外的其他会话全部终止，返回新的登录令牌
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.ChangePasswordRequest true "修改密码请求"
+// @Success 200 {object} dto.Response{data=service.LoginResponse} "修改成功"
+// @Router /user/password [put]
+func (h *PasswordHandler) Change(c *gin.Context) {
+	if middleware.GetCurrentClientID(c) != "" {
+		c.JSON(http.StatusForbidden, dto.Response{
+			Code:    dto.CodeForbidden,
+			Message: "OAuth2令牌不能修改密码",
+		})
+		return
+	}
+
+	var req service.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	resp, err := h.passwordService.ChangePassword(middleware.GetCurrentUser(c), middleware.GetCurrentSessionID(c), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "修改密码成功",
+		Data:    resp,
+	})
+}
+
+// RequestReset 管理员发起密码重置
+// @Summary 发起密码重置
+// @Description 生成一次性重置令牌并通过通知渠道发送给用户，之前未使用的重置令牌作废
+// @Tags 用户
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} dto.Response{data=service.PasswordResetResult} "发起成功"
+// @Router /user/{id}/password/reset [post]
+func (h *PasswordHandler) RequestReset(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	result, err := h.passwordService.RequestReset(uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "已发送密码重置通知",
+		Data:    result,
+	})
+}
+
+// Expire 要求用户下次登录时修改密码
+// @Summary 强制修改密码
+// @Tags 用户
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} dto.Response "设置成功"
+// @Router /user/{id}/password/expire [post]
+func (h *PasswordHandler) Expire(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	if err := h.passwordService.ExpirePassword(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "用户下次登录时需修改密码",
+	})
+}
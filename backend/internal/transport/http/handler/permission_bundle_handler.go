@@ -0,0 +1,397 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+	"mcprapi/backend/internal/transport/middleware"
+)
+
+// PermissionBundleHandler 权限模板处理器
+type PermissionBundleHandler struct {
+	bundleService *service.PermissionBundleService
+}
+
+// NewPermissionBundleHandler 创建权限模板处理器
+func NewPermissionBundleHandler(bundleService *service.PermissionBundleService) *PermissionBundleHandler {
+	return &PermissionBundleHandler{
+		bundleService: bundleService,
+	}
+}
+
+// Register 注册路由
+func (h *PermissionBundleHandler) Register(router *gin.RouterGroup) {
+	bundleRouter := router.Group("/permission-bundle")
+	{
+		bundleRouter.GET("/list", h.List)
+		bundleRouter.GET("/:id", h.Get)
+		bundleRouter.GET("/:id/versions", h.ListVersions)
+		bundleRouter.GET("/:id/apis", h.Resolve)
+		bundleRouter.POST("", h.Create)
+		bundleRouter.PUT("/:id", h.Update)
+		bundleRouter.DELETE("/:id", h.Delete)
+		bundleRouter.POST("/:id/apply", h.Apply)
+		bundleRouter.POST("/:id/reapply", h.Reapply)
+		bundleRouter.DELETE("/:id/roles/:role_id", h.Revoke)
+	}
+
+	router.GET("/role/:id/permission-bundles", h.GetRoleBundles)
+}
+
+// List 获取权限模板列表
+// @Summary 获取权限模板列表
+// @Description 获取所有权限模板及其当前版本
+// @Tags 权限模板
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.Response{data=[]entity.PermissionBundle} "获取成功"
+// @Router /permission-bundle/list [get]
+func (h *PermissionBundleHandler) List(c *gin.Context) {
+	bundles, err := h.bundleService.ListBundles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取成功",
+		Data:    bundles,
+	})
+}
+
+// Get 获取权限模板详情
+// @Summary 获取权限模板详情
+// @Description 获取权限模板当前版本的规则，以及应用了该模板的角色和其应用的版本
+// @Tags 权限模板
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "模板ID"
+// @Success 200 {object} dto.Response{data=service.PermissionBundleDetail} "获取成功"
+// @Router /permission-bundle/{id} [get]
+func (h *PermissionBundleHandler) Get(c *gin.Context) {
+	id, ok := h.pathID(c, "id", "无效的模板ID")
+	if !ok {
+		return
+	}
+
+	detail, err := h.bundleService.GetBundle(id)
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取成功",
+		Data:    detail,
+	})
+}
+
+// ListVersions 获取权限模板的版本历史
+// @Summary 获取权限模板版本
+// @Description 获取权限模板的所有版本及各版本的规则，新版本在前
+// @Tags 权限模板
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "模板ID"
+// @Success 200 {object} dto.Response{data=[]service.PermissionBundleVersionInfo} "获取成功"
+// @Router /permission-bundle/{id}/versions [get]
+func (h *PermissionBundleHandler) ListVersions(c *gin.Context) {
+	id, ok := h.pathID(c, "id", "无效的模板ID")
+	if !ok {
+		return
+	}
+
+	versions, err := h.bundleService.ListVersions(id)
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取成功",
+		Data:    versions,
+	})
+}
+
+// Resolve 获取权限模板当前包含的API
+// @Summary 预览权限模板包含的API
+// @Description 按模板规则解析当前包含的API，即应用到角色时会授予的API
+// @Tags 权限模板
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "模板ID"
+// @Param version query int false "模板版本，默认为最新版本"
+// @Success 200 {object} dto.Response{data=[]entity.API} "获取成功"
+// @Router /permission-bundle/{id}/apis [get]
+func (h *PermissionBundleHandler) Resolve(c *gin.Context) {
+	id, ok := h.pathID(c, "id", "无效的模板ID")
+	if !ok {
+		return
+	}
+	version, _ := strconv.Atoi(c.Query("version"))
+
+	apis, err := h.bundleService.Resolve(id, version)
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取成功",
+		Data:    apis,
+	})
+}
+
+// Create 创建权限模板
+// @Summary 创建权限模板
+// @Description 创建权限模板，规则可以是指定的API、分类、业务线或keyMatch2路径模式，并可限定HTTP方法
+// @Tags 权限模板
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.CreatePermissionBundleRequest true "模板信息"
+// @Success 200 {object} dto.Response{data=entity.PermissionBundle} "创建成功"
+// @Router /permission-bundle [post]
+func (h *PermissionBundleHandler) Create(c *gin.Context) {
+	var req service.CreatePermissionBundleRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	bundle, err := h.bundleService.CreateBundle(&req, middleware.GetCurrentUsername(c))
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "创建成功",
+		Data:    bundle,
+	})
+}
+
+// Update 更新权限模板
+// @Summary 更新权限模板
+// @Description 更新权限模板，规则变化时生成新版本；已应用的角色在重新应用后使用新版本
+// @Tags 权限模板
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "模板ID"
+// @Param request body service.UpdatePermissionBundleRequest true "模板信息"
+// @Success 200 {object} dto.Response{data=entity.PermissionBundle} "更新成功"
+// @Router /permission-bundle/{id} [put]
+func (h *PermissionBundleHandler) Update(c *gin.Context) {
+	id, ok := h.pathID(c, "id", "无效的模板ID")
+	if !ok {
+		return
+	}
+	var req service.UpdatePermissionBundleRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	bundle, err := h.bundleService.UpdateBundle(id, &req, middleware.GetCurrentUsername(c))
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "更新成功",
+		Data:    bundle,
+	})
+}
+
+// Delete 删除权限模板
+// @Summary 删除权限模板
+// @Description 删除权限模板及其版本历史，已应用到角色的模板需先撤销
+// @Tags 权限模板
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "模板ID"
+// @Success 200 {object} dto.Response "删除成功"
+// @Router /permission-bundle/{id} [delete]
+func (h *PermissionBundleHandler) Delete(c *gin.Context) {
+	id, ok := h.pathID(c, "id", "无效的模板ID")
+	if !ok {
+		return
+	}
+
+	if err := h.bundleService.DeleteBundle(id); err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "删除成功",
+	})
+}
+
+// Apply 将权限模板应用到角色
+// @Summary 应用权限模板
+// @Description 为角色生成模板包含的API的策略，不影响角色已有的其他权限；prune为true时删除模板之前生成、但已不再属于模板的策略
+// @Tags 权限模板
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "模板ID"
+// @Param request body service.ApplyPermissionBundleRequest true "应用参数"
+// @Success 200 {object} dto.Response{data=[]service.PermissionBundleApplyResult} "应用成功"
+// @Router /permission-bundle/{id}/apply [post]
+func (h *PermissionBundleHandler) Apply(c *gin.Context) {
+	id, ok := h.pathID(c, "id", "无效的模板ID")
+	if !ok {
+		return
+	}
+	var req service.ApplyPermissionBundleRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	results, err := h.bundleService.Apply(id, &req)
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "应用成功",
+		Data:    results,
+	})
+}
+
+// Reapply 重新应用权限模板
+// @Summary 重新应用权限模板
+// @Description 按最新版本重新应用到所有已应用该模板的角色，补齐之后加入分类、业务线或匹配路径模式的API，并删除已不再属于模板的策略
+// @Tags 权限模板
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "模板ID"
+// @Success 200 {object} dto.Response{data=[]service.PermissionBundleApplyResult} "应用成功"
+// @Router /permission-bundle/{id}/reapply [post]
+func (h *PermissionBundleHandler) Reapply(c *gin.Context) {
+	id, ok := h.pathID(c, "id", "无效的模板ID")
+	if !ok {
+		return
+	}
+
+	results, err := h.bundleService.Reapply(id)
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "应用成功",
+		Data:    results,
+	})
+}
+
+// Revoke 撤销角色上的权限模板
+// @Summary 撤销权限模板
+// @Description 撤销角色上的权限模板并删除模板生成的策略，其他模板或API组仍授予的策略保留
+// @Tags 权限模板
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "模板ID"
+// @Param role_id path int true "角色ID"
+// @Success 200 {object} dto.Response "撤销成功"
+// @Router /permission-bundle/{id}/roles/{role_id} [delete]
+func (h *PermissionBundleHandler) Revoke(c *gin.Context) {
+	id, ok := h.pathID(c, "id", "无效的模板ID")
+	if !ok {
+		return
+	}
+	roleID, ok := h.pathID(c, "role_id", "无效的角色ID")
+	if !ok {
+		return
+	}
+
+	if err := h.bundleService.Revoke(id, roleID); err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "撤销成功",
+	})
+}
+
+// GetRoleBundles 获取角色应用的权限模板
+// @Summary 获取角色的权限模板
+// @Description 获取角色应用的权限模板及应用的版本
+// @Tags 权限模板
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "角色ID"
+// @Success 200 {object} dto.Response{data=[]service.RolePermissionBundleInfo} "获取成功"
+// @Router /role/{id}/permission-bundles [get]
+func (h *PermissionBundleHandler) GetRoleBundles(c *gin.Context) {
+	roleID, ok := h.pathID(c, "id", "无效的角色ID")
+	if !ok {
+		return
+	}
+
+	bundles, err := h.bundleService.GetRoleBundles(roleID)
+	if err != nil {
+		h.businessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取成功",
+		Data:    bundles,
+	})
+}
+
+// pathID 解析路径中的ID
+func (h *PermissionBundleHandler) pathID(c *gin.Context, param, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: message,
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// bind 解析JSON请求体
+func (h *PermissionBundleHandler) bind(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// businessError 返回业务错误
+func (h *PermissionBundleHandler) businessError(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, dto.Response{
+		Code:    dto.CodeBusinessError,
+		Message: err.Error(),
+	})
+}
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+	"mcprapi/backend/internal/transport/middleware"
+)
+
+// qrCodeMaxWait 长轮询最长等待时间
+const qrCodeMaxWait = 30 * time.Second
+
+// QRCodeHandler 扫码登录处理器
+type QRCodeHandler struct {
+	qrCodeService *service.QRCodeService
+}
+
+// NewQRCodeHandler 创建扫码登录处理器
+func NewQRCodeHandler(qrCodeService *service.QRCodeService) *QRCodeHandler {
+	return &QRCodeHandler{
+		qrCodeService: qrCodeService,
+	}
+}
+
+// CheckQRCodeRequest 查询二维码状态请求
+type CheckQRCodeRequest struct {
+	Secret string `form:"secret" binding:"required"` // 生成二维码时返回的凭证
+	Wait   int    `form:"wait"`                      // 长轮询等待秒数，0表示立即返回
+	Status string `form:"status"`                    // 浏览器已知的状态，状态变化时立即返回
+}
+
+// Generate 生成扫码登录二维码
+// @Summary 生成扫码登录二维码
+// @Description 生成二维码内容和查询凭证，浏览器凭secret查询状态
+// @Tags 认证
+// @Produce json
+// @Success 200 {object} dto.Response{data=service.GenerateQRCodeResponse} "生成成功"
+// @Router /auth/qrcode [post]
+func (h *QRCodeHandler) Generate(c *gin.Context) {
+	resp, err := h.qrCodeService.Generate(&service.GenerateQRCodeRequest{
+		ClientIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: "生成二维码失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "生成二维码成功",
+		Data:    resp,
+	})
+}
+
+// Check 查询二维码状态
+// @Summary 查询扫码登录状态
+// @Description 支持长轮询（wait参数）；请求头Accept为text/event-stream时以SSE推送状态变化，确认后返回登录令牌
+// @Tags 认证
+// @Produce json
+// @Param id path string true "二维码ID"
+// @Param secret query string true "生成二维码时返回的凭证"
+// @Param wait query int false "长轮询等待秒数，最长30秒"
+// @Param status query string false "浏览器已知的状态"
+// @Success 200 {object} dto.Response{data=service.QRCodeStatusResponse} "查询成功"
+// @Router /auth/qrcode/{id} [get]
+func (h *QRCodeHandler) Check(c *gin.Context) {
+	var req CheckQRCodeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+		h.stream(c, &req)
+		return
+	}
+
+	wait := time.Duration(req.Wait) * time.Second
+	if wait > qrCodeMaxWait {
+		wait = qrCodeMaxWait
+	}
+
+	resp, err := h.qrCodeService.Wait(c.Request.Context(), c.Param("id"), req.Secret, req.Status, wait)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "二维码状态检查成功",
+		Data:    resp,
+	})
+}
+
+// stream 以SSE推送状态变化，到达终态或客户端断开后结束
+func (h *QRCodeHandler) stream(c *gin.Context, req *CheckQRCodeRequest) {
+	id := c.Param("id")
+	lastStatus := req.Status
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Stream(func(w io.Writer) bool {
+		resp, err := h.qrCodeService.Wait(c.Request.Context(), id, req.Secret, lastStatus, qrCodeMaxWait)
+		if err != nil {
+			c.SSEvent("error", dto.Response{Code: dto.CodeBusinessError, Message: err.Error()})
+			return false
+		}
+		if c.Request.Context().Err() != nil {
+			return false
+		}
+		if resp.Status != lastStatus {
+			c.SSEvent("status", resp)
+			lastStatus = resp.Status
+		} else {
+			// 保持连接
+			_, _ = io.WriteString(w, ": ping\n\n")
+		}
+		return resp.Login == nil && resp.Status != service.QRCodeStatusExpired && resp.Status != service.QRCodeStatusCancelled
+	})
+}
+
+// Scan 手机端扫码
+// @Summary 扫描登录二维码
+// @Description 已登录的手机端扫码，返回待登录浏览器的信息供用户确认
+// @Tags 认证
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "二维码ID"
+// @Success 200 {object} dto.Response{data=service.QRCodeScanResponse} "扫码成功"
+// @Router /auth/qrcode/{id}/scan [post]
+func (h *QRCodeHandler) Scan(c *gin.Context) {
+	if rejectImpersonation(c) || !h.allowMobileSession(c) {
+		return
+	}
+
+	resp, err := h.qrCodeService.Scan(c.Param("id"), middleware.GetCurrentUser(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "扫码成功",
+		Data:    resp,
+	})
+}
+
+// Confirm 手机端确认登录
+// @Summary 确认扫码登录
+// @Description 扫码用户确认后为浏览器签发登录令牌
+// @Tags 认证
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "二维码ID"
+// @Success 200 {object} dto.Response "确认成功"
+// @Router /auth/qrcode/{id}/confirm [post]
+func (h *QRCodeHandler) Confirm(c *gin.Context) {
+	if rejectImpersonation(c) || !h.allowMobileSession(c) {
+		return
+	}
+
+	if err := h.qrCodeService.Confirm(c.Param("id"), middleware.GetCurrentUser(c)); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "确认登录成功",
+	})
+}
+
+// Cancel 手机端取消登录
+// @Summary 取消扫码登录
+// @Tags 认证
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "二维码ID"
+// @Success 200 {object} dto.Response "取消成功"
+// @Router /auth/qrcode/{id}/cancel [post]
+func (h *QRCodeHandler) Cancel(c *gin.Context) {
+	if err := h.qrCodeService.Cancel(c.Param("id"), middleware.GetCurrentUser(c)); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "已取消登录",
+	})
+}
+
+// allowMobileSession 只允许用户本人的登录会话扫码，OAuth2客户端令牌不能代替用户确认
+func (h *QRCodeHandler) allowMobileSession(c *gin.Context) bool {
+	if middleware.GetCurrentClientID(c) != "" {
+		c.JSON(http.StatusForbidden, dto.Response{
+			Code:    dto.CodeForbidden,
+			Message: "OAuth2令牌不能用于扫码登录",
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,340 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+)
+
+// scimContentType SCIM响应的内容类型
+const scimContentType = "application/scim+json"
+
+// SCIMHandler SCIM 2.0 用户和组同步处理器
+type SCIMHandler struct {
+	scimService *service.SCIMService
+}
+
+// NewSCIMHandler 创建SCIM处理器
+func NewSCIMHandler(scimService *service.SCIMService) *SCIMHandler {
+	return &SCIMHandler{
+		scimService: scimService,
+	}
+}
+
+// Register 注册路由，router需已挂载SCIM令牌认证中间件
+func (h *SCIMHandler) Register(router *gin.RouterGroup) {
+	router.GET("/ServiceProviderConfig", h.ServiceProviderConfig)
+
+	userRouter := router.Group("/Users")
+	{
+		userRouter.GET("", h.ListUsers)
+		userRouter.POST("", h.CreateUser)
+		userRouter.GET("/:id", h.GetUser)
+		userRouter.PUT("/:id", h.ReplaceUser)
+		userRouter.PATCH("/:id", h.PatchUser)
+		userRouter.DELETE("/:id", h.DeleteUser)
+	}
+
+	groupRouter := router.Group("/Groups")
+	{
+		groupRouter.GET("", h.ListGroups)
+		groupRouter.POST("", h.CreateGroup)
+		groupRouter.GET("/:id", h.GetGroup)
+		groupRouter.PUT("/:id", h.ReplaceGroup)
+		groupRouter.PATCH("/:id", h.PatchGroup)
+		groupRouter.DELETE("/:id", h.DeleteGroup)
+	}
+}
+
+// ServiceProviderConfig 服务能力声明
+// @Summary SCIM服务能力
+// @Tags SCIM
+// @Produce json
+// @Router /scim/v2/ServiceProviderConfig [get]
+func (h *SCIMHandler) ServiceProviderConfig(c *gin.Context) {
+	h.respond(c, http.StatusOK, gin.H{
+		"schemas":        []string{"urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"},
+		"patch":          gin.H{"supported": true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": 200},
+		"changePassword": gin.H{"supported": true},
+		"sort":           gin.H{"supported": false},
+		"etag":           gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "使用配置的SCIM预置令牌认证",
+		}},
+	})
+}
+
+// ListUsers 查询用户
+// @Summary SCIM查询用户
+// @Tags SCIM
+// @Produce json
+// @Param filter query string false "过滤条件，如 userName eq \"alice\""
+// @Param startIndex query int false "起始位置，从1开始"
+// @Param count query int false "每页数量"
+// @Success 200 {object} service.SCIMListResponse
+// @Router /scim/v2/Users [get]
+func (h *SCIMHandler) ListUsers(c *gin.Context) {
+	var req service.SCIMListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.fail(c, service.NewSCIMError(http.StatusBadRequest, "invalidValue", "参数错误: "+err.Error()))
+		return
+	}
+
+	resp, err := h.scimService.ListUsers(&req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, resp)
+}
+
+// GetUser 获取用户
+// @Summary SCIM获取用户
+// @Tags SCIM
+// @Produce json
+// @Param id path string true "用户ID"
+// @Success 200 {object} service.SCIMUser
+// @Router /scim/v2/Users/{id} [get]
+func (h *SCIMHandler) GetUser(c *gin.Context) {
+	user, err := h.scimService.GetUser(c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, user)
+}
+
+// CreateUser 创建用户
+// @Summary SCIM创建用户
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Param request body service.SCIMUser true "用户资源"
+// @Success 201 {object} service.SCIMUser
+// @Router /scim/v2/Users [post]
+func (h *SCIMHandler) CreateUser(c *gin.Context) {
+	var req service.SCIMUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.fail(c, service.NewSCIMError(http.StatusBadRequest, "invalidSyntax", "参数错误: "+err.Error()))
+		return
+	}
+
+	user, err := h.scimService.CreateUser(&req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.respond(c, http.StatusCreated, user)
+}
+
+// ReplaceUser 全量更新用户
+// @Summary SCIM替换用户
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Param id path string true "用户ID"
+// @Param request body service.SCIMUser true "用户资源"
+// @Success 200 {object} service.SCIMUser
+// @Router /scim/v2/Users/{id} [put]
+func (h *SCIMHandler) ReplaceUser(c *gin.Context) {
+	var req service.SCIMUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.fail(c, service.NewSCIMError(http.StatusBadRequest, "invalidSyntax", "参数错误: "+err.Error()))
+		return
+	}
+
+	user, err := h.scimService.ReplaceUser(c.Param("id"), &req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, user)
+}
+
+// PatchUser 部分更新用户
+// @Summary SCIM修改用户
+// @Description 支持add、replace、remove操作，active为false时停用用户
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Param id path string true "用户ID"
+// @Param request body service.SCIMPatchRequest true "PATCH操作"
+// @Success 200 {object} service.SCIMUser
+// @Router /scim/v2/Users/{id} [patch]
+func (h *SCIMHandler) PatchUser(c *gin.Context) {
+	var req service.SCIMPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.fail(c, service.NewSCIMError(http.StatusBadRequest, "invalidSyntax", "参数错误: "+err.Error()))
+		return
+	}
+
+	user, err := h.scimService.PatchUser(c.Param("id"), &req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, user)
+}
+
+// DeleteUser 删除用户
+// @Summary SCIM删除用户
+// @Tags SCIM
+// @Param id path string true "用户ID"
+// @Success 204
+// @Router /scim/v2/Users/{id} [delete]
+func (h *SCIMHandler) DeleteUser(c *gin.Context) {
+	if err := h.scimService.DeleteUser(c.Param("id")); err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListGroups 查询组
+// @Summary SCIM查询组
+// @Tags SCIM
+// @Produce json
+// @Param filter query string false "过滤条件，如 displayName eq \"研发\""
+// @Param startIndex query int false "起始位置，从1开始"
+// @Param count query int false "每页数量"
+// @Success 200 {object} service.SCIMListResponse
+// @Router /scim/v2/Groups [get]
+func (h *SCIMHandler) ListGroups(c *gin.Context) {
+	var req service.SCIMListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.fail(c, service.NewSCIMError(http.StatusBadRequest, "invalidValue", "参数错误: "+err.Error()))
+		return
+	}
+
+	resp, err := h.scimService.ListGroups(&req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, resp)
+}
+
+// GetGroup 获取组
+// @Summary SCIM获取组
+// @Tags SCIM
+// @Produce json
+// @Param id path string true "组ID"
+// @Success 200 {object} service.SCIMGroup
+// @Router /scim/v2/Groups/{id} [get]
+func (h *SCIMHandler) GetGroup(c *gin.Context) {
+	group, err := h.scimService.GetGroup(c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, group)
+}
+
+// CreateGroup 创建组
+// @Summary SCIM创建组
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Param request body service.SCIMGroup true "组资源"
+// @Success 201 {object} service.SCIMGroup
+// @Router /scim/v2/Groups [post]
+func (h *SCIMHandler) CreateGroup(c *gin.Context) {
+	var req service.SCIMGroup
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.fail(c, service.NewSCIMError(http.StatusBadRequest, "invalidSyntax", "参数错误: "+err.Error()))
+		return
+	}
+
+	group, err := h.scimService.CreateGroup(&req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.respond(c, http.StatusCreated, group)
+}
+
+// ReplaceGroup 全量更新组
+// @Summary SCIM替换组
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Param id path string true "组ID"
+// @Param request body service.SCIMGroup true "组资源"
+// @Success 200 {object} service.SCIMGroup
+// @Router /scim/v2/Groups/{id} [put]
+func (h *SCIMHandler) ReplaceGroup(c *gin.Context) {
+	var req service.SCIMGroup
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.fail(c, service.NewSCIMError(http.StatusBadRequest, "invalidSyntax", "参数错误: "+err.Error()))
+		return
+	}
+
+	group, err := h.scimService.ReplaceGroup(c.Param("id"), &req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, group)
+}
+
+// PatchGroup 部分更新组
+// @Summary SCIM修改组
+// @Description 主要用于增减成员，支持 members[value eq "id"] 路径
+// @Tags SCIM
+// @Accept json
+// @Produce json
+// @Param id path string true "组ID"
+// @Param request body service.SCIMPatchRequest true "PATCH操作"
+// @Success 200 {object} service.SCIMGroup
+// @Router /scim/v2/Groups/{id} [patch]
+func (h *SCIMHandler) PatchGroup(c *gin.Context) {
+	var req service.SCIMPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.fail(c, service.NewSCIMError(http.StatusBadRequest, "invalidSyntax", "参数错误: "+err.Error()))
+		return
+	}
+
+	group, err := h.scimService.PatchGroup(c.Param("id"), &req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.respond(c, http.StatusOK, group)
+}
+
+// DeleteGroup 删除组
+// @Summary SCIM删除组
+// @Description 只能删除通过SCIM创建的组
+// @Tags SCIM
+// @Param id path string true "组ID"
+// @Success 204
+// @Router /scim/v2/Groups/{id} [delete]
+func (h *SCIMHandler) DeleteGroup(c *gin.Context) {
+	if err := h.scimService.DeleteGroup(c.Param("id")); err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// respond 输出SCIM响应
+func (h *SCIMHandler) respond(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", scimContentType)
+	c.JSON(status, body)
+}
+
+// fail 输出SCIM错误，非SCIM错误按500处理
+func (h *SCIMHandler) fail(c *gin.Context, err error) {
+	var scimErr *service.SCIMError
+	if !errors.As(err, &scimErr) {
+		scimErr = service.NewSCIMError(http.StatusInternalServerError, "", err.Error())
+	}
+	h.respond(c, scimErr.HTTPStatus(), scimErr)
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+	"mcprapi/backend/internal/transport/middleware"
+)
+
+// SessionHandler 会话管理处理器
+type SessionHandler struct {
+	sessionService *service.SessionService
+}
+
+// NewSessionHandler 创建会话管理处理器
+func NewSessionHandler(sessionService *service.SessionService) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+	}
+}
+
+// Register 注册管理员路由
+func (h *SessionHandler) Register(router *gin.RouterGroup) {
+	// 获取用户的会话
+	router.GET("/user/:id/sessions", h.ListUserSessions)
+	// 终止用户的所有会话
+	router.DELETE("/user/:id/sessions", h.RevokeUserSessions)
+	// 终止用户的指定会话
+	router.DELETE("/user/:id/sessions/:session_id", h.RevokeUserSession)
+}
+
+// ListMySessions 获取当前用户的会话
+// @Summary 获取我的会话
+// @Description 返回当前用户所有有效的登录会话（设备、IP、User-Agent、签发和最近活跃时间）
+// @Tags 用户
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.Response{data=[]service.Session} "获取成功"
+// @Router /user/sessions [get]
+func (h *SessionHandler) ListMySessions(c *gin.Context) {
+	sessions, err := h.sessionService.List(middleware.GetCurrentUser(c), middleware.GetCurrentSessionID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取会话成功",
+		Data:    sessions,
+	})
+}
+
+// RevokeMySession 终止当前用户的指定会话
+// @Summary 终止我的会话
+// @Tags 用户
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "会话ID"
+// @Success 200 {object} dto.Response "终止成功"
+// @Router /user/sessions/{id} [delete]
+func (h *SessionHandler) RevokeMySession(c *gin.Context) {
+	if err := h.sessionService.RevokeForUser(middleware.GetCurrentUser(c), c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "终止会话成功",
+	})
+}
+
+// RevokeMyOtherSessions 终止当前用户除当前会话以外的所有会话
+// @Summary 终止我的其他会话
+// @Tags 用户
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.Response "终止成功"
+// @Router /user/sessions [delete]
+func (h *SessionHandler) RevokeMyOtherSessions(c *gin.Context) {
+	count, err := h.sessionService.RevokeAll(middleware.GetCurrentUser(c), middleware.GetCurrentSessionID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "终止会话成功",
+		Data:    gin.H{"revoked": count},
+	})
+}
+
+// ListUserSessions 获取用户的会话
+// @Summary 获取用户会话
+// @Tags 用户
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} dto.Response{data=[]service.Session} "获取成功"
+// @Router /user/{id}/sessions [get]
+func (h *SessionHandler) ListUserSessions(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.sessionService.List(userID, middleware.GetCurrentSessionID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取会话成功",
+		Data:    sessions,
+	})
+}
+
+// RevokeUserSessions 终止用户的所有会话
+// @Summary 终止用户所有会话
+// @Tags 用户
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} dto.Response "终止成功"
+// @Router /user/{id}/sessions [delete]
+func (h *SessionHandler) RevokeUserSessions(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+
+	count, err := h.sessionService.RevokeAll(userID, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "终止会话成功",
+		Data:    gin.H{"revoked": count},
+	})
+}
+
+// RevokeUserSession 终止用户的指定会话
+// @Summary 终止用户会话
+// @Tags 用户
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "用户ID"
+// @Param session_id path string true "会话ID"
+// @Success 200 {object} dto.Response "终止成功"
+// @Router /user/{id}/sessions/{session_id} [delete]
+func (h *SessionHandler) RevokeUserSession(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.sessionService.RevokeForUser(userID, c.Param("session_id")); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "终止会话成功",
+	})
+}
+
+// parseUserID 解析路径中的用户ID
+func (h *SessionHandler) parseUserID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "无效的用户ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
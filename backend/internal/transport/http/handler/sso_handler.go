@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+	"mcprapi/backend/internal/transport/middleware"
+)
+
+// SSOHandler 外部身份登录处理器
+type SSOHandler struct {
+	ssoService *service.SSOService
+}
+
+// NewSSOHandler 创建外部身份登录处理器
+func NewSSOHandler(ssoService *service.SSOService) *SSOHandler {
+	return &SSOHandler{
+		ssoService: ssoService,
+	}
+}
+
+// ListProviders 获取登录提供方列表
+// @Summary 获取外部登录提供方
+// @Description 获取已配置的外部身份提供方列表，用于登录页展示
+// @Tags 认证
+// @Produce json
+// @Success 200 {object} dto.Response{data=[]service.SSOProviderInfo} "获取成功"
+// @Router /auth/sso/providers [get]
+func (h *SSOHandler) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取登录提供方成功",
+		Data:    h.ssoService.ListProviders(),
+	})
+}
+
+// Login 跳转到外部身份提供方
+// @Summary 外部登录
+// @Description 重定向到外部身份提供方的授权页面
+// @Tags 认证
+// @Param provider path string true "登录提供方名称"
+// @Success 302 "重定向到身份提供方"
+// @Failure 400 {object} dto.Response "请求错误"
+// @Router /auth/sso/{provider}/login [get]
+func (h *SSOHandler) Login(c *gin.Context) {
+	authURL, err := h.ssoService.StartLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback 外部身份提供方回调
+// @Summary 外部登录回调
+// @Description 使用授权码完成外部登录或身份绑定，返回系统登录令牌；需要二次验证时返回mfa_token，凭其调用二次验证接口完成登录
+// @Tags 认证
+// @Produce json
+// @Param provider path string true "登录提供方名称"
+// @Param code query string true "授权码"
+// @Param state query string true "state"
+// @Success 200 {object} dto.Response{data=service.SSOLoginResponse} "登录成功"
+// @Failure 401 {object} dto.Response "认证失败"
+// @Router /auth/sso/{provider}/callback [get]
+func (h *SSOHandler) Callback(c *gin.Context) {
+	if errCode := c.Query("error"); errCode != "" {
+		c.JSON(http.StatusUnauthorized, dto.Response{
+			Code:    dto.CodeUnauthorized,
+			Message: "外部登录失败: " + errCode + " " + c.Query("error_description"),
+		})
+		return
+	}
+
+	origin := &service.LoginOrigin{ClientIP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	resp, err := h.ssoService.Callback(c.Request.Context(), c.Param("provider"), c.Query("code"), c.Query("state"), origin)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.Response{
+			Code:    dto.CodeUnauthorized,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "登录成功",
+		Data:    resp,
+	})
+}
+
+// ListIdentities 获取当前用户绑定的外部身份
+// @Summary 获取已绑定的外部身份
+// @Tags 认证
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.Response{data=[]entity.UserIdentity} "获取成功"
+// @Router /user/identities [get]
+func (h *SSOHandler) ListIdentities(c *gin.Context) {
+	identities, err := h.ssoService.ListIdentities(middleware.GetCurrentUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取外部身份成功",
+		Data:    identities,
+	})
+}
+
+// Link 绑定外部身份
+// @Summary 绑定外部身份
+// @Description 返回身份提供方授权地址，用户授权后在回调中完成绑定
+// @Tags 认证
+// @Produce json
+// @Security ApiKeyAuth
+// @Param provider path string true "登录提供方名称"
+// @Success 200 {object} dto.Response{data=map[string]string} "获取成功"
+// @Failure 400 {object} dto.Response "请求错误"
+// @Router /user/identities/{provider} [post]
+func (h *SSOHandler) Link(c *gin.Context) {
+	// 绑定后可凭外部身份直接登录，等同于签发新令牌
+	if rejectImpersonation(c) {
+		return
+	}
+
+	authURL, err := h.ssoService.StartLink(c.Request.Context(), c.Param("provider"), middleware.GetCurrentUser(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取绑定地址成功",
+		Data:    gin.H{"auth_url": authURL},
+	})
+}
+
+// Unlink 解绑外部身份
+// @Summary 解绑外部身份
+// @Tags 认证
+// @Produce json
+// @Security ApiKeyAuth
+// @Param provider path string true "登录提供方名称"
+// @Success 200 {object} dto.Response "解绑成功"
+// @Failure 400 {object} dto.Response "请求错误"
+// @Router /user/identities/{provider} [delete]
+func (h *SSOHandler) Unlink(c *gin.Context) {
+	if err := h.ssoService.Unlink(middleware.GetCurrentUser(c), c.Param("provider")); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "解绑成功",
+	})
+}
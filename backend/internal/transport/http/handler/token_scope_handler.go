@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+	"mcprapi/backend/internal/transport/middleware"
+)
+
+// TokenScopeHandler 受限令牌处理器
+type TokenScopeHandler struct {
+	tokenScopeService *service.TokenScopeService
+}
+
+// NewTokenScopeHandler 创建受限令牌处理器
+func NewTokenScopeHandler(tokenScopeService *service.TokenScopeService) *TokenScopeHandler {
+	return &TokenScopeHandler{
+		tokenScopeService: tokenScopeService,
+	}
+}
+
+// Issue 为当前用户签发受限令牌
+// @Summary 签发受限令牌
+// @Description 签发只能访问指定API或业务线的令牌（如提供给MCP Agent），权限为用户角色权限与令牌范围的交集；受限令牌只能签发范围更小、有效期不超过自身的令牌，自身会话终止时签发的令牌一并失效
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.ScopedTokenRequest true "令牌范围"
+// @Success 200 {object} dto.Response{data=service.ScopedTokenResponse} "签发成功"
+// @Router /user/scoped-token [post]
+func (h *TokenScopeHandler) Issue(c *gin.Context) {
+	if middleware.GetCurrentActor(c) != nil || middleware.GetCurrentClientID(c) != "" {
+		c.JSON(http.StatusForbidden, dto.Response{
+			Code:    dto.CodeForbidden,
+			Message: "代登录令牌和OAuth2令牌不能签发受限令牌",
+		})
+		return
+	}
+
+	var req service.ScopedTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeInvalidParams,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	current := &service.ParentToken{
+		SessionID:   middleware.GetCurrentSessionID(c),
+		ExpiresAt:   middleware.GetCurrentExpiresAt(c),
+		Restriction: middleware.GetCurrentRestriction(c),
+	}
+	resp, err := h.tokenScopeService.Issue(middleware.GetCurrentUser(c), current, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.Response{
+			Code:    dto.CodeBusinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "签发受限令牌成功",
+		Data:    resp,
+	})
+}
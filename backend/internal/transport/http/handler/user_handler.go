@@ -3,6 +3,7 @@ package handler
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
@@ -45,6 +46,8 @@ func (h *UserHandler) Login(c *gin.Context) {
 		})
 		return
 	}
+	req.ClientIP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
 
 	resp, err := h.authService.Login(&req)
 	if err != nil {
@@ -55,9 +58,15 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
+	message := "登录成功"
+	if resp.MFARequired {
+		message = "需要二次验证"
+	} else if resp.PasswordChangeRequired {
+		message = "密码已过期，请修改密码"
+	}
 	c.JSON(http.StatusOK, dto.Response{
 		Code:    dto.CodeSuccess,
-		Message: "登录成功",
+		Message: message,
 		Data:    resp,
 	})
 }
@@ -72,7 +81,7 @@ func (h *UserHandler) Login(c *gin.Context) {
 // @Failure 500 {object} dto.Response "内部错误"
 // @Router /auth/logout [post]
 func (h *UserHandler) Logout(c *gin.Context) {
-	resp, err := h.authService.Logout()
+	resp, err := h.authService.Logout(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, dto.Response{
 			Code:    dto.CodeInternalError,
@@ -88,43 +97,6 @@ func (h *UserHandler) Logout(c *gin.Context) {
 	})
 }
 
-// GenerateQRCode 生成扫码登录二维码
-func (h *UserHandler) GenerateQRCode(c *gin.Context) {
-	qrCode, err := h.authService.GenerateQRCode()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, dto.Response{
-			Code:    dto.CodeInternalError,
-			Message: "生成二维码失败: " + err.Error(),
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, dto.Response{
-		Code:    dto.CodeSuccess,
-		Message: "生成二维码成功",
-		Data:    gin.H{"qr_code": qrCode},
-	})
-}
-
-// CheckQRCode 检查二维码状态
-func (h *UserHandler) CheckQRCode(c *gin.Context) {
-	qrID := c.Param("id")
-	if qrID == "" {
-		c.JSON(http.StatusBadRequest, dto.Response{
-			Code:    dto.CodeInvalidParams,
-			Message: "二维码ID不能为空",
-		})
-		return
-	}
-
-	// 这里应该检查二维码状态，简化处理
-	c.JSON(http.StatusOK, dto.Response{
-		Code:    dto.CodeSuccess,
-		Message: "二维码状态检查成功",
-		Data:    gin.H{"status": "pending"},
-	})
-}
-
 // GetInfo 获取当前用户信息
 // @Summary 获取当前用户信息
 // @Description 获取当前登录用户的详细信息
@@ -178,6 +150,10 @@ func (h *UserHandler) GetInfo(c *gin.Context) {
 		"userId":      user.ID,     // 前端期望的字段名
 		"deptId":      user.DeptID, // 前端期望的字段名
 	}
+	// 代登录时返回实际操作的管理员，前端据此提示当前处于代登录状态
+	if actor := middleware.GetCurrentActor(c); actor != nil {
+		userInfo["impersonated_by"] = actor
+	}
 
 	c.JSON(http.StatusOK, dto.Response{
 		Code:    dto.CodeSuccess,
@@ -192,6 +168,8 @@ func (h *UserHandler) Register(router *gin.RouterGroup) {
 	{
 		// 获取用户列表
 		userRouter.GET("/list", h.List)
+		// 旧版密码哈希迁移报告
+		userRouter.GET("/password/legacy-report", h.LegacyPasswordReport)
 		// 创建用户
 		userRouter.POST("", h.Create)
 		// 更新用户
@@ -425,8 +403,37 @@ func (h *UserHandler) GetUserRoles(c *gin.Context) {
 	})
 }
 
+// LegacyPasswordReport 旧版密码哈希迁移报告
+// @Summary 旧版密码哈希报告
+// @Description 统计各密码哈希算法的账号数量，列出仍使用旧版SHA-256哈希的账号
+// @Tags 用户
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.Response{data=service.LegacyPasswordReport} "获取成功"
+// @Router /user/password/legacy-report [get]
+func (h *UserHandler) LegacyPasswordReport(c *gin.Context) {
+	report, err := h.userService.GetLegacyPasswordReport()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.Response{
+			Code:    dto.CodeInternalError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Response{
+		Code:    dto.CodeSuccess,
+		Message: "获取密码哈希报告成功",
+		Data:    report,
+	})
+}
+
 // GetUserToken 获取用户Token
 func (h *UserHandler) GetUserToken(c *gin.Context) {
+	if rejectImpersonation(c) {
+		return
+	}
+
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, dto.Response{
@@ -465,6 +472,10 @@ func (h *UserHandler) GetUserToken(c *gin.Context) {
 
 // RefreshUserToken 刷新用户Token（不递增版本号）
 func (h *UserHandler) RefreshUserToken(c *gin.Context) {
+	if rejectImpersonation(c) {
+		return
+	}
+
 	var req struct {
 		UserID     uint `json:"user_id" binding:"required"`
 		ExpireDays int  `json:"expire_days" binding:"required,min=1,max=365"`
@@ -511,6 +522,10 @@ func (h *UserHandler) RefreshUserToken(c *gin.Context) {
 
 // RefreshUserTokenWithVersion 刷新用户Token并递增版本号
 func (h *UserHandler) RefreshUserTokenWithVersion(c *gin.Context) {
+	if rejectImpersonation(c) {
+		return
+	}
+
 	var req struct {
 		UserID     uint `json:"user_id" binding:"required"`
 		ExpireDays int  `json:"expire_days" binding:"required,min=1,max=365"`
@@ -554,3 +569,15 @@ func (h *UserHandler) RefreshUserTokenWithVersion(c *gin.Context) {
 		},
 	})
 }
+
+// rejectImpersonation 代登录令牌不能签发新令牌，新令牌不携带act声明，会脱离代登录的审计和限制
+func rejectImpersonation(c *gin.Context) bool {
+	if middleware.GetCurrentActor(c) == nil {
+		return false
+	}
+	c.JSON(http.StatusForbidden, dto.Response{
+		Code:    dto.CodeForbidden,
+		Message: "代登录令牌不能签发新令牌",
+	})
+	return true
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+)
+
+// auditMaxRequestBody 审计时读取的请求体上限，超出部分不记录
+const auditMaxRequestBody = 64 * 1024
+
+// auditResponseWriter 在写出响应的同时保留一份响应体
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write 写出响应并保留响应体
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if w.body.Len() < auditMaxRequestBody {
+		w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Audit 审计中间件，需在JWT中间件之后使用；只记录审计服务中登记的管理操作路由
+func Audit(auditService *service.AuditService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route, ok := auditService.Route(c.Request.Method, c.FullPath())
+		if !ok {
+			c.Next()
+			return
+		}
+
+		// 读取请求体后放回，供处理器再次读取
+		var body []byte
+		if c.Request.Body != nil {
+			data, err := io.ReadAll(io.LimitReader(c.Request.Body, auditMaxRequestBody+1))
+			if err == nil {
+				c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), c.Request.Body))
+				if len(data) <= auditMaxRequestBody {
+					body = data
+				}
+			}
+		}
+
+		params := make(map[string]string, len(c.Params))
+		for _, p := range c.Params {
+			params[p.Key] = p.Value
+		}
+
+		req := &service.AuditRequest{
+			Route:         route,
+			ActorID:       GetCurrentUser(c),
+			ActorUsername: GetCurrentUsername(c),
+			ActorDeptID:   GetCurrentDeptID(c),
+			Method:        c.Request.Method,
+			Path:          c.Request.URL.Path,
+			IP:            c.ClientIP(),
+			RequestID:     GetRequestID(c),
+			Params:        params,
+			Body:          body,
+		}
+		if actor := GetCurrentActor(c); actor != nil {
+			req.ImpersonatorID = actor.UserID
+		}
+		auditService.Begin(req)
+
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		auditService.Finish(req, writer.Status(), writer.body.Bytes())
+	}
+}
@@ -10,18 +10,22 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"mcprapi/backend/internal/domain/entity"
 	"mcprapi/backend/internal/domain/repository"
+	"mcprapi/backend/internal/domain/service"
 	"mcprapi/backend/internal/transport/http/dto"
 	"mcprapi/backend/pkg/casbinx"
 )
 
-// Casbin Casbin中间件
-func Casbin(enforcer *casbinx.Enforcer, userRepo repository.UserRepository) gin.HandlerFunc {
+// Casbin Casbin中间件，受限令牌还需在authService校验的令牌访问范围之内；每次判断通过authService记录授权决策日志
+func Casbin(enforcer *casbinx.Enforcer, userRepo repository.UserRepository, authService *service.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 获取当前用户角色
+		// 获取当前用户角色；代登录令牌的用户为被代登录的用户，按其权限校验
 		userID := GetCurrentUser(c)
 		if userID == 0 {
 			c.JSON(http.StatusUnauthorized, dto.Response{
@@ -32,6 +36,8 @@ func Casbin(enforcer *casbinx.Enforcer, userRepo repository.UserRepository) gin.
 			return
 		}
 
+		start := time.Now()
+
 		// 获取当前用户部门ID
 		userDeptID := GetCurrentDeptID(c)
 
@@ -50,13 +56,35 @@ func Casbin(enforcer *casbinx.Enforcer, userRepo repository.UserRepository) gin.
 			return
 		}
 
-		// 如果用户没有角色，拒绝访问
-		if len(roles) == 0 {
+		// OAuth2令牌只能使用scope中授予的角色
+		roles = service.FilterRolesByScope(roles, GetCurrentScope(c))
+
+		decision := &entity.AuthzDecision{
+			Source:    entity.DecisionSourceMiddleware,
+			UserID:    userID,
+			Username:  GetCurrentUsername(c),
+			Roles:     strings.Join(roles, ","),
+			DeptID:    userDeptID,
+			Path:      path,
+			Method:    method,
+			ClientID:  GetCurrentClientID(c),
+			RequestID: GetRequestID(c),
+			IP:        c.ClientIP(),
+		}
+		deny := func(message string) {
+			decision.LatencyUs = time.Since(start).Microseconds()
+			authService.RecordDecision(decision)
 			c.JSON(http.StatusForbidden, dto.Response{
 				Code:    dto.CodeForbidden,
-				Message: "无权访问该资源",
+				Message: message,
 			})
 			c.Abort()
+		}
+
+		// 如果用户没有角色，拒绝访问
+		if len(roles) == 0 {
+			decision.Reason = service.DecisionReasonNoRole
+			deny("无权访问该资源")
 			return
 		}
 
@@ -64,21 +92,49 @@ func Casbin(enforcer *casbinx.Enforcer, userRepo repository.UserRepository) gin.
 		allowed := false
 		for _, role := range roles {
 			// 使用新的4参数Enforce方法：role, path, method, deptID
-			if enforcer.EnforceWithDept(role, path, method, userDeptID) {
+			if ok, rule := enforcer.EnforceWithDeptEx(role, path, method, userDeptID); ok {
 				allowed = true
+				decision.MatchedRule = strings.Join(rule, ", ")
 				break
 			}
 		}
 
 		if !allowed {
-			c.JSON(http.StatusForbidden, dto.Response{
-				Code:    dto.CodeForbidden,
-				Message: "无权访问该资源",
-			})
-			c.Abort()
+			decision.Reason = service.DecisionReasonNoPolicy
+			deny("无权访问该资源")
+			return
+		}
+
+		// 受限令牌只能访问角色权限与令牌范围的交集
+		if restriction := GetCurrentRestriction(c); restriction != nil {
+			inScope, err := authService.AllowedByRestriction(restriction, path, method)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, dto.Response{
+					Code:    dto.CodeInternalError,
+					Message: "校验令牌访问范围失败",
+				})
+				c.Abort()
+				return
+			}
+			if !inScope {
+				decision.Reason = service.DecisionReasonTokenScope
+				deny("令牌访问范围不包含该资源")
+				return
+			}
+		}
+
+		// 已下线、已禁用和尚未发布的API拒绝访问
+		if reason, message := service.APIDenyReason(authService.LookupAPILifecycle(path, method)); reason != "" {
+			decision.Reason = reason
+			deny(message)
 			return
 		}
 
+		decision.Allowed = true
+		decision.Reason = service.DecisionReasonPolicy
+		decision.LatencyUs = time.Since(start).Microseconds()
+		authService.RecordDecision(decision)
+
 		c.Next()
 	}
 }
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/transport/http/dto"
+)
+
+// ClientToken OAuth2令牌中间件，用于不经过Casbin中间件的路由组，需在JWT中间件之后使用
+// OAuth2令牌的权限由scope限定，而这些路由不按scope校验，因此只允许allowPaths（如按scope校验的权限校验接口）
+func ClientToken(allowPaths ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(allowPaths))
+	for _, p := range allowPaths {
+		allowed[p] = true
+	}
+
+	return func(c *gin.Context) {
+		if GetCurrentClientID(c) == "" || allowed[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		c.JSON(http.StatusForbidden, dto.Response{
+			Code:    dto.CodeForbidden,
+			Message: "OAuth2令牌不能访问该资源",
+		})
+		c.Abort()
+	}
+}
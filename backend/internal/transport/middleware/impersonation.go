@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/entity"
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+)
+
+// Impersonation 代登录中间件，需在JWT中间件之后使用
+// 拦截代登录期间禁止的请求，并记录每个代登录请求的管理员和被代登录用户；allowPaths不受限制规则约束
+func Impersonation(impersonationService *service.ImpersonationService, allowPaths ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(allowPaths))
+	for _, p := range allowPaths {
+		allowed[p] = true
+	}
+
+	return func(c *gin.Context) {
+		actor := GetCurrentActor(c)
+		if actor == nil {
+			c.Next()
+			return
+		}
+
+		log := &entity.ImpersonationLog{
+			SessionID:     GetCurrentSessionID(c),
+			ActorID:       actor.UserID,
+			ActorUsername: actor.Username,
+			UserID:        GetCurrentUser(c),
+			Username:      GetCurrentUsername(c),
+			Action:        entity.ImpersonationActionRequest,
+			Method:        c.Request.Method,
+			Path:          c.Request.URL.Path,
+			IP:            c.ClientIP(),
+		}
+
+		if !allowed[log.Path] && impersonationService.IsBlocked(log.Method, log.Path) {
+			log.Blocked = true
+			log.Status = http.StatusForbidden
+			impersonationService.Record(log)
+
+			c.JSON(http.StatusForbidden, dto.Response{
+				Code:    dto.CodeForbidden,
+				Message: "代登录期间禁止该操作",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+
+		log.Status = c.Writer.Status()
+		impersonationService.Record(log)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 
+	"mcprapi/backend/internal/domain/service"
 	"mcprapi/backend/internal/transport/http/dto"
 )
 
@@ -18,11 +19,29 @@ type JWTClaims struct {
 	Username     string `json:"username"`
 	DeptID       uint   `json:"dept_id"`
 	TokenVersion int    `json:"v,omitempty"` // Token版本号，用于安全控制
+	ClientID     string `json:"client_id,omitempty"` // OAuth2客户端ID
+	Scope        string `json:"scope,omitempty"`     // 授权范围
+	Act          *service.Actor `json:"act,omitempty"` // 代登录时实际操作的管理员
+	Rst          *service.TokenRestriction `json:"rst,omitempty"` // 受限令牌的访问范围
 	jwt.StandardClaims
 }
 
-// JWT JWT中间件
-func JWT(secret string) gin.HandlerFunc {
+// SessionChecker 会话校验，Touch返回false表示会话已被终止或过期
+type SessionChecker interface {
+	Touch(sessionID, ip, userAgent string) (bool, error)
+}
+
+// UserChecker 用户状态校验，用户已被停用或删除时返回false
+type UserChecker interface {
+	Active(userID uint) (bool, error)
+	// TokenVersion 用户当前的Token版本号，未携带会话ID的令牌据此判断是否已失效
+	TokenVersion(userID uint) (int, error)
+}
+
+// JWT JWT中间件，sessions不为空时校验Token对应的会话是否已被终止，users不为空时拒绝已停用或删除的用户；
+// 未携带会话ID的Token无法按会话终止，改为比较Token版本号，修改密码或重置Token后失效
+// 会话存储不可用时默认拒绝请求，failOpen为true时放行
+func JWT(secret string, sessions SessionChecker, users UserChecker, failOpen bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := c.GetHeader("Authorization")
 		if token == "" {
@@ -58,11 +77,83 @@ func JWT(secret string) gin.HandlerFunc {
 			return
 		}
 
+		// 检查会话是否已被终止；未携带会话ID的Token在下面比较Token版本号
+		if sessions != nil && claims.Id != "" {
+			active, err := sessions.Touch(claims.Id, c.ClientIP(), c.Request.UserAgent())
+			if err != nil && !failOpen {
+				c.JSON(http.StatusServiceUnavailable, dto.Response{
+					Code:    dto.CodeInternalError,
+					Message: "会话校验失败，请稍后重试",
+				})
+				c.Abort()
+				return
+			}
+			if err == nil && !active {
+				c.JSON(http.StatusUnauthorized, dto.Response{
+					Code:    dto.CodeUnauthorized,
+					Message: "会话已失效，请重新登录",
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		// 检查用户是否已被停用或删除
+		if users != nil {
+			active, err := users.Active(claims.UserID)
+			if err != nil {
+				c.JSON(http.StatusServiceUnavailable, dto.Response{
+					Code:    dto.CodeInternalError,
+					Message: "用户状态校验失败，请稍后重试",
+				})
+				c.Abort()
+				return
+			}
+			if !active {
+				c.JSON(http.StatusUnauthorized, dto.Response{
+					Code:    dto.CodeUnauthorized,
+					Message: "用户已禁用",
+				})
+				c.Abort()
+				return
+			}
+
+			if claims.Id == "" {
+				version, err := users.TokenVersion(claims.UserID)
+				if err != nil {
+					c.JSON(http.StatusServiceUnavailable, dto.Response{
+						Code:    dto.CodeInternalError,
+						Message: "用户状态校验失败，请稍后重试",
+					})
+					c.Abort()
+					return
+				}
+				if claims.TokenVersion < version {
+					c.JSON(http.StatusUnauthorized, dto.Response{
+						Code:    dto.CodeUnauthorized,
+						Message: "认证令牌已失效，请重新登录",
+					})
+					c.Abort()
+					return
+				}
+			}
+		}
+
 		// 将用户信息存储到上下文中
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("dept_id", claims.DeptID)
 		c.Set("token_version", claims.TokenVersion) // 存储Token版本号
+		c.Set("client_id", claims.ClientID)
+		c.Set("scope", claims.Scope)
+		c.Set("session_id", claims.Id)
+		c.Set("expires_at", claims.ExpiresAt)
+		if claims.Act != nil {
+			c.Set("actor", claims.Act)
+		}
+		if claims.Rst != nil {
+			c.Set("restriction", claims.Rst)
+		}
 
 		c.Next()
 	}
@@ -123,4 +214,64 @@ func GetCurrentTokenVersion(c *gin.Context) int {
 	}
 
 	return tokenVersion.(int)
-}
\ No newline at end of file
+}
+
+// GetCurrentClientID 获取当前Token的OAuth2客户端ID
+func GetCurrentClientID(c *gin.Context) string {
+	clientID, exists := c.Get("client_id")
+	if !exists {
+		return ""
+	}
+
+	return clientID.(string)
+}
+
+// GetCurrentScope 获取当前Token的授权范围
+func GetCurrentScope(c *gin.Context) string {
+	scope, exists := c.Get("scope")
+	if !exists {
+		return ""
+	}
+
+	return scope.(string)
+}
+
+// GetCurrentSessionID 获取当前Token的会话ID
+func GetCurrentSessionID(c *gin.Context) string {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return ""
+	}
+
+	return sessionID.(string)
+}
+
+// GetCurrentExpiresAt 获取当前Token的过期时间
+func GetCurrentExpiresAt(c *gin.Context) int64 {
+	expiresAt, exists := c.Get("expires_at")
+	if !exists {
+		return 0
+	}
+
+	return expiresAt.(int64)
+}
+
+// GetCurrentActor 获取代登录令牌中实际操作的管理员，非代登录时返回nil
+func GetCurrentActor(c *gin.Context) *service.Actor {
+	actor, exists := c.Get("actor")
+	if !exists {
+		return nil
+	}
+
+	return actor.(*service.Actor)
+}
+
+// GetCurrentRestriction 获取受限令牌的访问范围，不受限时返回nil
+func GetCurrentRestriction(c *gin.Context) *service.TokenRestriction {
+	restriction, exists := c.Get("restriction")
+	if !exists {
+		return nil
+	}
+
+	return restriction.(*service.TokenRestriction)
+}
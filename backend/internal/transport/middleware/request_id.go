@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader 请求ID的请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID 请求ID中间件，沿用调用方传入的请求ID，没有时生成新的ID，并写入响应头
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = uuid.New().String()
+		}
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// GetRequestID 获取当前请求ID
+func GetRequestID(c *gin.Context) string {
+	requestID, exists := c.Get("request_id")
+	if !exists {
+		return ""
+	}
+
+	return requestID.(string)
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+)
+
+// SCIMAuth SCIM预置令牌认证中间件，身份提供方使用固定的Bearer令牌调用；审计日志的操作人记为scim
+func SCIMAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		provided := strings.TrimPrefix(header, "Bearer ")
+		if token == "" || provided == header || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="scim"`)
+			c.Header("Content-Type", "application/scim+json")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, service.NewSCIMError(http.StatusUnauthorized, "", "无效的SCIM令牌"))
+			return
+		}
+		c.Set("username", service.AuditActorSCIM)
+		c.Next()
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"mcprapi/backend/internal/domain/service"
+	"mcprapi/backend/internal/transport/http/dto"
+)
+
+// TokenRestriction 受限令牌中间件，用于不经过Casbin中间件的路由组，需在JWT中间件之后使用
+// 受限令牌只能访问其范围内的请求，allowPaths（如权限校验接口）不受限制
+func TokenRestriction(authService *service.AuthService, allowPaths ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(allowPaths))
+	for _, p := range allowPaths {
+		allowed[p] = true
+	}
+
+	return func(c *gin.Context) {
+		restriction := GetCurrentRestriction(c)
+		if restriction == nil || allowed[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		inScope, err := authService.AllowedByRestriction(restriction, c.Request.URL.Path, c.Request.Method)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, dto.Response{
+				Code:    dto.CodeInternalError,
+				Message: "校验令牌访问范围失败",
+			})
+			c.Abort()
+			return
+		}
+		if !inScope {
+			c.JSON(http.StatusForbidden, dto.Response{
+				Code:    dto.CodeForbidden,
+				Message: "令牌访问范围不包含该资源",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -104,6 +104,17 @@ func (e *Enforcer) EnforceWithDept(sub, obj, act string, deptID uint) bool {
 	return result
 }
 
+// EnforceWithDeptEx 检查权限（包含部门维度），同时返回命中的策略
+func (e *Enforcer) EnforceWithDeptEx(sub, obj, act string, deptID uint) (bool, []string) {
+	deptStr := "*"
+	if deptID > 0 {
+		deptStr = fmt.Sprintf("%d", deptID)
+	}
+
+	result, explain, _ := e.enforcer.EnforceEx(sub, obj, act, deptStr)
+	return result, explain
+}
+
 // AddPolicy 添加策略
 func (e *Enforcer) AddPolicy(sub, obj, act, eft string) (bool, error) {
 	return e.enforcer.AddPolicy(sub, obj, act, eft)
@@ -0,0 +1,351 @@
+package ldapx
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ErrInvalidCredentials 用户名或密码错误
+var ErrInvalidCredentials = errors.New("LDAP用户名或密码错误")
+
+// Config LDAP配置
+type Config struct {
+	URL                string        `mapstructure:"url"` // ldap://host:389 或 ldaps://host:636
+	StartTLS           bool          `mapstructure:"start_tls"`
+	InsecureSkipVerify bool          `mapstructure:"insecure_skip_verify"`
+	Timeout            time.Duration `mapstructure:"timeout"`
+	BindDN             string        `mapstructure:"bind_dn"` // 用于搜索的服务账号
+	BindPassword       string        `mapstructure:"bind_password"`
+	BaseDN             string        `mapstructure:"base_dn"`
+
+	// 用户
+	UserBaseDN    string `mapstructure:"user_base_dn"`    // 默认使用BaseDN
+	UserFilter    string `mapstructure:"user_filter"`     // 默认(objectClass=inetOrgPerson)
+	UsernameAttr  string `mapstructure:"username_attr"`   // 默认uid，AD通常为sAMAccountName
+	NameAttr      string `mapstructure:"name_attr"`       // 默认cn
+	EmailAttr     string `mapstructure:"email_attr"`      // 默认mail
+	DisabledAttr  string `mapstructure:"disabled_attr"`   // 可选，值为TRUE表示账号已禁用
+	OUFilter      string `mapstructure:"ou_filter"`       // 默认(objectClass=organizationalUnit)
+	GroupBaseDN   string `mapstructure:"group_base_dn"`   // 默认使用BaseDN
+	GroupFilter   string `mapstructure:"group_filter"`    // 默认(|(objectClass=groupOfNames)(objectClass=group))
+	GroupNameAttr string `mapstructure:"group_name_attr"` // 默认cn
+	MemberAttr    string `mapstructure:"member_attr"`     // 默认member
+}
+
+// User 目录中的用户
+type User struct {
+	DN       string `json:"dn"`
+	ParentDN string `json:"parent_dn"` // 所在OU的DN
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Disabled bool   `json:"disabled"`
+}
+
+// OU 目录中的组织单元
+type OU struct {
+	DN       string `json:"dn"`
+	ParentDN string `json:"parent_dn"`
+	Name     string `json:"name"`
+}
+
+// Group 目录中的组
+type Group struct {
+	DN      string   `json:"dn"`
+	Name    string   `json:"name"`
+	Members []string `json:"members"` // 成员DN
+}
+
+// Client LDAP客户端，每次操作使用独立连接
+type Client struct {
+	cfg Config
+}
+
+// NewClient 创建LDAP客户端
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return nil, errors.New("LDAP配置缺少url或base_dn")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.UserBaseDN == "" {
+		cfg.UserBaseDN = cfg.BaseDN
+	}
+	if cfg.GroupBaseDN == "" {
+		cfg.GroupBaseDN = cfg.BaseDN
+	}
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(objectClass=inetOrgPerson)"
+	}
+	if cfg.UsernameAttr == "" {
+		cfg.UsernameAttr = "uid"
+	}
+	if cfg.NameAttr == "" {
+		cfg.NameAttr = "cn"
+	}
+	if cfg.EmailAttr == "" {
+		cfg.EmailAttr = "mail"
+	}
+	if cfg.OUFilter == "" {
+		cfg.OUFilter = "(objectClass=organizationalUnit)"
+	}
+	if cfg.GroupFilter == "" {
+		cfg.GroupFilter = "(|(objectClass=groupOfNames)(objectClass=group))"
+	}
+	if cfg.GroupNameAttr == "" {
+		cfg.GroupNameAttr = "cn"
+	}
+	if cfg.MemberAttr == "" {
+		cfg.MemberAttr = "member"
+	}
+	return &Client{cfg: cfg}, nil
+}
+
+// Authenticate 先用服务账号搜索用户DN，再以用户身份绑定校验密码
+func (c *Client) Authenticate(username, password string) (*User, error) {
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	filter := fmt.Sprintf("(&%s(%s=%s))", c.cfg.UserFilter, c.cfg.UsernameAttr, ldap.EscapeFilter(username))
+	entries, err := c.search(conn, c.cfg.UserBaseDN, filter, c.userAttributes())
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+
+	user := c.toUser(entries[0])
+	if user.Disabled {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := conn.Bind(entries[0].DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAP绑定失败: %v", err)
+	}
+
+	return user, nil
+}
+
+// ListUsers 列出目录中的全部用户
+func (c *Client) ListUsers() ([]*User, error) {
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entries, err := c.search(conn, c.cfg.UserBaseDN, c.cfg.UserFilter, c.userAttributes())
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*User, 0, len(entries))
+	for _, entry := range entries {
+		user := c.toUser(entry)
+		if user.Username == "" {
+			continue
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// ListOUs 列出BaseDN下的全部组织单元
+func (c *Client) ListOUs() ([]*OU, error) {
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entries, err := c.search(conn, c.cfg.BaseDN, c.cfg.OUFilter, []string{"ou"})
+	if err != nil {
+		return nil, err
+	}
+
+	ous := make([]*OU, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.GetAttributeValue("ou")
+		if name == "" {
+			name = firstRDNValue(entry.DN)
+		}
+		ous = append(ous, &OU{
+			DN:       NormalizeDN(entry.DN),
+			ParentDN: ParentDN(entry.DN),
+			Name:     name,
+		})
+	}
+	return ous, nil
+}
+
+// ListGroups 列出目录中的全部组
+func (c *Client) ListGroups() ([]*Group, error) {
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return c.listGroups(conn, c.cfg.GroupFilter)
+}
+
+// GroupsOf 获取用户所属的组
+func (c *Client) GroupsOf(userDN string) ([]*Group, error) {
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	filter := fmt.Sprintf("(&%s(%s=%s))", c.cfg.GroupFilter, c.cfg.MemberAttr, ldap.EscapeFilter(userDN))
+	return c.listGroups(conn, filter)
+}
+
+// listGroups 按过滤条件查询组
+func (c *Client) listGroups(conn *ldap.Conn, filter string) ([]*Group, error) {
+	entries, err := c.search(conn, c.cfg.GroupBaseDN, filter, []string{c.cfg.GroupNameAttr, c.cfg.MemberAttr})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]*Group, 0, len(entries))
+	for _, entry := range entries {
+		group := &Group{
+			DN:   NormalizeDN(entry.DN),
+			Name: entry.GetAttributeValue(c.cfg.GroupNameAttr),
+		}
+		for _, member := range entry.GetAttributeValues(c.cfg.MemberAttr) {
+			group.Members = append(group.Members, NormalizeDN(member))
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// connect 建立连接并使用服务账号绑定
+func (c *Client) connect() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.cfg.InsecureSkipVerify}
+	conn, err := ldap.DialURL(c.cfg.URL, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("连接LDAP失败: %v", err)
+	}
+	conn.SetTimeout(c.cfg.Timeout)
+
+	if c.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("LDAP StartTLS失败: %v", err)
+		}
+	}
+
+	if c.cfg.BindDN != "" {
+		if err := conn.Bind(c.cfg.BindDN, c.cfg.BindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("LDAP服务账号绑定失败: %v", err)
+		}
+	}
+	return conn, nil
+}
+
+// search 分页搜索，避免大目录触发服务端条数限制
+func (c *Client) search(conn *ldap.Conn, baseDN, filter string, attributes []string) ([]*ldap.Entry, error) {
+	req := ldap.NewSearchRequest(
+		baseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		filter,
+		attributes,
+		nil,
+	)
+	result, err := conn.SearchWithPaging(req, 500)
+	if err != nil {
+		return nil, fmt.Errorf("LDAP搜索失败: %v", err)
+	}
+	return result.Entries, nil
+}
+
+// userAttributes 用户查询需要的属性
+func (c *Client) userAttributes() []string {
+	attributes := []string{c.cfg.UsernameAttr, c.cfg.NameAttr, c.cfg.EmailAttr}
+	if c.cfg.DisabledAttr != "" {
+		attributes = append(attributes, c.cfg.DisabledAttr)
+	}
+	return attributes
+}
+
+// toUser 转换用户条目
+func (c *Client) toUser(entry *ldap.Entry) *User {
+	user := &User{
+		DN:       NormalizeDN(entry.DN),
+		ParentDN: ParentDN(entry.DN),
+		Username: entry.GetAttributeValue(c.cfg.UsernameAttr),
+		Name:     entry.GetAttributeValue(c.cfg.NameAttr),
+		Email:    entry.GetAttributeValue(c.cfg.EmailAttr),
+	}
+	if c.cfg.DisabledAttr != "" {
+		user.Disabled = strings.EqualFold(entry.GetAttributeValue(c.cfg.DisabledAttr), "TRUE")
+	}
+	return user
+}
+
+// NormalizeDN 规范化DN（小写、去除RDN之间的空格），用于比较
+func NormalizeDN(dn string) string {
+	rdns := normalizedRDNs(dn)
+	if rdns == nil {
+		return strings.ToLower(strings.TrimSpace(dn))
+	}
+	return strings.Join(rdns, ",")
+}
+
+// ParentDN 获取DN的上级DN（已规范化）
+func ParentDN(dn string) string {
+	rdns := normalizedRDNs(dn)
+	if len(rdns) <= 1 {
+		return ""
+	}
+	return strings.Join(rdns[1:], ",")
+}
+
+// normalizedRDNs 解析DN并返回规范化后的RDN列表，解析失败返回nil
+func normalizedRDNs(dn string) []string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return nil
+	}
+
+	escaper := strings.NewReplacer(`\`, `\\`, ",", `\,`, "+", `\+`, "=", `\=`)
+	rdns := make([]string, 0, len(parsed.RDNs))
+	for _, rdn := range parsed.RDNs {
+		attrs := make([]string, 0, len(rdn.Attributes))
+		for _, attr := range rdn.Attributes {
+			attrs = append(attrs, strings.ToLower(attr.Type)+"="+escaper.Replace(strings.ToLower(attr.Value)))
+		}
+		rdns = append(rdns, strings.Join(attrs, "+"))
+	}
+	return rdns
+}
+
+// firstRDNValue 获取DN第一个RDN的值
+func firstRDNValue(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return dn
+	}
+	return parsed.RDNs[0].Attributes[0].Value
+}
@@ -0,0 +1,72 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret RFC 6238附录B中SHA1算法的密钥"12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// RFC 6238附录B的测试向量，取8位验证码的后6位
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d) error = %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+	codeAt := func(offset int64) string {
+		code, err := Code(rfcSecret, current+offset)
+		if err != nil {
+			t.Fatalf("Code() error = %v", err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{"当前周期", rfcSecret, codeAt(0), 1, current, true},
+		{"上一周期在偏差内", rfcSecret, codeAt(-1), 1, current - 1, true},
+		{"下一周期在偏差内", rfcSecret, codeAt(1), 1, current + 1, true},
+		{"超出偏差的上一周期", rfcSecret, codeAt(-2), 1, 0, false},
+		{"超出偏差的下一周期", rfcSecret, codeAt(2), 1, 0, false},
+		{"不允许偏差时只接受当前周期", rfcSecret, codeAt(-1), 0, 0, false},
+		{"首尾空白", rfcSecret, " " + codeAt(0) + " ", 1, current, true},
+		{"小写密钥", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", codeAt(0), 1, current, true},
+		{"位数不足", rfcSecret, codeAt(0)[:5], 1, 0, false},
+		{"无效的密钥", "not-base32!", codeAt(0), 1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(tt.secret, tt.code, now, tt.skew)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Validate() = (%d, %v), want (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
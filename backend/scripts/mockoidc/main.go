@@ -0,0 +1,267 @@
+// 模拟OIDC身份提供方，仅用于本地开发和联调外部登录
+//
+// 使用方法:
+//
+//	go run ./scripts/mockoidc -addr :9400 -issuer http://localhost:9400
+//
+// 授权页面允许任意填写用户信息；带login_hint参数时跳过页面，直接以该用户名授权
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const keyID = "mock-key"
+
+// authCode 授权码携带的数据
+type authCode struct {
+	ClientID    string
+	RedirectURI string
+	Nonce       string
+	Claims      jwt.MapClaims
+	ExpiresAt   time.Time
+}
+
+// server 模拟OIDC服务
+type server struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	key          *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]*authCode
+}
+
+var authorizePage = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Mock OIDC</title></head>
+<body>
+<h3>Mock OIDC 登录</h3>
+<form method="post" action="/authorize">
+{{range $k, $v := .Query}}<input type="hidden" name="{{$k}}" value="{{index $v 0}}">
+{{end}}
+<p>用户名 <input name="username" value="alice"></p>
+<p>姓名 <input name="name" value="Alice"></p>
+<p>邮箱 <input name="email" value="alice@example.com"></p>
+<p>部门编码 <input name="department" value="default"></p>
+<p>用户标识(sub) <input name="sub" placeholder="默认同用户名"></p>
+<button type="submit">登录并授权</button>
+</form>
+</body></html>`))
+
+func main() {
+	var addr, issuer, clientID, clientSecret string
+	flag.StringVar(&addr, "addr", ":9400", "监听地址")
+	flag.StringVar(&issuer, "issuer", "http://localhost:9400", "issuer地址，需与后端配置一致")
+	flag.StringVar(&clientID, "client-id", "mcprapi", "客户端ID")
+	flag.StringVar(&clientSecret, "client-secret", "mock-secret", "客户端密钥")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalf("生成签名密钥失败: %v", err)
+	}
+
+	s := &server{
+		issuer:       issuer,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		key:          key,
+		codes:        make(map[string]*authCode),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+
+	log.Printf("模拟OIDC服务已启动: %s (issuer=%s)", addr, issuer)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+// discovery 发现文档
+func (s *server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+// jwks 公钥集合
+func (s *server) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": keyID,
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize 授权端点：GET展示登录页，POST或带login_hint时直接签发授权码
+func (s *server) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.Form.Get("client_id") != s.clientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	redirectURI := r.Form.Get("redirect_uri")
+	if redirectURI == "" {
+		http.Error(w, "missing redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	username := r.Form.Get("username")
+	if r.Method == http.MethodGet {
+		username = r.Form.Get("login_hint")
+		if username == "" {
+			_ = authorizePage.Execute(w, map[string]interface{}{"Query": r.URL.Query()})
+			return
+		}
+	}
+
+	subject := r.Form.Get("sub")
+	if subject == "" {
+		subject = username
+	}
+	email := r.Form.Get("email")
+	if email == "" {
+		email = username + "@example.com"
+	}
+	name := r.Form.Get("name")
+	if name == "" {
+		name = username
+	}
+
+	code := randomString()
+	s.mu.Lock()
+	s.codes[code] = &authCode{
+		ClientID:    s.clientID,
+		RedirectURI: redirectURI,
+		Nonce:       r.Form.Get("nonce"),
+		Claims: jwt.MapClaims{
+			"sub":                subject,
+			"preferred_username": username,
+			"name":               name,
+			"email":              email,
+			"email_verified":     true,
+			"department":         r.Form.Get("department"),
+		},
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}
+	s.mu.Unlock()
+
+	callback, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	query := callback.Query()
+	query.Set("code", code)
+	query.Set("state", r.Form.Get("state"))
+	callback.RawQuery = query.Encode()
+
+	http.Redirect(w, r, callback.String(), http.StatusFound)
+}
+
+// token 令牌端点
+func (s *server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.Form.Get("client_id")
+		clientSecret = r.Form.Get("client_secret")
+	}
+	if clientID != s.clientID || clientSecret != s.clientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.Form.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	s.mu.Lock()
+	code, ok := s.codes[r.Form.Get("code")]
+	delete(s.codes, r.Form.Get("code"))
+	s.mu.Unlock()
+	if !ok || time.Now().After(code.ExpiresAt) || code.RedirectURI != r.Form.Get("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": s.issuer,
+		"aud": s.clientID,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+	if code.Nonce != "" {
+		claims["nonce"] = code.Nonce
+	}
+	for k, v := range code.Claims {
+		claims[k] = v
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(s.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// writeJSON 输出JSON响应
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// randomString 生成随机字符串
+func randomString() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}